@@ -0,0 +1,332 @@
+package sdk
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/apache/arrow-go/v18/arrow"
+	"github.com/apache/arrow-go/v18/arrow/array"
+	"github.com/apache/arrow-go/v18/arrow/memory"
+)
+
+// newRecord builds an Arrow record from batch rows. Each column takes the Arrow type of its
+// first non-nil value and the server casts it to the table type; values of other Go types are
+// sent as text, and columns holding only nil are sent as nulls. Slices, maps and structs of
+// concrete Go types are sent as Arrow lists, maps and structs; those holding interface{}
+// values are sent as JSON text, which the server reads into list, map and struct columns.
+func newRecord(columns []string, rows [][]interface{}) (arrow.Record, error) {
+	fields := make([]arrow.Field, len(columns))
+	for c, name := range columns {
+		fields[c] = arrow.Field{Name: name, Type: arrow.Null, Nullable: true}
+		for _, row := range rows {
+			if row[c] != nil {
+				fields[c].Type = columnType(row[c])
+				break
+			}
+		}
+	}
+
+	builder := array.NewRecordBuilder(memory.DefaultAllocator, arrow.NewSchema(fields, nil))
+	defer builder.Release()
+	builder.Reserve(len(rows))
+
+	for i, row := range rows {
+		for c, value := range row {
+			if value == nil {
+				builder.Field(c).AppendNull()
+				continue
+			}
+			if !appendValue(builder.Field(c), value) {
+				return nil, fmt.Errorf("%w: row %d column %s holds %T, but earlier rows hold %s",
+					ErrBatchInvalid, i, columns[c], value, fields[c].Type)
+			}
+		}
+	}
+
+	return builder.NewRecord(), nil
+}
+
+// columnType returns the Arrow type a column is sent as, given one of its values
+func columnType(value interface{}) arrow.DataType {
+	switch value.(type) {
+	case bool:
+		return arrow.FixedWidthTypes.Boolean
+	case int8:
+		return arrow.PrimitiveTypes.Int8
+	case int16:
+		return arrow.PrimitiveTypes.Int16
+	case int32:
+		return arrow.PrimitiveTypes.Int32
+	case int, int64:
+		return arrow.PrimitiveTypes.Int64
+	case uint8:
+		return arrow.PrimitiveTypes.Uint8
+	case uint16:
+		return arrow.PrimitiveTypes.Uint16
+	case uint32:
+		return arrow.PrimitiveTypes.Uint32
+	case uint, uint64:
+		return arrow.PrimitiveTypes.Uint64
+	case float32:
+		return arrow.PrimitiveTypes.Float32
+	case float64:
+		return arrow.PrimitiveTypes.Float64
+	case []byte:
+		return arrow.BinaryTypes.Binary
+	case time.Time:
+		return arrow.FixedWidthTypes.Timestamp_ns
+	default:
+		if dataType, ok := nestedType(reflect.TypeOf(value)); ok {
+			return dataType
+		}
+		return arrow.BinaryTypes.String
+	}
+}
+
+// nestedType returns the Arrow list, map or struct type of a Go slice, map or struct type,
+// reporting false for other types and for those holding interface{} values
+func nestedType(t reflect.Type) (arrow.DataType, bool) {
+	switch t.Kind() {
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return nil, false
+		}
+		element, ok := elementType(t.Elem())
+		if !ok {
+			return nil, false
+		}
+		return arrow.ListOf(element), true
+	case reflect.Map:
+		key, ok := elementType(t.Key())
+		if !ok {
+			return nil, false
+		}
+		value, ok := elementType(t.Elem())
+		if !ok {
+			return nil, false
+		}
+		return arrow.MapOf(key, value), true
+	case reflect.Struct:
+		if t == reflect.TypeOf(time.Time{}) {
+			return nil, false
+		}
+		var fields []arrow.Field
+		for _, field := range structFields(t) {
+			fieldType, ok := elementType(field.Type)
+			if !ok {
+				return nil, false
+			}
+			fields = append(fields, arrow.Field{Name: field.Name, Type: fieldType, Nullable: true})
+		}
+		return arrow.StructOf(fields...), true
+	}
+	return nil, false
+}
+
+// elementType returns the Arrow type of the elements, keys, values or fields of a nested type
+func elementType(t reflect.Type) (arrow.DataType, bool) {
+	if t.Kind() == reflect.Interface {
+		return nil, false
+	}
+	if dataType := columnType(reflect.Zero(t).Interface()); dataType != arrow.BinaryTypes.String {
+		return dataType, true
+	}
+	if t.Kind() == reflect.String {
+		return arrow.BinaryTypes.String, true
+	}
+	return nestedType(t)
+}
+
+// structField is an exported field of a Go struct with the name it is sent under
+type structField struct {
+	Name  string
+	Type  reflect.Type
+	Index int
+}
+
+// structFields returns the exported fields of a struct type, named by their json tag when
+// they have one
+func structFields(t reflect.Type) []structField {
+	var fields []structField
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		name := field.Name
+		if tag, _, _ := strings.Cut(field.Tag.Get("json"), ","); tag == "-" {
+			continue
+		} else if tag != "" {
+			name = tag
+		}
+		fields = append(fields, structField{Name: name, Type: field.Type, Index: i})
+	}
+	return fields
+}
+
+// isNestedValue reports whether value is a slice (other than []byte), map or struct
+func isNestedValue(value interface{}) bool {
+	switch value.(type) {
+	case []byte, time.Time:
+		return false
+	}
+	switch v := reflect.ValueOf(value); v.Kind() {
+	case reflect.Slice, reflect.Array:
+		return v.Type().Elem().Kind() != reflect.Uint8
+	case reflect.Map, reflect.Struct:
+		return true
+	}
+	return false
+}
+
+// appendValue appends a non-nil value to a column builder, reporting false when the value
+// does not have the column's Go type
+func appendValue(builder array.Builder, value interface{}) bool {
+	switch b := builder.(type) {
+	case *array.BooleanBuilder:
+		v, ok := value.(bool)
+		if ok {
+			b.Append(v)
+		}
+		return ok
+	case *array.Int8Builder:
+		v, ok := value.(int8)
+		if ok {
+			b.Append(v)
+		}
+		return ok
+	case *array.Int16Builder:
+		v, ok := value.(int16)
+		if ok {
+			b.Append(v)
+		}
+		return ok
+	case *array.Int32Builder:
+		v, ok := value.(int32)
+		if ok {
+			b.Append(v)
+		}
+		return ok
+	case *array.Int64Builder:
+		switch v := value.(type) {
+		case int64:
+			b.Append(v)
+		case int:
+			b.Append(int64(v))
+		default:
+			return false
+		}
+		return true
+	case *array.Uint8Builder:
+		v, ok := value.(uint8)
+		if ok {
+			b.Append(v)
+		}
+		return ok
+	case *array.Uint16Builder:
+		v, ok := value.(uint16)
+		if ok {
+			b.Append(v)
+		}
+		return ok
+	case *array.Uint32Builder:
+		v, ok := value.(uint32)
+		if ok {
+			b.Append(v)
+		}
+		return ok
+	case *array.Uint64Builder:
+		switch v := value.(type) {
+		case uint64:
+			b.Append(v)
+		case uint:
+			b.Append(uint64(v))
+		default:
+			return false
+		}
+		return true
+	case *array.Float32Builder:
+		v, ok := value.(float32)
+		if ok {
+			b.Append(v)
+		}
+		return ok
+	case *array.Float64Builder:
+		v, ok := value.(float64)
+		if ok {
+			b.Append(v)
+		}
+		return ok
+	case *array.BinaryBuilder:
+		v, ok := value.([]byte)
+		if ok {
+			b.Append(v)
+		}
+		return ok
+	case *array.TimestampBuilder:
+		v, ok := value.(time.Time)
+		if ok {
+			b.AppendTime(v)
+		}
+		return ok
+	case *array.StringBuilder:
+		if v, ok := value.(string); ok {
+			b.Append(v)
+			return true
+		}
+		if isNestedValue(value) {
+			data, err := json.Marshal(value)
+			if err != nil {
+				return false
+			}
+			b.Append(string(data))
+			return true
+		}
+		if columnType(value) != arrow.BinaryTypes.String {
+			return false
+		}
+		b.Append(fmt.Sprintf("%v", value))
+		return true
+	case *array.ListBuilder:
+		v := reflect.ValueOf(value)
+		if (v.Kind() != reflect.Slice && v.Kind() != reflect.Array) || !arrow.TypeEqual(columnType(value), b.Type()) {
+			return false
+		}
+		b.Append(true)
+		for i := 0; i < v.Len(); i++ {
+			if !appendValue(b.ValueBuilder(), v.Index(i).Interface()) {
+				return false
+			}
+		}
+		return true
+	case *array.MapBuilder:
+		v := reflect.ValueOf(value)
+		if v.Kind() != reflect.Map || !arrow.TypeEqual(columnType(value), b.Type()) {
+			return false
+		}
+		b.Append(true)
+		iter := v.MapRange()
+		for iter.Next() {
+			if !appendValue(b.KeyBuilder(), iter.Key().Interface()) || !appendValue(b.ItemBuilder(), iter.Value().Interface()) {
+				return false
+			}
+		}
+		return true
+	case *array.StructBuilder:
+		v := reflect.ValueOf(value)
+		if v.Kind() != reflect.Struct || !arrow.TypeEqual(columnType(value), b.Type()) {
+			return false
+		}
+		b.Append(true)
+		for i, field := range structFields(v.Type()) {
+			if !appendValue(b.FieldBuilder(i), v.Field(field.Index).Interface()) {
+				return false
+			}
+		}
+		return true
+	}
+	return false
+}
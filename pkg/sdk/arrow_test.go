@@ -0,0 +1,106 @@
+package sdk
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/apache/arrow-go/v18/arrow"
+	"github.com/apache/arrow-go/v18/arrow/array"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestNewRecord tests that batch rows are encoded column by column
+func TestNewRecord(t *testing.T) {
+	t.Run("InfersColumnTypes", func(t *testing.T) {
+		at := time.Date(2024, 1, 2, 15, 4, 5, 0, time.UTC)
+		record, err := newRecord(
+			[]string{"id", "name", "score", "created_at", "note"},
+			[][]interface{}{
+				{1, nil, 1.5, at, nil},
+				{int64(2), "bob", 2.5, at, nil},
+			},
+		)
+		require.NoError(t, err)
+		defer record.Release()
+
+		assert.Equal(t, int64(2), record.NumRows())
+		assert.Equal(t, arrow.PrimitiveTypes.Int64, record.Schema().Field(0).Type)
+		assert.Equal(t, arrow.BinaryTypes.String, record.Schema().Field(1).Type)
+		assert.Equal(t, arrow.PrimitiveTypes.Float64, record.Schema().Field(2).Type)
+		assert.Equal(t, arrow.FixedWidthTypes.Timestamp_ns, record.Schema().Field(3).Type)
+		assert.Equal(t, arrow.Null, record.Schema().Field(4).Type)
+
+		assert.Equal(t, []int64{1, 2}, record.Column(0).(*array.Int64).Int64Values())
+		assert.True(t, record.Column(1).IsNull(0))
+		assert.Equal(t, "bob", record.Column(1).(*array.String).Value(1))
+		assert.Equal(t, at, record.Column(3).(*array.Timestamp).Value(0).ToTime(arrow.Nanosecond))
+	})
+
+	t.Run("EncodesNestedValues", func(t *testing.T) {
+		type point struct {
+			X     int32  `json:"x"`
+			Label string `json:"label,omitempty"`
+			skip  bool
+		}
+		record, err := newRecord(
+			[]string{"tags", "attrs", "point", "raw"},
+			[][]interface{}{
+				{[]string{"a", "b"}, map[string]int32{"x": 1}, point{X: 3, Label: "p"}, []interface{}{1, "two"}},
+				{[]string{}, nil, point{}, nil},
+			},
+		)
+		require.NoError(t, err)
+		defer record.Release()
+
+		assert.Equal(t, arrow.ListOf(arrow.BinaryTypes.String), record.Schema().Field(0).Type)
+		assert.Equal(t, arrow.MapOf(arrow.BinaryTypes.String, arrow.PrimitiveTypes.Int32), record.Schema().Field(1).Type)
+		assert.Equal(t, arrow.StructOf(
+			arrow.Field{Name: "x", Type: arrow.PrimitiveTypes.Int32, Nullable: true},
+			arrow.Field{Name: "label", Type: arrow.BinaryTypes.String, Nullable: true},
+		), record.Schema().Field(2).Type)
+
+		tags := record.Column(0).(*array.List)
+		assert.Equal(t, `["a" "b"]`, tags.ListValues().String())
+		start, end := tags.ValueOffsets(1)
+		assert.Equal(t, start, end)
+		assert.True(t, record.Column(1).IsNull(1))
+		assert.Equal(t, int32(3), record.Column(2).(*array.Struct).Field(0).(*array.Int32).Value(0))
+
+		// Values without a fixed element type are sent as JSON text
+		assert.Equal(t, arrow.BinaryTypes.String, record.Schema().Field(3).Type)
+		assert.Equal(t, `[1,"two"]`, record.Column(3).(*array.String).Value(0))
+	})
+
+	t.Run("RejectsMixedColumnTypes", func(t *testing.T) {
+		_, err := newRecord([]string{"id"}, [][]interface{}{{1}, {"two"}})
+		require.Error(t, err)
+		assert.True(t, errors.Is(err, ErrBatchInvalid))
+	})
+}
+
+// TestBatchRecords tests that appended rows and records are sent together
+func TestBatchRecords(t *testing.T) {
+	batch := &Batch{Columns: []string{"id"}}
+	require.NoError(t, batch.Append(int64(1)))
+
+	record, err := newRecord([]string{"id"}, [][]interface{}{{int64(2)}, {int64(3)}})
+	require.NoError(t, err)
+	require.NoError(t, batch.AppendRecord(record))
+	record.Release()
+
+	assert.Equal(t, 3, batch.Rows())
+
+	records, err := batch.records()
+	require.NoError(t, err)
+	require.Len(t, records, 2)
+	assert.Equal(t, int64(1), records[0].NumRows())
+	assert.Equal(t, int64(2), records[1].NumRows())
+	for _, record := range records {
+		record.Release()
+	}
+
+	batch.release()
+	assert.Equal(t, 1, batch.Rows())
+}
@@ -16,6 +16,7 @@ import (
 	"time"
 
 	"github.com/go-faster/errors"
+	"go.opentelemetry.io/otel/propagation"
 	"go.uber.org/zap"
 
 	// Unified protocol package
@@ -677,6 +678,16 @@ func (c *Client) dial(ctx context.Context) (*connection, error) {
 		tcpConn.SetKeepAlivePeriod(30 * time.Second)
 	}
 
+	// Wrap the connection when the server's native port has TLS enabled
+	if c.opt.TLS != nil {
+		tlsConn := tls.Client(conn, c.opt.TLS)
+		if err := tlsConn.HandshakeContext(ctx); err != nil {
+			conn.Close()
+			return nil, err
+		}
+		conn = tlsConn
+	}
+
 	connection := newConnection(conn, c, connID)
 
 	// Perform handshake
@@ -905,7 +916,7 @@ func (c *connection) ping(ctx context.Context) error {
 func (c *connection) query(ctx context.Context, queryStr string, args ...interface{}) (*Rows, error) {
 	// Create and send ClientQuery signal
 	queryID := generateQueryID()
-	query := signals.NewClientQuery(queryStr, queryID, c.client.opt.Auth.Database, c.client.opt.Auth.Username, c.client.opt.Auth.Password)
+	query := c.newClientQuery(ctx, queryStr, queryID)
 
 	message, err := c.codec.EncodeMessage(query)
 	if err != nil {
@@ -996,7 +1007,7 @@ func (c *connection) query(ctx context.Context, queryStr string, args ...interfa
 func (c *connection) exec(ctx context.Context, queryStr string, args ...interface{}) error {
 	// Create and send ClientQuery signal
 	queryID := generateQueryID()
-	query := signals.NewClientQuery(queryStr, queryID, c.client.opt.Auth.Database, c.client.opt.Auth.Username, c.client.opt.Auth.Password)
+	query := c.newClientQuery(ctx, queryStr, queryID)
 
 	message, err := c.codec.EncodeMessage(query)
 	if err != nil {
@@ -1056,6 +1067,7 @@ func (c *connection) exec(ctx context.Context, queryStr string, args ...interfac
 func (c *connection) prepareBatch(ctx context.Context, queryStr string, opts ...BatchOption) (*Batch, error) {
 	// Create batch with proper initialization
 	batch := &Batch{
+		Client: c.client,
 		Query: Query{
 			Body:    queryStr,
 			QueryID: generateQueryID(),
@@ -1066,6 +1078,14 @@ func (c *connection) prepareBatch(ctx context.Context, queryStr string, opts ...
 		Sent:      false,
 	}
 
+	// Take the table and columns from INSERT queries; other statements are prepared as is
+	if tableName, columns, err := ParseInsertQuery(queryStr); err == nil {
+		batch.TableName = tableName
+		if columns != nil {
+			batch.Columns = columns
+		}
+	}
+
 	// Apply options
 	options := &BatchOptions{}
 	for _, opt := range opts {
@@ -1092,6 +1112,18 @@ func (c *connection) close() {
 	}
 }
 
+// newClientQuery creates the ClientQuery signal of a query, carrying the trace context
+// of ctx so the server's spans join the caller's trace
+func (c *connection) newClientQuery(ctx context.Context, queryStr, queryID string) *signals.ClientQuery {
+	query := signals.NewClientQuery(queryStr, queryID, c.client.opt.Auth.Database, c.client.opt.Auth.Username, c.client.opt.Auth.Password)
+
+	carrier := propagation.MapCarrier{}
+	propagation.TraceContext{}.Inject(ctx, carrier)
+	query.TraceParent = carrier.Get("traceparent")
+
+	return query
+}
+
 // generateQueryID generates a unique query ID
 func generateQueryID() string {
 	return fmt.Sprintf("query_%d", time.Now().UnixNano())
@@ -4,8 +4,10 @@ import (
 	"context"
 	"database/sql"
 	"database/sql/driver"
+	"encoding/json"
 	"fmt"
 	"io"
+	"reflect"
 	"regexp"
 	"strings"
 	"time"
@@ -13,6 +15,7 @@ import (
 	"net"
 	"strconv"
 
+	"github.com/apache/arrow-go/v18/arrow"
 	"github.com/go-faster/errors"
 	"github.com/google/uuid"
 
@@ -100,13 +103,15 @@ func WithReleaseConnection() BatchOption {
 	}
 }
 
-// Batch represents a batch for insertion
+// Batch represents a batch for insertion. Rows appended with Append are sent as columnar
+// Arrow record batches, together with records appended with AppendRecord.
 type Batch struct {
 	Client    *Client
 	Query     Query
 	TableName string
 	Columns   []string
 	Data      [][]interface{}
+	Records   []arrow.Record
 	Sent      bool
 	onClose   func()
 }
@@ -193,6 +198,16 @@ func (r *Rows) Scan(dest ...interface{}) error {
 	}
 
 	for i, val := range row {
+		// NULL leaves the zero value of the destination
+		if val == nil {
+			target := reflect.ValueOf(dest[i])
+			if target.Kind() != reflect.Pointer || target.IsNil() {
+				return fmt.Errorf("unsupported scan destination type: %T", dest[i])
+			}
+			target.Elem().Set(reflect.Zero(target.Elem().Type()))
+			continue
+		}
+
 		// All values from readQueryResponse are stored as strings
 		strVal, ok := val.(string)
 		if !ok {
@@ -269,14 +284,37 @@ func (r *Rows) Scan(dest ...interface{}) error {
 				}
 			}
 			*d = parsed
+		case *[]byte:
+			*d = []byte(strVal)
+		case *interface{}:
+			*d = strVal
 		default:
-			return fmt.Errorf("unsupported scan destination type: %T", dest[i])
+			// Lists, maps and structs arrive as JSON
+			if !isNestedDestination(dest[i]) {
+				return fmt.Errorf("unsupported scan destination type: %T", dest[i])
+			}
+			if err := json.Unmarshal([]byte(strVal), dest[i]); err != nil {
+				return fmt.Errorf("failed to parse %T from '%s': %w", dest[i], strVal, err)
+			}
 		}
 	}
 
 	return nil
 }
 
+// isNestedDestination reports whether dest points to a slice, array, map or struct
+func isNestedDestination(dest interface{}) bool {
+	target := reflect.ValueOf(dest)
+	if target.Kind() != reflect.Pointer || target.IsNil() {
+		return false
+	}
+	switch target.Elem().Kind() {
+	case reflect.Slice, reflect.Array, reflect.Map, reflect.Struct:
+		return true
+	}
+	return false
+}
+
 // Columns returns the column names
 func (r *Rows) Columns() ([]string, error) {
 	if r.Closed {
@@ -321,6 +359,18 @@ func (b *Batch) Append(values ...interface{}) error {
 	return nil
 }
 
+// AppendRecord adds an Arrow record to the batch. Its columns are matched to the table
+// columns by name and cast to their types by the server; the batch retains the record.
+func (b *Batch) AppendRecord(record arrow.Record) error {
+	if b.Sent {
+		return errors.New("batch already sent")
+	}
+
+	record.Retain()
+	b.Records = append(b.Records, record)
+	return nil
+}
+
 // Send sends the batch to the server
 func (b *Batch) Send() error {
 	if b.Sent {
@@ -347,9 +397,35 @@ func (b *Batch) Send() error {
 
 	// Mark as sent
 	b.Sent = true
+	b.release()
 	return nil
 }
 
+// records returns the record batches to send, with the appended rows first
+func (b *Batch) records() ([]arrow.Record, error) {
+	records := make([]arrow.Record, 0, len(b.Records)+1)
+	if len(b.Data) > 0 {
+		record, err := newRecord(b.Columns, b.Data)
+		if err != nil {
+			return nil, err
+		}
+		records = append(records, record)
+	}
+	for _, record := range b.Records {
+		record.Retain()
+		records = append(records, record)
+	}
+	return records, nil
+}
+
+// release releases the records appended to the batch
+func (b *Batch) release() {
+	for _, record := range b.Records {
+		record.Release()
+	}
+	b.Records = nil
+}
+
 // Flush flushes the batch without closing
 func (b *Batch) Flush() error {
 	return b.Send()
@@ -368,7 +444,11 @@ func (b *Batch) Close() error {
 
 // Rows returns the number of rows in the batch
 func (b *Batch) Rows() int {
-	return len(b.Data)
+	rows := len(b.Data)
+	for _, record := range b.Records {
+		rows += int(record.NumRows())
+	}
+	return rows
 }
 
 // IsSent returns true if the batch has been sent
@@ -466,14 +546,21 @@ func (r *netConnReader) ReadByte() (byte, error) {
 	return buf[0], nil
 }
 
-// sendBatchData sends batch data to the server using unified protocol
+// sendBatchData sends batch data to the server using unified protocol; the caller reads
+// the server's response
 func (c *connection) sendBatchData(batch *Batch) error {
-	// Create ClientData signal using unified protocol
-	clientData := signals.NewClientData(
-		batch.TableName,
-		batch.Columns,
-		batch.Data,
-	)
+	records, err := batch.records()
+	if err != nil {
+		return err
+	}
+	defer func() {
+		for _, record := range records {
+			record.Release()
+		}
+	}()
+
+	// Create ClientData signal carrying the columnar record batches
+	clientData := signals.NewClientData(batch.TableName, records...)
 
 	// Use unified codec to encode and send the message
 	message, err := c.codec.EncodeMessage(clientData)
@@ -485,48 +572,6 @@ func (c *connection) sendBatchData(batch *Batch) error {
 		return fmt.Errorf("failed to send client data: %w", err)
 	}
 
-	// Read response packets using unified protocol until EndOfStream
-	for {
-		// Read and decode message using unified protocol
-		message, err := c.codec.ReadMessage(c.conn)
-		if err != nil {
-			if err == io.EOF {
-				break // Client disconnected, stop reading
-			}
-			return fmt.Errorf("failed to read message: %w", err)
-		}
-
-		// Unpack the message into a signal
-		signal, err := c.codec.UnpackSignal(message)
-		if err != nil {
-			return fmt.Errorf("failed to unpack message: %w", err)
-		}
-
-		switch signal.Type() {
-		case protocol.ServerEndOfStream:
-			// End of response, batch operation successful
-			return nil
-		case protocol.ServerException:
-			// Handle exception signal
-			exception := signal.(*signals.ServerException)
-			return fmt.Errorf("server exception [%s]: %s", exception.ErrorCode, exception.ErrorMessage)
-		case protocol.ServerClose:
-			// Handle server close signal
-			close := signal.(*signals.ServerClose)
-			c.bad = true // Mark connection as bad
-			return fmt.Errorf("server closed connection: %s", close.Reason)
-		case protocol.ServerData:
-			// Handle data signal - just continue reading for batch operations
-			continue
-		case protocol.ServerProgress:
-			// Handle progress signal - just continue reading
-			continue
-		default:
-			// Skip other packet types for now
-			continue
-		}
-	}
-
 	return nil
 }
 
@@ -0,0 +1,57 @@
+package sdk
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestRowsScanNested tests that list, map and struct values scan from their JSON text
+func TestRowsScanNested(t *testing.T) {
+	rows := &Rows{
+		Cols: []Column{
+			{Name: "tags", Type: "VARCHAR[]"},
+			{Name: "attrs", Type: "MAP(VARCHAR, INTEGER)"},
+			{Name: "point", Type: "STRUCT(x INTEGER, label VARCHAR)"},
+			{Name: "raw", Type: "INTEGER[]"},
+		},
+		Data: [][]interface{}{
+			{`["a",null]`, `{"x":1}`, `{"x":3,"label":"p"}`, `[1,2]`},
+			{nil, nil, nil, nil},
+		},
+	}
+
+	var (
+		tags  []*string
+		attrs map[string]int32
+		point struct {
+			X     int32  `json:"x"`
+			Label string `json:"label"`
+		}
+		raw interface{}
+	)
+	require.True(t, rows.Next())
+	require.NoError(t, rows.Scan(&tags, &attrs, &point, &raw))
+	require.Len(t, tags, 2)
+	assert.Equal(t, "a", *tags[0])
+	assert.Nil(t, tags[1])
+	assert.Equal(t, map[string]int32{"x": 1}, attrs)
+	assert.Equal(t, int32(3), point.X)
+	assert.Equal(t, "p", point.Label)
+	assert.Equal(t, "[1,2]", raw)
+
+	// NULL resets each destination to its zero value
+	require.True(t, rows.Next())
+	require.NoError(t, rows.Scan(&tags, &attrs, &point, &raw))
+	assert.Nil(t, tags)
+	assert.Nil(t, attrs)
+	assert.Zero(t, point)
+	assert.Nil(t, raw)
+
+	rows.Current = 1
+	var count int
+	assert.Error(t, rows.Scan(&count, &attrs, &point, &raw))
+	var bad chan int
+	assert.Error(t, rows.Scan(&tags, &attrs, &point, &bad))
+}
@@ -116,6 +116,11 @@ func (a *Astha) GetSchedulerStats() map[string]interface{} {
 	return a.scheduler.GetSchedulerStats()
 }
 
+// CheckComponentHealth runs the health check of every registered component
+func (a *Astha) CheckComponentHealth(ctx context.Context) map[string]error {
+	return a.scheduler.CheckComponentHealth(ctx)
+}
+
 // GetComponentInfo returns information about a specific component
 func (a *Astha) GetComponentInfo(name string) (ComponentInfo, bool) {
 	return a.scheduler.GetComponentInfo(name)
@@ -165,6 +165,11 @@ func TestAsthaComponentRegistration(t *testing.T) {
 	subscribers := astha.GetTableSubscribers("tables")
 	assert.Contains(t, subscribers, "test_component")
 
+	// Verify the health check reaches the component
+	health := astha.CheckComponentHealth(ctx)
+	assert.Contains(t, health, "test_component")
+	assert.NoError(t, health["test_component"])
+
 	// Unregister component
 	err = astha.UnregisterComponent("test_component")
 	require.NoError(t, err)
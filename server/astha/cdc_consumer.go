@@ -9,7 +9,9 @@ import (
 
 	"github.com/gear6io/ranger/pkg/errors"
 	"github.com/gear6io/ranger/server/metadata/registry/regtypes"
+	"github.com/gear6io/ranger/server/tracing"
 	"github.com/rs/zerolog"
+	"go.opentelemetry.io/otel/attribute"
 )
 
 // =============================================================================
@@ -93,13 +95,20 @@ func (c *CDCConsumer) processChanges(ctx context.Context) error {
 			Int64("last_id", changes[len(changes)-1].ID).
 			Msg("Processing CDC changes batch")
 
+		batchCtx, span := tracing.Start(ctx, "astha.process_changes",
+			attribute.Int("ranger.changes", len(changes)),
+			attribute.Int64("ranger.first_change_id", changes[0].ID),
+			attribute.Int64("ranger.last_change_id", changes[len(changes)-1].ID))
+
 		// 2. Process the batch
-		if err := c.processBatch(ctx, changes); err != nil {
-			return err
-		}
+		err = c.processBatch(batchCtx, changes)
 
 		// 3. ONLY if processing succeeds, delete processed logs
-		if err := c.deleteProcessedLogs(ctx, changes); err != nil {
+		if err == nil {
+			err = c.deleteProcessedLogs(batchCtx, changes)
+		}
+		tracing.End(span, err)
+		if err != nil {
 			return err
 		}
 
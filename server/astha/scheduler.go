@@ -8,7 +8,9 @@ import (
 
 	"github.com/gear6io/ranger/pkg/errors"
 	"github.com/gear6io/ranger/server/metadata/registry/regtypes"
+	"github.com/gear6io/ranger/server/tracing"
 	"github.com/rs/zerolog"
+	"go.opentelemetry.io/otel/attribute"
 )
 
 // Scheduler manages component subscriptions and event distribution
@@ -214,6 +216,31 @@ func (s *Scheduler) UpdateComponentHealth(name string, status string) error {
 	return nil
 }
 
+// CheckComponentHealth calls OnHealth on every registered component instance, records the
+// outcome as the component status and returns the error of each component by name
+func (s *Scheduler) CheckComponentHealth(ctx context.Context) map[string]error {
+	s.mu.RLock()
+	instances := make(map[string]Subscriber[any], len(s.componentInstances))
+	for name, instance := range s.componentInstances {
+		instances[name] = instance
+	}
+	s.mu.RUnlock()
+
+	results := make(map[string]error, len(instances))
+	for name, instance := range instances {
+		err := instance.OnHealth(ctx)
+		status := "active"
+		if err != nil {
+			status = "error"
+		}
+		// The component may have been unregistered while it was checked
+		_ = s.UpdateComponentHealth(name, status)
+		results[name] = err
+	}
+
+	return results
+}
+
 // eventDistributionLoop continuously distributes events to components
 func (s *Scheduler) eventDistributionLoop() {
 	ticker := time.NewTicker(50 * time.Millisecond) // Check for events every 50ms
@@ -244,20 +271,28 @@ func (s *Scheduler) distributeEvents() error {
 			continue
 		}
 
+		ctx, span := tracing.Start(s.ctx, "astha.distribute_events",
+			attribute.String("db.collection.name", table))
+
 		// Get events for this table
-		events, err := s.eventStore.GetEvents(s.ctx, table, 100) // Process up to 100 events at a time
+		events, err := s.eventStore.GetEvents(ctx, table, 100) // Process up to 100 events at a time
 		if err != nil {
+			tracing.End(span, err)
 			s.logger.Error().Err(err).Str("table", table).Msg("Failed to get events for table")
 			continue
 		}
+		span.SetAttributes(attribute.Int("ranger.events", len(events)))
 
 		if len(events) == 0 {
+			tracing.End(span, nil)
 			continue
 		}
 
 		// Get subscribers for this table
 		subscribers, exists := s.subscriptions[table]
+		span.SetAttributes(attribute.Int("ranger.subscribers", len(subscribers)))
 		if !exists || len(subscribers) == 0 {
+			tracing.End(span, nil)
 			s.logger.Debug().Str("table", table).Msg("No subscribers for table, skipping event distribution")
 			continue
 		}
@@ -266,6 +301,7 @@ func (s *Scheduler) distributeEvents() error {
 		for _, event := range events {
 			s.distributeEventToSubscribers(event, subscribers)
 		}
+		tracing.End(span, nil)
 	}
 
 	return nil
@@ -0,0 +1,157 @@
+package audit
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/gear6io/ranger/pkg/errors"
+	"github.com/gear6io/ranger/server/config"
+	"github.com/gear6io/ranger/server/metadata/registry/regtypes"
+	"github.com/rs/zerolog"
+)
+
+// pruneInterval is how often entries past the retention period are removed
+const pruneInterval = time.Hour
+
+// Logger records authentication attempts and statements to the configured sink and
+// removes entries past the retention period. A nil Logger records nothing.
+type Logger struct {
+	sink      Sink
+	selects   string
+	retention time.Duration
+	logger    zerolog.Logger
+
+	stop chan struct{}
+	wg   sync.WaitGroup
+}
+
+// NewLogger creates the audit logger described by cfg, writing registry entries through
+// store. It returns nil when auditing is disabled.
+func NewLogger(cfg config.AuditConfig, store Store, logger zerolog.Logger) (*Logger, error) {
+	if !cfg.Enabled {
+		return nil, nil
+	}
+
+	var sink Sink
+	switch cfg.Sink {
+	case "", config.AuditSinkRegistry:
+		sink = NewRegistrySink(store)
+	case config.AuditSinkFile:
+		fileSink, err := NewFileSink(cfg.FilePath)
+		if err != nil {
+			return nil, err
+		}
+		sink = fileSink
+	case config.AuditSinkBoth:
+		fileSink, err := NewFileSink(cfg.FilePath)
+		if err != nil {
+			return nil, err
+		}
+		sink = multiSink{NewRegistrySink(store), fileSink}
+	default:
+		return nil, errors.New(ErrUnknownSink, "unknown audit sink", nil).AddContext("sink", cfg.Sink)
+	}
+
+	selects := cfg.Selects
+	if selects == "" {
+		selects = config.AuditSelectsPrivileged
+	}
+
+	return NewLoggerWithSink(sink, selects, time.Duration(cfg.RetentionDays)*24*time.Hour, logger), nil
+}
+
+// NewLoggerWithSink creates an audit logger writing to sink. A positive retention starts
+// a background loop removing older entries.
+func NewLoggerWithSink(sink Sink, selects string, retention time.Duration, logger zerolog.Logger) *Logger {
+	l := &Logger{
+		sink:      sink,
+		selects:   selects,
+		retention: retention,
+		logger:    logger.With().Str("component", "audit").Logger(),
+		stop:      make(chan struct{}),
+	}
+
+	if retention > 0 {
+		l.wg.Add(1)
+		go l.retentionLoop()
+	}
+	return l
+}
+
+// Record writes entry to the sink. Failures are logged rather than returned so auditing
+// never fails the audited operation.
+func (l *Logger) Record(ctx context.Context, entry *regtypes.AccessLog) {
+	if l == nil {
+		return
+	}
+
+	if entry.CreatedAt.IsZero() {
+		entry.CreatedAt = time.Now()
+	}
+	entry.UpdatedAt = entry.CreatedAt
+
+	// Entries of cancelled queries are still recorded
+	if err := l.sink.Write(context.WithoutCancel(ctx), entry); err != nil {
+		l.logger.Error().Err(err).
+			Str("action", entry.Action).
+			Str("user", entry.Username).
+			Msg("Failed to record audit entry")
+	}
+}
+
+// RecordsSelect reports whether a SELECT should be recorded, given whether the user is a
+// superuser and whether the statement was denied
+func (l *Logger) RecordsSelect(superuser, denied bool) bool {
+	if l == nil {
+		return false
+	}
+
+	switch l.selects {
+	case config.AuditSelectsAll:
+		return true
+	case config.AuditSelectsPrivileged:
+		return superuser || denied
+	default:
+		return false
+	}
+}
+
+// Prune removes the entries older than the retention period
+func (l *Logger) Prune(ctx context.Context) error {
+	if l == nil || l.retention <= 0 {
+		return nil
+	}
+	return l.sink.Prune(ctx, time.Now().Add(-l.retention))
+}
+
+// Close stops the retention loop and closes the sink
+func (l *Logger) Close() error {
+	if l == nil {
+		return nil
+	}
+
+	close(l.stop)
+	l.wg.Wait()
+	return l.sink.Close()
+}
+
+// retentionLoop prunes old entries at startup and then every pruneInterval
+func (l *Logger) retentionLoop() {
+	defer l.wg.Done()
+
+	ticker := time.NewTicker(pruneInterval)
+	defer ticker.Stop()
+
+	for {
+		if err := l.Prune(context.Background()); err != nil {
+			l.logger.Error().Err(err).Msg("Failed to prune audit log")
+		}
+
+		select {
+		case <-l.stop:
+			return
+		case <-ticker.C:
+		}
+	}
+}
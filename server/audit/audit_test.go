@@ -0,0 +1,197 @@
+package audit
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gear6io/ranger/server/config"
+	"github.com/gear6io/ranger/server/metadata/registry/regtypes"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// memoryStore keeps registry audit entries in memory
+type memoryStore struct {
+	mu      sync.Mutex
+	entries []*regtypes.AccessLog
+}
+
+func (m *memoryStore) InsertAccessLog(ctx context.Context, entry *regtypes.AccessLog) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.entries = append(m.entries, entry)
+	return nil
+}
+
+func (m *memoryStore) PruneAccessLog(ctx context.Context, cutoff time.Time) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var kept []*regtypes.AccessLog
+	for _, entry := range m.entries {
+		if !entry.CreatedAt.Before(cutoff) {
+			kept = append(kept, entry)
+		}
+	}
+	removed := int64(len(m.entries) - len(kept))
+	m.entries = kept
+	return removed, nil
+}
+
+// entryAt returns an entry for action recorded at createdAt
+func entryAt(action string, createdAt time.Time) *regtypes.AccessLog {
+	return &regtypes.AccessLog{Action: action, TimeAuditable: regtypes.TimeAuditable{CreatedAt: createdAt}}
+}
+
+// readRecords reads the JSON lines of an audit file
+func readRecords(t *testing.T, path string) []fileRecord {
+	file, err := os.Open(path)
+	require.NoError(t, err)
+	defer file.Close()
+
+	var records []fileRecord
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		var record fileRecord
+		require.NoError(t, json.Unmarshal(scanner.Bytes(), &record))
+		records = append(records, record)
+	}
+	require.NoError(t, scanner.Err())
+	return records
+}
+
+func TestNewLogger(t *testing.T) {
+	store := &memoryStore{}
+
+	logger, err := NewLogger(config.AuditConfig{Enabled: false}, store, zerolog.Nop())
+	require.NoError(t, err)
+	assert.Nil(t, logger)
+	// A disabled logger records nothing
+	logger.Record(context.Background(), &regtypes.AccessLog{Action: "LOGIN"})
+	assert.False(t, logger.RecordsSelect(true, true))
+	require.NoError(t, logger.Close())
+
+	_, err = NewLogger(config.AuditConfig{Enabled: true, Sink: "syslog"}, store, zerolog.Nop())
+	require.Error(t, err)
+
+	path := filepath.Join(t.TempDir(), "audit.jsonl")
+	logger, err = NewLogger(config.AuditConfig{Enabled: true, Sink: config.AuditSinkBoth, FilePath: path}, store, zerolog.Nop())
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	logger.Record(ctx, &regtypes.AccessLog{Username: "alice", Protocol: "jdbc", Action: "LOGIN", Status: regtypes.AccessStatusOK})
+	require.NoError(t, logger.Close())
+
+	require.Len(t, store.entries, 1)
+	assert.Equal(t, "alice", store.entries[0].Username)
+	assert.False(t, store.entries[0].CreatedAt.IsZero())
+
+	records := readRecords(t, path)
+	require.Len(t, records, 1)
+	assert.Equal(t, "LOGIN", records[0].Action)
+	assert.Equal(t, regtypes.AccessStatusOK, records[0].Status)
+}
+
+func TestRecordsSelect(t *testing.T) {
+	tests := []struct {
+		selects   string
+		superuser bool
+		denied    bool
+		expected  bool
+	}{
+		{config.AuditSelectsNone, true, true, false},
+		{config.AuditSelectsAll, false, false, true},
+		{config.AuditSelectsPrivileged, false, false, false},
+		{config.AuditSelectsPrivileged, true, false, true},
+		{config.AuditSelectsPrivileged, false, true, true},
+	}
+
+	for _, tt := range tests {
+		logger := &Logger{selects: tt.selects}
+		assert.Equal(t, tt.expected, logger.RecordsSelect(tt.superuser, tt.denied), "%s superuser=%v denied=%v", tt.selects, tt.superuser, tt.denied)
+	}
+}
+
+func TestRetention(t *testing.T) {
+	store := &memoryStore{}
+	logger := NewLoggerWithSink(NewRegistrySink(store), config.AuditSelectsPrivileged, 0, zerolog.Nop())
+	defer logger.Close()
+
+	ctx := context.Background()
+	logger.Record(ctx, entryAt("DROP TABLE", time.Now().Add(-48*time.Hour)))
+	logger.Record(ctx, &regtypes.AccessLog{Action: "CREATE TABLE"})
+
+	// Without a retention period nothing is pruned
+	require.NoError(t, logger.Prune(ctx))
+	assert.Len(t, store.entries, 2)
+
+	logger.retention = 24 * time.Hour
+	require.NoError(t, logger.Prune(ctx))
+	require.Len(t, store.entries, 1)
+	assert.Equal(t, "CREATE TABLE", store.entries[0].Action)
+}
+
+func TestFileSinkRotation(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "logs", "audit.jsonl")
+	sink, err := NewFileSink(path)
+	require.NoError(t, err)
+	defer sink.Close()
+
+	ctx := context.Background()
+	day1 := time.Date(2026, 3, 1, 23, 0, 0, 0, time.Local)
+	day2 := day1.Add(2 * time.Hour)
+	day3 := day2.Add(24 * time.Hour)
+
+	require.NoError(t, sink.Write(ctx, entryAt("INSERT", day1)))
+	require.NoError(t, sink.Write(ctx, entryAt("UPDATE", day2)))
+	require.NoError(t, sink.Write(ctx, entryAt("DELETE", day3)))
+
+	first := readRecords(t, path+".2026-03-01")
+	require.Len(t, first, 1)
+	assert.Equal(t, "INSERT", first[0].Action)
+	second := readRecords(t, path+".2026-03-02")
+	require.Len(t, second, 1)
+	assert.Equal(t, "UPDATE", second[0].Action)
+	current := readRecords(t, path)
+	require.Len(t, current, 1)
+	assert.Equal(t, "DELETE", current[0].Action)
+
+	// Pruning removes rotated files of days before the cutoff only
+	require.NoError(t, sink.Prune(ctx, day3))
+	assert.NoFileExists(t, path+".2026-03-01")
+	assert.NoFileExists(t, path+".2026-03-02")
+	assert.FileExists(t, path)
+}
+
+func TestFileSinkRotationFailure(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.jsonl")
+	sink, err := NewFileSink(path)
+	require.NoError(t, err)
+	defer sink.Close()
+
+	ctx := context.Background()
+	day1 := time.Date(2026, 3, 1, 12, 0, 0, 0, time.Local)
+	day2 := day1.Add(24 * time.Hour)
+	require.NoError(t, sink.Write(ctx, entryAt("INSERT", day1)))
+
+	// A directory in place of the rotated file fails the rotation
+	require.NoError(t, os.Mkdir(path+".2026-03-01", 0755))
+	require.Error(t, sink.Write(ctx, entryAt("UPDATE", day2)))
+
+	// The sink stays usable and rotates once the obstacle is gone
+	require.NoError(t, os.Remove(path+".2026-03-01"))
+	require.NoError(t, sink.Write(ctx, entryAt("DELETE", day2)))
+	first := readRecords(t, path+".2026-03-01")
+	require.Len(t, first, 1)
+	assert.Equal(t, "INSERT", first[0].Action)
+	current := readRecords(t, path)
+	require.Len(t, current, 1)
+	assert.Equal(t, "DELETE", current[0].Action)
+}
@@ -0,0 +1,13 @@
+package audit
+
+import "github.com/gear6io/ranger/pkg/errors"
+
+// Audit-specific error codes
+var (
+	ErrFileSinkOpenFailed   = errors.MustNewCode("audit.file_sink_open_failed")
+	ErrFileSinkWriteFailed  = errors.MustNewCode("audit.file_sink_write_failed")
+	ErrFileSinkRotateFailed = errors.MustNewCode("audit.file_sink_rotate_failed")
+	ErrFileSinkPruneFailed  = errors.MustNewCode("audit.file_sink_prune_failed")
+	ErrRegistrySinkFailed   = errors.MustNewCode("audit.registry_sink_failed")
+	ErrUnknownSink          = errors.MustNewCode("audit.unknown_sink")
+)
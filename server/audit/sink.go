@@ -0,0 +1,281 @@
+package audit
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gear6io/ranger/pkg/errors"
+	"github.com/gear6io/ranger/server/metadata/registry/regtypes"
+)
+
+// rotatedDateLayout is the date suffix of rotated audit files
+const rotatedDateLayout = "2006-01-02"
+
+// Sink persists audit log entries
+type Sink interface {
+	// Write records one entry
+	Write(ctx context.Context, entry *regtypes.AccessLog) error
+	// Prune removes the entries recorded before cutoff
+	Prune(ctx context.Context, cutoff time.Time) error
+	// Close releases the sink's resources
+	Close() error
+}
+
+// Store is the registry API the registry sink writes through
+type Store interface {
+	InsertAccessLog(ctx context.Context, entry *regtypes.AccessLog) error
+	PruneAccessLog(ctx context.Context, cutoff time.Time) (int64, error)
+}
+
+// RegistrySink records entries in the registry access_log table
+type RegistrySink struct {
+	store Store
+}
+
+// NewRegistrySink creates a sink writing to the registry access_log table
+func NewRegistrySink(store Store) *RegistrySink {
+	return &RegistrySink{store: store}
+}
+
+// Write inserts entry into the access_log table
+func (s *RegistrySink) Write(ctx context.Context, entry *regtypes.AccessLog) error {
+	if err := s.store.InsertAccessLog(ctx, entry); err != nil {
+		return errors.New(ErrRegistrySinkFailed, "failed to write audit entry to registry", err)
+	}
+	return nil
+}
+
+// Prune deletes the access_log rows recorded before cutoff
+func (s *RegistrySink) Prune(ctx context.Context, cutoff time.Time) error {
+	if _, err := s.store.PruneAccessLog(ctx, cutoff); err != nil {
+		return errors.New(ErrRegistrySinkFailed, "failed to prune registry audit entries", err)
+	}
+	return nil
+}
+
+// Close is a no-op; the registry is owned by the metadata manager
+func (s *RegistrySink) Close() error {
+	return nil
+}
+
+// fileRecord is the JSON line written for each entry by the file sink
+type fileRecord struct {
+	Time          time.Time `json:"time"`
+	Username      string    `json:"username"`
+	Protocol      string    `json:"protocol"`
+	Action        string    `json:"action"`
+	Resource      string    `json:"resource"`
+	ClientAddress string    `json:"client_address,omitempty"`
+	UserAgent     string    `json:"user_agent,omitempty"`
+	Status        int       `json:"status"`
+	Error         string    `json:"error,omitempty"`
+	DurationMs    int64     `json:"duration_ms"`
+}
+
+// FileSink appends entries as JSON lines to a file. The file is rotated daily to
+// path.YYYY-MM-DD, and pruning removes rotated files older than the cutoff.
+type FileSink struct {
+	path string
+	mu   sync.Mutex
+	file *os.File
+	day  string // day of the entries in the open file
+}
+
+// NewFileSink opens or creates the audit file at path, rotating it first if it holds
+// entries of an earlier day
+func NewFileSink(path string) (*FileSink, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, errors.New(ErrFileSinkOpenFailed, "failed to create audit log directory", err).AddContext("path", path)
+	}
+
+	sink := &FileSink{path: path}
+	if info, err := os.Stat(path); err == nil && info.Size() > 0 {
+		sink.day = info.ModTime().Format(rotatedDateLayout)
+	}
+	if err := sink.open(); err != nil {
+		return nil, err
+	}
+	return sink, nil
+}
+
+// Write appends entry to the file, rotating it when the day changes
+func (s *FileSink) Write(ctx context.Context, entry *regtypes.AccessLog) error {
+	line, err := json.Marshal(fileRecord{
+		Time:          entry.CreatedAt,
+		Username:      entry.Username,
+		Protocol:      entry.Protocol,
+		Action:        entry.Action,
+		Resource:      entry.Resource,
+		ClientAddress: entry.IPAddress,
+		UserAgent:     entry.UserAgent,
+		Status:        entry.Status,
+		Error:         entry.Error,
+		DurationMs:    entry.Duration,
+	})
+	if err != nil {
+		return errors.New(ErrFileSinkWriteFailed, "failed to encode audit entry", err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	day := entry.CreatedAt.Format(rotatedDateLayout)
+	if s.day != "" && s.day != day {
+		if err := s.rotate(); err != nil {
+			return err
+		}
+	}
+	s.day = day
+
+	if _, err := s.file.Write(append(line, '\n')); err != nil {
+		return errors.New(ErrFileSinkWriteFailed, "failed to write audit entry", err).AddContext("path", s.path)
+	}
+	return nil
+}
+
+// Prune removes the rotated files of days before cutoff
+func (s *FileSink) Prune(ctx context.Context, cutoff time.Time) error {
+	rotated, err := filepath.Glob(s.path + ".*")
+	if err != nil {
+		return errors.New(ErrFileSinkPruneFailed, "failed to list rotated audit files", err).AddContext("path", s.path)
+	}
+
+	cutoffDay := cutoff.Format(rotatedDateLayout)
+	for _, file := range rotated {
+		day := strings.TrimPrefix(file, s.path+".")
+		if _, err := time.Parse(rotatedDateLayout, day); err != nil || day >= cutoffDay {
+			continue
+		}
+		if err := os.Remove(file); err != nil && !os.IsNotExist(err) {
+			return errors.New(ErrFileSinkPruneFailed, "failed to remove rotated audit file", err).AddContext("path", file)
+		}
+	}
+	return nil
+}
+
+// Close closes the audit file
+func (s *FileSink) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.file == nil {
+		return nil
+	}
+	err := s.file.Close()
+	s.file = nil
+	return err
+}
+
+// open opens the audit file for appending, rotating it first when it belongs to an earlier day
+func (s *FileSink) open() error {
+	if s.day != "" && s.day != time.Now().Format(rotatedDateLayout) {
+		if err := s.rotateFile(); err != nil {
+			return err
+		}
+		s.day = ""
+	}
+
+	file, err := os.OpenFile(s.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0640)
+	if err != nil {
+		return errors.New(ErrFileSinkOpenFailed, "failed to open audit log file", err).AddContext("path", s.path)
+	}
+	s.file = file
+	return nil
+}
+
+// rotate closes the open file, renames it after its day and opens a new one. The file is
+// reopened even when it could not be renamed, so that the sink stays usable and retries the
+// rotation with the next entry.
+func (s *FileSink) rotate() error {
+	var err error
+	if s.file != nil {
+		if closeErr := s.file.Close(); closeErr != nil {
+			err = errors.New(ErrFileSinkRotateFailed, "failed to close audit log file", closeErr).AddContext("path", s.path)
+		}
+		s.file = nil
+	}
+	if err == nil {
+		err = s.rotateFile()
+	}
+
+	file, openErr := os.OpenFile(s.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0640)
+	if openErr != nil {
+		return errors.New(ErrFileSinkOpenFailed, "failed to open audit log file", openErr).AddContext("path", s.path)
+	}
+	s.file = file
+	return err
+}
+
+// rotateFile renames the audit file to path.<day>, appending to an existing file of that day
+func (s *FileSink) rotateFile() error {
+	// Nothing is left to rotate when only reopening failed the last time
+	if _, err := os.Stat(s.path); os.IsNotExist(err) {
+		return nil
+	}
+
+	target := s.path + "." + s.day
+	if _, err := os.Stat(target); os.IsNotExist(err) {
+		if err := os.Rename(s.path, target); err != nil {
+			return errors.New(ErrFileSinkRotateFailed, "failed to rotate audit log file", err).AddContext("path", s.path)
+		}
+		return nil
+	}
+
+	content, err := os.ReadFile(s.path)
+	if err != nil {
+		return errors.New(ErrFileSinkRotateFailed, "failed to read audit log file", err).AddContext("path", s.path)
+	}
+	rotated, err := os.OpenFile(target, os.O_WRONLY|os.O_APPEND, 0640)
+	if err != nil {
+		return errors.New(ErrFileSinkRotateFailed, "failed to open rotated audit log file", err).AddContext("path", target)
+	}
+	defer rotated.Close()
+	if _, err := rotated.Write(content); err != nil {
+		return errors.New(ErrFileSinkRotateFailed, "failed to append to rotated audit log file", err).AddContext("path", target)
+	}
+	if err := os.Remove(s.path); err != nil {
+		return errors.New(ErrFileSinkRotateFailed, "failed to remove rotated audit log file", err).AddContext("path", s.path)
+	}
+	return nil
+}
+
+// multiSink writes every entry to several sinks
+type multiSink []Sink
+
+// Write writes entry to every sink, returning the first error
+func (m multiSink) Write(ctx context.Context, entry *regtypes.AccessLog) error {
+	var first error
+	for _, sink := range m {
+		if err := sink.Write(ctx, entry); err != nil && first == nil {
+			first = err
+		}
+	}
+	return first
+}
+
+// Prune prunes every sink, returning the first error
+func (m multiSink) Prune(ctx context.Context, cutoff time.Time) error {
+	var first error
+	for _, sink := range m {
+		if err := sink.Prune(ctx, cutoff); err != nil && first == nil {
+			first = err
+		}
+	}
+	return first
+}
+
+// Close closes every sink, returning the first error
+func (m multiSink) Close() error {
+	var first error
+	for _, sink := range m {
+		if err := sink.Close(); err != nil && first == nil {
+			first = err
+		}
+	}
+	return first
+}
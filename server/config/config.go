@@ -3,6 +3,7 @@ package config
 import (
 	"fmt"
 	"os"
+	"strings"
 
 	"github.com/gear6io/ranger/pkg/errors"
 	"gopkg.in/yaml.v3"
@@ -12,6 +13,12 @@ import (
 type Config struct {
 	Log     LogConfig     `yaml:"log"`
 	Storage StorageConfig `yaml:"storage"`
+	TLS     TLSConfig     `yaml:"tls"`
+	Audit   AuditConfig   `yaml:"audit"`
+	Auth    AuthConfig    `yaml:"auth"`
+	HTTP    HTTPConfig    `yaml:"http"`
+	Tracing TracingConfig `yaml:"tracing"`
+	Health  HealthConfig  `yaml:"health"`
 }
 
 // LogConfig represents logging configuration
@@ -26,11 +33,110 @@ type LogConfig struct {
 	Cleanup    bool   `yaml:"cleanup"`     // Whether to cleanup log file on startup
 }
 
+// TLSConfig represents TLS configuration for the network listeners
+type TLSConfig struct {
+	Enabled      bool     `yaml:"enabled"`
+	CertFile     string   `yaml:"cert_file"`
+	KeyFile      string   `yaml:"key_file"`
+	ClientCAFile string   `yaml:"client_ca_file"` // CA bundle used to verify client certificates
+	ClientAuth   string   `yaml:"client_auth"`    // "none", "optional" or "required" (mutual TLS)
+	MinVersion   string   `yaml:"min_version"`    // "1.2" or "1.3"
+	Listeners    []string `yaml:"listeners"`      // "http", "jdbc", "native"; all when empty
+	RequireJDBC  bool     `yaml:"require_jdbc"`   // Reject pgwire clients that do not request SSL
+}
+
+// AuditConfig represents audit logging configuration
+type AuditConfig struct {
+	Enabled       bool   `yaml:"enabled"`
+	Sink          string `yaml:"sink"`           // "registry", "file" or "both"
+	FilePath      string `yaml:"file_path"`      // JSON-lines file written by the file sink
+	RetentionDays int    `yaml:"retention_days"` // Entries older than this are removed; 0 keeps them forever
+	Selects       string `yaml:"selects"`        // SELECTs to record: "none", "privileged" or "all"
+}
+
+// AuthConfig represents the user store and password policy of the native and pgwire listeners
+type AuthConfig struct {
+	Provider           string    `yaml:"provider"`              // "registry" or "simple" (in-memory development accounts)
+	PasswordHash       string    `yaml:"password_hash"`         // "argon2id" or "bcrypt"
+	MaxFailedAttempts  int       `yaml:"max_failed_attempts"`   // Failed logins before lockout; 0 disables lockout
+	LockoutMinutes     int       `yaml:"lockout_minutes"`       // How long a locked account stays locked
+	PasswordMaxAgeDays int       `yaml:"password_max_age_days"` // Days before a password expires; 0 never expires
+	TokenTTLMinutes    int       `yaml:"token_ttl_minutes"`     // Lifetime of session tokens
+	AllowEmptyPassword bool      `yaml:"allow_empty_password"`  // Admit non-superusers without a password set
+	JWT                JWTConfig `yaml:"jwt"`
+}
+
+// JWTConfig represents bearer token authentication with JWTs signed by an OIDC identity provider
+type JWTConfig struct {
+	Enabled            bool              `yaml:"enabled"`
+	JWKSFile           string            `yaml:"jwks_file"`            // JSON Web Key Set read from disk
+	JWKSURL            string            `yaml:"jwks_url"`             // JSON Web Key Set fetched from the identity provider
+	JWKSRefreshMinutes int               `yaml:"jwks_refresh_minutes"` // How often the key set is reloaded
+	Issuer             string            `yaml:"issuer"`               // Required iss claim; any issuer when empty
+	Audience           string            `yaml:"audience"`             // Required aud claim; any audience when empty
+	UsernameClaim      string            `yaml:"username_claim"`       // Claim holding the Ranger user name
+	RolesClaim         string            `yaml:"roles_claim"`          // Claim listing roles, dotted for nested claims; roles are not synced when empty
+	RoleMapping        map[string]string `yaml:"role_mapping"`         // Identity provider role to Ranger role; claim values are used as is when empty
+	CreateUsers        bool              `yaml:"create_users"`         // Create users on their first login
+	ClockSkewSeconds   int               `yaml:"clock_skew_seconds"`   // Leeway when checking exp, nbf and iat
+}
+
+// HTTPConfig represents the asynchronous query API of the HTTP listener
+type HTTPConfig struct {
+	SpoolDir         string `yaml:"spool_dir"`          // Where results of asynchronous queries are spooled; a temporary directory when empty
+	ResultTTLMinutes int    `yaml:"result_ttl_minutes"` // How long spooled results are kept after the query ends; 60 when 0
+	MaxSpoolMB       int    `yaml:"max_spool_mb"`       // Largest result spooled per query, which is truncated beyond it; 0 is unlimited
+	MaxInsertMB      int    `yaml:"max_insert_mb"`      // Largest body accepted by the insert endpoint; 0 is unlimited
+}
+
+// TracingConfig represents the export of OpenTelemetry spans
+type TracingConfig struct {
+	Enabled     bool    `yaml:"enabled"`
+	Exporter    string  `yaml:"exporter"`     // "otlp" (OTLP over HTTP) or "stdout"
+	Endpoint    string  `yaml:"endpoint"`     // host:port of the OTLP collector
+	Insecure    bool    `yaml:"insecure"`     // Send OTLP over plain HTTP
+	ServiceName string  `yaml:"service_name"` // service.name of the exported spans
+	SampleRatio float64 `yaml:"sample_ratio"` // Fraction of new traces sampled; traces started by clients follow their sampling decision
+}
+
+// HealthConfig represents the liveness and readiness checks of the server
+type HealthConfig struct {
+	CheckTimeoutSeconds int `yaml:"check_timeout_seconds"` // Longest a component check may take before it counts as failed; 5 when 0
+	MaxIcebergBacklog   int `yaml:"max_iceberg_backlog"`   // Files waiting for Iceberg metadata beyond which the server is not ready; 0 disables the limit
+}
+
 // StorageConfig represents storage configuration
 type StorageConfig struct {
 	DataPath string              `yaml:"data_path"`
 	Catalog  CatalogConfig       `yaml:"catalog"`
 	Schema   SchemaManagerConfig `yaml:"schema"`
+	S3       S3Config            `yaml:"s3"`     // Bucket and transfers of the S3 engine; the bundled MinIO is used when no endpoint is set
+	Memory   MemoryConfig        `yaml:"memory"` // Hot tier of the MEMORY engine
+}
+
+// MemoryConfig bounds the memory that MEMORY tables hold before their oldest batches spill to
+// Parquet files of the spill engine
+type MemoryConfig struct {
+	HotTierMB   int    `yaml:"hot_tier_mb"`  // Memory of all MEMORY tables together; unbounded when 0
+	SpillEngine string `yaml:"spill_engine"` // FILESYSTEM or S3; FILESYSTEM when empty
+}
+
+// S3Config represents a bucket on an S3-compatible endpoint such as AWS S3, MinIO, Ceph or the
+// S3 interoperability API of Google Cloud Storage
+type S3Config struct {
+	Endpoint        string `yaml:"endpoint"`          // host[:port] of the endpoint
+	Region          string `yaml:"region"`            // Region of the bucket; detected by the endpoint when empty
+	Bucket          string `yaml:"bucket"`            // Existing bucket that holds the tables
+	Prefix          string `yaml:"prefix"`            // Key prefix under which tables are kept
+	AccessKeyID     string `yaml:"access_key_id"`     // Credentials come from the AWS environment when empty
+	SecretAccessKey string `yaml:"secret_access_key"` // Secret of access_key_id
+	SessionToken    string `yaml:"session_token"`     // Session token of temporary credentials
+	UsePathStyle    bool   `yaml:"use_path_style"`    // Address the bucket as endpoint/bucket, as MinIO and Ceph expect
+	Insecure        bool   `yaml:"insecure"`          // Use plain HTTP instead of HTTPS
+
+	PartSizeMB        int `yaml:"part_size_mb"`       // Size of multipart upload parts; 8 when 0, at least 5
+	UploadConcurrency int `yaml:"upload_concurrency"` // Parts of one object uploaded at once; 4 when 0
+	CacheSizeMB       int `yaml:"cache_size_mb"`      // Memory for cached footers and pages of read objects; 16 when 0, negative disables
 }
 
 // CatalogConfig represents catalog configuration
@@ -67,6 +173,51 @@ func LoadDefaultConfig() *Config {
 			MaxAge:     7,    // 7 days
 			Cleanup:    true, // Cleanup log file on startup by default
 		},
+		TLS: TLSConfig{
+			Enabled:    false,
+			ClientAuth: TLSClientAuthNone,
+			MinVersion: "1.2",
+		},
+		Audit: AuditConfig{
+			Enabled:       true,
+			Sink:          AuditSinkRegistry,
+			FilePath:      "logs/audit.jsonl",
+			RetentionDays: 90,
+			Selects:       AuditSelectsPrivileged,
+		},
+		Auth: AuthConfig{
+			Provider:           AuthProviderRegistry,
+			PasswordHash:       PasswordHashArgon2id,
+			MaxFailedAttempts:  5,
+			LockoutMinutes:     15,
+			PasswordMaxAgeDays: 0,
+			TokenTTLMinutes:    60,
+			AllowEmptyPassword: false,
+			JWT: JWTConfig{
+				Enabled:            false,
+				JWKSRefreshMinutes: 60,
+				UsernameClaim:      "sub",
+				CreateUsers:        true,
+				ClockSkewSeconds:   60,
+			},
+		},
+		HTTP: HTTPConfig{
+			ResultTTLMinutes: 60,
+			MaxSpoolMB:       1024,
+			MaxInsertMB:      1024,
+		},
+		Tracing: TracingConfig{
+			Enabled:     false,
+			Exporter:    TracingExporterOTLP,
+			Endpoint:    "localhost:4318",
+			Insecure:    true,
+			ServiceName: "ranger-server",
+			SampleRatio: 1,
+		},
+		Health: HealthConfig{
+			CheckTimeoutSeconds: 5,
+			MaxIcebergBacklog:   10000,
+		},
 		Storage: StorageConfig{
 			DataPath: "./data", // Default data path
 			Catalog: CatalogConfig{
@@ -129,6 +280,36 @@ func (c *Config) Validate() error {
 		return errors.New(ErrStorageValidationFailed, "storage validation failed", err)
 	}
 
+	// Validate TLS configuration
+	if err := c.TLS.Validate(); err != nil {
+		return errors.New(ErrTLSValidationFailed, "TLS validation failed", err)
+	}
+
+	// Validate audit configuration
+	if err := c.Audit.Validate(); err != nil {
+		return errors.New(ErrAuditValidationFailed, "audit validation failed", err)
+	}
+
+	// Validate authentication configuration
+	if err := c.Auth.Validate(); err != nil {
+		return errors.New(ErrAuthValidationFailed, "auth validation failed", err)
+	}
+
+	// Validate HTTP configuration
+	if err := c.HTTP.Validate(); err != nil {
+		return errors.New(ErrHTTPValidationFailed, "HTTP validation failed", err)
+	}
+
+	// Validate tracing configuration
+	if err := c.Tracing.Validate(); err != nil {
+		return errors.New(ErrTracingValidationFailed, "tracing validation failed", err)
+	}
+
+	// Validate health check configuration
+	if err := c.Health.Validate(); err != nil {
+		return errors.New(ErrHealthValidationFailed, "health validation failed", err)
+	}
+
 	// Port validation is no longer needed since ports are fixed
 	// Address validation could be added here if needed
 	return nil
@@ -146,6 +327,40 @@ func (s *StorageConfig) Validate() error {
 		return errors.New(ErrDataPathRequired, "data_path is required in storage configuration", nil)
 	}
 
+	// Validate S3 configuration
+	if err := s.S3.Validate(); err != nil {
+		return errors.New(ErrS3ValidationFailed, "S3 validation failed", err)
+	}
+
+	if s.Memory.HotTierMB < 0 {
+		return errors.New(ErrMemoryInvalidOption, "memory hot_tier_mb cannot be negative", nil).AddContext("hot_tier_mb", s.Memory.HotTierMB)
+	}
+	switch strings.ToUpper(s.Memory.SpillEngine) {
+	case "", SpillEngineFilesystem, SpillEngineS3:
+	default:
+		return errors.New(ErrMemoryInvalidOption, "memory spill_engine must be FILESYSTEM or S3", nil).AddContext("spill_engine", s.Memory.SpillEngine)
+	}
+
+	return nil
+}
+
+// Validate validates the S3 configuration
+func (s *S3Config) Validate() error {
+	if s.PartSizeMB != 0 && s.PartSizeMB < 5 {
+		return errors.New(ErrS3InvalidOption, "part_size_mb must be at least 5", nil).AddContext("part_size_mb", s.PartSizeMB)
+	}
+	if s.UploadConcurrency < 0 {
+		return errors.New(ErrS3InvalidOption, "upload_concurrency cannot be negative", nil)
+	}
+	if s.Endpoint == "" {
+		return nil
+	}
+	if s.Bucket == "" {
+		return errors.New(ErrS3InvalidOption, "bucket is required when an S3 endpoint is set", nil).AddContext("endpoint", s.Endpoint)
+	}
+	if (s.AccessKeyID == "") != (s.SecretAccessKey == "") {
+		return errors.New(ErrS3InvalidOption, "access_key_id and secret_access_key must be set together", nil)
+	}
 	return nil
 }
 
@@ -158,6 +373,163 @@ func (c *CatalogConfig) Validate() error {
 	return nil
 }
 
+// Validate validates the TLS configuration
+func (t *TLSConfig) Validate() error {
+	if !t.Enabled {
+		return nil
+	}
+
+	if t.CertFile == "" || t.KeyFile == "" {
+		return errors.New(ErrTLSCertificateRequired, "cert_file and key_file are required when TLS is enabled", nil)
+	}
+
+	switch t.ClientAuth {
+	case "", TLSClientAuthNone:
+	case TLSClientAuthOptional, TLSClientAuthRequired:
+		if t.ClientCAFile == "" {
+			return errors.New(ErrTLSClientCARequired, "client_ca_file is required for client certificate verification", nil).
+				AddContext("client_auth", t.ClientAuth)
+		}
+	default:
+		return errors.New(ErrTLSInvalidOption, "invalid client_auth", nil).AddContext("client_auth", t.ClientAuth)
+	}
+
+	switch t.MinVersion {
+	case "", "1.2", "1.3":
+	default:
+		return errors.New(ErrTLSInvalidOption, "invalid min_version, must be 1.2 or 1.3", nil).AddContext("min_version", t.MinVersion)
+	}
+
+	for _, listener := range t.Listeners {
+		switch listener {
+		case TLSListenerHTTP, TLSListenerJDBC, TLSListenerNative:
+		default:
+			return errors.New(ErrTLSInvalidOption, "invalid TLS listener", nil).AddContext("listener", listener)
+		}
+	}
+
+	return nil
+}
+
+// Validate validates the audit configuration
+func (a *AuditConfig) Validate() error {
+	if !a.Enabled {
+		return nil
+	}
+
+	switch a.Sink {
+	case "", AuditSinkRegistry:
+	case AuditSinkFile, AuditSinkBoth:
+		if a.FilePath == "" {
+			return errors.New(ErrAuditInvalidOption, "file_path is required for the file audit sink", nil).AddContext("sink", a.Sink)
+		}
+	default:
+		return errors.New(ErrAuditInvalidOption, "invalid audit sink, must be registry, file or both", nil).AddContext("sink", a.Sink)
+	}
+
+	switch a.Selects {
+	case "", AuditSelectsNone, AuditSelectsPrivileged, AuditSelectsAll:
+	default:
+		return errors.New(ErrAuditInvalidOption, "invalid audit selects, must be none, privileged or all", nil).AddContext("selects", a.Selects)
+	}
+
+	if a.RetentionDays < 0 {
+		return errors.New(ErrAuditInvalidOption, "retention_days cannot be negative", nil).AddContext("retention_days", a.RetentionDays)
+	}
+
+	return nil
+}
+
+// Validate validates the authentication configuration
+func (a *AuthConfig) Validate() error {
+	switch a.Provider {
+	case "", AuthProviderRegistry, AuthProviderSimple:
+	default:
+		return errors.New(ErrAuthInvalidOption, "invalid auth provider, must be registry or simple", nil).AddContext("provider", a.Provider)
+	}
+
+	switch a.PasswordHash {
+	case "", PasswordHashArgon2id, PasswordHashBcrypt:
+	default:
+		return errors.New(ErrAuthInvalidOption, "invalid password_hash, must be argon2id or bcrypt", nil).AddContext("password_hash", a.PasswordHash)
+	}
+
+	for option, value := range map[string]int{
+		"max_failed_attempts":   a.MaxFailedAttempts,
+		"lockout_minutes":       a.LockoutMinutes,
+		"password_max_age_days": a.PasswordMaxAgeDays,
+		"token_ttl_minutes":     a.TokenTTLMinutes,
+	} {
+		if value < 0 {
+			return errors.New(ErrAuthInvalidOption, option+" cannot be negative", nil).AddContext(option, value)
+		}
+	}
+
+	if a.JWT.Enabled {
+		if a.Provider == AuthProviderSimple {
+			return errors.New(ErrAuthInvalidOption, "jwt requires the registry auth provider", nil)
+		}
+		if err := a.JWT.Validate(); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Validate validates the JWT configuration
+func (j *JWTConfig) Validate() error {
+	if (j.JWKSFile == "") == (j.JWKSURL == "") {
+		return errors.New(ErrAuthInvalidOption, "jwt requires exactly one of jwks_file and jwks_url", nil)
+	}
+	if j.UsernameClaim == "" {
+		return errors.New(ErrAuthInvalidOption, "jwt username_claim cannot be empty", nil)
+	}
+	if j.JWKSRefreshMinutes < 0 || j.ClockSkewSeconds < 0 {
+		return errors.New(ErrAuthInvalidOption, "jwt jwks_refresh_minutes and clock_skew_seconds cannot be negative", nil)
+	}
+	return nil
+}
+
+// Validate validates the HTTP configuration
+func (h *HTTPConfig) Validate() error {
+	if h.ResultTTLMinutes < 0 || h.MaxSpoolMB < 0 || h.MaxInsertMB < 0 {
+		return errors.New(ErrHTTPInvalidOption, "result_ttl_minutes, max_spool_mb and max_insert_mb cannot be negative", nil)
+	}
+	return nil
+}
+
+// Validate validates the tracing configuration
+func (t *TracingConfig) Validate() error {
+	if !t.Enabled {
+		return nil
+	}
+
+	switch t.Exporter {
+	case "", TracingExporterOTLP:
+		if t.Endpoint == "" {
+			return errors.New(ErrTracingInvalidOption, "endpoint is required for the otlp exporter", nil)
+		}
+	case TracingExporterStdout:
+	default:
+		return errors.New(ErrTracingInvalidOption, "invalid tracing exporter, must be otlp or stdout", nil).AddContext("exporter", t.Exporter)
+	}
+
+	if t.SampleRatio < 0 || t.SampleRatio > 1 {
+		return errors.New(ErrTracingInvalidOption, "sample_ratio must be between 0 and 1", nil).AddContext("sample_ratio", t.SampleRatio)
+	}
+
+	return nil
+}
+
+// Validate validates the health check configuration
+func (h *HealthConfig) Validate() error {
+	if h.CheckTimeoutSeconds < 0 || h.MaxIcebergBacklog < 0 {
+		return errors.New(ErrHealthInvalidOption, "check_timeout_seconds and max_iceberg_backlog cannot be negative", nil)
+	}
+	return nil
+}
+
 // Validate validates the data storage configuration
 func (d *DataConfig) Validate() error {
 	// Storage type is now specified per-table, not globally
@@ -220,6 +592,22 @@ func (c *Config) IsNativeServerEnabled() bool {
 	return NATIVE_SERVER_ENABLED
 }
 
+// IsTLSEnabled returns whether TLS is enabled for the given listener
+func (c *Config) IsTLSEnabled(listener string) bool {
+	if !c.TLS.Enabled {
+		return false
+	}
+	if len(c.TLS.Listeners) == 0 {
+		return true
+	}
+	for _, enabled := range c.TLS.Listeners {
+		if enabled == listener {
+			return true
+		}
+	}
+	return false
+}
+
 // GetStorageType returns the storage type
 // Note: This method is deprecated. Storage engine is now specified per-table.
 func (c *Config) GetStorageType() string {
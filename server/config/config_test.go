@@ -40,3 +40,202 @@ func TestConfigValidation(t *testing.T) {
 		t.Error("Config with empty data_path should fail validation")
 	}
 }
+
+func TestTLSConfigValidation(t *testing.T) {
+	cfg := LoadDefaultConfig()
+	cfg.TLS.Enabled = true
+
+	// Enabling TLS without a certificate fails validation
+	if err := cfg.Validate(); err == nil {
+		t.Error("TLS config without cert_file should fail validation")
+	}
+
+	cfg.TLS.CertFile = "server.crt"
+	cfg.TLS.KeyFile = "server.key"
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("TLS config with certificate should validate, got error: %v", err)
+	}
+
+	// Mutual TLS needs a CA bundle to verify clients against
+	cfg.TLS.ClientAuth = TLSClientAuthRequired
+	if err := cfg.Validate(); err == nil {
+		t.Error("TLS config with client_auth required and no client_ca_file should fail validation")
+	}
+
+	cfg.TLS.ClientAuth = "sometimes"
+	if err := cfg.Validate(); err == nil {
+		t.Error("TLS config with invalid client_auth should fail validation")
+	}
+}
+
+func TestAuditConfigValidation(t *testing.T) {
+	cfg := LoadDefaultConfig()
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("Default audit config should validate, got error: %v", err)
+	}
+
+	// The file sink needs somewhere to write
+	cfg.Audit.Sink = AuditSinkBoth
+	cfg.Audit.FilePath = ""
+	if err := cfg.Validate(); err == nil {
+		t.Error("Audit config with file sink and no file_path should fail validation")
+	}
+
+	cfg.Audit.Sink = "syslog"
+	if err := cfg.Validate(); err == nil {
+		t.Error("Audit config with invalid sink should fail validation")
+	}
+
+	cfg.Audit.Sink = AuditSinkRegistry
+	cfg.Audit.Selects = "some"
+	if err := cfg.Validate(); err == nil {
+		t.Error("Audit config with invalid selects should fail validation")
+	}
+}
+
+func TestAuthConfigValidation(t *testing.T) {
+	cfg := LoadDefaultConfig()
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("Default auth config should validate, got error: %v", err)
+	}
+
+	cfg.Auth.PasswordHash = "md5"
+	if err := cfg.Validate(); err == nil {
+		t.Error("Auth config with invalid password_hash should fail validation")
+	}
+
+	cfg.Auth.PasswordHash = PasswordHashBcrypt
+	cfg.Auth.Provider = "ldap"
+	if err := cfg.Validate(); err == nil {
+		t.Error("Auth config with invalid provider should fail validation")
+	}
+
+	cfg.Auth.Provider = AuthProviderRegistry
+	cfg.Auth.LockoutMinutes = -1
+	if err := cfg.Validate(); err == nil {
+		t.Error("Auth config with negative lockout_minutes should fail validation")
+	}
+
+	cfg.Auth.LockoutMinutes = 15
+	cfg.Auth.JWT.Enabled = true
+	if err := cfg.Validate(); err == nil {
+		t.Error("JWT config without a key set should fail validation")
+	}
+
+	cfg.Auth.JWT.JWKSFile = "jwks.json"
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("JWT config with a key set file should validate, got error: %v", err)
+	}
+
+	cfg.Auth.Provider = AuthProviderSimple
+	if err := cfg.Validate(); err == nil {
+		t.Error("JWT config with the simple provider should fail validation")
+	}
+}
+
+func TestHTTPConfigValidation(t *testing.T) {
+	cfg := LoadDefaultConfig()
+	if cfg.HTTP.ResultTTLMinutes != 60 {
+		t.Errorf("Expected default result_ttl_minutes to be 60, got %d", cfg.HTTP.ResultTTLMinutes)
+	}
+
+	cfg.HTTP.MaxSpoolMB = -1
+	if err := cfg.Validate(); err == nil {
+		t.Error("HTTP config with negative max_spool_mb should fail validation")
+	}
+
+	cfg = LoadDefaultConfig()
+	cfg.HTTP.MaxInsertMB = -1
+	if err := cfg.Validate(); err == nil {
+		t.Error("HTTP config with negative max_insert_mb should fail validation")
+	}
+}
+
+func TestTracingConfigValidation(t *testing.T) {
+	cfg := LoadDefaultConfig()
+	cfg.Tracing.Enabled = true
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("Default tracing config should be valid, got %v", err)
+	}
+
+	cfg.Tracing.Exporter = "jaeger"
+	if err := cfg.Validate(); err == nil {
+		t.Error("Tracing config with an unknown exporter should fail validation")
+	}
+
+	cfg.Tracing.Exporter = TracingExporterStdout
+	cfg.Tracing.SampleRatio = 1.5
+	if err := cfg.Validate(); err == nil {
+		t.Error("Tracing config with sample_ratio above 1 should fail validation")
+	}
+}
+
+func TestHealthConfigValidation(t *testing.T) {
+	cfg := LoadDefaultConfig()
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("Default health config should be valid, got %v", err)
+	}
+
+	cfg.Health.MaxIcebergBacklog = -1
+	if err := cfg.Validate(); err == nil {
+		t.Error("Health config with negative max_iceberg_backlog should fail validation")
+	}
+}
+
+func TestS3ConfigValidation(t *testing.T) {
+	cfg := LoadDefaultConfig()
+	cfg.Storage.S3.Endpoint = "s3.us-east-1.amazonaws.com"
+	if err := cfg.Validate(); err == nil {
+		t.Error("S3 config with an endpoint but no bucket should fail validation")
+	}
+
+	cfg.Storage.S3.Bucket = "warehouse"
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("S3 config with environment credentials should be valid, got %v", err)
+	}
+
+	cfg.Storage.S3.AccessKeyID = "AKIAEXAMPLE"
+	if err := cfg.Validate(); err == nil {
+		t.Error("S3 config with an access key but no secret should fail validation")
+	}
+
+	cfg = LoadDefaultConfig()
+	cfg.Storage.S3.PartSizeMB = 1
+	if err := cfg.Validate(); err == nil {
+		t.Error("S3 config with parts below 5 MB should fail validation")
+	}
+
+	cfg = LoadDefaultConfig()
+	cfg.Storage.Memory.HotTierMB = -1
+	if err := cfg.Validate(); err == nil {
+		t.Error("Memory config with a negative hot tier should fail validation")
+	}
+
+	cfg = LoadDefaultConfig()
+	cfg.Storage.Memory.SpillEngine = "memory"
+	if err := cfg.Validate(); err == nil {
+		t.Error("Memory config spilling to an engine without data files should fail validation")
+	}
+	cfg.Storage.Memory.SpillEngine = "s3"
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("Memory config spilling to S3 should pass validation: %v", err)
+	}
+}
+
+func TestIsTLSEnabled(t *testing.T) {
+	cfg := LoadDefaultConfig()
+	if cfg.IsTLSEnabled(TLSListenerHTTP) {
+		t.Error("TLS should be disabled by default")
+	}
+
+	// All listeners are covered when none are listed
+	cfg.TLS.Enabled = true
+	if !cfg.IsTLSEnabled(TLSListenerNative) {
+		t.Error("TLS should cover every listener when listeners is empty")
+	}
+
+	cfg.TLS.Listeners = []string{TLSListenerJDBC}
+	if !cfg.IsTLSEnabled(TLSListenerJDBC) || cfg.IsTLSEnabled(TLSListenerHTTP) {
+		t.Error("TLS should only cover the listed listeners")
+	}
+}
@@ -42,6 +42,52 @@ const (
 	NATIVE_SERVER_ENABLED = true
 )
 
+// JDBC authentication method constants
+const (
+	// Password authentication requested from PostgreSQL wire protocol clients:
+	// "scram-sha-256", "md5", "password" (cleartext) or "trust"
+	JDBC_AUTH_METHOD = "scram-sha-256"
+)
+
+// Audit sink constants used in AuditConfig.Sink
+const (
+	AuditSinkRegistry = "registry"
+	AuditSinkFile     = "file"
+	AuditSinkBoth     = "both"
+)
+
+// Audit SELECT recording modes used in AuditConfig.Selects: privileged records
+// SELECTs run by superusers and SELECTs that were denied
+const (
+	AuditSelectsNone       = "none"
+	AuditSelectsPrivileged = "privileged"
+	AuditSelectsAll        = "all"
+)
+
+// Auth provider constants used in AuthConfig.Provider
+const (
+	AuthProviderRegistry = "registry"
+	AuthProviderSimple   = "simple"
+)
+
+// Password hashing algorithms used in AuthConfig.PasswordHash
+const (
+	PasswordHashArgon2id = "argon2id"
+	PasswordHashBcrypt   = "bcrypt"
+)
+
+// Engines MEMORY tables spill to, used in MemoryConfig.SpillEngine
+const (
+	SpillEngineFilesystem = "FILESYSTEM"
+	SpillEngineS3         = "S3"
+)
+
+// Span exporters used in TracingConfig.Exporter
+const (
+	TracingExporterOTLP   = "otlp"
+	TracingExporterStdout = "stdout"
+)
+
 // Port validation constants
 const (
 	MIN_PORT = 1
@@ -15,6 +15,41 @@ var (
 	ErrDataPathRequired            = errors.MustNewCode("config.data_path_required")
 	ErrCatalogTypeRequired         = errors.MustNewCode("config.catalog_type_required")
 
+	// TLS-specific error codes
+	ErrTLSValidationFailed    = errors.MustNewCode("config.tls_validation_failed")
+	ErrTLSCertificateRequired = errors.MustNewCode("config.tls_certificate_required")
+	ErrTLSClientCARequired    = errors.MustNewCode("config.tls_client_ca_required")
+	ErrTLSInvalidOption       = errors.MustNewCode("config.tls_invalid_option")
+	ErrTLSCertificateLoad     = errors.MustNewCode("config.tls_certificate_load_failed")
+	ErrTLSClientCALoad        = errors.MustNewCode("config.tls_client_ca_load_failed")
+
+	// Audit-specific error codes
+	ErrAuditValidationFailed = errors.MustNewCode("config.audit_validation_failed")
+	ErrAuditInvalidOption    = errors.MustNewCode("config.audit_invalid_option")
+
+	// Auth-specific error codes
+	ErrAuthValidationFailed = errors.MustNewCode("config.auth_validation_failed")
+	ErrAuthInvalidOption    = errors.MustNewCode("config.auth_invalid_option")
+
+	// HTTP-specific error codes
+	ErrHTTPValidationFailed = errors.MustNewCode("config.http_validation_failed")
+	ErrHTTPInvalidOption    = errors.MustNewCode("config.http_invalid_option")
+
+	// Tracing-specific error codes
+	ErrTracingValidationFailed = errors.MustNewCode("config.tracing_validation_failed")
+	ErrTracingInvalidOption    = errors.MustNewCode("config.tracing_invalid_option")
+
+	// Health-specific error codes
+	ErrHealthValidationFailed = errors.MustNewCode("config.health_validation_failed")
+	ErrHealthInvalidOption    = errors.MustNewCode("config.health_invalid_option")
+
+	// S3-specific error codes
+	ErrS3ValidationFailed = errors.MustNewCode("config.s3_validation_failed")
+	ErrS3InvalidOption    = errors.MustNewCode("config.s3_invalid_option")
+
+	// Memory-engine-specific error codes
+	ErrMemoryInvalidOption = errors.MustNewCode("config.memory_invalid_option")
+
 	// Logging-specific error codes
 	ErrLogDirectoryCreationFailed = errors.MustNewCode("config.log_directory_creation_failed")
 	ErrLogFileOpenFailed          = errors.MustNewCode("config.log_file_open_failed")
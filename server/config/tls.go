@@ -0,0 +1,144 @@
+package config
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+
+	"github.com/gear6io/ranger/pkg/errors"
+	"github.com/rs/zerolog"
+)
+
+// TLS listener names used in TLSConfig.Listeners
+const (
+	TLSListenerHTTP   = "http"
+	TLSListenerJDBC   = "jdbc"
+	TLSListenerNative = "native"
+)
+
+// TLS client certificate modes used in TLSConfig.ClientAuth
+const (
+	TLSClientAuthNone     = "none"
+	TLSClientAuthOptional = "optional"
+	TLSClientAuthRequired = "required"
+)
+
+// TLSManager holds the server certificate and client CA pool shared by the listeners.
+// Reload swaps them in place, so new handshakes pick up rotated certificates without
+// restarting the listeners.
+type TLSManager struct {
+	config TLSConfig
+	logger zerolog.Logger
+
+	mu          sync.RWMutex
+	certificate *tls.Certificate
+	clientCAs   *x509.CertPool
+}
+
+// NewTLSManager creates a TLS manager and loads the configured certificates
+func NewTLSManager(cfg TLSConfig, logger zerolog.Logger) (*TLSManager, error) {
+	manager := &TLSManager{
+		config: cfg,
+		logger: logger.With().Str("component", "tls").Logger(),
+	}
+	return manager, manager.Reload()
+}
+
+// Reload reads the certificate, key and client CA bundle from disk. On failure
+// the previously loaded certificates stay in use.
+func (m *TLSManager) Reload() error {
+	certificate, err := tls.LoadX509KeyPair(m.config.CertFile, m.config.KeyFile)
+	if err != nil {
+		return errors.New(ErrTLSCertificateLoad, "failed to load certificate", err).
+			AddContext("cert_file", m.config.CertFile).
+			AddContext("key_file", m.config.KeyFile)
+	}
+
+	var clientCAs *x509.CertPool
+	if m.config.ClientCAFile != "" {
+		pem, err := os.ReadFile(m.config.ClientCAFile)
+		if err != nil {
+			return errors.New(ErrTLSClientCALoad, "failed to read client CA file", err).AddContext("client_ca_file", m.config.ClientCAFile)
+		}
+		clientCAs = x509.NewCertPool()
+		if !clientCAs.AppendCertsFromPEM(pem) {
+			return errors.New(ErrTLSClientCALoad, "no certificates found in client CA file", nil).AddContext("client_ca_file", m.config.ClientCAFile)
+		}
+	}
+
+	m.mu.Lock()
+	m.certificate = &certificate
+	m.clientCAs = clientCAs
+	m.mu.Unlock()
+
+	m.logger.Info().Str("cert_file", m.config.CertFile).Msg("TLS certificates loaded")
+	return nil
+}
+
+// Config returns the TLS configuration the manager was created with
+func (m *TLSManager) Config() TLSConfig {
+	return m.config
+}
+
+// ServerConfig returns a tls.Config for a listener. Each handshake uses the
+// certificates current at that time.
+func (m *TLSManager) ServerConfig() *tls.Config {
+	return &tls.Config{
+		MinVersion: m.minVersion(),
+		GetConfigForClient: func(*tls.ClientHelloInfo) (*tls.Config, error) {
+			m.mu.RLock()
+			defer m.mu.RUnlock()
+
+			return &tls.Config{
+				MinVersion:   m.minVersion(),
+				Certificates: []tls.Certificate{*m.certificate},
+				ClientCAs:    m.clientCAs,
+				ClientAuth:   m.clientAuth(),
+			}, nil
+		},
+	}
+}
+
+// WatchReload reloads the certificates whenever the process receives SIGHUP, until ctx is done
+func (m *TLSManager) WatchReload(ctx context.Context) {
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGHUP)
+
+	go func() {
+		defer signal.Stop(signals)
+		for {
+			select {
+			case <-signals:
+				if err := m.Reload(); err != nil {
+					m.logger.Error().Err(err).Msg("TLS certificate reload failed, keeping previous certificates")
+				}
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+}
+
+// minVersion maps the configured minimum version onto the tls constant
+func (m *TLSManager) minVersion() uint16 {
+	if m.config.MinVersion == "1.3" {
+		return tls.VersionTLS13
+	}
+	return tls.VersionTLS12
+}
+
+// clientAuth maps the configured client certificate mode onto the tls constant
+func (m *TLSManager) clientAuth() tls.ClientAuthType {
+	switch m.config.ClientAuth {
+	case TLSClientAuthOptional:
+		return tls.VerifyClientCertIfGiven
+	case TLSClientAuthRequired:
+		return tls.RequireAndVerifyClientCert
+	default:
+		return tls.NoClientCert
+	}
+}
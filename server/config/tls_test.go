@@ -0,0 +1,110 @@
+package config
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+// writeSelfSignedCertificate writes a self-signed certificate and key for commonName into dir
+func writeSelfSignedCertificate(t *testing.T, dir, commonName string) (string, string) {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: commonName},
+		DNSNames:     []string{"localhost"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	certFile := filepath.Join(dir, "server.crt")
+	keyFile := filepath.Join(dir, "server.key")
+	if err := os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return certFile, keyFile
+}
+
+// servedCommonName returns the subject of the certificate the manager presents to a new client
+func servedCommonName(t *testing.T, manager *TLSManager) string {
+	t.Helper()
+
+	serverConfig, err := manager.ServerConfig().GetConfigForClient(&tls.ClientHelloInfo{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	leaf, err := x509.ParseCertificate(serverConfig.Certificates[0].Certificate[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	return leaf.Subject.CommonName
+}
+
+func TestTLSManagerReload(t *testing.T) {
+	dir := t.TempDir()
+	certFile, keyFile := writeSelfSignedCertificate(t, dir, "first")
+
+	manager, err := NewTLSManager(TLSConfig{Enabled: true, CertFile: certFile, KeyFile: keyFile}, zerolog.Nop())
+	if err != nil {
+		t.Fatalf("NewTLSManager failed: %v", err)
+	}
+	if name := servedCommonName(t, manager); name != "first" {
+		t.Errorf("Expected certificate 'first', got '%s'", name)
+	}
+
+	// Rotated certificates are served after a reload
+	writeSelfSignedCertificate(t, dir, "second")
+	if err := manager.Reload(); err != nil {
+		t.Fatalf("Reload failed: %v", err)
+	}
+	if name := servedCommonName(t, manager); name != "second" {
+		t.Errorf("Expected certificate 'second', got '%s'", name)
+	}
+
+	// A broken certificate leaves the previous one in place
+	if err := os.WriteFile(certFile, []byte("not a certificate"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := manager.Reload(); err == nil {
+		t.Error("Reload should fail for an invalid certificate")
+	}
+	if name := servedCommonName(t, manager); name != "second" {
+		t.Errorf("Expected certificate 'second' to stay in use, got '%s'", name)
+	}
+}
+
+func TestNewTLSManagerMissingCertificate(t *testing.T) {
+	_, err := NewTLSManager(TLSConfig{Enabled: true, CertFile: "missing.crt", KeyFile: "missing.key"}, zerolog.Nop())
+	if err == nil {
+		t.Error("NewTLSManager should fail when the certificate is missing")
+	}
+}
@@ -8,6 +8,8 @@ var (
 	ErrHTTPServerCreationFailed   = errors.MustNewCode("gateway.http_server_creation_failed")
 	ErrJDBCServerCreationFailed   = errors.MustNewCode("gateway.jdbc_server_creation_failed")
 	ErrNativeServerCreationFailed = errors.MustNewCode("gateway.native_server_creation_failed")
+	ErrTLSSetupFailed             = errors.MustNewCode("gateway.tls_setup_failed")
+	ErrAuthSetupFailed            = errors.MustNewCode("gateway.auth_setup_failed")
 
 	// Gateway lifecycle errors
 	ErrGatewayAlreadyStarted   = errors.MustNewCode("gateway.already_started")
@@ -2,20 +2,34 @@ package gateway
 
 import (
 	"context"
+	"os"
 	"sync"
+	"time"
 
 	"github.com/gear6io/ranger/pkg/errors"
 	"github.com/gear6io/ranger/server/config"
 	"github.com/gear6io/ranger/server/protocols/http"
 	"github.com/gear6io/ranger/server/protocols/jdbc"
 	"github.com/gear6io/ranger/server/protocols/native"
+	"github.com/gear6io/ranger/server/protocols/native/middleware"
 	"github.com/gear6io/ranger/server/query"
+	"github.com/gear6io/ranger/server/shared"
 	"github.com/rs/zerolog"
 )
 
 // ComponentType defines the gateway component type identifier
 const ComponentType = "gateway"
 
+// AdminPasswordEnv names the environment variable that sets the password of the admin user
+// when it has none, so a fresh registry does not start with a passwordless superuser
+const AdminPasswordEnv = "RANGER_ADMIN_PASSWORD"
+
+// authProvider authenticates native clients and exposes stored credentials to pgwire clients
+type authProvider interface {
+	middleware.AuthProvider
+	middleware.CredentialStore
+}
+
 // Gateway manages the lifecycle of all protocol servers
 type Gateway struct {
 	queryEngine  *query.Engine
@@ -35,24 +49,50 @@ type Gateway struct {
 	connectionMutex   sync.RWMutex
 }
 
-// NewGateway creates a new gateway instance
-func NewGateway(ctx context.Context, queryEngine *query.Engine, logger zerolog.Logger) (*Gateway, error) {
+// NewGateway creates a new gateway instance authenticating users against users; health
+// serves the liveness and readiness endpoints of the HTTP server
+func NewGateway(ctx context.Context, cfg *config.Config, queryEngine *query.Engine, users middleware.UserStore, health shared.HealthFunc, logger zerolog.Logger) (*Gateway, error) {
 	ctx, cancel := context.WithCancel(ctx)
 
+	// All TLS listeners share one manager so a SIGHUP reload covers every port
+	var tlsManager *config.TLSManager
+	if cfg.TLS.Enabled {
+		manager, err := config.NewTLSManager(cfg.TLS, logger)
+		if err != nil {
+			cancel()
+			return nil, errors.New(ErrTLSSetupFailed, "failed to load TLS certificates", err)
+		}
+		manager.WatchReload(ctx)
+		tlsManager = manager
+	}
+	listenerTLS := func(listener string) *config.TLSManager {
+		if cfg.IsTLSEnabled(listener) {
+			return tlsManager
+		}
+		return nil
+	}
+
+	// All servers authenticate against the same user store
+	authProvider, err := newAuthProvider(ctx, cfg.Auth, users, logger)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+
 	// Create all servers with the shared QueryEngine
-	httpServer, err := http.NewServer(queryEngine, logger)
+	httpServer, err := http.NewServer(queryEngine, authProvider, health, cfg.HTTP, listenerTLS(config.TLSListenerHTTP), logger)
 	if err != nil {
 		cancel()
 		return nil, errors.New(ErrHTTPServerCreationFailed, "failed to create HTTP server", err)
 	}
 
-	jdbcServer, err := jdbc.NewServer(queryEngine, logger)
+	jdbcServer, err := jdbc.NewServer(queryEngine, authProvider, listenerTLS(config.TLSListenerJDBC), logger)
 	if err != nil {
 		cancel()
 		return nil, errors.New(ErrJDBCServerCreationFailed, "failed to create JDBC server", err)
 	}
 
-	nativeServer, err := native.NewServer(queryEngine, logger)
+	nativeServer, err := native.NewServer(queryEngine, authProvider, listenerTLS(config.TLSListenerNative), logger)
 	if err != nil {
 		cancel()
 		return nil, errors.New(ErrNativeServerCreationFailed, "failed to create native server", err)
@@ -72,6 +112,30 @@ func NewGateway(ctx context.Context, queryEngine *query.Engine, logger zerolog.L
 	return gateway, gateway.start(ctx)
 }
 
+// newAuthProvider creates the authentication provider selected in cfg
+func newAuthProvider(ctx context.Context, cfg config.AuthConfig, users middleware.UserStore, logger zerolog.Logger) (authProvider, error) {
+	if cfg.Provider == config.AuthProviderSimple {
+		logger.Warn().Msg("Using the in-memory development user store; users and passwords are not persisted")
+		return middleware.NewSimpleAuthProvider(time.Duration(cfg.TokenTTLMinutes)*time.Minute, logger), nil
+	}
+
+	var validator *middleware.JWTValidator
+	if cfg.JWT.Enabled {
+		var err error
+		if validator, err = middleware.NewJWTValidator(ctx, cfg.JWT, logger); err != nil {
+			return nil, errors.New(ErrAuthSetupFailed, "failed to set up JWT authentication", err)
+		}
+	}
+
+	provider := middleware.NewRegistryAuthProvider(users, cfg, validator, logger)
+	if password := os.Getenv(AdminPasswordEnv); password != "" {
+		if err := provider.EnsurePassword(ctx, "admin", password); err != nil {
+			return nil, errors.New(ErrAuthSetupFailed, "failed to set the admin password", err)
+		}
+	}
+	return provider, nil
+}
+
 // Start starts all enabled servers
 func (g *Gateway) start(ctx context.Context) error {
 	g.mu.Lock()
@@ -219,6 +283,15 @@ func (g *Gateway) GetType() string {
 	return ComponentType
 }
 
+// CheckHealth reports whether the protocol servers are running
+func (g *Gateway) CheckHealth(ctx context.Context) shared.Health {
+	status := g.GetStatus()
+	if started, _ := status["started"].(bool); !started {
+		return shared.Health{Live: false, Ready: false, Message: "protocol servers are not running", Details: status}
+	}
+	return shared.Health{Live: true, Ready: true, Details: status}
+}
+
 // Helper methods to check server enabled states
 func (g *Gateway) isHTTPServerEnabled() bool {
 	return config.HTTP_SERVER_ENABLED
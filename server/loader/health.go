@@ -0,0 +1,120 @@
+package loader
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/gear6io/ranger/server/shared"
+)
+
+// Overall statuses of a health report
+const (
+	HealthStatusOK        = "ok"
+	HealthStatusNotReady  = "not_ready"
+	HealthStatusUnhealthy = "unhealthy"
+)
+
+// CheckHealth runs the health checks of the initialized components concurrently, each
+// bounded by the configured timeout, and aggregates them in initialization order. The
+// server is not ready while it starts or stops, whatever its components report.
+func (l *Loader) CheckHealth(ctx context.Context) *shared.HealthReport {
+	l.mu.RLock()
+	phase, initializing := l.phase, l.initializing
+	names := make([]string, 0, len(l.initOrder))
+	components := make([]shared.Component, 0, len(l.initOrder))
+	for _, name := range l.initOrder {
+		if component, exists := l.components[name]; exists {
+			names = append(names, name)
+			components = append(components, component)
+		}
+	}
+	l.mu.RUnlock()
+
+	report := &shared.HealthReport{Live: true, Ready: true, Phase: phase, Components: []shared.ComponentHealth{}}
+
+	// Components are being torn down, so their checks may block on the shutdown itself
+	if phase == PhaseStopping {
+		report.Ready = false
+		report.Message = "server is shutting down"
+		report.Status = HealthStatusNotReady
+		return report
+	}
+
+	timeout := time.Duration(l.config.Health.CheckTimeoutSeconds) * time.Second
+	if timeout <= 0 {
+		timeout = defaultHealthCheckTimeout
+	}
+
+	results := make([]chan shared.ComponentHealth, len(components))
+	for i, component := range components {
+		results[i] = make(chan shared.ComponentHealth, 1)
+		go func(result chan<- shared.ComponentHealth, name string, component shared.Component) {
+			result <- checkComponent(ctx, name, component, timeout)
+		}(results[i], names[i], component)
+	}
+
+	for _, result := range results {
+		health := <-result
+		report.Live = report.Live && health.Live
+		report.Ready = report.Ready && health.Ready
+		report.Components = append(report.Components, health)
+	}
+
+	if phase == PhaseStarting {
+		report.Ready = false
+		report.Message = "server is starting"
+		if initializing != "" {
+			report.Message = fmt.Sprintf("server is starting, initializing %s", initializing)
+			report.Components = append(report.Components, shared.ComponentHealth{
+				Name:    initializing,
+				Live:    true,
+				Message: "initializing",
+			})
+		}
+	}
+
+	switch {
+	case !report.Live:
+		report.Status = HealthStatusUnhealthy
+	case !report.Ready:
+		report.Status = HealthStatusNotReady
+	default:
+		report.Status = HealthStatusOK
+	}
+	return report
+}
+
+// checkComponent runs the health check of one component; components without a check are
+// healthy once initialized, and a check that outlives timeout leaves the component not ready
+func checkComponent(ctx context.Context, name string, component shared.Component, timeout time.Duration) shared.ComponentHealth {
+	start := time.Now()
+	checker, ok := component.(shared.HealthChecker)
+	if !ok {
+		return shared.ComponentHealth{Name: name, Live: true, Ready: true}
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	done := make(chan shared.Health, 1)
+	go func() {
+		done <- checker.CheckHealth(ctx)
+	}()
+
+	var health shared.Health
+	select {
+	case health = <-done:
+	case <-ctx.Done():
+		health = shared.Health{Live: true, Message: fmt.Sprintf("health check did not finish within %s", timeout)}
+	}
+
+	return shared.ComponentHealth{
+		Name:      name,
+		Live:      health.Live,
+		Ready:     health.Ready,
+		Message:   health.Message,
+		Details:   health.Details,
+		LatencyMs: float64(time.Since(start).Microseconds()) / 1000,
+	}
+}
@@ -2,6 +2,8 @@ package loader
 
 import (
 	"context"
+	"sync"
+	"time"
 
 	"github.com/gear6io/ranger/pkg/errors"
 	"github.com/gear6io/ranger/server/catalog"
@@ -12,6 +14,7 @@ import (
 	"github.com/gear6io/ranger/server/query"
 	"github.com/gear6io/ranger/server/shared"
 	"github.com/gear6io/ranger/server/storage"
+	"github.com/gear6io/ranger/server/tracing"
 	"github.com/rs/zerolog"
 )
 
@@ -28,8 +31,19 @@ type LoaderInterface interface {
 	GetMetadataManager() *metadata.MetadataManager
 	GetConfig() *config.Config
 	GetLogger() zerolog.Logger
+	CheckHealth(ctx context.Context) *shared.HealthReport
 }
 
+// Lifecycle phases reported by the health endpoints
+const (
+	PhaseStarting = "starting"
+	PhaseRunning  = "running"
+	PhaseStopping = "stopping"
+)
+
+// defaultHealthCheckTimeout bounds a component health check when the config sets none
+const defaultHealthCheckTimeout = 5 * time.Second
+
 // Loader initializes and manages all core components
 type Loader struct {
 	config *config.Config
@@ -39,6 +53,11 @@ type Loader struct {
 	initFunctions []InitFunction
 	components    map[string]shared.Component
 	initOrder     []string // Store initialization order for shutdown
+
+	// mu guards components, phase and initializing, which health checks read while Start runs
+	mu           sync.RWMutex
+	phase        string
+	initializing string // Component being initialized while starting
 }
 
 // NewLoader creates a new Loader instance
@@ -49,6 +68,7 @@ func NewLoader(ctx context.Context, cfg *config.Config, logger zerolog.Logger) (
 		initFunctions: make([]InitFunction, 0),
 		components:    make(map[string]shared.Component),
 		initOrder:     make([]string, 0),
+		phase:         PhaseStarting,
 	}
 
 	// Register components in initialization order
@@ -65,7 +85,12 @@ func (l *Loader) RegisterComponent(name string, initFunc InitFunction) {
 
 // registerComponents registers all components in the correct initialization order
 func (l *Loader) registerComponents() {
-	// This order determines initialization sequence
+	// This order determines initialization sequence; tracing comes first so it is shut
+	// down last, after the spans of every other component are ended
+	l.RegisterComponent("tracing", func(ctx context.Context, loader LoaderInterface) (shared.Component, error) {
+		return tracing.NewProvider(ctx, loader.GetConfig().Tracing, loader.GetLogger())
+	})
+
 	l.RegisterComponent("paths", func(ctx context.Context, loader LoaderInterface) (shared.Component, error) {
 		return paths.NewManager(loader.GetConfig().GetStoragePath()), nil
 	})
@@ -75,10 +100,15 @@ func (l *Loader) registerComponents() {
 	})
 
 	l.RegisterComponent("metadata", func(ctx context.Context, loader LoaderInterface) (shared.Component, error) {
-		return metadata.NewMetadataManager(ctx, loader.GetCatalog(),
+		manager, err := metadata.NewMetadataManager(ctx, loader.GetCatalog(),
 			loader.GetPathManager().GetInternalMetadataDBPath(),
 			loader.GetConfig().GetStoragePath(),
 			loader.GetLogger())
+		if err != nil {
+			return nil, err
+		}
+		manager.SetMaxIcebergBacklog(loader.GetConfig().Health.MaxIcebergBacklog)
+		return manager, nil
 	})
 
 	l.RegisterComponent("storage", func(ctx context.Context, loader LoaderInterface) (shared.Component, error) {
@@ -90,7 +120,7 @@ func (l *Loader) registerComponents() {
 	})
 
 	l.RegisterComponent("gateway", func(ctx context.Context, loader LoaderInterface) (shared.Component, error) {
-		return gateway.NewGateway(ctx, loader.GetQueryEngine(), loader.GetLogger())
+		return gateway.NewGateway(ctx, loader.GetConfig(), loader.GetQueryEngine(), loader.GetMetadataManager(), loader.CheckHealth, loader.GetLogger())
 	})
 }
 
@@ -99,6 +129,10 @@ func (l *Loader) Start(ctx context.Context) error {
 	l.logger.Info().Msg("Initializing components...")
 
 	for i, initFunc := range l.initFunctions {
+		l.mu.Lock()
+		l.initializing = l.initOrder[i]
+		l.mu.Unlock()
+
 		component, err := initFunc(ctx, l)
 		if err != nil {
 			return errors.New(ErrComponentInitFailed, "failed to initialize component", err).AddContext("component_index", i)
@@ -106,10 +140,17 @@ func (l *Loader) Start(ctx context.Context) error {
 
 		// Store component by its type
 		componentType := component.GetType()
+		l.mu.Lock()
 		l.components[componentType] = component
+		l.mu.Unlock()
 		l.logger.Info().Str("type", componentType).Msg("Component initialized successfully")
 	}
 
+	l.mu.Lock()
+	l.phase = PhaseRunning
+	l.initializing = ""
+	l.mu.Unlock()
+
 	l.logger.Info().Msg("All components initialized successfully")
 	return nil
 }
@@ -118,11 +159,15 @@ func (l *Loader) Start(ctx context.Context) error {
 func (l *Loader) Shutdown(ctx context.Context) error {
 	l.logger.Info().Msg("Shutting down components...")
 
+	l.mu.Lock()
+	l.phase = PhaseStopping
+	l.mu.Unlock()
+
 	// Shutdown components in reverse order (LIFO - Last In, First Out)
 	for i := len(l.initOrder) - 1; i >= 0; i-- {
 		componentType := l.initOrder[i]
 
-		if component, exists := l.components[componentType]; exists {
+		if component, exists := l.component(componentType); exists {
 			l.logger.Info().Str("type", componentType).Msg("Shutting down component")
 			if err := component.Shutdown(ctx); err != nil {
 				// Log error but continue with other components
@@ -139,6 +184,14 @@ func (l *Loader) Shutdown(ctx context.Context) error {
 	return nil
 }
 
+// component returns the initialized component of the given type
+func (l *Loader) component(componentType string) (shared.Component, bool) {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	component, exists := l.components[componentType]
+	return component, exists
+}
+
 // GetConfig returns the configuration
 func (l *Loader) GetConfig() *config.Config {
 	return l.config
@@ -151,7 +204,7 @@ func (l *Loader) GetLogger() zerolog.Logger {
 
 // GetCatalog returns the catalog instance
 func (l *Loader) GetCatalog() catalog.CatalogInterface {
-	if comp, exists := l.components[storage.ComponentType]; exists {
+	if comp, exists := l.component(storage.ComponentType); exists {
 		// Catalog comes from storage manager
 		storageManager := comp.(*storage.Storage)
 		return storageManager.GetCatalog()
@@ -161,7 +214,7 @@ func (l *Loader) GetCatalog() catalog.CatalogInterface {
 
 // GetQueryEngine returns the QueryEngine instance
 func (l *Loader) GetQueryEngine() *query.Engine {
-	if comp, exists := l.components[query.ComponentType]; exists {
+	if comp, exists := l.component(query.ComponentType); exists {
 		return comp.(*query.Engine)
 	}
 	return nil
@@ -169,7 +222,7 @@ func (l *Loader) GetQueryEngine() *query.Engine {
 
 // GetGateway returns the Gateway instance
 func (l *Loader) GetGateway() *gateway.Gateway {
-	if comp, exists := l.components[gateway.ComponentType]; exists {
+	if comp, exists := l.component(gateway.ComponentType); exists {
 		return comp.(*gateway.Gateway)
 	}
 	return nil
@@ -177,7 +230,7 @@ func (l *Loader) GetGateway() *gateway.Gateway {
 
 // GetStorage returns the storage manager
 func (l *Loader) GetStorage() *storage.Storage {
-	if comp, exists := l.components[storage.ComponentType]; exists {
+	if comp, exists := l.component(storage.ComponentType); exists {
 		return comp.(*storage.Storage)
 	}
 	return nil
@@ -185,7 +238,7 @@ func (l *Loader) GetStorage() *storage.Storage {
 
 // GetPathManager returns the path manager from component registry
 func (l *Loader) GetPathManager() paths.PathManager {
-	if comp, exists := l.components[paths.ComponentType]; exists {
+	if comp, exists := l.component(paths.ComponentType); exists {
 		return comp.(*paths.Manager)
 	}
 	return nil
@@ -193,7 +246,7 @@ func (l *Loader) GetPathManager() paths.PathManager {
 
 // GetMetadataManager returns the metadata manager from component registry
 func (l *Loader) GetMetadataManager() *metadata.MetadataManager {
-	if comp, exists := l.components[metadata.ComponentType]; exists {
+	if comp, exists := l.component(metadata.ComponentType); exists {
 		return comp.(*metadata.MetadataManager)
 	}
 	return nil
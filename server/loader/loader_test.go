@@ -3,8 +3,10 @@ package loader
 import (
 	"context"
 	"testing"
+	"time"
 
 	"github.com/gear6io/ranger/server/config"
+	"github.com/gear6io/ranger/server/shared"
 	"github.com/rs/zerolog"
 )
 
@@ -55,3 +57,76 @@ func TestNewLoader(t *testing.T) {
 		t.Error("Storage should not be nil")
 	}
 }
+
+// healthComponent is a component reporting a fixed health
+type healthComponent struct {
+	name   string
+	health shared.Health
+	delay  time.Duration
+}
+
+func (c *healthComponent) GetType() string                    { return c.name }
+func (c *healthComponent) Shutdown(ctx context.Context) error { return nil }
+func (c *healthComponent) CheckHealth(ctx context.Context) shared.Health {
+	select {
+	case <-time.After(c.delay):
+		return c.health
+	case <-ctx.Done():
+		return shared.Health{Live: true}
+	}
+}
+
+func TestCheckHealth(t *testing.T) {
+	cfg := config.LoadDefaultConfig()
+	cfg.Health.CheckTimeoutSeconds = 1
+	loader := &Loader{
+		config:     cfg,
+		logger:     zerolog.Nop(),
+		components: make(map[string]shared.Component),
+		initOrder:  []string{"paths", "metadata", "query"},
+		phase:      PhaseStarting,
+	}
+	loader.components["paths"] = &healthComponent{name: "paths", health: shared.Health{Live: true, Ready: true}}
+	loader.initializing = "metadata"
+
+	// Not ready while a component is still initializing
+	report := loader.CheckHealth(context.Background())
+	if report.Ready || !report.Live || report.Status != HealthStatusNotReady {
+		t.Fatalf("Starting server should be live but not ready, got %+v", report)
+	}
+	if len(report.Components) != 2 || report.Components[1].Name != "metadata" {
+		t.Fatalf("Report should list the initialized and the initializing component, got %+v", report.Components)
+	}
+
+	loader.components["metadata"] = &healthComponent{name: "metadata", health: shared.Health{Live: true, Ready: false, Message: "backlog"}}
+	loader.components["query"] = &healthComponent{name: "query", health: shared.Health{Live: true, Ready: true}, delay: time.Minute}
+	loader.phase = PhaseRunning
+	loader.initializing = ""
+
+	report = loader.CheckHealth(context.Background())
+	if report.Ready || report.Status != HealthStatusNotReady {
+		t.Fatalf("A component that is not ready should make the server not ready, got %+v", report)
+	}
+	if len(report.Components) != 3 {
+		t.Fatalf("Report should list every component, got %+v", report.Components)
+	}
+	if report.Components[1].Message != "backlog" {
+		t.Errorf("Component message should be reported, got %q", report.Components[1].Message)
+	}
+	// A check that outlives the timeout leaves its component not ready
+	if report.Components[2].Ready {
+		t.Error("Component whose check timed out should not be ready")
+	}
+
+	loader.components["metadata"] = &healthComponent{name: "metadata", health: shared.Health{Live: true, Ready: true}}
+	delete(loader.components, "query")
+	report = loader.CheckHealth(context.Background())
+	if !report.Ready || report.Status != HealthStatusOK {
+		t.Errorf("Healthy running server should be ready, got %+v", report)
+	}
+
+	loader.phase = PhaseStopping
+	if report = loader.CheckHealth(context.Background()); report.Ready {
+		t.Error("Stopping server should not be ready")
+	}
+}
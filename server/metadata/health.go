@@ -0,0 +1,74 @@
+package metadata
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/gear6io/ranger/server/shared"
+)
+
+// SetMaxIcebergBacklog sets the Iceberg backlog beyond which the manager reports not ready; 0 disables the limit
+func (mm *MetadataManager) SetMaxIcebergBacklog(limit int) {
+	mm.mu.Lock()
+	defer mm.mu.Unlock()
+	mm.maxIcebergBacklog = limit
+}
+
+// CheckHealth reports the migration state of the registry, the health of the Astha
+// subscribers and the Iceberg metadata backlog
+func (mm *MetadataManager) CheckHealth(ctx context.Context) shared.Health {
+	mm.mu.RLock()
+	running := mm.running
+	maxBacklog := mm.maxIcebergBacklog
+	mm.mu.RUnlock()
+
+	if !running {
+		return shared.Health{Live: true, Ready: false, Message: "metadata manager is not running"}
+	}
+
+	health := shared.Health{Live: true, Ready: true, Details: make(map[string]interface{})}
+	var problems []string
+
+	// Migrations run while the store opens; a registry behind the latest version cannot be used
+	status, err := mm.hybrid.GetDeploymentStatus(ctx)
+	if err != nil {
+		problems = append(problems, fmt.Sprintf("failed to read migration status: %v", err))
+	} else {
+		health.Details["migration_version"] = status.CurrentVersion
+		health.Details["latest_migration_version"] = status.LatestVersion
+		health.Details["pending_migrations"] = status.PendingCount
+		if status.PendingCount > 0 || !status.SchemaValid {
+			problems = append(problems, fmt.Sprintf("%d registry migrations pending", status.PendingCount))
+		}
+	}
+
+	// Astha subscribers report their own health
+	if mm.astha != nil {
+		subscribers := make(map[string]string)
+		for name, err := range mm.astha.CheckComponentHealth(ctx) {
+			if err != nil {
+				subscribers[name] = err.Error()
+				problems = append(problems, fmt.Sprintf("subscriber %s is unhealthy: %v", name, err))
+				continue
+			}
+			subscribers[name] = "ok"
+		}
+		health.Details["subscribers"] = subscribers
+	}
+
+	// Queries read Iceberg metadata, so a large backlog means they see stale data
+	backlog := mm.icebergManager.Backlog()
+	health.Details["iceberg_backlog"] = backlog
+	if maxBacklog > 0 && backlog > maxBacklog {
+		problems = append(problems, fmt.Sprintf("iceberg backlog of %d files exceeds %d", backlog, maxBacklog))
+	}
+
+	if len(problems) > 0 {
+		sort.Strings(problems)
+		health.Ready = false
+		health.Message = strings.Join(problems, "; ")
+	}
+	return health
+}
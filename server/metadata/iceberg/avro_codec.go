@@ -164,7 +164,7 @@ func (ac *AvroCodec) ConvertTableFileToManifestEntry(tableFile *regtypes.TableFi
 			UpperBounds:     make(map[int][]byte), // TODO: Calculate actual bounds
 			KeyMetadata:     []byte{},             // TODO: Add actual key metadata
 			SplitOffsets:    []int64{},            // TODO: Add actual split offsets
-			SortOrderID:     0,                    // TODO: Get actual sort order ID
+			SortOrderID:     fileSortOrderID(tableFile),
 		},
 	}
 
@@ -161,6 +161,7 @@ func TestAvroCodec_ConvertTableFileToManifestEntry(t *testing.T) {
 		FileType:      "PARQUET",
 		RowCount:      100,
 		PartitionPath: "year=2024/month=1",
+		IsSorted:      true,
 	}
 
 	snapshotID := time.Now().UnixNano()
@@ -180,6 +181,7 @@ func TestAvroCodec_ConvertTableFileToManifestEntry(t *testing.T) {
 	assert.Equal(t, "PARQUET", dataFile.FileFormat)
 	assert.Equal(t, int64(100), dataFile.RecordCount)
 	assert.Equal(t, int64(1024), dataFile.FileSizeInBytes)
+	assert.Equal(t, TableSortOrderID, dataFile.SortOrderID)
 
 	// Verify partition values
 	expectedPartition := map[string]interface{}{
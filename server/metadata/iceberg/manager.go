@@ -11,6 +11,7 @@ import (
 	"github.com/gear6io/ranger/server/metadata/registry"
 	"github.com/gear6io/ranger/server/metadata/registry/regtypes"
 	"github.com/gear6io/ranger/server/paths"
+	"github.com/gear6io/ranger/server/tracing"
 	"github.com/rs/zerolog"
 )
 
@@ -63,7 +64,7 @@ func NewManager(pathManager paths.PathManager, logger zerolog.Logger) *Manager {
 	fileQueue := NewFileQueue()
 	metadataGenerator := NewMetadataGenerator(pathManager, logger)
 
-	return &Manager{
+	manager := &Manager{
 		pathManager:       pathManager,
 		logger:            logger,
 		workerPool:        workerPool,
@@ -71,6 +72,8 @@ func NewManager(pathManager paths.PathManager, logger zerolog.Logger) *Manager {
 		metadataGenerator: metadataGenerator,
 		stats:             &ManagerStats{},
 	}
+	manager.registerMetrics()
+	return manager
 }
 
 // Start starts the Iceberg metadata manager
@@ -180,6 +183,12 @@ func (m *Manager) GetPendingFiles() []*regtypes.TableFile {
 	return m.fileQueue.GetPendingFiles()
 }
 
+// Backlog returns the number of files waiting for or undergoing Iceberg metadata generation
+func (m *Manager) Backlog() int {
+	queueStats := m.fileQueue.GetStats()
+	return queueStats.PendingCount + queueStats.ProcessingCount
+}
+
 // GetStats returns manager statistics
 func (m *Manager) GetStats() *ManagerStats {
 	m.mu.RLock()
@@ -213,7 +222,10 @@ func (m *Manager) loadPendingFiles() error {
 }
 
 // generateManifest creates an Iceberg manifest file for a batch of files
-func (m *Manager) generateManifest(ctx context.Context, batch BatchInfo, tableInfo *registry.CompleteTableInfo) (string, error) {
+func (m *Manager) generateManifest(ctx context.Context, batch BatchInfo, tableInfo *registry.CompleteTableInfo) (_ string, err error) {
+	ctx, span := tracing.Start(ctx, "iceberg.generate_manifest")
+	defer func() { tracing.End(span, err) }()
+
 	// Validate table info before processing
 	if err := m.validateTableInfo(tableInfo); err != nil {
 		return "", errors.New(IcebergManagerOperationFailed, "invalid table info", err).AddContext("table_id", tableInfo.ID)
@@ -223,7 +235,10 @@ func (m *Manager) generateManifest(ctx context.Context, batch BatchInfo, tableIn
 }
 
 // updateMetadataFile updates the Iceberg metadata file with new snapshot
-func (m *Manager) updateMetadataFile(ctx context.Context, batch BatchInfo, manifestPath string, tableInfo *registry.CompleteTableInfo) error {
+func (m *Manager) updateMetadataFile(ctx context.Context, batch BatchInfo, manifestPath string, tableInfo *registry.CompleteTableInfo) (err error) {
+	ctx, span := tracing.Start(ctx, "iceberg.update_metadata")
+	defer func() { tracing.End(span, err) }()
+
 	// Validate table info before processing
 	if err := m.validateTableInfo(tableInfo); err != nil {
 		return errors.New(IcebergManagerOperationFailed, "invalid table info", err).AddContext("table_id", tableInfo.ID)
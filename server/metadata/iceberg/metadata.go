@@ -76,6 +76,16 @@ type Snapshot struct {
 	Manifests        []ManifestFile    `json:"manifests"`
 }
 
+// TableMetadata represents the table state written to each metadata file: the sort orders
+// that data files reference by ID, and the snapshot that added the latest files
+type TableMetadata struct {
+	FormatVersion      int         `json:"format-version"`
+	SortOrders         []SortOrder `json:"sort-orders"`
+	DefaultSortOrderID int         `json:"default-sort-order-id"`
+	CurrentSnapshotID  int64       `json:"current-snapshot-id"`
+	Snapshots          []Snapshot  `json:"snapshots"`
+}
+
 // ManifestFile represents a manifest file in the snapshot
 type ManifestFile struct {
 	ManifestPath      string `json:"manifest_path"`
@@ -121,7 +131,7 @@ func (mg *MetadataGenerator) GenerateManifest(ctx context.Context, batch BatchIn
 				UpperBounds:     make(map[int][]byte), // TODO: Calculate actual bounds
 				KeyMetadata:     []byte{},             // TODO: Add actual key metadata
 				SplitOffsets:    []int64{},            // TODO: Add actual split offsets
-				SortOrderID:     0,                    // TODO: Get actual sort order ID
+				SortOrderID:     fileSortOrderID(file),
 			},
 		}
 		entries = append(entries, entry)
@@ -153,7 +163,8 @@ func (mg *MetadataGenerator) GenerateManifest(ctx context.Context, batch BatchIn
 	return manifestPath, nil
 }
 
-// UpdateMetadataFile updates the Iceberg metadata file with new snapshot information
+// UpdateMetadataFile updates the Iceberg metadata file with new snapshot information and the
+// sort orders of the table
 func (mg *MetadataGenerator) UpdateMetadataFile(ctx context.Context, batch BatchInfo, manifestPath string, tableInfo *registry.CompleteTableInfo) error {
 	// Get metadata directory path using actual database and table names
 	metadataDir := mg.pathManager.GetTableMetadataPath([]string{tableInfo.Database}, tableInfo.Name)
@@ -162,7 +173,7 @@ func (mg *MetadataGenerator) UpdateMetadataFile(ctx context.Context, batch Batch
 	}
 
 	// Generate metadata filename
-	metadataFile := "metadata-" + strconv.FormatInt(time.Now().Unix(), 10) + ".avro"
+	metadataFile := "metadata-" + strconv.FormatInt(time.Now().Unix(), 10) + ".metadata.json"
 	metadataPath := filepath.Join(metadataDir, metadataFile)
 
 	// Create snapshot
@@ -190,15 +201,26 @@ func (mg *MetadataGenerator) UpdateMetadataFile(ctx context.Context, batch Batch
 		},
 	}
 
-	// Encode snapshot to Avro format
-	metadataBytes, err := mg.avroCodec.EncodeSnapshot(&snapshot)
+	sortOrders, defaultSortOrderID, err := tableSortOrders(ctx, tableInfo)
+	if err != nil {
+		return err
+	}
+	tableMetadata := TableMetadata{
+		FormatVersion:      2,
+		SortOrders:         sortOrders,
+		DefaultSortOrderID: defaultSortOrderID,
+		CurrentSnapshotID:  snapshot.SnapshotID,
+		Snapshots:          []Snapshot{snapshot},
+	}
+
+	// Iceberg table metadata is JSON
+	metadataBytes, err := json.MarshalIndent(tableMetadata, "", "  ")
 	if err != nil {
-		return errors.New(errors.CommonInternal, "while encoding snapshot to Avro", err)
+		return errors.New(errors.CommonInternal, "while marshaling table metadata", err)
 	}
 
-	// Write Avro file with proper header
-	if err := mg.avroCodec.WriteAvroFile(metadataPath, metadataBytes, "snapshot"); err != nil {
-		return errors.New(errors.CommonInternal, "while writing Avro metadata file", err).AddContext("path", metadataPath)
+	if err := os.WriteFile(metadataPath, metadataBytes, 0644); err != nil {
+		return errors.New(errors.CommonInternal, "while writing metadata file", err).AddContext("path", metadataPath)
 	}
 
 	mg.logger.Debug().
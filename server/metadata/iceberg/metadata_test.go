@@ -2,9 +2,11 @@ package iceberg
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 	"time"
 
@@ -151,6 +153,77 @@ func TestMetadataGenerator_UpdateMetadataFile(t *testing.T) {
 	assert.Contains(t, string(metadataBytes), "snapshot_id")
 	assert.Contains(t, string(metadataBytes), "operation")
 	assert.Contains(t, string(metadataBytes), "append")
+	assert.Contains(t, string(metadataBytes), `"default-sort-order-id": 0`)
+
+	// The metadata file is plain JSON
+	assert.True(t, strings.HasSuffix(files[0].Name(), ".metadata.json"))
+	var tableMetadata TableMetadata
+	require.NoError(t, json.Unmarshal(metadataBytes, &tableMetadata))
+	assert.Equal(t, 2, tableMetadata.FormatVersion)
+	require.Len(t, tableMetadata.Snapshots, 1)
+	assert.Equal(t, tableMetadata.CurrentSnapshotID, tableMetadata.Snapshots[0].SnapshotID)
+}
+
+func TestMetadataGenerator_SortedTableManifest(t *testing.T) {
+	pathManager := &MockPathManager{BasePath: t.TempDir()}
+	generator := NewMetadataGenerator(pathManager, zerolog.Nop())
+
+	batch := BatchInfo{
+		ID: "test-batch-1",
+		Files: []*regtypes.TableFile{
+			{ID: 1, TableID: 1, FileName: "test1.parquet", FilePath: "/data/test1.parquet", FileSize: 1024, RowCount: 100, IsSorted: true},
+			{ID: 2, TableID: 1, FileName: "test2.parquet", FilePath: "/data/test2.parquet", FileSize: 1024, RowCount: 100},
+		},
+		CreatedAt: time.Now(),
+		Status:    "pending",
+	}
+	tableInfo := &registry.CompleteTableInfo{
+		Database:    "default",
+		Table:       &regtypes.Table{ID: 1, Name: "events"},
+		StorageInfo: &regtypes.TableMetadata{SortOrder: `["ts","id"]`, SortStrategy: "asc"},
+	}
+
+	// Only the entries of files written sorted reference the sort order of the table, since
+	// files added before ORDER BY was set, or by other writers, may be in any order
+	manifestPath, err := generator.GenerateManifest(context.Background(), batch, tableInfo)
+	require.NoError(t, err)
+	manifestBytes, err := os.ReadFile(manifestPath)
+	require.NoError(t, err)
+	var manifest struct {
+		Entries []ManifestEntry `json:"entries"`
+	}
+	require.NoError(t, json.Unmarshal(manifestBytes, &manifest))
+	require.Len(t, manifest.Entries, 2)
+	assert.Equal(t, TableSortOrderID, manifest.Entries[0].DataFile.SortOrderID)
+	assert.Equal(t, UnsortedSortOrderID, manifest.Entries[1].DataFile.SortOrderID)
+}
+
+func TestNewSortOrder(t *testing.T) {
+	columns := []*regtypes.TableColumn{
+		{ID: 4, ColumnName: "id"},
+		{ID: 7, ColumnName: "ts"},
+	}
+
+	order, err := NewSortOrder([]string{"ts", "id"}, columns, "asc")
+	require.NoError(t, err)
+	assert.Equal(t, SortOrder{
+		OrderID: TableSortOrderID,
+		Fields: []SortField{
+			{Transform: "identity", SourceID: 7, Direction: "asc", NullOrder: "nulls-first"},
+			{Transform: "identity", SourceID: 4, Direction: "asc", NullOrder: "nulls-first"},
+		},
+	}, order)
+
+	// Tables without ORDER BY are unsorted
+	order, err = NewSortOrder(nil, columns, "asc")
+	require.NoError(t, err)
+	assert.Equal(t, UnsortedSortOrderID, order.OrderID)
+	assert.Empty(t, order.Fields)
+
+	_, err = NewSortOrder([]string{"missing"}, columns, "asc")
+	assert.Error(t, err)
+	_, err = NewSortOrder([]string{"ts"}, columns, "sideways")
+	assert.Error(t, err)
 }
 
 // MockPathManager implements paths.PathManager for testing
@@ -0,0 +1,43 @@
+package iceberg
+
+import "github.com/gear6io/ranger/server/metrics"
+
+// registerMetrics exposes the statistics of the manager, its file queue and its worker pool
+func (m *Manager) registerMetrics() {
+	metrics.Register(metrics.NewStatsCollector("iceberg", m.GetStats,
+		metrics.Counter("batches_processed_total", "File batches given Iceberg metadata.",
+			func(s *ManagerStats) float64 { return float64(s.BatchesProcessed) }),
+		metrics.Counter("processing_seconds_total", "Time spent generating Iceberg metadata.",
+			func(s *ManagerStats) float64 { return s.TotalProcessingTime.Seconds() }),
+		metrics.Counter("errors_total", "Failed Iceberg metadata generations.",
+			func(s *ManagerStats) float64 { return float64(s.Errors) }),
+	))
+
+	metrics.Register(metrics.NewStatsCollector("iceberg_file_queue", m.fileQueue.GetStats,
+		metrics.Gauge("pending_files", "Files waiting for Iceberg metadata.",
+			func(s *QueueStats) float64 { return float64(s.PendingCount) }),
+		metrics.Gauge("processing_files", "Files being given Iceberg metadata.",
+			func(s *QueueStats) float64 { return float64(s.ProcessingCount) }),
+		metrics.Counter("enqueued_total", "Files added to the queue.",
+			func(s *QueueStats) float64 { return float64(s.TotalEnqueued) }),
+		metrics.Counter("completed_total", "Files given Iceberg metadata.",
+			func(s *QueueStats) float64 { return float64(s.TotalCompleted) }),
+		metrics.Counter("failed_total", "Files whose Iceberg metadata failed.",
+			func(s *QueueStats) float64 { return float64(s.TotalFailed) }),
+	))
+
+	metrics.Register(metrics.NewStatsCollector("iceberg_worker_pool", m.workerPool.GetStats,
+		metrics.Gauge("workers", "Workers in the pool.",
+			func(s *PoolStats) float64 { return float64(s.TotalWorkers) }),
+		metrics.Gauge("active_workers", "Workers running a task.",
+			func(s *PoolStats) float64 { return float64(s.ActiveWorkers) }),
+		metrics.Gauge("queued_tasks", "Tasks waiting for a worker.",
+			func(s *PoolStats) float64 { return float64(s.TasksQueued) }),
+		metrics.Counter("tasks_completed_total", "Tasks completed by the pool.",
+			func(s *PoolStats) float64 { return float64(s.TasksCompleted) }),
+		metrics.Counter("tasks_failed_total", "Tasks that failed.",
+			func(s *PoolStats) float64 { return float64(s.TasksFailed) }),
+		metrics.Counter("wait_seconds_total", "Time tasks spent waiting for a worker.",
+			func(s *PoolStats) float64 { return s.TotalWaitTime.Seconds() }),
+	))
+}
@@ -0,0 +1,122 @@
+package iceberg
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+
+	"github.com/gear6io/ranger/pkg/errors"
+	"github.com/gear6io/ranger/server/metadata/registry"
+	"github.com/gear6io/ranger/server/metadata/registry/regtypes"
+)
+
+// Package-specific error codes for sort orders
+var (
+	SortOrderInvalid       = errors.MustNewCode("iceberg.sort_order.invalid")
+	SortOrderUnknownColumn = errors.MustNewCode("iceberg.sort_order.unknown_column")
+)
+
+const (
+	// UnsortedSortOrderID is the sort order of data files in no particular order, reserved
+	// by the Iceberg spec
+	UnsortedSortOrderID = 0
+
+	// TableSortOrderID is the sort order of data files written sorted by the ORDER BY key
+	// of their table
+	TableSortOrderID = 1
+)
+
+// SortOrder is an Iceberg sort order, which data files reference by ID
+type SortOrder struct {
+	OrderID int         `json:"order-id"`
+	Fields  []SortField `json:"fields"`
+}
+
+// SortField sorts by a table column, referenced by its field ID
+type SortField struct {
+	Transform string `json:"transform"`
+	SourceID  int    `json:"source-id"`
+	Direction string `json:"direction"`  // asc or desc
+	NullOrder string `json:"null-order"` // nulls-first or nulls-last
+}
+
+// NewSortOrder returns the sort order of data files sorted by columns in the direction of
+// strategy, binding each column to its field ID. Writers place nulls first.
+func NewSortOrder(columns []string, tableColumns []*regtypes.TableColumn, strategy string) (SortOrder, error) {
+	if len(columns) == 0 {
+		return SortOrder{OrderID: UnsortedSortOrderID, Fields: []SortField{}}, nil
+	}
+
+	direction := strings.ToLower(strategy)
+	if direction == "" {
+		direction = "asc"
+	}
+	if direction != "asc" && direction != "desc" {
+		return SortOrder{}, errors.New(SortOrderInvalid, "sort direction must be asc or desc", nil).AddContext("sort_strategy", strategy)
+	}
+
+	fieldIDs := make(map[string]int, len(tableColumns))
+	for _, column := range tableColumns {
+		fieldIDs[column.ColumnName] = column.ID
+	}
+
+	fields := make([]SortField, 0, len(columns))
+	for _, column := range columns {
+		fieldID, ok := fieldIDs[column]
+		if !ok {
+			return SortOrder{}, errors.New(SortOrderUnknownColumn, "sort column is not a column of the table", nil).AddContext("column", column)
+		}
+		fields = append(fields, SortField{
+			Transform: "identity",
+			SourceID:  fieldID,
+			Direction: direction,
+			NullOrder: "nulls-first",
+		})
+	}
+	return SortOrder{OrderID: TableSortOrderID, Fields: fields}, nil
+}
+
+// sortColumns returns the ORDER BY columns of a table; none when it has no sort key
+func sortColumns(tableInfo *registry.CompleteTableInfo) ([]string, error) {
+	if tableInfo == nil || tableInfo.StorageInfo == nil || tableInfo.StorageInfo.SortOrder == "" {
+		return nil, nil
+	}
+
+	var columns []string
+	if err := json.Unmarshal([]byte(tableInfo.StorageInfo.SortOrder), &columns); err != nil {
+		return nil, errors.New(SortOrderInvalid, "failed to parse table sort order", err).AddContext("sort_order", tableInfo.StorageInfo.SortOrder)
+	}
+	return columns, nil
+}
+
+// fileSortOrderID returns the ID of the sort order a data file was written with
+func fileSortOrderID(file *regtypes.TableFile) int {
+	if file.IsSorted {
+		return TableSortOrderID
+	}
+	return UnsortedSortOrderID
+}
+
+// tableSortOrders returns the sort orders of a table, unsorted first, and the ID of the one
+// its data files are written with
+func tableSortOrders(ctx context.Context, tableInfo *registry.CompleteTableInfo) ([]SortOrder, int, error) {
+	unsorted := SortOrder{OrderID: UnsortedSortOrderID, Fields: []SortField{}}
+
+	columns, err := sortColumns(tableInfo)
+	if err != nil {
+		return nil, 0, err
+	}
+	if len(columns) == 0 {
+		return []SortOrder{unsorted}, UnsortedSortOrderID, nil
+	}
+
+	tableColumns, err := tableInfo.GetColumns(ctx)
+	if err != nil {
+		return nil, 0, errors.New(errors.CommonInternal, "failed to load table columns", err).AddContext("table_id", tableInfo.ID)
+	}
+	sorted, err := NewSortOrder(columns, tableColumns, tableInfo.StorageInfo.SortStrategy)
+	if err != nil {
+		return nil, 0, err
+	}
+	return []SortOrder{unsorted, sorted}, sorted.OrderID, nil
+}
@@ -8,6 +8,8 @@ import (
 
 	"github.com/gear6io/ranger/pkg/errors"
 	"github.com/gear6io/ranger/server/metadata/registry/regtypes"
+	"github.com/gear6io/ranger/server/tracing"
+	"go.opentelemetry.io/otel/attribute"
 )
 
 // Package-specific error codes for task processing
@@ -25,7 +27,12 @@ type ProcessFileTask struct {
 }
 
 // Execute processes the file task
-func (t *ProcessFileTask) Execute(ctx context.Context) error {
+func (t *ProcessFileTask) Execute(ctx context.Context) (err error) {
+	_, span := tracing.Start(ctx, "iceberg.process_file",
+		attribute.Int64("ranger.file_id", t.FileInfo.ID),
+		attribute.Int64("ranger.table_id", t.FileInfo.TableID))
+	defer func() { tracing.End(span, err) }()
+
 	// Mark file as processing
 	if err := t.Manager.fileQueue.MarkCompleted(t.FileInfo.ID); err != nil {
 		return err
@@ -52,7 +59,12 @@ type ProcessBatchTask struct {
 }
 
 // Execute processes the batch task
-func (t *ProcessBatchTask) Execute(ctx context.Context) error {
+func (t *ProcessBatchTask) Execute(ctx context.Context) (err error) {
+	ctx, span := tracing.Start(ctx, "iceberg.process_batch",
+		attribute.String("ranger.batch_id", t.Batch.ID),
+		attribute.Int("ranger.files", len(t.Batch.Files)))
+	defer func() { tracing.End(span, err) }()
+
 	// Generate manifest for the batch
 	// TODO: Need table info for manifest generation
 	manifestPath, err := t.Manager.generateManifest(ctx, t.Batch, nil)
@@ -59,6 +59,9 @@ type MetadataManager struct {
 	logger         zerolog.Logger
 	mu             sync.RWMutex
 	running        bool
+
+	// maxIcebergBacklog is the Iceberg backlog beyond which the manager is not ready; 0 disables the limit
+	maxIcebergBacklog int
 }
 
 // NewMetadataManager creates a new metadata manager with bun migrations
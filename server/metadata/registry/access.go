@@ -0,0 +1,389 @@
+package registry
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+	"time"
+
+	"github.com/gear6io/ranger/pkg/errors"
+	"github.com/gear6io/ranger/server/metadata/registry/regtypes"
+)
+
+// CreateUser adds an active user with the given password
+func (sm *Store) CreateUser(ctx context.Context, username string, password regtypes.UserPassword) error {
+	if sm.roleExists(ctx, username) {
+		return errors.New(RegistryRoleExists, "a role with this name already exists", nil).AddContext("username", username)
+	}
+
+	now := time.Now()
+	result, err := sm.db.ExecContext(ctx, `
+		INSERT OR IGNORE INTO users (username, email, display_name, is_active, is_admin, password_hash, scram_secret, password_expires_at, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`, username, username+"@ranger.local", username, true, false, password.Hash, password.SCRAMSecret, password.ExpiresAt, now, now)
+	if err != nil {
+		return errors.New(errors.CommonInternal, "failed to create user", err).AddContext("username", username)
+	}
+
+	if rowsAffected, err := result.RowsAffected(); err == nil && rowsAffected == 0 {
+		return errors.New(RegistryUserExists, "user already exists", nil).AddContext("username", username)
+	}
+	return nil
+}
+
+// DropUser removes a user together with its role memberships and privileges
+func (sm *Store) DropUser(ctx context.Context, username string) error {
+	return sm.withTx(ctx, func(tx *sql.Tx) error {
+		result, err := tx.ExecContext(ctx, `DELETE FROM users WHERE username = ?`, username)
+		if err != nil {
+			return errors.New(errors.CommonInternal, "failed to drop user", err).AddContext("username", username)
+		}
+		if rowsAffected, err := result.RowsAffected(); err == nil && rowsAffected == 0 {
+			return errors.New(RegistryUserNotFound, "user not found", nil).AddContext("username", username)
+		}
+
+		// Role memberships are removed by the foreign key cascade
+		if _, err := tx.ExecContext(ctx, `DELETE FROM privileges WHERE grantee_type = ? AND grantee = ?`, regtypes.GranteeTypeUser, username); err != nil {
+			return errors.New(errors.CommonInternal, "failed to drop user privileges", err).AddContext("username", username)
+		}
+		if _, err := tx.ExecContext(ctx, `DELETE FROM auth_tokens WHERE username = ?`, username); err != nil {
+			return errors.New(errors.CommonInternal, "failed to drop user tokens", err).AddContext("username", username)
+		}
+		return nil
+	})
+}
+
+// RenameUser renames a user and carries its privileges over to the new name
+func (sm *Store) RenameUser(ctx context.Context, username, newUsername string) error {
+	if sm.roleExists(ctx, newUsername) {
+		return errors.New(RegistryRoleExists, "a role with this name already exists", nil).AddContext("username", newUsername)
+	}
+
+	return sm.withTx(ctx, func(tx *sql.Tx) error {
+		result, err := tx.ExecContext(ctx, `UPDATE users SET username = ?, updated_at = ? WHERE username = ?`, newUsername, time.Now(), username)
+		if err != nil {
+			return errors.New(RegistryUserExists, "failed to rename user", err).AddContext("username", username).AddContext("new_username", newUsername)
+		}
+		if rowsAffected, err := result.RowsAffected(); err == nil && rowsAffected == 0 {
+			return errors.New(RegistryUserNotFound, "user not found", nil).AddContext("username", username)
+		}
+
+		if _, err := tx.ExecContext(ctx, `UPDATE privileges SET grantee = ? WHERE grantee_type = ? AND grantee = ?`, newUsername, regtypes.GranteeTypeUser, username); err != nil {
+			return errors.New(errors.CommonInternal, "failed to move user privileges", err).AddContext("username", username)
+		}
+		// Sessions opened under the old name end with it
+		return revokeUserTokens(ctx, tx, username)
+	})
+}
+
+// SetUserPassword replaces the password of a user, unlocking the account and revoking its tokens
+func (sm *Store) SetUserPassword(ctx context.Context, username string, password regtypes.UserPassword) error {
+	return sm.withTx(ctx, func(tx *sql.Tx) error {
+		result, err := tx.ExecContext(ctx, `
+			UPDATE users SET password_hash = ?, scram_secret = ?, password_expires_at = ?,
+				failed_login_attempts = 0, locked_until = NULL, updated_at = ?
+			WHERE username = ?
+		`, password.Hash, password.SCRAMSecret, password.ExpiresAt, time.Now(), username)
+		if err != nil {
+			return errors.New(errors.CommonInternal, "failed to set user password", err).AddContext("username", username)
+		}
+		if rowsAffected, err := result.RowsAffected(); err == nil && rowsAffected == 0 {
+			return errors.New(RegistryUserNotFound, "user not found", nil).AddContext("username", username)
+		}
+		return revokeUserTokens(ctx, tx, username)
+	})
+}
+
+// ListUsers returns the names of all users
+func (sm *Store) ListUsers(ctx context.Context) ([]string, error) {
+	return sm.queryNames(ctx, `SELECT username FROM users ORDER BY username`)
+}
+
+// CreateRole adds a role
+func (sm *Store) CreateRole(ctx context.Context, name string) error {
+	if _, err := sm.GetUser(ctx, name); err == nil {
+		return errors.New(RegistryUserExists, "a user with this name already exists", nil).AddContext("role", name)
+	}
+
+	now := time.Now()
+	result, err := sm.db.ExecContext(ctx, `INSERT OR IGNORE INTO roles (name, created_at, updated_at) VALUES (?, ?, ?)`, name, now, now)
+	if err != nil {
+		return errors.New(errors.CommonInternal, "failed to create role", err).AddContext("role", name)
+	}
+	if rowsAffected, err := result.RowsAffected(); err == nil && rowsAffected == 0 {
+		return errors.New(RegistryRoleExists, "role already exists", nil).AddContext("role", name)
+	}
+	return nil
+}
+
+// DropRole removes a role, its memberships and its privileges
+func (sm *Store) DropRole(ctx context.Context, name string) error {
+	return sm.withTx(ctx, func(tx *sql.Tx) error {
+		result, err := tx.ExecContext(ctx, `DELETE FROM roles WHERE name = ?`, name)
+		if err != nil {
+			return errors.New(errors.CommonInternal, "failed to drop role", err).AddContext("role", name)
+		}
+		if rowsAffected, err := result.RowsAffected(); err == nil && rowsAffected == 0 {
+			return errors.New(RegistryRoleNotFound, "role not found", nil).AddContext("role", name)
+		}
+
+		if _, err := tx.ExecContext(ctx, `DELETE FROM privileges WHERE grantee_type = ? AND grantee = ?`, regtypes.GranteeTypeRole, name); err != nil {
+			return errors.New(errors.CommonInternal, "failed to drop role privileges", err).AddContext("role", name)
+		}
+		return nil
+	})
+}
+
+// RoleExists reports whether a role exists
+func (sm *Store) RoleExists(ctx context.Context, name string) bool {
+	return sm.roleExists(ctx, name)
+}
+
+// GrantRole makes username a member of role
+func (sm *Store) GrantRole(ctx context.Context, role, username string) error {
+	roleID, userID, err := sm.roleMemberIDs(ctx, role, username)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	if _, err := sm.db.ExecContext(ctx, `
+		INSERT OR IGNORE INTO role_members (role_id, user_id, created_at, updated_at) VALUES (?, ?, ?, ?)
+	`, roleID, userID, now, now); err != nil {
+		return errors.New(errors.CommonInternal, "failed to grant role", err).AddContext("role", role).AddContext("username", username)
+	}
+	return nil
+}
+
+// RevokeRole removes username from role
+func (sm *Store) RevokeRole(ctx context.Context, role, username string) error {
+	roleID, userID, err := sm.roleMemberIDs(ctx, role, username)
+	if err != nil {
+		return err
+	}
+
+	if _, err := sm.db.ExecContext(ctx, `DELETE FROM role_members WHERE role_id = ? AND user_id = ?`, roleID, userID); err != nil {
+		return errors.New(errors.CommonInternal, "failed to revoke role", err).AddContext("role", role).AddContext("username", username)
+	}
+	return nil
+}
+
+// ListUserRoles returns the names of the roles granted to username
+func (sm *Store) ListUserRoles(ctx context.Context, username string) ([]string, error) {
+	return sm.queryNames(ctx, `
+		SELECT r.name
+		FROM roles r
+		JOIN role_members m ON m.role_id = r.id
+		JOIN users u ON m.user_id = u.id
+		WHERE u.username = ?
+		ORDER BY r.name
+	`, username)
+}
+
+// GrantPrivilege records a privilege; granting an existing privilege again is a no-op
+func (sm *Store) GrantPrivilege(ctx context.Context, privilege *regtypes.Privilege) error {
+	now := time.Now()
+	if _, err := sm.db.ExecContext(ctx, `
+		INSERT OR IGNORE INTO privileges (grantee_type, grantee, action, database_name, table_name, column_name, grantor, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`, privilege.GranteeType, privilege.Grantee, privilege.Action, privilege.Database, privilege.Table, privilege.Column, privilege.Grantor, now, now); err != nil {
+		return errors.New(errors.CommonInternal, "failed to grant privilege", err).
+			AddContext("grantee", privilege.Grantee).
+			AddContext("action", privilege.Action)
+	}
+	return nil
+}
+
+// RevokePrivilege removes a privilege. Revoking a table privilege also revokes it on
+// every column of the table, and revoking ALL removes every action on the object.
+func (sm *Store) RevokePrivilege(ctx context.Context, privilege *regtypes.Privilege, all bool) error {
+	query := `DELETE FROM privileges WHERE grantee_type = ? AND grantee = ? AND database_name = ? AND table_name = ?`
+	args := []interface{}{privilege.GranteeType, privilege.Grantee, privilege.Database, privilege.Table}
+	if !all {
+		query += ` AND action = ?`
+		args = append(args, privilege.Action)
+	}
+	if privilege.Column != "" {
+		query += ` AND column_name = ?`
+		args = append(args, privilege.Column)
+	}
+
+	if _, err := sm.db.ExecContext(ctx, query, args...); err != nil {
+		return errors.New(errors.CommonInternal, "failed to revoke privilege", err).
+			AddContext("grantee", privilege.Grantee).
+			AddContext("action", privilege.Action)
+	}
+	return nil
+}
+
+// ListPrivileges returns the privileges granted directly to a user or role
+func (sm *Store) ListPrivileges(ctx context.Context, granteeType, grantee string) ([]*regtypes.Privilege, error) {
+	rows, err := sm.db.QueryContext(ctx, `
+		SELECT id, grantee_type, grantee, action, database_name, table_name, column_name, grantor
+		FROM privileges
+		WHERE grantee_type = ? AND grantee = ?
+		ORDER BY database_name, table_name, column_name, action
+	`, granteeType, grantee)
+	if err != nil {
+		return nil, errors.New(errors.CommonInternal, "failed to query privileges", err).AddContext("grantee", grantee)
+	}
+	defer rows.Close()
+
+	var privileges []*regtypes.Privilege
+	for rows.Next() {
+		var privilege regtypes.Privilege
+		var grantor sql.NullString
+		if err := rows.Scan(&privilege.ID, &privilege.GranteeType, &privilege.Grantee, &privilege.Action,
+			&privilege.Database, &privilege.Table, &privilege.Column, &grantor); err != nil {
+			return nil, errors.New(errors.CommonInternal, "failed to scan privilege", err).AddContext("grantee", grantee)
+		}
+		privilege.Grantor = grantor.String
+		privileges = append(privileges, &privilege)
+	}
+
+	return privileges, rows.Err()
+}
+
+// CreateAccessPolicy records a row access or masking policy. A table has at most one
+// policy of a name, and at most one masking policy per column.
+func (sm *Store) CreateAccessPolicy(ctx context.Context, policy *regtypes.AccessPolicy) error {
+	if policy.Kind == regtypes.PolicyKindMasking {
+		var existing string
+		err := sm.db.QueryRowContext(ctx, `
+			SELECT name FROM access_policies
+			WHERE kind = ? AND database_name = ? AND table_name = ? AND column_name = ?
+		`, policy.Kind, policy.Database, policy.Table, policy.Column).Scan(&existing)
+		if err == nil {
+			return errors.New(RegistryPolicyExists, "column already has a masking policy", nil).
+				AddContext("policy", existing).
+				AddContext("column", policy.Column)
+		}
+		if err != sql.ErrNoRows {
+			return errors.New(errors.CommonInternal, "failed to check masking policies", err).AddContext("policy", policy.Name)
+		}
+	}
+
+	now := time.Now()
+	result, err := sm.db.ExecContext(ctx, `
+		INSERT OR IGNORE INTO access_policies (kind, name, database_name, table_name, column_name, expression, grantees, created_by, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`, policy.Kind, policy.Name, policy.Database, policy.Table, policy.Column, policy.Expression, strings.Join(policy.Grantees, ","), policy.CreatedBy, now, now)
+	if err != nil {
+		return errors.New(errors.CommonInternal, "failed to create access policy", err).AddContext("policy", policy.Name)
+	}
+
+	if rowsAffected, err := result.RowsAffected(); err == nil && rowsAffected == 0 {
+		return errors.New(RegistryPolicyExists, "policy already exists", nil).
+			AddContext("policy", policy.Name).
+			AddContext("table", policy.Database+"."+policy.Table)
+	}
+	return nil
+}
+
+// DropAccessPolicy removes the policy of the given kind and name from a table
+func (sm *Store) DropAccessPolicy(ctx context.Context, kind, database, table, name string) error {
+	result, err := sm.db.ExecContext(ctx, `
+		DELETE FROM access_policies WHERE kind = ? AND database_name = ? AND table_name = ? AND name = ?
+	`, kind, database, table, name)
+	if err != nil {
+		return errors.New(errors.CommonInternal, "failed to drop access policy", err).AddContext("policy", name)
+	}
+	if rowsAffected, err := result.RowsAffected(); err == nil && rowsAffected == 0 {
+		return errors.New(RegistryPolicyNotFound, "policy not found", nil).
+			AddContext("policy", name).
+			AddContext("table", database+"."+table)
+	}
+	return nil
+}
+
+// ListAccessPolicies returns the row access and masking policies of a table
+func (sm *Store) ListAccessPolicies(ctx context.Context, database, table string) ([]*regtypes.AccessPolicy, error) {
+	rows, err := sm.db.QueryContext(ctx, `
+		SELECT id, kind, name, database_name, table_name, column_name, expression, grantees, created_by
+		FROM access_policies
+		WHERE database_name = ? AND table_name = ?
+		ORDER BY kind, name
+	`, database, table)
+	if err != nil {
+		return nil, errors.New(errors.CommonInternal, "failed to query access policies", err).AddContext("table", database+"."+table)
+	}
+	defer rows.Close()
+
+	var policies []*regtypes.AccessPolicy
+	for rows.Next() {
+		var policy regtypes.AccessPolicy
+		var grantees string
+		var createdBy sql.NullString
+		if err := rows.Scan(&policy.ID, &policy.Kind, &policy.Name, &policy.Database, &policy.Table,
+			&policy.Column, &policy.Expression, &grantees, &createdBy); err != nil {
+			return nil, errors.New(errors.CommonInternal, "failed to scan access policy", err).AddContext("table", database+"."+table)
+		}
+		if grantees != "" {
+			policy.Grantees = strings.Split(grantees, ",")
+		}
+		policy.CreatedBy = createdBy.String
+		policies = append(policies, &policy)
+	}
+
+	return policies, rows.Err()
+}
+
+// roleExists reports whether a role exists
+func (sm *Store) roleExists(ctx context.Context, name string) bool {
+	var exists int
+	return sm.db.QueryRowContext(ctx, `SELECT 1 FROM roles WHERE name = ? LIMIT 1`, name).Scan(&exists) == nil
+}
+
+// roleMemberIDs resolves a role and a user name to their ids
+func (sm *Store) roleMemberIDs(ctx context.Context, role, username string) (int64, int64, error) {
+	var roleID int64
+	if err := sm.db.QueryRowContext(ctx, `SELECT id FROM roles WHERE name = ?`, role).Scan(&roleID); err != nil {
+		if err == sql.ErrNoRows {
+			return 0, 0, errors.New(RegistryRoleNotFound, "role not found", nil).AddContext("role", role)
+		}
+		return 0, 0, errors.New(errors.CommonInternal, "failed to get role", err).AddContext("role", role)
+	}
+
+	user, err := sm.GetUser(ctx, username)
+	if err != nil {
+		return 0, 0, err
+	}
+	return roleID, user.ID, nil
+}
+
+// queryNames runs a query returning a single string column
+func (sm *Store) queryNames(ctx context.Context, query string, args ...interface{}) ([]string, error) {
+	rows, err := sm.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, errors.New(errors.CommonInternal, "failed to query names", err)
+	}
+	defer rows.Close()
+
+	var names []string
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, errors.New(errors.CommonInternal, "failed to scan name", err)
+		}
+		names = append(names, name)
+	}
+	return names, rows.Err()
+}
+
+// withTx runs fn in a transaction, committing only if it succeeds
+func (sm *Store) withTx(ctx context.Context, fn func(tx *sql.Tx) error) error {
+	tx, err := sm.db.BeginTx(ctx, nil)
+	if err != nil {
+		return errors.New(RegistryTransactionFailed, "failed to begin transaction", err)
+	}
+
+	if err := fn(tx); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return errors.New(RegistryTransactionFailed, "failed to commit transaction", err)
+	}
+	return nil
+}
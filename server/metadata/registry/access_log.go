@@ -0,0 +1,35 @@
+package registry
+
+import (
+	"context"
+	"time"
+
+	"github.com/gear6io/ranger/pkg/errors"
+	"github.com/gear6io/ranger/server/metadata/registry/regtypes"
+)
+
+// InsertAccessLog appends an entry to the audit log, linking it to the user of that name if any
+func (sm *Store) InsertAccessLog(ctx context.Context, entry *regtypes.AccessLog) error {
+	createdAt := entry.CreatedAt
+	if createdAt.IsZero() {
+		createdAt = time.Now()
+	}
+
+	if _, err := sm.db.ExecContext(ctx, `
+		INSERT INTO access_log (user_id, username, protocol, action, resource, ip_address, user_agent, status, error, duration_ms, created_at, updated_at)
+		VALUES ((SELECT id FROM users WHERE username = ?), ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`, entry.Username, entry.Username, entry.Protocol, entry.Action, entry.Resource, entry.IPAddress, entry.UserAgent,
+		entry.Status, entry.Error, entry.Duration, createdAt, createdAt); err != nil {
+		return errors.New(errors.CommonInternal, "failed to insert access log entry", err).AddContext("action", entry.Action)
+	}
+	return nil
+}
+
+// PruneAccessLog removes the audit log entries recorded before cutoff and returns how many were removed
+func (sm *Store) PruneAccessLog(ctx context.Context, cutoff time.Time) (int64, error) {
+	result, err := sm.db.ExecContext(ctx, `DELETE FROM access_log WHERE created_at < ?`, cutoff)
+	if err != nil {
+		return 0, errors.New(errors.CommonInternal, "failed to prune access log", err)
+	}
+	return result.RowsAffected()
+}
@@ -0,0 +1,49 @@
+package registry
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/gear6io/ranger/server/metadata/registry/regtypes"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStoreAccessLog(t *testing.T) {
+	tempDir := t.TempDir()
+	store, err := NewStore(filepath.Join(tempDir, "test.db"), filepath.Join(tempDir, "data"))
+	require.NoError(t, err)
+	defer store.Close()
+
+	ctx := context.Background()
+	old := time.Now().Add(-48 * time.Hour)
+
+	require.NoError(t, store.InsertAccessLog(ctx, &regtypes.AccessLog{
+		Username: "admin", Protocol: "jdbc", Action: "LOGIN", Resource: "default", Status: regtypes.AccessStatusOK,
+		TimeAuditable: regtypes.TimeAuditable{CreatedAt: old},
+	}))
+	require.NoError(t, store.InsertAccessLog(ctx, &regtypes.AccessLog{
+		Username: "mallory", Protocol: "jdbc", Action: "LOGIN", Resource: "default", Status: regtypes.AccessStatusUnauthenticated,
+		Error: "password authentication failed",
+	}))
+
+	// Entries of registry users are linked to them; unknown users keep only their name
+	var linked int
+	require.NoError(t, store.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM access_log WHERE user_id IS NOT NULL`).Scan(&linked))
+	assert.Equal(t, 1, linked)
+
+	var username, errorMessage string
+	var status int
+	require.NoError(t, store.db.QueryRowContext(ctx, `
+		SELECT username, status, error FROM system_access_log ORDER BY event_time DESC LIMIT 1
+	`).Scan(&username, &status, &errorMessage))
+	assert.Equal(t, "mallory", username)
+	assert.Equal(t, regtypes.AccessStatusUnauthenticated, status)
+	assert.Equal(t, "password authentication failed", errorMessage)
+
+	pruned, err := store.PruneAccessLog(ctx, time.Now().Add(-24*time.Hour))
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), pruned)
+}
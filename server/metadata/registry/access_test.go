@@ -0,0 +1,174 @@
+package registry
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/gear6io/ranger/pkg/errors"
+	"github.com/gear6io/ranger/server/metadata/registry/regtypes"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStoreAccessControl(t *testing.T) {
+	tempDir := t.TempDir()
+	store, err := NewStore(filepath.Join(tempDir, "test.db"), filepath.Join(tempDir, "data"))
+	require.NoError(t, err)
+	defer store.Close()
+
+	ctx := context.Background()
+
+	t.Run("BuiltinUsers", func(t *testing.T) {
+		admin, err := store.GetUser(ctx, "admin")
+		require.NoError(t, err)
+		assert.True(t, admin.IsAdmin)
+
+		privileges, err := store.ListPrivileges(ctx, regtypes.GranteeTypeUser, "readonly")
+		require.NoError(t, err)
+		require.Len(t, privileges, 1)
+		assert.Equal(t, "SELECT", privileges[0].Action)
+		assert.Equal(t, regtypes.PrivilegeWildcard, privileges[0].Database)
+
+		// default is confined to the default database and holds no ALL privilege
+		privileges, err = store.ListPrivileges(ctx, regtypes.GranteeTypeUser, "default")
+		require.NoError(t, err)
+		require.Len(t, privileges, 4)
+		for _, privilege := range privileges {
+			assert.NotEqual(t, "ALL", privilege.Action)
+			assert.Equal(t, "default", privilege.Database)
+		}
+	})
+
+	t.Run("Users", func(t *testing.T) {
+		require.NoError(t, store.CreateUser(ctx, "alice", regtypes.UserPassword{Hash: "hash", SCRAMSecret: "verifier"}))
+		err := store.CreateUser(ctx, "alice", regtypes.UserPassword{Hash: "hash", SCRAMSecret: "verifier"})
+		assert.Equal(t, RegistryUserExists.String(), errors.GetCode(err))
+
+		user, err := store.GetUser(ctx, "alice")
+		require.NoError(t, err)
+		assert.Equal(t, "hash", user.PasswordHash)
+		assert.Equal(t, "verifier", user.SCRAMSecret)
+		assert.False(t, user.IsAdmin)
+
+		require.NoError(t, store.SetUserPassword(ctx, "alice", regtypes.UserPassword{Hash: "rotated"}))
+		user, err = store.GetUser(ctx, "alice")
+		require.NoError(t, err)
+		assert.Equal(t, "rotated", user.PasswordHash)
+
+		users, err := store.ListUsers(ctx)
+		require.NoError(t, err)
+		assert.Contains(t, users, "alice")
+	})
+
+	t.Run("Roles", func(t *testing.T) {
+		require.NoError(t, store.CreateRole(ctx, "analyst"))
+		assert.True(t, store.RoleExists(ctx, "analyst"))
+
+		// Users and roles share one namespace
+		assert.Equal(t, RegistryUserExists.String(), errors.GetCode(store.CreateRole(ctx, "alice")))
+		assert.Equal(t, RegistryRoleExists.String(), errors.GetCode(store.CreateUser(ctx, "analyst", regtypes.UserPassword{})))
+
+		require.NoError(t, store.GrantRole(ctx, "analyst", "alice"))
+		require.NoError(t, store.GrantRole(ctx, "analyst", "alice"))
+		roles, err := store.ListUserRoles(ctx, "alice")
+		require.NoError(t, err)
+		assert.Equal(t, []string{"analyst"}, roles)
+
+		err = store.GrantRole(ctx, "missing", "alice")
+		assert.Equal(t, RegistryRoleNotFound.String(), errors.GetCode(err))
+
+		require.NoError(t, store.RevokeRole(ctx, "analyst", "alice"))
+		roles, err = store.ListUserRoles(ctx, "alice")
+		require.NoError(t, err)
+		assert.Empty(t, roles)
+	})
+
+	t.Run("Privileges", func(t *testing.T) {
+		grant := func(action, table, column string) {
+			require.NoError(t, store.GrantPrivilege(ctx, &regtypes.Privilege{
+				GranteeType: regtypes.GranteeTypeUser, Grantee: "alice",
+				Action: action, Database: "sales", Table: table, Column: column, Grantor: "admin",
+			}))
+		}
+		grant("SELECT", "orders", "")
+		grant("SELECT", "orders", "")
+		grant("INSERT", "orders", "")
+		grant("SELECT", "customers", "email")
+
+		privileges, err := store.ListPrivileges(ctx, regtypes.GranteeTypeUser, "alice")
+		require.NoError(t, err)
+		assert.Len(t, privileges, 3)
+
+		// Revoking a table privilege also revokes its column privileges
+		require.NoError(t, store.RevokePrivilege(ctx, &regtypes.Privilege{
+			GranteeType: regtypes.GranteeTypeUser, Grantee: "alice", Action: "SELECT", Database: "sales", Table: "customers",
+		}, false))
+		// Revoking ALL removes every action on the object
+		require.NoError(t, store.RevokePrivilege(ctx, &regtypes.Privilege{
+			GranteeType: regtypes.GranteeTypeUser, Grantee: "alice", Action: "ALL", Database: "sales", Table: "orders",
+		}, true))
+
+		privileges, err = store.ListPrivileges(ctx, regtypes.GranteeTypeUser, "alice")
+		require.NoError(t, err)
+		assert.Empty(t, privileges)
+	})
+
+	t.Run("RenameAndDropUser", func(t *testing.T) {
+		require.NoError(t, store.GrantPrivilege(ctx, &regtypes.Privilege{
+			GranteeType: regtypes.GranteeTypeUser, Grantee: "alice", Action: "SELECT", Database: "sales", Table: regtypes.PrivilegeWildcard,
+		}))
+		require.NoError(t, store.RenameUser(ctx, "alice", "alicia"))
+
+		privileges, err := store.ListPrivileges(ctx, regtypes.GranteeTypeUser, "alicia")
+		require.NoError(t, err)
+		assert.Len(t, privileges, 1)
+
+		require.NoError(t, store.DropUser(ctx, "alicia"))
+		_, err = store.GetUser(ctx, "alicia")
+		assert.Equal(t, RegistryUserNotFound.String(), errors.GetCode(err))
+
+		privileges, err = store.ListPrivileges(ctx, regtypes.GranteeTypeUser, "alicia")
+		require.NoError(t, err)
+		assert.Empty(t, privileges)
+
+		require.NoError(t, store.DropRole(ctx, "analyst"))
+		assert.False(t, store.RoleExists(ctx, "analyst"))
+	})
+	t.Run("AccessPolicies", func(t *testing.T) {
+		rowPolicy := &regtypes.AccessPolicy{
+			Kind: regtypes.PolicyKindRowAccess, Name: "eu_only", Database: "sales", Table: "customers",
+			Expression: "region = 'EU'", Grantees: []string{"analyst", "bob"}, CreatedBy: "admin",
+		}
+		require.NoError(t, store.CreateAccessPolicy(ctx, rowPolicy))
+		err := store.CreateAccessPolicy(ctx, rowPolicy)
+		assert.Equal(t, RegistryPolicyExists.String(), errors.GetCode(err))
+
+		mask := &regtypes.AccessPolicy{
+			Kind: regtypes.PolicyKindMasking, Name: "hide_email", Database: "sales", Table: "customers",
+			Column: "email", Expression: "md5(email)", CreatedBy: "admin",
+		}
+		require.NoError(t, store.CreateAccessPolicy(ctx, mask))
+		err = store.CreateAccessPolicy(ctx, &regtypes.AccessPolicy{
+			Kind: regtypes.PolicyKindMasking, Name: "null_email", Database: "sales", Table: "customers",
+			Column: "email", Expression: "NULL",
+		})
+		assert.Equal(t, RegistryPolicyExists.String(), errors.GetCode(err))
+
+		policies, err := store.ListAccessPolicies(ctx, "sales", "customers")
+		require.NoError(t, err)
+		require.Len(t, policies, 2)
+		assert.Equal(t, "hide_email", policies[0].Name)
+		assert.Empty(t, policies[0].Grantees)
+		assert.Equal(t, "eu_only", policies[1].Name)
+		assert.Equal(t, []string{"analyst", "bob"}, policies[1].Grantees)
+
+		var count int
+		require.NoError(t, store.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM system_policies WHERE table_name = ?`, "customers").Scan(&count))
+		assert.Equal(t, 2, count)
+
+		require.NoError(t, store.DropAccessPolicy(ctx, regtypes.PolicyKindMasking, "sales", "customers", "hide_email"))
+		err = store.DropAccessPolicy(ctx, regtypes.PolicyKindMasking, "sales", "customers", "hide_email")
+		assert.Equal(t, RegistryPolicyNotFound.String(), errors.GetCode(err))
+	})
+}
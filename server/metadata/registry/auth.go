@@ -0,0 +1,124 @@
+package registry
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/gear6io/ranger/pkg/errors"
+	"github.com/gear6io/ranger/server/metadata/registry/regtypes"
+)
+
+// RecordLoginFailure counts a failed login of username and locks the account until
+// now+lockout once maxAttempts consecutive failures are reached. It returns the time the
+// account is locked until, or nil when it is not locked. A zero maxAttempts never locks.
+func (sm *Store) RecordLoginFailure(ctx context.Context, username string, maxAttempts int, lockout time.Duration) (*time.Time, error) {
+	var lockedUntil *time.Time
+	err := sm.withTx(ctx, func(tx *sql.Tx) error {
+		var attempts int
+		if err := tx.QueryRowContext(ctx, `
+			UPDATE users SET failed_login_attempts = failed_login_attempts + 1
+			WHERE username = ?
+			RETURNING failed_login_attempts
+		`, username).Scan(&attempts); err != nil {
+			if err == sql.ErrNoRows {
+				return errors.New(RegistryUserNotFound, "user not found", nil).AddContext("username", username)
+			}
+			return errors.New(errors.CommonInternal, "failed to record login failure", err).AddContext("username", username)
+		}
+
+		if maxAttempts <= 0 || attempts < maxAttempts {
+			return nil
+		}
+
+		until := time.Now().Add(lockout)
+		if _, err := tx.ExecContext(ctx, `
+			UPDATE users SET failed_login_attempts = 0, locked_until = ? WHERE username = ?
+		`, until, username); err != nil {
+			return errors.New(errors.CommonInternal, "failed to lock user", err).AddContext("username", username)
+		}
+		lockedUntil = &until
+		return nil
+	})
+	return lockedUntil, err
+}
+
+// RecordLoginSuccess resets the failed login count of username and stamps its last login
+func (sm *Store) RecordLoginSuccess(ctx context.Context, username string) error {
+	now := time.Now()
+	if _, err := sm.db.ExecContext(ctx, `
+		UPDATE users SET failed_login_attempts = 0, locked_until = NULL, last_login_at = ? WHERE username = ?
+	`, now, username); err != nil {
+		return errors.New(errors.CommonInternal, "failed to record login", err).AddContext("username", username)
+	}
+	return nil
+}
+
+// CreateAuthToken stores the hash of a session token issued to username
+func (sm *Store) CreateAuthToken(ctx context.Context, token *regtypes.AuthToken) error {
+	now := time.Now()
+	if _, err := sm.db.ExecContext(ctx, `
+		INSERT INTO auth_tokens (token_hash, username, expires_at, created_at, updated_at) VALUES (?, ?, ?, ?, ?)
+	`, token.TokenHash, token.Username, token.ExpiresAt, now, now); err != nil {
+		return errors.New(errors.CommonInternal, "failed to store auth token", err).AddContext("username", token.Username)
+	}
+	return nil
+}
+
+// GetAuthToken returns the session token with the given hash
+func (sm *Store) GetAuthToken(ctx context.Context, tokenHash string) (*regtypes.AuthToken, error) {
+	var token regtypes.AuthToken
+	err := sm.db.QueryRowContext(ctx, `
+		SELECT id, token_hash, username, expires_at, revoked_at, created_at, updated_at
+		FROM auth_tokens WHERE token_hash = ?
+	`, tokenHash).Scan(&token.ID, &token.TokenHash, &token.Username, &token.ExpiresAt, &token.RevokedAt, &token.CreatedAt, &token.UpdatedAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, errors.New(RegistryTokenNotFound, "auth token not found", nil)
+		}
+		return nil, errors.New(errors.CommonInternal, "failed to get auth token", err)
+	}
+	return &token, nil
+}
+
+// RevokeAuthToken revokes the session token with the given hash
+func (sm *Store) RevokeAuthToken(ctx context.Context, tokenHash string) error {
+	now := time.Now()
+	result, err := sm.db.ExecContext(ctx, `
+		UPDATE auth_tokens SET revoked_at = ?, updated_at = ? WHERE token_hash = ? AND revoked_at IS NULL
+	`, now, now, tokenHash)
+	if err != nil {
+		return errors.New(errors.CommonInternal, "failed to revoke auth token", err)
+	}
+	if rowsAffected, err := result.RowsAffected(); err == nil && rowsAffected == 0 {
+		return errors.New(RegistryTokenNotFound, "auth token not found", nil)
+	}
+	return nil
+}
+
+// RevokeUserTokens revokes every session token of username
+func (sm *Store) RevokeUserTokens(ctx context.Context, username string) error {
+	return sm.withTx(ctx, func(tx *sql.Tx) error {
+		return revokeUserTokens(ctx, tx, username)
+	})
+}
+
+// revokeUserTokens revokes every session token of username within tx
+func revokeUserTokens(ctx context.Context, tx *sql.Tx, username string) error {
+	now := time.Now()
+	if _, err := tx.ExecContext(ctx, `
+		UPDATE auth_tokens SET revoked_at = ?, updated_at = ? WHERE username = ? AND revoked_at IS NULL
+	`, now, now, username); err != nil {
+		return errors.New(errors.CommonInternal, "failed to revoke user tokens", err).AddContext("username", username)
+	}
+	return nil
+}
+
+// PruneAuthTokens removes the session tokens that expired before cutoff
+func (sm *Store) PruneAuthTokens(ctx context.Context, cutoff time.Time) (int64, error) {
+	result, err := sm.db.ExecContext(ctx, `DELETE FROM auth_tokens WHERE expires_at < ?`, cutoff)
+	if err != nil {
+		return 0, errors.New(errors.CommonInternal, "failed to prune auth tokens", err)
+	}
+	return result.RowsAffected()
+}
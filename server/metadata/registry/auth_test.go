@@ -0,0 +1,79 @@
+package registry
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/gear6io/ranger/pkg/errors"
+	"github.com/gear6io/ranger/server/metadata/registry/regtypes"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStoreUserAuthentication(t *testing.T) {
+	tempDir := t.TempDir()
+	store, err := NewStore(filepath.Join(tempDir, "test.db"), filepath.Join(tempDir, "data"))
+	require.NoError(t, err)
+	defer store.Close()
+
+	ctx := context.Background()
+	require.NoError(t, store.CreateUser(ctx, "alice", regtypes.UserPassword{Hash: "hash"}))
+
+	t.Run("Lockout", func(t *testing.T) {
+		for i := 0; i < 2; i++ {
+			lockedUntil, err := store.RecordLoginFailure(ctx, "alice", 3, time.Minute)
+			require.NoError(t, err)
+			assert.Nil(t, lockedUntil)
+		}
+		lockedUntil, err := store.RecordLoginFailure(ctx, "alice", 3, time.Minute)
+		require.NoError(t, err)
+		require.NotNil(t, lockedUntil)
+
+		user, err := store.GetUser(ctx, "alice")
+		require.NoError(t, err)
+		assert.True(t, user.IsLocked(time.Now()))
+		assert.False(t, user.IsLocked(time.Now().Add(2*time.Minute)))
+
+		require.NoError(t, store.RecordLoginSuccess(ctx, "alice"))
+		user, err = store.GetUser(ctx, "alice")
+		require.NoError(t, err)
+		assert.False(t, user.IsLocked(time.Now()))
+		assert.Zero(t, user.FailedLoginAttempts)
+		assert.NotNil(t, user.LastLoginAt)
+
+		_, err = store.RecordLoginFailure(ctx, "nobody", 3, time.Minute)
+		assert.Equal(t, RegistryUserNotFound.String(), errors.GetCode(err))
+	})
+
+	t.Run("Tokens", func(t *testing.T) {
+		expiresAt := time.Now().Add(time.Hour)
+		require.NoError(t, store.CreateAuthToken(ctx, &regtypes.AuthToken{TokenHash: "t1", Username: "alice", ExpiresAt: expiresAt}))
+		require.NoError(t, store.CreateAuthToken(ctx, &regtypes.AuthToken{TokenHash: "t2", Username: "alice", ExpiresAt: expiresAt}))
+
+		token, err := store.GetAuthToken(ctx, "t1")
+		require.NoError(t, err)
+		assert.Equal(t, "alice", token.Username)
+		assert.Nil(t, token.RevokedAt)
+
+		require.NoError(t, store.RevokeAuthToken(ctx, "t1"))
+		token, err = store.GetAuthToken(ctx, "t1")
+		require.NoError(t, err)
+		assert.NotNil(t, token.RevokedAt)
+		assert.Equal(t, RegistryTokenNotFound.String(), errors.GetCode(store.RevokeAuthToken(ctx, "t1")))
+
+		// Changing the password ends every session
+		require.NoError(t, store.SetUserPassword(ctx, "alice", regtypes.UserPassword{Hash: "rotated"}))
+		token, err = store.GetAuthToken(ctx, "t2")
+		require.NoError(t, err)
+		assert.NotNil(t, token.RevokedAt)
+
+		removed, err := store.PruneAuthTokens(ctx, expiresAt.Add(time.Second))
+		require.NoError(t, err)
+		assert.Equal(t, int64(2), removed)
+
+		_, err = store.GetAuthToken(ctx, "t1")
+		assert.Equal(t, RegistryTokenNotFound.String(), errors.GetCode(err))
+	})
+}
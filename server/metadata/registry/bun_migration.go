@@ -243,6 +243,7 @@ func (bmm *BunMigrationManager) VerifySchema(ctx context.Context) error {
 		"bun_migrations", "users", "databases", "tables", "table_metadata",
 		"table_files", "table_partitions", "table_indexes", "table_constraints",
 		"table_columns", "table_statistics", "access_log", "schema_versions",
+		"roles", "role_members", "privileges", "access_policies", "auth_tokens",
 	}
 
 	for _, tableName := range expectedTables {
@@ -297,6 +298,12 @@ func (bmm *BunMigrationManager) GetLatestVersion() int {
 func (bmm *BunMigrationManager) GetAvailableMigrations() []Migration {
 	return []Migration{
 		&migrations.Migration001{}, // from migrations/001_start.go
+		&migrations.Migration002{}, // from migrations/002_access_control.go
+		&migrations.Migration003{}, // from migrations/003_access_policies.go
+		&migrations.Migration004{}, // from migrations/004_audit_log.go
+		&migrations.Migration005{}, // from migrations/005_user_store.go
+		&migrations.Migration006{}, // from migrations/006_data_file_indexes.go
+		&migrations.Migration007{}, // from migrations/007_data_file_sort_order.go
 		// Future migrations will be added here
 	}
 }
@@ -0,0 +1,124 @@
+package migrations
+
+import (
+	"context"
+	"time"
+
+	"github.com/gear6io/ranger/pkg/errors"
+	"github.com/gear6io/ranger/server/metadata/registry/regtypes"
+	"github.com/uptrace/bun"
+)
+
+// Migration002 adds roles, role membership and privileges for access control
+type Migration002 struct{}
+
+// Version returns the migration version
+func (m *Migration002) Version() int {
+	return 2
+}
+
+// Name returns the migration name
+func (m *Migration002) Name() string {
+	return "access_control"
+}
+
+// Description returns the migration description
+func (m *Migration002) Description() string {
+	return "Roles, role membership, privileges and user password verifiers"
+}
+
+// Up runs the migration
+func (m *Migration002) Up(ctx context.Context, tx bun.Tx) error {
+	// Databases created before this migration have no password_hash column
+	var hasPasswordHash int
+	if err := tx.NewRaw(`SELECT COUNT(*) FROM pragma_table_info('users') WHERE name = 'password_hash'`).Scan(ctx, &hasPasswordHash); err != nil {
+		return errors.New(MigrationTableCreationFailed, "failed to inspect users table", err)
+	}
+	if hasPasswordHash == 0 {
+		if _, err := tx.ExecContext(ctx, `ALTER TABLE users ADD COLUMN password_hash VARCHAR`); err != nil {
+			return errors.New(MigrationTableCreationFailed, "failed to add users.password_hash column", err)
+		}
+	}
+
+	// Roles table
+	if _, err := tx.NewCreateTable().
+		Model((*regtypes.Role)(nil)).
+		Exec(ctx); err != nil {
+		return errors.New(MigrationTableCreationFailed, "failed to create roles table", err)
+	}
+
+	// Role members table (users granted a role)
+	if _, err := tx.NewCreateTable().
+		Model((*regtypes.RoleMember)(nil)).
+		ForeignKey(`("role_id") REFERENCES "roles" ("id") ON DELETE CASCADE`).
+		ForeignKey(`("user_id") REFERENCES "users" ("id") ON DELETE CASCADE`).
+		Exec(ctx); err != nil {
+		return errors.New(MigrationTableCreationFailed, "failed to create role_members table", err)
+	}
+
+	// Privileges table
+	if _, err := tx.NewCreateTable().
+		Model((*regtypes.Privilege)(nil)).
+		Exec(ctx); err != nil {
+		return errors.New(MigrationTableCreationFailed, "failed to create privileges table", err)
+	}
+
+	indexes := []string{
+		`CREATE UNIQUE INDEX IF NOT EXISTS idx_role_members_unique ON role_members(role_id, user_id)`,
+		`CREATE INDEX IF NOT EXISTS idx_role_members_user ON role_members(user_id)`,
+		`CREATE UNIQUE INDEX IF NOT EXISTS idx_privileges_unique ON privileges(grantee_type, grantee, action, database_name, table_name, column_name)`,
+		`CREATE INDEX IF NOT EXISTS idx_privileges_grantee ON privileges(grantee_type, grantee)`,
+	}
+	for _, indexSQL := range indexes {
+		if _, err := tx.ExecContext(ctx, indexSQL); err != nil {
+			return errors.New(MigrationIndexCreationFailed, "failed to create index", err)
+		}
+	}
+
+	now := time.Now()
+
+	// Registry records for the built-in AuthProvider accounts, so privileges apply to them.
+	// system and admin are superusers; default may read and write the default database and
+	// readonly may only read.
+	builtinUsers := []struct {
+		username string
+		isAdmin  bool
+	}{
+		{"admin", true},
+		{"default", false},
+		{"readonly", false},
+	}
+	for _, user := range builtinUsers {
+		if _, err := tx.ExecContext(ctx, `
+			INSERT OR IGNORE INTO users (username, email, display_name, is_active, is_admin, created_at, updated_at)
+			VALUES (?, ?, ?, ?, ?, ?, ?)
+		`, user.username, user.username+"@ranger.local", user.username, true, user.isAdmin, now, now); err != nil {
+			return errors.New(MigrationDataInsertionFailed, "failed to insert built-in user", err)
+		}
+	}
+	if _, err := tx.ExecContext(ctx, `UPDATE users SET is_admin = ? WHERE username = ?`, true, "system"); err != nil {
+		return errors.New(MigrationDataInsertionFailed, "failed to mark system user as admin", err)
+	}
+
+	builtinPrivileges := []struct {
+		grantee  string
+		action   string
+		database string
+	}{
+		{"default", "SELECT", "default"},
+		{"default", "INSERT", "default"},
+		{"default", "UPDATE", "default"},
+		{"default", "DELETE", "default"},
+		{"readonly", "SELECT", regtypes.PrivilegeWildcard},
+	}
+	for _, privilege := range builtinPrivileges {
+		if _, err := tx.ExecContext(ctx, `
+			INSERT OR IGNORE INTO privileges (grantee_type, grantee, action, database_name, table_name, column_name, grantor, created_at, updated_at)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+		`, regtypes.GranteeTypeUser, privilege.grantee, privilege.action, privilege.database, regtypes.PrivilegeWildcard, "", "system", now, now); err != nil {
+			return errors.New(MigrationDataInsertionFailed, "failed to insert built-in privilege", err)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,48 @@
+package migrations
+
+import (
+	"context"
+
+	"github.com/gear6io/ranger/pkg/errors"
+	"github.com/gear6io/ranger/server/metadata/registry/regtypes"
+	"github.com/uptrace/bun"
+)
+
+// Migration003 adds row access and masking policies
+type Migration003 struct{}
+
+// Version returns the migration version
+func (m *Migration003) Version() int {
+	return 3
+}
+
+// Name returns the migration name
+func (m *Migration003) Name() string {
+	return "access_policies"
+}
+
+// Description returns the migration description
+func (m *Migration003) Description() string {
+	return "Row access and column masking policies"
+}
+
+// Up runs the migration
+func (m *Migration003) Up(ctx context.Context, tx bun.Tx) error {
+	if _, err := tx.NewCreateTable().
+		Model((*regtypes.AccessPolicy)(nil)).
+		Exec(ctx); err != nil {
+		return errors.New(MigrationTableCreationFailed, "failed to create access_policies table", err)
+	}
+
+	indexes := []string{
+		`CREATE UNIQUE INDEX IF NOT EXISTS idx_access_policies_name ON access_policies(database_name, table_name, name)`,
+		`CREATE INDEX IF NOT EXISTS idx_access_policies_table ON access_policies(database_name, table_name)`,
+	}
+	for _, indexSQL := range indexes {
+		if _, err := tx.ExecContext(ctx, indexSQL); err != nil {
+			return errors.New(MigrationIndexCreationFailed, "failed to create index", err)
+		}
+	}
+
+	return nil
+}
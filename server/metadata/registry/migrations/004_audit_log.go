@@ -0,0 +1,50 @@
+package migrations
+
+import (
+	"context"
+
+	"github.com/gear6io/ranger/pkg/errors"
+	"github.com/uptrace/bun"
+)
+
+// Migration004 adds the columns written by the audit logger to access_log
+type Migration004 struct{}
+
+// Version returns the migration version
+func (m *Migration004) Version() int {
+	return 4
+}
+
+// Name returns the migration name
+func (m *Migration004) Name() string {
+	return "audit_log"
+}
+
+// Description returns the migration description
+func (m *Migration004) Description() string {
+	return "Username, protocol and error columns for the audit log"
+}
+
+// Up runs the migration
+func (m *Migration004) Up(ctx context.Context, tx bun.Tx) error {
+	// Databases created from the current model already have the columns
+	columns := []string{"username", "protocol", "error"}
+	for _, column := range columns {
+		var exists int
+		if err := tx.NewRaw(`SELECT COUNT(*) FROM pragma_table_info('access_log') WHERE name = ?`, column).Scan(ctx, &exists); err != nil {
+			return errors.New(MigrationTableCreationFailed, "failed to inspect access_log table", err)
+		}
+		if exists > 0 {
+			continue
+		}
+		if _, err := tx.ExecContext(ctx, `ALTER TABLE access_log ADD COLUMN `+column+` VARCHAR`); err != nil {
+			return errors.New(MigrationTableCreationFailed, "failed to add access_log."+column+" column", err).AddContext("column", column)
+		}
+	}
+
+	if _, err := tx.ExecContext(ctx, `CREATE INDEX IF NOT EXISTS idx_access_log_username ON access_log(username)`); err != nil {
+		return errors.New(MigrationIndexCreationFailed, "failed to create index", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,74 @@
+package migrations
+
+import (
+	"context"
+
+	"github.com/gear6io/ranger/pkg/errors"
+	"github.com/gear6io/ranger/server/metadata/registry/regtypes"
+	"github.com/uptrace/bun"
+)
+
+// Migration005 adds password policy columns to users and the auth_tokens table
+type Migration005 struct{}
+
+// Version returns the migration version
+func (m *Migration005) Version() int {
+	return 5
+}
+
+// Name returns the migration name
+func (m *Migration005) Name() string {
+	return "user_store"
+}
+
+// Description returns the migration description
+func (m *Migration005) Description() string {
+	return "Password hashes, lockout and expiry for users, and revocable session tokens"
+}
+
+// Up runs the migration
+func (m *Migration005) Up(ctx context.Context, tx bun.Tx) error {
+	// Databases created from the current model already have the columns
+	columns := []struct {
+		name       string
+		definition string
+	}{
+		{"scram_secret", "VARCHAR"},
+		{"failed_login_attempts", "INTEGER NOT NULL DEFAULT 0"},
+		{"locked_until", "TIMESTAMP"},
+		{"password_expires_at", "TIMESTAMP"},
+	}
+	for _, column := range columns {
+		var exists int
+		if err := tx.NewRaw(`SELECT COUNT(*) FROM pragma_table_info('users') WHERE name = ?`, column.name).Scan(ctx, &exists); err != nil {
+			return errors.New(MigrationTableCreationFailed, "failed to inspect users table", err)
+		}
+		if exists > 0 {
+			continue
+		}
+		if _, err := tx.ExecContext(ctx, `ALTER TABLE users ADD COLUMN `+column.name+` `+column.definition); err != nil {
+			return errors.New(MigrationTableCreationFailed, "failed to add users."+column.name+" column", err).AddContext("column", column.name)
+		}
+	}
+
+	// password_hash used to hold the SCRAM verifier; it now holds argon2id or bcrypt hashes
+	if _, err := tx.ExecContext(ctx, `
+		UPDATE users SET scram_secret = password_hash, password_hash = NULL
+		WHERE password_hash LIKE 'SCRAM-SHA-256$%'
+	`); err != nil {
+		return errors.New(MigrationDataInsertionFailed, "failed to move SCRAM verifiers", err)
+	}
+
+	if _, err := tx.NewCreateTable().
+		Model((*regtypes.AuthToken)(nil)).
+		IfNotExists().
+		Exec(ctx); err != nil {
+		return errors.New(MigrationTableCreationFailed, "failed to create auth_tokens table", err)
+	}
+
+	if _, err := tx.ExecContext(ctx, `CREATE INDEX IF NOT EXISTS idx_auth_tokens_username ON auth_tokens(username)`); err != nil {
+		return errors.New(MigrationIndexCreationFailed, "failed to create index", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,52 @@
+package migrations
+
+import (
+	"context"
+
+	"github.com/gear6io/ranger/pkg/errors"
+	"github.com/uptrace/bun"
+)
+
+// Migration006 records the bloom filters and page indexes written into each data file
+type Migration006 struct{}
+
+// Version returns the migration version
+func (m *Migration006) Version() int {
+	return 6
+}
+
+// Name returns the migration name
+func (m *Migration006) Name() string {
+	return "data_file_indexes"
+}
+
+// Description returns the migration description
+func (m *Migration006) Description() string {
+	return "Bloom filter columns and page index presence of table files"
+}
+
+// Up runs the migration
+func (m *Migration006) Up(ctx context.Context, tx bun.Tx) error {
+	// Databases created from the current model already have the columns
+	columns := []struct {
+		name       string
+		definition string
+	}{
+		{"bloom_filter_columns", "VARCHAR NOT NULL DEFAULT '[]'"},
+		{"has_page_index", "BOOLEAN NOT NULL DEFAULT false"},
+	}
+	for _, column := range columns {
+		var exists int
+		if err := tx.NewRaw(`SELECT COUNT(*) FROM pragma_table_info('table_files') WHERE name = ?`, column.name).Scan(ctx, &exists); err != nil {
+			return errors.New(MigrationTableCreationFailed, "failed to inspect table_files table", err)
+		}
+		if exists > 0 {
+			continue
+		}
+		if _, err := tx.ExecContext(ctx, `ALTER TABLE table_files ADD COLUMN `+column.name+` `+column.definition); err != nil {
+			return errors.New(MigrationTableCreationFailed, "failed to add table_files."+column.name+" column", err).AddContext("column", column.name)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,42 @@
+package migrations
+
+import (
+	"context"
+
+	"github.com/gear6io/ranger/pkg/errors"
+	"github.com/uptrace/bun"
+)
+
+// Migration007 records whether each data file is sorted by the sort key of its table
+type Migration007 struct{}
+
+// Version returns the migration version
+func (m *Migration007) Version() int {
+	return 7
+}
+
+// Name returns the migration name
+func (m *Migration007) Name() string {
+	return "data_file_sort_order"
+}
+
+// Description returns the migration description
+func (m *Migration007) Description() string {
+	return "Sort order of table files"
+}
+
+// Up runs the migration
+func (m *Migration007) Up(ctx context.Context, tx bun.Tx) error {
+	// Databases created from the current model already have the column
+	var exists int
+	if err := tx.NewRaw(`SELECT COUNT(*) FROM pragma_table_info('table_files') WHERE name = 'is_sorted'`).Scan(ctx, &exists); err != nil {
+		return errors.New(MigrationTableCreationFailed, "failed to inspect table_files table", err)
+	}
+	if exists > 0 {
+		return nil
+	}
+	if _, err := tx.ExecContext(ctx, `ALTER TABLE table_files ADD COLUMN is_sorted BOOLEAN NOT NULL DEFAULT false`); err != nil {
+		return errors.New(MigrationTableCreationFailed, "failed to add table_files.is_sorted column", err).AddContext("column", "is_sorted")
+	}
+	return nil
+}
@@ -24,6 +24,33 @@ const (
 	TableTypeExternal  = "external"
 )
 
+// =============================================================================
+// ACCESS CONTROL CONSTANTS
+// =============================================================================
+
+// Grantee type constants for privileges granted to users or roles
+const (
+	GranteeTypeUser = "user"
+	GranteeTypeRole = "role"
+)
+
+// PrivilegeWildcard matches every database or table in a privilege
+const PrivilegeWildcard = "*"
+
+// Access policy kind constants
+const (
+	PolicyKindRowAccess = "row_access"
+	PolicyKindMasking   = "masking"
+)
+
+// Access log status constants, modelled on HTTP status codes
+const (
+	AccessStatusOK              = 200
+	AccessStatusUnauthenticated = 401
+	AccessStatusDenied          = 403
+	AccessStatusFailed          = 500
+)
+
 // =============================================================================
 // STORAGE ENGINE CONSTANTS
 // =============================================================================
@@ -28,12 +28,57 @@ type User struct {
 	IsAdmin     bool       `bun:"is_admin,notnull,default:false" json:"is_admin"`
 	LastLoginAt *time.Time `bun:"last_login_at" json:"last_login_at,omitempty"`
 
+	// PasswordHash is the argon2id or bcrypt hash of the password, never the password itself
+	PasswordHash string `bun:"password_hash" json:"-"`
+	// SCRAMSecret is the SCRAM-SHA-256 verifier used by pgwire clients
+	SCRAMSecret string `bun:"scram_secret" json:"-"`
+
+	FailedLoginAttempts int        `bun:"failed_login_attempts,notnull,default:0" json:"failed_login_attempts"`
+	LockedUntil         *time.Time `bun:"locked_until" json:"locked_until,omitempty"`
+	PasswordExpiresAt   *time.Time `bun:"password_expires_at" json:"password_expires_at,omitempty"`
+
 	TimeAuditable
 
 	// Relations
 	// User can have many databases, tables, and access logs
 }
 
+// UserPassword holds the stored secrets of a password set with CREATE USER or ALTER USER
+type UserPassword struct {
+	Hash        string     // argon2id or bcrypt hash
+	SCRAMSecret string     // SCRAM-SHA-256 verifier
+	ExpiresAt   *time.Time // nil when the password never expires
+}
+
+// HasPassword reports whether the user has a password set
+func (u *User) HasPassword() bool {
+	return u.PasswordHash != "" || u.SCRAMSecret != ""
+}
+
+// IsLocked reports whether the account is locked out at now
+func (u *User) IsLocked(now time.Time) bool {
+	return u.LockedUntil != nil && now.Before(*u.LockedUntil)
+}
+
+// IsPasswordExpired reports whether the password has expired at now
+func (u *User) IsPasswordExpired(now time.Time) bool {
+	return u.PasswordExpiresAt != nil && !now.Before(*u.PasswordExpiresAt)
+}
+
+// AuthToken represents the auth_tokens table. Tokens are stored as SHA-256 hashes so
+// a registry leak does not expose live sessions.
+type AuthToken struct {
+	bun.BaseModel `bun:"table:auth_tokens"`
+
+	ID        int64      `bun:"id,pk,autoincrement" json:"id"`
+	TokenHash string     `bun:"token_hash,notnull,unique" json:"-"`
+	Username  string     `bun:"username,notnull" json:"username"`
+	ExpiresAt time.Time  `bun:"expires_at,notnull" json:"expires_at"`
+	RevokedAt *time.Time `bun:"revoked_at" json:"revoked_at,omitempty"`
+
+	TimeAuditable
+}
+
 // Database represents the databases table for organizing tables
 type Database struct {
 	bun.BaseModel `bun:"table:databases"`
@@ -75,7 +120,8 @@ type Table struct {
 	TimeAuditable
 
 	// Relations
-	Database *Database `bun:"rel:belongs-to,join:database_id=id"`
+	Database *Database      `bun:"rel:belongs-to,join:database_id=id"`
+	Metadata *TableMetadata `bun:"rel:has-one,join:id=table_id"` // Engine and layout settings given at creation
 	// Table has many TableMetadata, TableFile, TableColumn, etc.
 }
 
@@ -183,6 +229,9 @@ type TableFile struct {
 	Checksum             string `bun:"checksum" json:"checksum"`
 	IsCompressed         bool   `bun:"is_compressed,notnull,default:false" json:"is_compressed"`
 	IcebergMetadataState string `bun:"iceberg_metadata_state,notnull,default:'pending'" json:"iceberg_metadata_state"`
+	BloomFilterColumns   string `bun:"bloom_filter_columns,notnull,default:'[]'" json:"bloom_filter_columns"` // JSON array of columns with bloom filters
+	HasPageIndex         bool   `bun:"has_page_index,notnull,default:false" json:"has_page_index"`
+	IsSorted             bool   `bun:"is_sorted,notnull,default:false" json:"is_sorted"` // Rows are ordered by the table sort key
 
 	TimeAuditable
 
@@ -277,6 +326,73 @@ type TableStatistic struct {
 	Table *Table `bun:"rel:belongs-to,join:table_id=id"`
 }
 
+// =============================================================================
+// ACCESS CONTROL TABLES
+// =============================================================================
+
+// Role represents the roles table; a role collects privileges that are granted to users
+type Role struct {
+	bun.BaseModel `bun:"table:roles"`
+
+	ID   int64  `bun:"id,pk,autoincrement" json:"id"`
+	Name string `bun:"name,notnull,unique" json:"name"`
+
+	TimeAuditable
+}
+
+// RoleMember represents the role_members table linking users to the roles granted to them
+type RoleMember struct {
+	bun.BaseModel `bun:"table:role_members"`
+
+	ID     int64 `bun:"id,pk,autoincrement" json:"id"`
+	RoleID int64 `bun:"role_id,notnull" json:"role_id"`
+	UserID int64 `bun:"user_id,notnull" json:"user_id"`
+
+	TimeAuditable
+
+	// Relations
+	Role *Role `bun:"rel:belongs-to,join:role_id=id"`
+	User *User `bun:"rel:belongs-to,join:user_id=id"`
+}
+
+// Privilege represents the privileges table. A privilege is granted to a user or role
+// on every database (Database "*"), a database (Table "*"), a table, or one column.
+// Objects are referenced by name so grants survive dropping and recreating a table.
+type Privilege struct {
+	bun.BaseModel `bun:"table:privileges"`
+
+	ID          int64  `bun:"id,pk,autoincrement" json:"id"`
+	GranteeType string `bun:"grantee_type,notnull" json:"grantee_type"` // GranteeTypeUser or GranteeTypeRole
+	Grantee     string `bun:"grantee,notnull" json:"grantee"`
+	Action      string `bun:"action,notnull" json:"action"`
+	Database    string `bun:"database_name,notnull" json:"database"`
+	Table       string `bun:"table_name,notnull" json:"table"`
+	Column      string `bun:"column_name,notnull" json:"column"` // empty for the whole table
+	Grantor     string `bun:"grantor" json:"grantor"`
+
+	TimeAuditable
+}
+
+// AccessPolicy represents the access_policies table. A row access policy filters the rows
+// of a table with Expression; a masking policy replaces Column with Expression. Grantees
+// are the users and roles a row policy applies to (everyone when empty), or those exempt
+// from a masking policy.
+type AccessPolicy struct {
+	bun.BaseModel `bun:"table:access_policies"`
+
+	ID         int64    `bun:"id,pk,autoincrement" json:"id"`
+	Kind       string   `bun:"kind,notnull" json:"kind"` // PolicyKindRowAccess or PolicyKindMasking
+	Name       string   `bun:"name,notnull" json:"name"`
+	Database   string   `bun:"database_name,notnull" json:"database"`
+	Table      string   `bun:"table_name,notnull" json:"table"`
+	Column     string   `bun:"column_name,notnull" json:"column"` // empty for row access policies
+	Expression string   `bun:"expression,notnull" json:"expression"`
+	Grantees   []string `bun:"grantees,type:varchar,notnull" json:"grantees"` // stored comma-separated
+	CreatedBy  string   `bun:"created_by" json:"created_by"`
+
+	TimeAuditable
+}
+
 // =============================================================================
 // SYSTEM AND AUDIT TABLES
 // =============================================================================
@@ -287,11 +403,14 @@ type AccessLog struct {
 
 	ID        int64  `bun:"id,pk,autoincrement" json:"id"`
 	UserID    *int64 `bun:"user_id" json:"user_id,omitempty"`
+	Username  string `bun:"username" json:"username"`
+	Protocol  string `bun:"protocol" json:"protocol"` // http, jdbc or native
 	Action    string `bun:"action,notnull" json:"action"`
 	Resource  string `bun:"resource,notnull" json:"resource"`
 	IPAddress string `bun:"ip_address" json:"ip_address"`
 	UserAgent string `bun:"user_agent" json:"user_agent"`
-	Status    int    `bun:"status,notnull" json:"status"`
+	Status    int    `bun:"status,notnull" json:"status"` // one of the AccessStatus constants
+	Error     string `bun:"error" json:"error,omitempty"`
 	Duration  int64  `bun:"duration_ms" json:"duration_ms"`
 
 	TimeAuditable
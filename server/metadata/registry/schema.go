@@ -8,6 +8,7 @@ import (
 
 	"github.com/gear6io/ranger/pkg/errors"
 	"github.com/gear6io/ranger/server/metadata/registry/regtypes"
+	"github.com/gear6io/ranger/server/tracing"
 )
 
 // RetrieveAllSchemas fires a single SQL query to get all schema data across all databases
@@ -230,7 +231,10 @@ func (sm *Store) RetrieveAllSchemas(ctx context.Context) (map[string]*SchemaData
 
 // RetrieveSchema loads schema for a specific table and database
 // This is the direct function reference that will be passed to SchemaManager
-func (sm *Store) RetrieveSchema(ctx context.Context, database, tableName string) (*SchemaData, error) {
+func (sm *Store) RetrieveSchema(ctx context.Context, database, tableName string) (schema *SchemaData, err error) {
+	ctx, span := startSpan(ctx, "retrieve_schema", database, tableName)
+	defer func() { tracing.End(span, err) }()
+
 	// Single SQL query to get schema data for specific table
 	query := `
 		SELECT 
@@ -13,7 +13,9 @@ import (
 	"github.com/gear6io/ranger/pkg/errors"
 	"github.com/gear6io/ranger/server/metadata/registry/regtypes"
 	"github.com/gear6io/ranger/server/metadata/registry/system"
+	"github.com/gear6io/ranger/server/tracing"
 	_ "github.com/mattn/go-sqlite3"
+	"go.opentelemetry.io/otel/attribute"
 )
 
 // Package-specific error codes for registry operations
@@ -26,6 +28,13 @@ var (
 	RegistrySchemaVerification  = errors.MustNewCode("registry.schema_verification_failed")
 	RegistryTransactionFailed   = errors.MustNewCode("registry.transaction_failed")
 	RegistryFileOperationFailed = errors.MustNewCode("registry.file_operation_failed")
+	RegistryUserNotFound        = errors.MustNewCode("registry.user_not_found")
+	RegistryUserExists          = errors.MustNewCode("registry.user_exists")
+	RegistryRoleNotFound        = errors.MustNewCode("registry.role_not_found")
+	RegistryRoleExists          = errors.MustNewCode("registry.role_exists")
+	RegistryPolicyNotFound      = errors.MustNewCode("registry.policy_not_found")
+	RegistryPolicyExists        = errors.MustNewCode("registry.policy_exists")
+	RegistryTokenNotFound       = errors.MustNewCode("registry.token_not_found")
 )
 
 // Store implements metadata storage using SQLite with bun migrations
@@ -115,11 +124,15 @@ func (sm *Store) IsUsingBun() bool {
 }
 
 // GetPendingFilesForIceberg returns files that need Iceberg metadata generation
-func (sm *Store) GetPendingFilesForIceberg(ctx context.Context) ([]*regtypes.TableFile, error) {
+func (sm *Store) GetPendingFilesForIceberg(ctx context.Context) (_ []*regtypes.TableFile, err error) {
+	ctx, span := startSpan(ctx, "pending_files", "", "")
+	defer func() { tracing.End(span, err) }()
+
 	query := `
 		SELECT 
 			tf.id, tf.table_id, tf.file_name, tf.file_path, tf.file_size, tf.file_type,
 			tf.partition_path, tf.row_count, tf.checksum, tf.is_compressed,
+			tf.bloom_filter_columns, tf.has_page_index, tf.is_sorted,
 			tf.created_at, tf.updated_at, tf.iceberg_metadata_state
 		FROM table_files tf
 		WHERE tf.iceberg_metadata_state IN ('pending', 'failed')
@@ -138,6 +151,7 @@ func (sm *Store) GetPendingFilesForIceberg(ctx context.Context) ([]*regtypes.Tab
 		err := rows.Scan(
 			&file.ID, &file.TableID, &file.FileName, &file.FilePath, &file.FileSize, &file.FileType,
 			&file.PartitionPath, &file.RowCount, &file.Checksum, &file.IsCompressed,
+			&file.BloomFilterColumns, &file.HasPageIndex, &file.IsSorted,
 			&file.CreatedAt, &file.UpdatedAt, &file.IcebergMetadataState,
 		)
 		if err != nil {
@@ -226,7 +240,10 @@ func (sm *Store) DatabaseExists(ctx context.Context, dbName string) bool {
 }
 
 // CreateTable creates a new table with complete metadata in a single transaction
-func (sm *Store) CreateTable(ctx context.Context, database, tableName string, schema []byte, storageEngine string, engineConfig map[string]interface{}) (*TableMetadata, error) {
+func (sm *Store) CreateTable(ctx context.Context, database, tableName string, schema []byte, storageEngine string, engineConfig map[string]interface{}) (metadata *TableMetadata, err error) {
+	ctx, span := startSpan(ctx, "create_table", database, tableName)
+	defer func() { tracing.End(span, err) }()
+
 	// Check if database exists
 	if !sm.DatabaseExists(ctx, database) {
 		return nil, errors.New(RegistryDatabaseNotFound, "database does not exist", nil).AddContext("database", database)
@@ -235,7 +252,7 @@ func (sm *Store) CreateTable(ctx context.Context, database, tableName string, sc
 	// Get database ID
 	var dbID int64
 	query := `SELECT id FROM databases WHERE name = ?`
-	err := sm.db.QueryRowContext(ctx, query, database).Scan(&dbID)
+	err = sm.db.QueryRowContext(ctx, query, database).Scan(&dbID)
 	if err != nil {
 		return nil, errors.New(errors.CommonInternal, "failed to get database ID", err).AddContext("database", database)
 	}
@@ -381,9 +398,10 @@ func (sm *Store) CreateTableWithColumns(ctx context.Context, databaseName string
 		}
 	}
 
-	// Create table metadata record with default values
-	insertMetadataSQL := `INSERT INTO table_metadata (table_id, schema_version, storage_engine, engine_config, format, compression, last_modified, created_at, updated_at) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`
-	_, err = tx.ExecContext(ctx, insertMetadataSQL, tableID, 1, "iceberg", "{}", "parquet", "snappy", now, now, now)
+	// Create table metadata record from the settings given with the table, or default values
+	metadata := tableMetadataWithDefaults(table.Metadata)
+	insertMetadataSQL := `INSERT INTO table_metadata (table_id, schema_version, storage_engine, engine_config, format, compression, sort_order, sort_strategy, settings, last_modified, created_at, updated_at) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`
+	_, err = tx.ExecContext(ctx, insertMetadataSQL, tableID, 1, metadata.StorageEngine, metadata.EngineConfig, metadata.Format, metadata.Compression, metadata.SortOrder, metadata.SortStrategy, metadata.Settings, now, now, now)
 	if err != nil {
 		return 0, errors.New(errors.CommonInternal, "failed to create table metadata", err).AddContext("table", table.Name)
 	}
@@ -409,12 +427,54 @@ func (sm *Store) CreateTableWithColumns(ctx context.Context, databaseName string
 	return tableID, nil
 }
 
+// tableMetadataWithDefaults returns the table metadata given at creation with its unset
+// fields filled with defaults
+func tableMetadataWithDefaults(given *regtypes.TableMetadata) regtypes.TableMetadata {
+	metadata := regtypes.TableMetadata{
+		StorageEngine: "iceberg",
+		EngineConfig:  "{}",
+		Format:        "parquet",
+		Compression:   "snappy",
+		SortOrder:     "[]",
+		SortStrategy:  "asc",
+		Settings:      "{}",
+	}
+	if given == nil {
+		return metadata
+	}
+	if given.StorageEngine != "" {
+		metadata.StorageEngine = given.StorageEngine
+	}
+	if given.EngineConfig != "" {
+		metadata.EngineConfig = given.EngineConfig
+	}
+	if given.Format != "" {
+		metadata.Format = given.Format
+	}
+	if given.Compression != "" {
+		metadata.Compression = given.Compression
+	}
+	if given.SortOrder != "" {
+		metadata.SortOrder = given.SortOrder
+	}
+	if given.SortStrategy != "" {
+		metadata.SortStrategy = given.SortStrategy
+	}
+	if given.Settings != "" {
+		metadata.Settings = given.Settings
+	}
+	return metadata
+}
+
 // DropTable drops a table from the specified database
-func (sm *Store) DropTable(ctx context.Context, dbName, tableName string) error {
+func (sm *Store) DropTable(ctx context.Context, dbName, tableName string) (err error) {
+	ctx, span := startSpan(ctx, "drop_table", dbName, tableName)
+	defer func() { tracing.End(span, err) }()
+
 	// Get database ID
 	var dbID int64
 	query := `SELECT id FROM databases WHERE name = ?`
-	err := sm.db.QueryRowContext(ctx, query, dbName).Scan(&dbID)
+	err = sm.db.QueryRowContext(ctx, query, dbName).Scan(&dbID)
 	if err != nil {
 		return errors.New(errors.CommonInternal, "failed to get database ID", err).AddContext("database", dbName)
 	}
@@ -494,14 +554,17 @@ func (sm *Store) TableExists(ctx context.Context, dbName, tableName string) bool
 }
 
 // LoadTableMetadata loads detailed metadata for a table
-func (sm *Store) LoadTableMetadata(ctx context.Context, database, tableName string) (*TableMetadata, error) {
+func (sm *Store) LoadTableMetadata(ctx context.Context, database, tableName string) (metadata *TableMetadata, err error) {
+	ctx, span := startSpan(ctx, "load_table_metadata", database, tableName)
+	defer func() { tracing.End(span, err) }()
+
 	query := `SELECT tm.storage_engine, tm.engine_config, tm.last_modified, tm.created_at FROM table_metadata tm JOIN tables t ON tm.table_id = t.id JOIN databases d ON t.database_id = d.id WHERE d.name = ? AND t.name = ?`
 	row := sm.db.QueryRowContext(ctx, query, database, tableName)
 
 	var storageEngine, engineConfig string
 	var lastModified, createdAt time.Time
 
-	err := row.Scan(&storageEngine, &engineConfig, &lastModified, &createdAt)
+	err = row.Scan(&storageEngine, &engineConfig, &lastModified, &createdAt)
 	if err != nil {
 		return nil, errors.New(errors.CommonInternal, "failed to load table metadata", err).AddContext("database", database).AddContext("table", tableName)
 	}
@@ -556,8 +619,17 @@ func (sm *Store) ListAllTables(ctx context.Context) ([]string, error) {
 }
 
 // UpdateTableAfterInsertion performs all metadata updates after successful data insertion
-// This method atomically updates table files, statistics, and triggers CDC events
-func (sm *Store) UpdateTableAfterInsertion(ctx context.Context, database, tableName string, fileInfo FileInsertionInfo) error {
+// This method atomically registers the files of one insert, updates statistics, and triggers
+// CDC events, so that either every file is registered or none is
+func (sm *Store) UpdateTableAfterInsertion(ctx context.Context, database, tableName string, files ...FileInsertionInfo) (err error) {
+	ctx, span := startSpan(ctx, "update_table_after_insertion", database, tableName)
+	var rows int64
+	for _, fileInfo := range files {
+		rows += fileInfo.RowCount
+	}
+	span.SetAttributes(attribute.Int64("ranger.rows", rows), attribute.Int("ranger.files", len(files)))
+	defer func() { tracing.End(span, err) }()
+
 	// Start a transaction for atomic updates
 	tx, err := sm.db.BeginTx(ctx, nil)
 	if err != nil {
@@ -572,31 +644,51 @@ func (sm *Store) UpdateTableAfterInsertion(ctx context.Context, database, tableN
 		return errors.New(errors.CommonInternal, "failed to get table ID", err).AddContext("database", database).AddContext("table", tableName)
 	}
 
-	// 1. Insert table file record
 	now := time.Now().Format("2006-01-02 15:04:05")
-	insertFileSQL := `INSERT INTO table_files (table_id, file_name, file_path, file_size, file_type, partition_path, row_count, checksum, is_compressed, created_at, updated_at, iceberg_metadata_state) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`
+	insertFileSQL := `INSERT INTO table_files (table_id, file_name, file_path, file_size, file_type, partition_path, row_count, checksum, is_compressed, bloom_filter_columns, has_page_index, is_sorted, created_at, updated_at, iceberg_metadata_state) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`
+	var addedRows, addedSize int64
+	for _, fileInfo := range files {
+		// 1. Insert table file record
+		bloomFilterColumns := fileInfo.BloomFilterColumns
+		if bloomFilterColumns == nil {
+			bloomFilterColumns = []string{}
+		}
+		bloomFilterJSON, err := json.Marshal(bloomFilterColumns)
+		if err != nil {
+			return errors.New(errors.CommonInternal, "failed to encode bloom filter columns", err).AddContext("table", tableName)
+		}
+
+		_, err = tx.ExecContext(ctx, insertFileSQL,
+			tableID,
+			fileInfo.FileName,
+			fileInfo.FilePath,
+			fileInfo.FileSize,
+			fileInfo.FileType,
+			fileInfo.PartitionPath,
+			fileInfo.RowCount,
+			fileInfo.Checksum,
+			fileInfo.IsCompressed,
+			string(bloomFilterJSON),
+			fileInfo.PageIndex,
+			fileInfo.Sorted,
+			now,
+			now,
+			regtypes.IcebergMetadataGenerationStatePending)
 
-	_, err = tx.ExecContext(ctx, insertFileSQL,
-		tableID,
-		fileInfo.FileName,
-		fileInfo.FilePath,
-		fileInfo.FileSize,
-		fileInfo.FileType,
-		fileInfo.PartitionPath,
-		fileInfo.RowCount,
-		fileInfo.Checksum,
-		fileInfo.IsCompressed,
-		now,
-		now,
-		regtypes.IcebergMetadataGenerationStatePending)
+		if err != nil {
+			return errors.New(errors.CommonInternal, "failed to insert table file", err).AddContext("table", tableName).AddContext("file", fileInfo.FileName)
+		}
 
-	if err != nil {
-		return errors.New(errors.CommonInternal, "failed to insert table file", err).AddContext("table", tableName)
+		// Relocated rows were counted when they were inserted
+		if !fileInfo.Relocated {
+			addedRows += fileInfo.RowCount
+		}
+		addedSize += fileInfo.FileSize
 	}
 
 	// 2. Update table statistics
-	updateStatsSQL := `UPDATE tables SET row_count = row_count + ?, file_count = file_count + 1, total_size = total_size + ?, updated_at = ? WHERE id = ?`
-	_, err = tx.ExecContext(ctx, updateStatsSQL, fileInfo.RowCount, fileInfo.FileSize, now, tableID)
+	updateStatsSQL := `UPDATE tables SET row_count = row_count + ?, file_count = file_count + ?, total_size = total_size + ?, updated_at = ? WHERE id = ?`
+	_, err = tx.ExecContext(ctx, updateStatsSQL, addedRows, len(files), addedSize, now, tableID)
 	if err != nil {
 		return errors.New(errors.CommonInternal, "failed to update table statistics", err).AddContext("table", tableName)
 	}
@@ -609,6 +701,73 @@ func (sm *Store) UpdateTableAfterInsertion(ctx context.Context, database, tableN
 	return nil
 }
 
+// MergeTableSettings merges settings into the SETTINGS recorded for a table, replacing the
+// values of settings it already has, and returns the merged settings
+func (sm *Store) MergeTableSettings(ctx context.Context, database, tableName string, settings map[string]interface{}) (merged map[string]interface{}, err error) {
+	ctx, span := startSpan(ctx, "merge_table_settings", database, tableName)
+	defer func() { tracing.End(span, err) }()
+
+	tx, err := sm.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, errors.New(RegistryTransactionFailed, "failed to begin transaction", err)
+	}
+	defer tx.Rollback()
+
+	var tableID int64
+	var current sql.NullString
+	settingsQuery := `SELECT t.id, tm.settings FROM tables t JOIN databases d ON t.database_id = d.id JOIN table_metadata tm ON tm.table_id = t.id WHERE d.name = ? AND t.name = ?`
+	if err := tx.QueryRowContext(ctx, settingsQuery, database, tableName).Scan(&tableID, &current); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, errors.New(RegistryTableNotFound, "table does not exist in database", nil).AddContext("database", database).AddContext("table", tableName)
+		}
+		return nil, errors.New(errors.CommonInternal, "failed to get table settings", err).AddContext("database", database).AddContext("table", tableName)
+	}
+
+	merged = make(map[string]interface{}, len(settings))
+	if current.Valid && current.String != "" {
+		if err := json.Unmarshal([]byte(current.String), &merged); err != nil {
+			return nil, errors.New(errors.CommonInternal, "failed to decode table settings", err).AddContext("table", tableName)
+		}
+	}
+	for key, value := range settings {
+		merged[key] = value
+	}
+	mergedJSON, err := json.Marshal(merged)
+	if err != nil {
+		return nil, errors.New(errors.CommonInternal, "failed to encode table settings", err).AddContext("table", tableName)
+	}
+
+	now := time.Now().Format("2006-01-02 15:04:05")
+	updateSQL := `UPDATE table_metadata SET settings = ?, last_modified = ?, updated_at = ? WHERE table_id = ?`
+	if _, err := tx.ExecContext(ctx, updateSQL, string(mergedJSON), now, now, tableID); err != nil {
+		return nil, errors.New(errors.CommonInternal, "failed to update table settings", err).AddContext("table", tableName)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, errors.New(RegistryTransactionFailed, "failed to commit transaction", err)
+	}
+	return merged, nil
+}
+
+// AddTableRows counts rows inserted into a table whose engine keeps them itself rather than in
+// data files, such as a memory table
+func (sm *Store) AddTableRows(ctx context.Context, database, tableName string, rows int64) (err error) {
+	ctx, span := startSpan(ctx, "add_table_rows", database, tableName)
+	span.SetAttributes(attribute.Int64("ranger.rows", rows))
+	defer func() { tracing.End(span, err) }()
+
+	now := time.Now().Format("2006-01-02 15:04:05")
+	updateStatsSQL := `UPDATE tables SET row_count = row_count + ?, updated_at = ? WHERE id = (SELECT t.id FROM tables t JOIN databases d ON t.database_id = d.id WHERE d.name = ? AND t.name = ?)`
+	result, err := sm.db.ExecContext(ctx, updateStatsSQL, rows, now, database, tableName)
+	if err != nil {
+		return errors.New(errors.CommonInternal, "failed to update table statistics", err).AddContext("database", database).AddContext("table", tableName)
+	}
+	if updated, err := result.RowsAffected(); err == nil && updated == 0 {
+		return errors.New(RegistryTableNotFound, "table does not exist in database", nil).AddContext("database", database).AddContext("table", tableName)
+	}
+	return nil
+}
+
 // FileInsertionInfo contains all information needed for post-insertion metadata updates
 type FileInsertionInfo struct {
 	FileName      string
@@ -619,6 +778,13 @@ type FileInsertionInfo struct {
 	RowCount      int64
 	Checksum      string
 	IsCompressed  bool
+	Relocated     bool // Rows already in the table, moved to this file, such as those a memory table spills
+
+	// Indexes written into the file
+	BloomFilterColumns []string
+	PageIndex          bool
+
+	Sorted bool // Rows are ordered by the table sort key
 }
 
 // loadTableFiles loads file information for a table
@@ -681,6 +847,7 @@ func (sm *Store) GetCompleteTableInfoByID(ctx context.Context, tableID int64) (*
 			d.name as database_name,
 			tm.schema_version, tm.storage_engine, tm.engine_config,
 			tm.format, tm.compression, tm.partition_by, tm.sort_by, tm.settings,
+			tm.sort_order, tm.sort_strategy,
 			tm.last_modified, tm.created_at as metadata_created, tm.updated_at as metadata_updated
 		FROM tables t
 		JOIN databases d ON t.database_id = d.id
@@ -694,6 +861,7 @@ func (sm *Store) GetCompleteTableInfoByID(ctx context.Context, tableID int64) (*
 	var dbName string
 	var schemaVersion int
 	var storageEngine, engineConfig, format, compression, partitionBy, sortBy, settings string
+	var sortOrder, sortStrategy string
 	var lastModified, metadataCreated, metadataUpdated time.Time
 
 	err := row.Scan(
@@ -703,6 +871,7 @@ func (sm *Store) GetCompleteTableInfoByID(ctx context.Context, tableID int64) (*
 		&dbName,
 		&schemaVersion, &storageEngine, &engineConfig,
 		&format, &compression, &partitionBy, &sortBy, &settings,
+		&sortOrder, &sortStrategy,
 		&lastModified, &metadataCreated, &metadataUpdated,
 	)
 
@@ -729,6 +898,8 @@ func (sm *Store) GetCompleteTableInfoByID(ctx context.Context, tableID int64) (*
 			PartitionBy:   partitionBy,
 			SortBy:        sortBy,
 			Settings:      settings, // Renamed from Properties
+			SortOrder:     sortOrder,
+			SortStrategy:  sortStrategy,
 			LastModified:  lastModified,
 		}
 
@@ -854,6 +1025,35 @@ func (sm *Store) GetTable(ctx context.Context, databaseName, tableName string) (
 	return &table, nil
 }
 
+// GetUser retrieves a user from the users table by username
+func (sm *Store) GetUser(ctx context.Context, username string) (*regtypes.User, error) {
+	query := `
+		SELECT id, username, email, display_name, is_active, is_admin, last_login_at, password_hash, scram_secret,
+			failed_login_attempts, locked_until, password_expires_at, created_at, updated_at
+		FROM users
+		WHERE username = ?
+	`
+
+	var user regtypes.User
+	var displayName, passwordHash, scramSecret sql.NullString
+	err := sm.db.QueryRowContext(ctx, query, username).Scan(
+		&user.ID, &user.Username, &user.Email, &displayName, &user.IsActive, &user.IsAdmin,
+		&user.LastLoginAt, &passwordHash, &scramSecret,
+		&user.FailedLoginAttempts, &user.LockedUntil, &user.PasswordExpiresAt, &user.CreatedAt, &user.UpdatedAt,
+	)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, errors.New(RegistryUserNotFound, "user not found", nil).AddContext("username", username)
+		}
+		return nil, errors.New(errors.CommonInternal, "failed to get user", err).AddContext("username", username)
+	}
+	user.DisplayName = displayName.String
+	user.PasswordHash = passwordHash.String
+	user.SCRAMSecret = scramSecret.String
+
+	return &user, nil
+}
+
 // GetSystemManager returns the system database manager
 func (sm *Store) GetSystemManager() *system.Manager {
 	return sm.system
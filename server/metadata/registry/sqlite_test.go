@@ -140,6 +140,23 @@ func TestStoreWithProductionSchema(t *testing.T) {
 		assert.False(t, store.TableExists(ctx, "testdb", "droptable"))
 	})
 
+	t.Run("MergeTableSettings", func(t *testing.T) {
+		_, err := store.CreateTable(ctx, "testdb", "settingstable", []byte("{}"), "memory", nil)
+		require.NoError(t, err)
+
+		merged, err := store.MergeTableSettings(ctx, "testdb", "settingstable", map[string]interface{}{"hot_tier_mb": 8, "durability": "wal"})
+		require.NoError(t, err)
+		assert.Len(t, merged, 2)
+
+		// Later settings replace earlier values and keep the others
+		merged, err = store.MergeTableSettings(ctx, "testdb", "settingstable", map[string]interface{}{"hot_tier_mb": 16})
+		require.NoError(t, err)
+		assert.Equal(t, map[string]interface{}{"hot_tier_mb": 16, "durability": "wal"}, merged)
+
+		_, err = store.MergeTableSettings(ctx, "testdb", "missing", map[string]interface{}{"hot_tier_mb": 1})
+		assert.Error(t, err)
+	})
+
 	t.Run("DropDatabase", func(t *testing.T) {
 		// Create a database to drop
 		err := store.CreateDatabase(ctx, "dropdb")
@@ -155,6 +172,17 @@ func TestStoreWithProductionSchema(t *testing.T) {
 		// Verify it no longer exists
 		assert.False(t, store.DatabaseExists(ctx, "dropdb"))
 	})
+
+	t.Run("GetUser", func(t *testing.T) {
+		// The migration seeds the system user
+		user, err := store.GetUser(ctx, "system")
+		require.NoError(t, err)
+		assert.Equal(t, "system", user.Username)
+		assert.True(t, user.IsActive)
+
+		_, err = store.GetUser(ctx, "nobody")
+		assert.Error(t, err)
+	})
 }
 
 func TestMigrationSystem(t *testing.T) {
@@ -244,3 +272,40 @@ func TestProductionSchemaConstraintsInStore(t *testing.T) {
 		assert.Error(t, err)
 	})
 }
+
+func TestUpdateTableAfterInsertionIsAtomic(t *testing.T) {
+	tempDir := t.TempDir()
+	store, err := NewStore(filepath.Join(tempDir, "test.db"), filepath.Join(tempDir, "data"))
+	require.NoError(t, err)
+	defer store.Close()
+
+	ctx := context.Background()
+	require.NoError(t, store.CreateDatabase(ctx, "testdb"))
+	_, err = store.CreateTable(ctx, "testdb", "events", []byte("{}"), "memory", nil)
+	require.NoError(t, err)
+
+	countFiles := func() (files, rows int64) {
+		require.NoError(t, store.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM table_files`).Scan(&files))
+		require.NoError(t, store.db.QueryRowContext(ctx, `SELECT row_count FROM tables WHERE name = 'events'`).Scan(&rows))
+		return files, rows
+	}
+	initialFiles, _ := countFiles()
+
+	require.NoError(t, store.UpdateTableAfterInsertion(ctx, "testdb", "events",
+		FileInsertionInfo{FileName: "a.parquet", FilePath: "a.parquet", FileType: "parquet", RowCount: 2},
+		FileInsertionInfo{FileName: "b.parquet", FilePath: "b.parquet", FileType: "parquet", RowCount: 3}))
+	files, rows := countFiles()
+	assert.Equal(t, initialFiles+2, files)
+	assert.Equal(t, int64(5), rows)
+
+	// A file that cannot be registered leaves the other files of the insert unregistered
+	_, err = store.db.ExecContext(ctx, `CREATE TRIGGER reject_file BEFORE INSERT ON table_files
+		WHEN NEW.file_name = 'bad.parquet' BEGIN SELECT RAISE(ABORT, 'rejected'); END`)
+	require.NoError(t, err)
+	require.Error(t, store.UpdateTableAfterInsertion(ctx, "testdb", "events",
+		FileInsertionInfo{FileName: "c.parquet", FilePath: "c.parquet", FileType: "parquet", RowCount: 4},
+		FileInsertionInfo{FileName: "bad.parquet", FilePath: "bad.parquet", FileType: "parquet", RowCount: 1}))
+	files, rows = countFiles()
+	assert.Equal(t, initialFiles+2, files)
+	assert.Equal(t, int64(5), rows)
+}
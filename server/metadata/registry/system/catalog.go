@@ -0,0 +1,507 @@
+package system
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/gear6io/ranger/pkg/errors"
+)
+
+// Catalog schemas emulated on top of the registry for PostgreSQL clients
+const (
+	PgCatalogSchema         = "pg_catalog"
+	InformationSchemaSchema = "information_schema"
+	SystemSchema            = "system"
+
+	// CatalogDatabaseName is the single PostgreSQL database Ranger reports;
+	// Ranger databases are exposed as schemas inside it
+	CatalogDatabaseName = "ranger"
+
+	// CatalogServerVersion is reported by version() and matches the pgwire startup parameters
+	CatalogServerVersion = "PostgreSQL 14.1 (Ranger)"
+)
+
+var (
+	// qualifiedRelationPattern matches schema-qualified relations such as pg_catalog.pg_class
+	qualifiedRelationPattern = regexp.MustCompile(`(?i)\b(pg_catalog|information_schema|system)\s*\.\s*"?([a-z_][a-z0-9_]*)"?`)
+
+	// unqualifiedRelationPattern matches pg_catalog relations, which are always on the search path
+	unqualifiedRelationPattern = regexp.MustCompile(`(?i)\b(pg_namespace|pg_class|pg_attribute|pg_type|pg_database)\b`)
+
+	// sessionFunctionPattern matches the session information functions BI tools probe on connect
+	sessionFunctionPattern = regexp.MustCompile(`(?i)\b(?:pg_catalog\s*\.\s*)?(version|current_schema|current_database|current_user|session_user)\s*\(\s*\)`)
+
+	// typeCastPattern matches PostgreSQL-style casts (::regclass, ::text[]) which SQLite does not understand
+	typeCastPattern = regexp.MustCompile(`(?i)::\s*"?[a-z_][a-z0-9_]*"?(\[\])?`)
+)
+
+// catalogRelations are the relations the emulation answers, by schema. pg_catalog
+// relations may also be named without their schema.
+var catalogRelations = map[string]map[string]bool{
+	PgCatalogSchema: {
+		"pg_namespace": true, "pg_class": true, "pg_attribute": true, "pg_type": true, "pg_database": true,
+	},
+	InformationSchemaSchema: {"schemata": true, "tables": true, "columns": true},
+}
+
+// aliasStopWords are the keywords that may follow a relation and are never its alias
+var aliasStopWords = map[string]bool{
+	"where": true, "join": true, "inner": true, "left": true, "right": true, "full": true, "outer": true,
+	"cross": true, "natural": true, "on": true, "using": true, "group": true, "order": true, "having": true,
+	"limit": true, "offset": true, "union": true, "except": true, "intersect": true, "window": true,
+	"fetch": true, "for": true,
+}
+
+// CatalogSession carries the session values returned by catalog functions and the
+// objects the session may see
+type CatalogSession struct {
+	Database string
+	User     string
+
+	// Visible reports whether the session may see a database (table and column empty),
+	// any part of a table (column empty) or a column. A nil Visible shows every object.
+	Visible func(database, table, column string) bool
+}
+
+// IsCatalogQuery checks if a query is a single SELECT that reads nothing but the emulated
+// pg_catalog and information_schema relations, or only calls the session information
+// functions. Queries naming any other relation are not catalog queries.
+func (m *Manager) IsCatalogQuery(query string) bool {
+	relations, ok := m.CatalogRelations(query)
+	if !ok {
+		return false
+	}
+	if len(relations) > 0 {
+		return true
+	}
+	found := false
+	forEachUnquoted(query, func(segment string) string {
+		found = found || sessionFunctionPattern.MatchString(segment)
+		return segment
+	})
+	return found
+}
+
+// CatalogRelations returns the catalog relations a query reads as schema.relation. It
+// reports false unless the query is a single SELECT whose every relation is a catalog one.
+func (m *Manager) CatalogRelations(query string) ([]string, bool) {
+	tokens, ok := scanSQL(query)
+	for ok && len(tokens) > 0 && tokens[len(tokens)-1].text == ";" {
+		tokens = tokens[:len(tokens)-1]
+	}
+	if !ok || len(tokens) == 0 {
+		return nil, false
+	}
+	if first := tokens[0].keyword(); first != "select" && first != "with" {
+		return nil, false
+	}
+
+	// Common table expressions may be read like relations
+	ctes := make(map[string]bool)
+	for i, token := range tokens {
+		if token.text == ";" {
+			return nil, false
+		}
+		if token.identifier && i+2 < len(tokens) && tokens[i+1].keyword() == "as" && tokens[i+2].text == "(" {
+			ctes[strings.ToLower(token.text)] = true
+		}
+	}
+
+	var relations []string
+	seen := make(map[string]bool)
+	for i, token := range tokens {
+		keyword := token.keyword()
+		if keyword != "from" && keyword != "join" && keyword != "in" {
+			continue
+		}
+		// IN (...) lists and subqueries are scanned with the rest of the query, but
+		// SQLite also accepts a bare relation after IN
+		if keyword == "in" && (i+1 >= len(tokens) || !tokens[i+1].identifier) {
+			continue
+		}
+		for next := i + 1; ; next++ {
+			relation, end, ok := readCatalogRelation(tokens, next, ctes)
+			if !ok {
+				return nil, false
+			}
+			if relation != "" && !seen[relation] {
+				seen[relation] = true
+				relations = append(relations, relation)
+			}
+			if next = skipAlias(tokens, end); keyword == "in" || next >= len(tokens) || tokens[next].text != "," {
+				break
+			}
+		}
+	}
+	return relations, true
+}
+
+// readCatalogRelation reads the relation starting at tokens[i], returning it as
+// schema.relation ("" for subqueries and common table expressions) with the index after
+// it. It reports false for relations outside the catalog and for table functions.
+func readCatalogRelation(tokens []sqlToken, i int, ctes map[string]bool) (string, int, bool) {
+	if i >= len(tokens) {
+		return "", i, false
+	}
+	if tokens[i].text == "(" {
+		end, ok := closingParen(tokens, i)
+		return "", end + 1, ok
+	}
+	if !tokens[i].identifier {
+		return "", i, false
+	}
+
+	schema, name, end := "", strings.ToLower(tokens[i].text), i+1
+	if end+1 < len(tokens) && tokens[end].text == "." && tokens[end+1].identifier {
+		schema, name, end = name, strings.ToLower(tokens[end+1].text), end+2
+	}
+	if end < len(tokens) && tokens[end].text == "(" {
+		return "", end, false
+	}
+
+	switch {
+	case schema == "" && ctes[name]:
+		return "", end, true
+	case schema == "" && catalogRelations[PgCatalogSchema][name]:
+		return PgCatalogSchema + "." + name, end, true
+	case catalogRelations[schema][name]:
+		return schema + "." + name, end, true
+	}
+	return "", end, false
+}
+
+// skipAlias returns the index after the alias of a relation, if it has one
+func skipAlias(tokens []sqlToken, i int) int {
+	if i < len(tokens) && tokens[i].keyword() == "as" {
+		i++
+		if i < len(tokens) && tokens[i].identifier {
+			i++
+		}
+		return i
+	}
+	if i < len(tokens) && tokens[i].identifier && !aliasStopWords[tokens[i].keyword()] {
+		i++
+	}
+	return i
+}
+
+// closingParen returns the index of the parenthesis closing the one at tokens[i]
+func closingParen(tokens []sqlToken, i int) (int, bool) {
+	depth := 0
+	for ; i < len(tokens); i++ {
+		switch tokens[i].text {
+		case "(":
+			depth++
+		case ")":
+			if depth--; depth == 0 {
+				return i, true
+			}
+		}
+	}
+	return i, false
+}
+
+// sqlToken is a word, quoted identifier, literal or punctuation character of a query
+type sqlToken struct {
+	text       string
+	identifier bool
+	quoted     bool
+}
+
+// keyword returns the lower-cased text of an unquoted word, or "" for other tokens
+func (t sqlToken) keyword() string {
+	if !t.identifier || t.quoted {
+		return ""
+	}
+	return strings.ToLower(t.text)
+}
+
+// scanSQL splits a query into tokens, dropping comments and whitespace. Identifiers quoted
+// with double quotes, backticks or brackets are returned unquoted. It reports false for
+// unterminated literals, identifiers and comments.
+func scanSQL(query string) ([]sqlToken, bool) {
+	var tokens []sqlToken
+	for i := 0; i < len(query); {
+		c := query[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == '\f':
+			i++
+		case strings.HasPrefix(query[i:], "--"):
+			end := strings.IndexByte(query[i:], '\n')
+			if end < 0 {
+				return tokens, true
+			}
+			i += end + 1
+		case strings.HasPrefix(query[i:], "/*"):
+			end := strings.Index(query[i+2:], "*/")
+			if end < 0 {
+				return nil, false
+			}
+			i += end + 4
+		case c == '\'':
+			end := i + 1
+			for ; end < len(query); end++ {
+				if query[end] != '\'' {
+					continue
+				}
+				// A doubled quote is an escaped quote inside the literal
+				if end+1 < len(query) && query[end+1] == '\'' {
+					end++
+					continue
+				}
+				break
+			}
+			if end >= len(query) {
+				return nil, false
+			}
+			tokens = append(tokens, sqlToken{text: query[i : end+1]})
+			i = end + 1
+		case c == '"' || c == '`' || c == '[':
+			closing := map[byte]byte{'"': '"', '`': '`', '[': ']'}[c]
+			end := strings.IndexByte(query[i+1:], closing)
+			if end < 0 {
+				return nil, false
+			}
+			tokens = append(tokens, sqlToken{text: query[i+1 : i+1+end], identifier: true, quoted: true})
+			i += end + 2
+		case isIdentifierByte(c):
+			end := i + 1
+			for end < len(query) && isIdentifierByte(query[end]) {
+				end++
+			}
+			tokens = append(tokens, sqlToken{text: query[i:end], identifier: true})
+			i = end
+		default:
+			tokens = append(tokens, sqlToken{text: query[i : i+1]})
+			i++
+		}
+	}
+	return tokens, true
+}
+
+// isIdentifierByte reports whether c may appear in an unquoted identifier or number
+func isIdentifierByte(c byte) bool {
+	return c == '_' || c == '$' || c >= 0x80 ||
+		(c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || (c >= '0' && c <= '9')
+}
+
+// QueryCatalog executes a PostgreSQL catalog query against the registry views, showing
+// only the databases, tables and columns the session may see
+func (m *Manager) QueryCatalog(ctx context.Context, query string, session *CatalogSession) (*QueryResult, error) {
+	rewritten, functionColumns := rewriteCatalogQuery(query, session)
+
+	if session != nil && session.Visible != nil {
+		scope, err := m.catalogScope(ctx, session.Visible)
+		if err != nil {
+			return nil, err
+		}
+		rewritten = withCatalogScope(rewritten, scope)
+	}
+
+	result, err := m.Query(ctx, rewritten)
+	if err != nil {
+		return nil, err
+	}
+
+	// SQLite names a bare literal column after its text, PostgreSQL after the function
+	for i, column := range result.Columns {
+		if name, ok := functionColumns[column]; ok {
+			result.Columns[i] = name
+		}
+	}
+
+	return result, nil
+}
+
+// catalogScope returns common table expressions that shadow the catalog views with
+// versions restricted to the objects visible reports
+func (m *Manager) catalogScope(ctx context.Context, visible func(database, table, column string) bool) ([]string, error) {
+	rows, err := m.db.QueryContext(ctx, `SELECT d.id, d.name, t.id, t.name, c.column_name
+		FROM databases d
+		LEFT JOIN tables t ON t.database_id = d.id AND t.deleted_at IS NULL
+		LEFT JOIN table_columns c ON c.table_id = t.id
+		WHERE d.deleted_at IS NULL`)
+	if err != nil {
+		return nil, errors.New(errors.CommonInternal, "failed to list catalog objects", err)
+	}
+	defer rows.Close()
+
+	var databaseIDs, tableIDs, schemas, tables, columns, attributes []string
+	seenDatabases := make(map[int64]bool)
+	seenTables := make(map[int64]bool)
+	for rows.Next() {
+		var databaseID int64
+		var databaseName string
+		var tableID sql.NullInt64
+		var tableName, columnName sql.NullString
+		if err := rows.Scan(&databaseID, &databaseName, &tableID, &tableName, &columnName); err != nil {
+			return nil, errors.New(errors.CommonInternal, "failed to scan catalog object", err)
+		}
+
+		if !seenDatabases[databaseID] {
+			seenDatabases[databaseID] = true
+			if visible(databaseName, "", "") {
+				databaseIDs = append(databaseIDs, strconv.FormatInt(databaseID, 10))
+				schemas = append(schemas, quoteLiteral(databaseName))
+			}
+		}
+		if !tableID.Valid {
+			continue
+		}
+		if !seenTables[tableID.Int64] {
+			seenTables[tableID.Int64] = true
+			if visible(databaseName, tableName.String, "") {
+				tableIDs = append(tableIDs, strconv.FormatInt(tableID.Int64, 10))
+				tables = append(tables, fmt.Sprintf("(%s, %s)", quoteLiteral(databaseName), quoteLiteral(tableName.String)))
+			}
+		}
+		if columnName.Valid && visible(databaseName, tableName.String, columnName.String) {
+			columns = append(columns, fmt.Sprintf("(%s, %s, %s)",
+				quoteLiteral(databaseName), quoteLiteral(tableName.String), quoteLiteral(columnName.String)))
+			attributes = append(attributes, fmt.Sprintf("(%d, %s)", tableID.Int64, quoteLiteral(columnName.String)))
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, errors.New(errors.CommonInternal, "failed to list catalog objects", err)
+	}
+
+	// OIDs are offset from the registry IDs as in the pg_namespace and pg_class views
+	filter := func(view, condition string) string {
+		return fmt.Sprintf("%s AS (SELECT * FROM main.%s WHERE %s)", view, view, condition)
+	}
+	return []string{
+		filter("pg_catalog_pg_namespace", "oid < 16384 OR "+inList("oid - 16384", databaseIDs)),
+		filter("pg_catalog_pg_class", inList("oid - 1048576", tableIDs)),
+		filter("pg_catalog_pg_attribute", inList("(attrelid - 1048576, attname)", attributes)),
+		filter("information_schema_schemata", inList("schema_name", schemas)),
+		filter("information_schema_tables", inList("(table_schema, table_name)", tables)),
+		filter("information_schema_columns", inList("(table_schema, table_name, column_name)", columns)),
+	}, nil
+}
+
+// inList returns the condition that expr is one of values, which is false for no values.
+// Row values are matched with a VALUES list.
+func inList(expr string, values []string) string {
+	if len(values) == 0 {
+		return "0"
+	}
+	if strings.HasPrefix(expr, "(") {
+		return expr + " IN (VALUES " + strings.Join(values, ", ") + ")"
+	}
+	return expr + " IN (" + strings.Join(values, ", ") + ")"
+}
+
+// withCatalogScope prepends the scope's common table expressions to a query, merging
+// them with the query's own WITH clause
+func withCatalogScope(query string, scope []string) string {
+	tokens, _ := scanSQL(query)
+	if len(tokens) == 0 || tokens[0].keyword() != "with" {
+		return "WITH " + strings.Join(scope, ", ") + " " + query
+	}
+
+	// WITH is the first word of the query; RECURSIVE applies to the whole clause
+	prefix := "WITH "
+	rest := strings.TrimLeftFunc(query, unicode.IsSpace)[len("with"):]
+	if len(tokens) > 1 && tokens[1].keyword() == "recursive" {
+		prefix = "WITH RECURSIVE "
+		rest = strings.TrimLeftFunc(rest, unicode.IsSpace)[len("recursive"):]
+	}
+	return prefix + strings.Join(scope, ", ") + "," + rest
+}
+
+// rewriteCatalogQuery maps catalog relations onto registry views, replaces session
+// functions with literals and strips PostgreSQL casts. It returns the rewritten query
+// and the literal-to-function-name mapping used to restore result column names.
+func rewriteCatalogQuery(query string, session *CatalogSession) (string, map[string]string) {
+	if session == nil {
+		session = &CatalogSession{}
+	}
+	database := session.Database
+	if database == "" {
+		database = "default"
+	}
+
+	functionColumns := make(map[string]string)
+	rewritten := forEachUnquoted(query, func(segment string) string {
+		segment = sessionFunctionPattern.ReplaceAllStringFunc(segment, func(call string) string {
+			name := strings.ToLower(sessionFunctionPattern.FindStringSubmatch(call)[1])
+
+			var value string
+			switch name {
+			case "version":
+				value = CatalogServerVersion
+			case "current_schema":
+				value = database
+			case "current_database":
+				value = CatalogDatabaseName
+			default:
+				value = session.User
+			}
+
+			literal := quoteLiteral(value)
+			functionColumns[literal] = name
+			return literal
+		})
+		segment = rewriteCatalogRelations(segment)
+		return typeCastPattern.ReplaceAllString(segment, "")
+	})
+
+	return rewritten, functionColumns
+}
+
+// rewriteCatalogRelations maps schema-qualified and pg_catalog relations onto the
+// registry views, e.g. pg_catalog.pg_class -> pg_catalog_pg_class
+func rewriteCatalogRelations(segment string) string {
+	segment = qualifiedRelationPattern.ReplaceAllStringFunc(segment, func(relation string) string {
+		match := qualifiedRelationPattern.FindStringSubmatch(relation)
+		return strings.ToLower(match[1]) + "_" + strings.ToLower(match[2])
+	})
+	return unqualifiedRelationPattern.ReplaceAllStringFunc(segment, func(relation string) string {
+		return PgCatalogSchema + "_" + strings.ToLower(relation)
+	})
+}
+
+// forEachUnquoted applies fn to every part of query outside single-quoted literals
+// and returns the reassembled query
+func forEachUnquoted(query string, fn func(segment string) string) string {
+	var out strings.Builder
+	start := 0
+	inLiteral := false
+
+	for i := 0; i < len(query); i++ {
+		if query[i] != '\'' {
+			continue
+		}
+		if inLiteral {
+			// A doubled quote is an escaped quote inside the literal
+			if i+1 < len(query) && query[i+1] == '\'' {
+				i++
+				continue
+			}
+			out.WriteString(query[start : i+1])
+			start = i + 1
+			inLiteral = false
+		} else {
+			out.WriteString(fn(query[start:i]))
+			start = i
+			inLiteral = true
+		}
+	}
+
+	if inLiteral {
+		out.WriteString(query[start:])
+	} else {
+		out.WriteString(fn(query[start:]))
+	}
+	return out.String()
+}
+
+// quoteLiteral renders value as a single-quoted SQL string literal
+func quoteLiteral(value string) string {
+	return "'" + strings.ReplaceAll(value, "'", "''") + "'"
+}
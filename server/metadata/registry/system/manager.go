@@ -3,10 +3,12 @@ package system
 import (
 	"context"
 	"database/sql"
+	"encoding/json"
 	"fmt"
 	"io/fs"
 	"path/filepath"
 	"sort"
+	"strconv"
 	"strings"
 
 	"github.com/gear6io/ranger/pkg/errors"
@@ -75,6 +77,9 @@ func (m *Manager) init(ctx context.Context) error {
 
 // Query executes a query against the system database
 func (m *Manager) Query(ctx context.Context, query string) (*QueryResult, error) {
+	// Map schema-qualified system relations onto their views
+	query = forEachUnquoted(query, rewriteCatalogRelations)
+
 	// Execute the query
 	rows, err := m.db.QueryContext(ctx, query)
 	if err != nil {
@@ -294,11 +299,117 @@ func (m *Manager) GenerateCreateTableDDL(ctx context.Context, databaseName, tabl
 		ddl.WriteString(fmt.Sprintf(" WITH (type = '%s')", tableType))
 	}
 
+	// Add the storage engine, sort key and settings the table was created with
+	clauses, err := m.tableClauses(ctx, databaseName, tableName)
+	if err != nil {
+		return "", err
+	}
+	ddl.WriteString(clauses)
+
 	ddl.WriteString(";")
 
 	return ddl.String(), nil
 }
 
+// tableClauses returns the STORAGE, ORDER BY and SETTINGS clauses of a table's DDL, empty
+// for tables without table metadata
+func (m *Manager) tableClauses(ctx context.Context, databaseName, tableName string) (string, error) {
+	query := `SELECT tm.storage_engine, tm.sort_order, tm.settings
+		FROM table_metadata tm
+		JOIN tables t ON tm.table_id = t.id
+		JOIN databases d ON t.database_id = d.id
+		WHERE d.name = ? AND t.name = ? AND t.deleted_at IS NULL AND d.deleted_at IS NULL`
+	var storageEngine string
+	var sortOrder, settings sql.NullString
+	err := m.db.QueryRowContext(ctx, query, databaseName, tableName).Scan(&storageEngine, &sortOrder, &settings)
+	if err == sql.ErrNoRows {
+		return "", nil
+	}
+	if err != nil {
+		return "", errors.New(errors.CommonInternal, "failed to get table storage metadata", err)
+	}
+
+	var clauses strings.Builder
+	if storageEngine != "" {
+		clauses.WriteString(" STORAGE " + strings.ToUpper(storageEngine))
+	}
+
+	var sortColumns []string
+	if sortOrder.String != "" {
+		if err := json.Unmarshal([]byte(sortOrder.String), &sortColumns); err != nil {
+			return "", errors.New(errors.CommonInternal, "failed to parse table sort order", err).AddContext("sort_order", sortOrder.String)
+		}
+	}
+	if len(sortColumns) > 0 {
+		clauses.WriteString(" ORDER BY (" + strings.Join(sortColumns, ", ") + ")")
+	}
+
+	var settingValues map[string]interface{}
+	if settings.String != "" {
+		if err := json.Unmarshal([]byte(settings.String), &settingValues); err != nil {
+			return "", errors.New(errors.CommonInternal, "failed to parse table settings", err).AddContext("settings", settings.String)
+		}
+	}
+	if len(settingValues) > 0 {
+		keys := make([]string, 0, len(settingValues))
+		for key := range settingValues {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+
+		pairs := make([]string, 0, len(keys))
+		for _, key := range keys {
+			pairs = append(pairs, key+" = "+formatSettingValue(settingValues[key]))
+		}
+		clauses.WriteString(" SETTINGS " + strings.Join(pairs, ", "))
+	}
+
+	return clauses.String(), nil
+}
+
+// formatSettingValue formats a stored setting the way CREATE TABLE ... SETTINGS takes it
+func formatSettingValue(value interface{}) string {
+	switch v := value.(type) {
+	case bool:
+		return strconv.FormatBool(v)
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	}
+	// Quotes within the text are doubled to keep the literal closed
+	return "'" + strings.ReplaceAll(settingText(value), "'", "''") + "'"
+}
+
+// settingText returns the unquoted text of a stored setting
+func settingText(value interface{}) string {
+	switch v := value.(type) {
+	case bool:
+		return strconv.FormatBool(v)
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case string:
+		return v
+	case []interface{}:
+		items := make([]string, 0, len(v))
+		for _, item := range v {
+			items = append(items, settingText(item))
+		}
+		return strings.Join(items, ",")
+	case map[string]interface{}:
+		// Column settings are written as column:value pairs
+		columns := make([]string, 0, len(v))
+		for column := range v {
+			columns = append(columns, column)
+		}
+		sort.Strings(columns)
+		pairs := make([]string, 0, len(columns))
+		for _, column := range columns {
+			pairs = append(pairs, column+":"+settingText(v[column]))
+		}
+		return strings.Join(pairs, ",")
+	}
+	return fmt.Sprintf("%v", value)
+}
+
 // IsSystemDatabaseQuery checks if a query targets the system database
 func (m *Manager) IsSystemDatabaseQuery(query string) bool {
 	query = strings.ToUpper(strings.TrimSpace(query))
@@ -0,0 +1,15 @@
+-- System view for the audit log
+CREATE VIEW IF NOT EXISTS system_access_log AS
+SELECT 
+    l.id,
+    l.created_at as event_time,
+    l.username,
+    l.protocol,
+    l.action,
+    l.resource,
+    l.ip_address as client_address,
+    l.user_agent,
+    l.status,
+    l.error,
+    l.duration_ms
+FROM access_log l;
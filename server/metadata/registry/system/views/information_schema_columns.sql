@@ -0,0 +1,54 @@
+-- information_schema emulation: Ranger table columns with PostgreSQL type names
+CREATE VIEW IF NOT EXISTS information_schema_columns AS
+SELECT
+    'ranger' AS table_catalog,
+    d.name AS table_schema,
+    t.name AS table_name,
+    c.column_name,
+    c.ordinal_position,
+    NULLIF(c.default_value, '') AS column_default,
+    CASE WHEN c.is_nullable THEN 'YES' ELSE 'NO' END AS is_nullable,
+    CASE
+        WHEN lower(c.data_type) = 'boolean' THEN 'boolean'
+        WHEN lower(c.data_type) = 'int' THEN 'integer'
+        WHEN lower(c.data_type) = 'long' THEN 'bigint'
+        WHEN lower(c.data_type) = 'float' THEN 'real'
+        WHEN lower(c.data_type) = 'double' THEN 'double precision'
+        WHEN lower(c.data_type) LIKE 'decimal%' THEN 'numeric'
+        WHEN lower(c.data_type) = 'date' THEN 'date'
+        WHEN lower(c.data_type) = 'time' THEN 'time without time zone'
+        WHEN lower(c.data_type) = 'timestamp' THEN 'timestamp without time zone'
+        WHEN lower(c.data_type) = 'timestamptz' THEN 'timestamp with time zone'
+        WHEN lower(c.data_type) = 'uuid' THEN 'uuid'
+        WHEN lower(c.data_type) = 'binary' OR lower(c.data_type) LIKE 'fixed%' THEN 'bytea'
+        ELSE 'text'
+    END AS data_type,
+    CASE
+        WHEN lower(c.data_type) = 'boolean' THEN 'bool'
+        WHEN lower(c.data_type) = 'int' THEN 'int4'
+        WHEN lower(c.data_type) = 'long' THEN 'int8'
+        WHEN lower(c.data_type) = 'float' THEN 'float4'
+        WHEN lower(c.data_type) = 'double' THEN 'float8'
+        WHEN lower(c.data_type) LIKE 'decimal%' THEN 'numeric'
+        WHEN lower(c.data_type) = 'date' THEN 'date'
+        WHEN lower(c.data_type) = 'time' THEN 'time'
+        WHEN lower(c.data_type) = 'timestamp' THEN 'timestamp'
+        WHEN lower(c.data_type) = 'timestamptz' THEN 'timestamptz'
+        WHEN lower(c.data_type) = 'uuid' THEN 'uuid'
+        WHEN lower(c.data_type) = 'binary' OR lower(c.data_type) LIKE 'fixed%' THEN 'bytea'
+        ELSE 'text'
+    END AS udt_name,
+    NULLIF(c.max_length, 0) AS character_maximum_length,
+    NULLIF(c.precision, 0) AS numeric_precision,
+    NULLIF(c.scale, 0) AS numeric_scale,
+    NULL AS datetime_precision,
+    'ranger' AS udt_catalog,
+    'pg_catalog' AS udt_schema,
+    'NO' AS is_identity,
+    'NEVER' AS is_generated,
+    'YES' AS is_updatable,
+    c.description AS column_comment
+FROM table_columns c
+JOIN tables t ON c.table_id = t.id
+JOIN databases d ON t.database_id = d.id
+WHERE t.deleted_at IS NULL AND d.deleted_at IS NULL;
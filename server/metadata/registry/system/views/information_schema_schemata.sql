@@ -0,0 +1,12 @@
+-- information_schema emulation: Ranger databases as SQL schemas
+CREATE VIEW IF NOT EXISTS information_schema_schemata AS
+SELECT
+    'ranger' AS catalog_name,
+    name AS schema_name,
+    'ranger' AS schema_owner,
+    NULL AS default_character_set_catalog,
+    NULL AS default_character_set_schema,
+    NULL AS default_character_set_name,
+    NULL AS sql_path
+FROM databases
+WHERE deleted_at IS NULL;
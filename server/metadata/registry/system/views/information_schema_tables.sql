@@ -0,0 +1,22 @@
+-- information_schema emulation: Ranger tables
+CREATE VIEW IF NOT EXISTS information_schema_tables AS
+SELECT
+    'ranger' AS table_catalog,
+    d.name AS table_schema,
+    t.name AS table_name,
+    CASE
+        WHEN t.table_type = 'view' THEN 'VIEW'
+        WHEN t.is_temporary THEN 'LOCAL TEMPORARY'
+        ELSE 'BASE TABLE'
+    END AS table_type,
+    NULL AS self_referencing_column_name,
+    NULL AS reference_generation,
+    NULL AS user_defined_type_catalog,
+    NULL AS user_defined_type_schema,
+    NULL AS user_defined_type_name,
+    'YES' AS is_insertable_into,
+    'NO' AS is_typed,
+    NULL AS commit_action
+FROM tables t
+JOIN databases d ON t.database_id = d.id
+WHERE t.deleted_at IS NULL AND d.deleted_at IS NULL;
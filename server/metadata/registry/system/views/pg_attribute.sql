@@ -0,0 +1,43 @@
+-- PostgreSQL catalog emulation: Ranger table columns as pg_attribute rows
+-- Iceberg types are mapped onto the closest built-in PostgreSQL type OID
+CREATE VIEW IF NOT EXISTS pg_catalog_pg_attribute AS
+SELECT
+    1048576 + t.id AS attrelid,
+    c.column_name AS attname,
+    CASE
+        WHEN lower(c.data_type) = 'boolean' THEN 16
+        WHEN lower(c.data_type) = 'int' THEN 23
+        WHEN lower(c.data_type) = 'long' THEN 20
+        WHEN lower(c.data_type) = 'float' THEN 700
+        WHEN lower(c.data_type) = 'double' THEN 701
+        WHEN lower(c.data_type) LIKE 'decimal%' THEN 1700
+        WHEN lower(c.data_type) = 'date' THEN 1082
+        WHEN lower(c.data_type) = 'time' THEN 1083
+        WHEN lower(c.data_type) = 'timestamp' THEN 1114
+        WHEN lower(c.data_type) = 'timestamptz' THEN 1184
+        WHEN lower(c.data_type) = 'uuid' THEN 2950
+        WHEN lower(c.data_type) = 'binary' OR lower(c.data_type) LIKE 'fixed%' THEN 17
+        ELSE 25
+    END AS atttypid,
+    CASE
+        WHEN lower(c.data_type) = 'boolean' THEN 1
+        WHEN lower(c.data_type) IN ('int', 'float', 'date') THEN 4
+        WHEN lower(c.data_type) IN ('long', 'double', 'time', 'timestamp', 'timestamptz') THEN 8
+        WHEN lower(c.data_type) = 'uuid' THEN 16
+        ELSE -1
+    END AS attlen,
+    c.ordinal_position AS attnum,
+    0 AS attndims,
+    CASE WHEN c.max_length > 0 THEN c.max_length + 4 ELSE -1 END AS atttypmod,
+    CASE WHEN c.is_nullable THEN 'f' ELSE 't' END AS attnotnull,
+    CASE WHEN c.default_value IS NOT NULL AND c.default_value != '' THEN 't' ELSE 'f' END AS atthasdef,
+    '' AS attidentity,
+    '' AS attgenerated,
+    'f' AS attisdropped,
+    't' AS attislocal,
+    0 AS attcollation,
+    NULL AS attacl
+FROM table_columns c
+JOIN tables t ON c.table_id = t.id
+JOIN databases d ON t.database_id = d.id
+WHERE t.deleted_at IS NULL AND d.deleted_at IS NULL;
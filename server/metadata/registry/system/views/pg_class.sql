@@ -0,0 +1,29 @@
+-- PostgreSQL catalog emulation: Ranger tables as pg_class relations
+-- Relation OIDs for user tables start at 1048576 to stay clear of namespace OIDs
+CREATE VIEW IF NOT EXISTS pg_catalog_pg_class AS
+SELECT
+    1048576 + t.id AS oid,
+    t.name AS relname,
+    16384 + d.id AS relnamespace,
+    0 AS reltype,
+    10 AS relowner,
+    0 AS relam,
+    0 AS relfilenode,
+    0 AS reltablespace,
+    t.file_count AS relpages,
+    t.row_count AS reltuples,
+    'f' AS relhasindex,
+    'f' AS relisshared,
+    CASE WHEN t.is_temporary THEN 't' ELSE 'p' END AS relpersistence,
+    CASE WHEN t.table_type = 'view' THEN 'v' ELSE 'r' END AS relkind,
+    (SELECT COUNT(*) FROM table_columns c WHERE c.table_id = t.id) AS relnatts,
+    'f' AS relhasrules,
+    'f' AS relhastriggers,
+    'f' AS relhassubclass,
+    'f' AS relrowsecurity,
+    'f' AS relispartition,
+    NULL AS relacl,
+    NULL AS reloptions
+FROM tables t
+JOIN databases d ON t.database_id = d.id
+WHERE t.deleted_at IS NULL AND d.deleted_at IS NULL;
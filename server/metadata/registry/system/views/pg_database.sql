@@ -0,0 +1,13 @@
+-- PostgreSQL catalog emulation: a single "ranger" database holding every schema
+CREATE VIEW IF NOT EXISTS pg_catalog_pg_database AS
+SELECT
+    16383 AS oid,
+    'ranger' AS datname,
+    10 AS datdba,
+    6 AS encoding,
+    'C' AS datcollate,
+    'C' AS datctype,
+    'f' AS datistemplate,
+    't' AS datallowconn,
+    -1 AS datconnlimit,
+    NULL AS datacl;
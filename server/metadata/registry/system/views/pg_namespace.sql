@@ -0,0 +1,14 @@
+-- PostgreSQL catalog emulation: every Ranger database is exposed as a schema
+-- Namespace OIDs for user databases start at 16384 (first non-system OID)
+CREATE VIEW IF NOT EXISTS pg_catalog_pg_namespace AS
+SELECT 11 AS oid, 'pg_catalog' AS nspname, 10 AS nspowner, NULL AS nspacl
+UNION ALL
+SELECT 13000 AS oid, 'information_schema' AS nspname, 10 AS nspowner, NULL AS nspacl
+UNION ALL
+SELECT
+    16384 + d.id AS oid,
+    d.name AS nspname,
+    10 AS nspowner,
+    NULL AS nspacl
+FROM databases d
+WHERE d.deleted_at IS NULL;
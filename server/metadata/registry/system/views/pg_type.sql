@@ -0,0 +1,19 @@
+-- PostgreSQL catalog emulation: the built-in types Ranger columns map onto
+CREATE VIEW IF NOT EXISTS pg_catalog_pg_type (oid, typname, typnamespace, typowner, typlen, typbyval, typtype, typcategory, typisdefined, typdelim, typrelid, typelem, typarray, typbasetype, typtypmod, typnotnull) AS
+VALUES
+    (16, 'bool', 11, 10, 1, 't', 'b', 'B', 't', ',', 0, 0, 1000, 0, -1, 'f'),
+    (17, 'bytea', 11, 10, -1, 'f', 'b', 'U', 't', ',', 0, 0, 1001, 0, -1, 'f'),
+    (20, 'int8', 11, 10, 8, 't', 'b', 'N', 't', ',', 0, 0, 1016, 0, -1, 'f'),
+    (21, 'int2', 11, 10, 2, 't', 'b', 'N', 't', ',', 0, 0, 1005, 0, -1, 'f'),
+    (23, 'int4', 11, 10, 4, 't', 'b', 'N', 't', ',', 0, 0, 1007, 0, -1, 'f'),
+    (25, 'text', 11, 10, -1, 'f', 'b', 'S', 't', ',', 0, 0, 1009, 0, -1, 'f'),
+    (26, 'oid', 11, 10, 4, 't', 'b', 'N', 't', ',', 0, 0, 1028, 0, -1, 'f'),
+    (700, 'float4', 11, 10, 4, 't', 'b', 'N', 't', ',', 0, 0, 1021, 0, -1, 'f'),
+    (701, 'float8', 11, 10, 8, 't', 'b', 'N', 't', ',', 0, 0, 1022, 0, -1, 'f'),
+    (1043, 'varchar', 11, 10, -1, 'f', 'b', 'S', 't', ',', 0, 0, 1015, 0, -1, 'f'),
+    (1082, 'date', 11, 10, 4, 't', 'b', 'D', 't', ',', 0, 0, 1182, 0, -1, 'f'),
+    (1083, 'time', 11, 10, 8, 't', 'b', 'D', 't', ',', 0, 0, 1183, 0, -1, 'f'),
+    (1114, 'timestamp', 11, 10, 8, 't', 'b', 'D', 't', ',', 0, 0, 1115, 0, -1, 'f'),
+    (1184, 'timestamptz', 11, 10, 8, 't', 'b', 'D', 't', ',', 0, 0, 1185, 0, -1, 'f'),
+    (1700, 'numeric', 11, 10, -1, 'f', 'b', 'N', 't', ',', 0, 0, 1231, 0, -1, 'f'),
+    (2950, 'uuid', 11, 10, 16, 'f', 'b', 'U', 't', ',', 0, 0, 2951, 0, -1, 'f');
@@ -0,0 +1,13 @@
+-- System view for row access and masking policies
+CREATE VIEW IF NOT EXISTS system_policies AS
+SELECT 
+    p.name as policy_name,
+    p.kind,
+    p.database_name,
+    p.table_name,
+    p.column_name,
+    p.expression,
+    p.grantees,
+    p.created_by,
+    p.created_at
+FROM access_policies p;
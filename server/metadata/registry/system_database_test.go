@@ -8,6 +8,7 @@ import (
 	"testing"
 
 	"github.com/gear6io/ranger/server/metadata/registry/regtypes"
+	"github.com/gear6io/ranger/server/metadata/registry/system"
 )
 
 func TestSystemDatabaseManager(t *testing.T) {
@@ -175,6 +176,40 @@ func TestSystemDatabaseManager(t *testing.T) {
 		}
 	})
 
+	t.Run("GenerateCreateTableDDL_Settings", func(t *testing.T) {
+		if err := store.CreateDatabase(ctx, "settingsdb"); err != nil {
+			t.Fatalf("Failed to create settings database: %v", err)
+		}
+
+		table := &regtypes.Table{
+			Name:      "events",
+			TableType: "user",
+			Metadata: &regtypes.TableMetadata{
+				StorageEngine: "FILESYSTEM",
+				SortOrder:     `["ts"]`,
+				Settings:      `{"row_group_size":10000,"column_compression":"payload:zstd","dictionary":false,"bloom_filter_fpp":0.01}`,
+			},
+		}
+		columns := []*regtypes.TableColumn{
+			{ColumnName: "ts", DataType: "timestamp", IsNullable: false, OrdinalPosition: 1},
+			{ColumnName: "payload", DataType: "string", IsNullable: true, OrdinalPosition: 2},
+		}
+		if _, err := store.CreateTableWithColumns(ctx, "settingsdb", table, columns); err != nil {
+			t.Fatalf("Failed to create table with settings: %v", err)
+		}
+
+		ddl, err := systemMgr.GenerateCreateTableDDL(ctx, "settingsdb", "events")
+		if err != nil {
+			t.Fatalf("Failed to generate DDL: %v", err)
+		}
+
+		// Settings are echoed sorted by key, in the form CREATE TABLE takes them
+		expectedEnd := ") STORAGE FILESYSTEM ORDER BY (ts) SETTINGS bloom_filter_fpp = 0.01, column_compression = 'payload:zstd', dictionary = false, row_group_size = 10000;"
+		if !strings.HasSuffix(ddl, expectedEnd) {
+			t.Errorf("Expected DDL to end with %s, got: %s", expectedEnd, ddl)
+		}
+	})
+
 	t.Run("QuerySystemDatabase", func(t *testing.T) {
 		query := "SELECT * FROM system.columns WHERE table_name = 'users' AND database_name = 'testdb'"
 		result, err := systemMgr.Query(ctx, query)
@@ -263,6 +298,173 @@ func TestSystemDatabaseManagerErrors(t *testing.T) {
 	})
 }
 
+func TestCatalogEmulation(t *testing.T) {
+	tempDir := t.TempDir()
+
+	store, err := NewStore(filepath.Join(tempDir, "test.db"), filepath.Join(tempDir, "data"))
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+	defer store.Close()
+
+	ctx := context.Background()
+	systemMgr := store.GetSystemManager()
+
+	if err := store.CreateDatabase(ctx, "sales"); err != nil {
+		t.Fatalf("Failed to create database: %v", err)
+	}
+
+	table := &regtypes.Table{Name: "orders", TableType: "user"}
+	columns := []*regtypes.TableColumn{
+		{ColumnName: "id", DataType: "long", IsNullable: false, OrdinalPosition: 1},
+		{ColumnName: "amount", DataType: "decimal(10,2)", IsNullable: true, OrdinalPosition: 2},
+	}
+	if _, err := store.CreateTableWithColumns(ctx, "sales", table, columns); err != nil {
+		t.Fatalf("Failed to create table: %v", err)
+	}
+
+	session := &system.CatalogSession{Database: "sales", User: "alice"}
+
+	t.Run("IsCatalogQuery", func(t *testing.T) {
+		tests := []struct {
+			query    string
+			expected bool
+		}{
+			{"SELECT version()", true},
+			{"SELECT current_schema()", true},
+			{"SELECT * FROM pg_catalog.pg_class", true},
+			{"SELECT c.relname FROM pg_class c", true},
+			{"SELECT * FROM information_schema.tables", true},
+			{"SELECT * FROM system.tables", false},
+			{"SELECT * FROM sales.orders", false},
+			{"SELECT * FROM sales.orders WHERE note = 'pg_class'", false},
+			{"SELECT version(), username, password_hash FROM users", false},
+			{"SELECT current_user() FROM pg_class, auth_tokens", false},
+			{"SELECT * FROM pg_class WHERE oid IN (SELECT id FROM privileges)", false},
+			{"SELECT * FROM pg_class c JOIN main.users u ON 1 = 1", false},
+			{"SELECT * FROM [users]", false},
+			{"SELECT 1 IN access_log", false},
+			{"SELECT * FROM pragma_table_info('users')", false},
+			{"SELECT * FROM pg_class; DELETE FROM users", false},
+			{"WITH t AS (SELECT relname FROM pg_class) SELECT * FROM t", true},
+			{"SELECT n.nspname FROM pg_namespace AS n, pg_catalog.pg_class c WHERE c.relnamespace = n.oid;", true},
+		}
+
+		for _, tt := range tests {
+			if result := systemMgr.IsCatalogQuery(tt.query); result != tt.expected {
+				t.Errorf("Query '%s': expected %v, got %v", tt.query, tt.expected, result)
+			}
+		}
+	})
+
+	t.Run("SessionFunctions", func(t *testing.T) {
+		result, err := systemMgr.QueryCatalog(ctx, "SELECT version(), current_schema(), current_user()", session)
+		if err != nil {
+			t.Fatalf("Failed to query catalog: %v", err)
+		}
+
+		if got := strings.Join(result.Columns, ","); got != "version,current_schema,current_user" {
+			t.Errorf("Unexpected columns: %s", got)
+		}
+		if result.Data[0][0] != system.CatalogServerVersion || result.Data[0][1] != "sales" || result.Data[0][2] != "alice" {
+			t.Errorf("Unexpected row: %v", result.Data[0])
+		}
+	})
+
+	t.Run("PgCatalogRelations", func(t *testing.T) {
+		query := `SELECT c.relname, a.attname, ty.typname
+			FROM pg_catalog.pg_class c
+			JOIN pg_catalog.pg_namespace n ON n.oid = c.relnamespace
+			JOIN pg_attribute a ON a.attrelid = c.oid
+			JOIN pg_type ty ON ty.oid = a.atttypid
+			WHERE n.nspname = 'sales' AND c.relkind = 'r' AND a.attnum > 0
+			ORDER BY a.attnum`
+		result, err := systemMgr.QueryCatalog(ctx, query, session)
+		if err != nil {
+			t.Fatalf("Failed to query catalog: %v", err)
+		}
+
+		if result.RowCount != 2 {
+			t.Fatalf("Expected 2 rows, got %d", result.RowCount)
+		}
+		if result.Data[0][1] != "id" || result.Data[0][2] != "int8" {
+			t.Errorf("Unexpected first column: %v", result.Data[0])
+		}
+		if result.Data[1][1] != "amount" || result.Data[1][2] != "numeric" {
+			t.Errorf("Unexpected second column: %v", result.Data[1])
+		}
+	})
+
+	t.Run("RegclassCast", func(t *testing.T) {
+		query := "SELECT relname FROM pg_catalog.pg_class WHERE oid = (SELECT oid FROM pg_class WHERE relname = 'orders')::regclass"
+		result, err := systemMgr.QueryCatalog(ctx, query, session)
+		if err != nil {
+			t.Fatalf("Failed to query catalog: %v", err)
+		}
+		if result.RowCount != 1 {
+			t.Errorf("Expected 1 row, got %d", result.RowCount)
+		}
+	})
+
+	t.Run("InformationSchema", func(t *testing.T) {
+		result, err := systemMgr.QueryCatalog(ctx, "SELECT table_name, table_type FROM information_schema.tables WHERE table_schema = 'sales'", session)
+		if err != nil {
+			t.Fatalf("Failed to query tables: %v", err)
+		}
+		if result.RowCount != 1 || result.Data[0][0] != "orders" || result.Data[0][1] != "BASE TABLE" {
+			t.Errorf("Unexpected tables result: %v", result.Data)
+		}
+
+		result, err = systemMgr.QueryCatalog(ctx, "SELECT column_name, data_type, is_nullable FROM information_schema.columns WHERE table_schema = 'sales' AND table_name = 'orders' ORDER BY ordinal_position", session)
+		if err != nil {
+			t.Fatalf("Failed to query columns: %v", err)
+		}
+		if result.RowCount != 2 {
+			t.Fatalf("Expected 2 columns, got %d", result.RowCount)
+		}
+		if result.Data[0][1] != "bigint" || result.Data[0][2] != "NO" {
+			t.Errorf("Unexpected id column: %v", result.Data[0])
+		}
+	})
+
+	t.Run("VisibleObjects", func(t *testing.T) {
+		if _, err := store.CreateTableWithColumns(ctx, "sales", &regtypes.Table{Name: "secrets", TableType: "user"}, []*regtypes.TableColumn{
+			{ColumnName: "id", DataType: "long", OrdinalPosition: 1},
+		}); err != nil {
+			t.Fatalf("Failed to create table: %v", err)
+		}
+		scoped := &system.CatalogSession{Database: "sales", User: "alice", Visible: func(database, table, column string) bool {
+			return database == "sales" && (table == "" || table == "orders") && column != "amount"
+		}}
+
+		queries := map[string]string{
+			"SELECT relname FROM pg_class": "orders",
+			"SELECT table_name FROM information_schema.tables WHERE table_schema = 'sales'":                       "orders",
+			"WITH c AS (SELECT * FROM information_schema.columns) SELECT table_name || '.' || column_name FROM c": "orders.id",
+			"SELECT c.relname || '.' || a.attname FROM pg_attribute a JOIN pg_class c ON c.oid = a.attrelid":      "orders.id",
+		}
+		for query, expected := range queries {
+			result, err := systemMgr.QueryCatalog(ctx, query, scoped)
+			if err != nil {
+				t.Fatalf("Failed to query catalog: %v", err)
+			}
+			if result.RowCount != 1 || result.Data[0][0] != expected {
+				t.Errorf("Query '%s': expected only %s, got %v", query, expected, result.Data)
+			}
+		}
+
+		result, err := systemMgr.QueryCatalog(ctx, "SELECT nspname FROM pg_namespace ORDER BY oid", &system.CatalogSession{
+			Visible: func(database, table, column string) bool { return false },
+		})
+		if err != nil {
+			t.Fatalf("Failed to query catalog: %v", err)
+		}
+		if result.RowCount != 2 {
+			t.Errorf("Expected only the built-in schemas, got %v", result.Data)
+		}
+	})
+}
+
 // Helper function to check if a string contains a substring
 func contains(s, substr string) bool {
 	return strings.Contains(s, substr)
@@ -0,0 +1,17 @@
+package registry
+
+import (
+	"context"
+
+	"github.com/gear6io/ranger/server/tracing"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// startSpan starts the span of a registry operation on a table
+func startSpan(ctx context.Context, operation, database, tableName string) (context.Context, trace.Span) {
+	return tracing.Start(ctx, "registry."+operation,
+		attribute.String("db.system.name", "sqlite"),
+		attribute.String("db.namespace", database),
+		attribute.String("db.collection.name", tableName))
+}
@@ -238,7 +238,7 @@ func (cti *CompleteTableInfo) PreloadFields(ctx context.Context, fields []string
 // Helper methods for loading related table data
 func (cti *CompleteTableInfo) loadTableColumns(ctx context.Context) ([]*regtypes.TableColumn, error) {
 	query := `
-		SELECT id, table_id, column_name, column_type, is_nullable, column_default, column_comment
+		SELECT id, table_id, column_name, data_type, is_nullable, default_value, description
 		FROM table_columns
 		WHERE table_id = ?
 		ORDER BY ordinal_position
@@ -0,0 +1,137 @@
+// Package metrics holds the server's Prometheus metrics: the latency and outcome of
+// queries, inserts and storage writes, and the statistics components already keep, read
+// when the registry is scraped.
+package metrics
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// namespace prefixes every metric name
+const namespace = "ranger"
+
+// ProtocolInternal labels queries that did not arrive through a client protocol
+const ProtocolInternal = "internal"
+
+// Statuses of inserts and storage writes
+const (
+	StatusOK     = "ok"
+	StatusFailed = "failed"
+	StatusDenied = "denied"
+)
+
+// latencyBuckets span 1ms to about 4 minutes
+var latencyBuckets = prometheus.ExponentialBuckets(0.001, 4, 10)
+
+// registry holds every metric of the server
+var registry = prometheus.NewRegistry()
+
+var (
+	queriesTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "queries_total",
+		Help:      "Queries executed, by protocol and final status.",
+	}, []string{"protocol", "status"})
+
+	queryDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "query_duration_seconds",
+		Help:      "Query latency, by protocol and final status.",
+		Buckets:   latencyBuckets,
+	}, []string{"protocol", "status"})
+
+	insertsTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "inserts_total",
+		Help:      "Bulk inserts such as COPY FROM, native data blocks and HTTP inserts, by protocol, table and status.",
+	}, []string{"protocol", "table", "status"})
+
+	insertDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "insert_duration_seconds",
+		Help:      "Bulk insert latency, by protocol, table and status.",
+		Buckets:   latencyBuckets,
+	}, []string{"protocol", "table", "status"})
+
+	storageRowsWritten = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "storage",
+		Name:      "rows_written_total",
+		Help:      "Rows written to tables, by table.",
+	}, []string{"table"})
+
+	storageWriteDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "storage",
+		Name:      "write_duration_seconds",
+		Help:      "Latency of batch writes to tables, by table and status.",
+		Buckets:   latencyBuckets,
+	}, []string{"table", "status"})
+)
+
+func init() {
+	registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		queriesTotal, queryDuration,
+		insertsTotal, insertDuration,
+		storageRowsWritten, storageWriteDuration,
+	)
+}
+
+// Handler serves the metrics in the Prometheus exposition format
+func Handler() http.Handler {
+	return promhttp.HandlerFor(registry, promhttp.HandlerOpts{})
+}
+
+// Register adds a component's collector, replacing the collector of an earlier instance
+// of the component
+func Register(collector prometheus.Collector) {
+	if err := registry.Register(collector); err != nil {
+		if existing, ok := err.(prometheus.AlreadyRegisteredError); ok {
+			registry.Unregister(existing.ExistingCollector)
+			registry.MustRegister(collector)
+			return
+		}
+		panic(err)
+	}
+}
+
+// Unregister removes a component's collector
+func Unregister(collector prometheus.Collector) {
+	registry.Unregister(collector)
+}
+
+// ObserveQuery records a finished query; status is its final execution status
+func ObserveQuery(protocol, status string, duration time.Duration) {
+	if protocol == "" {
+		protocol = ProtocolInternal
+	}
+	queriesTotal.WithLabelValues(protocol, status).Inc()
+	queryDuration.WithLabelValues(protocol, status).Observe(duration.Seconds())
+}
+
+// ObserveInsert records a finished bulk insert into table, given as database.table
+func ObserveInsert(protocol, table, status string, duration time.Duration) {
+	if protocol == "" {
+		protocol = ProtocolInternal
+	}
+	insertsTotal.WithLabelValues(protocol, table, status).Inc()
+	insertDuration.WithLabelValues(protocol, table, status).Observe(duration.Seconds())
+}
+
+// ObserveStorageWrite records a batch of rows written to table, given as database.table
+func ObserveStorageWrite(table string, rows int, failed bool, duration time.Duration) {
+	status := StatusOK
+	if failed {
+		status = StatusFailed
+	} else {
+		storageRowsWritten.WithLabelValues(table).Add(float64(rows))
+	}
+	storageWriteDuration.WithLabelValues(table, status).Observe(duration.Seconds())
+}
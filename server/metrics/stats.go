@@ -0,0 +1,106 @@
+package metrics
+
+import (
+	"sort"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Stat is a metric read from a snapshot of a component's statistics
+type Stat[T any] struct {
+	name      string
+	help      string
+	label     string
+	valueType prometheus.ValueType
+	values    func(T) map[string]float64
+}
+
+// Counter is a stat that only grows, such as a number of operations
+func Counter[T any](name, help string, value func(T) float64) Stat[T] {
+	return Stat[T]{name: name, help: help, valueType: prometheus.CounterValue, values: single(value)}
+}
+
+// Gauge is a stat that goes up and down, such as a number of open connections
+func Gauge[T any](name, help string, value func(T) float64) Stat[T] {
+	return Stat[T]{name: name, help: help, valueType: prometheus.GaugeValue, values: single(value)}
+}
+
+// GaugeVec is a gauge with one series per value of label
+func GaugeVec[T any](name, help, label string, values func(T) map[string]float64) Stat[T] {
+	return Stat[T]{name: name, help: help, label: label, valueType: prometheus.GaugeValue, values: values}
+}
+
+// single adapts the value of an unlabelled stat
+func single[T any](value func(T) float64) func(T) map[string]float64 {
+	return func(snapshot T) map[string]float64 {
+		return map[string]float64{"": value(snapshot)}
+	}
+}
+
+// StatsCollector exposes a component's statistics. It takes one snapshot per scrape, so
+// all its metrics are consistent with each other.
+type StatsCollector[T any] struct {
+	snapshot func() T
+	stats    []Stat[T]
+	descs    []*prometheus.Desc
+}
+
+// NewStatsCollector creates a collector for the stats of a component, named
+// ranger_<subsystem>_<stat>
+func NewStatsCollector[T any](subsystem string, snapshot func() T, stats ...Stat[T]) *StatsCollector[T] {
+	descs := make([]*prometheus.Desc, len(stats))
+	for i, stat := range stats {
+		var labels []string
+		if stat.label != "" {
+			labels = []string{stat.label}
+		}
+		descs[i] = prometheus.NewDesc(prometheus.BuildFQName(namespace, subsystem, stat.name), stat.help, labels, nil)
+	}
+	return &StatsCollector[T]{snapshot: snapshot, stats: stats, descs: descs}
+}
+
+// Describe implements prometheus.Collector
+func (c *StatsCollector[T]) Describe(ch chan<- *prometheus.Desc) {
+	for _, desc := range c.descs {
+		ch <- desc
+	}
+}
+
+// Collect implements prometheus.Collector
+func (c *StatsCollector[T]) Collect(ch chan<- prometheus.Metric) {
+	snapshot := c.snapshot()
+	for i, stat := range c.stats {
+		values := stat.values(snapshot)
+		keys := make([]string, 0, len(values))
+		for key := range values {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			if stat.label == "" {
+				ch <- prometheus.MustNewConstMetric(c.descs[i], stat.valueType, values[key])
+			} else {
+				ch <- prometheus.MustNewConstMetric(c.descs[i], stat.valueType, values[key], key)
+			}
+		}
+	}
+}
+
+// Number converts a value of a stats map to a metric value; durations become seconds and
+// values that are not numbers become 0
+func Number(value interface{}) float64 {
+	switch value := value.(type) {
+	case int:
+		return float64(value)
+	case int32:
+		return float64(value)
+	case int64:
+		return float64(value)
+	case float64:
+		return value
+	case time.Duration:
+		return value.Seconds()
+	}
+	return 0
+}
@@ -0,0 +1,74 @@
+package metrics
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type testStats struct {
+	requests int64
+	open     int
+	states   map[string]float64
+}
+
+func newTestCollector(stats *testStats) *StatsCollector[*testStats] {
+	return NewStatsCollector("test", func() *testStats { return stats },
+		Counter("requests_total", "Requests served.", func(s *testStats) float64 { return float64(s.requests) }),
+		Gauge("open", "Open things.", func(s *testStats) float64 { return float64(s.open) }),
+		GaugeVec("state", "Current state.", "state", func(s *testStats) map[string]float64 { return s.states }),
+	)
+}
+
+func TestStatsCollector(t *testing.T) {
+	stats := &testStats{requests: 3, open: 2, states: map[string]float64{"open": 0, "closed": 1}}
+	collector := newTestCollector(stats)
+
+	expected := `
+# HELP ranger_test_open Open things.
+# TYPE ranger_test_open gauge
+ranger_test_open 2
+# HELP ranger_test_requests_total Requests served.
+# TYPE ranger_test_requests_total counter
+ranger_test_requests_total 3
+# HELP ranger_test_state Current state.
+# TYPE ranger_test_state gauge
+ranger_test_state{state="closed"} 1
+ranger_test_state{state="open"} 0
+`
+	require.NoError(t, testutil.CollectAndCompare(collector, strings.NewReader(expected)))
+
+	// Values are read at each scrape
+	stats.requests = 5
+	require.NoError(t, testutil.CollectAndCompare(collector, strings.NewReader(`
+# HELP ranger_test_requests_total Requests served.
+# TYPE ranger_test_requests_total counter
+ranger_test_requests_total 5
+`), "ranger_test_requests_total"))
+}
+
+func TestRegisterReplacesEarlierInstance(t *testing.T) {
+	first := newTestCollector(&testStats{requests: 1})
+	second := newTestCollector(&testStats{requests: 2})
+	Register(first)
+	Register(second)
+	defer Unregister(second)
+
+	ObserveQuery("", "completed", 20*time.Millisecond)
+	ObserveStorageWrite("default.events", 10, false, time.Millisecond)
+
+	recorder := httptest.NewRecorder()
+	Handler().ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	body := recorder.Body.String()
+	assert.Contains(t, body, "ranger_test_requests_total 2\n")
+	assert.NotContains(t, body, "ranger_test_requests_total 1\n")
+	assert.Contains(t, body, `ranger_queries_total{protocol="internal",status="completed"}`)
+	assert.Contains(t, body, `ranger_storage_rows_written_total{table="default.events"} 10`)
+	assert.Contains(t, body, "go_goroutines")
+}
@@ -0,0 +1,329 @@
+package http
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"time"
+
+	"github.com/apache/arrow-go/v18/arrow"
+	"github.com/apache/arrow-go/v18/arrow/array"
+	"github.com/apache/arrow-go/v18/arrow/ipc"
+	"github.com/apache/arrow-go/v18/arrow/memory"
+	"github.com/apache/arrow-go/v18/parquet"
+	"github.com/apache/arrow-go/v18/parquet/compress"
+	"github.com/apache/arrow-go/v18/parquet/pqarrow"
+	"github.com/gear6io/ranger/pkg/errors"
+)
+
+// arrowBatchRows is the largest record batch of the Arrow format; smaller batches are sent
+// when rows arrive slowly
+const arrowBatchRows = 8192
+
+// parquetRowGroupRows is the row group size of the Parquet format
+const parquetRowGroupRows = 64 * 1024
+
+// arrowEncoder writes an Arrow IPC stream
+type arrowEncoder struct {
+	w       io.Writer
+	columns []resultColumn
+	builder *array.RecordBuilder
+	writer  *ipc.Writer
+	pending int
+}
+
+func (e *arrowEncoder) begin(queryID string, columns []resultColumn) error {
+	schema := arrowSchema(columns)
+	e.columns = columns
+	e.builder = array.NewRecordBuilder(memory.DefaultAllocator, schema)
+	e.writer = ipc.NewWriter(e.w, ipc.WithSchema(schema), ipc.WithAllocator(memory.DefaultAllocator))
+	return nil
+}
+
+func (e *arrowEncoder) row(values []interface{}) error {
+	if err := appendRow(e.builder, e.columns, values); err != nil {
+		return err
+	}
+	e.pending++
+	if e.pending >= arrowBatchRows {
+		return e.flush()
+	}
+	return nil
+}
+
+func (e *arrowEncoder) flush() error {
+	if e.pending == 0 {
+		return nil
+	}
+	record := e.builder.NewRecord()
+	defer record.Release()
+	e.pending = 0
+	return e.writer.Write(record)
+}
+
+func (e *arrowEncoder) end(summary resultSummary) error {
+	defer e.builder.Release()
+	if err := e.flush(); err != nil {
+		return err
+	}
+	// Closing writes the end-of-stream marker, and the schema of empty results
+	return e.writer.Close()
+}
+
+// abort leaves the stream without its end-of-stream marker, so readers see it is incomplete
+func (e *arrowEncoder) abort(cause error) error {
+	if e.builder != nil {
+		e.builder.Release()
+	}
+	return nil
+}
+
+// parquetEncoder writes a Parquet file, a row group at a time
+type parquetEncoder struct {
+	w       io.Writer
+	columns []resultColumn
+	builder *array.RecordBuilder
+	writer  *pqarrow.FileWriter
+	pending int
+}
+
+func (e *parquetEncoder) begin(queryID string, columns []resultColumn) error {
+	schema := arrowSchema(columns)
+	properties := parquet.NewWriterProperties(parquet.WithCompression(compress.Codecs.Snappy))
+	writer, err := pqarrow.NewFileWriter(schema, e.w, properties, pqarrow.DefaultWriterProps())
+	if err != nil {
+		return err
+	}
+	e.columns = columns
+	e.builder = array.NewRecordBuilder(memory.DefaultAllocator, schema)
+	e.writer = writer
+	return nil
+}
+
+func (e *parquetEncoder) row(values []interface{}) error {
+	if err := appendRow(e.builder, e.columns, values); err != nil {
+		return err
+	}
+	e.pending++
+	if e.pending >= parquetRowGroupRows {
+		return e.writeRowGroup()
+	}
+	return nil
+}
+
+// flush keeps buffered rows, so slow results do not produce tiny row groups
+func (e *parquetEncoder) flush() error { return nil }
+
+// writeRowGroup writes the buffered rows as a row group
+func (e *parquetEncoder) writeRowGroup() error {
+	if e.pending == 0 {
+		return nil
+	}
+	record := e.builder.NewRecord()
+	defer record.Release()
+	e.pending = 0
+	return e.writer.Write(record)
+}
+
+func (e *parquetEncoder) end(summary resultSummary) error {
+	defer e.builder.Release()
+	if err := e.writeRowGroup(); err != nil {
+		return err
+	}
+	// Closing writes the footer
+	return e.writer.Close()
+}
+
+// abort leaves the file without its footer, so readers reject it
+func (e *parquetEncoder) abort(cause error) error {
+	if e.builder != nil {
+		e.builder.Release()
+	}
+	return nil
+}
+
+// arrowSchema returns the Arrow schema of the result columns; all columns are nullable
+func arrowSchema(columns []resultColumn) *arrow.Schema {
+	fields := make([]arrow.Field, len(columns))
+	for i, column := range columns {
+		fields[i] = arrow.Field{Name: column.Name, Type: arrowType(column.Type), Nullable: true}
+	}
+	return arrow.NewSchema(fields, nil)
+}
+
+// arrowType maps a DuckDB type name to the Arrow type of its column. Lists become Arrow
+// lists; types without an exact counterpart, such as DECIMAL, HUGEINT, maps and structs, are
+// written as strings (maps and structs as JSON).
+func arrowType(typeName string) arrow.DataType {
+	if element := elementTypeName(typeName); element != "" {
+		return arrow.ListOf(arrowType(element))
+	}
+	switch baseTypeName(typeName) {
+	case "BOOLEAN":
+		return arrow.FixedWidthTypes.Boolean
+	case "TINYINT", "SMALLINT", "INTEGER", "BIGINT", "UTINYINT", "USMALLINT", "UINTEGER":
+		return arrow.PrimitiveTypes.Int64
+	case "UBIGINT":
+		return arrow.PrimitiveTypes.Uint64
+	case "FLOAT", "REAL", "DOUBLE":
+		return arrow.PrimitiveTypes.Float64
+	case "DATE":
+		return arrow.FixedWidthTypes.Date32
+	case "TIMESTAMP", "DATETIME", "TIMESTAMP_S", "TIMESTAMP_MS", "TIMESTAMP_NS":
+		return &arrow.TimestampType{Unit: arrow.Microsecond}
+	case "TIMESTAMPTZ", "TIMESTAMP WITH TIME ZONE":
+		return &arrow.TimestampType{Unit: arrow.Microsecond, TimeZone: "UTC"}
+	case "BLOB":
+		return arrow.BinaryTypes.Binary
+	default:
+		return arrow.BinaryTypes.String
+	}
+}
+
+// appendRow appends a result row to the column builders
+func appendRow(builder *array.RecordBuilder, columns []resultColumn, values []interface{}) error {
+	fields := builder.Fields()
+	if len(values) != len(fields) {
+		return errors.New(ErrResultWriteFailed, "row does not match the result columns", nil).
+			AddContext("values", len(values)).AddContext("columns", len(fields))
+	}
+	for i, value := range values {
+		if err := appendValue(fields[i], value, columns[i].Type); err != nil {
+			return errors.AddContext(err, "column", columns[i].Name)
+		}
+	}
+	return nil
+}
+
+// appendValue appends a value to a column builder of the type arrowType chose for typeName
+func appendValue(builder array.Builder, value interface{}, typeName string) error {
+	if value == nil {
+		builder.AppendNull()
+		return nil
+	}
+
+	ok := true
+	switch builder := builder.(type) {
+	case *array.BooleanBuilder:
+		var boolean bool
+		if boolean, ok = value.(bool); ok {
+			builder.Append(boolean)
+		}
+	case *array.Int64Builder:
+		var number int64
+		if number, ok = toInt64(value); ok {
+			builder.Append(number)
+		}
+	case *array.Uint64Builder:
+		var number uint64
+		if number, ok = toUint64(value); ok {
+			builder.Append(number)
+		}
+	case *array.Float64Builder:
+		var number float64
+		if number, ok = toFloat64(value); ok {
+			builder.Append(number)
+		}
+	case *array.Date32Builder:
+		var date time.Time
+		if date, ok = value.(time.Time); ok {
+			builder.Append(arrow.Date32FromTime(date))
+		}
+	case *array.TimestampBuilder:
+		var timestamp time.Time
+		if timestamp, ok = value.(time.Time); ok {
+			builder.Append(arrow.Timestamp(timestamp.UnixMicro()))
+		}
+	case *array.BinaryBuilder:
+		switch value := value.(type) {
+		case []byte:
+			builder.Append(value)
+		case string:
+			builder.AppendString(value)
+		default:
+			ok = false
+		}
+	case *array.StringBuilder:
+		builder.Append(formatText(value, typeName))
+	case *array.ListBuilder:
+		var elements []interface{}
+		if elements, ok = value.([]interface{}); ok {
+			builder.Append(true)
+			for _, element := range elements {
+				if err := appendValue(builder.ValueBuilder(), element, elementTypeName(typeName)); err != nil {
+					return err
+				}
+			}
+		}
+	default:
+		ok = false
+	}
+
+	if !ok {
+		return errors.New(ErrResultWriteFailed, fmt.Sprintf("unexpected %T value for an Arrow %s column", value, builder.Type()), nil)
+	}
+	return nil
+}
+
+// toInt64 converts an integer value to int64
+func toInt64(value interface{}) (int64, bool) {
+	switch value := value.(type) {
+	case int:
+		return int64(value), true
+	case int8:
+		return int64(value), true
+	case int16:
+		return int64(value), true
+	case int32:
+		return int64(value), true
+	case int64:
+		return value, true
+	case uint8:
+		return int64(value), true
+	case uint16:
+		return int64(value), true
+	case uint32:
+		return int64(value), true
+	case uint:
+		return int64(value), uint64(value) <= math.MaxInt64
+	case uint64:
+		return int64(value), value <= math.MaxInt64
+	default:
+		return 0, false
+	}
+}
+
+// toUint64 converts a non-negative integer value to uint64
+func toUint64(value interface{}) (uint64, bool) {
+	switch value := value.(type) {
+	case uint64:
+		return value, true
+	case uint:
+		return uint64(value), true
+	case uint32:
+		return uint64(value), true
+	case uint16:
+		return uint64(value), true
+	case uint8:
+		return uint64(value), true
+	default:
+		number, ok := toInt64(value)
+		return uint64(number), ok && number >= 0
+	}
+}
+
+// toFloat64 converts a numeric value to float64
+func toFloat64(value interface{}) (float64, bool) {
+	switch value := value.(type) {
+	case float64:
+		return value, true
+	case float32:
+		return float64(value), true
+	default:
+		if number, ok := toInt64(value); ok {
+			return float64(number), true
+		}
+		number, ok := toUint64(value)
+		return float64(number), ok
+	}
+}
@@ -0,0 +1,442 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gear6io/ranger/pkg/errors"
+	"github.com/gear6io/ranger/server/config"
+	"github.com/gear6io/ranger/server/query"
+	"github.com/gear6io/ranger/server/tracing"
+	"github.com/gear6io/ranger/server/types"
+)
+
+// defaultResultTTL is how long spooled results are kept when the configuration sets no TTL
+const defaultResultTTL = time.Hour
+
+// Pagination of GET /v1/queries/{id}/results
+const (
+	defaultPageRows = 1000
+	maxPageRows     = 100000
+)
+
+// asyncQuery is a query submitted through POST /v1/queries. Its result is spooled to a
+// temporary file that is kept until the TTL after the query ends.
+type asyncQuery struct {
+	id          string
+	user        string
+	sql         string
+	submittedAt time.Time
+	cancel      context.CancelFunc
+	spool       *resultSpool
+	done        chan struct{}
+
+	// Set when done is closed
+	finishedAt time.Time
+	message    string
+	err        error
+	cancelled  bool
+}
+
+// finished reports whether the query has ended
+func (q *asyncQuery) finished() bool {
+	select {
+	case <-q.done:
+		return true
+	default:
+		return false
+	}
+}
+
+// asyncQueryStatus is the JSON body describing an asynchronous query
+type asyncQueryStatus struct {
+	QueryID     string         `json:"query_id"`
+	Status      string         `json:"status"`
+	User        string         `json:"user"`
+	Query       string         `json:"query"`
+	SubmittedAt time.Time      `json:"submitted_at"`
+	FinishedAt  *time.Time     `json:"finished_at,omitempty"`
+	ExpiresAt   *time.Time     `json:"expires_at,omitempty"`
+	Elapsed     float64        `json:"elapsed"`
+	RowsRead    int64          `json:"rows_read"`
+	Rows        int64          `json:"rows"`
+	Bytes       int64          `json:"bytes"`
+	Truncated   bool           `json:"truncated"`
+	Message     string         `json:"message,omitempty"`
+	Error       string         `json:"error,omitempty"`
+	ErrorCode   string         `json:"error_code,omitempty"`
+	Meta        []resultColumn `json:"meta,omitempty"`
+	ResultsURI  string         `json:"results_uri,omitempty"`
+}
+
+// asyncQueryPage is the JSON body of a page of results
+type asyncQueryPage struct {
+	QueryID   string          `json:"query_id"`
+	Meta      []resultColumn  `json:"meta"`
+	Data      [][]interface{} `json:"data"`
+	Offset    int64           `json:"offset"`
+	Rows      int             `json:"rows"`
+	TotalRows int64           `json:"total_rows"`
+	Truncated bool            `json:"truncated"`
+	NextURI   string          `json:"next_uri,omitempty"`
+}
+
+// asyncQueries holds the asynchronous queries of the server
+type asyncQueries struct {
+	mu       sync.Mutex
+	queries  map[string]*asyncQuery
+	dir      string
+	ttl      time.Duration
+	maxBytes int64
+}
+
+// newAsyncQueries creates the registry of asynchronous queries from the HTTP configuration
+func newAsyncQueries(cfg config.HTTPConfig) *asyncQueries {
+	dir := cfg.SpoolDir
+	if dir == "" {
+		dir = filepath.Join(os.TempDir(), "ranger-spool")
+	}
+	ttl := time.Duration(cfg.ResultTTLMinutes) * time.Minute
+	if ttl == 0 {
+		ttl = defaultResultTTL
+	}
+	return &asyncQueries{
+		queries:  make(map[string]*asyncQuery),
+		dir:      dir,
+		ttl:      ttl,
+		maxBytes: int64(cfg.MaxSpoolMB) << 20,
+	}
+}
+
+// prepare creates the spool directory, removing the spool files of an earlier run
+func (a *asyncQueries) prepare() error {
+	if err := os.MkdirAll(a.dir, 0o700); err != nil {
+		return errors.New(ErrSpoolFailed, "failed to create spool directory", err).AddContext("dir", a.dir)
+	}
+	stale, _ := filepath.Glob(filepath.Join(a.dir, "query-*.spool"))
+	for _, file := range stale {
+		os.Remove(file)
+	}
+	return nil
+}
+
+// add registers a query under its ID, which must not belong to a query still held
+func (a *asyncQueries) add(q *asyncQuery) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if _, exists := a.queries[q.id]; exists {
+		return errors.New(query.ErrQueryIDInUse, "a query with this ID already exists", nil).AddContext("query_id", q.id)
+	}
+	a.queries[q.id] = q
+	return nil
+}
+
+// get returns the query with the given ID submitted by user
+func (a *asyncQueries) get(id, user string) (*asyncQuery, error) {
+	a.mu.Lock()
+	q, exists := a.queries[id]
+	a.mu.Unlock()
+	// Queries of other users are not revealed
+	if !exists || q.user != user {
+		return nil, errors.New(ErrQueryNotFound, "query not found", nil).AddContext("query_id", id)
+	}
+	return q, nil
+}
+
+// remove unregisters a query and deletes its spooled result
+func (a *asyncQueries) remove(q *asyncQuery) error {
+	a.mu.Lock()
+	delete(a.queries, q.id)
+	a.mu.Unlock()
+	return q.spool.remove()
+}
+
+// expired returns the finished queries whose results outlived the TTL, or all queries
+// when shutting down
+func (a *asyncQueries) expired(now time.Time, all bool) []*asyncQuery {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	var expired []*asyncQuery
+	for _, q := range a.queries {
+		if all || (q.finished() && now.Sub(q.finishedAt) >= a.ttl) {
+			expired = append(expired, q)
+		}
+	}
+	return expired
+}
+
+// handleSubmitQuery starts a query in the background and returns its ID at once
+func (s *Server) handleSubmitQuery(w http.ResponseWriter, r *http.Request) {
+	username, ok := s.authenticate(w, r)
+	if !ok {
+		return
+	}
+	queryCtx, settings, err := s.prepareQuery(r, username)
+	if err != nil {
+		s.writeError(w, err)
+		return
+	}
+
+	spool, err := newResultSpool(s.async.dir, s.async.maxBytes, settings.MaxResultRows)
+	if err != nil {
+		s.writeError(w, err)
+		return
+	}
+	// The query outlives the request, so it runs under the server's context, in the
+	// request's trace
+	ctx := tracing.WithSpanOf(s.ctx, r.Context())
+	var cancel context.CancelFunc
+	if settings.MaxExecutionTime > 0 {
+		ctx, cancel = context.WithTimeout(ctx, settings.MaxExecutionTime)
+	} else {
+		ctx, cancel = context.WithCancel(ctx)
+	}
+	q := &asyncQuery{
+		id:          queryCtx.QueryID,
+		user:        username,
+		sql:         queryCtx.Query,
+		submittedAt: time.Now(),
+		cancel:      cancel,
+		spool:       spool,
+		done:        make(chan struct{}),
+	}
+	if err := s.async.add(q); err != nil {
+		cancel()
+		spool.remove()
+		s.writeError(w, err)
+		return
+	}
+
+	s.logger.Info().Str("query", queryCtx.Query).Str("query_id", q.id).Str("user", username).Msg("Submitting asynchronous query via HTTP")
+	s.wg.Add(1)
+	go s.runAsyncQuery(ctx, q, queryCtx)
+
+	w.Header().Set("Location", "/v1/queries/"+q.id)
+	s.writeJSON(w, http.StatusAccepted, s.asyncStatus(q))
+}
+
+// runAsyncQuery executes a submitted query, spooling its result
+func (s *Server) runAsyncQuery(ctx context.Context, q *asyncQuery, queryCtx *types.QueryContext) {
+	defer s.wg.Done()
+	defer q.cancel()
+
+	result, err := s.queryEngine.StreamQuery(ctx, queryCtx, q.spool)
+	if err == nil {
+		err = q.spool.finish()
+	}
+	if err != nil {
+		s.logger.Error().Err(err).Str("query_id", q.id).Msg("Asynchronous query failed")
+		q.err = err
+		q.cancelled = ctx.Err() == context.Canceled
+		// The query may also have been cancelled through another protocol
+		if info, infoErr := s.queryEngine.GetQueryInfo(q.id); infoErr == nil && info.Status == query.QueryStatusCancelled {
+			q.cancelled = true
+		}
+	} else {
+		q.message = result.Message
+	}
+	q.finishedAt = time.Now()
+	close(q.done)
+}
+
+// handleQueryStatus reports the status and progress of a submitted query
+func (s *Server) handleQueryStatus(w http.ResponseWriter, r *http.Request) {
+	username, ok := s.authenticate(w, r)
+	if !ok {
+		return
+	}
+	q, err := s.async.get(r.PathValue("id"), username)
+	if err != nil {
+		s.writeError(w, err)
+		return
+	}
+	s.writeJSON(w, http.StatusOK, s.asyncStatus(q))
+}
+
+// handleQueryResults returns a page of the result of a finished query
+func (s *Server) handleQueryResults(w http.ResponseWriter, r *http.Request) {
+	username, ok := s.authenticate(w, r)
+	if !ok {
+		return
+	}
+	q, err := s.async.get(r.PathValue("id"), username)
+	if err != nil {
+		s.writeError(w, err)
+		return
+	}
+	if !q.finished() {
+		s.writeError(w, errors.New(ErrQueryRunning, "query is still running", nil).AddContext("query_id", q.id))
+		return
+	}
+	if q.err != nil {
+		s.writeError(w, q.err)
+		return
+	}
+
+	offset, err := pageParam(r, "offset", 0)
+	if err != nil {
+		s.writeError(w, err)
+		return
+	}
+	limit, err := pageParam(r, "limit", defaultPageRows)
+	if err != nil {
+		s.writeError(w, err)
+		return
+	}
+	limit = min(limit, maxPageRows)
+
+	rows, err := q.spool.read(offset, limit)
+	if err != nil {
+		s.writeError(w, err)
+		return
+	}
+	progress := q.spool.progress()
+	page := asyncQueryPage{
+		QueryID:   q.id,
+		Meta:      q.spool.columns,
+		Data:      rows,
+		Offset:    offset,
+		Rows:      len(rows),
+		TotalRows: progress.rows,
+		Truncated: progress.truncated,
+	}
+	if next := offset + int64(len(rows)); next < progress.rows {
+		page.NextURI = fmt.Sprintf("/v1/queries/%s/results?offset=%d&limit=%d", q.id, next, limit)
+	}
+	s.writeJSON(w, http.StatusOK, page)
+}
+
+// handleCancelQuery cancels a running query, or discards the result of a finished one
+func (s *Server) handleCancelQuery(w http.ResponseWriter, r *http.Request) {
+	username, ok := s.authenticate(w, r)
+	if !ok {
+		return
+	}
+	q, err := s.async.get(r.PathValue("id"), username)
+	if err != nil {
+		s.writeError(w, err)
+		return
+	}
+
+	if !q.finished() {
+		// The engine has not registered a query that is just starting, so the query's own
+		// context is cancelled as well
+		if err := s.queryEngine.CancelQuery(q.id); err != nil {
+			s.logger.Debug().Err(err).Str("query_id", q.id).Msg("Query not cancelled by the engine")
+		}
+		q.cancel()
+		<-q.done
+		s.logger.Info().Str("query_id", q.id).Str("user", username).Msg("Asynchronous query cancelled")
+	}
+	if err := s.async.remove(q); err != nil {
+		s.logger.Warn().Err(err).Str("query_id", q.id).Msg("Failed to remove query result")
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// asyncStatus describes a submitted query, taking progress from the query engine while
+// it runs
+func (s *Server) asyncStatus(q *asyncQuery) asyncQueryStatus {
+	progress := q.spool.progress()
+	status := asyncQueryStatus{
+		QueryID:     q.id,
+		Status:      string(query.QueryStatusPending),
+		User:        q.user,
+		Query:       q.sql,
+		SubmittedAt: q.submittedAt,
+		Elapsed:     time.Since(q.submittedAt).Seconds(),
+		Rows:        progress.rows,
+		Bytes:       progress.bytes,
+		Truncated:   progress.truncated,
+	}
+
+	if !q.finished() {
+		if info, err := s.queryEngine.GetQueryInfo(q.id); err == nil {
+			status.Status = string(query.QueryStatusRunning)
+			status.RowsRead = info.RowsRead
+		}
+		return status
+	}
+
+	finishedAt, expiresAt := q.finishedAt, q.finishedAt.Add(s.async.ttl)
+	status.FinishedAt, status.ExpiresAt = &finishedAt, &expiresAt
+	status.Elapsed = finishedAt.Sub(q.submittedAt).Seconds()
+	status.RowsRead = progress.rows
+	if info, err := s.queryEngine.GetQueryInfo(q.id); err == nil && info.RowCount > 0 {
+		status.RowsRead = info.RowCount
+	}
+	switch {
+	case q.cancelled:
+		status.Status = string(query.QueryStatusCancelled)
+	case q.err != nil:
+		status.Status = string(query.QueryStatusFailed)
+		status.Error = q.err.Error()
+		status.ErrorCode = errors.GetCode(q.err)
+	default:
+		status.Status = string(query.QueryStatusCompleted)
+		status.Message = q.message
+		status.Meta = q.spool.columns
+		status.ResultsURI = "/v1/queries/" + q.id + "/results"
+	}
+	return status
+}
+
+// expireQueries removes query results older than the TTL until the server stops, and all
+// remaining ones when it does
+func (s *Server) expireQueries() {
+	defer s.wg.Done()
+
+	interval := min(max(s.async.ttl/4, time.Second), time.Minute)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-s.ctx.Done():
+			for _, q := range s.async.expired(time.Now(), true) {
+				<-q.done
+				s.async.remove(q)
+			}
+			return
+		case now := <-ticker.C:
+			for _, q := range s.async.expired(now, false) {
+				if err := s.async.remove(q); err != nil {
+					s.logger.Warn().Err(err).Str("query_id", q.id).Msg("Failed to remove expired query result")
+				}
+			}
+		}
+	}
+}
+
+// pageParam reads a non-negative pagination parameter
+func pageParam(r *http.Request, name string, fallback int64) (int64, error) {
+	text := r.URL.Query().Get(name)
+	if text == "" {
+		return fallback, nil
+	}
+	value, err := strconv.ParseInt(text, 10, 64)
+	if err != nil || value < 0 {
+		return 0, errors.New(ErrInvalidPage, fmt.Sprintf("%s must be a non-negative integer", name), err).AddContext(name, text)
+	}
+	return value, nil
+}
+
+// writeJSON sends a JSON response body
+func (s *Server) writeJSON(w http.ResponseWriter, status int, body interface{}) {
+	data, err := json.Marshal(body)
+	if err != nil {
+		s.logger.Error().Err(err).Msg("Failed to marshal response")
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	w.Write(data)
+}
@@ -0,0 +1,24 @@
+package http
+
+import "github.com/gear6io/ranger/pkg/errors"
+
+// HTTP-specific error codes
+var (
+	ErrInvalidRequest       = errors.MustNewCode("http.invalid_request")
+	ErrRequestTooLarge      = errors.MustNewCode("http.request_too_large")
+	ErrMissingQuery         = errors.MustNewCode("http.missing_query")
+	ErrUnknownFormat        = errors.MustNewCode("http.unknown_format")
+	ErrUnknownSetting       = errors.MustNewCode("http.unknown_setting")
+	ErrInvalidSetting       = errors.MustNewCode("http.invalid_setting")
+	ErrMissingParameter     = errors.MustNewCode("http.missing_parameter")
+	ErrInvalidParameter     = errors.MustNewCode("http.invalid_parameter")
+	ErrResultWriteFailed    = errors.MustNewCode("http.result_write_failed")
+	ErrQueryNotFound        = errors.MustNewCode("http.query_not_found")
+	ErrQueryRunning         = errors.MustNewCode("http.query_running")
+	ErrInvalidPage          = errors.MustNewCode("http.invalid_page")
+	ErrSpoolFailed          = errors.MustNewCode("http.spool_failed")
+	ErrUnsupportedMediaType = errors.MustNewCode("http.unsupported_media_type")
+	ErrIngestInvalid        = errors.MustNewCode("http.ingest_invalid")
+	ErrUnknownColumn        = errors.MustNewCode("http.unknown_column")
+	ErrTooManyInvalidRows   = errors.MustNewCode("http.too_many_invalid_rows")
+)
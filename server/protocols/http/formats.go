@@ -0,0 +1,440 @@
+package http
+
+import (
+	"encoding/base64"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/gear6io/ranger/pkg/errors"
+	"github.com/google/uuid"
+)
+
+// outputFormat is a result encoding, selected by the format URL parameter or the Accept header
+type outputFormat struct {
+	name        string
+	aliases     []string
+	contentType string
+	mediaTypes  []string // Accept header media types selecting the format
+	newEncoder  func(w io.Writer) resultEncoder
+}
+
+// outputFormats lists the result encodings; the first is the default
+var outputFormats = []*outputFormat{
+	{
+		name:        "JSON",
+		contentType: "application/json",
+		mediaTypes:  []string{"application/json"},
+		newEncoder:  func(w io.Writer) resultEncoder { return &jsonEncoder{w: w} },
+	},
+	{
+		name:        "JSONEachRow",
+		aliases:     []string{"NDJSON", "JSONL"},
+		contentType: "application/x-ndjson",
+		mediaTypes:  []string{"application/x-ndjson", "application/ndjson", "application/jsonl"},
+		newEncoder:  func(w io.Writer) resultEncoder { return &ndjsonEncoder{w: w} },
+	},
+	{
+		name:        "CSV",
+		aliases:     []string{"CSVWithNames"},
+		contentType: "text/csv; charset=utf-8",
+		mediaTypes:  []string{"text/csv"},
+		newEncoder:  func(w io.Writer) resultEncoder { return &csvEncoder{w: csv.NewWriter(w)} },
+	},
+	{
+		name:        "TSV",
+		aliases:     []string{"TabSeparatedWithNames", "TSVWithNames"},
+		contentType: "text/tab-separated-values; charset=utf-8",
+		mediaTypes:  []string{"text/tab-separated-values"},
+		newEncoder:  func(w io.Writer) resultEncoder { return &tsvEncoder{w: w} },
+	},
+	{
+		name:        "Arrow",
+		aliases:     []string{"ArrowStream"},
+		contentType: "application/vnd.apache.arrow.stream",
+		mediaTypes:  []string{"application/vnd.apache.arrow.stream"},
+		newEncoder:  func(w io.Writer) resultEncoder { return &arrowEncoder{w: w} },
+	},
+	{
+		name:        "Parquet",
+		contentType: "application/vnd.apache.parquet",
+		mediaTypes:  []string{"application/vnd.apache.parquet", "application/x-parquet"},
+		newEncoder:  func(w io.Writer) resultEncoder { return &parquetEncoder{w: w} },
+	},
+}
+
+// selectFormat picks the output format named by the format URL parameter, or else the
+// first format of the Accept header the server can produce
+func selectFormat(r *http.Request) (*outputFormat, error) {
+	if name := r.URL.Query().Get("format"); name != "" {
+		for _, format := range outputFormats {
+			if strings.EqualFold(format.name, name) {
+				return format, nil
+			}
+			for _, alias := range format.aliases {
+				if strings.EqualFold(alias, name) {
+					return format, nil
+				}
+			}
+		}
+		return nil, errors.New(ErrUnknownFormat, fmt.Sprintf("unknown output format %s", name), nil).AddContext("format", name)
+	}
+
+	accept := r.Header.Get("Accept")
+	if accept == "" {
+		return outputFormats[0], nil
+	}
+	wildcard := false
+	for _, part := range strings.Split(accept, ",") {
+		mediaType, _, _ := strings.Cut(part, ";")
+		mediaType = strings.ToLower(strings.TrimSpace(mediaType))
+		if mediaType == "*/*" || mediaType == "application/*" {
+			wildcard = true
+			continue
+		}
+		for _, format := range outputFormats {
+			for _, candidate := range format.mediaTypes {
+				if mediaType == candidate {
+					return format, nil
+				}
+			}
+		}
+	}
+	if wildcard {
+		return outputFormats[0], nil
+	}
+	return nil, errors.New(ErrUnknownFormat, "no acceptable output format", nil).AddContext("accept", accept)
+}
+
+// resultColumn describes a result column
+type resultColumn struct {
+	Name string `json:"name"`
+	Type string `json:"type"`
+}
+
+// resultSummary describes a completed result
+type resultSummary struct {
+	Rows      int64
+	Message   string
+	Truncated bool
+	Elapsed   time.Duration
+}
+
+// resultEncoder writes a query result in an output format
+type resultEncoder interface {
+	// begin starts the output with the result columns
+	begin(queryID string, columns []resultColumn) error
+	// row writes a result row
+	row(values []interface{}) error
+	// flush passes rows buffered by the encoder on to the response
+	flush() error
+	// end completes the output after the last row
+	end(summary resultSummary) error
+	// abort ends the output after an error, reporting it in-band where the format allows
+	abort(err error) error
+}
+
+// jsonEncoder writes a single JSON object with the columns under meta and the rows, as
+// arrays, under data
+type jsonEncoder struct {
+	w       io.Writer
+	columns []resultColumn
+	rows    int64
+}
+
+func (e *jsonEncoder) begin(queryID string, columns []resultColumn) error {
+	e.columns = columns
+	id, _ := json.Marshal(queryID)
+	meta, err := json.Marshal(columns)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(e.w, `{"query_id":%s,"meta":%s,"data":[`, id, meta)
+	return err
+}
+
+func (e *jsonEncoder) row(values []interface{}) error {
+	row := make([]interface{}, len(values))
+	for i, value := range values {
+		row[i] = jsonValue(value, columnType(e.columns, i))
+	}
+	data, err := json.Marshal(row)
+	if err != nil {
+		return err
+	}
+	if e.rows > 0 {
+		data = append([]byte{','}, data...)
+	}
+	e.rows++
+	_, err = e.w.Write(data)
+	return err
+}
+
+func (e *jsonEncoder) flush() error { return nil }
+
+func (e *jsonEncoder) end(summary resultSummary) error {
+	message, _ := json.Marshal(summary.Message)
+	_, err := fmt.Fprintf(e.w, `],"rows":%d,"message":%s,"truncated":%t,"statistics":{"elapsed":%g}}`+"\n",
+		summary.Rows, message, summary.Truncated, summary.Elapsed.Seconds())
+	return err
+}
+
+func (e *jsonEncoder) abort(cause error) error {
+	exception, _ := json.Marshal(cause.Error())
+	_, err := fmt.Fprintf(e.w, `],"exception":%s}`+"\n", exception)
+	return err
+}
+
+// ndjsonEncoder writes one JSON object per row, keyed by column name
+type ndjsonEncoder struct {
+	w       io.Writer
+	columns []resultColumn
+	keys    [][]byte
+}
+
+func (e *ndjsonEncoder) begin(queryID string, columns []resultColumn) error {
+	e.columns = columns
+	e.keys = make([][]byte, len(columns))
+	for i, column := range columns {
+		key, err := json.Marshal(column.Name)
+		if err != nil {
+			return err
+		}
+		e.keys[i] = append(key, ':')
+	}
+	return nil
+}
+
+func (e *ndjsonEncoder) row(values []interface{}) error {
+	line := []byte{'{'}
+	for i, value := range values {
+		if i >= len(e.keys) {
+			break
+		}
+		data, err := json.Marshal(jsonValue(value, columnType(e.columns, i)))
+		if err != nil {
+			return err
+		}
+		if i > 0 {
+			line = append(line, ',')
+		}
+		line = append(append(line, e.keys[i]...), data...)
+	}
+	_, err := e.w.Write(append(line, '}', '\n'))
+	return err
+}
+
+func (e *ndjsonEncoder) flush() error { return nil }
+
+func (e *ndjsonEncoder) end(summary resultSummary) error { return nil }
+
+func (e *ndjsonEncoder) abort(cause error) error {
+	exception, _ := json.Marshal(cause.Error())
+	_, err := fmt.Fprintf(e.w, `{"exception":%s}`+"\n", exception)
+	return err
+}
+
+// csvEncoder writes RFC 4180 CSV with a header row; NULL is an empty field
+type csvEncoder struct {
+	w       *csv.Writer
+	columns []resultColumn
+}
+
+func (e *csvEncoder) begin(queryID string, columns []resultColumn) error {
+	e.columns = columns
+	names := make([]string, len(columns))
+	for i, column := range columns {
+		names[i] = column.Name
+	}
+	return e.w.Write(names)
+}
+
+func (e *csvEncoder) row(values []interface{}) error {
+	record := make([]string, len(values))
+	for i, value := range values {
+		if value != nil {
+			record[i] = formatText(value, columnType(e.columns, i))
+		}
+	}
+	return e.w.Write(record)
+}
+
+func (e *csvEncoder) flush() error {
+	e.w.Flush()
+	return e.w.Error()
+}
+
+func (e *csvEncoder) end(summary resultSummary) error { return e.flush() }
+
+func (e *csvEncoder) abort(cause error) error {
+	if err := e.w.Write([]string{"Exception: " + cause.Error()}); err != nil {
+		return err
+	}
+	return e.flush()
+}
+
+// tsvEncoder writes tab-separated values with a header row, escaping tabs, newlines and
+// backslashes; NULL is \N
+type tsvEncoder struct {
+	w       io.Writer
+	columns []resultColumn
+}
+
+// tsvEscaper escapes the characters that delimit tab-separated values
+var tsvEscaper = strings.NewReplacer("\\", "\\\\", "\t", "\\t", "\n", "\\n", "\r", "\\r")
+
+func (e *tsvEncoder) begin(queryID string, columns []resultColumn) error {
+	e.columns = columns
+	names := make([]string, len(columns))
+	for i, column := range columns {
+		names[i] = tsvEscaper.Replace(column.Name)
+	}
+	_, err := io.WriteString(e.w, strings.Join(names, "\t")+"\n")
+	return err
+}
+
+func (e *tsvEncoder) row(values []interface{}) error {
+	fields := make([]string, len(values))
+	for i, value := range values {
+		if value == nil {
+			fields[i] = `\N`
+		} else {
+			fields[i] = tsvEscaper.Replace(formatText(value, columnType(e.columns, i)))
+		}
+	}
+	_, err := io.WriteString(e.w, strings.Join(fields, "\t")+"\n")
+	return err
+}
+
+func (e *tsvEncoder) flush() error { return nil }
+
+func (e *tsvEncoder) end(summary resultSummary) error { return nil }
+
+func (e *tsvEncoder) abort(cause error) error {
+	_, err := io.WriteString(e.w, "Exception: "+tsvEscaper.Replace(cause.Error())+"\n")
+	return err
+}
+
+// columnType returns the type name of column i
+func columnType(columns []resultColumn, i int) string {
+	if i < len(columns) {
+		return columns[i].Type
+	}
+	return ""
+}
+
+// baseTypeName returns a DuckDB type name without its parameters, as in DECIMAL(18,3)
+func baseTypeName(typeName string) string {
+	base, _, _ := strings.Cut(typeName, "(")
+	return strings.ToUpper(strings.TrimSpace(base))
+}
+
+// formatText renders a non-NULL value for the text formats
+func formatText(value interface{}, typeName string) string {
+	switch value := value.(type) {
+	case string:
+		return value
+	case time.Time:
+		switch baseTypeName(typeName) {
+		case "DATE":
+			return value.Format(time.DateOnly)
+		case "TIME":
+			return value.Format("15:04:05.999999")
+		case "TIMESTAMPTZ", "TIMESTAMP WITH TIME ZONE":
+			return value.Format("2006-01-02 15:04:05.999999Z07:00")
+		}
+		return value.Format("2006-01-02 15:04:05.999999")
+	case []byte:
+		if baseTypeName(typeName) == "UUID" && len(value) == 16 {
+			return uuid.UUID(value).String()
+		}
+		return string(value)
+	case float64:
+		return strconv.FormatFloat(value, 'g', -1, 64)
+	case float32:
+		return strconv.FormatFloat(float64(value), 'g', -1, 32)
+	case []interface{}, map[string]interface{}:
+		return formatNested(value, typeName)
+	default:
+		if reflect.ValueOf(value).Kind() == reflect.Map {
+			return formatNested(value, typeName)
+		}
+		return fmt.Sprint(value)
+	}
+}
+
+// formatNested renders a list, map or struct value as JSON for the text formats
+func formatNested(value interface{}, typeName string) string {
+	data, err := json.Marshal(jsonValue(value, typeName))
+	if err != nil {
+		return fmt.Sprint(value)
+	}
+	return string(data)
+}
+
+// elementTypeName returns the element type of a DuckDB list type name such as DATE[]
+func elementTypeName(typeName string) string {
+	if element, ok := strings.CutSuffix(strings.TrimSpace(typeName), "[]"); ok {
+		return element
+	}
+	return ""
+}
+
+// jsonValue converts a value to one encoding/json renders faithfully
+func jsonValue(value interface{}, typeName string) interface{} {
+	switch value := value.(type) {
+	case nil, bool, string, int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64:
+		return value
+	case float64:
+		if math.IsNaN(value) || math.IsInf(value, 0) {
+			return formatText(value, typeName)
+		}
+		return value
+	case float32:
+		if math.IsNaN(float64(value)) || math.IsInf(float64(value), 0) {
+			return formatText(value, typeName)
+		}
+		return value
+	case time.Time:
+		return formatText(value, typeName)
+	case []byte:
+		if baseTypeName(typeName) == "UUID" || utf8.Valid(value) {
+			return formatText(value, typeName)
+		}
+		return base64.StdEncoding.EncodeToString(value)
+	case []interface{}:
+		converted := make([]interface{}, len(value))
+		for i, element := range value {
+			converted[i] = jsonValue(element, elementTypeName(typeName))
+		}
+		return converted
+	case map[string]interface{}:
+		converted := make(map[string]interface{}, len(value))
+		for key, element := range value {
+			converted[key] = jsonValue(element, "")
+		}
+		return converted
+	case fmt.Stringer:
+		// DuckDB decimals, UUIDs and intervals
+		return value.String()
+	default:
+		// DuckDB maps have keys of any type, which JSON objects cannot have
+		if entries := reflect.ValueOf(value); entries.Kind() == reflect.Map {
+			converted := make(map[string]interface{}, entries.Len())
+			iter := entries.MapRange()
+			for iter.Next() {
+				converted[formatText(iter.Key().Interface(), "")] = jsonValue(iter.Value().Interface(), "")
+			}
+			return converted
+		}
+		return value
+	}
+}
@@ -0,0 +1,167 @@
+package http
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/apache/arrow-go/v18/arrow"
+	"github.com/apache/arrow-go/v18/arrow/array"
+	"github.com/apache/arrow-go/v18/arrow/ipc"
+	"github.com/apache/arrow-go/v18/arrow/memory"
+	"github.com/apache/arrow-go/v18/parquet/file"
+	"github.com/apache/arrow-go/v18/parquet/pqarrow"
+	"github.com/gear6io/ranger/pkg/errors"
+	"github.com/gear6io/ranger/server/query"
+	"github.com/gear6io/ranger/server/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var (
+	testColumns     = []string{"id", "name", "day", "score"}
+	testColumnTypes = []string{"BIGINT", "VARCHAR", "DATE", "DOUBLE"}
+	testRows        = [][]interface{}{
+		{int64(1), "tab\there", time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC), 1.5},
+		{int64(2), nil, time.Date(2024, 3, 2, 0, 0, 0, 0, time.UTC), nil},
+	}
+)
+
+// streamResult writes the test rows through a resultStream and returns the response
+func streamResult(t *testing.T, formatName string, maxRows int64) *httptest.ResponseRecorder {
+	t.Helper()
+	var format *outputFormat
+	for _, candidate := range outputFormats {
+		if candidate.name == formatName {
+			format = candidate
+		}
+	}
+	require.NotNil(t, format)
+
+	recorder := httptest.NewRecorder()
+	stream := newResultStream(recorder, format, "q1", maxRows)
+	require.NoError(t, stream.Columns(testColumns, testColumnTypes))
+	for _, row := range testRows {
+		if err := stream.Row(row); err != nil {
+			require.Equal(t, types.ErrStopRows.String(), errors.GetCode(err))
+			break
+		}
+	}
+	require.NoError(t, stream.finish(&query.QueryResult{Message: "OK"}, time.Second))
+	return recorder
+}
+
+func TestTextFormats(t *testing.T) {
+	response := streamResult(t, "JSON", 0)
+	assert.Equal(t, "application/json", response.Header().Get("Content-Type"))
+	var result struct {
+		QueryID string          `json:"query_id"`
+		Meta    []resultColumn  `json:"meta"`
+		Data    [][]interface{} `json:"data"`
+		Rows    int64           `json:"rows"`
+	}
+	require.NoError(t, json.Unmarshal(response.Body.Bytes(), &result), response.Body.String())
+	assert.Equal(t, "q1", result.QueryID)
+	assert.Equal(t, resultColumn{Name: "day", Type: "DATE"}, result.Meta[2])
+	assert.Equal(t, []interface{}{float64(1), "tab\there", "2024-03-01", 1.5}, result.Data[0])
+	assert.Equal(t, int64(2), result.Rows)
+
+	assert.Equal(t, `{"id":1,"name":"tab\there","day":"2024-03-01","score":1.5}`+"\n"+
+		`{"id":2,"name":null,"day":"2024-03-02","score":null}`+"\n", streamResult(t, "JSONEachRow", 0).Body.String())
+	assert.Equal(t, "id,name,day,score\n1,tab\there,2024-03-01,1.5\n2,,2024-03-02,\n", streamResult(t, "CSV", 0).Body.String())
+	assert.Equal(t, "id\tname\tday\tscore\n1\ttab\\there\t2024-03-01\t1.5\n2\t\\N\t2024-03-02\t\\N\n", streamResult(t, "TSV", 0).Body.String())
+
+	// max_result_rows ends the result early
+	assert.Equal(t, "id,name,day,score\n1,tab\there,2024-03-01,1.5\n", streamResult(t, "CSV", 1).Body.String())
+	require.NoError(t, json.Unmarshal(streamResult(t, "JSON", 1).Body.Bytes(), &result))
+	assert.Len(t, result.Data, 1)
+}
+
+func TestArrowFormats(t *testing.T) {
+	reader, err := ipc.NewReader(bytes.NewReader(streamResult(t, "Arrow", 0).Body.Bytes()))
+	require.NoError(t, err)
+	defer reader.Release()
+	assert.Equal(t, arrow.PrimitiveTypes.Int64, reader.Schema().Field(0).Type)
+	assert.Equal(t, arrow.FixedWidthTypes.Date32, reader.Schema().Field(2).Type)
+	require.True(t, reader.Next())
+	record := reader.Record()
+	assert.Equal(t, int64(2), record.NumRows())
+	assert.Equal(t, "tab\there", record.Column(1).(*array.String).Value(0))
+	assert.True(t, record.Column(3).IsNull(1))
+	assert.False(t, reader.Next())
+	require.NoError(t, reader.Err())
+
+	parquetFile, err := file.NewParquetReader(bytes.NewReader(streamResult(t, "Parquet", 0).Body.Bytes()))
+	require.NoError(t, err)
+	defer parquetFile.Close()
+	fileReader, err := pqarrow.NewFileReader(parquetFile, pqarrow.ArrowReadProperties{}, memory.DefaultAllocator)
+	require.NoError(t, err)
+	table, err := fileReader.ReadTable(context.Background())
+	require.NoError(t, err)
+	defer table.Release()
+	assert.Equal(t, int64(2), table.NumRows())
+	assert.Equal(t, "score", table.Schema().Field(3).Name)
+}
+
+func TestNestedFormats(t *testing.T) {
+	columns := []string{"tags", "attrs", "point"}
+	columnTypes := []string{"DATE[]", "MAP(INTEGER, VARCHAR)", "STRUCT(x INTEGER, label VARCHAR)"}
+	day := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	// DuckDB returns lists as slices, structs as string-keyed maps and maps with keys of any type
+	row := []interface{}{
+		[]interface{}{day, nil},
+		map[interface{}]interface{}{int32(1): "a,b"},
+		map[string]interface{}{"x": int32(3), "label": nil},
+	}
+	stream := func(formatName string) *httptest.ResponseRecorder {
+		var format *outputFormat
+		for _, candidate := range outputFormats {
+			if candidate.name == formatName {
+				format = candidate
+			}
+		}
+		recorder := httptest.NewRecorder()
+		stream := newResultStream(recorder, format, "q1", 0)
+		require.NoError(t, stream.Columns(columns, columnTypes))
+		require.NoError(t, stream.Row(row))
+		require.NoError(t, stream.finish(&query.QueryResult{Message: "OK"}, time.Second))
+		return recorder
+	}
+
+	assert.Equal(t, `{"tags":["2024-03-01",null],"attrs":{"1":"a,b"},"point":{"label":null,"x":3}}`+"\n",
+		stream("JSONEachRow").Body.String())
+	assert.Equal(t, "tags,attrs,point\n"+`"[""2024-03-01"",null]","{""1"":""a,b""}","{""label"":null,""x"":3}"`+"\n",
+		stream("CSV").Body.String())
+
+	// Arrow keeps lists as lists and writes maps and structs as JSON
+	reader, err := ipc.NewReader(bytes.NewReader(stream("Arrow").Body.Bytes()))
+	require.NoError(t, err)
+	defer reader.Release()
+	assert.Equal(t, arrow.ListOf(arrow.FixedWidthTypes.Date32), reader.Schema().Field(0).Type)
+	require.True(t, reader.Next())
+	tags := reader.Record().Column(0).(*array.List)
+	assert.Equal(t, `[19783 (null)]`, tags.ListValues().String())
+	assert.Equal(t, `{"1":"a,b"}`, reader.Record().Column(1).(*array.String).Value(0))
+}
+
+func TestResultStreamFailure(t *testing.T) {
+	format := outputFormats[0]
+
+	// Before the result starts, errors are left to the handler
+	recorder := httptest.NewRecorder()
+	stream := newResultStream(recorder, format, "q1", 0)
+	assert.False(t, stream.fail(errors.New(ErrInvalidRequest, "bad", nil)))
+
+	// Afterwards they end the result in-band and in the trailer
+	stream = newResultStream(recorder, format, "q1", 0)
+	require.NoError(t, stream.Columns(testColumns, testColumnTypes))
+	require.NoError(t, stream.Row(testRows[0]))
+	assert.True(t, stream.fail(errors.New(ErrResultWriteFailed, "connection lost", nil)))
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.Contains(t, recorder.Body.String(), `"exception":"connection lost"`)
+	assert.Equal(t, "connection lost", recorder.Result().Trailer.Get(exceptionTrailer))
+}
@@ -0,0 +1,277 @@
+package http
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/apache/arrow-go/v18/arrow"
+	"github.com/gear6io/ranger/pkg/errors"
+	"github.com/gear6io/ranger/server/query/access"
+	"github.com/gear6io/ranger/server/query/parser"
+	"github.com/gear6io/ranger/server/storage/parquet"
+	"github.com/gear6io/ranger/server/types"
+	"github.com/google/uuid"
+)
+
+// Defaults of the table settings used by the insert endpoint
+const (
+	defaultIngestBatchSize = 10000
+	defaultIngestMaxErrors = 100
+)
+
+// maxReportedRowErrors caps the row errors listed in an insert report
+const maxReportedRowErrors = 1000
+
+// ingestRowError describes a rejected row of an insert body; rows are numbered from 1
+type ingestRowError struct {
+	Row    int64  `json:"row"`
+	Column string `json:"column,omitempty"`
+	Error  string `json:"error"`
+}
+
+// ingestReport is the JSON response of POST /v1/tables/{db}/{table}/insert
+type ingestReport struct {
+	Database     string           `json:"database"`
+	Table        string           `json:"table"`
+	RowsRead     int64            `json:"rows_read"`
+	RowsInserted int64            `json:"rows_inserted"`
+	RowsRejected int64            `json:"rows_rejected"`
+	Errors       []ingestRowError `json:"errors"`
+	Error        string           `json:"error,omitempty"`
+	ErrorCode    string           `json:"error_code,omitempty"`
+	Elapsed      float64          `json:"elapsed"`
+}
+
+// ingestBatcher validates the rows of an insert body and commits them a batch at a time.
+// Invalid rows are rejected until more than maxErrors of them fail the insert.
+type ingestBatcher struct {
+	database  string
+	table     string
+	schema    *arrow.Schema
+	batchSize int
+	maxErrors int64
+	commit    func(rows [][]interface{}) error
+	report    *ingestReport
+
+	rows    [][]interface{}
+	numbers []int64 // body row number of each buffered row
+}
+
+// add buffers a row, committing the batch once it is full
+func (b *ingestBatcher) add(number int64, row []interface{}) error {
+	b.rows = append(b.rows, row)
+	b.numbers = append(b.numbers, number)
+	if len(b.rows) >= b.batchSize {
+		return b.flush()
+	}
+	return nil
+}
+
+// reject records a row that cannot be inserted
+func (b *ingestBatcher) reject(number int64, column string, err error) error {
+	b.report.RowsRejected++
+	if len(b.report.Errors) < maxReportedRowErrors {
+		b.report.Errors = append(b.report.Errors, ingestRowError{Row: number, Column: column, Error: err.Error()})
+	}
+	if b.report.RowsRejected > b.maxErrors {
+		return errors.New(ErrTooManyInvalidRows, fmt.Sprintf("more than %d invalid rows", b.maxErrors), nil).
+			AddContext("database", b.database).
+			AddContext("table", b.table)
+	}
+	return nil
+}
+
+// flush validates the buffered rows, rejecting the invalid ones, and commits the rest
+func (b *ingestBatcher) flush() error {
+	rows, numbers := b.rows, b.numbers
+	b.rows, b.numbers = nil, nil
+
+	for start := 0; start < len(rows); {
+		err := parquet.ValidateDataWithContext(rows[start:], b.schema, b.database, b.table)
+		if err == nil {
+			break
+		}
+		detail, ok := validationDetail(err)
+		if !ok {
+			return err
+		}
+		bad := start + detail.RowIndex
+		if err := b.reject(numbers[bad], detail.ColumnName, validationMessage(detail)); err != nil {
+			return err
+		}
+		rows = append(rows[:bad], rows[bad+1:]...)
+		numbers = append(numbers[:bad], numbers[bad+1:]...)
+		start = bad
+	}
+
+	if len(rows) == 0 {
+		return nil
+	}
+	if err := b.commit(rows); err != nil {
+		return err
+	}
+	b.report.RowsInserted += int64(len(rows))
+	return nil
+}
+
+// validationDetail returns the row and column a validation error is about
+func validationDetail(err error) (*parquet.DetailedValidationError, bool) {
+	if e, ok := err.(*errors.Error); ok {
+		detail, ok := e.Cause.(*parquet.DetailedValidationError)
+		return detail, ok
+	}
+	return nil, false
+}
+
+// validationMessage describes a validation failure without repeating its row and column
+func validationMessage(detail *parquet.DetailedValidationError) error {
+	if detail.ExpectedType == "" {
+		return detail
+	}
+	return fmt.Errorf("expected %s but got %s", detail.ExpectedType, detail.ActualType)
+}
+
+// handleInsert bulk loads a CSV, NDJSON, Arrow IPC or Parquet body into a table and reports
+// the rows that were rejected. Batches are committed as they fill, so a failed insert may
+// leave the batches before the failure in the table.
+func (s *Server) handleInsert(w http.ResponseWriter, r *http.Request) {
+	start := time.Now()
+	database, table := r.PathValue("db"), r.PathValue("table")
+
+	username, ok := s.authenticate(w, r)
+	if !ok {
+		return
+	}
+	queryCtx := &types.QueryContext{
+		Query:      fmt.Sprintf("INSERT INTO %s.%s", database, table),
+		Database:   database,
+		User:       username,
+		ClientAddr: r.RemoteAddr,
+		Protocol:   types.ProtocolHTTP,
+		QueryID:    uuid.NewString(),
+	}
+	var failure error
+	defer func() {
+		s.queryEngine.AuditInsert(s.ctx, queryCtx, database, table, start, failure)
+	}()
+	fail := func(err error) {
+		failure = err
+		s.writeError(w, err)
+	}
+
+	// Nothing about the table is looked up, nor is the body read, for users who may not
+	// insert into any of its columns
+	ctx := r.Context()
+	requirement := access.Requirement{Action: parser.PRIV_INSERT, Database: database, Table: table, AnyColumn: true}
+	if err := s.queryEngine.Authorize(ctx, username, requirement); err != nil {
+		fail(err)
+		return
+	}
+
+	format, err := selectIngestFormat(r)
+	if err != nil {
+		fail(err)
+		return
+	}
+	icebergSchema, err := s.queryEngine.GetTableSchema(ctx, database, table)
+	if err != nil {
+		fail(err)
+		return
+	}
+	fields := icebergSchema.Fields()
+	arrowSchema, err := parquet.ConvertIcebergToArrowSchema(icebergSchema)
+	if err != nil {
+		fail(err)
+		return
+	}
+
+	if s.maxInsertBytes > 0 {
+		r.Body = http.MaxBytesReader(w, r.Body, s.maxInsertBytes)
+	}
+	decoder, err := format.newDecoder(r, fields)
+	if err != nil {
+		fail(err)
+		return
+	}
+	defer decoder.close()
+
+	// The columns the body fills in are known once the decoder has read its header
+	requirement.Columns, requirement.AnyColumn = decoder.columns(), false
+	if err := s.queryEngine.Authorize(ctx, username, requirement); err != nil {
+		fail(err)
+		return
+	}
+	settings, err := s.queryEngine.GetTableSettings(ctx, database, table)
+	if err != nil {
+		fail(err)
+		return
+	}
+
+	report := &ingestReport{Database: database, Table: table, Errors: []ingestRowError{}}
+	batcher := &ingestBatcher{
+		database:  database,
+		table:     table,
+		schema:    arrowSchema,
+		batchSize: defaultIngestBatchSize,
+		maxErrors: defaultIngestMaxErrors,
+		report:    report,
+		commit: func(rows [][]interface{}) error {
+			return s.queryEngine.InsertDataBatchStreaming(ctx, database, table, rows, len(rows))
+		},
+	}
+	if settings != nil {
+		if settings.BatchSize > 0 {
+			batcher.batchSize = settings.BatchSize
+		}
+		batcher.maxErrors = int64(settings.MaxValidationErrors)
+	}
+
+	s.logger.Info().Str("database", database).Str("table", table).Str("user", username).Str("format", format.name).Msg("Inserting data via HTTP")
+	failure = ingestRows(ctx, decoder, batcher)
+	report.Elapsed = time.Since(start).Seconds()
+	sort.SliceStable(report.Errors, func(i, j int) bool { return report.Errors[i].Row < report.Errors[j].Row })
+
+	status := http.StatusOK
+	if failure != nil {
+		s.logger.Error().Err(failure).Str("database", database).Str("table", table).Msg("HTTP insert failed")
+		report.Error = failure.Error()
+		report.ErrorCode = errors.GetCode(failure)
+		if report.ErrorCode != "" {
+			w.Header().Set("X-Ranger-Error-Code", report.ErrorCode)
+		}
+		status = errorStatus(failure)
+	}
+	s.writeJSON(w, status, report)
+}
+
+// ingestRows feeds the rows of a decoder through the batcher
+func ingestRows(ctx context.Context, decoder rowDecoder, batcher *ingestBatcher) error {
+	for number := int64(1); ; number++ {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		row, err := decoder.next()
+		if err == io.EOF {
+			break
+		}
+		if rowErr, ok := err.(*rowError); ok {
+			batcher.report.RowsRead++
+			if err := batcher.reject(number, rowErr.column, rowErr.err); err != nil {
+				return err
+			}
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		batcher.report.RowsRead++
+		if err := batcher.add(number, row); err != nil {
+			return err
+		}
+	}
+	return batcher.flush()
+}
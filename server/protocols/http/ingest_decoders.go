@@ -0,0 +1,419 @@
+package http
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/apache/arrow-go/v18/arrow"
+	"github.com/apache/arrow-go/v18/arrow/array"
+	"github.com/apache/arrow-go/v18/arrow/ipc"
+	"github.com/apache/arrow-go/v18/arrow/memory"
+	"github.com/apache/arrow-go/v18/parquet/file"
+	"github.com/apache/arrow-go/v18/parquet/pqarrow"
+	"github.com/apache/iceberg-go"
+	"github.com/gear6io/ranger/pkg/errors"
+	"github.com/gear6io/ranger/server/storage/parquet"
+)
+
+// rowDecoder reads the rows of an ingest body, in the column order of the table
+type rowDecoder interface {
+	// columns returns the table columns the body provides
+	columns() []string
+	// next returns the next row, a *rowError for a row that cannot be converted, or io.EOF
+	next() ([]interface{}, error)
+	close() error
+}
+
+// rowError is a row of an ingest body that cannot be inserted; it is reported and skipped
+type rowError struct {
+	column string
+	err    error
+}
+
+// Error implements the error interface
+func (e *rowError) Error() string {
+	if e.column == "" {
+		return e.err.Error()
+	}
+	return fmt.Sprintf("column %s: %v", e.column, e.err)
+}
+
+// ingestFormat is a body format accepted by the insert endpoint
+type ingestFormat struct {
+	name       string
+	aliases    []string
+	mediaTypes []string
+	newDecoder func(r *http.Request, fields []iceberg.NestedField) (rowDecoder, error)
+}
+
+// ingestFormats are the body formats of POST /v1/tables/{db}/{table}/insert
+var ingestFormats = []*ingestFormat{
+	{name: "CSV", aliases: []string{"CSVWithNames"}, mediaTypes: []string{"text/csv"}, newDecoder: newCSVDecoder},
+	{name: "JSONEachRow", aliases: []string{"NDJSON", "JSONL"}, mediaTypes: []string{"application/x-ndjson", "application/jsonl"}, newDecoder: newNDJSONDecoder},
+	{name: "Arrow", aliases: []string{"ArrowStream"}, mediaTypes: []string{"application/vnd.apache.arrow.stream"}, newDecoder: newArrowDecoder},
+	{name: "Parquet", mediaTypes: []string{"application/vnd.apache.parquet", "application/x-parquet"}, newDecoder: newParquetDecoder},
+}
+
+// selectIngestFormat picks the body format from the format URL parameter or the Content-Type
+func selectIngestFormat(r *http.Request) (*ingestFormat, error) {
+	if name := r.URL.Query().Get("format"); name != "" {
+		for _, format := range ingestFormats {
+			if strings.EqualFold(format.name, name) || containsFold(format.aliases, name) {
+				return format, nil
+			}
+		}
+		return nil, errors.New(ErrUnsupportedMediaType, fmt.Sprintf("unsupported insert format %s", name), nil).AddContext("format", name)
+	}
+
+	contentType := r.Header.Get("Content-Type")
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	for _, format := range ingestFormats {
+		if containsFold(format.mediaTypes, mediaType) {
+			return format, nil
+		}
+	}
+	return nil, errors.New(ErrUnsupportedMediaType, "unsupported Content-Type for insert, expected CSV, NDJSON, Arrow or Parquet", nil).
+		AddContext("content_type", contentType)
+}
+
+// containsFold reports whether values contains value, ignoring case
+func containsFold(values []string, value string) bool {
+	for _, candidate := range values {
+		if strings.EqualFold(candidate, value) {
+			return true
+		}
+	}
+	return false
+}
+
+// columnTargets maps the column names of a body onto positions in the table
+func columnTargets(names []string, fields []iceberg.NestedField) ([]int, error) {
+	targets := make([]int, len(names))
+	for i, name := range names {
+		targets[i] = fieldIndex(fields, strings.TrimSpace(name))
+		if targets[i] < 0 {
+			return nil, errors.New(ErrUnknownColumn, fmt.Sprintf("column %s does not exist in the table", name), nil).AddContext("column", name)
+		}
+	}
+	return targets, nil
+}
+
+// fieldIndex returns the position of the named column, or -1
+func fieldIndex(fields []iceberg.NestedField, name string) int {
+	for i, field := range fields {
+		if strings.EqualFold(field.Name, name) {
+			return i
+		}
+	}
+	return -1
+}
+
+// targetNames returns the names of the table columns at targets
+func targetNames(fields []iceberg.NestedField, targets []int) []string {
+	names := make([]string, len(targets))
+	for i, target := range targets {
+		names[i] = fields[target].Name
+	}
+	return names
+}
+
+// allTargets maps every table column onto itself
+func allTargets(fields []iceberg.NestedField) []int {
+	targets := make([]int, len(fields))
+	for i := range fields {
+		targets[i] = i
+	}
+	return targets
+}
+
+// csvDecoder reads CSV with a header row naming the columns, unless the header URL parameter
+// is false. Empty unquoted fields are NULL, as in the CSV output format.
+type csvDecoder struct {
+	reader  *csv.Reader
+	fields  []iceberg.NestedField
+	targets []int
+}
+
+// newCSVDecoder creates a decoder for a CSV body
+func newCSVDecoder(r *http.Request, fields []iceberg.NestedField) (rowDecoder, error) {
+	reader := csv.NewReader(r.Body)
+	reader.FieldsPerRecord = -1
+	reader.ReuseRecord = true
+	decoder := &csvDecoder{reader: reader, fields: fields, targets: allTargets(fields)}
+
+	if r.URL.Query().Get("header") != "false" {
+		header, err := reader.Read()
+		if err == io.EOF {
+			return decoder, nil
+		}
+		if err != nil {
+			return nil, ingestError(err, "failed to read CSV header")
+		}
+		if decoder.targets, err = columnTargets(header, fields); err != nil {
+			return nil, err
+		}
+	}
+	return decoder, nil
+}
+
+func (d *csvDecoder) columns() []string { return targetNames(d.fields, d.targets) }
+
+func (d *csvDecoder) next() ([]interface{}, error) {
+	record, err := d.reader.Read()
+	if err == io.EOF {
+		return nil, io.EOF
+	}
+	if err != nil {
+		return nil, ingestError(err, "malformed CSV")
+	}
+	if len(record) != len(d.targets) {
+		return nil, &rowError{err: fmt.Errorf("expected %d fields, got %d", len(d.targets), len(record))}
+	}
+
+	row := make([]interface{}, len(d.fields))
+	for i, text := range record {
+		if text == "" {
+			continue
+		}
+		field := d.fields[d.targets[i]]
+		value, err := parquet.ParseTextValue(text, field.Type)
+		if err != nil {
+			return nil, &rowError{column: field.Name, err: err}
+		}
+		row[d.targets[i]] = value
+	}
+	return row, nil
+}
+
+func (d *csvDecoder) close() error { return nil }
+
+// ndjsonDecoder reads one JSON value per row: an object keyed by column name, or an array
+// of all the table's columns in order
+type ndjsonDecoder struct {
+	decoder *json.Decoder
+	fields  []iceberg.NestedField
+}
+
+// newNDJSONDecoder creates a decoder for a newline-delimited JSON body
+func newNDJSONDecoder(r *http.Request, fields []iceberg.NestedField) (rowDecoder, error) {
+	decoder := json.NewDecoder(r.Body)
+	decoder.UseNumber()
+	return &ndjsonDecoder{decoder: decoder, fields: fields}, nil
+}
+
+func (d *ndjsonDecoder) columns() []string { return targetNames(d.fields, allTargets(d.fields)) }
+
+func (d *ndjsonDecoder) next() ([]interface{}, error) {
+	var value interface{}
+	if err := d.decoder.Decode(&value); err == io.EOF {
+		return nil, io.EOF
+	} else if err != nil {
+		return nil, ingestError(err, "malformed JSON")
+	}
+
+	row := make([]interface{}, len(d.fields))
+	switch value := value.(type) {
+	case map[string]interface{}:
+		for name, v := range value {
+			index := fieldIndex(d.fields, name)
+			if index < 0 {
+				return nil, &rowError{column: name, err: fmt.Errorf("column does not exist in the table")}
+			}
+			converted, err := jsonIngestValue(v, d.fields[index].Type)
+			if err != nil {
+				return nil, &rowError{column: d.fields[index].Name, err: err}
+			}
+			row[index] = converted
+		}
+	case []interface{}:
+		if len(value) != len(d.fields) {
+			return nil, &rowError{err: fmt.Errorf("expected %d values, got %d", len(d.fields), len(value))}
+		}
+		for i, v := range value {
+			converted, err := jsonIngestValue(v, d.fields[i].Type)
+			if err != nil {
+				return nil, &rowError{column: d.fields[i].Name, err: err}
+			}
+			row[i] = converted
+		}
+	default:
+		return nil, &rowError{err: fmt.Errorf("expected a JSON object or array")}
+	}
+	return row, nil
+}
+
+func (d *ndjsonDecoder) close() error { return nil }
+
+// jsonIngestValue converts a JSON value to the Go value the table column expects
+func jsonIngestValue(value interface{}, icebergType iceberg.Type) (interface{}, error) {
+	switch value := value.(type) {
+	case nil:
+		return nil, nil
+	case string:
+		return parquet.ParseTextValue(value, icebergType)
+	case json.Number:
+		return parquet.ParseTextValue(value.String(), icebergType)
+	case bool:
+		if icebergType == iceberg.PrimitiveTypes.Bool {
+			return value, nil
+		}
+		return parquet.ParseTextValue(fmt.Sprint(value), icebergType)
+	case []interface{}, map[string]interface{}:
+		switch icebergType.(type) {
+		case *iceberg.ListType, *iceberg.MapType, *iceberg.StructType:
+			return parquet.ParseNestedValue(value, icebergType)
+		}
+		return nil, fmt.Errorf("nested JSON values are not supported for %s columns", icebergType)
+	default:
+		return nil, fmt.Errorf("unsupported JSON value %T", value)
+	}
+}
+
+// recordDecoder reads the rows of Arrow record batches, from an IPC stream or a Parquet file
+type recordDecoder struct {
+	reader  array.RecordReader
+	fields  []iceberg.NestedField
+	targets []int
+	record  arrow.Record
+	index   int
+	cleanup func() error
+}
+
+// newRecordDecoder maps the columns of reader onto the table
+func newRecordDecoder(reader array.RecordReader, fields []iceberg.NestedField, cleanup func() error) (*recordDecoder, error) {
+	names := make([]string, len(reader.Schema().Fields()))
+	for i, field := range reader.Schema().Fields() {
+		names[i] = field.Name
+	}
+	targets, err := columnTargets(names, fields)
+	if err != nil {
+		reader.Release()
+		cleanup()
+		return nil, err
+	}
+	return &recordDecoder{reader: reader, fields: fields, targets: targets, cleanup: cleanup}, nil
+}
+
+// newArrowDecoder creates a decoder for an Arrow IPC stream body
+func newArrowDecoder(r *http.Request, fields []iceberg.NestedField) (rowDecoder, error) {
+	reader, err := ipc.NewReader(r.Body, ipc.WithAllocator(memory.DefaultAllocator))
+	if err != nil {
+		return nil, ingestError(err, "malformed Arrow IPC stream")
+	}
+	return newRecordDecoder(reader, fields, func() error { return nil })
+}
+
+// newParquetDecoder creates a decoder for a Parquet body, which is written to a temporary
+// file first because Parquet is read from its footer
+func newParquetDecoder(r *http.Request, fields []iceberg.NestedField) (rowDecoder, error) {
+	spool, err := os.CreateTemp("", "ranger-insert-*.parquet")
+	if err != nil {
+		return nil, errors.New(ErrSpoolFailed, "failed to create temporary file", err)
+	}
+	cleanup := func() error {
+		spool.Close()
+		return os.Remove(spool.Name())
+	}
+	if _, err := io.Copy(spool, r.Body); err != nil {
+		cleanup()
+		return nil, ingestError(err, "failed to read request body")
+	}
+
+	parquetFile, err := file.NewParquetReader(spool)
+	if err != nil {
+		cleanup()
+		return nil, errors.New(ErrIngestInvalid, "malformed Parquet file", err)
+	}
+	fileReader, err := pqarrow.NewFileReader(parquetFile, pqarrow.ArrowReadProperties{BatchSize: 8192}, memory.DefaultAllocator)
+	if err != nil {
+		parquetFile.Close()
+		cleanup()
+		return nil, errors.New(ErrIngestInvalid, "malformed Parquet file", err)
+	}
+	reader, err := fileReader.GetRecordReader(context.Background(), nil, nil)
+	if err != nil {
+		parquetFile.Close()
+		cleanup()
+		return nil, errors.New(ErrIngestInvalid, "failed to read Parquet file", err)
+	}
+	return newRecordDecoder(reader, fields, func() error {
+		parquetFile.Close()
+		return cleanup()
+	})
+}
+
+func (d *recordDecoder) columns() []string { return targetNames(d.fields, d.targets) }
+
+func (d *recordDecoder) next() ([]interface{}, error) {
+	for d.record == nil || d.index >= int(d.record.NumRows()) {
+		if !d.reader.Next() {
+			if err := d.reader.Err(); err != nil && err != io.EOF {
+				return nil, ingestError(err, "failed to read record batch")
+			}
+			return nil, io.EOF
+		}
+		d.record, d.index = d.reader.Record(), 0
+	}
+
+	row := make([]interface{}, len(d.fields))
+	for i, target := range d.targets {
+		field := d.fields[target]
+		value, err := arrowIngestValue(d.record.Column(i), d.index, field.Type)
+		if err != nil {
+			d.index++
+			return nil, &rowError{column: field.Name, err: err}
+		}
+		row[target] = value
+	}
+	d.index++
+	return row, nil
+}
+
+func (d *recordDecoder) close() error {
+	d.reader.Release()
+	return d.cleanup()
+}
+
+// arrowIngestValue converts an Arrow value to the Go value the table column expects. Values
+// without a direct equivalent go through their text form.
+func arrowIngestValue(column arrow.Array, i int, icebergType iceberg.Type) (interface{}, error) {
+	if column.IsNull(i) {
+		return nil, nil
+	}
+	switch column := column.(type) {
+	case *array.Binary:
+		if icebergType == iceberg.PrimitiveTypes.Binary {
+			return append([]byte(nil), column.Value(i)...), nil
+		}
+	case *array.Boolean:
+		if icebergType == iceberg.PrimitiveTypes.Bool {
+			return column.Value(i), nil
+		}
+	case *array.Date32:
+		if icebergType == iceberg.PrimitiveTypes.Date {
+			return column.Value(i).ToTime(), nil
+		}
+	case *array.Timestamp:
+		if icebergType == iceberg.PrimitiveTypes.Timestamp || icebergType == iceberg.PrimitiveTypes.TimestampTz {
+			return column.Value(i).ToTime(column.DataType().(*arrow.TimestampType).Unit), nil
+		}
+	case *array.List, *array.Map, *array.Struct:
+		return parquet.ParseNestedValue(parquet.ArrayValue(column, i), icebergType)
+	}
+	return parquet.ParseTextValue(column.ValueStr(i), icebergType)
+}
+
+// ingestError reports a malformed insert body, or one larger than the insert limit
+func ingestError(err error, message string) error {
+	if tooLarge := tooLargeError(err); tooLarge != nil {
+		return tooLarge
+	}
+	return errors.New(ErrIngestInvalid, message, err)
+}
@@ -0,0 +1,276 @@
+package http
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/apache/arrow-go/v18/arrow"
+	"github.com/apache/arrow-go/v18/arrow/array"
+	"github.com/apache/arrow-go/v18/arrow/ipc"
+	"github.com/apache/arrow-go/v18/arrow/memory"
+	"github.com/apache/iceberg-go"
+	"github.com/gear6io/ranger/server/storage/parquet"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var ingestFields = []iceberg.NestedField{
+	{ID: 1, Name: "id", Type: iceberg.PrimitiveTypes.Int64, Required: true},
+	{ID: 2, Name: "name", Type: iceberg.PrimitiveTypes.String},
+	{ID: 3, Name: "day", Type: iceberg.PrimitiveTypes.Date},
+}
+
+// nestedIngestFields are the columns of a table with list, map and struct columns
+var nestedIngestFields = []iceberg.NestedField{
+	{ID: 1, Name: "id", Type: iceberg.PrimitiveTypes.Int64, Required: true},
+	{ID: 2, Name: "scores", Type: &iceberg.ListType{ElementID: 4, Element: iceberg.PrimitiveTypes.Int32}},
+	{ID: 3, Name: "owner", Type: &iceberg.StructType{FieldList: []iceberg.NestedField{
+		{ID: 5, Name: "name", Type: iceberg.PrimitiveTypes.String},
+		{ID: 6, Name: "attrs", Type: &iceberg.MapType{KeyID: 7, KeyType: iceberg.PrimitiveTypes.String, ValueID: 8, ValueType: iceberg.PrimitiveTypes.Date}},
+	}}},
+}
+
+// decodeAll reads every row of a request body, keeping row errors in place of rows
+func decodeAll(t *testing.T, target, contentType string, body io.Reader) ([]string, []interface{}) {
+	t.Helper()
+	return decodeAllFields(t, ingestFields, target, contentType, body)
+}
+
+// decodeAllFields reads every row of a request body into a table with the given columns
+func decodeAllFields(t *testing.T, fields []iceberg.NestedField, target, contentType string, body io.Reader) ([]string, []interface{}) {
+	t.Helper()
+	r := httptest.NewRequest(http.MethodPost, target, body)
+	r.Header.Set("Content-Type", contentType)
+	format, err := selectIngestFormat(r)
+	require.NoError(t, err)
+	decoder, err := format.newDecoder(r, fields)
+	require.NoError(t, err)
+	defer decoder.close()
+
+	var rows []interface{}
+	for {
+		row, err := decoder.next()
+		if err == io.EOF {
+			return decoder.columns(), rows
+		}
+		if _, ok := err.(*rowError); ok {
+			rows = append(rows, err)
+			continue
+		}
+		require.NoError(t, err)
+		rows = append(rows, row)
+	}
+}
+
+func TestSelectIngestFormat(t *testing.T) {
+	for _, tc := range []struct {
+		target, contentType, format string
+	}{
+		{"/", "text/csv; charset=utf-8", "CSV"},
+		{"/", "application/x-ndjson", "JSONEachRow"},
+		{"/", "application/vnd.apache.arrow.stream", "Arrow"},
+		{"/", "application/x-parquet", "Parquet"},
+		{"/?format=jsonl", "text/plain", "JSONEachRow"},
+	} {
+		r := httptest.NewRequest(http.MethodPost, tc.target, nil)
+		r.Header.Set("Content-Type", tc.contentType)
+		format, err := selectIngestFormat(r)
+		require.NoError(t, err, tc.contentType)
+		assert.Equal(t, tc.format, format.name, tc.contentType)
+	}
+
+	r := httptest.NewRequest(http.MethodPost, "/", nil)
+	r.Header.Set("Content-Type", "application/json")
+	_, err := selectIngestFormat(r)
+	assertCode(t, ErrUnsupportedMediaType, err)
+	_, err = selectIngestFormat(httptest.NewRequest(http.MethodPost, "/?format=XML", nil))
+	assertCode(t, ErrUnsupportedMediaType, err)
+}
+
+func TestTextIngestDecoders(t *testing.T) {
+	day := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+
+	// CSV headers name the columns in any order; empty fields are NULL
+	columns, rows := decodeAll(t, "/", "text/csv", strings.NewReader("DAY,id,name\n2024-03-01,1,a\n,2,\nx,3,c\n4,d\n"))
+	assert.Equal(t, []string{"day", "id", "name"}, columns)
+	require.Len(t, rows, 4)
+	assert.Equal(t, []interface{}{int64(1), "a", day}, rows[0])
+	assert.Equal(t, []interface{}{int64(2), nil, nil}, rows[1])
+	assert.Equal(t, "day", rows[2].(*rowError).column)
+	assert.Contains(t, rows[3].(*rowError).Error(), "expected 3 fields, got 2")
+
+	_, rows = decodeAll(t, "/?header=false", "text/csv", strings.NewReader("1,a,2024-03-01\n"))
+	assert.Equal(t, []interface{}{[]interface{}{int64(1), "a", day}}, rows)
+
+	r := httptest.NewRequest(http.MethodPost, "/", strings.NewReader("id,color\n"))
+	_, err := newCSVDecoder(r, ingestFields)
+	assertCode(t, ErrUnknownColumn, err)
+
+	// NDJSON rows are objects by name or arrays by position
+	body := `{"id": 1, "name": "a", "day": "2024-03-01"}` + "\n" + `[2, null, null]` + "\n" +
+		`{"id": "x"}` + "\n" + `{"id": 4, "tags": ["a"]}` + "\n" + `{"id": 5, "name": {"a": 1}}` + "\n"
+	columns, rows = decodeAll(t, "/", "application/x-ndjson", strings.NewReader(body))
+	assert.Equal(t, []string{"id", "name", "day"}, columns)
+	require.Len(t, rows, 5)
+	assert.Equal(t, []interface{}{int64(1), "a", day}, rows[0])
+	assert.Equal(t, []interface{}{int64(2), nil, nil}, rows[1])
+	assert.Equal(t, "id", rows[2].(*rowError).column)
+	assert.Equal(t, "tags", rows[3].(*rowError).column)
+	assert.Contains(t, rows[4].(*rowError).Error(), "nested JSON values are not supported")
+
+	r = httptest.NewRequest(http.MethodPost, "/", strings.NewReader("{\"id\": 1}\n{oops"))
+	decoder, err := newNDJSONDecoder(r, ingestFields)
+	require.NoError(t, err)
+	_, err = decoder.next()
+	require.NoError(t, err)
+	_, err = decoder.next()
+	assertCode(t, ErrIngestInvalid, err)
+}
+
+func TestIngestBodyLimit(t *testing.T) {
+	// Reading past the insert limit is reported as a body that is too large
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodPost, "/", strings.NewReader("id,name\n1,a\n2,b\n"))
+	r.Body = http.MaxBytesReader(w, r.Body, 12)
+	decoder, err := newCSVDecoder(r, ingestFields)
+	require.NoError(t, err)
+	_, err = decoder.next()
+	require.NoError(t, err)
+	_, err = decoder.next()
+	assertCode(t, ErrRequestTooLarge, err)
+
+	r = httptest.NewRequest(http.MethodPost, "/", strings.NewReader(strings.Repeat("x", 64)))
+	r.Body = http.MaxBytesReader(w, r.Body, 16)
+	_, err = newParquetDecoder(r, ingestFields)
+	assertCode(t, ErrRequestTooLarge, err)
+}
+
+func TestArrowIngestDecoder(t *testing.T) {
+	schema := arrow.NewSchema([]arrow.Field{
+		{Name: "name", Type: arrow.BinaryTypes.String, Nullable: true},
+		{Name: "id", Type: arrow.PrimitiveTypes.Int32},
+	}, nil)
+	builder := array.NewRecordBuilder(memory.DefaultAllocator, schema)
+	defer builder.Release()
+	builder.Field(0).(*array.StringBuilder).AppendValues([]string{"a", ""}, []bool{true, false})
+	builder.Field(1).(*array.Int32Builder).AppendValues([]int32{1, 2}, nil)
+	record := builder.NewRecord()
+	defer record.Release()
+
+	var body bytes.Buffer
+	writer := ipc.NewWriter(&body, ipc.WithSchema(schema))
+	require.NoError(t, writer.Write(record))
+	require.NoError(t, writer.Close())
+
+	columns, rows := decodeAll(t, "/", "application/vnd.apache.arrow.stream", &body)
+	assert.Equal(t, []string{"name", "id"}, columns)
+	assert.Equal(t, []interface{}{
+		[]interface{}{int64(1), "a", nil},
+		[]interface{}{int64(2), nil, nil},
+	}, rows)
+}
+
+func TestNestedIngestDecoders(t *testing.T) {
+	day := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	expected := []interface{}{
+		[]interface{}{int64(1), []interface{}{int32(1), nil}, map[string]interface{}{
+			"name": "a", "attrs": map[string]interface{}{"since": day},
+		}},
+		[]interface{}{int64(2), []interface{}{}, nil},
+	}
+
+	// NDJSON takes nested JSON values
+	body := `{"id": 1, "scores": [1, null], "owner": {"name": "a", "attrs": {"since": "2024-03-01"}}}` + "\n" +
+		`{"id": 2, "scores": [], "owner": null}` + "\n" + `{"id": 3, "scores": ["x"]}` + "\n" + `{"id": 4, "owner": {"age": 1}}` + "\n"
+	_, rows := decodeAllFields(t, nestedIngestFields, "/", "application/x-ndjson", strings.NewReader(body))
+	require.Len(t, rows, 4)
+	assert.Equal(t, expected, rows[:2])
+	assert.Equal(t, "scores", rows[2].(*rowError).column)
+	assert.Equal(t, "owner", rows[3].(*rowError).column)
+
+	// CSV takes PostgreSQL array literals for lists and JSON for the rest
+	csv := "id,scores,owner\n" + `1,"{1,NULL}","{""name"": ""a"", ""attrs"": {""since"": ""2024-03-01""}}"` + "\n2,{},\n"
+	_, rows = decodeAllFields(t, nestedIngestFields, "/", "text/csv", strings.NewReader(csv))
+	assert.Equal(t, expected, rows)
+
+	// Arrow streams carry nested columns natively, or as JSON text
+	schema := arrow.NewSchema([]arrow.Field{
+		{Name: "id", Type: arrow.PrimitiveTypes.Int64},
+		{Name: "scores", Type: arrow.ListOf(arrow.PrimitiveTypes.Int64), Nullable: true},
+		{Name: "owner", Type: arrow.BinaryTypes.String, Nullable: true},
+	}, nil)
+	builder := array.NewRecordBuilder(memory.DefaultAllocator, schema)
+	defer builder.Release()
+	builder.Field(0).(*array.Int64Builder).AppendValues([]int64{1, 2}, nil)
+	scores := builder.Field(1).(*array.ListBuilder)
+	scores.Append(true)
+	scores.ValueBuilder().(*array.Int64Builder).AppendValues([]int64{1, 0}, []bool{true, false})
+	scores.Append(true)
+	builder.Field(2).(*array.StringBuilder).AppendValues([]string{`{"name": "a", "attrs": {"since": "2024-03-01"}}`, ""}, []bool{true, false})
+	record := builder.NewRecord()
+	defer record.Release()
+
+	var stream bytes.Buffer
+	writer := ipc.NewWriter(&stream, ipc.WithSchema(schema))
+	require.NoError(t, writer.Write(record))
+	require.NoError(t, writer.Close())
+	_, rows = decodeAllFields(t, nestedIngestFields, "/", "application/vnd.apache.arrow.stream", &stream)
+	assert.Equal(t, expected, rows)
+}
+
+func TestIngestBatcher(t *testing.T) {
+	icebergSchema := iceberg.NewSchema(0, ingestFields...)
+	arrowSchema, err := parquet.ConvertIcebergToArrowSchema(icebergSchema)
+	require.NoError(t, err)
+
+	var committed [][][]interface{}
+	newBatcher := func(maxErrors int64) *ingestBatcher {
+		committed = nil
+		return &ingestBatcher{
+			database:  "default",
+			table:     "events",
+			schema:    arrowSchema,
+			batchSize: 2,
+			maxErrors: maxErrors,
+			report:    &ingestReport{},
+			commit: func(rows [][]interface{}) error {
+				committed = append(committed, rows)
+				return nil
+			},
+		}
+	}
+
+	// Rows failing validation are rejected and the rest of their batch is committed
+	batcher := newBatcher(10)
+	require.NoError(t, batcher.add(1, []interface{}{int64(1), "a", nil}))
+	require.NoError(t, batcher.add(2, []interface{}{nil, "b", nil}))
+	require.NoError(t, batcher.add(3, []interface{}{int64(3), int64(7), nil}))
+	require.NoError(t, batcher.add(4, []interface{}{int64(4), "d", nil}))
+	require.NoError(t, batcher.add(5, []interface{}{int64(5), "e", nil}))
+	require.NoError(t, batcher.flush())
+
+	assert.Equal(t, [][][]interface{}{
+		{{int64(1), "a", nil}},
+		{{int64(4), "d", nil}},
+		{{int64(5), "e", nil}},
+	}, committed)
+	assert.Equal(t, int64(3), batcher.report.RowsInserted)
+	assert.Equal(t, int64(2), batcher.report.RowsRejected)
+	require.Len(t, batcher.report.Errors, 2)
+	assert.Equal(t, ingestRowError{Row: 2, Column: "id", Error: "expected non-null value but got null"}, batcher.report.Errors[0])
+	assert.Equal(t, int64(3), batcher.report.Errors[1].Row)
+	assert.Equal(t, "name", batcher.report.Errors[1].Column)
+
+	// The insert fails once more than maxErrors rows are rejected
+	batcher = newBatcher(1)
+	require.NoError(t, batcher.reject(1, "id", assert.AnError))
+	require.NoError(t, batcher.add(2, []interface{}{int64(2), "b", nil}))
+	err = batcher.add(3, []interface{}{nil, "c", nil})
+	assertCode(t, ErrTooManyInvalidRows, err)
+	assert.Empty(t, committed)
+}
@@ -0,0 +1,306 @@
+package http
+
+import (
+	"encoding/json"
+	stderrors "errors"
+	"fmt"
+	"io"
+	"math"
+	"mime"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gear6io/ranger/pkg/errors"
+)
+
+// maxRequestBodyBytes bounds the JSON or raw SQL body of a query request
+const maxRequestBodyBytes = 16 << 20
+
+// Per-query settings a request may set, in the body's settings or as URL parameters
+const (
+	settingMaxExecutionTime = "max_execution_time" // seconds; 0 keeps the server default
+	settingMaxResultRows    = "max_result_rows"    // rows returned at most; 0 is unlimited
+)
+
+// queryRequest is a query as sent in the JSON body of POST /query
+type queryRequest struct {
+	SQL      string                 `json:"sql"`
+	Database string                 `json:"database"`
+	Params   map[string]interface{} `json:"params"`
+	Settings map[string]interface{} `json:"settings"`
+	QueryID  string                 `json:"query_id"`
+}
+
+// querySettings are the settings of a request after validation
+type querySettings struct {
+	MaxExecutionTime time.Duration
+	MaxResultRows    int64
+}
+
+// parseQueryRequest reads a query from a JSON body, a raw SQL body or the q URL parameter.
+// The URL parameters database, query_id, param_<name> and the setting names fill in what
+// the body leaves out, so scripts can send plain SQL.
+func parseQueryRequest(r *http.Request) (*queryRequest, error) {
+	request := &queryRequest{}
+	body := http.MaxBytesReader(nil, r.Body, maxRequestBodyBytes)
+
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if mediaType == "application/json" {
+		decoder := json.NewDecoder(body)
+		decoder.UseNumber()
+		decoder.DisallowUnknownFields()
+		if err := decoder.Decode(request); err != nil {
+			return nil, bodyError(err, "invalid JSON request body")
+		}
+	} else {
+		data, err := io.ReadAll(body)
+		if err != nil {
+			return nil, bodyError(err, "failed to read request body")
+		}
+		request.SQL = string(data)
+	}
+
+	values := r.URL.Query()
+	if strings.TrimSpace(request.SQL) == "" {
+		request.SQL = values.Get("q")
+	}
+	if strings.TrimSpace(request.SQL) == "" {
+		return nil, errors.New(ErrMissingQuery, "missing query: send it as the request body, in the sql field or the q parameter", nil)
+	}
+	if request.Database == "" {
+		request.Database = values.Get("database")
+	}
+	if request.QueryID == "" {
+		request.QueryID = values.Get("query_id")
+	}
+
+	for name, value := range values {
+		if param, ok := strings.CutPrefix(name, "param_"); ok {
+			if request.Params == nil {
+				request.Params = make(map[string]interface{})
+			}
+			if _, set := request.Params[param]; !set {
+				request.Params[param] = value[0]
+			}
+		}
+	}
+	for _, name := range []string{settingMaxExecutionTime, settingMaxResultRows} {
+		if value := values.Get(name); value != "" {
+			if request.Settings == nil {
+				request.Settings = make(map[string]interface{})
+			}
+			if _, set := request.Settings[name]; !set {
+				request.Settings[name] = value
+			}
+		}
+	}
+
+	return request, nil
+}
+
+// bodyError reports a failure to read the request body
+func bodyError(err error, message string) error {
+	if tooLarge := tooLargeError(err); tooLarge != nil {
+		return tooLarge
+	}
+	return errors.New(ErrInvalidRequest, message, err)
+}
+
+// tooLargeError reports a body read past its http.MaxBytesReader limit, or returns nil when
+// err is about something else
+func tooLargeError(err error) error {
+	var tooLarge *http.MaxBytesError
+	if stderrors.As(err, &tooLarge) {
+		return errors.New(ErrRequestTooLarge, "request body is too large", err).AddContext("limit", tooLarge.Limit)
+	}
+	return nil
+}
+
+// settings validates the request's settings
+func (q *queryRequest) settings() (querySettings, error) {
+	var settings querySettings
+	for name, value := range q.Settings {
+		text, ok := paramText(value)
+		if !ok {
+			return settings, errors.New(ErrInvalidSetting, "setting must be a number", nil).AddContext("setting", name)
+		}
+
+		switch name {
+		case settingMaxExecutionTime:
+			seconds, err := strconv.ParseFloat(text, 64)
+			// NaN and durations past time.Duration's range have no timeout to apply
+			if err != nil || math.IsNaN(seconds) || math.IsInf(seconds, 0) || seconds < 0 || seconds > math.MaxInt64/float64(time.Second) {
+				return settings, errors.New(ErrInvalidSetting, "setting must be a non-negative number of seconds", err).AddContext("setting", name)
+			}
+			settings.MaxExecutionTime = time.Duration(seconds * float64(time.Second))
+		case settingMaxResultRows:
+			rows, err := strconv.ParseInt(text, 10, 64)
+			if err != nil || rows < 0 {
+				return settings, errors.New(ErrInvalidSetting, "setting must be a non-negative integer", err).AddContext("setting", name)
+			}
+			settings.MaxResultRows = rows
+		default:
+			return settings, errors.New(ErrUnknownSetting, fmt.Sprintf("unknown setting %s", name), nil).AddContext("setting", name)
+		}
+	}
+	return settings, nil
+}
+
+// placeholderPattern matches the inside of a {name:Type} query parameter placeholder
+var placeholderPattern = regexp.MustCompile(`^\s*([A-Za-z_][A-Za-z0-9_]*)\s*:\s*([A-Za-z][A-Za-z0-9_() ]*?)\s*$`)
+
+// identifierPattern matches an unquoted, optionally qualified identifier
+var identifierPattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*(\.[A-Za-z_][A-Za-z0-9_]*)*$`)
+
+// substituteParams replaces {name:Type} placeholders outside string literals, quoted
+// identifiers and comments with the SQL literal of the named parameter. Values are checked
+// against their type, so a parameter cannot change the structure of the query.
+func substituteParams(sql string, params map[string]interface{}) (string, error) {
+	var out strings.Builder
+	for i := 0; i < len(sql); {
+		switch {
+		case sql[i] == '\'' || sql[i] == '"':
+			// Quotes are escaped by doubling, which reads as two adjacent quoted runs
+			end := strings.IndexByte(sql[i+1:], sql[i])
+			if end < 0 {
+				out.WriteString(sql[i:])
+				return out.String(), nil
+			}
+			out.WriteString(sql[i : i+end+2])
+			i += end + 2
+		case strings.HasPrefix(sql[i:], "--"):
+			end := strings.IndexByte(sql[i:], '\n')
+			if end < 0 {
+				end = len(sql) - i
+			}
+			out.WriteString(sql[i : i+end])
+			i += end
+		case strings.HasPrefix(sql[i:], "/*"):
+			end := strings.Index(sql[i+2:], "*/")
+			if end < 0 {
+				out.WriteString(sql[i:])
+				return out.String(), nil
+			}
+			out.WriteString(sql[i : i+end+4])
+			i += end + 4
+		case sql[i] == '{':
+			end := strings.IndexByte(sql[i:], '}')
+			var match []string
+			if end > 0 {
+				match = placeholderPattern.FindStringSubmatch(sql[i+1 : i+end])
+			}
+			if match == nil {
+				// Not a placeholder, such as a DuckDB struct literal
+				out.WriteByte(sql[i])
+				i++
+				continue
+			}
+
+			value, ok := params[match[1]]
+			if !ok {
+				return "", errors.New(ErrMissingParameter, fmt.Sprintf("missing value for query parameter %s", match[1]), nil).AddContext("parameter", match[1])
+			}
+			literal, err := paramLiteral(value, match[2])
+			if err != nil {
+				return "", errors.AddContext(err, "parameter", match[1])
+			}
+			out.WriteString(literal)
+			i += end + 1
+		default:
+			out.WriteByte(sql[i])
+			i++
+		}
+	}
+	return out.String(), nil
+}
+
+// paramLiteral renders a parameter value as a SQL literal of the placeholder type, which
+// takes ClickHouse names such as UInt32 and Nullable(String) as well as SQL names
+func paramLiteral(value interface{}, typeName string) (string, error) {
+	if inner, ok := strings.CutPrefix(typeName, "Nullable("); ok && strings.HasSuffix(inner, ")") {
+		if value == nil {
+			return "NULL", nil
+		}
+		return paramLiteral(value, strings.TrimSuffix(inner, ")"))
+	}
+
+	text, ok := paramText(value)
+	if !ok {
+		return "", errors.New(ErrInvalidParameter, "query parameter must be a string, number or boolean", nil).AddContext("type", typeName)
+	}
+	invalid := func(cause error) error {
+		return errors.New(ErrInvalidParameter, fmt.Sprintf("query parameter value %q is not a valid %s", text, typeName), cause).AddContext("type", typeName)
+	}
+
+	switch strings.ToLower(typeName) {
+	case "string", "varchar", "text":
+		return quoteString(text), nil
+	case "int8", "int16", "int32", "int64", "tinyint", "smallint", "integer", "int", "bigint":
+		number, err := strconv.ParseInt(text, 10, 64)
+		if err != nil {
+			return "", invalid(err)
+		}
+		return strconv.FormatInt(number, 10), nil
+	case "uint8", "uint16", "uint32", "uint64", "utinyint", "usmallint", "uinteger", "ubigint":
+		number, err := strconv.ParseUint(text, 10, 64)
+		if err != nil {
+			return "", invalid(err)
+		}
+		return strconv.FormatUint(number, 10), nil
+	case "float32", "float64", "float", "double", "real":
+		number, err := strconv.ParseFloat(text, 64)
+		if err != nil || math.IsNaN(number) || math.IsInf(number, 0) {
+			return "", invalid(err)
+		}
+		return strconv.FormatFloat(number, 'f', -1, 64), nil
+	case "bool", "boolean":
+		boolean, err := strconv.ParseBool(text)
+		if err != nil {
+			return "", invalid(err)
+		}
+		return strings.ToUpper(strconv.FormatBool(boolean)), nil
+	case "date":
+		if _, err := time.Parse(time.DateOnly, text); err != nil {
+			return "", invalid(err)
+		}
+		return quoteString(text), nil
+	case "datetime", "datetime64", "timestamp":
+		if _, err := time.Parse(time.DateTime, text); err != nil {
+			if _, err := time.Parse(time.RFC3339Nano, text); err != nil {
+				return "", invalid(err)
+			}
+		}
+		return quoteString(text), nil
+	case "identifier":
+		if !identifierPattern.MatchString(text) {
+			return "", invalid(nil)
+		}
+		return text, nil
+	default:
+		return "", errors.New(ErrInvalidParameter, fmt.Sprintf("unsupported query parameter type %s", typeName), nil).AddContext("type", typeName)
+	}
+}
+
+// paramText returns the text of a scalar parameter or setting value
+func paramText(value interface{}) (string, bool) {
+	switch value := value.(type) {
+	case string:
+		return value, true
+	case json.Number:
+		return value.String(), true
+	case bool:
+		return strconv.FormatBool(value), true
+	case float64:
+		return strconv.FormatFloat(value, 'f', -1, 64), true
+	default:
+		return "", false
+	}
+}
+
+// quoteString renders a SQL string literal
+func quoteString(text string) string {
+	return "'" + strings.ReplaceAll(text, "'", "''") + "'"
+}
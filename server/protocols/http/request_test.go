@@ -0,0 +1,201 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gear6io/ranger/pkg/errors"
+	"github.com/gear6io/ranger/server/shared"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// assertCode fails the test unless err carries code
+func assertCode(t *testing.T, code errors.Code, err error) {
+	t.Helper()
+	require.Error(t, err)
+	assert.Equal(t, code.String(), errors.GetCode(err), err.Error())
+}
+
+func TestParseQueryRequest(t *testing.T) {
+	body := `{"sql": "SELECT * FROM t WHERE id = {id:UInt32}", "database": "sales", "params": {"id": 42}, "settings": {"max_result_rows": 10}, "query_id": "q1"}`
+	r := httptest.NewRequest(http.MethodPost, "/query?param_id=7&database=other", strings.NewReader(body))
+	r.Header.Set("Content-Type", "application/json")
+
+	request, err := parseQueryRequest(r)
+	require.NoError(t, err)
+	assert.Equal(t, "sales", request.Database)
+	assert.Equal(t, "q1", request.QueryID)
+	// The body wins over URL parameters
+	assert.Equal(t, json.Number("42"), request.Params["id"])
+
+	settings, err := request.settings()
+	require.NoError(t, err)
+	assert.Equal(t, int64(10), settings.MaxResultRows)
+
+	// Scripts send plain SQL with URL parameters
+	r = httptest.NewRequest(http.MethodPost, "/query?param_name=bob&max_execution_time=1.5&query_id=q2", strings.NewReader("SELECT {name:String}"))
+	request, err = parseQueryRequest(r)
+	require.NoError(t, err)
+	assert.Equal(t, "SELECT {name:String}", request.SQL)
+	assert.Equal(t, "bob", request.Params["name"])
+	assert.Equal(t, "q2", request.QueryID)
+	settings, err = request.settings()
+	require.NoError(t, err)
+	assert.Equal(t, 1500*time.Millisecond, settings.MaxExecutionTime)
+
+	// The q parameter of earlier releases still works
+	request, err = parseQueryRequest(httptest.NewRequest(http.MethodPost, "/query?q=SELECT+1%3B", nil))
+	require.NoError(t, err)
+	assert.Equal(t, "SELECT 1;", request.SQL)
+
+	_, err = parseQueryRequest(httptest.NewRequest(http.MethodPost, "/query", nil))
+	assertCode(t, ErrMissingQuery, err)
+
+	r = httptest.NewRequest(http.MethodPost, "/query", strings.NewReader(`{"sql": "SELECT 1", "unknown": true}`))
+	r.Header.Set("Content-Type", "application/json")
+	_, err = parseQueryRequest(r)
+	assertCode(t, ErrInvalidRequest, err)
+
+	r = httptest.NewRequest(http.MethodPost, "/query", strings.NewReader(strings.Repeat(" ", maxRequestBodyBytes+1)))
+	_, err = parseQueryRequest(r)
+	assertCode(t, ErrRequestTooLarge, err)
+
+	for settings, code := range map[string]errors.Code{
+		`{"max_result_rows": -1}`:       ErrInvalidSetting,
+		`{"max_execution_time": "x"}`:   ErrInvalidSetting,
+		`{"max_execution_time": "NaN"}`: ErrInvalidSetting,
+		`{"max_execution_time": "Inf"}`: ErrInvalidSetting,
+		`{"max_execution_time": 1e300}`: ErrInvalidSetting,
+		`{"max_threads": 4}`:            ErrUnknownSetting,
+	} {
+		r = httptest.NewRequest(http.MethodPost, "/query", strings.NewReader(`{"sql": "SELECT 1", "settings": `+settings+`}`))
+		r.Header.Set("Content-Type", "application/json")
+		request, err := parseQueryRequest(r)
+		require.NoError(t, err)
+		_, err = request.settings()
+		assertCode(t, code, err)
+	}
+}
+
+func TestSubstituteParams(t *testing.T) {
+	params := map[string]interface{}{
+		"name":  "O'Brien",
+		"id":    json.Number("42"),
+		"score": 1.5,
+		"ok":    true,
+		"day":   "2024-03-01",
+		"at":    "2024-03-01 10:00:00",
+		"table": "sales.orders",
+		"none":  nil,
+	}
+
+	sql, err := substituteParams("SELECT * FROM {table:Identifier} WHERE name = {name:String} AND id = {id:UInt64} AND score > {score:Float64} "+
+		"AND ok = {ok:Bool} AND day = {day:Date} AND at < {at:DateTime} AND x = {none:Nullable(String)};", params)
+	require.NoError(t, err)
+	assert.Equal(t, "SELECT * FROM sales.orders WHERE name = 'O''Brien' AND id = 42 AND score > 1.5 "+
+		"AND ok = TRUE AND day = '2024-03-01' AND at < '2024-03-01 10:00:00' AND x = NULL;", sql)
+
+	// Placeholders inside literals, quoted identifiers and comments are left alone, as are struct literals
+	sql, err = substituteParams(`SELECT '{name:String}', "{id:UInt64}", {'a': 1} -- {name:String}`+"\n/* {id:UInt64} */ FROM t;", params)
+	require.NoError(t, err)
+	assert.Equal(t, `SELECT '{name:String}', "{id:UInt64}", {'a': 1} -- {name:String}`+"\n/* {id:UInt64} */ FROM t;", sql)
+
+	_, err = substituteParams("SELECT {missing:String};", params)
+	assertCode(t, ErrMissingParameter, err)
+
+	for query, code := range map[string]errors.Code{
+		"SELECT {name:Int32};":            ErrInvalidParameter,
+		"SELECT {id:Date};":               ErrInvalidParameter,
+		"SELECT * FROM {name:Identifier}": ErrInvalidParameter,
+		"SELECT {none:String};":           ErrInvalidParameter,
+		"SELECT {id:Point};":              ErrInvalidParameter,
+	} {
+		_, err := substituteParams(query, params)
+		assertCode(t, code, err)
+	}
+}
+
+func TestSelectFormat(t *testing.T) {
+	request := func(target, accept string) *http.Request {
+		r := httptest.NewRequest(http.MethodPost, target, nil)
+		if accept != "" {
+			r.Header.Set("Accept", accept)
+		}
+		return r
+	}
+
+	for _, tc := range []struct {
+		target, accept, format string
+	}{
+		{"/query", "", "JSON"},
+		{"/query", "*/*", "JSON"},
+		{"/query", "text/csv", "CSV"},
+		{"/query", "application/x-parquet;q=0.9, text/csv", "Parquet"},
+		{"/query", "application/vnd.apache.arrow.stream", "Arrow"},
+		{"/query", "text/html, */*;q=0.8", "JSON"},
+		{"/query?format=ndjson", "text/csv", "JSONEachRow"},
+		{"/query?format=TabSeparatedWithNames", "", "TSV"},
+	} {
+		format, err := selectFormat(request(tc.target, tc.accept))
+		require.NoError(t, err, tc.target)
+		assert.Equal(t, tc.format, format.name, "%s %s", tc.target, tc.accept)
+	}
+
+	_, err := selectFormat(request("/query?format=XML", ""))
+	assertCode(t, ErrUnknownFormat, err)
+	_, err = selectFormat(request("/query", "text/html"))
+	assertCode(t, ErrUnknownFormat, err)
+}
+
+func TestTraceRequests(t *testing.T) {
+	var traceID string
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /v1/queries/{id}", func(w http.ResponseWriter, r *http.Request) {
+		traceID = trace.SpanContextFromContext(r.Context()).TraceID().String()
+		w.WriteHeader(http.StatusNotFound)
+	})
+	handler := traceRequests(mux)
+
+	r := httptest.NewRequest(http.MethodGet, "/v1/queries/q1", nil)
+	r.Header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, r)
+	assert.Equal(t, http.StatusNotFound, recorder.Code)
+	// Handlers run in the trace of the client
+	assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", traceID)
+}
+
+func TestHealthEndpoints(t *testing.T) {
+	s := &Server{health: func(ctx context.Context) *shared.HealthReport {
+		return &shared.HealthReport{
+			Status: "not_ready",
+			Live:   true,
+			Ready:  false,
+			Phase:  "running",
+			Components: []shared.ComponentHealth{
+				{Name: "metadata", Live: true, Ready: false, Message: "iceberg backlog of 20 files exceeds 10"},
+			},
+		}
+	}}
+
+	recorder := httptest.NewRecorder()
+	s.handleLive(recorder, httptest.NewRequest(http.MethodGet, "/health/live", nil))
+	assert.Equal(t, http.StatusOK, recorder.Code)
+
+	recorder = httptest.NewRecorder()
+	s.handleReady(recorder, httptest.NewRequest(http.MethodGet, "/health/ready", nil))
+	assert.Equal(t, http.StatusServiceUnavailable, recorder.Code)
+
+	var report shared.HealthReport
+	require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &report))
+	require.Len(t, report.Components, 1)
+	assert.Equal(t, "metadata", report.Components[0].Name)
+	assert.False(t, report.Components[0].Ready)
+}
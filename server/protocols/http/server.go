@@ -5,12 +5,19 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"strings"
 	"sync"
 	"time"
 
+	"github.com/gear6io/ranger/pkg/errors"
 	"github.com/gear6io/ranger/server/config"
+	"github.com/gear6io/ranger/server/metrics"
+	"github.com/gear6io/ranger/server/protocols/native/middleware"
 	"github.com/gear6io/ranger/server/query"
+	"github.com/gear6io/ranger/server/query/access"
+	"github.com/gear6io/ranger/server/shared"
 	"github.com/gear6io/ranger/server/types"
+	"github.com/google/uuid"
 	"github.com/rs/zerolog"
 )
 
@@ -22,10 +29,25 @@ type Server struct {
 	ctx         context.Context
 	cancel      context.CancelFunc
 	wg          sync.WaitGroup
+
+	// tlsManager serves HTTPS when set
+	tlsManager *config.TLSManager
+
+	// auth validates the bearer tokens of requests
+	auth middleware.AuthProvider
+
+	// async holds the queries submitted through /v1/queries
+	async *asyncQueries
+
+	// health reports the state of the server components; nil reports them all healthy
+	health shared.HealthFunc
+
+	// maxInsertBytes bounds the body of an insert; 0 is unlimited
+	maxInsertBytes int64
 }
 
-// NewServer creates a new HTTP server instance
-func NewServer(queryEngine *query.Engine, logger zerolog.Logger) (*Server, error) {
+// NewServer creates a new HTTP server instance; tlsManager may be nil to serve plain HTTP
+func NewServer(queryEngine *query.Engine, auth middleware.AuthProvider, health shared.HealthFunc, cfg config.HTTPConfig, tlsManager *config.TLSManager, logger zerolog.Logger) (*Server, error) {
 	ctx, cancel := context.WithCancel(context.Background())
 
 	return &Server{
@@ -33,6 +55,12 @@ func NewServer(queryEngine *query.Engine, logger zerolog.Logger) (*Server, error
 		logger:      logger.With().Str("component", "http-server").Logger(),
 		ctx:         ctx,
 		cancel:      cancel,
+		tlsManager:  tlsManager,
+		auth:        auth,
+		async:       newAsyncQueries(cfg),
+		health:      health,
+
+		maxInsertBytes: int64(cfg.MaxInsertMB) << 20,
 	}, nil
 }
 
@@ -46,7 +74,11 @@ func (s *Server) Start(ctx context.Context) error {
 	// Use fixed port and address from config constants
 	port := config.HTTP_SERVER_PORT
 	addr := fmt.Sprintf("%s:%d", config.DEFAULT_SERVER_ADDRESS, port)
-	s.logger.Info().Str("address", addr).Msg("Starting HTTP server")
+	s.logger.Info().Str("address", addr).Bool("tls", s.tlsManager != nil).Msg("Starting HTTP server")
+
+	if err := s.async.prepare(); err != nil {
+		return err
+	}
 
 	// Create HTTP server with query handling
 	mux := http.NewServeMux()
@@ -54,6 +86,15 @@ func (s *Server) Start(ctx context.Context) error {
 	// Add query endpoint
 	mux.HandleFunc("/query", s.handleQuery)
 
+	// Add asynchronous query endpoints
+	mux.HandleFunc("POST /v1/queries", s.handleSubmitQuery)
+	mux.HandleFunc("GET /v1/queries/{id}", s.handleQueryStatus)
+	mux.HandleFunc("GET /v1/queries/{id}/results", s.handleQueryResults)
+	mux.HandleFunc("DELETE /v1/queries/{id}", s.handleCancelQuery)
+
+	// Add bulk insert endpoint
+	mux.HandleFunc("POST /v1/tables/{db}/{table}/insert", s.handleInsert)
+
 	// Add status endpoint
 	mux.HandleFunc("/status", s.handleStatus)
 
@@ -62,17 +103,36 @@ func (s *Server) Start(ctx context.Context) error {
 
 	// Add health check endpoint
 	mux.HandleFunc("/health", s.handleHealth)
+	mux.HandleFunc("GET /health/live", s.handleLive)
+	mux.HandleFunc("GET /health/ready", s.handleReady)
+
+	// Add Prometheus metrics endpoint
+	mux.Handle("GET /metrics", metrics.Handler())
 
 	s.server = &http.Server{
 		Addr:    addr,
-		Handler: mux,
+		Handler: traceRequests(mux),
+	}
+	if s.tlsManager != nil {
+		s.server.TLSConfig = s.tlsManager.ServerConfig()
 	}
 
+	// Remove the results of asynchronous queries once they expire
+	s.wg.Add(1)
+	go s.expireQueries()
+
 	// Start server in goroutine
 	s.wg.Add(1)
 	go func() {
 		defer s.wg.Done()
-		if err := s.server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+		var err error
+		if s.tlsManager != nil {
+			// Certificates come from TLSConfig.GetConfigForClient
+			err = s.server.ListenAndServeTLS("", "")
+		} else {
+			err = s.server.ListenAndServe()
+		}
+		if err != nil && err != http.ErrServerClosed {
 			s.logger.Error().Err(err).Msg("HTTP server error")
 		}
 	}()
@@ -81,68 +141,151 @@ func (s *Server) Start(ctx context.Context) error {
 	return nil
 }
 
-// handleQuery handles SQL query requests
+// handleQuery executes the query of a request and streams its result in the negotiated
+// output format
 func (s *Server) handleQuery(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
 		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 		return
 	}
+	start := time.Now()
 
-	// Parse query from request body
-	queryStr := r.URL.Query().Get("q")
-	if queryStr == "" {
-		http.Error(w, "Missing query parameter 'q'", http.StatusBadRequest)
+	username, ok := s.authenticate(w, r)
+	if !ok {
 		return
 	}
-
-	s.logger.Info().Str("query", queryStr).Msg("Executing query via HTTP")
-
-	// Create query context for HTTP requests
-	queryCtx := &types.QueryContext{
-		Query:      queryStr,
-		Database:   "default",
-		User:       "http",
-		ClientAddr: r.RemoteAddr,
+	format, err := selectFormat(r)
+	if err != nil {
+		s.writeError(w, err)
+		return
 	}
-	result, err := s.queryEngine.ExecuteQuery(r.Context(), queryCtx)
+	queryCtx, settings, err := s.prepareQuery(r, username)
 	if err != nil {
-		s.logger.Error().Err(err).Str("query", queryStr).Msg("Query execution failed")
-		http.Error(w, fmt.Sprintf("Query execution failed: %v", err), http.StatusInternalServerError)
+		s.writeError(w, err)
 		return
 	}
+	queryID := queryCtx.QueryID
 
-	// Return comprehensive result as JSON
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusOK)
-
-	// Create a proper JSON response structure
-	response := map[string]interface{}{
-		"status":    "success",
-		"query":     queryStr,
-		"rowCount":  result.RowCount,
-		"columns":   result.Columns,
-		"message":   result.Message,
-		"timestamp": time.Now().UTC().Format(time.RFC3339),
+	ctx := r.Context()
+	if settings.MaxExecutionTime > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, settings.MaxExecutionTime)
+		defer cancel()
 	}
 
-	// Add data if available
-	if result.Data != nil {
-		if rows, ok := result.Data.([][]interface{}); ok {
-			response["data"] = rows
-		} else {
-			response["data"] = result.Data
+	s.logger.Info().Str("query", queryCtx.Query).Str("query_id", queryID).Str("user", username).Str("format", format.name).Msg("Executing query via HTTP")
+	w.Header().Set("X-Ranger-Query-Id", queryID)
+	stream := newResultStream(w, format, queryID, settings.MaxResultRows)
+
+	result, err := s.queryEngine.StreamQuery(ctx, queryCtx, stream)
+	if err != nil {
+		s.logger.Error().Err(err).Str("query_id", queryID).Msg("Query execution failed")
+		if !stream.fail(err) {
+			s.writeError(w, err)
 		}
+		return
 	}
+	if err := stream.finish(result, time.Since(start)); err != nil {
+		s.logger.Warn().Err(err).Str("query_id", queryID).Msg("Failed to send query result")
+	}
+}
 
-	// Convert to JSON
-	jsonResponse, err := json.Marshal(response)
+// prepareQuery builds the query context and settings of a query request, substituting
+// its parameters
+func (s *Server) prepareQuery(r *http.Request, username string) (*types.QueryContext, querySettings, error) {
+	request, err := parseQueryRequest(r)
 	if err != nil {
-		s.logger.Error().Err(err).Msg("Failed to marshal JSON response")
-		http.Error(w, "Internal server error", http.StatusInternalServerError)
-		return
+		return nil, querySettings{}, err
+	}
+	settings, err := request.settings()
+	if err != nil {
+		return nil, settings, err
+	}
+	queryStr, err := substituteParams(request.SQL, request.Params)
+	if err != nil {
+		return nil, settings, err
+	}
+	if !strings.HasSuffix(strings.TrimSpace(queryStr), ";") {
+		queryStr += ";"
 	}
 
-	w.Write(jsonResponse)
+	queryID := request.QueryID
+	if queryID == "" {
+		queryID = uuid.NewString()
+	}
+	database := request.Database
+	if database == "" {
+		database = "default"
+	}
+
+	return &types.QueryContext{
+		Query:      queryStr,
+		Database:   database,
+		User:       username,
+		ClientAddr: r.RemoteAddr,
+		Protocol:   types.ProtocolHTTP,
+		QueryID:    queryID,
+	}, settings, nil
+}
+
+// writeError sends an error that occurred before the result started
+func (s *Server) writeError(w http.ResponseWriter, err error) {
+	if code := errors.GetCode(err); code != "" {
+		w.Header().Set("X-Ranger-Error-Code", code)
+	}
+	http.Error(w, err.Error(), errorStatus(err))
+}
+
+// errorStatus maps an error to the HTTP status reported to the client
+func errorStatus(err error) int {
+	switch errors.GetCode(err) {
+	case ErrInvalidRequest.String(), ErrMissingQuery.String(), ErrUnknownSetting.String(), ErrInvalidSetting.String(),
+		ErrMissingParameter.String(), ErrInvalidParameter.String(), ErrInvalidPage.String(), query.ErrQueryParseValidationFailed.String(),
+		ErrIngestInvalid.String(), ErrUnknownColumn.String():
+		return http.StatusBadRequest
+	case ErrUnknownFormat.String():
+		return http.StatusNotAcceptable
+	case access.ErrPermissionDenied.String(), access.ErrSuperuserRequired.String():
+		return http.StatusForbidden
+	case ErrRequestTooLarge.String():
+		return http.StatusRequestEntityTooLarge
+	case ErrUnsupportedMediaType.String():
+		return http.StatusUnsupportedMediaType
+	case ErrTooManyInvalidRows.String():
+		return http.StatusUnprocessableEntity
+	case ErrQueryNotFound.String(), query.ErrTableNotFound.String():
+		return http.StatusNotFound
+	case query.ErrQueryIDInUse.String(), ErrQueryRunning.String():
+		return http.StatusConflict
+	}
+	return http.StatusInternalServerError
+}
+
+// authenticate returns the user of the request's bearer token, which is either a JWT or a
+// session token issued by the native protocol. Requests without credentials are rejected.
+// On failure it writes a 401 response and returns false.
+func (s *Server) authenticate(w http.ResponseWriter, r *http.Request) (string, bool) {
+	header := r.Header.Get("Authorization")
+	scheme, token, _ := strings.Cut(header, " ")
+	var err error
+	if header == "" {
+		err = errors.New(middleware.ErrInvalidToken, "missing bearer token", nil)
+	} else if !strings.EqualFold(scheme, "Bearer") || token == "" {
+		err = errors.New(middleware.ErrInvalidToken, "unsupported authorization scheme", nil).AddContext("scheme", scheme)
+	} else if s.auth == nil {
+		err = errors.New(middleware.ErrInvalidToken, "bearer tokens are not accepted", nil)
+	} else {
+		var result *middleware.AuthResult
+		if result, err = s.auth.ValidateToken(r.Context(), strings.TrimSpace(token)); err == nil {
+			return result.Username, true
+		}
+	}
+
+	s.queryEngine.AuditLogin(r.Context(), "", types.ProtocolHTTP, r.RemoteAddr, err)
+	s.logger.Warn().Err(err).Str("client", r.RemoteAddr).Msg("HTTP authentication failed")
+	w.Header().Set("WWW-Authenticate", `Bearer realm="ranger"`)
+	http.Error(w, "Unauthorized", http.StatusUnauthorized)
+	return "", false
 }
 
 // handleStatus handles status requests
@@ -167,9 +310,17 @@ func (s *Server) handleInfo(w http.ResponseWriter, r *http.Request) {
 		"queryEngine": "enabled",
 		"endpoints": []string{
 			"POST /query - Execute SQL queries",
+			"POST /v1/queries - Submit an asynchronous query",
+			"GET /v1/queries/{id} - Asynchronous query status",
+			"GET /v1/queries/{id}/results - Asynchronous query results",
+			"DELETE /v1/queries/{id} - Cancel an asynchronous query",
+			"POST /v1/tables/{db}/{table}/insert - Insert CSV, NDJSON, Arrow or Parquet data",
 			"GET /status - Server status",
 			"GET /info - Server information",
 			"GET /health - Health check",
+			"GET /health/live - Liveness of the server components",
+			"GET /health/ready - Readiness of the server components",
+			"GET /metrics - Prometheus metrics",
 		},
 	}
 
@@ -204,6 +355,37 @@ func (s *Server) handleHealth(w http.ResponseWriter, r *http.Request) {
 	w.Write(jsonResponse)
 }
 
+// handleLive answers 200 while every component is live and 503 once one has failed for good
+func (s *Server) handleLive(w http.ResponseWriter, r *http.Request) {
+	s.writeHealthReport(w, r, func(report *shared.HealthReport) bool { return report.Live })
+}
+
+// handleReady answers 200 while every component can serve requests and 503 otherwise,
+// including during startup and shutdown
+func (s *Server) handleReady(w http.ResponseWriter, r *http.Request) {
+	s.writeHealthReport(w, r, func(report *shared.HealthReport) bool { return report.Ready })
+}
+
+// writeHealthReport sends the health report with 503 when healthy rejects it
+func (s *Server) writeHealthReport(w http.ResponseWriter, r *http.Request, healthy func(*shared.HealthReport) bool) {
+	report := &shared.HealthReport{Status: "ok", Live: true, Ready: true, Components: []shared.ComponentHealth{}}
+	if s.health != nil {
+		report = s.health(r.Context())
+	}
+
+	status := http.StatusOK
+	if !healthy(report) {
+		status = http.StatusServiceUnavailable
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(report); err != nil {
+		s.logger.Error().Err(err).Msg("Failed to write health response")
+	}
+}
+
 // Stop stops the HTTP server
 func (s *Server) Stop() error {
 	s.logger.Info().Msg("Stopping HTTP server")
@@ -233,5 +415,6 @@ func (s *Server) GetStatus() map[string]interface{} {
 		"enabled": config.HTTP_SERVER_ENABLED,
 		"address": config.DEFAULT_SERVER_ADDRESS,
 		"port":    config.HTTP_SERVER_PORT,
+		"tls":     s.tlsManager != nil,
 	}
 }
@@ -0,0 +1,155 @@
+package http
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"os"
+	"sync"
+
+	"github.com/gear6io/ranger/pkg/errors"
+	"github.com/gear6io/ranger/server/types"
+)
+
+// spoolIndexInterval is how many rows lie between the indexed offsets of a spool file
+const spoolIndexInterval = 1024
+
+// resultSpool is the RowSink writing the result of an asynchronous query to a temporary
+// file, one JSON array per row, so it can be read back a page at a time
+type resultSpool struct {
+	mu        sync.Mutex
+	file      *os.File
+	out       *bufio.Writer
+	columns   []resultColumn
+	maxBytes  int64
+	maxRows   int64
+	rows      int64
+	bytes     int64
+	truncated bool
+	index     []int64 // file offset of every spoolIndexInterval-th row
+}
+
+// newResultSpool creates a spool file in dir holding at most maxBytes bytes and maxRows
+// rows, when positive
+func newResultSpool(dir string, maxBytes, maxRows int64) (*resultSpool, error) {
+	file, err := os.CreateTemp(dir, "query-*.spool")
+	if err != nil {
+		return nil, errors.New(ErrSpoolFailed, "failed to create spool file", err).AddContext("dir", dir)
+	}
+	return &resultSpool{
+		file:     file,
+		out:      bufio.NewWriterSize(file, 64<<10),
+		maxBytes: maxBytes,
+		maxRows:  maxRows,
+	}, nil
+}
+
+// Columns records the result columns
+func (s *resultSpool) Columns(names, typeNames []string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.columns = make([]resultColumn, len(names))
+	for i, name := range names {
+		s.columns[i] = resultColumn{Name: name, Type: "VARCHAR"}
+		if i < len(typeNames) {
+			s.columns[i].Type = typeNames[i]
+		}
+	}
+	return nil
+}
+
+// Row appends a row, ending the result once it reaches max_result_rows or the spool limit
+func (s *resultSpool) Row(values []interface{}) error {
+	row := make([]interface{}, len(values))
+	for i, value := range values {
+		row[i] = jsonValue(value, columnType(s.columns, i))
+	}
+	data, err := json.Marshal(row)
+	if err != nil {
+		return errors.New(ErrSpoolFailed, "failed to encode result row", err)
+	}
+	data = append(data, '\n')
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if (s.maxRows > 0 && s.rows >= s.maxRows) || (s.maxBytes > 0 && s.bytes+int64(len(data)) > s.maxBytes) {
+		s.truncated = true
+		return errors.New(types.ErrStopRows, "result limit reached", nil)
+	}
+	if s.rows%spoolIndexInterval == 0 {
+		s.index = append(s.index, s.bytes)
+	}
+	if _, err := s.out.Write(data); err != nil {
+		return errors.New(ErrSpoolFailed, "failed to write spool file", err).AddContext("file", s.file.Name())
+	}
+	s.rows++
+	s.bytes += int64(len(data))
+	return nil
+}
+
+// spoolProgress is a consistent view of a spool's counters
+type spoolProgress struct {
+	rows      int64
+	bytes     int64
+	truncated bool
+}
+
+// progress returns the rows and bytes spooled so far
+func (s *resultSpool) progress() spoolProgress {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return spoolProgress{rows: s.rows, bytes: s.bytes, truncated: s.truncated}
+}
+
+// finish writes out buffered rows after the last one
+func (s *resultSpool) finish() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.out.Flush(); err != nil {
+		return errors.New(ErrSpoolFailed, "failed to write spool file", err).AddContext("file", s.file.Name())
+	}
+	return nil
+}
+
+// read returns up to limit rows starting at row offset of a finished spool
+func (s *resultSpool) read(offset, limit int64) ([][]interface{}, error) {
+	s.mu.Lock()
+	rows, bytes, index := s.rows, s.bytes, s.index
+	s.mu.Unlock()
+
+	if offset >= rows || limit <= 0 {
+		return [][]interface{}{}, nil
+	}
+	block := offset / spoolIndexInterval
+	section := io.NewSectionReader(s.file, index[block], bytes-index[block])
+	decoder := json.NewDecoder(bufio.NewReader(section))
+	decoder.UseNumber()
+
+	for skip := offset - block*spoolIndexInterval; skip > 0; skip-- {
+		var row json.RawMessage
+		if err := decoder.Decode(&row); err != nil {
+			return nil, errors.New(ErrSpoolFailed, "failed to read spool file", err).AddContext("file", s.file.Name())
+		}
+	}
+	page := make([][]interface{}, 0, min(limit, rows-offset))
+	for int64(len(page)) < limit && offset+int64(len(page)) < rows {
+		var row []interface{}
+		if err := decoder.Decode(&row); err != nil {
+			return nil, errors.New(ErrSpoolFailed, "failed to read spool file", err).AddContext("file", s.file.Name())
+		}
+		page = append(page, row)
+	}
+	return page, nil
+}
+
+// remove deletes the spool file
+func (s *resultSpool) remove() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.file.Close()
+	if err := os.Remove(s.file.Name()); err != nil && !os.IsNotExist(err) {
+		return errors.New(ErrSpoolFailed, "failed to remove spool file", err).AddContext("file", s.file.Name())
+	}
+	return nil
+}
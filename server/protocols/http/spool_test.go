@@ -0,0 +1,63 @@
+package http
+
+import (
+	"encoding/json"
+	"os"
+	"strconv"
+	"testing"
+
+	"github.com/gear6io/ranger/pkg/errors"
+	"github.com/gear6io/ranger/server/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestResultSpool(t *testing.T) {
+	spool, err := newResultSpool(t.TempDir(), 0, 0)
+	require.NoError(t, err)
+	require.NoError(t, spool.Columns([]string{"id", "name"}, []string{"BIGINT", "VARCHAR"}))
+	for i := int64(0); i < 3000; i++ {
+		require.NoError(t, spool.Row([]interface{}{i, nil}))
+	}
+	require.NoError(t, spool.finish())
+	assert.Equal(t, int64(3000), spool.progress().rows)
+
+	// Pages start inside and across index blocks
+	for _, offset := range []int64{0, 1023, 1024, 2500} {
+		page, err := spool.read(offset, 600)
+		require.NoError(t, err)
+		require.NotEmpty(t, page)
+		assert.Equal(t, []interface{}{json.Number(strconv.FormatInt(offset, 10)), nil}, page[0])
+		assert.Len(t, page, int(min(600, 3000-offset)))
+	}
+	page, err := spool.read(3000, 10)
+	require.NoError(t, err)
+	assert.Empty(t, page)
+
+	name := spool.file.Name()
+	require.NoError(t, spool.remove())
+	_, err = os.Stat(name)
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestResultSpoolLimits(t *testing.T) {
+	for _, tc := range []struct {
+		maxBytes, maxRows, rows int64
+	}{
+		{maxRows: 5, rows: 5},
+		{maxBytes: 20, rows: 2}, // each row is [n,"x"] and a newline, 8 bytes
+	} {
+		spool, err := newResultSpool(t.TempDir(), tc.maxBytes, tc.maxRows)
+		require.NoError(t, err)
+		require.NoError(t, spool.Columns([]string{"id", "name"}, nil))
+
+		var stopped error
+		for i := 0; i < 10 && stopped == nil; i++ {
+			stopped = spool.Row([]interface{}{i % 10, "x"})
+		}
+		require.Error(t, stopped)
+		assert.Equal(t, types.ErrStopRows.String(), errors.GetCode(stopped))
+		assert.Equal(t, spoolProgress{rows: tc.rows, bytes: tc.rows * 8, truncated: true}, spool.progress())
+		require.NoError(t, spool.remove())
+	}
+}
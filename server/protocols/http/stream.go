@@ -0,0 +1,130 @@
+package http
+
+import (
+	"bufio"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gear6io/ranger/pkg/errors"
+	"github.com/gear6io/ranger/server/query"
+	"github.com/gear6io/ranger/server/types"
+)
+
+// flushInterval is how long rows may wait in buffers before they are sent to the client
+const flushInterval = 100 * time.Millisecond
+
+// exceptionTrailer is the HTTP trailer reporting an error that ended a result after the
+// response had started
+const exceptionTrailer = "X-Ranger-Exception"
+
+// resultStream is the RowSink writing a query result to an HTTP response as chunked
+// transfer while the rows arrive
+type resultStream struct {
+	w         http.ResponseWriter
+	out       *bufio.Writer
+	format    *outputFormat
+	encoder   resultEncoder
+	queryID   string
+	maxRows   int64
+	rows      int64
+	started   bool
+	truncated bool
+	lastFlush time.Time
+}
+
+// newResultStream creates a stream writing at most maxRows rows, when positive
+func newResultStream(w http.ResponseWriter, format *outputFormat, queryID string, maxRows int64) *resultStream {
+	out := bufio.NewWriterSize(w, 64<<10)
+	return &resultStream{
+		w:       w,
+		out:     out,
+		format:  format,
+		encoder: format.newEncoder(out),
+		queryID: queryID,
+		maxRows: maxRows,
+	}
+}
+
+// Columns starts the response with the result columns
+func (s *resultStream) Columns(names, typeNames []string) error {
+	columns := make([]resultColumn, len(names))
+	for i, name := range names {
+		columns[i] = resultColumn{Name: name, Type: "VARCHAR"}
+		if i < len(typeNames) {
+			columns[i].Type = typeNames[i]
+		}
+	}
+
+	header := s.w.Header()
+	header.Set("Content-Type", s.format.contentType)
+	header.Set("X-Ranger-Format", s.format.name)
+	header.Set("Trailer", exceptionTrailer)
+	s.w.WriteHeader(http.StatusOK)
+	s.started = true
+	s.lastFlush = time.Now()
+
+	if err := s.encoder.begin(s.queryID, columns); err != nil {
+		return errors.New(ErrResultWriteFailed, "failed to write result header", err).AddContext("format", s.format.name)
+	}
+	return nil
+}
+
+// Row writes a result row, ending the result once max_result_rows rows were written
+func (s *resultStream) Row(values []interface{}) error {
+	if s.maxRows > 0 && s.rows >= s.maxRows {
+		s.truncated = true
+		return errors.New(types.ErrStopRows, "max_result_rows reached", nil)
+	}
+	if err := s.encoder.row(values); err != nil {
+		return errors.New(ErrResultWriteFailed, "failed to write result row", err).AddContext("format", s.format.name)
+	}
+	s.rows++
+
+	if time.Since(s.lastFlush) >= flushInterval {
+		return s.flush()
+	}
+	return nil
+}
+
+// finish completes the response after the last row
+func (s *resultStream) finish(result *query.QueryResult, elapsed time.Duration) error {
+	if !s.started {
+		if err := s.Columns(result.Columns, result.ColumnTypes); err != nil {
+			return err
+		}
+	}
+	summary := resultSummary{Rows: s.rows, Message: result.Message, Truncated: s.truncated, Elapsed: elapsed}
+	if err := s.encoder.end(summary); err != nil {
+		return errors.New(ErrResultWriteFailed, "failed to complete result", err).AddContext("format", s.format.name)
+	}
+	return s.flush()
+}
+
+// fail ends a started response after err, reporting it in-band where the format allows
+// and in the exception trailer. It returns false when the response has not started, so
+// the error can still be sent with an error status.
+func (s *resultStream) fail(err error) bool {
+	if !s.started {
+		return false
+	}
+	s.encoder.abort(err)
+	s.flush()
+	s.w.Header().Set(exceptionTrailer, strings.Join(strings.Fields(err.Error()), " "))
+	return true
+}
+
+// flush sends buffered rows to the client
+func (s *resultStream) flush() error {
+	s.lastFlush = time.Now()
+	if err := s.encoder.flush(); err != nil {
+		return errors.New(ErrResultWriteFailed, "failed to write result rows", err).AddContext("format", s.format.name)
+	}
+	if err := s.out.Flush(); err != nil {
+		return errors.New(ErrResultWriteFailed, "failed to send result rows", err)
+	}
+	if flusher, ok := s.w.(http.Flusher); ok {
+		flusher.Flush()
+	}
+	return nil
+}
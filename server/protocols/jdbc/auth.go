@@ -0,0 +1,434 @@
+package jdbc
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/md5"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/gear6io/ranger/pkg/errors"
+	"github.com/gear6io/ranger/server/metadata/registry"
+	"github.com/gear6io/ranger/server/metadata/registry/regtypes"
+	"github.com/gear6io/ranger/server/protocols/native/middleware"
+)
+
+// Authentication methods offered to pgwire clients
+const (
+	AuthMethodTrust       = "trust"
+	AuthMethodPassword    = "password"
+	AuthMethodMD5         = "md5"
+	AuthMethodSCRAMSHA256 = "scram-sha-256"
+)
+
+// SCRAMSHA256Mechanism is the SASL mechanism name of SCRAM-SHA-256
+const SCRAMSHA256Mechanism = "SCRAM-SHA-256"
+
+// SQLSTATE codes reported when authentication fails
+const (
+	SQLStateInvalidAuthorization = "28000"
+	SQLStateInvalidPassword      = "28P01"
+	SQLStateProtocolViolation    = "08P01"
+)
+
+// RegistryUsers looks up users in the registry users table
+type RegistryUsers interface {
+	GetUser(ctx context.Context, username string) (*regtypes.User, error)
+}
+
+// Authenticator verifies pgwire clients against the native AuthProvider's user
+// store, and rejects users deactivated in the registry users table
+type Authenticator struct {
+	method      string
+	credentials middleware.CredentialStore
+	users       RegistryUsers
+
+	// tokens is set when the store accepts a JWT in place of a password; the password is
+	// then requested in cleartext, as a token cannot go through MD5 or SCRAM
+	tokens middleware.TokenAuthenticator
+
+	// saltKey derives the SCRAM salts of users without a stored SCRAM secret, generated once
+	// so that a user's salt, like a stored one, is the same on every attempt
+	saltKey []byte
+}
+
+// NewAuthenticator creates a new authenticator for the given method
+func NewAuthenticator(method string, credentials middleware.CredentialStore, users RegistryUsers) (*Authenticator, error) {
+	switch method {
+	case AuthMethodTrust, AuthMethodPassword, AuthMethodMD5, AuthMethodSCRAMSHA256:
+	default:
+		return nil, errors.New(ErrUnsupportedAuthMethod, "unsupported authentication method", nil).AddContext("method", method)
+	}
+
+	authenticator := &Authenticator{
+		method:      method,
+		credentials: credentials,
+		users:       users,
+		saltKey:     make([]byte, 32),
+	}
+	rand.Read(authenticator.saltKey)
+	if tokens, ok := credentials.(middleware.TokenAuthenticator); ok && tokens.AcceptsTokens() {
+		authenticator.tokens = tokens
+	}
+	return authenticator, nil
+}
+
+// Authenticate runs the password exchange for username on conn and returns the
+// user's credentials. It does not send AuthenticationOK.
+func (a *Authenticator) Authenticate(ctx context.Context, conn io.ReadWriter, username string) (*middleware.Credentials, error) {
+	if username == "" {
+		return nil, errors.New(ErrRoleNotFound, "no PostgreSQL user name specified in startup packet", nil)
+	}
+
+	creds, err := a.credentials.LookupCredentials(ctx, username)
+	known := err == nil
+	if err != nil && errors.GetCode(err) != middleware.ErrUserNotFound.String() {
+		return nil, errors.New(ErrUserLookupFailed, "failed to look up user", err)
+	}
+
+	if !known {
+		// Users created with CREATE USER exist only in the registry
+		if creds, err = a.registryCredentials(ctx, username); err != nil {
+			return nil, err
+		}
+		known = creds != nil
+	}
+
+	if !known {
+		if a.method == AuthMethodTrust {
+			return nil, errors.New(ErrRoleNotFound, fmt.Sprintf("role \"%s\" does not exist", username), nil)
+		}
+		// Unknown users go through the same exchange against a throwaway secret,
+		// so clients cannot tell them apart from a wrong password
+		creds = &middleware.Credentials{Username: username, Password: rand.Text()}
+	}
+
+	if a.method != AuthMethodTrust && creds.RequiresPassword() {
+		verified, tokenCreds, err := a.exchangePassword(ctx, conn, creds)
+		if err != nil {
+			return nil, err
+		}
+		if tokenCreds != nil {
+			// Token logins may create the user, so it need not have been known
+			if err := a.checkLoginPermitted(ctx, tokenCreds); err != nil {
+				return nil, err
+			}
+			return tokenCreds, nil
+		}
+		if !verified || !known {
+			// Failures of disabled users, such as locked ones, do not extend their lockout
+			if tracker, ok := a.credentials.(middleware.LoginTracker); ok && known && !creds.Disabled {
+				tracker.LoginFailed(ctx, username)
+			}
+			return nil, errors.New(ErrAuthenticationFailed, fmt.Sprintf("password authentication failed for user \"%s\"", username), nil)
+		}
+	}
+
+	if err := a.checkLoginPermitted(ctx, creds); err != nil {
+		return nil, err
+	}
+
+	if tracker, ok := a.credentials.(middleware.LoginTracker); ok {
+		if err := tracker.LoginSucceeded(ctx, username); err != nil {
+			return nil, errors.New(ErrUserLookupFailed, "failed to record login", err)
+		}
+	}
+
+	return creds, nil
+}
+
+// exchangePassword runs the configured password mechanism and reports whether the client proved
+// the password. When the client sent a valid JWT instead, it returns the credentials of the
+// token's user.
+func (a *Authenticator) exchangePassword(ctx context.Context, conn io.ReadWriter, creds *middleware.Credentials) (bool, *middleware.Credentials, error) {
+	if a.tokens != nil || a.method == AuthMethodPassword {
+		return a.exchangeCleartext(ctx, conn, creds)
+	}
+
+	var verified bool
+	var err error
+	if a.method == AuthMethodMD5 && creds.Password != "" {
+		verified, err = a.exchangeMD5(conn, creds)
+	} else {
+		// MD5 needs the cleartext password; hashed-only users fall back to SCRAM, as in PostgreSQL
+		verified, err = a.exchangeSCRAM(conn, creds)
+	}
+	return verified, nil, err
+}
+
+// exchangeCleartext requests the password in cleartext, accepting a JWT in its place when
+// the credential store takes tokens
+func (a *Authenticator) exchangeCleartext(ctx context.Context, conn io.ReadWriter, creds *middleware.Credentials) (bool, *middleware.Credentials, error) {
+	if err := WriteAuthenticationCleartextPassword(conn); err != nil {
+		return false, nil, err
+	}
+
+	data, err := readPasswordMessage(conn)
+	if err != nil {
+		return false, nil, err
+	}
+	password := string(bytes.TrimRight(data, "\x00"))
+
+	if a.tokens != nil && middleware.IsJWT(password) {
+		result, err := a.tokens.Authenticate(ctx, creds.Username, password, "")
+		if err != nil {
+			return false, nil, errors.New(ErrAuthenticationFailed, fmt.Sprintf("token authentication failed for user \"%s\"", creds.Username), err)
+		}
+		return true, &middleware.Credentials{Username: result.Username, Database: "default"}, nil
+	}
+
+	if creds.Password != "" {
+		return subtle.ConstantTimeCompare([]byte(password), []byte(creds.Password)) == 1, nil, nil
+	}
+	return creds.SCRAM.VerifyPassword(password), nil, nil
+}
+
+// exchangeMD5 requests md5(md5(password || username) || salt)
+func (a *Authenticator) exchangeMD5(conn io.ReadWriter, creds *middleware.Credentials) (bool, error) {
+	var salt [4]byte
+	rand.Read(salt[:])
+	if err := WriteAuthenticationMD5Password(conn, salt); err != nil {
+		return false, err
+	}
+
+	data, err := readPasswordMessage(conn)
+	if err != nil {
+		return false, err
+	}
+	response := bytes.TrimRight(data, "\x00")
+
+	expected := []byte(md5PasswordResponse(creds.Username, creds.Password, salt))
+	return subtle.ConstantTimeCompare(response, expected) == 1, nil
+}
+
+// md5PasswordResponse computes the response a client sends to an MD5 password request
+func md5PasswordResponse(username, password string, salt [4]byte) string {
+	inner := md5.Sum([]byte(password + username))
+	outer := md5.Sum(append([]byte(hex.EncodeToString(inner[:])), salt[:]...))
+	return "md5" + hex.EncodeToString(outer[:])
+}
+
+// exchangeSCRAM runs a SCRAM-SHA-256 exchange (RFC 5802, RFC 7677) without channel binding
+func (a *Authenticator) exchangeSCRAM(conn io.ReadWriter, creds *middleware.Credentials) (bool, error) {
+	secret := creds.SCRAM
+	if secret == nil {
+		// The salt depends only on the username, so unknown users cannot be told apart from
+		// known ones by a salt that changes between attempts
+		mac := hmac.New(sha256.New, a.saltKey)
+		mac.Write([]byte(creds.Username))
+		salt := mac.Sum(nil)[:16]
+		derived, err := middleware.DeriveSCRAMSecret(creds.Password, salt, middleware.SCRAMIterations)
+		if err != nil {
+			return false, errors.New(ErrAuthenticationFailed, "failed to derive SCRAM secret", err)
+		}
+		secret = derived
+	}
+
+	if err := WriteAuthenticationSASL(conn, SCRAMSHA256Mechanism); err != nil {
+		return false, err
+	}
+
+	// SASLInitialResponse: mechanism name, then the length-prefixed client-first-message
+	data, err := readPasswordMessage(conn)
+	if err != nil {
+		return false, err
+	}
+	mechanismEnd := bytes.IndexByte(data, 0)
+	if mechanismEnd < 0 || len(data) < mechanismEnd+5 {
+		return false, scramProtocolViolation("malformed SASLInitialResponse")
+	}
+	if mechanism := string(data[:mechanismEnd]); mechanism != SCRAMSHA256Mechanism {
+		return false, scramProtocolViolation("client selected an invalid SASL authentication mechanism")
+	}
+	clientFirst := data[mechanismEnd+5:]
+	if length := int32(binary.BigEndian.Uint32(data[mechanismEnd+1:])); length != int32(len(clientFirst)) {
+		return false, scramProtocolViolation("malformed SASLInitialResponse")
+	}
+
+	gs2Header, clientFirstBare, clientNonce, err := parseSCRAMClientFirst(string(clientFirst))
+	if err != nil {
+		return false, err
+	}
+
+	serverNonce := make([]byte, 18)
+	rand.Read(serverNonce)
+	nonce := clientNonce + base64.StdEncoding.EncodeToString(serverNonce)
+	serverFirst := fmt.Sprintf("r=%s,s=%s,i=%d", nonce, base64.StdEncoding.EncodeToString(secret.Salt), secret.Iterations)
+	if err := WriteAuthenticationSASLContinue(conn, []byte(serverFirst)); err != nil {
+		return false, err
+	}
+
+	// SASLResponse: client-final-message
+	data, err = readPasswordMessage(conn)
+	if err != nil {
+		return false, err
+	}
+	clientFinal := string(data)
+	proofStart := strings.LastIndex(clientFinal, ",p=")
+	if proofStart < 0 {
+		return false, scramProtocolViolation("malformed SCRAM message (proof missing)")
+	}
+	clientFinalWithoutProof := clientFinal[:proofStart]
+	attributes := parseSCRAMAttributes(clientFinalWithoutProof)
+	if attributes["c"] != base64.StdEncoding.EncodeToString([]byte(gs2Header)) {
+		return false, scramProtocolViolation("unexpected SCRAM channel-binding attribute in client-final-message")
+	}
+	if attributes["r"] != nonce {
+		return false, scramProtocolViolation("SCRAM nonce mismatch")
+	}
+	proof, err := base64.StdEncoding.DecodeString(clientFinal[proofStart+3:])
+	if err != nil || len(proof) != sha256.Size {
+		return false, scramProtocolViolation("malformed SCRAM message (invalid proof)")
+	}
+
+	authMessage := clientFirstBare + "," + serverFirst + "," + clientFinalWithoutProof
+	clientSignature := scramHMAC(secret.StoredKey, authMessage)
+	clientKey := make([]byte, sha256.Size)
+	for i := range clientKey {
+		clientKey[i] = proof[i] ^ clientSignature[i]
+	}
+	storedKey := sha256.Sum256(clientKey)
+	if !hmac.Equal(storedKey[:], secret.StoredKey) {
+		return false, nil
+	}
+
+	serverSignature := scramHMAC(secret.ServerKey, authMessage)
+	if err := WriteAuthenticationSASLFinal(conn, []byte("v="+base64.StdEncoding.EncodeToString(serverSignature))); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// parseSCRAMClientFirst splits a client-first-message into its GS2 header and bare
+// message and returns the client nonce
+func parseSCRAMClientFirst(message string) (string, string, string, error) {
+	switch {
+	case strings.HasPrefix(message, "p="):
+		return "", "", "", scramProtocolViolation("channel binding is not supported")
+	case !strings.HasPrefix(message, "n,") && !strings.HasPrefix(message, "y,"):
+		return "", "", "", scramProtocolViolation("malformed SCRAM message (unexpected GS2 header)")
+	}
+
+	// gs2-header = cbind-flag "," [authzid] ","
+	authzidEnd := strings.IndexByte(message[2:], ',')
+	if authzidEnd < 0 {
+		return "", "", "", scramProtocolViolation("malformed SCRAM message (unexpected GS2 header)")
+	}
+	headerLength := 2 + authzidEnd + 1
+	gs2Header, bare := message[:headerLength], message[headerLength:]
+
+	nonce := parseSCRAMAttributes(bare)["r"]
+	if nonce == "" {
+		return "", "", "", scramProtocolViolation("malformed SCRAM message (nonce missing)")
+	}
+	return gs2Header, bare, nonce, nil
+}
+
+// parseSCRAMAttributes parses comma-separated name=value SCRAM attributes
+func parseSCRAMAttributes(message string) map[string]string {
+	attributes := make(map[string]string)
+	for _, attribute := range strings.Split(message, ",") {
+		if name, value, ok := strings.Cut(attribute, "="); ok {
+			attributes[name] = value
+		}
+	}
+	return attributes
+}
+
+// scramHMAC computes HMAC-SHA-256(key, message)
+func scramHMAC(key []byte, message string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(message))
+	return mac.Sum(nil)
+}
+
+// scramProtocolViolation builds the error returned for malformed SCRAM exchanges
+func scramProtocolViolation(message string) error {
+	return errors.New(ErrAuthenticationProtocolViolation, message, nil)
+}
+
+// readPasswordMessage reads a PasswordMessage, SASLInitialResponse or SASLResponse
+func readPasswordMessage(conn io.Reader) ([]byte, error) {
+	msg, err := ReadMessage(conn)
+	if err != nil {
+		return nil, err
+	}
+	if msg.Type != MessageTypePassword {
+		return nil, errors.New(ErrAuthenticationProtocolViolation,
+			"expected password response, got message type "+strconv.QuoteRune(rune(msg.Type)), nil)
+	}
+	return msg.Data, nil
+}
+
+// registryCredentials returns the credentials of a registry user with a stored password
+// verifier, or nil if there is no such user
+func (a *Authenticator) registryCredentials(ctx context.Context, username string) (*middleware.Credentials, error) {
+	if a.users == nil {
+		return nil, nil
+	}
+
+	user, err := a.users.GetUser(ctx, username)
+	if err != nil {
+		if errors.GetCode(err) == registry.RegistryUserNotFound.String() {
+			return nil, nil
+		}
+		return nil, errors.New(ErrUserLookupFailed, "failed to look up user", err)
+	}
+	// Registry records without a verifier, such as the system user, cannot log in
+	if user.SCRAMSecret == "" {
+		return nil, nil
+	}
+
+	secret, err := middleware.ParseSCRAMSecret(user.SCRAMSecret)
+	if err != nil {
+		return nil, errors.New(ErrUserLookupFailed, "failed to read stored password", err).AddContext("username", username)
+	}
+	return &middleware.Credentials{Username: user.Username, Database: "default", SCRAM: secret}, nil
+}
+
+// checkLoginPermitted rejects users disabled in the credential store or deactivated in the registry
+func (a *Authenticator) checkLoginPermitted(ctx context.Context, creds *middleware.Credentials) error {
+	notPermitted := errors.New(ErrLoginNotPermitted, fmt.Sprintf("role \"%s\" is not permitted to log in", creds.Username), nil)
+	if creds.Disabled {
+		return notPermitted
+	}
+	if a.users == nil {
+		return nil
+	}
+
+	user, err := a.users.GetUser(ctx, creds.Username)
+	if err != nil {
+		// Users managed only by the AuthProvider have no registry record
+		if errors.GetCode(err) == registry.RegistryUserNotFound.String() {
+			return nil
+		}
+		return errors.New(ErrUserLookupFailed, "failed to look up user", err)
+	}
+	if !user.IsActive {
+		return notPermitted
+	}
+	return nil
+}
+
+// authenticationSQLState maps an authentication error to the SQLSTATE reported to the client
+func authenticationSQLState(err error) string {
+	switch errors.GetCode(err) {
+	case ErrAuthenticationFailed.String():
+		return SQLStateInvalidPassword
+	case ErrRoleNotFound.String(), ErrLoginNotPermitted.String():
+		return SQLStateInvalidAuthorization
+	case ErrAuthenticationProtocolViolation.String():
+		return SQLStateProtocolViolation
+	default:
+		return SQLStateInternalError
+	}
+}
@@ -0,0 +1,1195 @@
+package jdbc
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/apache/iceberg-go"
+	"github.com/gear6io/ranger/pkg/errors"
+	"github.com/gear6io/ranger/server/query"
+	"github.com/gear6io/ranger/server/query/access"
+	"github.com/gear6io/ranger/server/query/parser"
+	"github.com/gear6io/ranger/server/storage/parquet"
+)
+
+// copyBatchSize is the number of rows buffered before a COPY FROM STDIN batch is committed
+const copyBatchSize = 10000
+
+// Copy formats supported by the copy sub-protocol
+const (
+	CopyFormatText   = "text"
+	CopyFormatCSV    = "csv"
+	CopyFormatBinary = "binary"
+)
+
+// SQLSTATE codes reported by the copy sub-protocol and failed queries
+const (
+	SQLStateSyntaxError           = "42601"
+	SQLStateUndefinedTable        = "42P01"
+	SQLStateUndefinedColumn       = "42703"
+	SQLStateInsufficientPrivilege = "42501"
+	SQLStateBadCopyFileFormat     = "22P04"
+	SQLStateInvalidText           = "22P02"
+	SQLStateQueryCanceled         = "57014"
+	SQLStateInternalError         = "XX000"
+)
+
+// binaryCopySignature starts every binary COPY stream
+var binaryCopySignature = []byte("PGCOPY\n\377\r\n\000")
+
+// postgresEpoch is the zero point of binary date and timestamp values
+var postgresEpoch = time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC)
+
+var (
+	// copyQueryPattern matches COPY (query) TO STDOUT [options]
+	copyQueryPattern = regexp.MustCompile(`(?is)^COPY\s+\((.+)\)\s+TO\s+STDOUT\b\s*(.*?)\s*;?\s*$`)
+
+	// copyTablePattern matches COPY table [(columns)] FROM STDIN | TO STDOUT [options]
+	copyTablePattern = regexp.MustCompile(`(?is)^COPY\s+("?[a-z_][a-z0-9_]*"?(?:\s*\.\s*"?[a-z_][a-z0-9_]*"?)?)\s*(?:\(([^)]*)\))?\s+(FROM\s+STDIN|TO\s+STDOUT)\b\s*(.*?)\s*;?\s*$`)
+)
+
+// CopyOptions holds the options of a COPY statement
+type CopyOptions struct {
+	Format    string
+	Delimiter byte
+	Null      string
+	Header    bool
+	Quote     byte
+	Escape    byte
+}
+
+// CopyStatement represents a parsed COPY statement
+type CopyStatement struct {
+	Database  string
+	Table     string
+	Columns   []string
+	Query     string
+	FromStdin bool
+	Options   CopyOptions
+}
+
+// IsCopyStatement checks if a query is a COPY statement
+func IsCopyStatement(query string) bool {
+	trimmed := strings.TrimSpace(query)
+	return len(trimmed) > 4 && strings.EqualFold(trimmed[:4], "COPY") && isSpace(trimmed[4])
+}
+
+// ParseCopyStatement parses COPY ... FROM STDIN and COPY ... TO STDOUT statements
+func ParseCopyStatement(query string, defaultDatabase string) (*CopyStatement, error) {
+	query = strings.TrimSpace(query)
+	stmt := &CopyStatement{Database: defaultDatabase}
+
+	var optionText string
+	if match := copyQueryPattern.FindStringSubmatch(query); match != nil {
+		stmt.Query = strings.TrimSpace(match[1])
+		optionText = match[2]
+	} else if match := copyTablePattern.FindStringSubmatch(query); match != nil {
+		parts := strings.Split(match[1], ".")
+		stmt.Table = unquoteIdentifier(parts[len(parts)-1])
+		if len(parts) == 2 {
+			stmt.Database = unquoteIdentifier(parts[0])
+		}
+		if strings.TrimSpace(match[2]) != "" {
+			for _, column := range strings.Split(match[2], ",") {
+				stmt.Columns = append(stmt.Columns, unquoteIdentifier(column))
+			}
+		}
+		stmt.FromStdin = strings.HasPrefix(strings.ToUpper(match[3]), "FROM")
+		optionText = match[4]
+	} else {
+		return nil, errors.New(ErrCopyStatementInvalid, "only COPY ... FROM STDIN and COPY ... TO STDOUT are supported", nil).
+			AddContext("query", query)
+	}
+
+	options, err := parseCopyOptions(optionText)
+	if err != nil {
+		return nil, err
+	}
+	stmt.Options = *options
+
+	return stmt, nil
+}
+
+// copyOptionToken is a word, quoted string or punctuation mark of a COPY option clause
+type copyOptionToken struct {
+	text   string
+	quoted bool
+}
+
+// is reports whether the token is the given unquoted word or punctuation mark
+func (t copyOptionToken) is(text string) bool {
+	return !t.quoted && strings.EqualFold(t.text, text)
+}
+
+// parseCopyOptions parses both the parenthesized option list and the legacy
+// pre-9.0 option syntax (WITH CSV HEADER DELIMITER ',')
+func parseCopyOptions(text string) (*CopyOptions, error) {
+	tokens, err := tokenizeCopyOptions(text)
+	if err != nil {
+		return nil, err
+	}
+	if len(tokens) > 0 && tokens[0].is("WITH") {
+		tokens = tokens[1:]
+	}
+
+	settings := make(map[string]string)
+	if len(tokens) > 0 && tokens[0].is("(") {
+		if !tokens[len(tokens)-1].is(")") {
+			return nil, errors.New(ErrCopyStatementInvalid, "unterminated COPY option list", nil)
+		}
+		var item []string
+		for _, token := range append(tokens[1:len(tokens)-1], copyOptionToken{text: ","}) {
+			if !token.is(",") {
+				item = append(item, token.text)
+				continue
+			}
+			if len(item) == 0 {
+				return nil, errors.New(ErrCopyStatementInvalid, "empty COPY option", nil)
+			}
+			value := "true"
+			if len(item) > 1 {
+				value = item[1]
+			}
+			settings[strings.ToLower(item[0])] = value
+			item = nil
+		}
+	} else {
+		for i := 0; i < len(tokens); i++ {
+			name := strings.ToLower(tokens[i].text)
+			switch name {
+			case "binary", "csv":
+				settings["format"] = name
+			case "header":
+				settings["header"] = "true"
+			case "delimiter", "null", "quote", "escape":
+				if i+1 < len(tokens) && tokens[i+1].is("AS") {
+					i++
+				}
+				if i+1 >= len(tokens) {
+					return nil, errors.New(ErrCopyStatementInvalid, "missing value for COPY option", nil).AddContext("option", name)
+				}
+				i++
+				settings[name] = tokens[i].text
+			default:
+				return nil, errors.New(ErrCopyStatementInvalid, "unrecognized COPY option", nil).AddContext("option", tokens[i].text)
+			}
+		}
+	}
+
+	options := &CopyOptions{Format: CopyFormatText}
+	if format, ok := settings["format"]; ok {
+		options.Format = strings.ToLower(format)
+	}
+
+	switch options.Format {
+	case CopyFormatText:
+		options.Delimiter = '\t'
+		options.Null = `\N`
+	case CopyFormatCSV:
+		options.Delimiter = ','
+		options.Quote = '"'
+	case CopyFormatBinary:
+	default:
+		return nil, errors.New(ErrCopyStatementInvalid, "unsupported COPY format", nil).AddContext("format", options.Format)
+	}
+
+	for name, value := range settings {
+		switch name {
+		case "format":
+		case "header":
+			options.Header = parseCopyBool(value)
+		case "null":
+			options.Null = value
+		case "delimiter", "quote", "escape":
+			if len(value) != 1 {
+				return nil, errors.New(ErrCopyStatementInvalid, "COPY "+name+" must be a single one-byte character", nil)
+			}
+			switch name {
+			case "delimiter":
+				options.Delimiter = value[0]
+			case "quote":
+				options.Quote = value[0]
+			case "escape":
+				options.Escape = value[0]
+			}
+		default:
+			return nil, errors.New(ErrCopyStatementInvalid, "unrecognized COPY option", nil).AddContext("option", name)
+		}
+	}
+	if options.Escape == 0 {
+		options.Escape = options.Quote
+	}
+
+	return options, nil
+}
+
+// tokenizeCopyOptions splits a COPY option clause into words, quoted strings and punctuation
+func tokenizeCopyOptions(text string) ([]copyOptionToken, error) {
+	var tokens []copyOptionToken
+	for i := 0; i < len(text); {
+		c := text[i]
+		switch {
+		case isSpace(c):
+			i++
+		case c == '(' || c == ')' || c == ',':
+			tokens = append(tokens, copyOptionToken{text: string(c)})
+			i++
+		case c == '\'' || c == '"':
+			var value strings.Builder
+			j := i + 1
+			for ; j < len(text); j++ {
+				if text[j] == c {
+					if j+1 < len(text) && text[j+1] == c {
+						value.WriteByte(c)
+						j++
+						continue
+					}
+					break
+				}
+				value.WriteByte(text[j])
+			}
+			if j >= len(text) {
+				return nil, errors.New(ErrCopyStatementInvalid, "unterminated quoted string in COPY options", nil)
+			}
+			// E'\t' style escape strings are common for delimiters
+			literal := value.String()
+			if len(tokens) > 0 && tokens[len(tokens)-1].is("E") {
+				tokens = tokens[:len(tokens)-1]
+				literal = unescapeText(literal)
+			}
+			tokens = append(tokens, copyOptionToken{text: literal, quoted: true})
+			i = j + 1
+		default:
+			j := i
+			for j < len(text) && !isSpace(text[j]) && !strings.ContainsRune("(),'\"", rune(text[j])) {
+				j++
+			}
+			tokens = append(tokens, copyOptionToken{text: text[i:j]})
+			i = j
+		}
+	}
+	return tokens, nil
+}
+
+// parseCopyBool interprets boolean COPY option values
+func parseCopyBool(value string) bool {
+	switch strings.ToLower(value) {
+	case "false", "off", "0", "no":
+		return false
+	default:
+		return true
+	}
+}
+
+// unquoteIdentifier trims whitespace and double quotes from an identifier
+func unquoteIdentifier(identifier string) string {
+	return strings.Trim(strings.TrimSpace(identifier), `"`)
+}
+
+func isSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\r'
+}
+
+// =============================================================================
+// COPY FROM STDIN
+// =============================================================================
+
+// copyInReader exposes the CopyData messages of a COPY FROM STDIN as a byte stream
+type copyInReader struct {
+	conn     io.Reader
+	buf      []byte
+	done     bool
+	canceled bool
+	err      error
+}
+
+// Read implements io.Reader, returning io.EOF once the client sends CopyDone
+func (r *copyInReader) Read(p []byte) (int, error) {
+	for len(r.buf) == 0 {
+		if r.done {
+			return 0, io.EOF
+		}
+		if r.err != nil {
+			return 0, r.err
+		}
+
+		msg, err := ReadMessage(r.conn)
+		if err != nil {
+			r.err = err
+			return 0, err
+		}
+
+		switch msg.Type {
+		case MessageTypeCopyData:
+			r.buf = msg.Data
+		case MessageTypeCopyDone:
+			r.done = true
+		case MessageTypeCopyFail:
+			r.canceled = true
+			r.err = errors.New(ErrCopyAborted, "COPY from stdin failed: "+strings.TrimRight(string(msg.Data), "\x00"), nil)
+		case MessageTypeFlush, MessageTypeSync:
+			// Flush and Sync are ignored while copying
+		default:
+			r.err = errors.New(ErrCopyProtocolViolation, fmt.Sprintf("unexpected message type %c during COPY from stdin", msg.Type), nil)
+		}
+	}
+
+	n := copy(p, r.buf)
+	r.buf = r.buf[n:]
+	return n, nil
+}
+
+// drain discards the rest of the copy stream after a failure so the connection stays in sync
+func (r *copyInReader) drain() {
+	scratch := make([]byte, 4096)
+	for !r.done && r.err == nil {
+		r.buf = nil
+		_, _ = r.Read(scratch)
+	}
+}
+
+// copyRowDecoder yields the raw fields of each incoming row; nil fields are NULL
+type copyRowDecoder interface {
+	next() ([][]byte, error)
+}
+
+// newCopyRowDecoder returns the decoder for the statement's format
+func newCopyRowDecoder(reader io.Reader, options CopyOptions) copyRowDecoder {
+	buffered := bufio.NewReaderSize(reader, 64*1024)
+	switch options.Format {
+	case CopyFormatCSV:
+		return &csvCopyDecoder{reader: buffered, options: options, skipHeader: options.Header}
+	case CopyFormatBinary:
+		return &binaryCopyDecoder{reader: buffered}
+	default:
+		return &textCopyDecoder{reader: buffered, options: options, skipHeader: options.Header}
+	}
+}
+
+// textCopyDecoder decodes the PostgreSQL text COPY format
+type textCopyDecoder struct {
+	reader     *bufio.Reader
+	options    CopyOptions
+	skipHeader bool
+}
+
+func (d *textCopyDecoder) next() ([][]byte, error) {
+	for {
+		line, err := d.reader.ReadString('\n')
+		if err != nil && (err != io.EOF || line == "") {
+			return nil, err
+		}
+		line = strings.TrimSuffix(strings.TrimSuffix(line, "\n"), "\r")
+
+		// \. is the legacy end-of-data marker
+		if line == `\.` {
+			return nil, io.EOF
+		}
+		if d.skipHeader {
+			d.skipHeader = false
+			continue
+		}
+
+		var fields [][]byte
+		start := 0
+		for i := 0; i <= len(line); i++ {
+			if i+1 < len(line) && line[i] == '\\' {
+				i++
+				continue
+			}
+			if i < len(line) && line[i] != d.options.Delimiter {
+				continue
+			}
+			raw := line[start:min(i, len(line))]
+			if raw == d.options.Null {
+				fields = append(fields, nil)
+			} else {
+				fields = append(fields, []byte(unescapeText(raw)))
+			}
+			start = i + 1
+		}
+		return fields, nil
+	}
+}
+
+// unescapeText resolves the backslash escapes of the text COPY format
+func unescapeText(raw string) string {
+	if !strings.Contains(raw, `\`) {
+		return raw
+	}
+
+	var out strings.Builder
+	for i := 0; i < len(raw); i++ {
+		if raw[i] != '\\' || i+1 >= len(raw) {
+			out.WriteByte(raw[i])
+			continue
+		}
+		i++
+		switch c := raw[i]; c {
+		case 'b':
+			out.WriteByte('\b')
+		case 'f':
+			out.WriteByte('\f')
+		case 'n':
+			out.WriteByte('\n')
+		case 'r':
+			out.WriteByte('\r')
+		case 't':
+			out.WriteByte('\t')
+		case 'v':
+			out.WriteByte('\v')
+		case 'x':
+			j := i + 1
+			for j < len(raw) && j < i+3 && isHexDigit(raw[j]) {
+				j++
+			}
+			if j == i+1 {
+				out.WriteByte(c)
+				continue
+			}
+			v, _ := strconv.ParseUint(raw[i+1:j], 16, 8)
+			out.WriteByte(byte(v))
+			i = j - 1
+		default:
+			if c >= '0' && c <= '7' {
+				j := i
+				for j < len(raw) && j < i+3 && raw[j] >= '0' && raw[j] <= '7' {
+					j++
+				}
+				v, _ := strconv.ParseUint(raw[i:j], 8, 8)
+				out.WriteByte(byte(v))
+				i = j - 1
+				continue
+			}
+			out.WriteByte(c)
+		}
+	}
+	return out.String()
+}
+
+func isHexDigit(c byte) bool {
+	return (c >= '0' && c <= '9') || (c >= 'a' && c <= 'f') || (c >= 'A' && c <= 'F')
+}
+
+// csvCopyDecoder decodes the CSV COPY format; quoted fields may span lines
+type csvCopyDecoder struct {
+	reader     *bufio.Reader
+	options    CopyOptions
+	skipHeader bool
+}
+
+func (d *csvCopyDecoder) next() ([][]byte, error) {
+	for {
+		fields, err := d.readRecord()
+		if err != nil {
+			return nil, err
+		}
+		if d.skipHeader {
+			d.skipHeader = false
+			continue
+		}
+		return fields, nil
+	}
+}
+
+// readRecord reads one CSV record; an unquoted field matching the NULL string is NULL
+func (d *csvCopyDecoder) readRecord() ([][]byte, error) {
+	var fields [][]byte
+	var field bytes.Buffer
+	quoted, inQuotes, started := false, false, false
+
+	finishField := func() {
+		if !quoted && field.String() == d.options.Null {
+			fields = append(fields, nil)
+		} else {
+			fields = append(fields, append([]byte{}, field.Bytes()...))
+		}
+		field.Reset()
+		quoted = false
+	}
+
+	for {
+		c, err := d.reader.ReadByte()
+		if err == io.EOF {
+			if inQuotes {
+				return nil, errors.New(ErrCopyDataInvalid, "unterminated CSV quoted field", nil)
+			}
+			if !started {
+				return nil, io.EOF
+			}
+			finishField()
+			return fields, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		// \. on its own line is the legacy end-of-data marker
+		if !started && c == '\\' {
+			if peek, _ := d.reader.Peek(1); len(peek) == 1 && peek[0] == '.' {
+				d.reader.ReadByte()
+				d.reader.ReadString('\n')
+				return nil, io.EOF
+			}
+		}
+		started = true
+
+		switch {
+		case inQuotes && c == d.options.Escape && d.options.Escape != d.options.Quote:
+			next, err := d.reader.ReadByte()
+			if err != nil {
+				return nil, errors.New(ErrCopyDataInvalid, "unterminated CSV quoted field", nil)
+			}
+			field.WriteByte(next)
+		case inQuotes && c == d.options.Quote:
+			if peek, _ := d.reader.Peek(1); len(peek) == 1 && peek[0] == d.options.Quote {
+				d.reader.ReadByte()
+				field.WriteByte(c)
+			} else {
+				inQuotes = false
+			}
+		case inQuotes:
+			field.WriteByte(c)
+		case c == d.options.Quote:
+			inQuotes, quoted = true, true
+		case c == d.options.Delimiter:
+			finishField()
+		case c == '\r':
+			// \r\n line endings
+		case c == '\n':
+			finishField()
+			return fields, nil
+		default:
+			field.WriteByte(c)
+		}
+	}
+}
+
+// binaryCopyDecoder decodes the PostgreSQL binary COPY format
+type binaryCopyDecoder struct {
+	reader     *bufio.Reader
+	readHeader bool
+}
+
+func (d *binaryCopyDecoder) next() ([][]byte, error) {
+	if !d.readHeader {
+		header := make([]byte, len(binaryCopySignature)+8)
+		if _, err := io.ReadFull(d.reader, header); err != nil {
+			return nil, errors.New(ErrCopyDataInvalid, "missing binary COPY header", err)
+		}
+		if !bytes.Equal(header[:len(binaryCopySignature)], binaryCopySignature) {
+			return nil, errors.New(ErrCopyDataInvalid, "invalid binary COPY signature", nil)
+		}
+		extensionLength := binary.BigEndian.Uint32(header[len(binaryCopySignature)+4:])
+		if _, err := d.reader.Discard(int(extensionLength)); err != nil {
+			return nil, errors.New(ErrCopyDataInvalid, "truncated binary COPY header extension", err)
+		}
+		d.readHeader = true
+	}
+
+	var countBuf [2]byte
+	if _, err := io.ReadFull(d.reader, countBuf[:]); err != nil {
+		if err == io.EOF {
+			return nil, io.EOF
+		}
+		return nil, errors.New(ErrCopyDataInvalid, "truncated binary COPY tuple", err)
+	}
+	count := int16(binary.BigEndian.Uint16(countBuf[:]))
+	if count == -1 {
+		return nil, io.EOF
+	}
+	if count < 0 {
+		return nil, errors.New(ErrCopyDataInvalid, "invalid binary COPY field count", nil).AddContext("count", count)
+	}
+
+	fields := make([][]byte, count)
+	var lengthBuf [4]byte
+	for i := range fields {
+		if _, err := io.ReadFull(d.reader, lengthBuf[:]); err != nil {
+			return nil, errors.New(ErrCopyDataInvalid, "truncated binary COPY field", err)
+		}
+		length := int32(binary.BigEndian.Uint32(lengthBuf[:]))
+		if length == -1 {
+			continue
+		}
+		if length < 0 {
+			return nil, errors.New(ErrCopyDataInvalid, "invalid binary COPY field length", nil).AddContext("length", length)
+		}
+		// The field grows as it is read, so a length the stream does not hold allocates nothing
+		field, err := io.ReadAll(io.LimitReader(d.reader, int64(length)))
+		if err != nil || len(field) != int(length) {
+			return nil, errors.New(ErrCopyDataInvalid, "truncated binary COPY field", err)
+		}
+		fields[i] = field
+	}
+	return fields, nil
+}
+
+// decodeBinaryValue converts a binary-format field into the Go value expected for icebergType
+func decodeBinaryValue(data []byte, icebergType iceberg.Type) (interface{}, error) {
+	invalid := func() error {
+		return errors.New(ErrCopyDataInvalid, fmt.Sprintf("invalid binary value of %d bytes for type %s", len(data), icebergType.String()), nil)
+	}
+
+	switch icebergType {
+	case iceberg.PrimitiveTypes.Bool:
+		if len(data) != 1 {
+			return nil, invalid()
+		}
+		return data[0] != 0, nil
+	case iceberg.PrimitiveTypes.Int32, iceberg.PrimitiveTypes.Int64:
+		var v int64
+		switch len(data) {
+		case 2:
+			v = int64(int16(binary.BigEndian.Uint16(data)))
+		case 4:
+			v = int64(int32(binary.BigEndian.Uint32(data)))
+		case 8:
+			v = int64(binary.BigEndian.Uint64(data))
+		default:
+			return nil, invalid()
+		}
+		if icebergType == iceberg.PrimitiveTypes.Int32 {
+			if v < math.MinInt32 || v > math.MaxInt32 {
+				return nil, errors.New(ErrCopyDataInvalid, "value out of range for type int", nil)
+			}
+			return int32(v), nil
+		}
+		return v, nil
+	case iceberg.PrimitiveTypes.Float32, iceberg.PrimitiveTypes.Float64:
+		var v float64
+		switch len(data) {
+		case 4:
+			v = float64(math.Float32frombits(binary.BigEndian.Uint32(data)))
+		case 8:
+			v = math.Float64frombits(binary.BigEndian.Uint64(data))
+		default:
+			return nil, invalid()
+		}
+		if icebergType == iceberg.PrimitiveTypes.Float32 {
+			return float32(v), nil
+		}
+		return v, nil
+	case iceberg.PrimitiveTypes.String:
+		return string(data), nil
+	case iceberg.PrimitiveTypes.UUID:
+		if len(data) != 16 {
+			return string(data), nil
+		}
+		h := hex.EncodeToString(data)
+		return h[0:8] + "-" + h[8:12] + "-" + h[12:16] + "-" + h[16:20] + "-" + h[20:], nil
+	case iceberg.PrimitiveTypes.Binary:
+		return data, nil
+	case iceberg.PrimitiveTypes.Date:
+		if len(data) != 4 {
+			return nil, invalid()
+		}
+		return postgresEpoch.AddDate(0, 0, int(int32(binary.BigEndian.Uint32(data)))), nil
+	case iceberg.PrimitiveTypes.Time:
+		if len(data) != 8 {
+			return nil, invalid()
+		}
+		micros := int64(binary.BigEndian.Uint64(data))
+		return postgresEpoch.Add(time.Duration(micros) * time.Microsecond).Format("15:04:05.999999"), nil
+	case iceberg.PrimitiveTypes.Timestamp, iceberg.PrimitiveTypes.TimestampTz:
+		if len(data) != 8 {
+			return nil, invalid()
+		}
+		micros := int64(binary.BigEndian.Uint64(data))
+		return postgresEpoch.Add(time.Duration(micros) * time.Microsecond), nil
+	}
+
+	switch icebergType.(type) {
+	case iceberg.DecimalType:
+		return decodeBinaryNumeric(data)
+	case iceberg.FixedType:
+		return data, nil
+	}
+
+	return nil, errors.New(ErrCopyDataInvalid, "binary COPY is not supported for type "+icebergType.String(), nil)
+}
+
+// decodeBinaryNumeric renders a binary numeric (base-10000 digits) as a decimal string
+func decodeBinaryNumeric(data []byte) (string, error) {
+	if len(data) < 8 {
+		return "", errors.New(ErrCopyDataInvalid, "truncated binary numeric", nil)
+	}
+	ndigits := int(binary.BigEndian.Uint16(data[0:2]))
+	weight := int(int16(binary.BigEndian.Uint16(data[2:4])))
+	sign := binary.BigEndian.Uint16(data[4:6])
+	dscale := int(binary.BigEndian.Uint16(data[6:8]))
+	if len(data) != 8+2*ndigits {
+		return "", errors.New(ErrCopyDataInvalid, "truncated binary numeric", nil)
+	}
+	if sign == 0xC000 {
+		return "NaN", nil
+	}
+
+	digit := func(i int) int {
+		if i < 0 || i >= ndigits {
+			return 0
+		}
+		return int(binary.BigEndian.Uint16(data[8+2*i:]))
+	}
+
+	var out strings.Builder
+	if sign == 0x4000 {
+		out.WriteByte('-')
+	}
+	if weight < 0 {
+		out.WriteByte('0')
+	}
+	for i := 0; i <= weight; i++ {
+		if i == 0 {
+			out.WriteString(strconv.Itoa(digit(i)))
+		} else {
+			fmt.Fprintf(&out, "%04d", digit(i))
+		}
+	}
+	if dscale > 0 {
+		var fraction strings.Builder
+		for i := weight + 1; fraction.Len() < dscale; i++ {
+			fmt.Fprintf(&fraction, "%04d", digit(i))
+		}
+		out.WriteByte('.')
+		out.WriteString(fraction.String()[:dscale])
+	}
+	return out.String(), nil
+}
+
+// handleCopyIn runs COPY ... FROM STDIN, committing rows through Storage.InsertData in batches
+func (h *JDBCHandler) handleCopyIn(conn io.ReadWriter, stmt *CopyStatement) error {
+	// Rows bypass ExecuteQuery, so INSERT is checked and audited here
+	start := time.Now()
+	var failure error
+	defer func() {
+		h.queryEngine.AuditInsert(h.ctx, h.queryContext("", stmt.Database), stmt.Database, stmt.Table, start, failure)
+	}()
+
+	requirement := access.Requirement{Action: parser.PRIV_INSERT, Database: stmt.Database, Table: stmt.Table, Columns: stmt.Columns}
+	if err := h.queryEngine.Authorize(h.ctx, h.user, requirement); err != nil {
+		failure = err
+		return h.writeQueryError(conn, querySQLState(err), err.Error())
+	}
+
+	schema, err := h.queryEngine.GetTableSchema(h.ctx, stmt.Database, stmt.Table)
+	if err != nil {
+		failure = err
+		if errors.GetCode(err) == query.ErrTableNotFound.String() {
+			return h.writeQueryError(conn, SQLStateUndefinedTable, fmt.Sprintf("relation \"%s.%s\" does not exist", stmt.Database, stmt.Table))
+		}
+		return h.writeQueryError(conn, SQLStateInternalError, err.Error())
+	}
+
+	// Map each incoming column onto its position in the table schema
+	fields := schema.Fields()
+	targets := make([]int, 0, len(fields))
+	if len(stmt.Columns) == 0 {
+		for i := range fields {
+			targets = append(targets, i)
+		}
+	} else {
+		for _, column := range stmt.Columns {
+			index := -1
+			for i, field := range fields {
+				if strings.EqualFold(field.Name, column) {
+					index = i
+					break
+				}
+			}
+			if index < 0 {
+				failure = errors.New(ErrCopyDataInvalid, fmt.Sprintf("column \"%s\" of relation \"%s\" does not exist", column, stmt.Table), nil)
+				return h.writeQueryError(conn, SQLStateUndefinedColumn, failure.Error())
+			}
+			targets = append(targets, index)
+		}
+	}
+
+	format := int8(0)
+	if stmt.Options.Format == CopyFormatBinary {
+		format = 1
+	}
+	if err := WriteCopyInResponse(conn, format, len(targets)); err != nil {
+		return err
+	}
+
+	reader := &copyInReader{conn: conn}
+	decoder := newCopyRowDecoder(reader, stmt.Options)
+
+	var batch [][]interface{}
+	var total int64
+	flush := func() error {
+		if len(batch) == 0 {
+			return nil
+		}
+		if err := h.queryEngine.InsertDataBatchStreaming(h.ctx, stmt.Database, stmt.Table, batch, copyBatchSize); err != nil {
+			return err
+		}
+		total += int64(len(batch))
+		batch = nil
+		return nil
+	}
+
+	fail := func(sqlState string, err error) error {
+		reader.drain()
+		if reader.canceled {
+			sqlState = SQLStateQueryCanceled
+			err = reader.err
+		}
+		failure = err
+		h.logger.Warn().Err(err).Str("table", stmt.Table).Int64("rows_committed", total).Msg("COPY FROM STDIN failed")
+		return h.writeQueryError(conn, sqlState, err.Error())
+	}
+
+	for line := 1; ; line++ {
+		raw, err := decoder.next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return fail(SQLStateBadCopyFileFormat, err)
+		}
+		if len(raw) != len(targets) {
+			return fail(SQLStateBadCopyFileFormat, errors.New(ErrCopyDataInvalid,
+				fmt.Sprintf("line %d: expected %d columns, got %d", line, len(targets), len(raw)), nil))
+		}
+
+		row := make([]interface{}, len(fields))
+		for i, value := range raw {
+			if value == nil {
+				continue
+			}
+			field := fields[targets[i]]
+			var converted interface{}
+			if stmt.Options.Format == CopyFormatBinary {
+				converted, err = decodeBinaryValue(value, field.Type)
+			} else {
+				converted, err = parquet.ParseTextValue(string(value), field.Type)
+			}
+			if err != nil {
+				return fail(SQLStateInvalidText, errors.New(ErrCopyDataInvalid,
+					fmt.Sprintf("line %d, column %s: %v", line, field.Name, err), err))
+			}
+			row[targets[i]] = converted
+		}
+
+		batch = append(batch, row)
+		if len(batch) >= copyBatchSize {
+			if err := flush(); err != nil {
+				return fail(SQLStateBadCopyFileFormat, err)
+			}
+		}
+	}
+
+	if err := flush(); err != nil {
+		return fail(SQLStateBadCopyFileFormat, err)
+	}
+
+	h.logger.Info().Str("database", stmt.Database).Str("table", stmt.Table).Int64("rows", total).Msg("COPY FROM STDIN completed")
+
+	if err := WriteCommandComplete(conn, fmt.Sprintf("COPY %d", total)); err != nil {
+		return err
+	}
+	return WriteReadyForQuery(conn, 'I')
+}
+
+// =============================================================================
+// COPY TO STDOUT
+// =============================================================================
+
+// handleCopyOut runs COPY ... TO STDOUT, streaming every result row as a CopyData message
+func (h *JDBCHandler) handleCopyOut(conn io.Writer, stmt *CopyStatement) error {
+	sql := stmt.Query
+	if sql == "" {
+		columns := "*"
+		if len(stmt.Columns) > 0 {
+			columns = strings.Join(stmt.Columns, ", ")
+		}
+		sql = fmt.Sprintf("SELECT %s FROM %s.%s", columns, stmt.Database, stmt.Table)
+	}
+	if !strings.HasSuffix(strings.TrimSpace(sql), ";") {
+		sql += ";"
+	}
+
+	// Rows are sent as they are produced, so an export is never held in memory whole
+	sink := &copyOutSink{conn: conn, options: stmt.Options}
+	if _, err := h.queryEngine.StreamQuery(h.ctx, h.queryContext(sql, stmt.Database), sink); err != nil {
+		if sink.writeErr != nil {
+			return sink.writeErr
+		}
+		// An error once rows are flowing ends the copy like any other query error
+		return h.writeQueryError(conn, querySQLState(err), err.Error())
+	}
+
+	if stmt.Options.Format == CopyFormatBinary {
+		if err := WriteCopyData(conn, []byte{0xFF, 0xFF}); err != nil {
+			return err
+		}
+	}
+
+	if err := WriteCopyDone(conn); err != nil {
+		return err
+	}
+	if err := WriteCommandComplete(conn, fmt.Sprintf("COPY %d", sink.rows)); err != nil {
+		return err
+	}
+	return WriteReadyForQuery(conn, 'I')
+}
+
+// copyOutSink writes the result of COPY TO STDOUT to the client, one CopyData message per row
+type copyOutSink struct {
+	conn     io.Writer
+	options  CopyOptions
+	rows     int
+	writeErr error // Failure writing to the client, which ends the session
+}
+
+// Columns starts the copy and writes the binary header or the header line of the format
+func (s *copyOutSink) Columns(names, _ []string) error {
+	format := int8(0)
+	if s.options.Format == CopyFormatBinary {
+		format = 1
+	}
+	if err := WriteCopyOutResponse(s.conn, format, len(names)); err != nil {
+		return s.fail(err)
+	}
+
+	switch s.options.Format {
+	case CopyFormatBinary:
+		header := append(append([]byte(nil), binaryCopySignature...), 0, 0, 0, 0, 0, 0, 0, 0)
+		if err := WriteCopyData(s.conn, header); err != nil {
+			return s.fail(err)
+		}
+	default:
+		if s.options.Header {
+			header := make([]interface{}, len(names))
+			for i, name := range names {
+				header[i] = name
+			}
+			if err := WriteCopyData(s.conn, encodeCopyTextRow(header, s.options)); err != nil {
+				return s.fail(err)
+			}
+		}
+	}
+	return nil
+}
+
+// Row writes a row in the format of the copy
+func (s *copyOutSink) Row(values []interface{}) error {
+	var data []byte
+	if s.options.Format == CopyFormatBinary {
+		data = encodeCopyBinaryRow(values)
+	} else {
+		data = encodeCopyTextRow(values, s.options)
+	}
+	if err := WriteCopyData(s.conn, data); err != nil {
+		return s.fail(err)
+	}
+	s.rows++
+	return nil
+}
+
+// fail records a failure writing to the client
+func (s *copyOutSink) fail(err error) error {
+	s.writeErr = err
+	return err
+}
+
+// encodeCopyTextRow renders a row in the text or CSV COPY format
+func encodeCopyTextRow(row []interface{}, options CopyOptions) []byte {
+	var out bytes.Buffer
+	for i, value := range row {
+		if i > 0 {
+			out.WriteByte(options.Delimiter)
+		}
+		if value == nil {
+			out.WriteString(options.Null)
+			continue
+		}
+
+		text := formatCopyValue(value)
+		if options.Format == CopyFormatCSV {
+			if text == options.Null || strings.ContainsAny(text, string([]byte{options.Delimiter, options.Quote, '\r', '\n'})) {
+				quote := string(options.Quote)
+				text = quote + strings.ReplaceAll(text, quote, string(options.Escape)+quote) + quote
+			}
+			out.WriteString(text)
+			continue
+		}
+
+		for j := 0; j < len(text); j++ {
+			switch c := text[j]; c {
+			case '\\':
+				out.WriteString(`\\`)
+			case '\n':
+				out.WriteString(`\n`)
+			case '\r':
+				out.WriteString(`\r`)
+			case '\t':
+				out.WriteString(`\t`)
+			default:
+				if c == options.Delimiter {
+					out.WriteByte('\\')
+				}
+				out.WriteByte(c)
+			}
+		}
+	}
+	out.WriteByte('\n')
+	return out.Bytes()
+}
+
+// formatCopyValue renders a value the way PostgreSQL prints it in text format
+func formatCopyValue(value interface{}) string {
+	switch v := value.(type) {
+	case string:
+		return v
+	case []byte:
+		return `\x` + hex.EncodeToString(v)
+	case bool:
+		if v {
+			return "t"
+		}
+		return "f"
+	case float32:
+		return strconv.FormatFloat(float64(v), 'g', -1, 32)
+	case float64:
+		return strconv.FormatFloat(v, 'g', -1, 64)
+	case time.Time:
+		return v.Format("2006-01-02 15:04:05.999999")
+	default:
+		if text, ok := formatNestedValue(v); ok {
+			return text
+		}
+		return fmt.Sprintf("%v", v)
+	}
+}
+
+// formatNestedValue renders a list as a PostgreSQL array literal and a map or struct as JSON,
+// the text clients read for array and json columns. It reports false for other values.
+func formatNestedValue(value interface{}) (string, bool) {
+	if _, ok := value.([]byte); ok {
+		return "", false
+	}
+	switch reflect.ValueOf(value).Kind() {
+	case reflect.Slice:
+		return formatArrayLiteral(reflect.ValueOf(value)), true
+	case reflect.Map:
+		data, err := json.Marshal(jsonCompatible(value))
+		if err != nil {
+			return fmt.Sprintf("%v", value), true
+		}
+		return string(data), true
+	}
+	return "", false
+}
+
+// formatArrayLiteral renders the elements of a list as {a,b,"c d",NULL}
+func formatArrayLiteral(list reflect.Value) string {
+	var out strings.Builder
+	out.WriteByte('{')
+	for i := 0; i < list.Len(); i++ {
+		if i > 0 {
+			out.WriteByte(',')
+		}
+		element := list.Index(i).Interface()
+		if element == nil {
+			out.WriteString("NULL")
+			continue
+		}
+		text := formatCopyValue(element)
+		if reflect.ValueOf(element).Kind() == reflect.Slice && !isBytes(element) {
+			// Nested arrays are written bare, as PostgreSQL writes multidimensional arrays
+			out.WriteString(text)
+			continue
+		}
+		if text == "" || strings.EqualFold(text, "NULL") || strings.ContainsAny(text, "{}\",\\ \t\r\n") {
+			text = `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(text) + `"`
+		}
+		out.WriteString(text)
+	}
+	out.WriteByte('}')
+	return out.String()
+}
+
+// isBytes reports whether value is a byte string
+func isBytes(value interface{}) bool {
+	_, ok := value.([]byte)
+	return ok
+}
+
+// jsonCompatible converts maps of any key type, as DuckDB returns them, to maps keyed by
+// the text of each key so they can be rendered as JSON objects
+func jsonCompatible(value interface{}) interface{} {
+	switch v := reflect.ValueOf(value); v.Kind() {
+	case reflect.Map:
+		converted := make(map[string]interface{}, v.Len())
+		iter := v.MapRange()
+		for iter.Next() {
+			converted[fmt.Sprint(iter.Key().Interface())] = jsonCompatible(iter.Value().Interface())
+		}
+		return converted
+	case reflect.Slice:
+		if isBytes(value) {
+			return value
+		}
+		converted := make([]interface{}, v.Len())
+		for i := range converted {
+			converted[i] = jsonCompatible(v.Index(i).Interface())
+		}
+		return converted
+	}
+	return value
+}
+
+// encodeCopyBinaryRow renders a row as a binary COPY tuple, typing each field by its Go type
+func encodeCopyBinaryRow(row []interface{}) []byte {
+	data := binary.BigEndian.AppendUint16(nil, uint16(len(row)))
+	for _, value := range row {
+		var field []byte
+		switch v := value.(type) {
+		case nil:
+			data = binary.BigEndian.AppendUint32(data, 0xFFFFFFFF)
+			continue
+		case bool:
+			field = []byte{0}
+			if v {
+				field[0] = 1
+			}
+		case int8:
+			field = binary.BigEndian.AppendUint16(nil, uint16(v))
+		case int16:
+			field = binary.BigEndian.AppendUint16(nil, uint16(v))
+		case int32:
+			field = binary.BigEndian.AppendUint32(nil, uint32(v))
+		case int:
+			field = binary.BigEndian.AppendUint64(nil, uint64(v))
+		case int64:
+			field = binary.BigEndian.AppendUint64(nil, uint64(v))
+		case float32:
+			field = binary.BigEndian.AppendUint32(nil, math.Float32bits(v))
+		case float64:
+			field = binary.BigEndian.AppendUint64(nil, math.Float64bits(v))
+		case []byte:
+			field = v
+		case time.Time:
+			field = binary.BigEndian.AppendUint64(nil, uint64(v.Sub(postgresEpoch).Microseconds()))
+		default:
+			field = []byte(formatCopyValue(v))
+		}
+		data = binary.BigEndian.AppendUint32(data, uint32(len(field)))
+		data = append(data, field...)
+	}
+	return data
+}
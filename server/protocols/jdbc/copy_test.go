@@ -0,0 +1,263 @@
+package jdbc
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/apache/iceberg-go"
+	"github.com/gear6io/ranger/pkg/errors"
+	"github.com/gear6io/ranger/server/storage/parquet"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIsCopyStatement(t *testing.T) {
+	assert.True(t, IsCopyStatement("COPY t FROM STDIN"))
+	assert.True(t, IsCopyStatement("  copy\tt TO STDOUT"))
+	assert.False(t, IsCopyStatement("COPYRIGHT"))
+	assert.False(t, IsCopyStatement("SELECT 'COPY t FROM STDIN'"))
+}
+
+func TestParseCopyStatement(t *testing.T) {
+	tests := []struct {
+		name     string
+		query    string
+		expected CopyStatement
+	}{
+		{
+			name:  "text from stdin",
+			query: "COPY events FROM STDIN",
+			expected: CopyStatement{
+				Database: "default", Table: "events", FromStdin: true,
+				Options: CopyOptions{Format: CopyFormatText, Delimiter: '\t', Null: `\N`},
+			},
+		},
+		{
+			name:  "qualified table with columns and csv options",
+			query: `COPY analytics."Events" (id, "Name") FROM STDIN WITH (FORMAT csv, HEADER, DELIMITER ',', NULL 'NA');`,
+			expected: CopyStatement{
+				Database: "analytics", Table: "Events", Columns: []string{"id", "Name"}, FromStdin: true,
+				Options: CopyOptions{Format: CopyFormatCSV, Delimiter: ',', Null: "NA", Header: true, Quote: '"', Escape: '"'},
+			},
+		},
+		{
+			name:  "legacy csv syntax",
+			query: "COPY events TO STDOUT WITH CSV HEADER DELIMITER AS E'\\t'",
+			expected: CopyStatement{
+				Database: "default", Table: "events",
+				Options: CopyOptions{Format: CopyFormatCSV, Delimiter: '\t', Header: true, Quote: '"', Escape: '"'},
+			},
+		},
+		{
+			name:  "query to stdout",
+			query: "COPY (SELECT id FROM events WHERE id > 1) TO STDOUT (FORMAT binary)",
+			expected: CopyStatement{
+				Database: "default", Query: "SELECT id FROM events WHERE id > 1",
+				Options: CopyOptions{Format: CopyFormatBinary},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stmt, err := ParseCopyStatement(tt.query, "default")
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, *stmt)
+		})
+	}
+}
+
+func TestParseCopyStatementErrors(t *testing.T) {
+	for _, query := range []string{
+		"COPY events FROM '/tmp/events.csv'",
+		"COPY events FROM STDIN (FORMAT xml)",
+		"COPY events FROM STDIN (DELIMITER '||')",
+		"COPY events FROM STDIN (FORMAT csv",
+		"COPY events FROM STDIN (FREEZE true)",
+	} {
+		_, err := ParseCopyStatement(query, "default")
+		assert.Error(t, err, query)
+	}
+}
+
+func readAllRows(t *testing.T, decoder copyRowDecoder) [][][]byte {
+	t.Helper()
+	var rows [][][]byte
+	for {
+		row, err := decoder.next()
+		if err == io.EOF {
+			return rows
+		}
+		require.NoError(t, err)
+		rows = append(rows, row)
+	}
+}
+
+func TestTextCopyDecoder(t *testing.T) {
+	options := CopyOptions{Format: CopyFormatText, Delimiter: '\t', Null: `\N`, Header: true}
+	input := "id\tname\n1\ta\\tb\n2\t\\N\n3\tc\\\\d\n\\.\n4\tignored\n"
+
+	rows := readAllRows(t, newCopyRowDecoder(strings.NewReader(input), options))
+	require.Len(t, rows, 3)
+	assert.Equal(t, [][]byte{[]byte("1"), []byte("a\tb")}, rows[0])
+	assert.Equal(t, [][]byte{[]byte("2"), nil}, rows[1])
+	assert.Equal(t, [][]byte{[]byte("3"), []byte(`c\d`)}, rows[2])
+}
+
+func TestCSVCopyDecoder(t *testing.T) {
+	options := CopyOptions{Format: CopyFormatCSV, Delimiter: ',', Quote: '"', Escape: '"'}
+	input := "1,\"hello, \"\"world\"\"\"\r\n2,\n3,\"\"\n4,\"multi\nline\"\n"
+
+	rows := readAllRows(t, newCopyRowDecoder(strings.NewReader(input), options))
+	require.Len(t, rows, 4)
+	assert.Equal(t, []byte(`hello, "world"`), rows[0][1])
+	assert.Nil(t, rows[1][1])
+	assert.Equal(t, []byte{}, rows[2][1])
+	assert.Equal(t, []byte("multi\nline"), rows[3][1])
+}
+
+func TestBinaryCopyRoundTrip(t *testing.T) {
+	var input bytes.Buffer
+	input.Write(binaryCopySignature)
+	input.Write(make([]byte, 8))
+	input.Write(encodeCopyBinaryRow([]interface{}{int32(7), "seven", nil}))
+	input.Write([]byte{0xFF, 0xFF})
+
+	rows := readAllRows(t, newCopyRowDecoder(&input, CopyOptions{Format: CopyFormatBinary}))
+	require.Len(t, rows, 1)
+	require.Len(t, rows[0], 3)
+
+	id, err := decodeBinaryValue(rows[0][0], iceberg.PrimitiveTypes.Int64)
+	require.NoError(t, err)
+	assert.Equal(t, int64(7), id)
+
+	name, err := decodeBinaryValue(rows[0][1], iceberg.PrimitiveTypes.String)
+	require.NoError(t, err)
+	assert.Equal(t, "seven", name)
+	assert.Nil(t, rows[0][2])
+}
+
+func TestBinaryCopyMalformed(t *testing.T) {
+	header := append(append([]byte{}, binaryCopySignature...), make([]byte, 8)...)
+	for name, tuple := range map[string][]byte{
+		"negative count":  {0xFF, 0xFE},
+		"negative length": {0x00, 0x01, 0xFF, 0xFF, 0xFF, 0xFB},
+		"huge length":     {0x00, 0x01, 0x7F, 0xFF, 0xFF, 0xFF, 'x'},
+		"truncated field": {0x00, 0x01, 0x00, 0x00, 0x00, 0x04, 'a', 'b'},
+	} {
+		decoder := newCopyRowDecoder(bytes.NewReader(append(header, tuple...)), CopyOptions{Format: CopyFormatBinary})
+		_, err := decoder.next()
+		require.Error(t, err, name)
+		assert.Equal(t, ErrCopyDataInvalid.String(), errors.GetCode(err), name)
+	}
+}
+
+func TestDecodeBinaryNumeric(t *testing.T) {
+	numeric := func(weight int16, sign uint16, dscale uint16, digits ...uint16) []byte {
+		data := binary.BigEndian.AppendUint16(nil, uint16(len(digits)))
+		data = binary.BigEndian.AppendUint16(data, uint16(weight))
+		data = binary.BigEndian.AppendUint16(data, sign)
+		data = binary.BigEndian.AppendUint16(data, dscale)
+		for _, digit := range digits {
+			data = binary.BigEndian.AppendUint16(data, digit)
+		}
+		return data
+	}
+
+	tests := []struct {
+		data     []byte
+		expected string
+	}{
+		{numeric(1, 0, 2, 1, 2345, 6700), "12345.67"},
+		{numeric(0, 0x4000, 0, 42), "-42"},
+		{numeric(-1, 0, 4, 50), "0.0050"},
+		{numeric(0, 0, 0), "0"},
+	}
+	for _, tt := range tests {
+		value, err := decodeBinaryNumeric(tt.data)
+		require.NoError(t, err)
+		assert.Equal(t, tt.expected, value)
+	}
+}
+
+func TestEncodeCopyTextRow(t *testing.T) {
+	text := CopyOptions{Format: CopyFormatText, Delimiter: '\t', Null: `\N`}
+	assert.Equal(t, "1\ta\\tb\t\\N\tt\n", string(encodeCopyTextRow([]interface{}{int64(1), "a\tb", nil, true}, text)))
+
+	csv := CopyOptions{Format: CopyFormatCSV, Delimiter: ',', Quote: '"', Escape: '"'}
+	assert.Equal(t, "1,\"say \"\"hi\"\"\",,\"\"\n", string(encodeCopyTextRow([]interface{}{int32(1), `say "hi"`, nil, ""}, csv)))
+}
+
+func TestCopyNestedValuesRoundTrip(t *testing.T) {
+	tags := &iceberg.ListType{ElementID: 3, Element: iceberg.PrimitiveTypes.String}
+	point := &iceberg.StructType{FieldList: []iceberg.NestedField{
+		{ID: 4, Name: "x", Type: iceberg.PrimitiveTypes.Int32},
+		{ID: 5, Name: "label", Type: iceberg.PrimitiveTypes.String},
+	}}
+	row := []interface{}{
+		[]interface{}{"a", "b, c", `d"e`, "", "NULL", nil},
+		map[string]interface{}{"x": int32(3), "label": "p\tq"},
+	}
+
+	// Lists are written as array literals and structs as JSON, escaped for the COPY format
+	text := CopyOptions{Format: CopyFormatText, Delimiter: '\t', Null: `\N`}
+	encoded := encodeCopyTextRow(row, text)
+	assert.Equal(t, `{a,"b, c","d\\"e","","NULL",NULL}`+"\t"+`{"label":"p\\tq","x":3}`+"\n", string(encoded))
+
+	rows := readAllRows(t, newCopyRowDecoder(bytes.NewReader(encoded), text))
+	require.Len(t, rows, 1)
+	for i, icebergType := range []iceberg.Type{tags, point} {
+		value, err := parquet.ParseTextValue(string(rows[0][i]), icebergType)
+		require.NoError(t, err)
+		assert.Equal(t, row[i], value)
+	}
+
+	// Nested arrays are written bare and maps of any key type as JSON objects
+	value, ok := formatNestedValue([]interface{}{[]interface{}{int64(1), int64(2)}, []interface{}{}})
+	assert.True(t, ok)
+	assert.Equal(t, "{{1,2},{}}", value)
+	value, ok = formatNestedValue(map[interface{}]interface{}{int32(1): []interface{}{true}})
+	assert.True(t, ok)
+	assert.Equal(t, `{"1":[true]}`, value)
+	_, ok = formatNestedValue([]byte("raw"))
+	assert.False(t, ok)
+}
+
+func TestCopyOutSink(t *testing.T) {
+	var out bytes.Buffer
+	sink := &copyOutSink{conn: &out, options: CopyOptions{Format: CopyFormatCSV, Delimiter: ',', Quote: '"', Escape: '"', Header: true}}
+	require.NoError(t, sink.Columns([]string{"id", "name"}, []string{"BIGINT", "VARCHAR"}))
+	require.NoError(t, sink.Row([]interface{}{int64(1), "a"}))
+	require.NoError(t, sink.Row([]interface{}{int64(2), nil}))
+	assert.Equal(t, 2, sink.rows)
+
+	// The copy starts once the columns are known, then each row is a message of its own
+	var types []byte
+	var data []string
+	for out.Len() > 0 {
+		msg, err := ReadMessage(&out)
+		require.NoError(t, err)
+		types = append(types, msg.Type)
+		if msg.Type == ResponseTypeCopyData {
+			data = append(data, string(msg.Data))
+		}
+	}
+	assert.Equal(t, []byte{ResponseTypeCopyOutResponse, ResponseTypeCopyData, ResponseTypeCopyData, ResponseTypeCopyData}, types)
+	assert.Equal(t, []string{"id,name\n", "1,a\n", "2,\n"}, data)
+
+	// A client that went away ends the copy
+	sink = &copyOutSink{conn: failingWriter{}, options: CopyOptions{Format: CopyFormatText}}
+	assert.Error(t, sink.Columns([]string{"id"}, []string{"BIGINT"}))
+	assert.Error(t, sink.writeErr)
+}
+
+// failingWriter fails every write
+type failingWriter struct{}
+
+// Write fails
+func (failingWriter) Write([]byte) (int, error) {
+	return 0, io.ErrClosedPipe
+}
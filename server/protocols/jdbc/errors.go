@@ -24,4 +24,22 @@ var (
 	ErrQueryParseFailed               = errors.MustNewCode("jdbc.query_parse_failed")
 	ErrStatementTypeNotAllowed        = errors.MustNewCode("jdbc.statement_type_not_allowed")
 	ErrQueryValidationFailed          = errors.MustNewCode("jdbc.query_validation_failed")
+
+	// Authentication error codes
+	ErrAuthenticationFailed            = errors.MustNewCode("jdbc.authentication_failed")
+	ErrRoleNotFound                    = errors.MustNewCode("jdbc.role_not_found")
+	ErrLoginNotPermitted               = errors.MustNewCode("jdbc.login_not_permitted")
+	ErrAuthenticationProtocolViolation = errors.MustNewCode("jdbc.authentication_protocol_violation")
+	ErrUnsupportedAuthMethod           = errors.MustNewCode("jdbc.unsupported_auth_method")
+	ErrUserLookupFailed                = errors.MustNewCode("jdbc.user_lookup_failed")
+
+	// Encryption error codes
+	ErrTLSHandshakeFailed = errors.MustNewCode("jdbc.tls_handshake_failed")
+	ErrSSLRequired        = errors.MustNewCode("jdbc.ssl_required")
+
+	// Copy sub-protocol error codes
+	ErrCopyStatementInvalid  = errors.MustNewCode("jdbc.copy_statement_invalid")
+	ErrCopyDataInvalid       = errors.MustNewCode("jdbc.copy_data_invalid")
+	ErrCopyAborted           = errors.MustNewCode("jdbc.copy_aborted")
+	ErrCopyProtocolViolation = errors.MustNewCode("jdbc.copy_protocol_violation")
 )
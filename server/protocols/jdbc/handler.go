@@ -2,42 +2,63 @@ package jdbc
 
 import (
 	"context"
+	"crypto/tls"
 	"fmt"
 	"io"
+	"net"
 	"strings"
 	"time"
 
 	"github.com/gear6io/ranger/pkg/errors"
+	"github.com/gear6io/ranger/server/config"
 	"github.com/gear6io/ranger/server/query"
+	"github.com/gear6io/ranger/server/query/access"
 	"github.com/gear6io/ranger/server/types"
 	"github.com/rs/zerolog"
 )
 
 // JDBCHandler handles JDBC protocol communication and SQL execution
 type JDBCHandler struct {
-	queryEngine *query.Engine
-	logger      zerolog.Logger
-	ctx         context.Context
+	queryEngine   *query.Engine
+	authenticator *Authenticator
+	tlsManager    *config.TLSManager
+	logger        zerolog.Logger
+	ctx           context.Context
+
+	// user is the authenticated session user
+	user string
+	// clientAddr is the remote address of the connection
+	clientAddr string
 }
 
-// NewJDBCHandler creates a new JDBC handler
-func NewJDBCHandler(queryEngine *query.Engine, logger zerolog.Logger, ctx context.Context) *JDBCHandler {
+// NewJDBCHandler creates a new JDBC handler; tlsManager may be nil to decline SSLRequest
+func NewJDBCHandler(queryEngine *query.Engine, authenticator *Authenticator, tlsManager *config.TLSManager, logger zerolog.Logger, ctx context.Context) *JDBCHandler {
 	return &JDBCHandler{
-		queryEngine: queryEngine,
-		logger:      logger,
-		ctx:         ctx,
+		queryEngine:   queryEngine,
+		authenticator: authenticator,
+		tlsManager:    tlsManager,
+		logger:        logger,
+		ctx:           ctx,
 	}
 }
 
 // HandleConnection handles a JDBC connection
-func (h *JDBCHandler) HandleConnection(conn io.ReadWriteCloser) error {
-	defer conn.Close()
+func (h *JDBCHandler) HandleConnection(conn net.Conn) error {
+	// Closes the TLS layer too once conn is replaced by the upgraded connection
+	defer func() { conn.Close() }()
+	h.clientAddr = conn.RemoteAddr().String()
 
-	// Handle startup
-	if err := h.handleStartup(conn); err != nil {
+	// Handle startup; the session continues on the upgraded connection after an SSLRequest
+	session, err := h.handleStartup(conn)
+	if err != nil {
 		h.logger.Error().Err(err).Msg("Startup failed")
 		return err
 	}
+	if session == nil {
+		// Cancel request, nothing more to do on this connection
+		return nil
+	}
+	conn = session
 
 	// Main message loop
 	for {
@@ -59,26 +80,78 @@ func (h *JDBCHandler) HandleConnection(conn io.ReadWriteCloser) error {
 	}
 }
 
-// handleStartup handles the initial connection startup
-func (h *JDBCHandler) handleStartup(conn io.ReadWriteCloser) error {
-	// Read startup message
-	params, err := ParseStartupMessage(conn)
-	if err != nil {
-		return errors.New(ErrStartupMessageParseFailed, "failed to parse startup message", err)
+// handleStartup negotiates encryption, authenticates the client and starts the session.
+// It returns the connection to use from then on, which is a TLS connection after a
+// successful SSLRequest, or nil for a cancel request.
+func (h *JDBCHandler) handleStartup(conn net.Conn) (net.Conn, error) {
+	secure := false
+	var params map[string]string
+	for params == nil {
+		code, startupParams, err := ParseStartupMessage(conn)
+		if err != nil {
+			return nil, errors.New(ErrStartupMessageParseFailed, "failed to parse startup message", err)
+		}
+
+		switch code {
+		case MessageTypeSSLRequest:
+			if secure || h.tlsManager == nil {
+				if _, err := conn.Write([]byte{'N'}); err != nil {
+					return nil, errors.New(ErrStartupResponseWriteFailed, "failed to decline SSL request", err)
+				}
+				continue
+			}
+			if _, err := conn.Write([]byte{'S'}); err != nil {
+				return nil, errors.New(ErrStartupResponseWriteFailed, "failed to accept SSL request", err)
+			}
+			tlsConn := tls.Server(conn, h.tlsManager.ServerConfig())
+			if err := tlsConn.HandshakeContext(h.ctx); err != nil {
+				return nil, errors.New(ErrTLSHandshakeFailed, "TLS handshake failed", err)
+			}
+			conn, secure = tlsConn, true
+		case MessageTypeGSSENCRequest:
+			if _, err := conn.Write([]byte{'N'}); err != nil {
+				return nil, errors.New(ErrStartupResponseWriteFailed, "failed to decline GSSAPI encryption request", err)
+			}
+		case MessageTypeCancel:
+			h.logger.Debug().Msg("Ignoring cancel request")
+			return nil, nil
+		default:
+			params = startupParams
+		}
+	}
+
+	h.logger.Debug().Interface("params", params).Bool("tls", secure).Msg("Startup parameters")
+
+	if h.tlsManager != nil && h.tlsManager.Config().RequireJDBC && !secure {
+		WriteFatalResponse(conn, SQLStateInvalidAuthorization, "SSL connection is required")
+		return nil, errors.New(ErrSSLRequired, "client did not request SSL", nil)
 	}
 
-	h.logger.Debug().Interface("params", params).Msg("Startup parameters")
+	// Authenticate before the session starts; failures end the connection
+	user := params["user"]
+	if _, err := h.authenticator.Authenticate(h.ctx, conn, user); err != nil {
+		h.logger.Warn().Err(err).Str("user", user).Msg("Authentication failed")
+		h.auditLogin(user, err)
+		message := err.Error()
+		if errors.GetCode(err) == ErrUserLookupFailed.String() {
+			message = "authentication failed due to an internal error"
+		}
+		WriteFatalResponse(conn, authenticationSQLState(err), message)
+		return nil, err
+	}
+	h.user = user
+	h.auditLogin(user, nil)
 
 	// Send startup response
-	if err := WriteStartupResponse(conn); err != nil {
-		return errors.New(ErrStartupResponseWriteFailed, "failed to write startup response", err)
+	if err := WriteStartupResponse(conn, user); err != nil {
+		return nil, errors.New(ErrStartupResponseWriteFailed, "failed to write startup response", err)
 	}
 
-	return nil
+	return conn, nil
 }
 
 // handleMessage handles individual PostgreSQL messages
-func (h *JDBCHandler) handleMessage(conn io.WriteCloser, msg *Message) error {
+func (h *JDBCHandler) handleMessage(conn io.ReadWriteCloser, msg *Message) error {
 	switch msg.Type {
 	case MessageTypeQuery:
 		return h.handleQuery(conn, msg)
@@ -103,21 +176,22 @@ func (h *JDBCHandler) handleMessage(conn io.WriteCloser, msg *Message) error {
 }
 
 // handleQuery handles a simple query
-func (h *JDBCHandler) handleQuery(conn io.WriteCloser, msg *Message) error {
-	queryStr := strings.TrimSpace(string(msg.Data))
+func (h *JDBCHandler) handleQuery(conn io.ReadWriteCloser, msg *Message) error {
+	queryStr := strings.TrimSpace(strings.TrimRight(string(msg.Data), "\x00"))
+
+	// COPY is handled by the copy sub-protocol rather than the query engine
+	if IsCopyStatement(queryStr) {
+		return h.handleCopy(conn, queryStr)
+	}
+
 	h.logger.Debug().Str("query", queryStr).Msg("Executing query using QueryEngine")
 
 	// Create query context for JDBC requests
-	queryCtx := &types.QueryContext{
-		Query:      queryStr,
-		Database:   "default",
-		User:       "jdbc",
-		ClientAddr: "jdbc",
-	}
+	queryCtx := h.queryContext(queryStr, "default")
 	result, err := h.queryEngine.ExecuteQuery(h.ctx, queryCtx)
 	if err != nil {
 		h.logger.Error().Err(err).Str("query", queryStr).Msg("Query execution failed")
-		return WriteErrorResponse(conn, "XX000", fmt.Sprintf("Query execution failed: %v", err))
+		return WriteErrorResponse(conn, querySQLState(err), fmt.Sprintf("Query execution failed: %v", err))
 	}
 
 	// Convert QueryEngine result to JDBC format
@@ -159,6 +233,54 @@ func (h *JDBCHandler) handleQuery(conn io.WriteCloser, msg *Message) error {
 	return WriteReadyForQuery(conn, 'I')
 }
 
+// handleCopy dispatches a COPY statement to the copy-in or copy-out flow
+func (h *JDBCHandler) handleCopy(conn io.ReadWriter, queryStr string) error {
+	stmt, err := ParseCopyStatement(queryStr, "default")
+	if err != nil {
+		return h.writeQueryError(conn, SQLStateSyntaxError, err.Error())
+	}
+
+	if stmt.FromStdin {
+		return h.handleCopyIn(conn, stmt)
+	}
+	return h.handleCopyOut(conn, stmt)
+}
+
+// querySQLState maps a query engine error to the SQLSTATE reported to the client
+func querySQLState(err error) string {
+	switch errors.GetCode(err) {
+	case access.ErrPermissionDenied.String(), access.ErrSuperuserRequired.String():
+		return SQLStateInsufficientPrivilege
+	}
+	return SQLStateInternalError
+}
+
+// queryContext returns the context of a query run by the session user
+func (h *JDBCHandler) queryContext(query, database string) *types.QueryContext {
+	return &types.QueryContext{
+		Query:      query,
+		Database:   database,
+		User:       h.user,
+		ClientAddr: h.clientAddr,
+		Protocol:   types.ProtocolJDBC,
+	}
+}
+
+// auditLogin records an authentication attempt; handlers built without an engine record nothing
+func (h *JDBCHandler) auditLogin(user string, err error) {
+	if h.queryEngine != nil {
+		h.queryEngine.AuditLogin(h.ctx, user, types.ProtocolJDBC, h.clientAddr, err)
+	}
+}
+
+// writeQueryError reports a failed query and returns the connection to the idle state
+func (h *JDBCHandler) writeQueryError(conn io.Writer, sqlState, message string) error {
+	if err := WriteErrorResponse(conn, sqlState, message); err != nil {
+		return err
+	}
+	return WriteReadyForQuery(conn, 'I')
+}
+
 // handleParse handles a parse message (prepared statement)
 func (h *JDBCHandler) handleParse(conn io.WriteCloser, msg *Message) error {
 	// TODO: Implement prepared statement parsing
@@ -208,12 +330,7 @@ func (h *JDBCHandler) handleTerminate(conn io.WriteCloser, msg *Message) error {
 // ExecuteQuery executes a SQL query (for testing)
 func (h *JDBCHandler) ExecuteQuery(ctx context.Context, query string) (*QueryResult, error) {
 	// Create query context for testing
-	queryCtx := &types.QueryContext{
-		Query:      query,
-		Database:   "default",
-		User:       "jdbc",
-		ClientAddr: "jdbc",
-	}
+	queryCtx := h.queryContext(query, "default")
 	result, err := h.queryEngine.ExecuteQuery(ctx, queryCtx)
 	if err != nil {
 		return nil, err
@@ -0,0 +1,160 @@
+package jdbc
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/binary"
+	"encoding/pem"
+	"io"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/gear6io/ranger/server/config"
+	"github.com/gear6io/ranger/server/protocols/native/middleware"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newTestTLSManager writes a self-signed localhost certificate and loads it into a TLS manager
+func newTestTLSManager(t *testing.T, requireJDBC bool) *config.TLSManager {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "localhost"},
+		DNSNames:     []string{"localhost"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.NoError(t, err)
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	require.NoError(t, err)
+
+	dir := t.TempDir()
+	certFile := filepath.Join(dir, "server.crt")
+	keyFile := filepath.Join(dir, "server.key")
+	require.NoError(t, os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0600))
+	require.NoError(t, os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0600))
+
+	manager, err := config.NewTLSManager(config.TLSConfig{
+		Enabled:     true,
+		CertFile:    certFile,
+		KeyFile:     keyFile,
+		RequireJDBC: requireJDBC,
+	}, zerolog.Nop())
+	require.NoError(t, err)
+	return manager
+}
+
+// startupPacket builds a startup-phase packet carrying code followed by params
+func startupPacket(code uint32, params ...string) []byte {
+	body := binary.BigEndian.AppendUint32(nil, code)
+	if len(params) > 0 {
+		for _, param := range params {
+			body = append(body, param...)
+			body = append(body, 0)
+		}
+		body = append(body, 0)
+	}
+	return append(binary.BigEndian.AppendUint32(nil, uint32(len(body)+4)), body...)
+}
+
+// runStartup runs handleStartup against a scripted client and returns the server-side error
+func runStartup(t *testing.T, tlsManager *config.TLSManager, client func(conn net.Conn)) error {
+	t.Helper()
+
+	authenticator, err := NewAuthenticator(AuthMethodSCRAMSHA256, middleware.NewSimpleAuthProvider(time.Hour, zerolog.Nop()), nil)
+	require.NoError(t, err)
+	handler := NewJDBCHandler(nil, authenticator, tlsManager, zerolog.Nop(), context.Background())
+
+	serverConn, clientConn := net.Pipe()
+	defer clientConn.Close()
+
+	result := make(chan error, 1)
+	go func() {
+		defer serverConn.Close()
+		_, err := handler.handleStartup(serverConn)
+		result <- err
+	}()
+
+	client(clientConn)
+	return <-result
+}
+
+// readStartupResponse reads messages until ReadyForQuery and returns the message types seen
+func readStartupResponse(t *testing.T, conn io.Reader) []byte {
+	t.Helper()
+
+	var types []byte
+	for {
+		msg, err := ReadMessage(conn)
+		require.NoError(t, err)
+		types = append(types, msg.Type)
+		if msg.Type == ResponseTypeReadyForQuery {
+			return types
+		}
+	}
+}
+
+func TestStartupSSLRequest(t *testing.T) {
+	sslRequest := startupPacket(MessageTypeSSLRequest)
+	startup := startupPacket(196608, "user", "default", "database", "default")
+
+	// Without TLS the request is declined and the client carries on in plaintext
+	err := runStartup(t, nil, func(conn net.Conn) {
+		_, err := conn.Write(sslRequest)
+		require.NoError(t, err)
+		reply := make([]byte, 1)
+		_, err = io.ReadFull(conn, reply)
+		require.NoError(t, err)
+		assert.Equal(t, byte('N'), reply[0])
+
+		_, err = conn.Write(startup)
+		require.NoError(t, err)
+		assert.Contains(t, readStartupResponse(t, conn), byte(ResponseTypeReadyForQuery))
+	})
+	require.NoError(t, err)
+
+	// With TLS the connection is upgraded before the startup message
+	err = runStartup(t, newTestTLSManager(t, true), func(conn net.Conn) {
+		_, err := conn.Write(sslRequest)
+		require.NoError(t, err)
+		reply := make([]byte, 1)
+		_, err = io.ReadFull(conn, reply)
+		require.NoError(t, err)
+		require.Equal(t, byte('S'), reply[0])
+
+		tlsConn := tls.Client(conn, &tls.Config{InsecureSkipVerify: true})
+		require.NoError(t, tlsConn.Handshake())
+		_, err = tlsConn.Write(startup)
+		require.NoError(t, err)
+		assert.Contains(t, readStartupResponse(t, tlsConn), byte(ResponseTypeReadyForQuery))
+	})
+	require.NoError(t, err)
+}
+
+func TestStartupSSLRequired(t *testing.T) {
+	err := runStartup(t, newTestTLSManager(t, true), func(conn net.Conn) {
+		_, err := conn.Write(startupPacket(196608, "user", "default"))
+		require.NoError(t, err)
+		msg, err := ReadMessage(conn)
+		require.NoError(t, err)
+		assert.Equal(t, byte(ResponseTypeErrorResponse), msg.Type)
+		assert.Contains(t, string(msg.Data), "SSL connection is required")
+	})
+	assert.Error(t, err)
+}
@@ -19,6 +19,10 @@ const (
 	MessageTypeSync          = 'S'
 	MessageTypeTerminate     = 'X'
 	MessageTypePassword      = 'p'
+	MessageTypeFlush         = 'H'
+	MessageTypeCopyData      = 'd'
+	MessageTypeCopyDone      = 'c'
+	MessageTypeCopyFail      = 'f'
 	MessageTypeStartup       = 0
 	MessageTypeSSLRequest    = 80877103
 	MessageTypeCancel        = 80877102
@@ -41,8 +45,25 @@ const (
 	ResponseTypeCloseComplete    = '3'
 	ResponseTypeNoData           = 'n'
 	ResponseTypePortalSuspended  = 's'
+	ResponseTypeCopyInResponse   = 'G'
+	ResponseTypeCopyOutResponse  = 'H'
+	ResponseTypeCopyData         = 'd'
+	ResponseTypeCopyDone         = 'c'
 )
 
+// PostgreSQL authentication request codes
+const (
+	AuthenticationOK                = 0
+	AuthenticationCleartextPassword = 3
+	AuthenticationMD5Password       = 5
+	AuthenticationSASL              = 10
+	AuthenticationSASLContinue      = 11
+	AuthenticationSASLFinal         = 12
+)
+
+// maxStartupMessageLength bounds the startup packet, as PostgreSQL does
+const maxStartupMessageLength = 10000
+
 // Message represents a PostgreSQL wire protocol message
 type Message struct {
 	Type   byte
@@ -85,8 +106,8 @@ func ReadMessage(reader io.Reader) (*Message, error) {
 
 // WriteMessage writes a PostgreSQL message to the connection
 func WriteMessage(writer io.Writer, msgType byte, data []byte) error {
-	// Calculate total length (1 byte type + 4 bytes length + data length)
-	totalLength := 5 + len(data)
+	// The length field counts itself and the data, but not the type byte
+	totalLength := 4 + len(data)
 
 	// Write message type
 	if _, err := writer.Write([]byte{msgType}); err != nil {
@@ -112,8 +133,18 @@ func WriteMessage(writer io.Writer, msgType byte, data []byte) error {
 
 // WriteErrorResponse writes an error response
 func WriteErrorResponse(writer io.Writer, code, message string) error {
+	return writeErrorResponse(writer, "ERROR", code, message)
+}
+
+// WriteFatalResponse writes an error response that terminates the session
+func WriteFatalResponse(writer io.Writer, code, message string) error {
+	return writeErrorResponse(writer, "FATAL", code, message)
+}
+
+// writeErrorResponse writes an error response with the given severity
+func writeErrorResponse(writer io.Writer, severity, code, message string) error {
 	// Format: field type + field value pairs, terminated by null byte
-	data := fmt.Sprintf("S%s\x00C%s\x00M%s\x00\x00", code, code, message)
+	data := fmt.Sprintf("S%s\x00V%s\x00C%s\x00M%s\x00\x00", severity, severity, code, message)
 	return WriteMessage(writer, ResponseTypeErrorResponse, []byte(data))
 }
 
@@ -142,6 +173,42 @@ func WriteAuthenticationOK(writer io.Writer) error {
 	return WriteMessage(writer, ResponseTypeAuthenticationOK, data)
 }
 
+// WriteAuthenticationCleartextPassword requests a cleartext password from the client
+func WriteAuthenticationCleartextPassword(writer io.Writer) error {
+	return writeAuthenticationRequest(writer, AuthenticationCleartextPassword, nil)
+}
+
+// WriteAuthenticationMD5Password requests an MD5-hashed password using the given salt
+func WriteAuthenticationMD5Password(writer io.Writer, salt [4]byte) error {
+	return writeAuthenticationRequest(writer, AuthenticationMD5Password, salt[:])
+}
+
+// WriteAuthenticationSASL offers the given SASL mechanisms to the client
+func WriteAuthenticationSASL(writer io.Writer, mechanisms ...string) error {
+	var data []byte
+	for _, mechanism := range mechanisms {
+		data = append(data, mechanism...)
+		data = append(data, 0)
+	}
+	return writeAuthenticationRequest(writer, AuthenticationSASL, append(data, 0))
+}
+
+// WriteAuthenticationSASLContinue sends a SASL challenge
+func WriteAuthenticationSASLContinue(writer io.Writer, data []byte) error {
+	return writeAuthenticationRequest(writer, AuthenticationSASLContinue, data)
+}
+
+// WriteAuthenticationSASLFinal sends the SASL outcome data
+func WriteAuthenticationSASLFinal(writer io.Writer, data []byte) error {
+	return writeAuthenticationRequest(writer, AuthenticationSASLFinal, data)
+}
+
+// writeAuthenticationRequest writes an authentication message with the given request code
+func writeAuthenticationRequest(writer io.Writer, code int32, payload []byte) error {
+	data := binary.BigEndian.AppendUint32(nil, uint32(code))
+	return WriteMessage(writer, ResponseTypeAuthenticationOK, append(data, payload...))
+}
+
 // WriteParameterStatus writes a parameter status response
 func WriteParameterStatus(writer io.Writer, name, value string) error {
 	data := name + "\x00" + value + "\x00"
@@ -214,8 +281,11 @@ func WriteDataRow(writer io.Writer, values []interface{}) error {
 			binary.BigEndian.PutUint32(nullLength, 0xFFFFFFFF)
 			data = append(data, nullLength...)
 		} else {
-			// Convert value to string
-			valueStr := fmt.Sprintf("%v", value)
+			// Convert value to string; lists are sent as arrays, maps and structs as JSON
+			valueStr, ok := formatNestedValue(value)
+			if !ok {
+				valueStr = fmt.Sprintf("%v", value)
+			}
 			valueBytes := []byte(valueStr)
 
 			// Value length (4 bytes)
@@ -231,6 +301,38 @@ func WriteDataRow(writer io.Writer, values []interface{}) error {
 	return WriteMessage(writer, ResponseTypeDataRow, data)
 }
 
+// WriteCopyInResponse tells the client to start sending CopyData messages
+func WriteCopyInResponse(writer io.Writer, format int8, columnCount int) error {
+	return WriteMessage(writer, ResponseTypeCopyInResponse, copyResponseData(format, columnCount))
+}
+
+// WriteCopyOutResponse tells the client that CopyData messages will follow
+func WriteCopyOutResponse(writer io.Writer, format int8, columnCount int) error {
+	return WriteMessage(writer, ResponseTypeCopyOutResponse, copyResponseData(format, columnCount))
+}
+
+// WriteCopyData writes a single CopyData message
+func WriteCopyData(writer io.Writer, data []byte) error {
+	return WriteMessage(writer, ResponseTypeCopyData, data)
+}
+
+// WriteCopyDone writes a CopyDone message
+func WriteCopyDone(writer io.Writer) error {
+	return WriteMessage(writer, ResponseTypeCopyDone, nil)
+}
+
+// copyResponseData builds the body shared by CopyInResponse and CopyOutResponse:
+// overall format (1 byte), column count (2 bytes) and a format code per column
+func copyResponseData(format int8, columnCount int) []byte {
+	data := make([]byte, 3+2*columnCount)
+	data[0] = byte(format)
+	binary.BigEndian.PutUint16(data[1:3], uint16(columnCount))
+	for i := 0; i < columnCount; i++ {
+		binary.BigEndian.PutUint16(data[3+2*i:], uint16(format))
+	}
+	return data
+}
+
 // ColumnDescription represents a column in a row description
 type ColumnDescription struct {
 	Name     string
@@ -238,25 +340,33 @@ type ColumnDescription struct {
 	TypeSize int16
 }
 
-// ParseStartupMessage parses a startup message
-func ParseStartupMessage(reader io.Reader) (map[string]string, error) {
+// ParseStartupMessage parses an untyped startup-phase packet. It returns the request
+// code (the protocol version, or MessageTypeSSLRequest, MessageTypeCancel or
+// MessageTypeGSSENCRequest) and, for startup messages, the connection parameters.
+func ParseStartupMessage(reader io.Reader) (uint32, map[string]string, error) {
 	// Read message length (4 bytes)
 	lengthBuf := make([]byte, 4)
 	if _, err := io.ReadFull(reader, lengthBuf); err != nil {
-		return nil, errors.New(ErrMessageLengthReadFailed, "failed to read startup message length", err)
+		return 0, nil, errors.New(ErrMessageLengthReadFailed, "failed to read startup message length", err)
 	}
 
 	length := binary.BigEndian.Uint32(lengthBuf)
+	if length < 8 || length > maxStartupMessageLength {
+		return 0, nil, errors.New(ErrMessageDataReadFailed, "invalid startup message length", nil).AddContext("length", length)
+	}
 
 	// Read message data
 	data := make([]byte, length-4)
 	if _, err := io.ReadFull(reader, data); err != nil {
-		return nil, errors.New(ErrMessageDataReadFailed, "failed to read startup message data", err)
+		return 0, nil, errors.New(ErrMessageDataReadFailed, "failed to read startup message data", err)
 	}
 
+	// The request code (4 bytes) precedes the key-value pairs
+	code := binary.BigEndian.Uint32(data[:4])
+
 	// Parse key-value pairs
 	params := make(map[string]string)
-	pos := 0
+	pos := 4
 
 	for pos < len(data) {
 		// Find end of key
@@ -286,11 +396,11 @@ func ParseStartupMessage(reader io.Reader) (map[string]string, error) {
 		params[key] = value
 	}
 
-	return params, nil
+	return code, params, nil
 }
 
-// WriteStartupResponse writes the initial startup response
-func WriteStartupResponse(writer io.Writer) error {
+// WriteStartupResponse writes the initial startup response for an authenticated user
+func WriteStartupResponse(writer io.Writer, user string) error {
 	// Send authentication OK
 	if err := WriteAuthenticationOK(writer); err != nil {
 		return err
@@ -305,7 +415,7 @@ func WriteStartupResponse(writer io.Writer) error {
 		"server_encoding":             "UTF8",
 		"integer_datetimes":           "on",
 		"is_superuser":                "on",
-		"session_authorization":       "ranger",
+		"session_authorization":       user,
 		"standard_conforming_strings": "on",
 	}
 
@@ -0,0 +1,44 @@
+package jdbc
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseStartupMessage(t *testing.T) {
+	body := binary.BigEndian.AppendUint32(nil, 196608) // protocol 3.0
+	body = append(body, "user\x00alice\x00database\x00ranger\x00\x00"...)
+	packet := append(binary.BigEndian.AppendUint32(nil, uint32(len(body)+4)), body...)
+
+	code, params, err := ParseStartupMessage(bytes.NewReader(packet))
+	require.NoError(t, err)
+	assert.Equal(t, uint32(196608), code)
+	assert.Equal(t, map[string]string{"user": "alice", "database": "ranger"}, params)
+
+	// SSLRequest carries no parameters
+	code, params, err = ParseStartupMessage(bytes.NewReader([]byte{0, 0, 0, 8, 0x04, 0xd2, 0x16, 0x2f}))
+	require.NoError(t, err)
+	assert.Equal(t, uint32(MessageTypeSSLRequest), code)
+	assert.Empty(t, params)
+
+	_, _, err = ParseStartupMessage(bytes.NewReader([]byte{0, 0, 0, 2}))
+	assert.Error(t, err)
+}
+
+func TestWriteMessageRoundTrip(t *testing.T) {
+	var buf bytes.Buffer
+	require.NoError(t, WriteMessage(&buf, MessageTypeQuery, []byte("SELECT 1\x00")))
+
+	// Length covers itself and the data, not the type byte
+	assert.Equal(t, uint32(4+9), binary.BigEndian.Uint32(buf.Bytes()[1:5]))
+
+	msg, err := ReadMessage(&buf)
+	require.NoError(t, err)
+	assert.Equal(t, byte(MessageTypeQuery), msg.Type)
+	assert.Equal(t, "SELECT 1\x00", string(msg.Data))
+	assert.Zero(t, buf.Len())
+}
@@ -8,6 +8,7 @@ import (
 
 	"github.com/gear6io/ranger/pkg/errors"
 	"github.com/gear6io/ranger/server/config"
+	"github.com/gear6io/ranger/server/protocols/native/middleware"
 	"github.com/gear6io/ranger/server/query"
 	"github.com/rs/zerolog"
 )
@@ -20,17 +21,31 @@ type Server struct {
 	cancel      context.CancelFunc
 	wg          sync.WaitGroup
 	queryEngine *query.Engine
+
+	// authenticator verifies clients against the shared AuthProvider user store
+	authenticator *Authenticator
+
+	// tlsManager accepts SSLRequest when set
+	tlsManager *config.TLSManager
 }
 
-// NewServer creates a new JDBC server
-func NewServer(queryEngine *query.Engine, logger zerolog.Logger) (*Server, error) {
+// NewServer creates a new JDBC server that authenticates clients against credentials,
+// the user store shared with the native server. tlsManager may be nil to decline SSL.
+func NewServer(queryEngine *query.Engine, credentials middleware.CredentialStore, tlsManager *config.TLSManager, logger zerolog.Logger) (*Server, error) {
+	authenticator, err := NewAuthenticator(config.JDBC_AUTH_METHOD, credentials, queryEngine)
+	if err != nil {
+		return nil, err
+	}
+
 	ctx, cancel := context.WithCancel(context.Background())
 
 	return &Server{
-		logger:      logger.With().Str("component", "jdbc-server").Logger(),
-		ctx:         ctx,
-		cancel:      cancel,
-		queryEngine: queryEngine,
+		logger:        logger.With().Str("component", "jdbc-server").Logger(),
+		ctx:           ctx,
+		cancel:        cancel,
+		queryEngine:   queryEngine,
+		authenticator: authenticator,
+		tlsManager:    tlsManager,
 	}, nil
 }
 
@@ -85,6 +100,7 @@ func (s *Server) GetStatus() map[string]interface{} {
 		"enabled": config.JDBC_SERVER_ENABLED,
 		"address": config.DEFAULT_SERVER_ADDRESS,
 		"port":    config.JDBC_SERVER_PORT,
+		"tls":     s.tlsManager != nil,
 	}
 }
 
@@ -125,7 +141,7 @@ func (s *Server) handleConnection(conn net.Conn) {
 	s.logger.Debug().Str("remote_addr", clientAddr).Msg("New JDBC connection")
 
 	// Create a new JDBC handler with the QueryEngine
-	handler := NewJDBCHandler(s.queryEngine, s.logger, s.ctx)
+	handler := NewJDBCHandler(s.queryEngine, s.authenticator, s.tlsManager, s.logger, s.ctx)
 
 	// Handle the connection using the QueryEngine
 	if err := handler.HandleConnection(conn); err != nil {
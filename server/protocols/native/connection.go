@@ -5,23 +5,29 @@ import (
 	"fmt"
 	"io"
 	"net"
+	"strings"
 	"sync"
 	"time"
 
+	"github.com/apache/arrow-go/v18/arrow/array"
 	"github.com/gear6io/ranger/pkg/errors"
 	"github.com/gear6io/ranger/server/protocols/native/middleware"
 	"github.com/gear6io/ranger/server/protocols/native/protocol"
 	"github.com/gear6io/ranger/server/protocols/native/protocol/signals"
 	"github.com/gear6io/ranger/server/query"
+	"github.com/gear6io/ranger/server/query/access"
+	"github.com/gear6io/ranger/server/query/parser"
+	"github.com/gear6io/ranger/server/tracing"
 	"github.com/gear6io/ranger/server/types"
 	"github.com/rs/zerolog"
+	"go.opentelemetry.io/otel/attribute"
 )
 
-// DataBlock represents a block of data for transfer
+// DataBlock represents a block of columnar data for transfer
 type DataBlock struct {
 	TableName string
-	Columns   []string
-	Rows      [][]interface{}
+	Records   array.RecordReader
+	Rows      int64
 }
 
 // Protocol constants for backward compatibility
@@ -45,6 +51,7 @@ type ConnectionHandler struct {
 	// Middleware system
 	connCtx    *middleware.ConnectionContext
 	middleware *middleware.Chain
+	auth       *middleware.AuthMiddleware
 
 	// Server context for graceful shutdown
 	serverCtx context.Context
@@ -56,7 +63,7 @@ type ConnectionHandler struct {
 }
 
 // NewConnectionHandler creates a new connection handler
-func NewConnectionHandler(conn net.Conn, queryEngine *query.Engine, logger zerolog.Logger, middlewareChain *middleware.Chain, serverCtx context.Context) *ConnectionHandler {
+func NewConnectionHandler(conn net.Conn, queryEngine *query.Engine, logger zerolog.Logger, middlewareChain *middleware.Chain, auth *middleware.AuthMiddleware, serverCtx context.Context) *ConnectionHandler {
 	// Create registry and factory
 	registry := protocol.NewRegistry()
 	factory := protocol.NewSignalFactory()
@@ -104,6 +111,7 @@ func NewConnectionHandler(conn net.Conn, queryEngine *query.Engine, logger zerol
 		tables:       make(map[string][][]interface{}),
 		connCtx:      connCtx,
 		middleware:   middlewareChain,
+		auth:         auth,
 		serverCtx:    serverCtx,
 		idleTimeout:  IDLE_TIMEOUT, // Will be overridden by ClientHello
 		readTimeout:  READ_TIMEOUT, // Will be overridden by ClientHello
@@ -253,27 +261,68 @@ func (h *ConnectionHandler) handleClientPing() error {
 }
 
 // processDataBlock processes the received data block
-func (h *ConnectionHandler) processDataBlock(block *DataBlock) error {
+func (h *ConnectionHandler) processDataBlock(block *DataBlock) (err error) {
+	ctx, span := tracing.StartServer(context.Background(), "native.insert",
+		attribute.String("db.collection.name", block.TableName),
+		attribute.Int64("ranger.rows", block.Rows),
+		attribute.String("client.address", h.connCtx.ClientAddr))
+	defer func() { tracing.End(span, err) }()
+
 	h.logger.Info().
 		Str("table", block.TableName).
-		Interface("columns", block.Columns).
-		Interface("rows", len(block.Rows)).
+		Int("columns", len(block.Records.Schema().Fields())).
+		Int64("rows", block.Rows).
 		Msg("Processing data block")
 
+	// Data blocks bypass ExecuteQuery, so INSERT is checked and audited here
+	start := time.Now()
+	database, table := h.blockTable(block)
+	requirement := access.Requirement{Action: parser.PRIV_INSERT, Database: database, Table: table}
+	if err := h.queryEngine.Authorize(ctx, h.connCtx.Username, requirement); err != nil {
+		h.queryEngine.AuditInsert(ctx, h.queryContext(""), database, table, start, err)
+		return err
+	}
+
 	// Use Query Engine to store the data
-	if err := h.queryEngine.InsertData(context.Background(), block.TableName, block.Rows); err != nil {
-		h.logger.Error().Err(err).Str("table", block.TableName).Msg("Failed to store data via Query Engine")
+	err = h.queryEngine.InsertRecords(ctx, database, table, block.Records)
+	h.queryEngine.AuditInsert(ctx, h.queryContext(""), database, table, start, err)
+	if err != nil {
+		h.logger.Error().Err(err).Str("database", database).Str("table", table).Msg("Failed to store data via Query Engine")
 		return err
 	}
 
 	h.logger.Info().
 		Str("table", block.TableName).
-		Int("total_rows", len(block.Rows)).
+		Int64("total_rows", block.Rows).
 		Msg("Data stored successfully via Query Engine")
 
 	return nil
 }
 
+// blockTable returns the database and table a data block is inserted into. A db.table name
+// is used as is; a bare table name belongs to the session database, or "default" when the
+// session has none.
+func (h *ConnectionHandler) blockTable(block *DataBlock) (string, string) {
+	if database, table, ok := strings.Cut(block.TableName, "."); ok {
+		return database, table
+	}
+	if h.connCtx.Database != "" {
+		return h.connCtx.Database, block.TableName
+	}
+	return "default", block.TableName
+}
+
+// queryContext returns the context of an operation run by the session user
+func (h *ConnectionHandler) queryContext(query string) *types.QueryContext {
+	return &types.QueryContext{
+		Query:      query,
+		Database:   h.connCtx.Database,
+		User:       h.connCtx.Username,
+		ClientAddr: h.connCtx.ClientAddr,
+		Protocol:   types.ProtocolNative,
+	}
+}
+
 // sendException sends exception response
 func (h *ConnectionHandler) sendException(err error) error {
 	return h.sendExceptionSignal(err)
@@ -368,12 +417,28 @@ func (h *ConnectionHandler) handleClientHelloSignal(hello *signals.ClientHello)
 	// Set read timeout from client hello message
 	h.readTimeout = time.Duration(hello.ReadTimeout) * time.Second
 
+	// Authenticating sets the session user that every statement of the connection runs as
+	if err := h.auth.Authenticate(h.serverCtx, h.connCtx, hello.User, hello.Password, hello.Database); err != nil {
+		h.queryEngine.AuditLogin(h.serverCtx, hello.User, types.ProtocolNative, h.connCtx.ClientAddr, err)
+		h.logger.Warn().Err(err).Str("user", hello.User).Str("client", h.connCtx.ClientAddr).Msg("Authentication failed")
+		// The client is not told why, so it cannot probe for user names
+		h.sendExceptionSignal(errors.Newf(ErrAuthenticationFailed, "authentication failed for user %s", hello.User))
+		return err
+	}
+	h.queryEngine.AuditLogin(h.serverCtx, hello.User, types.ProtocolNative, h.connCtx.ClientAddr, nil)
+
 	// Send server hello response
 	return h.sendServerHelloSignal()
 }
 
 // handleClientQuerySignal handles client query message using decoded signal
-func (h *ConnectionHandler) handleClientQuerySignal(ctx context.Context, query *signals.ClientQuery) error {
+func (h *ConnectionHandler) handleClientQuerySignal(ctx context.Context, query *signals.ClientQuery) (err error) {
+	ctx, span := tracing.StartServer(tracing.WithTraceParent(ctx, query.TraceParent), "native.query",
+		attribute.String("ranger.query_id", query.QueryID),
+		attribute.String("db.namespace", query.Database),
+		attribute.String("client.address", h.connCtx.ClientAddr))
+	defer func() { tracing.End(span, err) }()
+
 	h.logger.Debug().
 		Str("query", query.Query).
 		Str("query_id", query.QueryID).
@@ -385,12 +450,14 @@ func (h *ConnectionHandler) handleClientQuerySignal(ctx context.Context, query *
 	queryCtx := &types.QueryContext{
 		Query:      query.Query,
 		Database:   query.Database,
-		User:       query.User,
+		User:       h.connCtx.Username,
 		ClientAddr: h.connCtx.ClientAddr,
+		Protocol:   types.ProtocolNative,
 	}
 	result, err := h.queryEngine.ExecuteQuery(ctx, queryCtx)
 	if err != nil {
 		h.logger.Error().Err(err).Str("query", query.Query).Msg("Query execution failed")
+		tracing.RecordError(span, err)
 		return h.sendExceptionSignal(err)
 	}
 
@@ -400,20 +467,31 @@ func (h *ConnectionHandler) handleClientQuerySignal(ctx context.Context, query *
 
 // handleClientDataSignal handles client data message using decoded signal
 func (h *ConnectionHandler) handleClientDataSignal(data *signals.ClientData) error {
+	defer data.Release()
+
 	h.logger.Debug().
 		Str("table_name", data.TableName).
-		Int("column_count", len(data.Columns)).
-		Int("row_count", len(data.Rows)).
+		Int("record_batches", len(data.Records)).
+		Int64("row_count", data.NumRows()).
 		Msg("Processing ClientData signal")
 
-	// Process the data block
+	records, err := data.Reader()
+	if err != nil {
+		return err
+	}
+	defer records.Release()
+
+	// Process the data block; the client waits for an exception or the end of the stream
 	block := &DataBlock{
 		TableName: data.TableName,
-		Columns:   data.Columns,
-		Rows:      data.Rows,
+		Records:   records,
+		Rows:      data.NumRows(),
 	}
 
-	return h.processDataBlock(block)
+	if err := h.processDataBlock(block); err != nil {
+		return h.sendExceptionSignal(err)
+	}
+	return h.sendServerEndOfStreamSignal()
 }
 
 // handleClientCancelSignal handles client cancel message using decoded signal
@@ -478,7 +556,11 @@ func (h *ConnectionHandler) sendQueryEngineResultsSignal(result *query.QueryResu
 	// Convert query result to protocol format
 	columns := make([][]string, len(result.Columns))
 	for i, colName := range result.Columns {
-		columns[i] = []string{colName, "String"} // Default type to String
+		columnType := "String" // Default type to String
+		if i < len(result.ColumnTypes) && result.ColumnTypes[i] != "" {
+			columnType = result.ColumnTypes[i]
+		}
+		columns[i] = []string{colName, columnType}
 	}
 
 	// Convert data interface{} to [][]interface{}
@@ -0,0 +1,24 @@
+package native
+
+import (
+	"testing"
+
+	"github.com/gear6io/ranger/server/protocols/native/middleware"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBlockTable(t *testing.T) {
+	h := &ConnectionHandler{connCtx: &middleware.ConnectionContext{Database: "sales"}}
+
+	database, table := h.blockTable(&DataBlock{TableName: "orders"})
+	assert.Equal(t, "sales", database)
+	assert.Equal(t, "orders", table)
+
+	database, table = h.blockTable(&DataBlock{TableName: "analytics.events"})
+	assert.Equal(t, "analytics", database)
+	assert.Equal(t, "events", table)
+
+	h.connCtx.Database = ""
+	database, _ = h.blockTable(&DataBlock{TableName: "orders"})
+	assert.Equal(t, "default", database)
+}
@@ -0,0 +1,40 @@
+package native
+
+import "github.com/gear6io/ranger/server/metrics"
+
+// circuitStates are the states reported by the circuit breaker
+var circuitStates = []string{"closed", "open", "half-open"}
+
+// registerMetrics exposes the statistics of the connection pool and the circuit breaker
+func (s *Server) registerMetrics() {
+	metrics.Register(metrics.NewStatsCollector("native_connections", s.connectionPool.GetStats,
+		metrics.Gauge("active", "Open native protocol connections.",
+			func(stats map[string]interface{}) float64 { return metrics.Number(stats["active_connections"]) }),
+		metrics.Gauge("max", "Native protocol connection limit.",
+			func(stats map[string]interface{}) float64 { return metrics.Number(stats["max_connections"]) }),
+		metrics.Gauge("peak", "Most native protocol connections open at once.",
+			func(stats map[string]interface{}) float64 { return metrics.Number(stats["peak_connections"]) }),
+		metrics.Counter("accepted_total", "Native protocol connections accepted.",
+			func(stats map[string]interface{}) float64 { return metrics.Number(stats["total_connections"]) }),
+		metrics.Counter("rejected_total", "Native protocol connections rejected at the limit.",
+			func(stats map[string]interface{}) float64 { return metrics.Number(stats["rejected_connections"]) }),
+	))
+
+	metrics.Register(metrics.NewStatsCollector("native_circuit_breaker", s.circuitBreaker.GetStats,
+		metrics.GaugeVec("state", "Circuit breaker state; 1 for the current state.", "state",
+			func(stats map[string]interface{}) map[string]float64 {
+				values := make(map[string]float64, len(circuitStates))
+				for _, state := range circuitStates {
+					values[state] = 0
+					if stats["state"] == state {
+						values[state] = 1
+					}
+				}
+				return values
+			}),
+		metrics.Gauge("failures", "Failures counted towards opening the circuit.",
+			func(stats map[string]interface{}) float64 { return metrics.Number(stats["failure_count"]) }),
+		metrics.Gauge("active_queries", "Queries running under the circuit breaker.",
+			func(stats map[string]interface{}) float64 { return metrics.Number(stats["active_queries"]) }),
+	))
+}
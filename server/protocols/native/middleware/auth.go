@@ -4,7 +4,6 @@ import (
 	"context"
 	"crypto/rand"
 	"encoding/hex"
-	"strings"
 	"sync"
 	"time"
 
@@ -160,13 +159,7 @@ func (a *AuthMiddleware) OnQuery(ctx context.Context, connCtx *ConnectionContext
 		return errors.New(ErrAuthenticationRequired, "authentication required for query execution", nil)
 	}
 
-	// Check if query requires special permissions
-	if a.requiresSpecialPermissions(query) {
-		if !a.hasPermission(connCtx, "admin") {
-			return errors.New(ErrInsufficientPermissions, "insufficient permissions for this query", nil)
-		}
-	}
-
+	// Privileges are checked by the query engine against the parsed statement
 	return nil
 }
 
@@ -254,31 +247,6 @@ func (a *AuthMiddleware) ValidateToken(ctx context.Context, connCtx *ConnectionC
 	return nil
 }
 
-// requiresSpecialPermissions checks if a query requires special permissions
-func (a *AuthMiddleware) requiresSpecialPermissions(query string) bool {
-	// Add logic to detect dangerous queries
-	dangerousKeywords := []string{
-		"DROP", "DELETE", "TRUNCATE", "ALTER", "CREATE", "GRANT", "REVOKE",
-		"SHUTDOWN", "KILL", "SYSTEM", "EXEC", "EXECUTE",
-	}
-
-	queryUpper := strings.ToUpper(query)
-	for _, keyword := range dangerousKeywords {
-		if strings.Contains(queryUpper, keyword) {
-			return true
-		}
-	}
-
-	return false
-}
-
-// hasPermission checks if a connection has a specific permission
-func (a *AuthMiddleware) hasPermission(connCtx *ConnectionContext, permission string) bool {
-	// This would check against the cached auth result permissions
-	// For now, return false to be safe
-	return true
-}
-
 // cacheAuthResult caches an authentication result
 func (a *AuthMiddleware) cacheAuthResult(token string, result *AuthResult) {
 	a.tokenCacheMu.Lock()
@@ -0,0 +1,144 @@
+package middleware
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/pbkdf2"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/gear6io/ranger/pkg/errors"
+)
+
+// SCRAMIterations is the PBKDF2 iteration count used for derived SCRAM-SHA-256 secrets
+const SCRAMIterations = 4096
+
+// CredentialStore is implemented by AuthProviders that can expose stored credentials to
+// challenge-response mechanisms (MD5, SCRAM-SHA-256), which never see the cleartext password
+type CredentialStore interface {
+	LookupCredentials(ctx context.Context, username string) (*Credentials, error)
+}
+
+// LoginTracker is implemented by CredentialStores that count failed challenge-response
+// logins toward an account lockout
+type LoginTracker interface {
+	LoginFailed(ctx context.Context, username string)
+	LoginSucceeded(ctx context.Context, username string) error
+}
+
+// TokenAuthenticator is implemented by CredentialStores that accept a bearer token in place
+// of a password, which challenge-response mechanisms cannot carry
+type TokenAuthenticator interface {
+	AcceptsTokens() bool
+	Authenticate(ctx context.Context, username, password, database string) (*AuthResult, error)
+}
+
+// Credentials contains the stored secrets of a user
+type Credentials struct {
+	Username    string
+	Database    string
+	Permissions []string
+	Disabled    bool
+
+	// Password is the cleartext password, set only by providers that keep it
+	Password string
+
+	// SCRAM is the stored SCRAM-SHA-256 secret, set by providers that hash passwords
+	SCRAM *SCRAMSecret
+}
+
+// RequiresPassword reports whether the user has a password at all; users without
+// one are admitted without a password exchange, as in Authenticate
+func (c *Credentials) RequiresPassword() bool {
+	return c.Password != "" || c.SCRAM != nil
+}
+
+// SCRAMSecret is a SCRAM-SHA-256 verifier (RFC 5802, RFC 7677)
+type SCRAMSecret struct {
+	Iterations int
+	Salt       []byte
+	StoredKey  []byte
+	ServerKey  []byte
+}
+
+// DeriveSCRAMSecret computes the SCRAM-SHA-256 verifier of password for salt and iterations
+func DeriveSCRAMSecret(password string, salt []byte, iterations int) (*SCRAMSecret, error) {
+	saltedPassword, err := pbkdf2.Key(sha256.New, password, salt, iterations, sha256.Size)
+	if err != nil {
+		return nil, err
+	}
+
+	clientKey := scramHMAC(saltedPassword, "Client Key")
+	storedKey := sha256.Sum256(clientKey)
+
+	return &SCRAMSecret{
+		Iterations: iterations,
+		Salt:       salt,
+		StoredKey:  storedKey[:],
+		ServerKey:  scramHMAC(saltedPassword, "Server Key"),
+	}, nil
+}
+
+// ParseSCRAMSecret parses a verifier in the PostgreSQL rolpassword format:
+// SCRAM-SHA-256$<iterations>:<salt>$<StoredKey>:<ServerKey>
+func ParseSCRAMSecret(verifier string) (*SCRAMSecret, error) {
+	parts := strings.Split(verifier, "$")
+	if len(parts) != 3 || parts[0] != "SCRAM-SHA-256" {
+		return nil, errors.New(ErrInvalidSCRAMSecret, "invalid SCRAM-SHA-256 verifier", nil)
+	}
+
+	iterationsAndSalt := strings.SplitN(parts[1], ":", 2)
+	keys := strings.SplitN(parts[2], ":", 2)
+	if len(iterationsAndSalt) != 2 || len(keys) != 2 {
+		return nil, errors.New(ErrInvalidSCRAMSecret, "invalid SCRAM-SHA-256 verifier", nil)
+	}
+
+	iterations, err := strconv.Atoi(iterationsAndSalt[0])
+	if err != nil || iterations <= 0 {
+		return nil, errors.New(ErrInvalidSCRAMSecret, "invalid SCRAM-SHA-256 iteration count", err)
+	}
+
+	secret := &SCRAMSecret{Iterations: iterations}
+	for _, field := range []struct {
+		encoded string
+		decoded *[]byte
+	}{
+		{iterationsAndSalt[1], &secret.Salt},
+		{keys[0], &secret.StoredKey},
+		{keys[1], &secret.ServerKey},
+	} {
+		if *field.decoded, err = base64.StdEncoding.DecodeString(field.encoded); err != nil {
+			return nil, errors.New(ErrInvalidSCRAMSecret, "invalid SCRAM-SHA-256 verifier encoding", err)
+		}
+	}
+
+	return secret, nil
+}
+
+// String renders the secret in the PostgreSQL rolpassword format
+func (s *SCRAMSecret) String() string {
+	return fmt.Sprintf("SCRAM-SHA-256$%d:%s$%s:%s",
+		s.Iterations,
+		base64.StdEncoding.EncodeToString(s.Salt),
+		base64.StdEncoding.EncodeToString(s.StoredKey),
+		base64.StdEncoding.EncodeToString(s.ServerKey))
+}
+
+// VerifyPassword checks a cleartext password against the secret
+func (s *SCRAMSecret) VerifyPassword(password string) bool {
+	derived, err := DeriveSCRAMSecret(password, s.Salt, s.Iterations)
+	if err != nil {
+		return false
+	}
+	return hmac.Equal(derived.StoredKey, s.StoredKey)
+}
+
+// scramHMAC computes HMAC-SHA-256(key, message)
+func scramHMAC(key []byte, message string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(message))
+	return mac.Sum(nil)
+}
@@ -19,10 +19,20 @@ var (
 	ErrInvalidTokenFormat     = errors.MustNewCode("native.middleware.invalid_token_format")
 	ErrInvalidToken           = errors.MustNewCode("native.middleware.invalid_token")
 	ErrUserAlreadyExists      = errors.MustNewCode("native.middleware.user_already_exists")
+	ErrInvalidSCRAMSecret     = errors.MustNewCode("native.middleware.invalid_scram_secret")
+	ErrAccountLocked          = errors.MustNewCode("native.middleware.account_locked")
+	ErrAccountDisabled        = errors.MustNewCode("native.middleware.account_disabled")
+	ErrPasswordRequired       = errors.MustNewCode("native.middleware.password_required")
+	ErrPasswordExpired        = errors.MustNewCode("native.middleware.password_expired")
+	ErrTokenRevoked           = errors.MustNewCode("native.middleware.token_revoked")
+	ErrTokenExpired           = errors.MustNewCode("native.middleware.token_expired")
+	ErrPasswordHashFailed     = errors.MustNewCode("native.middleware.password_hash_failed")
+	ErrUnknownPasswordHash    = errors.MustNewCode("native.middleware.unknown_password_hash")
+	ErrUserStoreFailed        = errors.MustNewCode("native.middleware.user_store_failed")
+	ErrJWKSLoadFailed         = errors.MustNewCode("native.middleware.jwks_load_failed")
 	
 	// General authentication errors
 	ErrAuthenticationRequired = errors.MustNewCode("native.middleware.authentication_required")
-	ErrInsufficientPermissions = errors.MustNewCode("native.middleware.insufficient_permissions")
 	ErrAuthenticationFailed   = errors.MustNewCode("native.middleware.authentication_failed")
 	ErrInvalidCredentials     = errors.MustNewCode("native.middleware.invalid_credentials")
 	ErrTokenValidationFailed  = errors.MustNewCode("native.middleware.token_validation_failed")
@@ -0,0 +1,272 @@
+package middleware
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"encoding/json"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gear6io/ranger/pkg/errors"
+	"github.com/gear6io/ranger/server/config"
+	"github.com/go-jose/go-jose/v4"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/rs/zerolog"
+)
+
+// jwtAlgorithms are the signature algorithms accepted in bearer tokens. Symmetric algorithms
+// and "none" are excluded, so a token cannot be signed with a public key as HMAC secret.
+var jwtAlgorithms = []string{"RS256", "RS384", "RS512", "PS256", "PS384", "PS512", "ES256", "ES384", "ES512", "EdDSA"}
+
+// jwksMinRefreshInterval limits how often a token with an unknown key ID reloads the key set
+const jwksMinRefreshInterval = time.Minute
+
+// jwksFetchTimeout bounds a JSON Web Key Set download
+const jwksFetchTimeout = 10 * time.Second
+
+// Identity is the Ranger user a validated bearer token was issued to
+type Identity struct {
+	Username  string
+	Roles     []string // Ranger role names, after role_mapping
+	ExpiresAt time.Time
+}
+
+// JWTValidator validates JWTs against the JSON Web Key Set of an OIDC identity provider
+// and maps their claims to a Ranger user and roles
+type JWTValidator struct {
+	cfg    config.JWTConfig
+	parser *jwt.Parser
+	logger zerolog.Logger
+	client *http.Client
+
+	mu       sync.Mutex
+	keys     *jose.JSONWebKeySet
+	loadedAt time.Time
+}
+
+// NewJWTValidator creates a validator and loads the configured key set
+func NewJWTValidator(ctx context.Context, cfg config.JWTConfig, logger zerolog.Logger) (*JWTValidator, error) {
+	options := []jwt.ParserOption{
+		jwt.WithValidMethods(jwtAlgorithms),
+		jwt.WithLeeway(time.Duration(cfg.ClockSkewSeconds) * time.Second),
+		jwt.WithExpirationRequired(),
+		jwt.WithIssuedAt(),
+	}
+	if cfg.Issuer != "" {
+		options = append(options, jwt.WithIssuer(cfg.Issuer))
+	}
+	if cfg.Audience != "" {
+		options = append(options, jwt.WithAudience(cfg.Audience))
+	}
+
+	validator := &JWTValidator{
+		cfg:    cfg,
+		parser: jwt.NewParser(options...),
+		logger: logger.With().Str("component", "jwt").Logger(),
+		client: &http.Client{Timeout: jwksFetchTimeout},
+	}
+	if err := validator.reload(ctx); err != nil {
+		return nil, err
+	}
+	return validator, nil
+}
+
+// IsJWT reports whether a credential has the shape of a compact JWS, so it can be told apart
+// from a password
+func IsJWT(credential string) bool {
+	return strings.HasPrefix(credential, "eyJ") && strings.Count(credential, ".") == 2
+}
+
+// Validate checks the signature, expiry, issuer and audience of token and returns the
+// identity it was issued to
+func (v *JWTValidator) Validate(ctx context.Context, token string) (*Identity, error) {
+	claims := jwt.MapClaims{}
+	if _, err := v.parser.ParseWithClaims(token, claims, func(token *jwt.Token) (interface{}, error) {
+		kid, _ := token.Header["kid"].(string)
+		return v.verificationKeys(ctx, kid, token.Method.Alg())
+	}); err != nil {
+		return nil, errors.New(ErrInvalidToken, "invalid bearer token", err)
+	}
+
+	username, _ := lookupClaim(claims, v.cfg.UsernameClaim).(string)
+	if username == "" {
+		return nil, errors.New(ErrInvalidToken, "bearer token has no user name claim", nil).AddContext("claim", v.cfg.UsernameClaim)
+	}
+
+	identity := &Identity{Username: username, Roles: v.mapRoles(claims)}
+	if expiresAt, err := claims.GetExpirationTime(); err == nil && expiresAt != nil {
+		identity.ExpiresAt = expiresAt.Time
+	}
+	return identity, nil
+}
+
+// mapRoles returns the Ranger roles named by the roles claim. With a role mapping only
+// mapped roles count; without one claim values are role names.
+func (v *JWTValidator) mapRoles(claims jwt.MapClaims) []string {
+	if v.cfg.RolesClaim == "" {
+		return nil
+	}
+
+	var values []string
+	switch claim := lookupClaim(claims, v.cfg.RolesClaim).(type) {
+	case []interface{}:
+		for _, value := range claim {
+			if role, ok := value.(string); ok {
+				values = append(values, role)
+			}
+		}
+	case string:
+		// Space-separated, like the OAuth scope claim
+		values = strings.Fields(claim)
+	}
+
+	roles := make([]string, 0, len(values))
+	for _, value := range values {
+		if len(v.cfg.RoleMapping) == 0 {
+			roles = append(roles, value)
+		} else if role, ok := v.cfg.RoleMapping[value]; ok {
+			roles = append(roles, role)
+		}
+	}
+	return roles
+}
+
+// lookupClaim returns a claim by name, following dots into nested objects such as
+// realm_access.roles
+func lookupClaim(claims jwt.MapClaims, name string) interface{} {
+	if value, ok := claims[name]; ok {
+		return value
+	}
+
+	var current interface{} = map[string]interface{}(claims)
+	for _, part := range strings.Split(name, ".") {
+		object, ok := current.(map[string]interface{})
+		if !ok {
+			return nil
+		}
+		current = object[part]
+	}
+	return current
+}
+
+// verificationKeys returns the signing keys matching kid and alg, reloading the key set
+// once when none match so rotated keys are picked up
+func (v *JWTValidator) verificationKeys(ctx context.Context, kid, alg string) (interface{}, error) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	refresh := time.Duration(v.cfg.JWKSRefreshMinutes) * time.Minute
+	if refresh > 0 && time.Since(v.loadedAt) > refresh {
+		v.reloadLocked(ctx)
+	}
+
+	keys := matchingKeys(v.keys, kid, alg)
+	if len(keys) == 0 && time.Since(v.loadedAt) > jwksMinRefreshInterval {
+		v.reloadLocked(ctx)
+		keys = matchingKeys(v.keys, kid, alg)
+	}
+
+	switch len(keys) {
+	case 0:
+		return nil, errors.New(ErrInvalidToken, "no signing key matches the token", nil).AddContext("kid", kid)
+	case 1:
+		return keys[0], nil
+	default:
+		return jwt.VerificationKeySet{Keys: keys}, nil
+	}
+}
+
+// matchingKeys returns the public signature keys of set usable for kid and alg; every
+// key is a candidate when the token names no key
+func matchingKeys(set *jose.JSONWebKeySet, kid, alg string) []jwt.VerificationKey {
+	candidates := set.Keys
+	if kid != "" {
+		candidates = set.Key(kid)
+	}
+
+	var keys []jwt.VerificationKey
+	for _, key := range candidates {
+		if (key.Use != "" && key.Use != "sig") || (key.Algorithm != "" && key.Algorithm != alg) {
+			continue
+		}
+		switch public := key.Public().Key.(type) {
+		case *rsa.PublicKey, *ecdsa.PublicKey, ed25519.PublicKey:
+			keys = append(keys, public)
+		}
+	}
+	return keys
+}
+
+// reload loads the key set, failing when it cannot be read
+func (v *JWTValidator) reload(ctx context.Context) error {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	keys, err := v.loadKeys(ctx)
+	if err != nil {
+		return err
+	}
+	v.keys, v.loadedAt = keys, time.Now()
+	return nil
+}
+
+// reloadLocked reloads the key set, keeping the previous keys when it cannot be read
+func (v *JWTValidator) reloadLocked(ctx context.Context) {
+	keys, err := v.loadKeys(ctx)
+	// Failed reloads are not retried until the next interval, so an unreachable identity
+	// provider is not hit on every login
+	v.loadedAt = time.Now()
+	if err != nil {
+		v.logger.Error().Err(err).Msg("Failed to reload JSON Web Key Set, keeping the previous keys")
+		return
+	}
+	v.keys = keys
+}
+
+// loadKeys reads the key set from the configured file or URL
+func (v *JWTValidator) loadKeys(ctx context.Context) (*jose.JSONWebKeySet, error) {
+	var data []byte
+	if v.cfg.JWKSFile != "" {
+		content, err := os.ReadFile(v.cfg.JWKSFile)
+		if err != nil {
+			return nil, errors.New(ErrJWKSLoadFailed, "failed to read JSON Web Key Set", err).AddContext("path", v.cfg.JWKSFile)
+		}
+		data = content
+	} else {
+		content, err := v.fetchKeys(ctx)
+		if err != nil {
+			return nil, errors.New(ErrJWKSLoadFailed, "failed to fetch JSON Web Key Set", err).AddContext("url", v.cfg.JWKSURL)
+		}
+		data = content
+	}
+
+	var keys jose.JSONWebKeySet
+	if err := json.Unmarshal(data, &keys); err != nil {
+		return nil, errors.New(ErrJWKSLoadFailed, "failed to parse JSON Web Key Set", err)
+	}
+	return &keys, nil
+}
+
+// fetchKeys downloads the key set from the configured URL
+func (v *JWTValidator) fetchKeys(ctx context.Context) ([]byte, error) {
+	request, err := http.NewRequestWithContext(context.WithoutCancel(ctx), http.MethodGet, v.cfg.JWKSURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	response, err := v.client.Do(request)
+	if err != nil {
+		return nil, err
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusOK {
+		return nil, errors.New(ErrJWKSLoadFailed, "unexpected response status", nil).AddContext("status", response.Status)
+	}
+	return io.ReadAll(io.LimitReader(response.Body, 1<<20))
+}
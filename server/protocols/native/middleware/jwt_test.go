@@ -0,0 +1,219 @@
+package middleware
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/gear6io/ranger/server/config"
+	"github.com/gear6io/ranger/server/metadata/registry/regtypes"
+	"github.com/go-jose/go-jose/v4"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testIssuer signs tokens the way an OIDC identity provider would
+type testIssuer struct {
+	key *rsa.PrivateKey
+	kid string
+}
+
+func newTestIssuer(t *testing.T, kid string) *testIssuer {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	return &testIssuer{key: key, kid: kid}
+}
+
+// jwks renders the public key sets of issuers
+func jwks(t *testing.T, issuers ...*testIssuer) []byte {
+	var set jose.JSONWebKeySet
+	for _, issuer := range issuers {
+		set.Keys = append(set.Keys, jose.JSONWebKey{Key: &issuer.key.PublicKey, KeyID: issuer.kid, Algorithm: "RS256", Use: "sig"})
+	}
+	data, err := json.Marshal(set)
+	require.NoError(t, err)
+	return data
+}
+
+// sign issues a token with the standard claims of the test identity provider plus extra
+func (i *testIssuer) sign(t *testing.T, subject string, extra jwt.MapClaims) string {
+	claims := jwt.MapClaims{
+		"iss": "https://idp.example.com",
+		"aud": "ranger",
+		"sub": subject,
+		"iat": time.Now().Unix(),
+		"exp": time.Now().Add(time.Hour).Unix(),
+	}
+	for name, value := range extra {
+		claims[name] = value
+	}
+
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	token.Header["kid"] = i.kid
+	signed, err := token.SignedString(i.key)
+	require.NoError(t, err)
+	return signed
+}
+
+// testJWTConfig returns a JWT configuration reading the key set from path
+func testJWTConfig(path string) config.JWTConfig {
+	return config.JWTConfig{
+		Enabled:            true,
+		JWKSFile:           path,
+		JWKSRefreshMinutes: 60,
+		Issuer:             "https://idp.example.com",
+		Audience:           "ranger",
+		UsernameClaim:      "sub",
+		CreateUsers:        true,
+	}
+}
+
+// writeJWKS writes the key set of issuers to a file and returns its path
+func writeJWKS(t *testing.T, issuers ...*testIssuer) string {
+	path := filepath.Join(t.TempDir(), "jwks.json")
+	require.NoError(t, os.WriteFile(path, jwks(t, issuers...), 0o600))
+	return path
+}
+
+func TestJWTValidator(t *testing.T) {
+	ctx := context.Background()
+	issuer := newTestIssuer(t, "k1")
+	cfg := testJWTConfig(writeJWKS(t, issuer))
+	cfg.RolesClaim = "realm_access.roles"
+	cfg.RoleMapping = map[string]string{"data-analysts": "analyst"}
+
+	validator, err := NewJWTValidator(ctx, cfg, zerolog.Nop())
+	require.NoError(t, err)
+
+	token := issuer.sign(t, "alice", jwt.MapClaims{"realm_access": map[string]interface{}{"roles": []string{"data-analysts", "offline_access"}}})
+	assert.True(t, IsJWT(token))
+	assert.False(t, IsJWT("s3cret"))
+
+	identity, err := validator.Validate(ctx, token)
+	require.NoError(t, err)
+	assert.Equal(t, "alice", identity.Username)
+	// Only mapped roles are kept
+	assert.Equal(t, []string{"analyst"}, identity.Roles)
+	assert.WithinDuration(t, time.Now().Add(time.Hour), identity.ExpiresAt, time.Minute)
+
+	rejected := map[string]string{
+		"expired":      issuer.sign(t, "alice", jwt.MapClaims{"exp": time.Now().Add(-time.Hour).Unix()}),
+		"issuer":       issuer.sign(t, "alice", jwt.MapClaims{"iss": "https://evil.example.com"}),
+		"audience":     issuer.sign(t, "alice", jwt.MapClaims{"aud": "other"}),
+		"no subject":   issuer.sign(t, "", nil),
+		"unknown key":  newTestIssuer(t, "k1").sign(t, "alice", nil),
+		"unsigned":     token[:len(token)-10] + "AAAAAAAAAA",
+		"no algorithm": jwtWithoutSignature(t),
+	}
+	for name, token := range rejected {
+		t.Run(name, func(t *testing.T) {
+			_, err := validator.Validate(ctx, token)
+			assertCode(t, ErrInvalidToken, err)
+		})
+	}
+}
+
+// jwtWithoutSignature returns an unsigned token with the none algorithm
+func jwtWithoutSignature(t *testing.T) string {
+	token := jwt.NewWithClaims(jwt.SigningMethodNone, jwt.MapClaims{"sub": "alice", "exp": time.Now().Add(time.Hour).Unix()})
+	signed, err := token.SignedString(jwt.UnsafeAllowNoneSignatureType)
+	require.NoError(t, err)
+	return signed
+}
+
+func TestJWTValidatorKeyRotation(t *testing.T) {
+	ctx := context.Background()
+	first, second := newTestIssuer(t, "k1"), newTestIssuer(t, "k2")
+
+	served := jwks(t, first)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/jwks.json" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write(served)
+	}))
+	defer server.Close()
+
+	cfg := testJWTConfig("")
+	cfg.JWKSURL = server.URL + "/jwks.json"
+	validator, err := NewJWTValidator(ctx, cfg, zerolog.Nop())
+	require.NoError(t, err)
+
+	_, err = validator.Validate(ctx, first.sign(t, "alice", nil))
+	require.NoError(t, err)
+
+	// A new key is picked up once the key set may be reloaded
+	served = jwks(t, first, second)
+	_, err = validator.Validate(ctx, second.sign(t, "alice", nil))
+	assertCode(t, ErrInvalidToken, err)
+	validator.loadedAt = time.Now().Add(-2 * jwksMinRefreshInterval)
+	_, err = validator.Validate(ctx, second.sign(t, "alice", nil))
+	require.NoError(t, err)
+
+	cfg.JWKSURL = server.URL + "/missing"
+	_, err = NewJWTValidator(ctx, cfg, zerolog.Nop())
+	assertCode(t, ErrJWKSLoadFailed, err)
+}
+
+func TestRegistryAuthProviderJWT(t *testing.T) {
+	ctx := context.Background()
+	issuer := newTestIssuer(t, "k1")
+	cfg := testAuthConfig()
+	cfg.JWT = testJWTConfig(writeJWKS(t, issuer))
+	cfg.JWT.RolesClaim = "roles"
+
+	validator, err := NewJWTValidator(ctx, cfg.JWT, zerolog.Nop())
+	require.NoError(t, err)
+	store := newMemoryUserStore(&regtypes.User{Username: "bob"})
+	store.roles["analyst"] = true
+	store.roles["auditor"] = true
+	require.NoError(t, store.GrantRole(ctx, "auditor", "bob"))
+	provider := NewRegistryAuthProvider(store, cfg, validator, zerolog.Nop())
+	assert.True(t, provider.AcceptsTokens())
+
+	// Unknown users are created on their first login, with the roles of their token
+	token := issuer.sign(t, "alice", jwt.MapClaims{"roles": []string{"analyst", "missing"}})
+	result, err := provider.Authenticate(ctx, "", token, "default")
+	require.NoError(t, err)
+	assert.Equal(t, "alice", result.Username)
+	assert.Equal(t, token, result.Token)
+	roles, err := store.ListUserRoles(ctx, "alice")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"analyst"}, roles)
+
+	// Created users cannot log in with a password
+	_, err = provider.Authenticate(ctx, "alice", "", "default")
+	assertCode(t, ErrInvalidPassword, err)
+
+	// Memberships follow the token
+	validated, err := provider.ValidateToken(ctx, issuer.sign(t, "bob", jwt.MapClaims{"roles": "analyst"}))
+	require.NoError(t, err)
+	assert.Equal(t, "bob", validated.Username)
+	roles, err = store.ListUserRoles(ctx, "bob")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"analyst"}, roles)
+
+	_, err = provider.Authenticate(ctx, "bob", token, "default")
+	assertCode(t, ErrInvalidToken, err)
+	_, err = provider.RefreshToken(ctx, token)
+	assertCode(t, ErrInvalidToken, err)
+
+	store.users["bob"].IsActive = false
+	_, err = provider.ValidateToken(ctx, issuer.sign(t, "bob", nil))
+	assertCode(t, ErrAccountDisabled, err)
+
+	cfg.JWT.CreateUsers = false
+	provider = NewRegistryAuthProvider(store, cfg, validator, zerolog.Nop())
+	_, err = provider.Authenticate(ctx, "", issuer.sign(t, "carol", nil), "default")
+	assertCode(t, ErrUserNotFound, err)
+}
@@ -0,0 +1,117 @@
+package middleware
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/gear6io/ranger/pkg/errors"
+	"github.com/gear6io/ranger/server/config"
+	"github.com/gear6io/ranger/server/metadata/registry/regtypes"
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// Argon2id parameters for new hashes (RFC 9106 second recommended option, scaled to OWASP guidance)
+const (
+	argon2Time    = 2
+	argon2Memory  = 19 * 1024 // KiB
+	argon2Threads = 1
+	argon2KeyLen  = 32
+	argon2SaltLen = 16
+)
+
+// NewUserPassword hashes password with the algorithm of cfg and derives its SCRAM-SHA-256
+// verifier, setting the expiry from the configured maximum password age
+func NewUserPassword(password string, cfg config.AuthConfig) (regtypes.UserPassword, error) {
+	hash, err := HashPassword(password, cfg.PasswordHash)
+	if err != nil {
+		return regtypes.UserPassword{}, err
+	}
+
+	salt := make([]byte, 16)
+	if _, err := rand.Read(salt); err != nil {
+		return regtypes.UserPassword{}, errors.New(ErrPasswordHashFailed, "failed to generate salt", err)
+	}
+	secret, err := DeriveSCRAMSecret(password, salt, SCRAMIterations)
+	if err != nil {
+		return regtypes.UserPassword{}, errors.New(ErrPasswordHashFailed, "failed to derive SCRAM verifier", err)
+	}
+
+	userPassword := regtypes.UserPassword{Hash: hash, SCRAMSecret: secret.String()}
+	if cfg.PasswordMaxAgeDays > 0 {
+		expiresAt := time.Now().AddDate(0, 0, cfg.PasswordMaxAgeDays)
+		userPassword.ExpiresAt = &expiresAt
+	}
+	return userPassword, nil
+}
+
+// HashPassword hashes password with argon2id (the default) or bcrypt
+func HashPassword(password, algorithm string) (string, error) {
+	switch algorithm {
+	case "", config.PasswordHashArgon2id:
+		salt := make([]byte, argon2SaltLen)
+		if _, err := rand.Read(salt); err != nil {
+			return "", errors.New(ErrPasswordHashFailed, "failed to generate salt", err)
+		}
+		key := argon2.IDKey([]byte(password), salt, argon2Time, argon2Memory, argon2Threads, argon2KeyLen)
+		return fmt.Sprintf("$argon2id$v=%d$m=%d,t=%d,p=%d$%s$%s", argon2.Version, argon2Memory, argon2Time, argon2Threads,
+			base64.RawStdEncoding.EncodeToString(salt), base64.RawStdEncoding.EncodeToString(key)), nil
+	case config.PasswordHashBcrypt:
+		hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+		if err != nil {
+			return "", errors.New(ErrPasswordHashFailed, "failed to hash password", err)
+		}
+		return string(hash), nil
+	default:
+		return "", errors.New(ErrUnknownPasswordHash, "unknown password hash algorithm", nil).AddContext("algorithm", algorithm)
+	}
+}
+
+// VerifyPasswordHash checks password against an argon2id, bcrypt or SCRAM-SHA-256 hash
+func VerifyPasswordHash(hash, password string) bool {
+	switch {
+	case strings.HasPrefix(hash, "$argon2id$"):
+		return verifyArgon2id(hash, password)
+	case strings.HasPrefix(hash, "$2a$"), strings.HasPrefix(hash, "$2b$"), strings.HasPrefix(hash, "$2y$"):
+		return bcrypt.CompareHashAndPassword([]byte(hash), []byte(password)) == nil
+	case strings.HasPrefix(hash, "SCRAM-SHA-256$"):
+		secret, err := ParseSCRAMSecret(hash)
+		return err == nil && secret.VerifyPassword(password)
+	default:
+		return false
+	}
+}
+
+// verifyArgon2id checks password against a hash in the PHC string format
+func verifyArgon2id(hash, password string) bool {
+	parts := strings.Split(hash, "$")
+	if len(parts) != 6 {
+		return false
+	}
+
+	var version int
+	var memory, iterations uint32
+	var threads uint8
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil || version != argon2.Version {
+		return false
+	}
+	if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &memory, &iterations, &threads); err != nil {
+		return false
+	}
+
+	salt, err := base64.RawStdEncoding.DecodeString(parts[4])
+	if err != nil {
+		return false
+	}
+	key, err := base64.RawStdEncoding.DecodeString(parts[5])
+	if err != nil {
+		return false
+	}
+
+	derived := argon2.IDKey([]byte(password), salt, iterations, memory, threads, uint32(len(key)))
+	return subtle.ConstantTimeCompare(derived, key) == 1
+}
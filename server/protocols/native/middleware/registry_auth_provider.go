@@ -0,0 +1,465 @@
+package middleware
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"sync"
+	"time"
+
+	"github.com/gear6io/ranger/pkg/errors"
+	"github.com/gear6io/ranger/server/config"
+	"github.com/gear6io/ranger/server/metadata/registry"
+	"github.com/gear6io/ranger/server/metadata/registry/regtypes"
+	"github.com/rs/zerolog"
+)
+
+// UserStore is the persistent user and session token storage behind RegistryAuthProvider
+type UserStore interface {
+	GetUser(ctx context.Context, username string) (*regtypes.User, error)
+	CreateUser(ctx context.Context, username string, password regtypes.UserPassword) error
+	SetUserPassword(ctx context.Context, username string, password regtypes.UserPassword) error
+	RoleExists(ctx context.Context, name string) bool
+	GrantRole(ctx context.Context, role, username string) error
+	RevokeRole(ctx context.Context, role, username string) error
+	ListUserRoles(ctx context.Context, username string) ([]string, error)
+	RecordLoginFailure(ctx context.Context, username string, maxAttempts int, lockout time.Duration) (*time.Time, error)
+	RecordLoginSuccess(ctx context.Context, username string) error
+	CreateAuthToken(ctx context.Context, token *regtypes.AuthToken) error
+	GetAuthToken(ctx context.Context, tokenHash string) (*regtypes.AuthToken, error)
+	RevokeAuthToken(ctx context.Context, tokenHash string) error
+	PruneAuthTokens(ctx context.Context, cutoff time.Time) (int64, error)
+}
+
+// tokenPruneInterval is how often issuing a session token also removes the expired ones
+const tokenPruneInterval = time.Hour
+
+// RegistryAuthProvider authenticates users against the registry users table. Passwords are
+// stored as argon2id or bcrypt hashes, repeated failures lock the account and session
+// tokens are kept as hashes so they can be revoked. With a JWTValidator, a JWT is accepted
+// wherever a password or session token is.
+type RegistryAuthProvider struct {
+	store  UserStore
+	cfg    config.AuthConfig
+	jwt    *JWTValidator
+	logger zerolog.Logger
+
+	pruneMu  sync.Mutex
+	prunedAt time.Time
+}
+
+// NewRegistryAuthProvider creates an authentication provider backed by store; jwt may be
+// nil to accept passwords only
+func NewRegistryAuthProvider(store UserStore, cfg config.AuthConfig, jwt *JWTValidator, logger zerolog.Logger) *RegistryAuthProvider {
+	return &RegistryAuthProvider{
+		store:  store,
+		cfg:    cfg,
+		jwt:    jwt,
+		logger: logger.With().Str("component", "auth").Logger(),
+	}
+}
+
+// AcceptsTokens reports whether a JWT may be sent in place of a password
+func (provider *RegistryAuthProvider) AcceptsTokens() bool {
+	return provider.jwt != nil
+}
+
+// Authenticate verifies the password of username and issues a session token. A JWT sent
+// as the password is validated instead; username may then be empty.
+func (provider *RegistryAuthProvider) Authenticate(ctx context.Context, username, password, database string) (*AuthResult, error) {
+	if provider.jwt != nil && IsJWT(password) {
+		return provider.authenticateJWT(ctx, username, password, database)
+	}
+
+	user, err := provider.getUser(ctx, username)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	if !user.IsActive {
+		return nil, errors.Newf(ErrAccountDisabled, "account is disabled: %s", username)
+	}
+	if user.IsLocked(now) {
+		return nil, errors.Newf(ErrAccountLocked, "account is locked: %s", username).AddContext("locked_until", user.LockedUntil.Format(time.RFC3339))
+	}
+
+	if !user.HasPassword() {
+		if !provider.allowsEmptyPassword(user) {
+			return nil, errors.Newf(ErrPasswordRequired, "user has no password set: %s", username)
+		}
+	} else if !VerifyUserPassword(user, password) {
+		if lockedUntil := provider.recordFailure(ctx, username); lockedUntil != nil {
+			return nil, errors.Newf(ErrAccountLocked, "account is locked: %s", username).AddContext("locked_until", lockedUntil.Format(time.RFC3339))
+		}
+		return nil, errors.Newf(ErrInvalidPassword, "invalid password for user: %s", username)
+	}
+
+	// Expiry is only reported once the password is known to be right
+	if user.IsPasswordExpired(now) {
+		return nil, errors.Newf(ErrPasswordExpired, "password has expired for user: %s", username)
+	}
+
+	if err := provider.LoginSucceeded(ctx, username); err != nil {
+		return nil, err
+	}
+
+	token, expiresAt, err := provider.issueToken(ctx, username)
+	if err != nil {
+		return nil, err
+	}
+
+	provider.logger.Debug().
+		Str("username", username).
+		Str("database", database).
+		Msg("User authenticated successfully")
+
+	return &AuthResult{
+		Authenticated: true,
+		Username:      username,
+		Database:      database,
+		ExpiresAt:     expiresAt,
+		Token:         token,
+	}, nil
+}
+
+// ValidateToken checks that a session token was issued by this provider, is neither revoked
+// nor expired, and that its user may still log in. JWTs are validated against the key set.
+func (provider *RegistryAuthProvider) ValidateToken(ctx context.Context, token string) (*AuthResult, error) {
+	if provider.jwt != nil && IsJWT(token) {
+		return provider.authenticateJWT(ctx, "", token, "")
+	}
+
+	stored, err := provider.store.GetAuthToken(ctx, hashToken(token))
+	if err != nil {
+		if errors.GetCode(err) == registry.RegistryTokenNotFound.String() {
+			return nil, errors.New(ErrInvalidToken, "unknown token", nil)
+		}
+		return nil, errors.New(ErrUserStoreFailed, "failed to look up token", err)
+	}
+
+	now := time.Now()
+	if stored.RevokedAt != nil {
+		return nil, errors.New(ErrTokenRevoked, "token has been revoked", nil)
+	}
+	if !now.Before(stored.ExpiresAt) {
+		return nil, errors.New(ErrTokenExpired, "token has expired", nil)
+	}
+
+	user, err := provider.getUser(ctx, stored.Username)
+	if err != nil {
+		return nil, err
+	}
+	if !user.IsActive {
+		return nil, errors.Newf(ErrAccountDisabled, "account is disabled: %s", user.Username)
+	}
+	if user.IsLocked(now) {
+		return nil, errors.Newf(ErrAccountLocked, "account is locked: %s", user.Username)
+	}
+
+	return &AuthResult{
+		Authenticated: true,
+		Username:      stored.Username,
+		ExpiresAt:     stored.ExpiresAt,
+		Token:         token,
+	}, nil
+}
+
+// RefreshToken revokes a valid session token and issues a new one for the same user
+func (provider *RegistryAuthProvider) RefreshToken(ctx context.Context, token string) (*AuthResult, error) {
+	if provider.jwt != nil && IsJWT(token) {
+		return nil, errors.New(ErrInvalidToken, "bearer tokens are refreshed by the identity provider", nil)
+	}
+
+	existing, err := provider.ValidateToken(ctx, token)
+	if err != nil {
+		return nil, errors.New(ErrInvalidToken, "invalid existing token", err)
+	}
+
+	if err := provider.RevokeToken(ctx, token); err != nil {
+		return nil, err
+	}
+
+	newToken, expiresAt, err := provider.issueToken(ctx, existing.Username)
+	if err != nil {
+		return nil, err
+	}
+
+	return &AuthResult{
+		Authenticated: true,
+		Username:      existing.Username,
+		ExpiresAt:     expiresAt,
+		Token:         newToken,
+	}, nil
+}
+
+// RevokeToken revokes a session token before it expires
+func (provider *RegistryAuthProvider) RevokeToken(ctx context.Context, token string) error {
+	if err := provider.store.RevokeAuthToken(ctx, hashToken(token)); err != nil {
+		if errors.GetCode(err) == registry.RegistryTokenNotFound.String() {
+			return errors.New(ErrInvalidToken, "unknown token", nil)
+		}
+		return errors.New(ErrUserStoreFailed, "failed to revoke token", err)
+	}
+	return nil
+}
+
+// LookupCredentials returns the SCRAM-SHA-256 verifier of a user for challenge-response
+// authentication. Users that may not log in are returned as disabled.
+func (provider *RegistryAuthProvider) LookupCredentials(ctx context.Context, username string) (*Credentials, error) {
+	user, err := provider.getUser(ctx, username)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	credentials := &Credentials{
+		Username: user.Username,
+		Database: "default",
+		Disabled: !user.IsActive || user.IsLocked(now) || user.IsPasswordExpired(now) ||
+			(!user.HasPassword() && !provider.allowsEmptyPassword(user)),
+	}
+
+	if user.SCRAMSecret != "" {
+		secret, err := ParseSCRAMSecret(user.SCRAMSecret)
+		if err != nil {
+			return nil, err
+		}
+		credentials.SCRAM = secret
+	} else if user.HasPassword() {
+		// A password without a SCRAM verifier cannot be checked over a challenge-response exchange
+		credentials.Disabled = true
+	}
+	return credentials, nil
+}
+
+// LoginFailed counts a failed challenge-response login toward the account lockout
+func (provider *RegistryAuthProvider) LoginFailed(ctx context.Context, username string) {
+	provider.recordFailure(ctx, username)
+}
+
+// LoginSucceeded resets the failed login count of username
+func (provider *RegistryAuthProvider) LoginSucceeded(ctx context.Context, username string) error {
+	if err := provider.store.RecordLoginSuccess(ctx, username); err != nil {
+		return errors.New(ErrUserStoreFailed, "failed to record login", err).AddContext("username", username)
+	}
+	return nil
+}
+
+// EnsurePassword sets the password of username unless it already has one, so a fresh
+// registry can be given an administrator password at startup
+func (provider *RegistryAuthProvider) EnsurePassword(ctx context.Context, username, password string) error {
+	user, err := provider.getUser(ctx, username)
+	if err != nil {
+		return err
+	}
+	if user.HasPassword() {
+		return nil
+	}
+
+	userPassword, err := NewUserPassword(password, provider.cfg)
+	if err != nil {
+		return err
+	}
+	if err := provider.store.SetUserPassword(ctx, username, userPassword); err != nil {
+		return errors.New(ErrUserStoreFailed, "failed to set password", err).AddContext("username", username)
+	}
+
+	provider.logger.Info().Str("username", username).Msg("Initial password set")
+	return nil
+}
+
+// authenticateJWT validates a JWT and logs in the user it was issued to, creating the user
+// and syncing its role memberships as configured
+func (provider *RegistryAuthProvider) authenticateJWT(ctx context.Context, username, token, database string) (*AuthResult, error) {
+	identity, err := provider.jwt.Validate(ctx, token)
+	if err != nil {
+		return nil, err
+	}
+	if username != "" && username != identity.Username {
+		return nil, errors.Newf(ErrInvalidToken, "bearer token was not issued to user %s", username)
+	}
+
+	user, err := provider.tokenUser(ctx, identity.Username)
+	if err != nil {
+		return nil, err
+	}
+	if !user.IsActive {
+		return nil, errors.Newf(ErrAccountDisabled, "account is disabled: %s", user.Username)
+	}
+	if user.IsLocked(time.Now()) {
+		return nil, errors.Newf(ErrAccountLocked, "account is locked: %s", user.Username)
+	}
+
+	if provider.cfg.JWT.RolesClaim != "" {
+		if err := provider.syncRoles(ctx, user, identity.Roles); err != nil {
+			return nil, err
+		}
+	}
+	if err := provider.LoginSucceeded(ctx, user.Username); err != nil {
+		return nil, err
+	}
+
+	return &AuthResult{
+		Authenticated: true,
+		Username:      user.Username,
+		Database:      database,
+		ExpiresAt:     identity.ExpiresAt,
+		Token:         token,
+	}, nil
+}
+
+// tokenUser returns the user a JWT was issued to, creating it when configured. Created users
+// get a random password, so they can only log in with a token.
+func (provider *RegistryAuthProvider) tokenUser(ctx context.Context, username string) (*regtypes.User, error) {
+	user, err := provider.getUser(ctx, username)
+	if err == nil || errors.GetCode(err) != ErrUserNotFound.String() || !provider.cfg.JWT.CreateUsers {
+		return user, err
+	}
+
+	bytes := make([]byte, 32)
+	if _, err := rand.Read(bytes); err != nil {
+		return nil, errors.New(ErrPasswordHashFailed, "failed to generate password", err)
+	}
+	password, err := NewUserPassword(hex.EncodeToString(bytes), config.AuthConfig{PasswordHash: provider.cfg.PasswordHash})
+	if err != nil {
+		return nil, err
+	}
+	// A concurrent login may have created the user first
+	if err := provider.store.CreateUser(ctx, username, password); err != nil && errors.GetCode(err) != registry.RegistryUserExists.String() {
+		return nil, errors.New(ErrUserStoreFailed, "failed to create user", err).AddContext("username", username)
+	}
+
+	provider.logger.Info().Str("username", username).Msg("Created user on first token login")
+	return provider.getUser(ctx, username)
+}
+
+// syncRoles makes the role memberships of user match the roles of its token. Roles that do
+// not exist in the registry are ignored; superusers keep their memberships.
+func (provider *RegistryAuthProvider) syncRoles(ctx context.Context, user *regtypes.User, roles []string) error {
+	if user.IsAdmin {
+		return nil
+	}
+
+	wanted := make(map[string]bool, len(roles))
+	for _, role := range roles {
+		if provider.store.RoleExists(ctx, role) {
+			wanted[role] = true
+		}
+	}
+
+	current, err := provider.store.ListUserRoles(ctx, user.Username)
+	if err != nil {
+		return errors.New(ErrUserStoreFailed, "failed to list user roles", err).AddContext("username", user.Username)
+	}
+	for _, role := range current {
+		if wanted[role] {
+			delete(wanted, role)
+			continue
+		}
+		if err := provider.store.RevokeRole(ctx, role, user.Username); err != nil {
+			return errors.New(ErrUserStoreFailed, "failed to revoke role", err).AddContext("role", role)
+		}
+	}
+	for role := range wanted {
+		if err := provider.store.GrantRole(ctx, role, user.Username); err != nil {
+			return errors.New(ErrUserStoreFailed, "failed to grant role", err).AddContext("role", role)
+		}
+	}
+	return nil
+}
+
+// VerifyUserPassword checks password against the stored hash of user, falling back to its
+// SCRAM-SHA-256 verifier for users created before passwords were hashed separately
+func VerifyUserPassword(user *regtypes.User, password string) bool {
+	if user.PasswordHash != "" {
+		return VerifyPasswordHash(user.PasswordHash, password)
+	}
+	return user.SCRAMSecret != "" && VerifyPasswordHash(user.SCRAMSecret, password)
+}
+
+// allowsEmptyPassword reports whether user may log in without a password. Superusers
+// always need one.
+func (provider *RegistryAuthProvider) allowsEmptyPassword(user *regtypes.User) bool {
+	return provider.cfg.AllowEmptyPassword && !user.IsAdmin
+}
+
+// recordFailure counts a failed login and returns the time the account is locked until,
+// if this failure locked it
+func (provider *RegistryAuthProvider) recordFailure(ctx context.Context, username string) *time.Time {
+	lockout := time.Duration(provider.cfg.LockoutMinutes) * time.Minute
+	lockedUntil, err := provider.store.RecordLoginFailure(ctx, username, provider.cfg.MaxFailedAttempts, lockout)
+	if err != nil {
+		provider.logger.Error().Err(err).Str("username", username).Msg("Failed to record login failure")
+		return nil
+	}
+
+	if lockedUntil != nil {
+		provider.logger.Warn().
+			Str("username", username).
+			Time("locked_until", *lockedUntil).
+			Msg("Account locked after repeated failed logins")
+	}
+	return lockedUntil
+}
+
+// getUser looks up username, mapping a missing user to ErrUserNotFound
+func (provider *RegistryAuthProvider) getUser(ctx context.Context, username string) (*regtypes.User, error) {
+	user, err := provider.store.GetUser(ctx, username)
+	if err != nil {
+		if errors.GetCode(err) == registry.RegistryUserNotFound.String() {
+			return nil, errors.Newf(ErrUserNotFound, "user not found: %s", username)
+		}
+		return nil, errors.New(ErrUserStoreFailed, "failed to look up user", err).AddContext("username", username)
+	}
+	return user, nil
+}
+
+// issueToken creates a random session token for username and stores its hash
+func (provider *RegistryAuthProvider) issueToken(ctx context.Context, username string) (string, time.Time, error) {
+	bytes := make([]byte, 32)
+	if _, err := rand.Read(bytes); err != nil {
+		return "", time.Time{}, errors.New(ErrTokenGenerationFailed, "failed to generate token", err)
+	}
+	token := hex.EncodeToString(bytes)
+
+	expiresAt := time.Now().Add(time.Duration(provider.cfg.TokenTTLMinutes) * time.Minute)
+	if err := provider.store.CreateAuthToken(ctx, &regtypes.AuthToken{
+		TokenHash: hashToken(token),
+		Username:  username,
+		ExpiresAt: expiresAt,
+	}); err != nil {
+		return "", time.Time{}, errors.New(ErrTokenGenerationFailed, "failed to store token", err)
+	}
+	provider.pruneTokens(ctx)
+	return token, expiresAt, nil
+}
+
+// pruneTokens removes the expired session tokens, at most once every tokenPruneInterval,
+// so every login adding a token does not grow the token table without bound
+func (provider *RegistryAuthProvider) pruneTokens(ctx context.Context) {
+	now := time.Now()
+	provider.pruneMu.Lock()
+	if now.Sub(provider.prunedAt) < tokenPruneInterval {
+		provider.pruneMu.Unlock()
+		return
+	}
+	provider.prunedAt = now
+	provider.pruneMu.Unlock()
+
+	removed, err := provider.store.PruneAuthTokens(ctx, now)
+	if err != nil {
+		provider.logger.Warn().Err(err).Msg("Failed to prune expired session tokens")
+		return
+	}
+	if removed > 0 {
+		provider.logger.Debug().Int64("removed", removed).Msg("Pruned expired session tokens")
+	}
+}
+
+// hashToken returns the form in which a session token is stored
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,334 @@
+package middleware
+
+import (
+	"context"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/gear6io/ranger/pkg/errors"
+	"github.com/gear6io/ranger/server/config"
+	"github.com/gear6io/ranger/server/metadata/registry"
+	"github.com/gear6io/ranger/server/metadata/registry/regtypes"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// memoryUserStore keeps users and session tokens in memory
+type memoryUserStore struct {
+	users   map[string]*regtypes.User
+	tokens  map[string]*regtypes.AuthToken
+	roles   map[string]bool
+	members map[string]map[string]bool // username to role names
+}
+
+func newMemoryUserStore(users ...*regtypes.User) *memoryUserStore {
+	store := &memoryUserStore{
+		users:   make(map[string]*regtypes.User),
+		tokens:  make(map[string]*regtypes.AuthToken),
+		roles:   make(map[string]bool),
+		members: make(map[string]map[string]bool),
+	}
+	for _, user := range users {
+		user.IsActive = true
+		store.users[user.Username] = user
+	}
+	return store
+}
+
+func (m *memoryUserStore) GetUser(ctx context.Context, username string) (*regtypes.User, error) {
+	user, ok := m.users[username]
+	if !ok {
+		return nil, errors.New(registry.RegistryUserNotFound, "user not found", nil)
+	}
+	copied := *user
+	return &copied, nil
+}
+
+func (m *memoryUserStore) CreateUser(ctx context.Context, username string, password regtypes.UserPassword) error {
+	if _, ok := m.users[username]; ok {
+		return errors.New(registry.RegistryUserExists, "user already exists", nil)
+	}
+	m.users[username] = &regtypes.User{Username: username, IsActive: true, PasswordHash: password.Hash, SCRAMSecret: password.SCRAMSecret}
+	return nil
+}
+
+func (m *memoryUserStore) RoleExists(ctx context.Context, name string) bool {
+	return m.roles[name]
+}
+
+func (m *memoryUserStore) GrantRole(ctx context.Context, role, username string) error {
+	if m.members[username] == nil {
+		m.members[username] = make(map[string]bool)
+	}
+	m.members[username][role] = true
+	return nil
+}
+
+func (m *memoryUserStore) RevokeRole(ctx context.Context, role, username string) error {
+	delete(m.members[username], role)
+	return nil
+}
+
+func (m *memoryUserStore) ListUserRoles(ctx context.Context, username string) ([]string, error) {
+	var roles []string
+	for role := range m.members[username] {
+		roles = append(roles, role)
+	}
+	sort.Strings(roles)
+	return roles, nil
+}
+
+func (m *memoryUserStore) SetUserPassword(ctx context.Context, username string, password regtypes.UserPassword) error {
+	user := m.users[username]
+	user.PasswordHash, user.SCRAMSecret, user.PasswordExpiresAt = password.Hash, password.SCRAMSecret, password.ExpiresAt
+	return nil
+}
+
+func (m *memoryUserStore) RecordLoginFailure(ctx context.Context, username string, maxAttempts int, lockout time.Duration) (*time.Time, error) {
+	user, ok := m.users[username]
+	if !ok {
+		return nil, errors.New(registry.RegistryUserNotFound, "user not found", nil)
+	}
+	user.FailedLoginAttempts++
+	if maxAttempts <= 0 || user.FailedLoginAttempts < maxAttempts {
+		return nil, nil
+	}
+	until := time.Now().Add(lockout)
+	user.FailedLoginAttempts, user.LockedUntil = 0, &until
+	return &until, nil
+}
+
+func (m *memoryUserStore) RecordLoginSuccess(ctx context.Context, username string) error {
+	user := m.users[username]
+	user.FailedLoginAttempts, user.LockedUntil = 0, nil
+	return nil
+}
+
+func (m *memoryUserStore) CreateAuthToken(ctx context.Context, token *regtypes.AuthToken) error {
+	m.tokens[token.TokenHash] = token
+	return nil
+}
+
+func (m *memoryUserStore) GetAuthToken(ctx context.Context, tokenHash string) (*regtypes.AuthToken, error) {
+	token, ok := m.tokens[tokenHash]
+	if !ok {
+		return nil, errors.New(registry.RegistryTokenNotFound, "auth token not found", nil)
+	}
+	return token, nil
+}
+
+func (m *memoryUserStore) PruneAuthTokens(ctx context.Context, cutoff time.Time) (int64, error) {
+	var removed int64
+	for hash, token := range m.tokens {
+		if token.ExpiresAt.Before(cutoff) {
+			delete(m.tokens, hash)
+			removed++
+		}
+	}
+	return removed, nil
+}
+
+func (m *memoryUserStore) RevokeAuthToken(ctx context.Context, tokenHash string) error {
+	token, ok := m.tokens[tokenHash]
+	if !ok || token.RevokedAt != nil {
+		return errors.New(registry.RegistryTokenNotFound, "auth token not found", nil)
+	}
+	now := time.Now()
+	token.RevokedAt = &now
+	return nil
+}
+
+// testAuthConfig returns the default auth settings
+func testAuthConfig() config.AuthConfig {
+	return config.AuthConfig{
+		Provider:           config.AuthProviderRegistry,
+		PasswordHash:       config.PasswordHashArgon2id,
+		MaxFailedAttempts:  3,
+		LockoutMinutes:     15,
+		TokenTTLMinutes:    60,
+		AllowEmptyPassword: true,
+	}
+}
+
+// userWithPassword returns a user whose password is password
+func userWithPassword(t *testing.T, username, password string) *regtypes.User {
+	userPassword, err := NewUserPassword(password, testAuthConfig())
+	require.NoError(t, err)
+	return &regtypes.User{Username: username, PasswordHash: userPassword.Hash, SCRAMSecret: userPassword.SCRAMSecret}
+}
+
+// assertCode fails the test unless err carries code
+func assertCode(t *testing.T, code errors.Code, err error) {
+	t.Helper()
+	require.Error(t, err)
+	assert.Equal(t, code.String(), errors.GetCode(err), err.Error())
+}
+
+func TestPasswordHashes(t *testing.T) {
+	for _, algorithm := range []string{config.PasswordHashArgon2id, config.PasswordHashBcrypt} {
+		hash, err := HashPassword("s3cret", algorithm)
+		require.NoError(t, err)
+		assert.NotContains(t, hash, "s3cret")
+		assert.True(t, VerifyPasswordHash(hash, "s3cret"), algorithm)
+		assert.False(t, VerifyPasswordHash(hash, "wrong"), algorithm)
+	}
+
+	_, err := HashPassword("s3cret", "md5")
+	assertCode(t, ErrUnknownPasswordHash, err)
+	assert.False(t, VerifyPasswordHash("plaintext", "plaintext"))
+
+	userPassword, err := NewUserPassword("s3cret", config.AuthConfig{PasswordMaxAgeDays: 30})
+	require.NoError(t, err)
+	assert.True(t, VerifyPasswordHash(userPassword.SCRAMSecret, "s3cret"))
+	require.NotNil(t, userPassword.ExpiresAt)
+	assert.WithinDuration(t, time.Now().AddDate(0, 0, 30), *userPassword.ExpiresAt, time.Minute)
+}
+
+func TestRegistryAuthProviderAuthenticate(t *testing.T) {
+	ctx := context.Background()
+	store := newMemoryUserStore(
+		userWithPassword(t, "alice", "s3cret"),
+		&regtypes.User{Username: "guest"},
+		&regtypes.User{Username: "admin", IsAdmin: true},
+	)
+	provider := NewRegistryAuthProvider(store, testAuthConfig(), nil, zerolog.Nop())
+
+	result, err := provider.Authenticate(ctx, "alice", "s3cret", "default")
+	require.NoError(t, err)
+	assert.True(t, result.Authenticated)
+	assert.Len(t, result.Token, 64)
+	// Only the hash of the token is stored
+	assert.NotContains(t, store.tokens, result.Token)
+	assert.Contains(t, store.tokens, hashToken(result.Token))
+
+	_, err = provider.Authenticate(ctx, "nobody", "s3cret", "default")
+	assertCode(t, ErrUserNotFound, err)
+
+	// Passwordless users may log in when allowed, superusers never
+	_, err = provider.Authenticate(ctx, "guest", "", "default")
+	require.NoError(t, err)
+	_, err = provider.Authenticate(ctx, "admin", "", "default")
+	assertCode(t, ErrPasswordRequired, err)
+
+	// The password is checked before the expiry is reported
+	expired := time.Now().Add(-time.Hour)
+	store.users["alice"].PasswordExpiresAt = &expired
+	_, err = provider.Authenticate(ctx, "alice", "wrong", "default")
+	assertCode(t, ErrInvalidPassword, err)
+	_, err = provider.Authenticate(ctx, "alice", "s3cret", "default")
+	assertCode(t, ErrPasswordExpired, err)
+	store.users["alice"].PasswordExpiresAt = nil
+
+	store.users["guest"].IsActive = false
+	_, err = provider.Authenticate(ctx, "guest", "", "default")
+	assertCode(t, ErrAccountDisabled, err)
+}
+
+func TestRegistryAuthProviderLockout(t *testing.T) {
+	ctx := context.Background()
+	store := newMemoryUserStore(userWithPassword(t, "alice", "s3cret"))
+	provider := NewRegistryAuthProvider(store, testAuthConfig(), nil, zerolog.Nop())
+
+	// A success resets the count of failed attempts
+	_, err := provider.Authenticate(ctx, "alice", "wrong", "default")
+	assertCode(t, ErrInvalidPassword, err)
+	_, err = provider.Authenticate(ctx, "alice", "s3cret", "default")
+	require.NoError(t, err)
+	assert.Zero(t, store.users["alice"].FailedLoginAttempts)
+
+	_, err = provider.Authenticate(ctx, "alice", "wrong", "default")
+	assertCode(t, ErrInvalidPassword, err)
+	provider.LoginFailed(ctx, "alice")
+	_, err = provider.Authenticate(ctx, "alice", "wrong", "default")
+	assertCode(t, ErrAccountLocked, err)
+
+	// A locked account rejects even the right password
+	_, err = provider.Authenticate(ctx, "alice", "s3cret", "default")
+	assertCode(t, ErrAccountLocked, err)
+	credentials, err := provider.LookupCredentials(ctx, "alice")
+	require.NoError(t, err)
+	assert.True(t, credentials.Disabled)
+
+	past := time.Now().Add(-time.Second)
+	store.users["alice"].LockedUntil = &past
+	_, err = provider.Authenticate(ctx, "alice", "s3cret", "default")
+	require.NoError(t, err)
+}
+
+func TestRegistryAuthProviderTokens(t *testing.T) {
+	ctx := context.Background()
+	store := newMemoryUserStore(userWithPassword(t, "alice", "s3cret"))
+	provider := NewRegistryAuthProvider(store, testAuthConfig(), nil, zerolog.Nop())
+
+	result, err := provider.Authenticate(ctx, "alice", "s3cret", "default")
+	require.NoError(t, err)
+
+	validated, err := provider.ValidateToken(ctx, result.Token)
+	require.NoError(t, err)
+	assert.Equal(t, "alice", validated.Username)
+
+	_, err = provider.ValidateToken(ctx, "unknown")
+	assertCode(t, ErrInvalidToken, err)
+
+	// Refreshing revokes the old token
+	refreshed, err := provider.RefreshToken(ctx, result.Token)
+	require.NoError(t, err)
+	assert.NotEqual(t, result.Token, refreshed.Token)
+	_, err = provider.ValidateToken(ctx, result.Token)
+	assertCode(t, ErrTokenRevoked, err)
+
+	store.tokens[hashToken(refreshed.Token)].ExpiresAt = time.Now().Add(-time.Second)
+	_, err = provider.ValidateToken(ctx, refreshed.Token)
+	assertCode(t, ErrTokenExpired, err)
+
+	another, err := provider.Authenticate(ctx, "alice", "s3cret", "default")
+	require.NoError(t, err)
+	require.NoError(t, provider.RevokeToken(ctx, another.Token))
+	_, err = provider.ValidateToken(ctx, another.Token)
+	assertCode(t, ErrTokenRevoked, err)
+}
+
+func TestRegistryAuthProviderPrunesTokens(t *testing.T) {
+	ctx := context.Background()
+	store := newMemoryUserStore(userWithPassword(t, "alice", "s3cret"))
+	provider := NewRegistryAuthProvider(store, testAuthConfig(), nil, zerolog.Nop())
+
+	first, err := provider.Authenticate(ctx, "alice", "s3cret", "default")
+	require.NoError(t, err)
+	store.tokens[hashToken(first.Token)].ExpiresAt = time.Now().Add(-time.Second)
+
+	// Expired tokens outlive logins within the prune interval
+	_, err = provider.Authenticate(ctx, "alice", "s3cret", "default")
+	require.NoError(t, err)
+	assert.Len(t, store.tokens, 2)
+
+	// and are removed by the first login after it
+	provider.prunedAt = time.Now().Add(-tokenPruneInterval)
+	_, err = provider.Authenticate(ctx, "alice", "s3cret", "default")
+	require.NoError(t, err)
+	assert.Len(t, store.tokens, 2)
+	assert.NotContains(t, store.tokens, hashToken(first.Token))
+}
+
+func TestRegistryAuthProviderEnsurePassword(t *testing.T) {
+	ctx := context.Background()
+	store := newMemoryUserStore(&regtypes.User{Username: "admin", IsAdmin: true})
+	provider := NewRegistryAuthProvider(store, testAuthConfig(), nil, zerolog.Nop())
+
+	require.NoError(t, provider.EnsurePassword(ctx, "admin", "first"))
+	// An existing password is kept
+	require.NoError(t, provider.EnsurePassword(ctx, "admin", "second"))
+
+	_, err := provider.Authenticate(ctx, "admin", "first", "default")
+	require.NoError(t, err)
+	_, err = provider.Authenticate(ctx, "admin", "second", "default")
+	assertCode(t, ErrInvalidPassword, err)
+
+	credentials, err := provider.LookupCredentials(ctx, "admin")
+	require.NoError(t, err)
+	assert.False(t, credentials.Disabled)
+	require.NotNil(t, credentials.SCRAM)
+	assert.True(t, credentials.SCRAM.VerifyPassword("first"))
+}
@@ -121,6 +121,24 @@ func (provider *SimpleAuthProvider) Authenticate(ctx context.Context, username,
 	return result, nil
 }
 
+// LookupCredentials returns the stored credentials of a user for challenge-response authentication
+func (provider *SimpleAuthProvider) LookupCredentials(ctx context.Context, username string) (*Credentials, error) {
+	provider.mu.RLock()
+	user, exists := provider.users[username]
+	provider.mu.RUnlock()
+
+	if !exists {
+		return nil, errors.Newf(ErrUserNotFound, "user not found: %s", username)
+	}
+
+	return &Credentials{
+		Username:    user.Username,
+		Database:    user.Database,
+		Permissions: user.Permissions,
+		Password:    user.Password,
+	}, nil
+}
+
 // ValidateToken validates an existing authentication token
 func (provider *SimpleAuthProvider) ValidateToken(ctx context.Context, token string) (*AuthResult, error) {
 	// For simple provider, we'll just check if the token format is valid
@@ -1,16 +1,22 @@
 package signals
 
 import (
+	"bytes"
 	"fmt"
 
+	"github.com/apache/arrow-go/v18/arrow"
+	"github.com/apache/arrow-go/v18/arrow/array"
+	"github.com/apache/arrow-go/v18/arrow/ipc"
+	"github.com/apache/arrow-go/v18/arrow/util"
 	"github.com/gear6io/ranger/server/protocols/native/protocol"
 )
 
-// ClientData represents a client data message for batch insertion
+// ClientData represents a client data message for batch insertion. The rows travel as
+// columnar Arrow record batches in an IPC stream, so the server stores them without
+// converting each value; the server casts columns to the table types.
 type ClientData struct {
 	TableName string
-	Columns   []string
-	Rows      [][]interface{}
+	Records   []arrow.Record
 }
 
 // Type returns the signal type
@@ -20,9 +26,22 @@ func (d *ClientData) Type() protocol.SignalType {
 
 // Pack serializes the data message to bytes
 func (d *ClientData) Pack() ([]byte, error) {
-	// Calculate total size
-	size := d.Size()
-	buf := make([]byte, 0, size)
+	// Encode the records as an Arrow IPC stream
+	var stream bytes.Buffer
+	if len(d.Records) > 0 {
+		writer := ipc.NewWriter(&stream, ipc.WithSchema(d.Records[0].Schema()))
+		for _, record := range d.Records {
+			if err := writer.Write(record); err != nil {
+				writer.Close()
+				return nil, fmt.Errorf("failed to encode record batch: %w", err)
+			}
+		}
+		if err := writer.Close(); err != nil {
+			return nil, fmt.Errorf("failed to encode record batches: %w", err)
+		}
+	}
+
+	buf := make([]byte, 0, 4+len(d.TableName)+4+stream.Len())
 
 	// Pack table name (4 bytes length + string)
 	tableBytes := []byte(d.TableName)
@@ -31,56 +50,24 @@ func (d *ClientData) Pack() ([]byte, error) {
 	buf = append(buf, tableLenBytes...)
 	buf = append(buf, tableBytes...)
 
-	// Pack column count (4 bytes)
-	columnCount := uint32(len(d.Columns))
-	columnCountBytes := make([]byte, 4)
-	protocol.WriteUint32BigEndian(columnCountBytes, columnCount)
-	buf = append(buf, columnCountBytes...)
-
-	// Pack row count (4 bytes)
-	rowCount := uint32(len(d.Rows))
-	rowCountBytes := make([]byte, 4)
-	protocol.WriteUint32BigEndian(rowCountBytes, rowCount)
-	buf = append(buf, rowCountBytes...)
-
-	// Pack column names (4 bytes length + string for each column)
-	for _, col := range d.Columns {
-		colBytes := []byte(col)
-		colLenBytes := make([]byte, 4)
-		protocol.WriteUint32BigEndian(colLenBytes, uint32(len(colBytes)))
-		buf = append(buf, colLenBytes...)
-		buf = append(buf, colBytes...)
-	}
-
-	// Column types are not sent - Ranger knows the schema
-
-	// Pack row data (4 bytes length + string for each value)
-	for _, row := range d.Rows {
-		for _, value := range row {
-			strValue := fmt.Sprintf("%v", value)
-			valueBytes := []byte(strValue)
-			valueLenBytes := make([]byte, 4)
-			protocol.WriteUint32BigEndian(valueLenBytes, uint32(len(valueBytes)))
-			buf = append(buf, valueLenBytes...)
-			buf = append(buf, valueBytes...)
-		}
-	}
+	// Pack the IPC stream (4 bytes length + stream); column types travel in its schema
+	streamLenBytes := make([]byte, 4)
+	protocol.WriteUint32BigEndian(streamLenBytes, uint32(stream.Len()))
+	buf = append(buf, streamLenBytes...)
+	buf = append(buf, stream.Bytes()...)
 
 	return buf, nil
 }
 
 // Unpack deserializes the data message from bytes
 func (d *ClientData) Unpack(data []byte) error {
-	if len(data) < 12 { // minimum: 4 bytes table length + 4 bytes column count + 4 bytes row count
+	if len(data) < 8 { // minimum: 4 bytes table length + 4 bytes stream length
 		return fmt.Errorf("insufficient data for client data")
 	}
 
 	pos := 0
 
 	// Read table name length (4 bytes, big endian)
-	if pos+4 > len(data) {
-		return fmt.Errorf("insufficient data for table name length")
-	}
 	tableLen := protocol.ReadUint32BigEndian(data[pos:])
 	pos += 4
 
@@ -91,55 +78,36 @@ func (d *ClientData) Unpack(data []byte) error {
 	d.TableName = string(data[pos : pos+int(tableLen)])
 	pos += int(tableLen)
 
-	// Read column count (4 bytes, big endian)
+	// Read IPC stream length (4 bytes, big endian)
 	if pos+4 > len(data) {
-		return fmt.Errorf("insufficient data for column count")
+		return fmt.Errorf("insufficient data for record stream length")
 	}
-	columnCount := protocol.ReadUint32BigEndian(data[pos:])
+	streamLen := protocol.ReadUint32BigEndian(data[pos:])
 	pos += 4
 
-	// Read row count (4 bytes, big endian)
-	if pos+4 > len(data) {
-		return fmt.Errorf("insufficient data for row count")
+	if pos+int(streamLen) > len(data) {
+		return fmt.Errorf("insufficient data for record stream")
 	}
-	rowCount := protocol.ReadUint32BigEndian(data[pos:])
-	pos += 4
-
-	// Read column names
-	d.Columns = make([]string, columnCount)
-	for i := uint32(0); i < columnCount; i++ {
-		if pos+4 > len(data) {
-			return fmt.Errorf("insufficient data for column %d name length", i)
-		}
-		colLen := protocol.ReadUint32BigEndian(data[pos:])
-		pos += 4
-
-		if pos+int(colLen) > len(data) {
-			return fmt.Errorf("insufficient data for column %d name", i)
-		}
-		d.Columns[i] = string(data[pos : pos+int(colLen)])
-		pos += int(colLen)
+	d.Records = nil
+	if streamLen == 0 {
+		return nil
 	}
 
-	// Column types are not read - Ranger knows the schema
-
-	// Read row data
-	d.Rows = make([][]interface{}, rowCount)
-	for i := uint32(0); i < rowCount; i++ {
-		d.Rows[i] = make([]interface{}, columnCount)
-		for j := uint32(0); j < columnCount; j++ {
-			if pos+4 > len(data) {
-				return fmt.Errorf("insufficient data for row %d column %d value length", i, j)
-			}
-			valueLen := protocol.ReadUint32BigEndian(data[pos:])
-			pos += 4
+	// Read the record batches
+	reader, err := ipc.NewReader(bytes.NewReader(data[pos : pos+int(streamLen)]))
+	if err != nil {
+		return fmt.Errorf("failed to read record stream: %w", err)
+	}
+	defer reader.Release()
 
-			if pos+int(valueLen) > len(data) {
-				return fmt.Errorf("insufficient data for row %d column %d value", i, j)
-			}
-			d.Rows[i][j] = string(data[pos : pos+int(valueLen)])
-			pos += int(valueLen)
-		}
+	for reader.Next() {
+		record := reader.Record()
+		record.Retain()
+		d.Records = append(d.Records, record)
+	}
+	if err := reader.Err(); err != nil {
+		d.Release()
+		return fmt.Errorf("failed to read record batch: %w", err)
 	}
 
 	return nil
@@ -147,30 +115,46 @@ func (d *ClientData) Unpack(data []byte) error {
 
 // Size returns the estimated size of the packed message
 func (d *ClientData) Size() int {
-	size := 4 + len(d.TableName) + 4 + 4 // table name + column count + row count
+	size := 4 + len(d.TableName) + 4 // table name + stream length
 
-	// Add column names
-	for _, col := range d.Columns {
-		size += 4 + len(col)
+	// Add record batch buffers
+	for _, record := range d.Records {
+		size += int(util.TotalRecordSize(record))
 	}
 
-	// Add row data
-	for _, row := range d.Rows {
-		for _, value := range row {
-			strValue := fmt.Sprintf("%v", value)
-			size += 4 + len(strValue)
-		}
+	return size
+}
+
+// NumRows returns the number of rows in the message
+func (d *ClientData) NumRows() int64 {
+	var rows int64
+	for _, record := range d.Records {
+		rows += record.NumRows()
 	}
+	return rows
+}
 
-	return size
+// Reader returns a reader over the record batches of the message
+func (d *ClientData) Reader() (array.RecordReader, error) {
+	if len(d.Records) == 0 {
+		return array.NewRecordReader(arrow.NewSchema(nil, nil), nil)
+	}
+	return array.NewRecordReader(d.Records[0].Schema(), d.Records)
+}
+
+// Release releases the record batches of the message
+func (d *ClientData) Release() {
+	for _, record := range d.Records {
+		record.Release()
+	}
+	d.Records = nil
 }
 
 // NewClientData creates a new client data message
-func NewClientData(tableName string, columns []string, rows [][]interface{}) *ClientData {
+func NewClientData(tableName string, records ...arrow.Record) *ClientData {
 	return &ClientData{
 		TableName: tableName,
-		Columns:   columns,
-		Rows:      rows,
+		Records:   records,
 	}
 }
 
@@ -1,21 +1,28 @@
 package signals
 
 import (
-	"fmt"
 	"testing"
 
+	"github.com/apache/arrow-go/v18/arrow"
+	"github.com/apache/arrow-go/v18/arrow/array"
+	"github.com/apache/arrow-go/v18/arrow/memory"
 	"github.com/gear6io/ranger/server/protocols/native/protocol"
 )
 
 func TestClientData(t *testing.T) {
 	// Test creating a new client data message
-	columns := []string{"id", "name", "email"}
-	rows := [][]interface{}{
-		{1, "John Doe", "john@example.com"},
-		{2, "Jane Smith", "jane@example.com"},
-	}
+	schema := arrow.NewSchema([]arrow.Field{
+		{Name: "id", Type: arrow.PrimitiveTypes.Int64},
+		{Name: "name", Type: arrow.BinaryTypes.String, Nullable: true},
+	}, nil)
+	builder := array.NewRecordBuilder(memory.DefaultAllocator, schema)
+	defer builder.Release()
+	builder.Field(0).(*array.Int64Builder).AppendValues([]int64{1, 2}, nil)
+	builder.Field(1).(*array.StringBuilder).AppendValues([]string{"John Doe", ""}, []bool{true, false})
+	record := builder.NewRecord()
+	defer record.Release()
 
-	data := NewClientData("users", columns, rows)
+	data := NewClientData("users", record)
 
 	// Test Type method
 	if data.Type() != protocol.ClientData {
@@ -23,19 +30,8 @@ func TestClientData(t *testing.T) {
 	}
 
 	// Test Size method
-	expectedSize := 4 + len("users") + 4 + 4 // table name + column count + row count
-	for _, col := range columns {
-		expectedSize += 4 + len(col)
-	}
-	for _, row := range rows {
-		for _, value := range row {
-			strValue := fmt.Sprintf("%v", value)
-			expectedSize += 4 + len(strValue)
-		}
-	}
-
-	if data.Size() != expectedSize {
-		t.Errorf("Expected Size() to return %d, got %d", expectedSize, data.Size())
+	if data.Size() <= 4+len("users")+4 {
+		t.Errorf("Expected Size() to include the record batch, got %d", data.Size())
 	}
 
 	// Test Pack method
@@ -54,32 +50,50 @@ func TestClientData(t *testing.T) {
 	if err != nil {
 		t.Fatalf("Unpack() failed: %v", err)
 	}
+	defer newData.Release()
 
 	// Verify all fields were unpacked correctly
 	if newData.TableName != data.TableName {
 		t.Errorf("TableName mismatch: expected %s, got %s", data.TableName, newData.TableName)
 	}
-	if len(newData.Columns) != len(data.Columns) {
-		t.Errorf("Columns count mismatch: expected %d, got %d", len(data.Columns), len(newData.Columns))
+	if newData.NumRows() != data.NumRows() {
+		t.Errorf("Rows count mismatch: expected %d, got %d", data.NumRows(), newData.NumRows())
 	}
-	if len(newData.Rows) != len(data.Rows) {
-		t.Errorf("Rows count mismatch: expected %d, got %d", len(data.Rows), len(newData.Rows))
+	if len(newData.Records) != 1 {
+		t.Fatalf("Expected 1 record batch, got %d", len(newData.Records))
 	}
 
-	// Verify column names
-	for i, col := range data.Columns {
-		if newData.Columns[i] != col {
-			t.Errorf("Column %d name mismatch: expected %s, got %s", i, col, newData.Columns[i])
-		}
+	// Verify the columns keep their types and values
+	if !newData.Records[0].Schema().Equal(schema) {
+		t.Errorf("Schema mismatch: expected %s, got %s", schema, newData.Records[0].Schema())
+	}
+	if !array.RecordEqual(newData.Records[0], record) {
+		t.Errorf("Record mismatch: expected %v, got %v", record, newData.Records[0])
 	}
 
-	// Verify row data
-	for i, row := range data.Rows {
-		for j, value := range row {
-			if fmt.Sprintf("%v", newData.Rows[i][j]) != fmt.Sprintf("%v", value) {
-				t.Errorf("Row %d column %d value mismatch: expected %v, got %v", i, j, value, newData.Rows[i][j])
-			}
-		}
+	// Test Reader method
+	reader, err := newData.Reader()
+	if err != nil {
+		t.Fatalf("Reader() failed: %v", err)
+	}
+	defer reader.Release()
+	if !reader.Next() || reader.Record().NumRows() != 2 {
+		t.Error("Reader() did not return the record batch")
+	}
+}
+
+func TestClientDataEmpty(t *testing.T) {
+	packed, err := NewClientData("users").Pack()
+	if err != nil {
+		t.Fatalf("Pack() failed: %v", err)
+	}
+
+	data := &ClientData{}
+	if err := data.Unpack(packed); err != nil {
+		t.Fatalf("Unpack() failed: %v", err)
+	}
+	if data.TableName != "users" || data.NumRows() != 0 {
+		t.Errorf("Expected an empty message for users, got %s with %d rows", data.TableName, data.NumRows())
 	}
 }
 
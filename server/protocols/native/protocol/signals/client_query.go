@@ -13,6 +13,10 @@ type ClientQuery struct {
 	Database string
 	User     string
 	Password string
+
+	// TraceParent is the W3C traceparent of the client's span. It is packed after the
+	// other fields only when set, so servers that do not read it ignore it.
+	TraceParent string
 }
 
 // Type returns the signal type
@@ -61,6 +65,15 @@ func (q *ClientQuery) Pack() ([]byte, error) {
 	buf = append(buf, pwdLenBytes...)
 	buf = append(buf, pwdBytes...)
 
+	// Pack trace parent (4 bytes length + string), only when set
+	if q.TraceParent != "" {
+		traceBytes := []byte(q.TraceParent)
+		traceLenBytes := make([]byte, 4)
+		protocol.WriteUint32BigEndian(traceLenBytes, uint32(len(traceBytes)))
+		buf = append(buf, traceLenBytes...)
+		buf = append(buf, traceBytes...)
+	}
+
 	return buf, nil
 }
 
@@ -140,6 +153,24 @@ func (q *ClientQuery) Unpack(data []byte) error {
 		return fmt.Errorf("insufficient data for password")
 	}
 	q.Password = string(data[pos : pos+int(pwdLen)])
+	pos += int(pwdLen)
+
+	// Read the trace parent sent by clients that trace their queries
+	q.TraceParent = ""
+	if pos == len(data) {
+		return nil
+	}
+	if pos+4 > len(data) {
+		return fmt.Errorf("insufficient data for trace parent length")
+	}
+	traceLen := protocol.ReadUint32BigEndian(data[pos:])
+	pos += 4
+
+	// Read trace parent
+	if pos+int(traceLen) > len(data) {
+		return fmt.Errorf("insufficient data for trace parent")
+	}
+	q.TraceParent = string(data[pos : pos+int(traceLen)])
 
 	return nil
 }
@@ -147,7 +178,11 @@ func (q *ClientQuery) Unpack(data []byte) error {
 // Size returns the estimated size of the packed message
 func (q *ClientQuery) Size() int {
 	// 4 bytes per length + string lengths
-	return 4 + len(q.Query) + 4 + len(q.QueryID) + 4 + len(q.Database) + 4 + len(q.User) + 4 + len(q.Password)
+	size := 4 + len(q.Query) + 4 + len(q.QueryID) + 4 + len(q.Database) + 4 + len(q.User) + 4 + len(q.Password)
+	if q.TraceParent != "" {
+		size += 4 + len(q.TraceParent)
+	}
+	return size
 }
 
 // NewClientQuery creates a new client query message
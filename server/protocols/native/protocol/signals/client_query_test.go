@@ -56,6 +56,43 @@ func TestClientQuery(t *testing.T) {
 	}
 }
 
+func TestClientQueryTraceParent(t *testing.T) {
+	query := NewClientQuery("SELECT 1", "query123", "testdb", "testuser", "testpass")
+	withoutTrace, err := query.Pack()
+	if err != nil {
+		t.Fatalf("Pack() failed: %v", err)
+	}
+
+	query.TraceParent = "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+	packed, err := query.Pack()
+	if err != nil {
+		t.Fatalf("Pack() failed: %v", err)
+	}
+	if len(packed) != query.Size() || len(packed) != len(withoutTrace)+4+len(query.TraceParent) {
+		t.Errorf("Expected the trace parent to be appended, got %d bytes", len(packed))
+	}
+
+	newQuery := &ClientQuery{}
+	if err := newQuery.Unpack(packed); err != nil {
+		t.Fatalf("Unpack() failed: %v", err)
+	}
+	if newQuery.TraceParent != query.TraceParent || newQuery.Password != query.Password {
+		t.Errorf("TraceParent mismatch: expected %s, got %s", query.TraceParent, newQuery.TraceParent)
+	}
+
+	// Queries of clients that do not trace carry no trace parent
+	if err := newQuery.Unpack(withoutTrace); err != nil {
+		t.Fatalf("Unpack() failed: %v", err)
+	}
+	if newQuery.TraceParent != "" {
+		t.Errorf("Expected no trace parent, got %s", newQuery.TraceParent)
+	}
+
+	if err := newQuery.Unpack(packed[:len(packed)-1]); err == nil {
+		t.Error("Expected error when unpacking a truncated trace parent")
+	}
+}
+
 func TestClientQueryUnpackEmpty(t *testing.T) {
 	query := &ClientQuery{}
 	err := query.Unpack([]byte{})
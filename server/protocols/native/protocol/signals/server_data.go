@@ -1,8 +1,10 @@
 package signals
 
 import (
+	"encoding/binary"
+	"encoding/json"
 	"fmt"
-	"strings"
+	"reflect"
 
 	"github.com/gear6io/ranger/server/protocols/native/protocol"
 )
@@ -79,28 +81,31 @@ func (d *ServerData) Pack() ([]byte, error) {
 	}
 	buf = append(buf, byte(rowCount))
 
-	// Pack row data for each column
+	// Pack row data for each column: every value is a uvarint of its length plus one
+	// (zero for NULL) followed by its text, so values may hold any character
 	for colIdx := range d.Columns {
-		// Collect all values for this column
-		var columnValues []string
+		var columnData []byte
 		for _, row := range d.Rows {
+			var value interface{}
 			if colIdx < len(row) {
-				columnValues = append(columnValues, fmt.Sprintf("%v", row[colIdx]))
-			} else {
-				columnValues = append(columnValues, "")
+				value = row[colIdx]
 			}
+			if value == nil {
+				columnData = binary.AppendUvarint(columnData, 0)
+				continue
+			}
+			text := formatValue(value)
+			columnData = binary.AppendUvarint(columnData, uint64(len(text))+1)
+			columnData = append(columnData, text...)
 		}
 
-		// Join values with comma and pack as single string
-		columnData := strings.Join(columnValues, ",")
-		dataBytes := []byte(columnData)
-		dataLen := uint64(len(dataBytes))
+		dataLen := uint64(len(columnData))
 		for dataLen >= 0x80 {
 			buf = append(buf, byte(dataLen)|0x80)
 			dataLen >>= 7
 		}
 		buf = append(buf, byte(dataLen))
-		buf = append(buf, dataBytes...)
+		buf = append(buf, columnData...)
 	}
 
 	return buf, nil
@@ -187,15 +192,24 @@ func (d *ServerData) Unpack(data []byte) error {
 		if pos+int(dataLen) > len(data) {
 			return fmt.Errorf("insufficient data for column %d data", colIdx)
 		}
-		columnData := string(data[pos : pos+int(dataLen)])
+		columnData := data[pos : pos+int(dataLen)]
 		pos += int(dataLen)
 
-		// Parse comma-separated values
-		values := strings.Split(columnData, ",")
-		for rowIdx, value := range values {
-			if rowIdx < int(d.RowCount) {
-				d.Rows[rowIdx][colIdx] = strings.TrimSpace(value)
+		// Read the value of each row; NULL values are left nil
+		for rowIdx := 0; rowIdx < int(d.RowCount); rowIdx++ {
+			valueLen, bytesRead := d.readUvarint(columnData)
+			if bytesRead == 0 {
+				return fmt.Errorf("failed to read column %d row %d length", colIdx, rowIdx)
+			}
+			columnData = columnData[bytesRead:]
+			if valueLen == 0 {
+				continue
 			}
+			if valueLen-1 > uint64(len(columnData)) {
+				return fmt.Errorf("insufficient data for column %d row %d", colIdx, rowIdx)
+			}
+			d.Rows[rowIdx][colIdx] = string(columnData[:valueLen-1])
+			columnData = columnData[valueLen-1:]
 		}
 	}
 
@@ -220,14 +234,55 @@ func (d *ServerData) Size() int {
 	// Row data
 	for _, row := range d.Rows {
 		for _, value := range row {
-			strValue := fmt.Sprintf("%v", value)
-			size += 8 + len(strValue)
+			size += 8 + len(formatValue(value))
 		}
 	}
 
 	return size
 }
 
+// formatValue renders a value as text. Lists, maps and structs are rendered as JSON, with
+// map keys as text, so clients can decode them back into nested values.
+func formatValue(value interface{}) string {
+	switch v := value.(type) {
+	case string:
+		return v
+	case []byte:
+		return string(v)
+	}
+	switch reflect.ValueOf(value).Kind() {
+	case reflect.Slice, reflect.Map:
+		if data, err := json.Marshal(jsonCompatible(value)); err == nil {
+			return string(data)
+		}
+	}
+	return fmt.Sprintf("%v", value)
+}
+
+// jsonCompatible converts maps of any key type to maps keyed by the text of each key, as
+// JSON objects need
+func jsonCompatible(value interface{}) interface{} {
+	switch v := reflect.ValueOf(value); v.Kind() {
+	case reflect.Map:
+		converted := make(map[string]interface{}, v.Len())
+		iter := v.MapRange()
+		for iter.Next() {
+			converted[fmt.Sprint(iter.Key().Interface())] = jsonCompatible(iter.Value().Interface())
+		}
+		return converted
+	case reflect.Slice:
+		if data, ok := value.([]byte); ok {
+			return string(data)
+		}
+		converted := make([]interface{}, v.Len())
+		for i := range converted {
+			converted[i] = jsonCompatible(v.Index(i).Interface())
+		}
+		return converted
+	}
+	return value
+}
+
 // readUvarint reads a variable-length integer from the beginning of data
 func (d *ServerData) readUvarint(data []byte) (uint64, int) {
 	var value uint64
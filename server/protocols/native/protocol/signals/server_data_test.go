@@ -109,3 +109,40 @@ func TestServerDataUnpackInsufficient(t *testing.T) {
 		t.Error("Expected error when unpacking insufficient data")
 	}
 }
+
+func TestServerDataNestedValues(t *testing.T) {
+	columns := []Column{
+		{Name: "name", Type: "VARCHAR"},
+		{Name: "tags", Type: "VARCHAR[]"},
+		{Name: "attrs", Type: "MAP(INTEGER, VARCHAR)"},
+	}
+	rows := [][]interface{}{
+		{"a, b", []interface{}{"x", nil}, map[interface{}]interface{}{int32(1): "one"}},
+		{nil, []interface{}{}, map[string]interface{}{"point": map[string]interface{}{"x": 1}}},
+	}
+
+	packed, err := NewServerData(columns, rows).Pack()
+	if err != nil {
+		t.Fatalf("Pack() failed: %v", err)
+	}
+	unpacked := &ServerData{}
+	if err := unpacked.Unpack(packed); err != nil {
+		t.Fatalf("Unpack() failed: %v", err)
+	}
+
+	// Commas survive, NULLs stay NULL and nested values arrive as JSON
+	expected := [][]interface{}{
+		{"a, b", `["x",null]`, `{"1":"one"}`},
+		{nil, `[]`, `{"point":{"x":1}}`},
+	}
+	if len(unpacked.Rows) != len(expected) {
+		t.Fatalf("Rows count mismatch: expected %d, got %d", len(expected), len(unpacked.Rows))
+	}
+	for i, row := range expected {
+		for j, value := range row {
+			if unpacked.Rows[i][j] != value {
+				t.Errorf("Row %d column %d value mismatch: expected %v, got %v", i, j, value, unpacked.Rows[i][j])
+			}
+		}
+	}
+}
@@ -2,6 +2,7 @@ package native
 
 import (
 	"context"
+	"crypto/tls"
 	"fmt"
 	"net"
 	"sync"
@@ -28,10 +29,14 @@ type Server struct {
 	connectionPool  *middleware.ConnectionPool
 	authMiddleware  *middleware.AuthMiddleware
 	circuitBreaker  *middleware.CircuitBreaker
+
+	// tlsManager wraps the listener in TLS when set
+	tlsManager *config.TLSManager
 }
 
-// NewServer creates a new native server instance
-func NewServer(queryEngine *query.Engine, logger zerolog.Logger) (*Server, error) {
+// NewServer creates a new native server instance authenticating against authProvider;
+// tlsManager may be nil to accept plain TCP connections
+func NewServer(queryEngine *query.Engine, authProvider middleware.AuthProvider, tlsManager *config.TLSManager, logger zerolog.Logger) (*Server, error) {
 	ctx, cancel := context.WithCancel(context.Background())
 
 	// Create middleware components
@@ -42,12 +47,6 @@ func NewServer(queryEngine *query.Engine, logger zerolog.Logger) (*Server, error
 		logger,
 	)
 
-	// Create simple auth provider
-	authProvider := middleware.NewSimpleAuthProvider(
-		1*time.Hour, // token TTL
-		logger,
-	)
-
 	authMiddleware := middleware.NewAuthMiddleware(
 		authProvider,
 		true,           // enabled
@@ -89,7 +88,9 @@ func NewServer(queryEngine *query.Engine, logger zerolog.Logger) (*Server, error
 		connectionPool:  connectionPool,
 		authMiddleware:  authMiddleware,
 		circuitBreaker:  circuitBreaker,
+		tlsManager:      tlsManager,
 	}
+	server.registerMetrics()
 
 	return server, nil
 }
@@ -104,12 +105,15 @@ func (s *Server) Start(ctx context.Context) error {
 	// Use fixed port and address from config constants
 	port := config.NATIVE_SERVER_PORT
 	addr := fmt.Sprintf("%s:%d", config.DEFAULT_SERVER_ADDRESS, port)
-	s.logger.Info().Str("address", addr).Msg("Starting native protocol server")
+	s.logger.Info().Str("address", addr).Bool("tls", s.tlsManager != nil).Msg("Starting native protocol server")
 
 	listener, err := net.Listen("tcp", addr)
 	if err != nil {
 		return errors.New(ErrServerListenFailed, "failed to listen on address", err).AddContext("address", addr)
 	}
+	if s.tlsManager != nil {
+		listener = tls.NewListener(listener, s.tlsManager.ServerConfig())
+	}
 	s.server = listener
 
 	// Start accepting connections
@@ -197,7 +201,7 @@ func (s *Server) handleConnection(conn net.Conn) {
 
 	// Create a new connection handler with the QueryEngine and middleware chain
 	// Note: idleTimeout is set to 0 (no timeout) - client must specify timeout in connection options
-	handler := NewConnectionHandler(conn, s.queryEngine, s.logger, s.middlewareChain, s.ctx)
+	handler := NewConnectionHandler(conn, s.queryEngine, s.logger, s.middlewareChain, s.authMiddleware, s.ctx)
 
 	// Handle the connection
 	if err := handler.Handle(); err != nil {
@@ -213,6 +217,7 @@ func (s *Server) GetStatus() map[string]interface{} {
 		"enabled": config.NATIVE_SERVER_ENABLED,
 		"address": config.DEFAULT_SERVER_ADDRESS,
 		"port":    config.NATIVE_SERVER_PORT,
+		"tls":     s.tlsManager != nil,
 	}
 
 	// Add middleware statistics
@@ -0,0 +1,205 @@
+package access
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/gear6io/ranger/pkg/errors"
+	"github.com/gear6io/ranger/server/metadata/registry"
+	"github.com/gear6io/ranger/server/metadata/registry/regtypes"
+	"github.com/gear6io/ranger/server/query/parser"
+)
+
+// PrivilegeStore reads users, role memberships and privileges from the registry
+type PrivilegeStore interface {
+	GetUser(ctx context.Context, username string) (*regtypes.User, error)
+	ListUserRoles(ctx context.Context, username string) ([]string, error)
+	ListPrivileges(ctx context.Context, granteeType, grantee string) ([]*regtypes.Privilege, error)
+}
+
+// Checker authorizes parsed statements against the privileges stored in the registry
+type Checker struct {
+	store PrivilegeStore
+}
+
+// NewChecker creates a new checker backed by store
+func NewChecker(store PrivilegeStore) *Checker {
+	return &Checker{store: store}
+}
+
+// Grants are the effective privileges of a user: its own and those of its roles
+type Grants struct {
+	Username   string
+	Superuser  bool
+	Roles      []string
+	Privileges []*regtypes.Privilege
+}
+
+// EffectivePrivileges returns the privileges username holds directly and through its roles.
+// Users without an active registry record hold none.
+func (c *Checker) EffectivePrivileges(ctx context.Context, username string) (*Grants, error) {
+	grants := &Grants{Username: username}
+
+	user, err := c.store.GetUser(ctx, username)
+	if err != nil {
+		if errors.GetCode(err) == registry.RegistryUserNotFound.String() {
+			return grants, nil
+		}
+		return nil, errors.New(ErrPrivilegeLookupFailed, "failed to look up user", err).AddContext("user", username)
+	}
+	if !user.IsActive {
+		return grants, nil
+	}
+	grants.Superuser = user.IsAdmin
+
+	privileges, err := c.store.ListPrivileges(ctx, regtypes.GranteeTypeUser, user.Username)
+	if err != nil {
+		return nil, errors.New(ErrPrivilegeLookupFailed, "failed to list user privileges", err).AddContext("user", username)
+	}
+	grants.Privileges = privileges
+
+	grants.Roles, err = c.store.ListUserRoles(ctx, user.Username)
+	if err != nil {
+		return nil, errors.New(ErrPrivilegeLookupFailed, "failed to list user roles", err).AddContext("user", username)
+	}
+	for _, role := range grants.Roles {
+		privileges, err := c.store.ListPrivileges(ctx, regtypes.GranteeTypeRole, role)
+		if err != nil {
+			return nil, errors.New(ErrPrivilegeLookupFailed, "failed to list role privileges", err).AddContext("role", role)
+		}
+		grants.Privileges = append(grants.Privileges, privileges...)
+	}
+
+	return grants, nil
+}
+
+// Check returns an error unless username may run stmt in database
+func (c *Checker) Check(ctx context.Context, username string, stmt parser.Statement, database string) error {
+	grants, err := c.EffectivePrivileges(ctx, username)
+	if err != nil {
+		return err
+	}
+	return grants.Check(stmt, database)
+}
+
+// Authorize returns an error unless username holds every requirement
+func (c *Checker) Authorize(ctx context.Context, username string, requirements ...Requirement) error {
+	grants, err := c.EffectivePrivileges(ctx, username)
+	if err != nil {
+		return err
+	}
+	return grants.Authorize(requirements...)
+}
+
+// Check returns an error unless the grants allow running stmt in database
+func (g *Grants) Check(stmt parser.Statement, database string) error {
+	var requirements []Requirement
+	switch stmt := stmt.(type) {
+	case *parser.AlterUserStmt:
+		// Users may change their own password
+		if stmt.SetType == parser.ALTER_USER_SET_PASSWORD && stmt.Username != nil && stmt.Username.Value == g.Username {
+			return nil
+		}
+		requirements = Requirements(stmt, database)
+	case *parser.ShowStmt:
+		// Users may list their own grants
+		if stmt.ShowType == parser.SHOW_GRANTS && stmt.For != nil && stmt.For.Value != g.Username {
+			requirements = []Requirement{{Action: ActionSuperuser, Database: regtypes.PrivilegeWildcard, Table: regtypes.PrivilegeWildcard}}
+		} else {
+			requirements = Requirements(stmt, database)
+		}
+	default:
+		requirements = Requirements(stmt, database)
+	}
+
+	return g.Authorize(requirements...)
+}
+
+// Authorize returns an error for the first requirement the grants do not cover
+func (g *Grants) Authorize(requirements ...Requirement) error {
+	for _, requirement := range requirements {
+		if g.Allows(requirement) {
+			continue
+		}
+		if requirement.Action == ActionSuperuser {
+			return errors.New(ErrSuperuserRequired, "permission denied: only superusers may run this statement", nil).
+				AddContext("user", g.Username)
+		}
+
+		object := requirement.Object()
+		if len(requirement.Columns) > 0 {
+			object = fmt.Sprintf("%s (%s)", object, strings.Join(requirement.Columns, ", "))
+		}
+		return errors.New(ErrPermissionDenied, fmt.Sprintf("permission denied: user %s lacks %s on %s", g.Username, requirement.Action, object), nil).
+			AddContext("user", g.Username).
+			AddContext("action", string(requirement.Action)).
+			AddContext("object", requirement.Object())
+	}
+	return nil
+}
+
+// Allows reports whether the grants cover requirement. A column requirement is
+// covered by a table privilege or by column privileges on every listed column, and
+// an AnyColumn requirement by any privilege on the table.
+func (g *Grants) Allows(requirement Requirement) bool {
+	if g.Superuser {
+		return true
+	}
+	if requirement.Action == ActionSuperuser {
+		return false
+	}
+
+	missing := make(map[string]bool, len(requirement.Columns))
+	for _, column := range requirement.Columns {
+		missing[strings.ToLower(column)] = true
+	}
+
+	for _, privilege := range g.Privileges {
+		if !actionMatches(privilege.Action, requirement.Action) ||
+			!nameMatches(privilege.Database, requirement.Database) ||
+			!nameMatches(privilege.Table, requirement.Table) {
+			continue
+		}
+		if privilege.Column == "" || requirement.AnyColumn {
+			return true
+		}
+		delete(missing, strings.ToLower(privilege.Column))
+	}
+
+	return len(requirement.Columns) > 0 && len(missing) == 0
+}
+
+// Visible reports whether the grants cover any privilege on a database (table and column
+// empty), on any part of a table (column empty) or on a column. It decides which objects the
+// catalog views show.
+func (g *Grants) Visible(database, table, column string) bool {
+	if g.Superuser {
+		return true
+	}
+	for _, privilege := range g.Privileges {
+		if !nameMatches(privilege.Database, database) {
+			continue
+		}
+		if table == "" {
+			return true
+		}
+		if !nameMatches(privilege.Table, table) {
+			continue
+		}
+		if column == "" || privilege.Column == "" || strings.EqualFold(privilege.Column, column) {
+			return true
+		}
+	}
+	return false
+}
+
+// actionMatches reports whether a granted action covers the required one
+func actionMatches(granted string, required parser.PrivilegeAction) bool {
+	return granted == string(parser.PRIV_ALL) || strings.EqualFold(granted, string(required))
+}
+
+// nameMatches reports whether a granted database or table name covers the required one
+func nameMatches(granted, required string) bool {
+	return granted == regtypes.PrivilegeWildcard || strings.EqualFold(granted, required)
+}
@@ -0,0 +1,192 @@
+package access
+
+import (
+	"context"
+	"testing"
+
+	"github.com/gear6io/ranger/pkg/errors"
+	"github.com/gear6io/ranger/server/metadata/registry"
+	"github.com/gear6io/ranger/server/metadata/registry/regtypes"
+	"github.com/gear6io/ranger/server/query/parser"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeStore serves users, roles and privileges from memory
+type fakeStore struct {
+	users      map[string]*regtypes.User
+	roles      map[string][]string
+	privileges []*regtypes.Privilege
+}
+
+func (f *fakeStore) GetUser(ctx context.Context, username string) (*regtypes.User, error) {
+	if user, ok := f.users[username]; ok {
+		return user, nil
+	}
+	return nil, errors.New(registry.RegistryUserNotFound, "user not found", nil)
+}
+
+func (f *fakeStore) ListUserRoles(ctx context.Context, username string) ([]string, error) {
+	return f.roles[username], nil
+}
+
+func (f *fakeStore) ListPrivileges(ctx context.Context, granteeType, grantee string) ([]*regtypes.Privilege, error) {
+	var privileges []*regtypes.Privilege
+	for _, privilege := range f.privileges {
+		if privilege.GranteeType == granteeType && privilege.Grantee == grantee {
+			privileges = append(privileges, privilege)
+		}
+	}
+	return privileges, nil
+}
+
+func newFakeStore() *fakeStore {
+	store := &fakeStore{
+		users: map[string]*regtypes.User{
+			"admin":    {Username: "admin", IsActive: true, IsAdmin: true},
+			"alice":    {Username: "alice", IsActive: true},
+			"mallory":  {Username: "mallory", IsActive: false},
+			"readonly": {Username: "readonly", IsActive: true},
+		},
+		roles: map[string][]string{"alice": {"analyst"}},
+	}
+
+	grant := func(granteeType, grantee, object string, actions []parser.PrivilegeAction, columns ...string) {
+		definition := &parser.PrivilegeDefinition{Actions: actions, Object: &parser.Identifier{Value: object}}
+		for _, column := range columns {
+			definition.Columns = append(definition.Columns, &parser.Identifier{Value: column})
+		}
+		privileges, err := PrivilegesFromDefinition(definition, granteeType, grantee, "admin")
+		if err != nil {
+			panic(err)
+		}
+		store.privileges = append(store.privileges, privileges...)
+	}
+	grant(regtypes.GranteeTypeUser, "readonly", "*.*", []parser.PrivilegeAction{parser.PRIV_SELECT})
+	grant(regtypes.GranteeTypeUser, "alice", "sales.orders", []parser.PrivilegeAction{parser.PRIV_SELECT, parser.PRIV_INSERT})
+	grant(regtypes.GranteeTypeUser, "alice", "sales.customers", []parser.PrivilegeAction{parser.PRIV_SELECT, parser.PRIV_UPDATE}, "id", "name")
+	grant(regtypes.GranteeTypeRole, "analyst", "analytics.*", []parser.PrivilegeAction{parser.PRIV_ALL})
+	grant(regtypes.GranteeTypeUser, "mallory", "*.*", []parser.PrivilegeAction{parser.PRIV_ALL})
+	return store
+}
+
+func TestCheck(t *testing.T) {
+	checker := NewChecker(newFakeStore())
+	ctx := context.Background()
+
+	tests := []struct {
+		name     string
+		user     string
+		query    string
+		database string
+		code     string
+	}{
+		{"SuperuserRunsAnything", "admin", "DROP TABLE sales.orders;", "default", ""},
+		{"SelectGrantedTable", "alice", "SELECT * FROM orders;", "sales", ""},
+		{"SelectUngrantedTable", "alice", "SELECT * FROM sales.secrets;", "default", ErrPermissionDenied.String()},
+		{"SelectGrantedColumns", "alice", "SELECT id, name FROM sales.customers WHERE id = 1;", "default", ""},
+		{"SelectUngrantedColumn", "alice", "SELECT email FROM sales.customers;", "default", ErrPermissionDenied.String()},
+		{"WildcardNeedsTablePrivilege", "alice", "SELECT * FROM sales.customers;", "default", ErrPermissionDenied.String()},
+		{"SubqueryChecked", "alice", "SELECT id FROM sales.orders WHERE id IN (SELECT id FROM sales.secrets);", "default", ErrPermissionDenied.String()},
+		{"FromSubqueryNeedsSuperuser", "alice", "SELECT * FROM (SELECT * FROM sales.secrets) x;", "default", ErrSuperuserRequired.String()},
+		{"SelectWithoutFrom", "nobody", "SELECT 1;", "default", ""},
+		{"InsertGranted", "alice", "INSERT INTO sales.orders (id) VALUES (1);", "default", ""},
+		{"DeleteNotGranted", "alice", "DELETE FROM sales.orders WHERE id = 1;", "default", ErrPermissionDenied.String()},
+		{"UpdateGrantedColumn", "alice", "UPDATE sales.customers SET name = 'x' WHERE id = 1;", "default", ""},
+		{"UpdateFilterNeedsSelect", "alice", "UPDATE sales.customers SET name = 'x' WHERE email = 'y';", "default", ErrPermissionDenied.String()},
+		{"RolePrivilege", "alice", "CREATE TABLE analytics.events (id int32) STORAGE MEMORY;", "default", ""},
+		{"CreateDatabaseNeedsGlobalCreate", "alice", "CREATE DATABASE scratch;", "default", ErrPermissionDenied.String()},
+		{"ReadonlyCannotWrite", "readonly", "INSERT INTO t (a) VALUES (1);", "default", ErrPermissionDenied.String()},
+		{"ReadonlyReadsAnything", "readonly", "SELECT a FROM anywhere.t;", "default", ""},
+		{"InactiveUserHoldsNothing", "mallory", "SELECT a FROM t;", "default", ErrPermissionDenied.String()},
+		{"UnknownUserHoldsNothing", "nobody", "SELECT a FROM t;", "default", ErrPermissionDenied.String()},
+		{"ShowDatabasesUnchecked", "nobody", "SHOW DATABASES;", "default", ""},
+		{"GrantNeedsSuperuser", "alice", "GRANT SELECT ON sales.* TO alice;", "default", ErrSuperuserRequired.String()},
+		{"OwnGrants", "alice", "SHOW GRANTS FOR alice;", "default", ""},
+		{"OthersGrants", "alice", "SHOW GRANTS FOR admin;", "default", ErrSuperuserRequired.String()},
+		{"OwnPassword", "alice", "ALTER USER alice SET PASSWORD 'secret';", "default", ""},
+		{"OthersPassword", "alice", "ALTER USER admin SET PASSWORD 'secret';", "default", ErrSuperuserRequired.String()},
+		{"AccessLogNeedsSuperuser", "readonly", "SELECT action FROM system.access_log;", "default", ErrSuperuserRequired.String()},
+		{"SuperuserReadsAccessLog", "admin", "SELECT action FROM system.access_log;", "default", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stmt, err := parser.Parse(tt.query)
+			require.NoError(t, err)
+
+			err = checker.Check(ctx, tt.user, stmt, tt.database)
+			if tt.code == "" {
+				assert.NoError(t, err)
+			} else {
+				assert.Equal(t, tt.code, errors.GetCode(err), "error: %v", err)
+			}
+		})
+	}
+}
+
+func TestVisible(t *testing.T) {
+	checker := NewChecker(newFakeStore())
+	alice, err := checker.EffectivePrivileges(context.Background(), "alice")
+	require.NoError(t, err)
+
+	assert.True(t, alice.Visible("sales", "", ""))
+	assert.True(t, alice.Visible("sales", "orders", "total"))
+	assert.True(t, alice.Visible("sales", "customers", ""))
+	assert.True(t, alice.Visible("sales", "customers", "name"))
+	assert.False(t, alice.Visible("sales", "customers", "email"))
+	assert.False(t, alice.Visible("sales", "secrets", ""))
+	assert.True(t, alice.Visible("analytics", "events", "id"))
+	assert.False(t, alice.Visible("hr", "", ""))
+
+	nobody, err := checker.EffectivePrivileges(context.Background(), "nobody")
+	require.NoError(t, err)
+	assert.False(t, nobody.Visible("sales", "", ""))
+}
+
+func TestAllowsAnyColumn(t *testing.T) {
+	checker := NewChecker(newFakeStore())
+	alice, err := checker.EffectivePrivileges(context.Background(), "alice")
+	require.NoError(t, err)
+
+	// Column privileges cover part of the table, which an AnyColumn requirement accepts
+	assert.False(t, alice.Allows(Requirement{Action: parser.PRIV_UPDATE, Database: "sales", Table: "customers"}))
+	assert.True(t, alice.Allows(Requirement{Action: parser.PRIV_UPDATE, Database: "sales", Table: "customers", AnyColumn: true}))
+	assert.False(t, alice.Allows(Requirement{Action: parser.PRIV_INSERT, Database: "sales", Table: "customers", AnyColumn: true}))
+	assert.False(t, alice.Allows(Requirement{Action: parser.PRIV_UPDATE, Database: "sales", Table: "secrets", AnyColumn: true}))
+}
+
+func TestRequirements(t *testing.T) {
+	// Unqualified columns may come from either table, so both must grant them
+	stmt, err := parser.Parse("SELECT id, name AS label FROM sales.orders AS o, customers AS c ORDER BY label;")
+	require.NoError(t, err)
+
+	assert.ElementsMatch(t, []Requirement{
+		{Action: parser.PRIV_SELECT, Database: "sales", Table: "orders", Columns: []string{"id", "name"}},
+		{Action: parser.PRIV_SELECT, Database: "default", Table: "customers", Columns: []string{"id", "name"}},
+	}, Requirements(stmt, "default"))
+}
+
+func TestPrivilegesFromDefinition(t *testing.T) {
+	t.Run("NoObjectMeansEverything", func(t *testing.T) {
+		privileges, err := PrivilegesFromDefinition(&parser.PrivilegeDefinition{Actions: []parser.PrivilegeAction{parser.PRIV_SELECT}}, regtypes.GranteeTypeUser, "bob", "admin")
+		require.NoError(t, err)
+		require.Len(t, privileges, 1)
+		assert.Equal(t, "*", privileges[0].Database)
+		assert.Equal(t, "*", privileges[0].Table)
+	})
+
+	t.Run("UnsupportedAction", func(t *testing.T) {
+		_, err := PrivilegesFromDefinition(&parser.PrivilegeDefinition{Actions: []parser.PrivilegeAction{parser.PRIV_CONNECT}}, regtypes.GranteeTypeUser, "bob", "admin")
+		assert.Equal(t, ErrUnsupportedPrivilege.String(), errors.GetCode(err))
+	})
+
+	t.Run("ColumnsNeedTable", func(t *testing.T) {
+		_, err := PrivilegesFromDefinition(&parser.PrivilegeDefinition{
+			Actions: []parser.PrivilegeAction{parser.PRIV_SELECT},
+			Columns: []*parser.Identifier{{Value: "id"}},
+			Object:  &parser.Identifier{Value: "sales.*"},
+		}, regtypes.GranteeTypeUser, "bob", "admin")
+		assert.Equal(t, ErrInvalidPrivilegeObject.String(), errors.GetCode(err))
+	})
+}
@@ -0,0 +1,13 @@
+package access
+
+import "github.com/gear6io/ranger/pkg/errors"
+
+// Access control error codes
+var (
+	ErrPermissionDenied       = errors.MustNewCode("query.access.permission_denied")
+	ErrSuperuserRequired      = errors.MustNewCode("query.access.superuser_required")
+	ErrPrivilegeLookupFailed  = errors.MustNewCode("query.access.privilege_lookup_failed")
+	ErrUnsupportedPrivilege   = errors.MustNewCode("query.access.unsupported_privilege")
+	ErrInvalidPrivilegeObject = errors.MustNewCode("query.access.invalid_privilege_object")
+	ErrPolicyLookupFailed     = errors.MustNewCode("query.access.policy_lookup_failed")
+)
@@ -0,0 +1,87 @@
+package access
+
+import (
+	"strings"
+
+	"github.com/gear6io/ranger/pkg/errors"
+	"github.com/gear6io/ranger/server/metadata/registry/regtypes"
+	"github.com/gear6io/ranger/server/query/parser"
+)
+
+// grantableActions are the privilege actions enforced by the checker
+var grantableActions = map[parser.PrivilegeAction]bool{
+	parser.PRIV_ALL:    true,
+	parser.PRIV_SELECT: true,
+	parser.PRIV_INSERT: true,
+	parser.PRIV_UPDATE: true,
+	parser.PRIV_DELETE: true,
+	parser.PRIV_CREATE: true,
+	parser.PRIV_DROP:   true,
+	parser.PRIV_ALTER:  true,
+}
+
+// columnActions are the privilege actions that may be restricted to columns
+var columnActions = map[parser.PrivilegeAction]bool{
+	parser.PRIV_SELECT: true,
+	parser.PRIV_INSERT: true,
+	parser.PRIV_UPDATE: true,
+}
+
+// PrivilegesFromDefinition converts the privilege definition of a GRANT or REVOKE
+// statement into registry privileges, one per action and column
+func PrivilegesFromDefinition(definition *parser.PrivilegeDefinition, granteeType, grantee, grantor string) ([]*regtypes.Privilege, error) {
+	database, table, err := ParseObject(definition.Object)
+	if err != nil {
+		return nil, err
+	}
+
+	columns := identifierValues(definition.Columns)
+	if len(columns) > 0 && table == regtypes.PrivilegeWildcard {
+		return nil, errors.New(ErrInvalidPrivilegeObject, "column privileges must name a table", nil).AddContext("object", database+"."+table)
+	}
+	if len(columns) == 0 {
+		columns = []string{""}
+	}
+
+	var privileges []*regtypes.Privilege
+	for _, action := range definition.Actions {
+		if !grantableActions[action] {
+			return nil, errors.New(ErrUnsupportedPrivilege, "unsupported privilege "+string(action), nil).AddContext("action", string(action))
+		}
+		if columns[0] != "" && !columnActions[action] {
+			return nil, errors.New(ErrUnsupportedPrivilege, "privilege "+string(action)+" cannot be granted on columns", nil).AddContext("action", string(action))
+		}
+
+		for _, column := range columns {
+			privileges = append(privileges, &regtypes.Privilege{
+				GranteeType: granteeType,
+				Grantee:     grantee,
+				Action:      string(action),
+				Database:    database,
+				Table:       table,
+				Column:      column,
+				Grantor:     grantor,
+			})
+		}
+	}
+
+	return privileges, nil
+}
+
+// ParseObject splits a privilege object (db.table, db.* or *.*) into database and table.
+// A missing object means every database.
+func ParseObject(object *parser.Identifier) (string, string, error) {
+	if object == nil || object.Value == "" {
+		return regtypes.PrivilegeWildcard, regtypes.PrivilegeWildcard, nil
+	}
+
+	database, table, ok := strings.Cut(object.Value, ".")
+	if !ok || database == "" || table == "" {
+		return "", "", errors.New(ErrInvalidPrivilegeObject, "invalid privilege object", nil).AddContext("object", object.Value)
+	}
+	if database == regtypes.PrivilegeWildcard && table != regtypes.PrivilegeWildcard {
+		return "", "", errors.New(ErrInvalidPrivilegeObject, "a table privilege must name its database", nil).AddContext("object", object.Value)
+	}
+
+	return database, table, nil
+}
@@ -0,0 +1,241 @@
+package access
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/gear6io/ranger/pkg/errors"
+	"github.com/gear6io/ranger/server/metadata/registry/regtypes"
+	"github.com/gear6io/ranger/server/query/parser"
+)
+
+// PolicyStore reads row access and masking policies from the registry
+type PolicyStore interface {
+	ListAccessPolicies(ctx context.Context, database, table string) ([]*regtypes.AccessPolicy, error)
+}
+
+// PolicyRewriter injects row access and masking policies into the queries of non-superusers
+type PolicyRewriter struct {
+	store PolicyStore
+}
+
+// NewPolicyRewriter creates a new policy rewriter backed by store
+func NewPolicyRewriter(store PolicyStore) *PolicyRewriter {
+	return &PolicyRewriter{store: store}
+}
+
+// tablePolicies are the policies of one table that apply to a user
+type tablePolicies struct {
+	rowPolicies []*regtypes.AccessPolicy // applicable row access policies
+	rowFiltered bool                     // whether the table has row access policies at all
+	masks       []*regtypes.AccessPolicy // applicable masking policies
+}
+
+// Rewrite returns query with the policies that apply to grants injected, and the policies
+// applied. Every table read is replaced by a subquery filtering its rows and masking its
+// columns; the target of an UPDATE or DELETE has its row filter added to the WHERE clause.
+// A table with row access policies shows a user only the rows matched by one of the
+// policies applying to them, and none when no policy applies. Superusers bypass policies.
+func (r *PolicyRewriter) Rewrite(ctx context.Context, grants *Grants, stmt parser.Statement, query, database string) (string, []*regtypes.AccessPolicy, error) {
+	if grants.Superuser {
+		return query, nil, nil
+	}
+
+	target := stmt
+	if explain, ok := stmt.(*parser.ExplainStmt); ok {
+		target, _ = explain.Stmt.(parser.Statement)
+	}
+
+	var modified *parser.TableIdentifier
+	switch target := target.(type) {
+	case *parser.SelectStmt:
+	case *parser.UpdateStmt:
+		modified = target.TableName
+	case *parser.DeleteStmt:
+		modified = target.TableName
+	default:
+		return query, nil, nil
+	}
+
+	cache := make(map[string]*tablePolicies)
+	lookup := func(db, table string) (*tablePolicies, error) {
+		key := strings.ToLower(db + "." + table)
+		if policies, ok := cache[key]; ok {
+			return policies, nil
+		}
+		policies, err := r.applicablePolicies(ctx, grants, db, table)
+		if err != nil {
+			return nil, err
+		}
+		cache[key] = policies
+		return policies, nil
+	}
+
+	applied := make(map[int64]*regtypes.AccessPolicy)
+	markApplied := func(policies ...*regtypes.AccessPolicy) {
+		for _, policy := range policies {
+			applied[policy.ID] = policy
+		}
+	}
+
+	// Replace references from the last one so earlier offsets stay valid
+	references := parser.TableReferences(query)
+	for i := len(references) - 1; i >= 0; i-- {
+		reference := references[i]
+		db := reference.Database
+		if db == "" {
+			db = database
+		}
+
+		policies, err := lookup(db, reference.Table)
+		if err != nil {
+			return "", nil, err
+		}
+		if !policies.rowFiltered && len(policies.masks) == 0 {
+			continue
+		}
+
+		query = query[:reference.Offset] + policedTable(reference, policies) + query[reference.Offset+reference.Length:]
+		markApplied(policies.rowPolicies...)
+		markApplied(policies.masks...)
+	}
+
+	if modified != nil && modified.Table != nil {
+		db := database
+		if modified.Database != nil && modified.Database.Value != "" {
+			db = modified.Database.Value
+		}
+
+		policies, err := lookup(db, modified.Table.Value)
+		if err != nil {
+			return "", nil, err
+		}
+		if policies.rowFiltered {
+			start, end, found := parser.WhereClauseSpan(query)
+			if found {
+				query = fmt.Sprintf("%s (%s) AND %s%s", query[:start], strings.TrimSpace(query[start:end]), rowFilter(policies), query[end:])
+			} else {
+				query = fmt.Sprintf("%s WHERE %s%s", strings.TrimRight(query[:end], " \t\r\n"), rowFilter(policies), query[end:])
+			}
+			markApplied(policies.rowPolicies...)
+		}
+	}
+
+	policies := make([]*regtypes.AccessPolicy, 0, len(applied))
+	for _, policy := range applied {
+		policies = append(policies, policy)
+	}
+	sort.Slice(policies, func(i, j int) bool {
+		a, b := policies[i], policies[j]
+		if a.Database != b.Database {
+			return a.Database < b.Database
+		}
+		if a.Table != b.Table {
+			return a.Table < b.Table
+		}
+		return a.Name < b.Name
+	})
+
+	return query, policies, nil
+}
+
+// applicablePolicies returns the policies of a table that apply to grants
+func (r *PolicyRewriter) applicablePolicies(ctx context.Context, grants *Grants, database, table string) (*tablePolicies, error) {
+	policies, err := r.store.ListAccessPolicies(ctx, database, table)
+	if err != nil {
+		return nil, errors.New(ErrPolicyLookupFailed, "failed to look up access policies", err).
+			AddContext("table", database+"."+table)
+	}
+
+	applicable := &tablePolicies{}
+	for _, policy := range policies {
+		switch policy.Kind {
+		case regtypes.PolicyKindRowAccess:
+			applicable.rowFiltered = true
+			if len(policy.Grantees) == 0 || grants.isGrantee(policy.Grantees) {
+				applicable.rowPolicies = append(applicable.rowPolicies, policy)
+			}
+		case regtypes.PolicyKindMasking:
+			if !grants.isGrantee(policy.Grantees) {
+				applicable.masks = append(applicable.masks, policy)
+			}
+		}
+	}
+	return applicable, nil
+}
+
+// isGrantee reports whether the user or one of its roles is among grantees
+func (g *Grants) isGrantee(grantees []string) bool {
+	for _, grantee := range grantees {
+		if strings.EqualFold(grantee, g.Username) {
+			return true
+		}
+		for _, role := range g.Roles {
+			if strings.EqualFold(grantee, role) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// policedTable returns the subquery replacing a table reference
+func policedTable(reference parser.TableReference, policies *tablePolicies) string {
+	name := reference.Table
+	if reference.Database != "" {
+		name = reference.Database + "." + reference.Table
+	}
+
+	var b strings.Builder
+	b.WriteString("(SELECT *")
+	if len(policies.masks) > 0 {
+		replacements := make([]string, len(policies.masks))
+		for i, mask := range policies.masks {
+			replacements[i] = fmt.Sprintf("(%s) AS %s", mask.Expression, quoteIdentifier(mask.Column))
+		}
+		fmt.Fprintf(&b, " REPLACE (%s)", strings.Join(replacements, ", "))
+	}
+	fmt.Fprintf(&b, " FROM %s", name)
+	if policies.rowFiltered {
+		fmt.Fprintf(&b, " WHERE %s", rowFilter(policies))
+	}
+
+	alias := reference.Alias
+	if alias == "" {
+		alias = reference.Table
+	}
+	fmt.Fprintf(&b, ") AS %s", quoteIdentifier(alias))
+	return b.String()
+}
+
+// rowFilter returns the predicate admitting the rows matched by any applicable row policy
+func rowFilter(policies *tablePolicies) string {
+	if len(policies.rowPolicies) == 0 {
+		return "false"
+	}
+
+	predicates := make([]string, len(policies.rowPolicies))
+	for i, policy := range policies.rowPolicies {
+		predicates[i] = "(" + policy.Expression + ")"
+	}
+	if len(predicates) == 1 {
+		return predicates[0]
+	}
+	return "(" + strings.Join(predicates, " OR ") + ")"
+}
+
+// quoteIdentifier quotes an identifier for DuckDB
+func quoteIdentifier(name string) string {
+	return `"` + strings.ReplaceAll(name, `"`, `""`) + `"`
+}
+
+// DescribePolicy returns a one-line description of a policy, as shown by EXPLAIN
+func DescribePolicy(policy *regtypes.AccessPolicy) string {
+	object := policy.Database + "." + policy.Table
+	if policy.Kind == regtypes.PolicyKindMasking {
+		return fmt.Sprintf("MASKING POLICY %s ON %s (%s) USING (%s)", policy.Name, object, policy.Column, policy.Expression)
+	}
+	return fmt.Sprintf("ROW ACCESS POLICY %s ON %s USING (%s)", policy.Name, object, policy.Expression)
+}
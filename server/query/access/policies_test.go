@@ -0,0 +1,130 @@
+package access
+
+import (
+	"context"
+	"testing"
+
+	"github.com/gear6io/ranger/server/metadata/registry/regtypes"
+	"github.com/gear6io/ranger/server/query/parser"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakePolicyStore serves access policies from memory
+type fakePolicyStore []*regtypes.AccessPolicy
+
+func (f fakePolicyStore) ListAccessPolicies(ctx context.Context, database, table string) ([]*regtypes.AccessPolicy, error) {
+	var policies []*regtypes.AccessPolicy
+	for _, policy := range f {
+		if policy.Database == database && policy.Table == table {
+			policies = append(policies, policy)
+		}
+	}
+	return policies, nil
+}
+
+func TestPolicyRewriter(t *testing.T) {
+	rewriter := NewPolicyRewriter(fakePolicyStore{
+		{ID: 1, Kind: regtypes.PolicyKindRowAccess, Name: "eu_only", Database: "sales", Table: "customers", Expression: "region = 'EU'", Grantees: []string{"analyst"}},
+		{ID: 2, Kind: regtypes.PolicyKindRowAccess, Name: "own_rows", Database: "sales", Table: "customers", Expression: "owner = 'bob'", Grantees: []string{"bob"}},
+		{ID: 3, Kind: regtypes.PolicyKindMasking, Name: "hide_email", Database: "sales", Table: "customers", Column: "email", Expression: "md5(email)", Grantees: []string{"support"}},
+	})
+	ctx := context.Background()
+
+	alice := &Grants{Username: "alice", Roles: []string{"analyst"}}
+	tests := []struct {
+		name     string
+		grants   *Grants
+		query    string
+		database string
+		expected string
+		applied  []string
+	}{
+		{
+			name:     "RowFilterAndMask",
+			grants:   alice,
+			query:    "SELECT email FROM customers;",
+			database: "sales",
+			expected: `SELECT email FROM (SELECT * REPLACE ((md5(email)) AS "email") FROM customers WHERE (region = 'EU')) AS "customers";`,
+			applied:  []string{"eu_only", "hide_email"},
+		},
+		{
+			name:     "AliasKept",
+			grants:   alice,
+			query:    "SELECT email FROM sales.customers AS c WHERE id = 1;",
+			database: "default",
+			expected: `SELECT email FROM (SELECT * REPLACE ((md5(email)) AS "email") FROM sales.customers WHERE (region = 'EU')) AS "c" WHERE id = 1;`,
+			applied:  []string{"eu_only", "hide_email"},
+		},
+		{
+			name:     "NoApplicableRowPolicyHidesRows",
+			grants:   &Grants{Username: "carol", Roles: []string{"support"}},
+			query:    "SELECT id FROM sales.customers;",
+			database: "default",
+			expected: `SELECT id FROM (SELECT * FROM sales.customers WHERE false) AS "customers";`,
+		},
+		{
+			name:     "RowPoliciesCombined",
+			grants:   &Grants{Username: "bob", Roles: []string{"analyst"}},
+			query:    "SELECT id FROM sales.customers;",
+			database: "default",
+			expected: `SELECT id FROM (SELECT * REPLACE ((md5(email)) AS "email") FROM sales.customers WHERE ((region = 'EU') OR (owner = 'bob'))) AS "customers";`,
+			applied:  []string{"eu_only", "hide_email", "own_rows"},
+		},
+		{
+			name:     "UnpolicedTableUnchanged",
+			grants:   alice,
+			query:    "SELECT id FROM sales.orders;",
+			database: "default",
+			expected: "SELECT id FROM sales.orders;",
+		},
+		{
+			name:     "SuperuserBypasses",
+			grants:   &Grants{Username: "admin", Superuser: true},
+			query:    "SELECT email FROM sales.customers;",
+			database: "default",
+			expected: "SELECT email FROM sales.customers;",
+		},
+		{
+			name:     "DeleteFiltered",
+			grants:   alice,
+			query:    "DELETE FROM sales.customers WHERE id = 1;",
+			database: "default",
+			expected: "DELETE FROM sales.customers WHERE (id = 1) AND (region = 'EU');",
+			applied:  []string{"eu_only"},
+		},
+		{
+			name:     "UpdateWithoutWhereFiltered",
+			grants:   alice,
+			query:    "UPDATE customers SET name = 'x';",
+			database: "sales",
+			expected: "UPDATE customers SET name = 'x' WHERE (region = 'EU');",
+			applied:  []string{"eu_only"},
+		},
+		{
+			name:     "ExplainRewritten",
+			grants:   alice,
+			query:    "EXPLAIN SELECT id FROM sales.customers;",
+			database: "default",
+			expected: `EXPLAIN SELECT id FROM (SELECT * REPLACE ((md5(email)) AS "email") FROM sales.customers WHERE (region = 'EU')) AS "customers";`,
+			applied:  []string{"eu_only", "hide_email"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stmt, err := parser.Parse(tt.query)
+			require.NoError(t, err)
+
+			query, policies, err := rewriter.Rewrite(ctx, tt.grants, stmt, tt.query, tt.database)
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, query)
+
+			var applied []string
+			for _, policy := range policies {
+				applied = append(applied, policy.Name)
+			}
+			assert.Equal(t, tt.applied, applied)
+		})
+	}
+}
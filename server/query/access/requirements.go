@@ -0,0 +1,333 @@
+package access
+
+import (
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/gear6io/ranger/server/metadata/registry/regtypes"
+	"github.com/gear6io/ranger/server/metadata/registry/system"
+	"github.com/gear6io/ranger/server/query/parser"
+)
+
+// ActionSuperuser marks statements only superusers may run, such as GRANT and CREATE USER
+const ActionSuperuser parser.PrivilegeAction = "SUPERUSER"
+
+// superuserTables are the system tables only superusers may read
+var superuserTables = map[string]bool{
+	system.SystemSchema + ".access_log": true,
+}
+
+// Requirement is a privilege a statement needs on one object. Table is "*" for
+// database-wide requirements; an empty Columns list requires the whole table, unless
+// AnyColumn accepts a privilege on any of its columns.
+type Requirement struct {
+	Action    parser.PrivilegeAction
+	Database  string
+	Table     string
+	Columns   []string
+	AnyColumn bool
+}
+
+// Object returns the requirement's object as db.table
+func (r Requirement) Object() string {
+	return r.Database + "." + r.Table
+}
+
+// Requirements returns the privileges stmt needs when run in database
+func Requirements(stmt parser.Statement, database string) []Requirement {
+	switch stmt := stmt.(type) {
+	case *parser.SelectStmt:
+		return selectRequirements(stmt, database)
+	case *parser.ExplainStmt:
+		return Requirements(stmt.Stmt, database)
+	case *parser.InsertStmt:
+		db, table := resolveTable(stmt.TableName, database)
+		return []Requirement{{Action: parser.PRIV_INSERT, Database: db, Table: table, Columns: identifierValues(stmt.ColumnNames)}}
+	case *parser.UpdateStmt:
+		db, table := resolveTable(stmt.TableName, database)
+		columns := make([]string, 0, len(stmt.SetClause))
+		for _, set := range stmt.SetClause {
+			columns = append(columns, set.Column.Value)
+		}
+		requirements := []Requirement{{Action: parser.PRIV_UPDATE, Database: db, Table: table, Columns: columns}}
+		return append(requirements, whereRequirements(stmt.WhereClause, db, table)...)
+	case *parser.DeleteStmt:
+		db, table := resolveTable(stmt.TableName, database)
+		requirements := []Requirement{{Action: parser.PRIV_DELETE, Database: db, Table: table}}
+		return append(requirements, whereRequirements(stmt.WhereClause, db, table)...)
+	case *parser.CreateTableStmt:
+		db, table := resolveTable(stmt.TableName, database)
+		return []Requirement{{Action: parser.PRIV_CREATE, Database: db, Table: table}}
+	case *parser.CreateDatabaseStmt:
+		return []Requirement{{Action: parser.PRIV_CREATE, Database: regtypes.PrivilegeWildcard, Table: regtypes.PrivilegeWildcard}}
+	case *parser.DropTableStmt:
+		db, table := resolveTable(stmt.TableName, database)
+		return []Requirement{{Action: parser.PRIV_DROP, Database: db, Table: table}}
+	case *parser.DropDatabaseStmt:
+		return []Requirement{{Action: parser.PRIV_DROP, Database: stmt.Name.Value, Table: regtypes.PrivilegeWildcard}}
+	case *parser.AlterTableStmt:
+		db, table := resolveTable(stmt.TableName, database)
+		return []Requirement{{Action: parser.PRIV_ALTER, Database: db, Table: table}}
+	case *parser.ShowStmt:
+		return showRequirements(stmt, database)
+	case *parser.UseStmt, *parser.BeginStmt, *parser.CommitStmt, *parser.RollbackStmt:
+		return nil
+	default:
+		// Anything else (user and role management, procedures, cursors) is reserved to superusers
+		return []Requirement{{Action: ActionSuperuser, Database: regtypes.PrivilegeWildcard, Table: regtypes.PrivilegeWildcard}}
+	}
+}
+
+// showRequirements returns the privileges a SHOW statement needs
+func showRequirements(stmt *parser.ShowStmt, database string) []Requirement {
+	switch stmt.ShowType {
+	case parser.SHOW_COLUMNS, parser.SHOW_CREATE_TABLE:
+		if stmt.TableName == nil {
+			return nil
+		}
+		db, table := resolveTable(stmt.TableName, database)
+		return []Requirement{{Action: parser.PRIV_SELECT, Database: db, Table: table}}
+	case parser.SHOW_USERS:
+		return []Requirement{{Action: ActionSuperuser, Database: regtypes.PrivilegeWildcard, Table: regtypes.PrivilegeWildcard}}
+	default:
+		// SHOW GRANTS FOR another user is checked by Grants.Check, which knows the session user
+		return nil
+	}
+}
+
+// whereRequirements returns the SELECT privileges needed to read the columns a WHERE clause filters on
+func whereRequirements(where *parser.WhereClause, database, table string) []Requirement {
+	if where == nil {
+		return nil
+	}
+	scope := &selectScope{
+		database: database,
+		tables:   []*scopeTable{{database: database, name: table, columns: map[string]bool{}}},
+		aliases:  map[string]bool{},
+	}
+	scope.walk(reflect.ValueOf(where.SearchCondition))
+	return scope.collect(true)
+}
+
+// selectRequirements returns the SELECT privileges needed by stmt, its subqueries and UNION branches
+func selectRequirements(stmt *parser.SelectStmt, database string) []Requirement {
+	var requirements []Requirement
+	for ; stmt != nil; stmt = stmt.Union {
+		scope := newSelectScope(stmt, database, nil)
+		scope.walkSelect(stmt)
+		requirements = append(requirements, scope.collect(false)...)
+	}
+	return requirements
+}
+
+// scopeTable is a table referenced in a FROM clause and the columns read from it
+type scopeTable struct {
+	database string
+	name     string
+	alias    string
+	columns  map[string]bool
+	whole    bool
+}
+
+// selectScope resolves column references against the tables of one SELECT
+type selectScope struct {
+	database   string
+	parent     *selectScope
+	tables     []*scopeTable
+	aliases    map[string]bool
+	subqueries []*selectScope
+	unresolved bool // whether the FROM clause reads something other than named tables
+}
+
+// newSelectScope creates the scope of a SELECT from its FROM clause and select list aliases
+func newSelectScope(stmt *parser.SelectStmt, database string, parent *selectScope) *selectScope {
+	scope := &selectScope{database: database, parent: parent, aliases: map[string]bool{}}
+	if stmt.TableExpression != nil && stmt.TableExpression.FromClause != nil {
+		// A FROM clause read without its tables, such as one of subqueries, could hide any table
+		from := stmt.TableExpression.FromClause
+		scope.unresolved = len(from.Tables) == 0
+		for _, table := range from.Tables {
+			if table == nil || table.Name == nil {
+				scope.unresolved = true
+				continue
+			}
+			entry := &scopeTable{database: database, name: table.Name.Value, columns: map[string]bool{}}
+			if table.Database != nil && table.Database.Value != "" {
+				entry.database = table.Database.Value
+			}
+			if table.Alias != nil {
+				entry.alias = table.Alias.Value
+			}
+			scope.tables = append(scope.tables, entry)
+		}
+	}
+	if stmt.SelectList != nil {
+		for _, expression := range stmt.SelectList.Expressions {
+			if expression != nil && expression.Alias != nil {
+				scope.aliases[strings.ToLower(expression.Alias.Value)] = true
+			}
+		}
+	}
+	return scope
+}
+
+// collect returns one requirement per table of the scope and its subqueries. Tables
+// nothing was read from still need SELECT on the whole table unless columnsOnly is set,
+// and scopes whose tables are unknown are reserved to superusers.
+func (s *selectScope) collect(columnsOnly bool) []Requirement {
+	var requirements []Requirement
+	if s.unresolved {
+		requirements = append(requirements, Requirement{Action: ActionSuperuser, Database: regtypes.PrivilegeWildcard, Table: regtypes.PrivilegeWildcard})
+	}
+	for _, subquery := range s.subqueries {
+		requirements = append(requirements, subquery.collect(false)...)
+	}
+	for _, table := range s.tables {
+		if columnsOnly && len(table.columns) == 0 && !table.whole {
+			continue
+		}
+		if superuserTables[strings.ToLower(table.database+"."+table.name)] {
+			requirements = append(requirements, Requirement{Action: ActionSuperuser, Database: regtypes.PrivilegeWildcard, Table: regtypes.PrivilegeWildcard})
+			continue
+		}
+		requirement := Requirement{Action: parser.PRIV_SELECT, Database: table.database, Table: table.name}
+		if !table.whole {
+			for column := range table.columns {
+				requirement.Columns = append(requirement.Columns, column)
+			}
+			sort.Strings(requirement.Columns)
+		}
+		requirements = append(requirements, requirement)
+	}
+	return requirements
+}
+
+// walk visits every node below v, recording column references and subqueries
+func (s *selectScope) walk(v reflect.Value) {
+	switch v.Kind() {
+	case reflect.Interface, reflect.Pointer:
+		if v.IsNil() {
+			return
+		}
+		if v.CanInterface() {
+			switch node := v.Interface().(type) {
+			case *parser.SelectStmt:
+				s.walkSubquery(node)
+				return
+			case *parser.ColumnSpecification:
+				s.reference(node)
+				return
+			case *parser.Wildcard:
+				s.wildcard("")
+				return
+			}
+		}
+		s.walk(v.Elem())
+	case reflect.Struct:
+		for i := 0; i < v.NumField(); i++ {
+			s.walk(v.Field(i))
+		}
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			s.walk(v.Index(i))
+		}
+	case reflect.Map:
+		iter := v.MapRange()
+		for iter.Next() {
+			s.walk(iter.Value())
+		}
+	}
+}
+
+// walkSubquery resolves a nested SELECT in its own scope, falling back to s for correlated references
+func (s *selectScope) walkSubquery(stmt *parser.SelectStmt) {
+	for ; stmt != nil; stmt = stmt.Union {
+		subquery := newSelectScope(stmt, s.database, s)
+		subquery.walkSelect(stmt)
+		s.subqueries = append(s.subqueries, subquery)
+	}
+}
+
+// walkSelect visits the select list and clauses of stmt; the FROM clause was already read into the scope
+func (s *selectScope) walkSelect(stmt *parser.SelectStmt) {
+	s.walk(reflect.ValueOf(stmt.SelectList))
+	if stmt.TableExpression != nil {
+		expression := *stmt.TableExpression
+		expression.FromClause = nil
+		s.walk(reflect.ValueOf(&expression))
+	}
+}
+
+// reference records a column reference
+func (s *selectScope) reference(column *parser.ColumnSpecification) {
+	if column.ColumnName == nil {
+		return
+	}
+	name := column.ColumnName.Value
+
+	if column.TableName != nil && column.TableName.Value != "" {
+		if name == "*" {
+			s.wildcard(column.TableName.Value)
+			return
+		}
+		for scope := s; scope != nil; scope = scope.parent {
+			if table := scope.lookup(column.TableName.Value); table != nil {
+				table.columns[strings.ToLower(name)] = true
+				return
+			}
+		}
+	} else if s.aliases[strings.ToLower(name)] {
+		// ORDER BY and HAVING may refer to select list aliases
+		return
+	}
+
+	// Unqualified or unresolved references could belong to any table in scope,
+	// so the column is required from each of them
+	for _, table := range s.tables {
+		table.columns[strings.ToLower(name)] = true
+	}
+}
+
+// wildcard records a * (all tables) or table.* reference
+func (s *selectScope) wildcard(qualifier string) {
+	if qualifier != "" {
+		if table := s.lookup(qualifier); table != nil {
+			table.whole = true
+			return
+		}
+	}
+	for _, table := range s.tables {
+		table.whole = true
+	}
+}
+
+// lookup finds a table of the scope by alias or name
+func (s *selectScope) lookup(name string) *scopeTable {
+	for _, table := range s.tables {
+		if strings.EqualFold(table.alias, name) || (table.alias == "" && strings.EqualFold(table.name, name)) {
+			return table
+		}
+	}
+	return nil
+}
+
+// resolveTable returns the database and table a TableIdentifier refers to
+func resolveTable(table *parser.TableIdentifier, database string) (string, string) {
+	if table == nil || table.Table == nil {
+		return database, regtypes.PrivilegeWildcard
+	}
+	if table.IsQualified() {
+		return table.Database.Value, table.Table.Value
+	}
+	return database, table.Table.Value
+}
+
+// identifierValues returns the values of identifiers
+func identifierValues(identifiers []*parser.Identifier) []string {
+	values := make([]string, 0, len(identifiers))
+	for _, identifier := range identifiers {
+		values = append(values, identifier.Value)
+	}
+	return values
+}
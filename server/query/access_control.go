@@ -0,0 +1,348 @@
+package query
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/gear6io/ranger/pkg/errors"
+	"github.com/gear6io/ranger/server/metadata/registry/regtypes"
+	"github.com/gear6io/ranger/server/protocols/native/middleware"
+	"github.com/gear6io/ranger/server/query/access"
+	"github.com/gear6io/ranger/server/query/parser"
+	"github.com/gear6io/ranger/server/types"
+)
+
+// Authorize returns an error unless the user holds every requirement. Protocol paths
+// that write without a SQL statement, such as COPY FROM STDIN, check through it.
+func (e *Engine) Authorize(ctx context.Context, username string, requirements ...access.Requirement) error {
+	return e.accessChecker.Authorize(ctx, username, requirements...)
+}
+
+// executeGrant handles GRANT privilege statements
+func (e *Engine) executeGrant(ctx context.Context, stmt *parser.GrantStmt, queryCtx *types.QueryContext) (*QueryResult, error) {
+	definition := stmt.PrivilegeDefinition
+	granteeType, err := e.resolveGrantee(ctx, definition.Grantee.Value)
+	if err != nil {
+		return nil, err
+	}
+
+	privileges, err := access.PrivilegesFromDefinition(definition, granteeType, definition.Grantee.Value, queryCtx.User)
+	if err != nil {
+		return nil, err
+	}
+	for _, privilege := range privileges {
+		if err := e.storageMgr.GrantPrivilege(ctx, privilege); err != nil {
+			return nil, errors.New(ErrPrivilegeUpdateFailed, "failed to grant privilege", err).AddContext("grantee", definition.Grantee.Value)
+		}
+	}
+
+	return e.accessControlResult(fmt.Sprintf("Granted %s to %s", describePrivileges(definition), definition.Grantee.Value)), nil
+}
+
+// executeRevoke handles REVOKE privilege statements
+func (e *Engine) executeRevoke(ctx context.Context, stmt *parser.RevokeStmt) (*QueryResult, error) {
+	definition := stmt.PrivilegeDefinition
+	granteeType, err := e.resolveGrantee(ctx, definition.Revokee.Value)
+	if err != nil {
+		return nil, err
+	}
+
+	privileges, err := access.PrivilegesFromDefinition(definition, granteeType, definition.Revokee.Value, "")
+	if err != nil {
+		return nil, err
+	}
+	for _, privilege := range privileges {
+		if err := e.storageMgr.RevokePrivilege(ctx, privilege, privilege.Action == string(parser.PRIV_ALL)); err != nil {
+			return nil, errors.New(ErrPrivilegeUpdateFailed, "failed to revoke privilege", err).AddContext("grantee", definition.Revokee.Value)
+		}
+	}
+
+	return e.accessControlResult(fmt.Sprintf("Revoked %s from %s", describePrivileges(definition), definition.Revokee.Value)), nil
+}
+
+// executeGrantRole handles GRANT role TO user statements
+func (e *Engine) executeGrantRole(ctx context.Context, stmt *parser.GrantRoleStmt) (*QueryResult, error) {
+	roles := identifierNames(stmt.Roles)
+	for _, role := range roles {
+		if err := e.storageMgr.GrantRole(ctx, role, stmt.Grantee.Value); err != nil {
+			return nil, errors.New(ErrPrivilegeUpdateFailed, "failed to grant role", err).AddContext("role", role)
+		}
+	}
+
+	return e.accessControlResult(fmt.Sprintf("Granted role %s to %s", strings.Join(roles, ", "), stmt.Grantee.Value)), nil
+}
+
+// executeRevokeRole handles REVOKE role FROM user statements
+func (e *Engine) executeRevokeRole(ctx context.Context, stmt *parser.RevokeRoleStmt) (*QueryResult, error) {
+	roles := identifierNames(stmt.Roles)
+	for _, role := range roles {
+		if err := e.storageMgr.RevokeRole(ctx, role, stmt.Revokee.Value); err != nil {
+			return nil, errors.New(ErrPrivilegeUpdateFailed, "failed to revoke role", err).AddContext("role", role)
+		}
+	}
+
+	return e.accessControlResult(fmt.Sprintf("Revoked role %s from %s", strings.Join(roles, ", "), stmt.Revokee.Value)), nil
+}
+
+// executeCreateUser handles CREATE USER statements
+func (e *Engine) executeCreateUser(ctx context.Context, stmt *parser.CreateUserStmt) (*QueryResult, error) {
+	password, err := e.userPassword(stmt.Password)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := e.storageMgr.CreateUser(ctx, stmt.Username.Value, password); err != nil {
+		return nil, errors.New(ErrUserManagementFailed, "failed to create user", err).AddContext("user", stmt.Username.Value)
+	}
+
+	return e.accessControlResult(fmt.Sprintf("User %s created", stmt.Username.Value)), nil
+}
+
+// executeDropUser handles DROP USER statements
+func (e *Engine) executeDropUser(ctx context.Context, stmt *parser.DropUserStmt, queryCtx *types.QueryContext) (*QueryResult, error) {
+	if stmt.Username.Value == queryCtx.User {
+		return nil, errors.New(ErrUserManagementFailed, "cannot drop the current user", nil).AddContext("user", stmt.Username.Value)
+	}
+
+	if err := e.storageMgr.DropUser(ctx, stmt.Username.Value); err != nil {
+		return nil, errors.New(ErrUserManagementFailed, "failed to drop user", err).AddContext("user", stmt.Username.Value)
+	}
+
+	return e.accessControlResult(fmt.Sprintf("User %s dropped", stmt.Username.Value)), nil
+}
+
+// executeAlterUser handles ALTER USER SET PASSWORD and SET USERNAME statements
+func (e *Engine) executeAlterUser(ctx context.Context, stmt *parser.AlterUserStmt) (*QueryResult, error) {
+	switch stmt.SetType {
+	case parser.ALTER_USER_SET_PASSWORD:
+		password, err := e.userPassword(stmt.Value)
+		if err != nil {
+			return nil, err
+		}
+		if err := e.storageMgr.SetUserPassword(ctx, stmt.Username.Value, password); err != nil {
+			return nil, errors.New(ErrUserManagementFailed, "failed to change password", err).AddContext("user", stmt.Username.Value)
+		}
+		return e.accessControlResult(fmt.Sprintf("Password of user %s changed", stmt.Username.Value)), nil
+	case parser.ALTER_USER_SET_USERNAME:
+		newUsername := fmt.Sprintf("%v", stmt.Value.Value)
+		if err := e.storageMgr.RenameUser(ctx, stmt.Username.Value, newUsername); err != nil {
+			return nil, errors.New(ErrUserManagementFailed, "failed to rename user", err).AddContext("user", stmt.Username.Value)
+		}
+		return e.accessControlResult(fmt.Sprintf("User %s renamed to %s", stmt.Username.Value, newUsername)), nil
+	default:
+		return nil, errors.New(ErrUnsupportedStatementType, "unsupported ALTER USER action", nil)
+	}
+}
+
+// executeCreateRole handles CREATE ROLE statements
+func (e *Engine) executeCreateRole(ctx context.Context, stmt *parser.CreateRoleStmt) (*QueryResult, error) {
+	if err := e.storageMgr.CreateRole(ctx, stmt.Name.Value); err != nil {
+		return nil, errors.New(ErrUserManagementFailed, "failed to create role", err).AddContext("role", stmt.Name.Value)
+	}
+
+	return e.accessControlResult(fmt.Sprintf("Role %s created", stmt.Name.Value)), nil
+}
+
+// executeDropRole handles DROP ROLE statements
+func (e *Engine) executeDropRole(ctx context.Context, stmt *parser.DropRoleStmt) (*QueryResult, error) {
+	if err := e.storageMgr.DropRole(ctx, stmt.Name.Value); err != nil {
+		return nil, errors.New(ErrUserManagementFailed, "failed to drop role", err).AddContext("role", stmt.Name.Value)
+	}
+
+	return e.accessControlResult(fmt.Sprintf("Role %s dropped", stmt.Name.Value)), nil
+}
+
+// executeCreatePolicy handles CREATE ROW ACCESS POLICY and CREATE MASKING POLICY statements
+func (e *Engine) executeCreatePolicy(ctx context.Context, stmt *parser.CreatePolicyStmt, queryCtx *types.QueryContext) (*QueryResult, error) {
+	database, table := e.policyTable(stmt.Table, queryCtx)
+	policy := &regtypes.AccessPolicy{
+		Kind:       regtypes.PolicyKindRowAccess,
+		Name:       stmt.Name.Value,
+		Database:   database,
+		Table:      table,
+		Expression: stmt.Expression,
+		Grantees:   identifierNames(stmt.To),
+		CreatedBy:  queryCtx.User,
+	}
+	if stmt.Kind == parser.POLICY_MASKING {
+		policy.Kind = regtypes.PolicyKindMasking
+		policy.Column = stmt.Column.Value
+		policy.Grantees = identifierNames(stmt.Exempt)
+	}
+
+	for _, grantee := range policy.Grantees {
+		if _, err := e.resolveGrantee(ctx, grantee); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := e.storageMgr.CreateAccessPolicy(ctx, policy); err != nil {
+		return nil, errors.New(ErrPolicyUpdateFailed, "failed to create policy", err).AddContext("policy", policy.Name)
+	}
+
+	return e.accessControlResult(fmt.Sprintf("%s policy %s created on %s.%s", stmt.Kind, policy.Name, database, table)), nil
+}
+
+// executeDropPolicy handles DROP ROW ACCESS POLICY and DROP MASKING POLICY statements
+func (e *Engine) executeDropPolicy(ctx context.Context, stmt *parser.DropPolicyStmt, queryCtx *types.QueryContext) (*QueryResult, error) {
+	database, table := e.policyTable(stmt.Table, queryCtx)
+	kind := regtypes.PolicyKindRowAccess
+	if stmt.Kind == parser.POLICY_MASKING {
+		kind = regtypes.PolicyKindMasking
+	}
+
+	if err := e.storageMgr.DropAccessPolicy(ctx, kind, database, table, stmt.Name.Value); err != nil {
+		return nil, errors.New(ErrPolicyUpdateFailed, "failed to drop policy", err).AddContext("policy", stmt.Name.Value)
+	}
+
+	return e.accessControlResult(fmt.Sprintf("%s policy %s dropped from %s.%s", stmt.Kind, stmt.Name.Value, database, table)), nil
+}
+
+// applyPolicies rewrites the query with the row access and masking policies that apply to
+// the session user's grants, returning the rewritten query and the policies applied
+func (e *Engine) applyPolicies(ctx context.Context, grants *access.Grants, stmt parser.Statement, queryCtx *types.QueryContext) (string, []*regtypes.AccessPolicy, error) {
+	switch stmt.(type) {
+	case *parser.SelectStmt, *parser.UpdateStmt, *parser.DeleteStmt, *parser.ExplainStmt:
+	default:
+		return queryCtx.Query, nil, nil
+	}
+
+	return e.policyRewriter.Rewrite(ctx, grants, stmt, queryCtx.Query, e.getDatabaseFromContext(queryCtx))
+}
+
+// policyTable resolves the table of a policy statement against the session database
+func (e *Engine) policyTable(table *parser.TableIdentifier, queryCtx *types.QueryContext) (string, string) {
+	if table.Database != nil && table.Database.Value != "" {
+		return table.Database.Value, table.Table.Value
+	}
+	return e.getDatabaseFromContext(queryCtx), table.Table.Value
+}
+
+// executeShowGrants lists the effective privileges of a user, or the privileges of a role
+func (e *Engine) executeShowGrants(ctx context.Context, stmt *parser.ShowStmt, queryCtx *types.QueryContext) (*QueryResult, error) {
+	name := queryCtx.User
+	if stmt.For != nil {
+		name = stmt.For.Value
+	}
+
+	var grants *access.Grants
+	if e.storageMgr.RoleExists(ctx, name) {
+		privileges, err := e.storageMgr.ListPrivileges(ctx, regtypes.GranteeTypeRole, name)
+		if err != nil {
+			return nil, errors.New(ErrPrivilegeListFailed, "failed to list role privileges", err).AddContext("role", name)
+		}
+		grants = &access.Grants{Username: name, Privileges: privileges}
+	} else {
+		var err error
+		if grants, err = e.accessChecker.EffectivePrivileges(ctx, name); err != nil {
+			return nil, errors.New(ErrPrivilegeListFailed, "failed to list privileges", err).AddContext("user", name)
+		}
+	}
+
+	var rows [][]interface{}
+	if grants.Superuser {
+		rows = append(rows, []interface{}{"SUPERUSER", regtypes.PrivilegeWildcard, regtypes.PrivilegeWildcard, "", ""})
+	}
+	for _, privilege := range grants.Privileges {
+		grantedVia := ""
+		if privilege.GranteeType == regtypes.GranteeTypeRole && privilege.Grantee != name {
+			grantedVia = privilege.Grantee
+		}
+		rows = append(rows, []interface{}{privilege.Action, privilege.Database, privilege.Table, privilege.Column, grantedVia})
+	}
+
+	return &QueryResult{
+		Data:     rows,
+		RowCount: int64(len(rows)),
+		Columns:  []string{"privilege", "database", "table", "column", "granted_via"},
+		Message:  fmt.Sprintf("Found %d grant(s) for %s", len(rows), name),
+	}, nil
+}
+
+// executeShowUsers lists registry users and the roles granted to them
+func (e *Engine) executeShowUsers(ctx context.Context) (*QueryResult, error) {
+	users, err := e.storageMgr.ListUsers(ctx)
+	if err != nil {
+		return nil, errors.New(ErrPrivilegeListFailed, "failed to list users", err)
+	}
+
+	var rows [][]interface{}
+	for _, username := range users {
+		roles, err := e.storageMgr.ListUserRoles(ctx, username)
+		if err != nil {
+			return nil, errors.New(ErrPrivilegeListFailed, "failed to list user roles", err).AddContext("user", username)
+		}
+		rows = append(rows, []interface{}{username, strings.Join(roles, ", ")})
+	}
+
+	return &QueryResult{
+		Data:     rows,
+		RowCount: int64(len(rows)),
+		Columns:  []string{"user", "roles"},
+		Message:  fmt.Sprintf("Found %d user(s)", len(rows)),
+	}, nil
+}
+
+// resolveGrantee returns whether name is a role or a user; roles take precedence
+func (e *Engine) resolveGrantee(ctx context.Context, name string) (string, error) {
+	if e.storageMgr.RoleExists(ctx, name) {
+		return regtypes.GranteeTypeRole, nil
+	}
+	if _, err := e.storageMgr.GetUser(ctx, name); err != nil {
+		return "", errors.New(ErrGranteeNotFound, fmt.Sprintf("user or role '%s' does not exist", name), err)
+	}
+	return regtypes.GranteeTypeUser, nil
+}
+
+// accessControlResult builds the empty result of a user, role or privilege statement
+func (e *Engine) accessControlResult(message string) *QueryResult {
+	return &QueryResult{
+		Data:     [][]interface{}{},
+		RowCount: 0,
+		Columns:  []string{},
+		Message:  message,
+	}
+}
+
+// userPassword hashes a password with the configured algorithm and derives the SCRAM-SHA-256
+// verifier pgwire clients authenticate against, so the server never keeps the cleartext
+func (e *Engine) userPassword(password *parser.Literal) (regtypes.UserPassword, error) {
+	if password == nil {
+		return regtypes.UserPassword{}, nil
+	}
+
+	userPassword, err := middleware.NewUserPassword(fmt.Sprintf("%v", password.Value), e.authConfig)
+	if err != nil {
+		return regtypes.UserPassword{}, errors.New(ErrPasswordHashFailed, "failed to hash password", err)
+	}
+	return userPassword, nil
+}
+
+// describePrivileges renders the actions, columns and object of a privilege definition
+func describePrivileges(definition *parser.PrivilegeDefinition) string {
+	actions := make([]string, 0, len(definition.Actions))
+	for _, action := range definition.Actions {
+		actions = append(actions, string(action))
+	}
+
+	description := strings.Join(actions, ", ")
+	if len(definition.Columns) > 0 {
+		description += " (" + strings.Join(identifierNames(definition.Columns), ", ") + ")"
+	}
+
+	object := regtypes.PrivilegeWildcard + "." + regtypes.PrivilegeWildcard
+	if definition.Object != nil {
+		object = definition.Object.Value
+	}
+	return description + " on " + object
+}
+
+// identifierNames returns the values of identifiers
+func identifierNames(identifiers []*parser.Identifier) []string {
+	names := make([]string, 0, len(identifiers))
+	for _, identifier := range identifiers {
+		names = append(names, identifier.Value)
+	}
+	return names
+}
@@ -0,0 +1,179 @@
+package query
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/gear6io/ranger/pkg/errors"
+	"github.com/gear6io/ranger/server/metadata/registry/regtypes"
+	"github.com/gear6io/ranger/server/metrics"
+	"github.com/gear6io/ranger/server/query/access"
+	"github.com/gear6io/ranger/server/query/parser"
+	"github.com/gear6io/ranger/server/types"
+)
+
+// AuditLogin records an authentication attempt; a non-nil err marks it as failed
+func (e *Engine) AuditLogin(ctx context.Context, username, protocol, clientAddr string, err error) {
+	entry := &regtypes.AccessLog{
+		Username:  username,
+		Protocol:  protocol,
+		Action:    "LOGIN",
+		Resource:  "*",
+		IPAddress: clientAddr,
+		Status:    regtypes.AccessStatusOK,
+	}
+	if err != nil {
+		entry.Status = regtypes.AccessStatusUnauthenticated
+		entry.Error = err.Error()
+	}
+	e.auditor.Record(ctx, entry)
+}
+
+// AuditInsert records a bulk insert that does not go through ExecuteQuery, such as COPY FROM
+// or a native data block, in the access log and the insert metrics
+func (e *Engine) AuditInsert(ctx context.Context, queryCtx *types.QueryContext, database, table string, start time.Time, err error) {
+	metrics.ObserveInsert(queryCtx.Protocol, database+"."+table, metricsStatus(err), time.Since(start))
+
+	entry := &regtypes.AccessLog{
+		Username:  queryCtx.User,
+		Protocol:  queryCtx.Protocol,
+		Action:    "INSERT",
+		Resource:  database + "." + table,
+		IPAddress: queryCtx.ClientAddr,
+		Status:    accessStatus(err),
+		Duration:  time.Since(start).Milliseconds(),
+	}
+	if err != nil {
+		entry.Error = err.Error()
+	}
+	e.auditor.Record(ctx, entry)
+}
+
+// auditStatement records a statement in the access log when the audit settings require it.
+// grants is nil when the user's privileges could not be looked up.
+func (e *Engine) auditStatement(ctx context.Context, stmt parser.Statement, queryCtx *types.QueryContext, grants *access.Grants, start time.Time, err error) {
+	action, read := statementAction(stmt)
+	if action == "" {
+		return
+	}
+	e.auditAccess(ctx, action, read, statementResource(stmt, e.getDatabaseFromContext(queryCtx)), queryCtx, grants, start, err)
+}
+
+// auditCatalogQuery records a catalog query, which is a read of the catalog relations
+func (e *Engine) auditCatalogQuery(ctx context.Context, relations []string, queryCtx *types.QueryContext, grants *access.Grants, start time.Time, err error) {
+	resource := "*"
+	if len(relations) > 0 {
+		sorted := append([]string(nil), relations...)
+		sort.Strings(sorted)
+		resource = strings.Join(sorted, ", ")
+	}
+	e.auditAccess(ctx, "SELECT", true, resource, queryCtx, grants, start, err)
+}
+
+// auditAccess records an action on resource when the audit settings require it
+func (e *Engine) auditAccess(ctx context.Context, action string, read bool, resource string, queryCtx *types.QueryContext, grants *access.Grants, start time.Time, err error) {
+	status := accessStatus(err)
+	if read && !e.auditor.RecordsSelect(grants != nil && grants.Superuser, status == regtypes.AccessStatusDenied) {
+		return
+	}
+
+	entry := &regtypes.AccessLog{
+		Username:  queryCtx.User,
+		Protocol:  queryCtx.Protocol,
+		Action:    action,
+		Resource:  resource,
+		IPAddress: queryCtx.ClientAddr,
+		Status:    status,
+		Duration:  time.Since(start).Milliseconds(),
+	}
+	if err != nil {
+		entry.Error = err.Error()
+	}
+	e.auditor.Record(ctx, entry)
+}
+
+// statementAction returns the access log action of stmt and whether it only reads data.
+// Statements that are not audited, such as SHOW and USE, have no action.
+func statementAction(stmt parser.Statement) (string, bool) {
+	switch stmt := stmt.(type) {
+	case *parser.SelectStmt:
+		return "SELECT", true
+	case *parser.ExplainStmt:
+		return "EXPLAIN", true
+	case *parser.InsertStmt:
+		return "INSERT", false
+	case *parser.UpdateStmt:
+		return "UPDATE", false
+	case *parser.DeleteStmt:
+		return "DELETE", false
+	case *parser.CreateTableStmt:
+		return "CREATE TABLE", false
+	case *parser.CreateDatabaseStmt:
+		return "CREATE DATABASE", false
+	case *parser.DropTableStmt:
+		return "DROP TABLE", false
+	case *parser.DropDatabaseStmt:
+		return "DROP DATABASE", false
+	case *parser.AlterTableStmt:
+		return "ALTER TABLE", false
+	case *parser.GrantStmt:
+		return "GRANT", false
+	case *parser.RevokeStmt:
+		return "REVOKE", false
+	case *parser.GrantRoleStmt:
+		return "GRANT ROLE", false
+	case *parser.RevokeRoleStmt:
+		return "REVOKE ROLE", false
+	case *parser.CreateUserStmt:
+		return "CREATE USER", false
+	case *parser.DropUserStmt:
+		return "DROP USER", false
+	case *parser.AlterUserStmt:
+		return "ALTER USER", false
+	case *parser.CreateRoleStmt:
+		return "CREATE ROLE", false
+	case *parser.DropRoleStmt:
+		return "DROP ROLE", false
+	case *parser.CreatePolicyStmt:
+		return "CREATE " + string(stmt.Kind) + " POLICY", false
+	case *parser.DropPolicyStmt:
+		return "DROP " + string(stmt.Kind) + " POLICY", false
+	default:
+		return "", false
+	}
+}
+
+// statementResource returns the objects stmt touches as a comma-separated list of db.table,
+// or * for statements that are not about tables, such as user management
+func statementResource(stmt parser.Statement, database string) string {
+	seen := make(map[string]bool)
+	var objects []string
+	for _, requirement := range access.Requirements(stmt, database) {
+		if requirement.Action == access.ActionSuperuser || seen[requirement.Object()] {
+			continue
+		}
+		seen[requirement.Object()] = true
+		objects = append(objects, requirement.Object())
+	}
+
+	if len(objects) == 0 {
+		return "*"
+	}
+	sort.Strings(objects)
+	return strings.Join(objects, ", ")
+}
+
+// accessStatus maps the outcome of an audited operation to an access log status
+func accessStatus(err error) int {
+	if err == nil {
+		return regtypes.AccessStatusOK
+	}
+	switch errors.GetCode(err) {
+	case access.ErrPermissionDenied.String(), access.ErrSuperuserRequired.String():
+		return regtypes.AccessStatusDenied
+	default:
+		return regtypes.AccessStatusFailed
+	}
+}
@@ -15,6 +15,9 @@ import (
 	"github.com/apache/iceberg-go/table"
 	"github.com/gear6io/ranger/pkg/errors"
 	"github.com/gear6io/ranger/server/catalog"
+	"github.com/gear6io/ranger/server/tracing"
+	"github.com/gear6io/ranger/server/types"
+	"go.opentelemetry.io/otel/attribute"
 
 	_ "github.com/marcboeker/go-duckdb/v2"
 )
@@ -60,13 +63,14 @@ type EngineMetrics struct {
 
 // QueryResult represents the result of a SQL query
 type QueryResult struct {
-	Columns  []string
-	Rows     [][]interface{}
-	Schema   *arrow.Schema
-	Table    arrow.Table
-	RowCount int64
-	Duration time.Duration
-	QueryID  string
+	Columns     []string
+	ColumnTypes []string // DuckDB type names
+	Rows        [][]interface{}
+	Schema      *arrow.Schema
+	Table       arrow.Table
+	RowCount    int64
+	Duration    time.Duration
+	QueryID     string
 }
 
 // SecurityError represents a security-related error
@@ -293,6 +297,20 @@ func (e *Engine) Close() error {
 	return nil
 }
 
+// Ping verifies that the DuckDB connection is still usable
+func (e *Engine) Ping(ctx context.Context) error {
+	e.mutex.RLock()
+	defer e.mutex.RUnlock()
+
+	if e.db == nil {
+		return errors.New(ErrDuckDBPingFailed, "DuckDB connection is not open", nil)
+	}
+	if err := e.db.PingContext(ctx); err != nil {
+		return errors.New(ErrDuckDBPingFailed, "failed to ping DuckDB", err)
+	}
+	return nil
+}
+
 // GetMetrics returns current engine performance metrics
 func (e *Engine) GetMetrics() *EngineMetrics {
 	e.metrics.mu.RLock()
@@ -329,8 +347,32 @@ func (e *Engine) GetConfig() *EngineConfig {
 	}
 }
 
+// maxBufferedRows caps the rows ExecuteQuery keeps in memory; StreamQuery has no limit
+const maxBufferedRows = 100000
+
 // ExecuteQuery executes a SQL query and returns the results
 func (e *Engine) ExecuteQuery(ctx context.Context, query string) (*QueryResult, error) {
+	buffer := &rowBuffer{limit: maxBufferedRows}
+	result, err := e.StreamQuery(ctx, query, buffer)
+	if err != nil {
+		return nil, err
+	}
+
+	// Memory management: limit result size for very large queries
+	if buffer.truncated {
+		e.log.Printf("Warning: Query [%s] result truncated at %d rows", result.QueryID, maxBufferedRows)
+	}
+	result.Rows = buffer.rows
+	result.RowCount = int64(len(buffer.rows))
+	return result, nil
+}
+
+// StreamQuery executes a SQL query and passes its rows to sink as they are scanned. The
+// returned result carries no rows.
+func (e *Engine) StreamQuery(ctx context.Context, query string, sink types.RowSink) (result *QueryResult, err error) {
+	ctx, span := tracing.Start(ctx, "duckdb.query", attribute.String("db.system.name", "duckdb"))
+	defer func() { tracing.End(span, err) }()
+
 	if !e.initialized {
 		return nil, errors.New(ErrDuckDBConfigurationFailed, "engine not initialized", nil)
 	}
@@ -382,23 +424,22 @@ func (e *Engine) ExecuteQuery(ctx context.Context, query string) (*QueryResult,
 	defer rows.Close()
 
 	// Get column information
-	columns, err := rows.Columns()
+	columnTypes, err := rows.ColumnTypes()
 	if err != nil {
 		e.incrementErrorCount()
 		return nil, errors.Newf(ErrDuckDBColumnRetrievalFailed, "failed to get columns for query [%s]: %w", queryID, err)
 	}
+	columns := make([]string, len(columnTypes))
+	typeNames := make([]string, len(columnTypes))
+	for i, columnType := range columnTypes {
+		columns[i], typeNames[i] = columnType.Name(), columnType.DatabaseTypeName()
+	}
+	if err := sink.Columns(columns, typeNames); err != nil {
+		return nil, err
+	}
 
-	// Fetch all rows with memory management
-	var resultRows [][]interface{}
 	rowCount := int64(0)
-
 	for rows.Next() {
-		// Memory management: limit result size for very large queries
-		if rowCount > 100000 { // 100k row limit
-			e.log.Printf("Warning: Query [%s] result truncated at 100,000 rows", queryID)
-			break
-		}
-
 		// Create slice to hold row values
 		values := make([]interface{}, len(columns))
 		valuePtrs := make([]interface{}, len(columns))
@@ -411,7 +452,12 @@ func (e *Engine) ExecuteQuery(ctx context.Context, query string) (*QueryResult,
 			return nil, errors.Newf(ErrDuckDBScanFailed, "failed to scan row %d in query [%s]: %w", rowCount, queryID, err)
 		}
 
-		resultRows = append(resultRows, values)
+		if err := sink.Row(values); err != nil {
+			if errors.GetCode(err) == types.ErrStopRows.String() {
+				break
+			}
+			return nil, err
+		}
 		rowCount++
 	}
 
@@ -431,16 +477,35 @@ func (e *Engine) ExecuteQuery(ctx context.Context, query string) (*QueryResult,
 	if e.config.EnableQueryLog {
 		e.log.Printf("Query [%s] completed in %v, returned %d rows", queryID, duration, rowCount)
 	}
+	span.SetAttributes(attribute.Int64("db.response.returned_rows", rowCount))
 
 	return &QueryResult{
-		Columns:  columns,
-		Rows:     resultRows,
-		RowCount: rowCount,
-		Duration: duration,
-		QueryID:  queryID,
+		Columns:     columns,
+		ColumnTypes: typeNames,
+		RowCount:    rowCount,
+		Duration:    duration,
+		QueryID:     queryID,
 	}, nil
 }
 
+// rowBuffer is a RowSink collecting up to limit rows in memory
+type rowBuffer struct {
+	limit     int
+	rows      [][]interface{}
+	truncated bool
+}
+
+func (b *rowBuffer) Columns(names, types []string) error { return nil }
+
+func (b *rowBuffer) Row(values []interface{}) error {
+	if len(b.rows) >= b.limit {
+		b.truncated = true
+		return errors.New(types.ErrStopRows, "buffered row limit reached", nil)
+	}
+	b.rows = append(b.rows, values)
+	return nil
+}
+
 // RegisterTable registers an Iceberg table for querying using DuckDB's native Iceberg support
 func (e *Engine) RegisterTable(ctx context.Context, identifier table.Identifier, icebergTable *table.Table) error {
 	if !e.initialized {
@@ -8,13 +8,22 @@ import (
 	"strings"
 	"time"
 
+	"github.com/apache/arrow-go/v18/arrow/array"
+	"github.com/apache/iceberg-go"
 	"github.com/gear6io/ranger/pkg/errors"
+	"github.com/gear6io/ranger/server/audit"
 	"github.com/gear6io/ranger/server/config"
+	"github.com/gear6io/ranger/server/metadata/registry/regtypes"
+	"github.com/gear6io/ranger/server/metadata/registry/system"
+	"github.com/gear6io/ranger/server/query/access"
 	"github.com/gear6io/ranger/server/query/duckdb"
 	"github.com/gear6io/ranger/server/query/parser"
+	"github.com/gear6io/ranger/server/shared"
 	"github.com/gear6io/ranger/server/storage"
+	"github.com/gear6io/ranger/server/tracing"
 	"github.com/gear6io/ranger/server/types"
 	"github.com/rs/zerolog"
+	"go.opentelemetry.io/otel/attribute"
 )
 
 // ComponentType defines the query engine component type identifier
@@ -22,20 +31,28 @@ const ComponentType = "query"
 
 // Engine represents the shared query engine service with embedded storage
 type Engine struct {
-	duckdbEngine *duckdb.Engine
-	storageMgr   *storage.Storage
-	logger       zerolog.Logger
-	queryManager *ExecutionManager
+	duckdbEngine   *duckdb.Engine
+	storageMgr     *storage.Storage
+	logger         zerolog.Logger
+	queryManager   *ExecutionManager
+	accessChecker  *access.Checker
+	policyRewriter *access.PolicyRewriter
+	auditor        *audit.Logger
+	authConfig     config.AuthConfig
 }
 
 // QueryResult represents the result of a query execution
 type QueryResult struct {
-	Data     interface{}
-	RowCount int64
-	Columns  []string
-	Message  string
-	Error    error
-	QueryID  string
+	Data        interface{}
+	RowCount    int64
+	Columns     []string
+	ColumnTypes []string // SQL type names, when known
+	Message     string
+	Error       error
+	QueryID     string
+
+	// streamed is set when the rows went to a RowSink instead of Data
+	streamed bool
 }
 
 // NewEngine creates a new shared query engine service with storage
@@ -72,13 +89,25 @@ func NewEngine(cfg *config.Config, storageMgr *storage.Storage, logger zerolog.L
 	// Create execution manager for tracking and cancellation
 	queryManager := NewExecutionManager(logger)
 
+	// Create the audit logger; it is nil when auditing is disabled
+	auditor, err := audit.NewLogger(cfg.Audit, storageMgr, logger)
+	if err != nil {
+		duckdbEngine.Close()
+		return nil, errors.New(ErrAuditLoggerCreationFailed, "failed to create audit logger", err)
+	}
+
 	// Create engine instance
 	engine := &Engine{
-		duckdbEngine: duckdbEngine,
-		storageMgr:   storageMgr,
-		logger:       logger,
-		queryManager: queryManager,
+		duckdbEngine:   duckdbEngine,
+		storageMgr:     storageMgr,
+		logger:         logger,
+		queryManager:   queryManager,
+		accessChecker:  access.NewChecker(storageMgr),
+		policyRewriter: access.NewPolicyRewriter(storageMgr),
+		auditor:        auditor,
+		authConfig:     cfg.Auth,
 	}
+	engine.registerMetrics()
 
 	// System database is now initialized by the Store during creation
 
@@ -87,27 +116,167 @@ func NewEngine(cfg *config.Config, storageMgr *storage.Storage, logger zerolog.L
 
 // ExecuteQuery executes a query with full tracking and cancellation support
 func (e *Engine) ExecuteQuery(ctx context.Context, queryCtx *types.QueryContext) (*QueryResult, error) {
-	// Generate unique query ID
-	queryID := fmt.Sprintf("query_%d", time.Now().UnixNano())
+	return e.execute(ctx, queryCtx, nil)
+}
 
-	// Start tracking the query
-	_, trackedCtx := e.queryManager.StartQuery(ctx, queryID, queryCtx.Query, queryCtx.User, queryCtx.ClientAddr)
+// StreamQuery executes a query like ExecuteQuery, passing the result rows to sink as they are
+// produced instead of returning them in Data
+func (e *Engine) StreamQuery(ctx context.Context, queryCtx *types.QueryContext, sink types.RowSink) (*QueryResult, error) {
+	result, err := e.execute(ctx, queryCtx, sink)
+	if err != nil || result.streamed {
+		return result, err
+	}
+
+	// Results built in memory, such as SHOW and system queries, are replayed
+	rows, _ := result.Data.([][]interface{})
+	columnTypes := result.ColumnTypes
+	if columnTypes == nil {
+		columnTypes = inferColumnTypes(len(result.Columns), rows)
+	}
+	if err := sink.Columns(result.Columns, columnTypes); err != nil {
+		return nil, err
+	}
+	for _, row := range rows {
+		if err := sink.Row(row); err != nil {
+			if errors.GetCode(err) == types.ErrStopRows.String() {
+				break
+			}
+			return nil, err
+		}
+	}
+	result.Data = nil
+	return result, nil
+}
+
+// inferColumnTypes derives SQL type names from the first non-NULL value of each column
+func inferColumnTypes(columns int, rows [][]interface{}) []string {
+	columnTypes := make([]string, columns)
+	for i := range columnTypes {
+		columnTypes[i] = "VARCHAR"
+		for _, row := range rows {
+			if i < len(row) && row[i] != nil {
+				columnTypes[i] = sqlTypeName(row[i])
+				break
+			}
+		}
+	}
+	return columnTypes
+}
+
+// sqlTypeName returns the DuckDB type name of a Go value
+func sqlTypeName(value interface{}) string {
+	switch value.(type) {
+	case bool:
+		return "BOOLEAN"
+	case int8:
+		return "TINYINT"
+	case int16:
+		return "SMALLINT"
+	case int32:
+		return "INTEGER"
+	case int, int64:
+		return "BIGINT"
+	case uint8:
+		return "UTINYINT"
+	case uint16:
+		return "USMALLINT"
+	case uint32:
+		return "UINTEGER"
+	case uint, uint64:
+		return "UBIGINT"
+	case float32:
+		return "FLOAT"
+	case float64:
+		return "DOUBLE"
+	case time.Time:
+		return "TIMESTAMP"
+	case []byte:
+		return "BLOB"
+	default:
+		return "VARCHAR"
+	}
+}
+
+// progressInterval is how many streamed rows pass between progress updates of a query
+const progressInterval = 1000
+
+// progressSink passes rows on to a RowSink, reporting the number of rows to the execution
+// manager as the query progresses
+type progressSink struct {
+	types.RowSink
+	manager *ExecutionManager
+	queryID string
+	rows    int64
+}
+
+// Row passes a row on and counts it
+func (p *progressSink) Row(values []interface{}) error {
+	if err := p.RowSink.Row(values); err != nil {
+		return err
+	}
+	p.rows++
+	if p.rows%progressInterval == 0 {
+		p.manager.UpdateProgress(p.queryID, p.rows)
+	}
+	return nil
+}
+
+// execute runs a query, streaming the rows of reads to sink when it is set
+func (e *Engine) execute(ctx context.Context, queryCtx *types.QueryContext, sink types.RowSink) (result *QueryResult, err error) {
+	ctx, span := tracing.Start(ctx, "query.execute",
+		attribute.String("db.query.text", queryCtx.Query),
+		attribute.String("db.namespace", e.getDatabaseFromContext(queryCtx)),
+		attribute.String("ranger.protocol", queryCtx.Protocol),
+		attribute.String("ranger.user", queryCtx.User))
+	defer func() { tracing.End(span, err) }()
+
+	// Generate unique query ID unless the client chose one
+	queryID := queryCtx.QueryID
+	var trackedCtx context.Context
+	if queryID != "" {
+		if _, trackedCtx, err = e.queryManager.StartUniqueQuery(ctx, queryID, queryCtx.Query, queryCtx.User, queryCtx.ClientAddr); err != nil {
+			return nil, err
+		}
+	} else {
+		queryID = fmt.Sprintf("query_%d", time.Now().UnixNano())
+		_, trackedCtx = e.queryManager.StartQuery(ctx, queryID, queryCtx.Query, queryCtx.User, queryCtx.ClientAddr)
+	}
+	span.SetAttributes(attribute.String("ranger.query_id", queryID))
 
 	// Use the tracked context for execution
 	ctx = trackedCtx
+	if sink != nil {
+		sink = &progressSink{RowSink: sink, manager: e.queryManager, queryID: queryID}
+	}
 
 	// Ensure query completion is tracked
 	defer func() {
 		if r := recover(); r != nil {
-			e.queryManager.CompleteQuery(queryID, 0, errors.New(ErrQueryPanic, "query execution panic", nil).AddContext("panic_value", fmt.Sprintf("%v", r)))
+			e.completeQuery(queryCtx, queryID, 0, errors.New(ErrQueryPanic, "query execution panic", nil).AddContext("panic_value", fmt.Sprintf("%v", r)))
 			panic(r)
 		}
 	}()
 
+	// PostgreSQL catalog introspection from BI tools uses syntax the parser does not
+	// accept, so queries reading nothing but the catalog are answered from the registry
+	// views before parsing, showing only the objects the user holds privileges on
+	if e.isCatalogQuery(queryCtx.Query) {
+		result, err := e.executeCatalogQuery(ctx, queryCtx)
+		if err != nil {
+			e.completeQuery(queryCtx, queryID, 0, err)
+			return nil, err
+		}
+		result.QueryID = queryID
+		e.completeQuery(queryCtx, queryID, result.RowCount, nil)
+		return result, nil
+	}
+
 	// Parse the query (validation will be handled separately if needed)
-	stmt, err := parser.Parse(queryCtx.Query)
+	_, parseSpan := tracing.Start(ctx, "query.parse")
+	stmt, err := parser.ParseSingle(queryCtx.Query)
+	tracing.End(parseSpan, err)
 	if err != nil {
-		e.queryManager.CompleteQuery(queryID, 0, err)
+		e.completeQuery(queryCtx, queryID, 0, err)
 		return nil, errors.New(ErrQueryParseValidationFailed, "failed to parse and validate query", err)
 	}
 
@@ -116,13 +285,48 @@ func (e *Engine) ExecuteQuery(ctx context.Context, queryCtx *types.QueryContext)
 		Str("statement_type", fmt.Sprintf("%T", stmt)).
 		Msg("Parsed statement type")
 
+	// Record the statement in the access log with its final outcome
+	start := time.Now()
+	var grants *access.Grants
+	defer func() {
+		e.auditStatement(ctx, stmt, queryCtx, grants, start, err)
+	}()
+
+	// Check the user's privileges on every object the statement references
+	authCtx, authSpan := tracing.Start(ctx, "query.authorize")
+	grants, err = e.accessChecker.EffectivePrivileges(authCtx, queryCtx.User)
+	if err == nil {
+		err = grants.Check(stmt, e.getDatabaseFromContext(queryCtx))
+	}
+	if err != nil {
+		tracing.End(authSpan, err)
+		e.completeQuery(queryCtx, queryID, 0, err)
+		return nil, err
+	}
+
+	// Inject the row access and masking policies that apply to the user
+	query, policies, err := e.applyPolicies(authCtx, grants, stmt, queryCtx)
+	tracing.End(authSpan, err)
+	if err != nil {
+		e.completeQuery(queryCtx, queryID, 0, err)
+		return nil, err
+	}
+
 	// Route based on statement type
-	var result *QueryResult
+	span.SetAttributes(attribute.String("db.operation.name", statementName(stmt)))
 	switch stmt := stmt.(type) {
 	case *parser.SelectStmt:
-		result, err = e.executeReadQuery(ctx, queryCtx.Query, queryCtx)
+		// Equality lookups on indexed columns read only the row groups that may match,
+		// unless policies rewrote the query
+		handled := false
+		if lookup, ok := parsePointLookup(queryCtx.Query, e.getDatabaseFromContext(queryCtx)); ok && len(policies) == 0 {
+			result, handled, err = e.executePointLookup(ctx, lookup)
+		}
+		if !handled {
+			result, err = e.executeReadQuery(ctx, query, queryCtx, sink)
+		}
 	case *parser.InsertStmt:
-		result, err = e.executeInsertQuery(ctx, queryCtx.Query, queryCtx)
+		result, err = e.executeInsertQuery(ctx, query, queryCtx)
 	case *parser.CreateTableStmt:
 		result, err = e.executeDDLQuery(ctx, queryCtx.Query, queryCtx)
 	case *parser.CreateDatabaseStmt:
@@ -131,21 +335,45 @@ func (e *Engine) ExecuteQuery(ctx context.Context, queryCtx *types.QueryContext)
 		result, err = e.executeShowStmt(ctx, stmt, queryCtx)
 	case *parser.DropTableStmt:
 		result, err = e.executeDropTable(ctx, stmt, queryCtx)
+	case *parser.AlterTableStmt:
+		result, err = e.executeAlterTable(ctx, stmt, queryCtx)
 	case *parser.UpdateStmt:
-		result, err = e.executeUpdateQuery(ctx, queryCtx.Query, queryCtx)
+		result, err = e.executeUpdateQuery(ctx, query, queryCtx)
 	case *parser.DeleteStmt:
-		result, err = e.executeDeleteQuery(ctx, queryCtx.Query, queryCtx)
+		result, err = e.executeDeleteQuery(ctx, query, queryCtx)
 	case *parser.UseStmt:
 		result, err = e.executeUseStmt(ctx, stmt, queryCtx)
 	case *parser.ExplainStmt:
-		result, err = e.executeExplainStmt(ctx, stmt, queryCtx)
+		result, err = e.executeExplainStmt(ctx, query, policies)
+	case *parser.GrantStmt:
+		result, err = e.executeGrant(ctx, stmt, queryCtx)
+	case *parser.RevokeStmt:
+		result, err = e.executeRevoke(ctx, stmt)
+	case *parser.GrantRoleStmt:
+		result, err = e.executeGrantRole(ctx, stmt)
+	case *parser.RevokeRoleStmt:
+		result, err = e.executeRevokeRole(ctx, stmt)
+	case *parser.CreateUserStmt:
+		result, err = e.executeCreateUser(ctx, stmt)
+	case *parser.DropUserStmt:
+		result, err = e.executeDropUser(ctx, stmt, queryCtx)
+	case *parser.AlterUserStmt:
+		result, err = e.executeAlterUser(ctx, stmt)
+	case *parser.CreateRoleStmt:
+		result, err = e.executeCreateRole(ctx, stmt)
+	case *parser.DropRoleStmt:
+		result, err = e.executeDropRole(ctx, stmt)
+	case *parser.CreatePolicyStmt:
+		result, err = e.executeCreatePolicy(ctx, stmt, queryCtx)
+	case *parser.DropPolicyStmt:
+		result, err = e.executeDropPolicy(ctx, stmt, queryCtx)
 	default:
 		err = errors.New(ErrUnsupportedStatementType, "unsupported statement type", nil).AddContext("statement_type", fmt.Sprintf("%T", stmt))
 	}
 
 	// Track query completion
 	if err != nil {
-		e.queryManager.CompleteQuery(queryID, 0, err)
+		e.completeQuery(queryCtx, queryID, 0, err)
 		return nil, err
 	}
 
@@ -153,11 +381,16 @@ func (e *Engine) ExecuteQuery(ctx context.Context, queryCtx *types.QueryContext)
 	result.QueryID = queryID
 
 	// Track successful completion
-	e.queryManager.CompleteQuery(queryID, result.RowCount, nil)
+	e.completeQuery(queryCtx, queryID, result.RowCount, nil)
 
 	return result, nil
 }
 
+// statementName returns the name of a statement type, such as Select or CreateTable
+func statementName(stmt parser.Node) string {
+	return strings.TrimSuffix(strings.TrimPrefix(fmt.Sprintf("%T", stmt), "*parser."), "Stmt")
+}
+
 // getDatabaseFromContext determines the database to use based on the query context
 func (e *Engine) getDatabaseFromContext(queryCtx *types.QueryContext) string {
 	// If database is specified in query context, use it
@@ -208,8 +441,9 @@ func (e *Engine) validateDatabaseExists(ctx context.Context, database string) er
 	return errors.New(ErrDatabaseNotFound, fmt.Sprintf("database '%s' does not exist", database), nil)
 }
 
-// executeReadQuery redirects read queries to DuckDB or system database
-func (e *Engine) executeReadQuery(ctx context.Context, query string, queryCtx *types.QueryContext) (*QueryResult, error) {
+// executeReadQuery redirects read queries to DuckDB or system database; DuckDB rows go to
+// sink when it is set
+func (e *Engine) executeReadQuery(ctx context.Context, query string, queryCtx *types.QueryContext, sink types.RowSink) (*QueryResult, error) {
 	e.logger.Debug().Str("query", query).Msg("Executing read query")
 
 	// Check if this is a system database query
@@ -217,6 +451,20 @@ func (e *Engine) executeReadQuery(ctx context.Context, query string, queryCtx *t
 		return e.executeSystemDatabaseQuery(ctx, query, queryCtx)
 	}
 
+	if sink != nil {
+		result, err := e.duckdbEngine.StreamQuery(ctx, query, sink)
+		if err != nil {
+			return nil, errors.New(ErrDuckDBExecutionFailed, "DuckDB execution failed", err)
+		}
+		return &QueryResult{
+			RowCount:    result.RowCount,
+			Columns:     result.Columns,
+			ColumnTypes: result.ColumnTypes,
+			Message:     "OK",
+			streamed:    true,
+		}, nil
+	}
+
 	// Execute on DuckDB engine for regular queries
 	result, err := e.duckdbEngine.ExecuteQuery(ctx, query)
 	if err != nil {
@@ -224,10 +472,11 @@ func (e *Engine) executeReadQuery(ctx context.Context, query string, queryCtx *t
 	}
 
 	return &QueryResult{
-		Data:     result.Rows,
-		RowCount: result.RowCount,
-		Columns:  result.Columns,
-		Message:  "OK",
+		Data:        result.Rows,
+		RowCount:    result.RowCount,
+		Columns:     result.Columns,
+		ColumnTypes: result.ColumnTypes,
+		Message:     "OK",
 	}, nil
 }
 
@@ -263,6 +512,10 @@ func (e *Engine) executeShowStmt(ctx context.Context, stmt *parser.ShowStmt, que
 		return e.executeShowColumns(ctx, stmt, queryCtx)
 	case parser.SHOW_CREATE_TABLE:
 		return e.executeShowCreateTable(ctx, stmt, queryCtx)
+	case parser.SHOW_GRANTS:
+		return e.executeShowGrants(ctx, stmt, queryCtx)
+	case parser.SHOW_USERS:
+		return e.executeShowUsers(ctx)
 	default:
 		return nil, errors.New(ErrUnsupportedShowType, "unsupported SHOW type", nil).AddContext("show_type", stmt.ShowType.String())
 	}
@@ -552,6 +805,38 @@ func (e *Engine) executeDropTable(ctx context.Context, stmt *parser.DropTableStm
 	}, nil
 }
 
+// executeAlterTable handles ALTER TABLE statements; SETTINGS are applied by the storage
+// engine of the table
+func (e *Engine) executeAlterTable(ctx context.Context, stmt *parser.AlterTableStmt, queryCtx *types.QueryContext) (*QueryResult, error) {
+	e.logger.Debug().
+		Str("table", stmt.TableName.Table.Value).
+		Msg("Executing ALTER TABLE")
+
+	database := e.getDatabaseFromContext(queryCtx)
+	if stmt.TableName.IsQualified() {
+		database = stmt.TableName.Database.Value
+	}
+	tableName := stmt.TableName.Table.Value
+
+	if stmt.Action != parser.AlterTableActionSettings {
+		return nil, errors.New(ErrUnsupportedAlterAction, "only ALTER TABLE ... SETTINGS is supported", nil).AddContext("database", database).AddContext("table", tableName)
+	}
+	if !e.storageMgr.TableExists(ctx, database, tableName) {
+		return nil, errors.New(ErrTableNotFound, fmt.Sprintf("table '%s' does not exist", tableName), nil).AddContext("database", database)
+	}
+
+	if err := e.storageMgr.AlterTableSettings(ctx, database, tableName, stmt.Settings); err != nil {
+		return nil, errors.New(ErrTableAlterFailed, "failed to alter table", err).AddContext("database", database).AddContext("table", tableName)
+	}
+
+	return &QueryResult{
+		Data:     [][]interface{}{},
+		RowCount: 0,
+		Columns:  []string{},
+		Message:  fmt.Sprintf("Table %s.%s altered successfully", database, tableName),
+	}, nil
+}
+
 // executeUpdateQuery handles UPDATE statements
 func (e *Engine) executeUpdateQuery(ctx context.Context, query string, queryCtx *types.QueryContext) (*QueryResult, error) {
 	e.logger.Debug().Str("query", query).Msg("Executing UPDATE query")
@@ -613,20 +898,32 @@ func (e *Engine) executeUseStmt(ctx context.Context, stmt *parser.UseStmt, query
 }
 
 // executeExplainStmt handles EXPLAIN statements
-func (e *Engine) executeExplainStmt(ctx context.Context, stmt *parser.ExplainStmt, queryCtx *types.QueryContext) (*QueryResult, error) {
+func (e *Engine) executeExplainStmt(ctx context.Context, query string, policies []*regtypes.AccessPolicy) (*QueryResult, error) {
 	e.logger.Debug().Msg("Executing EXPLAIN statement")
 
 	// For now, route to DuckDB for explanation
 	// TODO: Implement custom explanation logic
-	explainQuery := fmt.Sprintf("EXPLAIN %s", queryCtx.Query)
-	result, err := e.duckdbEngine.ExecuteQuery(ctx, explainQuery)
+	result, err := e.duckdbEngine.ExecuteQuery(ctx, query)
 	if err != nil {
 		return nil, errors.New(ErrDuckDBExecutionFailed, "EXPLAIN execution failed", err)
 	}
 
+	// DuckDB plans the rewritten query, so list the policies that shaped it
+	rows := result.Rows
+	for _, policy := range policies {
+		row := make([]interface{}, len(result.Columns))
+		if len(row) > 0 {
+			row[0] = "applied_policy"
+		}
+		if len(row) > 1 {
+			row[1] = access.DescribePolicy(policy)
+		}
+		rows = append(rows, row)
+	}
+
 	return &QueryResult{
-		Data:     result.Rows,
-		RowCount: result.RowCount,
+		Data:     rows,
+		RowCount: int64(len(rows)),
 		Columns:  result.Columns,
 		Message:  "EXPLAIN completed",
 	}, nil
@@ -645,6 +942,18 @@ func (e *Engine) InsertData(ctx context.Context, tableName string, data [][]inte
 	return nil
 }
 
+// InsertRecords inserts columnar Arrow records into the specified table
+func (e *Engine) InsertRecords(ctx context.Context, database, tableName string, reader array.RecordReader) error {
+	e.logger.Info().Str("database", database).Str("table", tableName).Msg("Inserting Arrow records")
+
+	if err := e.storageMgr.InsertRecords(ctx, database, tableName, reader); err != nil {
+		return err
+	}
+
+	e.logger.Info().Str("database", database).Str("table", tableName).Msg("Arrow records inserted successfully")
+	return nil
+}
+
 // InsertDataStreaming inserts data using streaming for memory efficiency
 func (e *Engine) InsertDataStreaming(ctx context.Context, database, tableName string, dataReader io.Reader) error {
 	e.logger.Info().Str("database", database).Str("table", tableName).Msg("Inserting data using streaming")
@@ -683,6 +992,35 @@ func (e *Engine) InsertDataBatchStreaming(ctx context.Context, database, tableNa
 	return nil
 }
 
+// GetTableSchema returns the Iceberg schema of the specified table
+func (e *Engine) GetTableSchema(ctx context.Context, database, tableName string) (*iceberg.Schema, error) {
+	if !e.storageMgr.TableExists(ctx, database, tableName) {
+		return nil, errors.New(ErrTableNotFound, fmt.Sprintf("table '%s' does not exist", tableName), nil).AddContext("database", database)
+	}
+
+	schema, err := e.storageMgr.GetSchema(ctx, database, tableName)
+	if err != nil {
+		return nil, errors.New(ErrSchemaRetrievalFailed, "failed to retrieve table schema", err).
+			AddContext("database", database).
+			AddContext("table", tableName)
+	}
+
+	return schema, nil
+}
+
+// GetTableSettings returns the registry settings of the specified table, or nil when it has none
+func (e *Engine) GetTableSettings(ctx context.Context, database, tableName string) (*regtypes.TableMetadata, error) {
+	if !e.storageMgr.TableExists(ctx, database, tableName) {
+		return nil, errors.New(ErrTableNotFound, fmt.Sprintf("table '%s' does not exist", tableName), nil).AddContext("database", database)
+	}
+	return e.storageMgr.GetTableSettings(ctx, database, tableName)
+}
+
+// GetUser returns the registry record of the specified user
+func (e *Engine) GetUser(ctx context.Context, username string) (*regtypes.User, error) {
+	return e.storageMgr.GetUser(ctx, username)
+}
+
 // GetTableData retrieves data from the specified table
 func (e *Engine) GetTableData(ctx context.Context, database, tableName string, limit int) ([][]interface{}, error) {
 	e.logger.Info().Str("database", database).Str("table", tableName).Int("limit", limit).Msg("Retrieving table data")
@@ -1048,11 +1386,69 @@ func (e *Engine) executeSystemDatabaseQuery(ctx context.Context, query string, q
 	}, nil
 }
 
+// isCatalogQuery checks if a query targets the emulated pg_catalog or information_schema
+func (e *Engine) isCatalogQuery(query string) bool {
+	systemMgr := e.storageMgr.GetSystemManager()
+	return systemMgr.IsCatalogQuery(query)
+}
+
+// executeCatalogQuery executes PostgreSQL catalog queries against the registry views,
+// restricted to the objects the user holds privileges on, and records them in the access log
+func (e *Engine) executeCatalogQuery(ctx context.Context, queryCtx *types.QueryContext) (result *QueryResult, err error) {
+	e.logger.Debug().Str("query", queryCtx.Query).Msg("Executing catalog query")
+
+	systemMgr := e.storageMgr.GetSystemManager()
+	relations, _ := systemMgr.CatalogRelations(queryCtx.Query)
+
+	start := time.Now()
+	var grants *access.Grants
+	defer func() {
+		e.auditCatalogQuery(ctx, relations, queryCtx, grants, start, err)
+	}()
+
+	authCtx, authSpan := tracing.Start(ctx, "query.authorize")
+	grants, err = e.accessChecker.EffectivePrivileges(authCtx, queryCtx.User)
+	tracing.End(authSpan, err)
+	if err != nil {
+		return nil, err
+	}
+
+	catalog, err := systemMgr.QueryCatalog(ctx, queryCtx.Query, &system.CatalogSession{
+		Database: e.getDatabaseFromContext(queryCtx),
+		User:     queryCtx.User,
+		Visible:  grants.Visible,
+	})
+	if err != nil {
+		return nil, errors.New(ErrCatalogQueryFailed, "catalog query execution failed", err)
+	}
+
+	return &QueryResult{
+		Data:     catalog.Data,
+		RowCount: catalog.RowCount,
+		Columns:  catalog.Columns,
+		Message:  fmt.Sprintf("Catalog query executed successfully, %d rows returned", catalog.RowCount),
+	}, nil
+}
+
 // GetType returns the component type identifier
 func (e *Engine) GetType() string {
 	return ComponentType
 }
 
+// CheckHealth reports whether DuckDB still answers and how many queries are running
+func (e *Engine) CheckHealth(ctx context.Context) shared.Health {
+	health := shared.Health{
+		Live:    true,
+		Ready:   true,
+		Details: map[string]interface{}{"running_queries": len(e.queryManager.ListRunningQueries())},
+	}
+	if err := e.duckdbEngine.Ping(ctx); err != nil {
+		health.Ready = false
+		health.Message = err.Error()
+	}
+	return health
+}
+
 // Shutdown gracefully shuts down the query engine
 func (e *Engine) Shutdown(ctx context.Context) error {
 	e.logger.Info().Msg("Shutting down query engine")
@@ -1062,6 +1458,11 @@ func (e *Engine) Shutdown(ctx context.Context) error {
 		return errors.New(ErrQueryEngineCloseFailed, "failed to close query engine", err)
 	}
 
+	// Flush and close the audit sink
+	if err := e.auditor.Close(); err != nil {
+		return errors.New(ErrAuditLoggerCloseFailed, "failed to close audit logger", err)
+	}
+
 	e.logger.Info().Msg("Query engine shut down successfully")
 	return nil
 }
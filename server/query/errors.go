@@ -35,9 +35,21 @@ var (
 	ErrQueryNotRunning             = errors.MustNewCode("query.not_running")
 	ErrTableNotFound               = errors.MustNewCode("query.table_not_found")
 	ErrTableDropFailed             = errors.MustNewCode("query.table_drop_failed")
+	ErrTableAlterFailed            = errors.MustNewCode("query.table_alter_failed")
+	ErrUnsupportedAlterAction      = errors.MustNewCode("query.unsupported_alter_action")
 	ErrTableCreationFailed         = errors.MustNewCode("query.table_creation_failed")
 	ErrSchemaUnmarshalFailed       = errors.MustNewCode("query.schema_unmarshal_failed")
 	ErrTableNameRequired           = errors.MustNewCode("query.table_name_required")
 	ErrColumnListFailed            = errors.MustNewCode("query.column_list_failed")
 	ErrDDLGenerationFailed         = errors.MustNewCode("query.ddl_generation_failed")
+	ErrCatalogQueryFailed          = errors.MustNewCode("query.catalog_query_failed")
+	ErrGranteeNotFound             = errors.MustNewCode("query.grantee_not_found")
+	ErrPrivilegeUpdateFailed       = errors.MustNewCode("query.privilege_update_failed")
+	ErrPrivilegeListFailed         = errors.MustNewCode("query.privilege_list_failed")
+	ErrUserManagementFailed        = errors.MustNewCode("query.user_management_failed")
+	ErrPasswordHashFailed          = errors.MustNewCode("query.password_hash_failed")
+	ErrPolicyUpdateFailed          = errors.MustNewCode("query.policy_update_failed")
+	ErrAuditLoggerCreationFailed   = errors.MustNewCode("query.audit_logger_creation_failed")
+	ErrAuditLoggerCloseFailed      = errors.MustNewCode("query.audit_logger_close_failed")
+	ErrQueryIDInUse                = errors.MustNewCode("query.id_in_use")
 )
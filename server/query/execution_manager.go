@@ -33,6 +33,7 @@ type QueryInfo struct {
 	CancelFunc context.CancelFunc `json:"-"`
 	Error      error              `json:"error,omitempty"`
 	RowCount   int64              `json:"row_count,omitempty"`
+	RowsRead   int64              `json:"rows_read,omitempty"` // progress while running
 }
 
 // ExecutionManager manages running queries and provides cancellation capabilities
@@ -55,6 +56,24 @@ func (em *ExecutionManager) StartQuery(ctx context.Context, queryID, query, user
 	em.mu.Lock()
 	defer em.mu.Unlock()
 
+	return em.startQueryLocked(ctx, queryID, query, user, clientAddr)
+}
+
+// StartUniqueQuery starts tracking a query under a client-chosen ID, failing while another
+// query with that ID is running
+func (em *ExecutionManager) StartUniqueQuery(ctx context.Context, queryID, query, user, clientAddr string) (*QueryInfo, context.Context, error) {
+	em.mu.Lock()
+	defer em.mu.Unlock()
+
+	if existing, exists := em.queries[queryID]; exists && existing.Status == QueryStatusRunning {
+		return nil, nil, errors.New(ErrQueryIDInUse, "a query with this ID is already running", nil).AddContext("query_id", queryID)
+	}
+	queryInfo, ctx := em.startQueryLocked(ctx, queryID, query, user, clientAddr)
+	return queryInfo, ctx, nil
+}
+
+// startQueryLocked registers a running query; em.mu must be held
+func (em *ExecutionManager) startQueryLocked(ctx context.Context, queryID, query, user, clientAddr string) (*QueryInfo, context.Context) {
 	// Create cancellable context
 	ctx, cancel := context.WithCancel(ctx)
 
@@ -94,10 +113,13 @@ func (em *ExecutionManager) CompleteQuery(queryID string, rowCount int64, err er
 	queryInfo.Duration = &duration
 	queryInfo.RowCount = rowCount
 
-	if err != nil {
+	switch {
+	case queryInfo.Status == QueryStatusCancelled:
+		// A cancelled query ends with the cancellation error, which is not a failure
+	case err != nil:
 		queryInfo.Status = QueryStatusFailed
 		queryInfo.Error = err
-	} else {
+	default:
 		queryInfo.Status = QueryStatusCompleted
 	}
 
@@ -111,6 +133,16 @@ func (em *ExecutionManager) CompleteQuery(queryID string, rowCount int64, err er
 	return nil
 }
 
+// UpdateProgress records the number of rows a running query has produced so far
+func (em *ExecutionManager) UpdateProgress(queryID string, rowsRead int64) {
+	em.mu.Lock()
+	defer em.mu.Unlock()
+
+	if queryInfo, exists := em.queries[queryID]; exists && queryInfo.Status == QueryStatusRunning {
+		queryInfo.RowsRead = rowsRead
+	}
+}
+
 // CancelQuery cancels a running query
 func (em *ExecutionManager) CancelQuery(queryID string) error {
 	em.mu.Lock()
@@ -143,7 +175,7 @@ func (em *ExecutionManager) CancelQuery(queryID string) error {
 	return nil
 }
 
-// GetQueryInfo returns information about a specific query
+// GetQueryInfo returns a snapshot of information about a specific query
 func (em *ExecutionManager) GetQueryInfo(queryID string) (*QueryInfo, error) {
 	em.mu.RLock()
 	defer em.mu.RUnlock()
@@ -153,7 +185,9 @@ func (em *ExecutionManager) GetQueryInfo(queryID string) (*QueryInfo, error) {
 		return nil, errors.New(ErrQueryNotFound, "query not found", nil).AddContext("query_id", queryID)
 	}
 
-	return queryInfo, nil
+	// The query keeps changing while it runs, so callers get a copy
+	snapshot := *queryInfo
+	return &snapshot, nil
 }
 
 // ListQueries returns a list of all queries (running and completed)
@@ -0,0 +1,61 @@
+package query
+
+import (
+	"github.com/gear6io/ranger/server/metadata/registry/regtypes"
+	"github.com/gear6io/ranger/server/metrics"
+	"github.com/gear6io/ranger/server/query/duckdb"
+	"github.com/gear6io/ranger/server/types"
+)
+
+// registerMetrics exposes the statistics of the execution manager and the DuckDB engine
+func (e *Engine) registerMetrics() {
+	metrics.Register(metrics.NewStatsCollector("query", e.queryManager.GetStats,
+		metrics.GaugeVec("tracked_queries", "Queries held by the execution manager, by status.", "status",
+			func(stats map[string]interface{}) map[string]float64 {
+				values := make(map[string]float64, len(stats))
+				for status, count := range stats {
+					if status != "total" {
+						values[status] = metrics.Number(count)
+					}
+				}
+				return values
+			}),
+	))
+
+	metrics.Register(metrics.NewStatsCollector("duckdb", e.duckdbEngine.GetMetrics,
+		metrics.Counter("queries_executed_total", "Statements executed by DuckDB.",
+			func(m *duckdb.EngineMetrics) float64 { return float64(m.QueriesExecuted) }),
+		metrics.Counter("query_seconds_total", "Time DuckDB spent executing statements.",
+			func(m *duckdb.EngineMetrics) float64 { return m.TotalQueryTime.Seconds() }),
+		metrics.Counter("errors_total", "Statements DuckDB failed to execute.",
+			func(m *duckdb.EngineMetrics) float64 { return float64(m.ErrorCount) }),
+		metrics.Counter("blocked_queries_total", "Statements rejected by query validation.",
+			func(m *duckdb.EngineMetrics) float64 { return float64(m.BlockedQueries) }),
+		metrics.Counter("tables_registered_total", "Tables registered with DuckDB.",
+			func(m *duckdb.EngineMetrics) float64 { return float64(m.TablesRegistered) }),
+		metrics.Counter("cache_hits_total", "DuckDB table cache hits.",
+			func(m *duckdb.EngineMetrics) float64 { return float64(m.CacheHits) }),
+		metrics.Counter("cache_misses_total", "DuckDB table cache misses.",
+			func(m *duckdb.EngineMetrics) float64 { return float64(m.CacheMisses) }),
+	))
+}
+
+// completeQuery ends the tracking of a query and records it in the query metrics
+func (e *Engine) completeQuery(queryCtx *types.QueryContext, queryID string, rowCount int64, err error) {
+	e.queryManager.CompleteQuery(queryID, rowCount, err)
+	if info, infoErr := e.queryManager.GetQueryInfo(queryID); infoErr == nil && info.Duration != nil {
+		metrics.ObserveQuery(queryCtx.Protocol, string(info.Status), *info.Duration)
+	}
+}
+
+// metricsStatus returns the metrics status of an operation that ended with err
+func metricsStatus(err error) string {
+	switch accessStatus(err) {
+	case regtypes.AccessStatusOK:
+		return metrics.StatusOK
+	case regtypes.AccessStatusDenied:
+		return metrics.StatusDenied
+	default:
+		return metrics.StatusFailed
+	}
+}
@@ -31,6 +31,11 @@ func TestAlterTableSettingsParsing(t *testing.T) {
 			sql:         "ALTER TABLE analytics SETTINGS max_schema_versions=5, batch_validation_size=20000",
 			expectError: false,
 		},
+		{
+			name:        "ALTER TABLE SETTINGS with quoted values",
+			sql:         "ALTER TABLE hot_events SETTINGS hot_tier_bytes=1048576, durability='wal'",
+			expectError: false,
+		},
 		{
 			name:        "ALTER TABLE SETTINGS missing equals",
 			sql:         "ALTER TABLE test_db.users SETTINGS cache_enabled",
@@ -120,6 +125,13 @@ func TestAlterTableSettingsParsing(t *testing.T) {
 				if val, exists := alterStmt.Settings["compression"]; !exists || val != "gzip" {
 					t.Errorf("Expected compression=gzip, got %v", val)
 				}
+			case "ALTER TABLE SETTINGS with quoted values":
+				if val, exists := alterStmt.Settings["hot_tier_bytes"]; !exists || val != 1048576 {
+					t.Errorf("Expected hot_tier_bytes=1048576, got %v", val)
+				}
+				if val, exists := alterStmt.Settings["durability"]; !exists || val != "wal" {
+					t.Errorf("Expected durability=wal, got %v", val)
+				}
 			}
 		})
 	}
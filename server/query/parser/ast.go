@@ -175,6 +175,19 @@ func (stmt *CreateTableStmt) Validate() error {
 		}
 	}
 
+	// Data files are sorted by the ORDER BY columns, which must hold primitive values
+	for _, column := range stmt.OrderBy {
+		colDef, ok := stmt.TableSchema.ColumnDefinitions[column.Value]
+		if !ok {
+			return errors.New(ErrUnknownSortColumn,
+				fmt.Sprintf("ORDER BY column '%s' is not a column of the table", column.Value), nil)
+		}
+		if icebergType, err := validator.ParseType(colDef.DataType); err == nil && icebergType.IsComplex() {
+			return errors.New(ErrUnsortableColumn,
+				fmt.Sprintf("ORDER BY column '%s': cannot sort by type '%s'", column.Value, colDef.DataType), nil)
+		}
+	}
+
 	stmt.validated = true
 	return nil
 }
@@ -474,9 +487,58 @@ type RevokeStmt struct {
 // PrivilegeDefinition Privilege represents a privilege
 type PrivilegeDefinition struct {
 	Actions []PrivilegeAction
-	Object  *Identifier // can be dbname.* or dbname.tablename, or *
-	Grantee *Identifier // User
-	Revokee *Identifier // User
+	Columns []*Identifier // optional column list, i.e. SELECT (a, b) ON db.t
+	Object  *Identifier   // can be dbname.* or dbname.tablename, or *
+	Grantee *Identifier   // User or role
+	Revokee *Identifier   // User or role
+}
+
+// GrantRoleStmt represents a GRANT role TO user statement
+type GrantRoleStmt struct {
+	Roles   []*Identifier
+	Grantee *Identifier
+}
+
+// RevokeRoleStmt represents a REVOKE role FROM user statement
+type RevokeRoleStmt struct {
+	Roles   []*Identifier
+	Revokee *Identifier
+}
+
+// CreateRoleStmt represents a CREATE ROLE statement
+type CreateRoleStmt struct {
+	Name *Identifier
+}
+
+// DropRoleStmt represents a DROP ROLE statement
+type DropRoleStmt struct {
+	Name *Identifier
+}
+
+// PolicyKind is the kind of an access policy
+type PolicyKind string
+
+const (
+	POLICY_ROW_ACCESS PolicyKind = "ROW ACCESS"
+	POLICY_MASKING    PolicyKind = "MASKING"
+)
+
+// CreatePolicyStmt represents a CREATE ROW ACCESS POLICY or CREATE MASKING POLICY statement
+type CreatePolicyStmt struct {
+	Kind       PolicyKind
+	Name       *Identifier
+	Table      *TableIdentifier
+	Column     *Identifier   // masked column, masking policies only
+	Expression string        // row predicate or masking expression, as SQL text
+	To         []*Identifier // users and roles a row access policy applies to; everyone when empty
+	Exempt     []*Identifier // users and roles a masking policy does not apply to
+}
+
+// DropPolicyStmt represents a DROP ROW ACCESS POLICY or DROP MASKING POLICY statement
+type DropPolicyStmt struct {
+	Kind  PolicyKind
+	Name  *Identifier
+	Table *TableIdentifier
 }
 
 // CreateUserStmt represents a CREATE USER statement
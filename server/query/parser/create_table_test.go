@@ -73,6 +73,15 @@ func TestCreateTableParsing(t *testing.T) {
 				}
 			},
 		},
+		{
+			name: "CREATE TABLE with quoted SETTINGS value",
+			sql:  "CREATE TABLE sessions (id int32) STORAGE MEMORY SETTINGS durability = 'wal'",
+			checkFunc: func(t *testing.T, stmt *CreateTableStmt) {
+				if stmt.Settings["durability"] != "wal" {
+					t.Errorf("Expected durability=wal without quotes, got %v", stmt.Settings["durability"])
+				}
+			},
+		},
 		{
 			name: "CREATE TABLE with all clauses",
 			sql:  "CREATE TABLE analytics (id int32, date date, metric string, value float64) STORAGE s3 PARTITION BY (date) ORDER BY (metric) SETTINGS compression=gzip, cache_size=1000",
@@ -182,6 +191,18 @@ func TestCreateTableClauseParsing(t *testing.T) {
 			expectError: false,
 			description: "Should parse multiple clauses correctly",
 		},
+		{
+			name:        "ORDER BY unknown column",
+			sql:         "CREATE TABLE test (id int32, name string) STORAGE FILESYSTEM ORDER BY (created_at);",
+			expectError: true,
+			description: "Should reject sorting by a column the table does not have",
+		},
+		{
+			name:        "ORDER BY complex column",
+			sql:         "CREATE TABLE test (id int32, tags list<string>) STORAGE FILESYSTEM ORDER BY (tags);",
+			expectError: true,
+			description: "Should reject sorting by a column that does not hold primitive values",
+		},
 	}
 
 	for _, tt := range tests {
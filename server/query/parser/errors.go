@@ -64,6 +64,7 @@ var (
 	ErrExpectedSelectInsert        = errors.MustNewCode("parser.syntax.expected_select_insert_update_delete")
 	ErrExpectedPrivilege           = errors.MustNewCode("parser.syntax.expected_privilege")
 	ErrExpectedOn                  = errors.MustNewCode("parser.syntax.expected_on")
+	ErrExpectedExpression          = errors.MustNewCode("parser.syntax.expected_expression")
 	ErrExpectedStarOrTable         = errors.MustNewCode("parser.syntax.expected_star_or_table")
 	ErrExpectedDotAfterDB          = errors.MustNewCode("parser.syntax.expected_dot_after_database")
 	ErrExpectedDatabaseOrStar      = errors.MustNewCode("parser.syntax.expected_database_or_star")
@@ -73,6 +74,8 @@ var (
 	ErrExpectedExistsAfterIf       = errors.MustNewCode("parser.syntax.expected_exists_after_if")
 	ErrExpectedValues              = errors.MustNewCode("parser.syntax.expected_values")
 	ErrExpectedLiteralOrNull       = errors.MustNewCode("parser.syntax.expected_literal_or_null")
+	ErrMultipleStatements          = errors.MustNewCode("parser.syntax.multiple_statements")
+	ErrUnparsedTokens              = errors.MustNewCode("parser.syntax.unparsed_tokens")
 
 	// Validation errors
 	ErrTableNameRequired            = errors.MustNewCode("parser.validation.table_name_required")
@@ -90,6 +93,8 @@ var (
 	ErrDuplicateColumnName   = errors.MustNewCode("parser.iceberg.duplicate_column_name")
 	ErrEmptyColumnName       = errors.MustNewCode("parser.iceberg.empty_column_name")
 	ErrNoColumnsSpecified    = errors.MustNewCode("parser.iceberg.no_columns_specified")
+	ErrUnknownSortColumn     = errors.MustNewCode("parser.iceberg.unknown_sort_column")
+	ErrUnsortableColumn      = errors.MustNewCode("parser.iceberg.unsortable_column")
 
 	// Multi-error reporting
 	ErrMultipleParseErrors = errors.MustNewCode("parser.syntax.multiple_parse_errors")
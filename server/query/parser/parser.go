@@ -730,6 +730,33 @@ func Parse(query string) (Node, error) {
 	return parser.Parse()
 }
 
+// ParseSingle parses query as one statement for running as a whole, as its text is run once
+// checked. Input holding several statements is rejected, and so are reads and writes the
+// parser stopped short of the end of.
+func ParseSingle(query string) (Node, error) {
+	parser := NewParser(NewLexer([]byte(query)))
+	stmt, err := parser.Parse()
+	if err != nil {
+		return nil, err
+	}
+	for _, token := range parser.lexer.tokens[:len(parser.lexer.tokens)-1] {
+		if token.tokenT == SEMICOLON_TOK {
+			return nil, errors.New(ErrMultipleStatements, "only one statement may be run at a time", nil).
+				AddContext("offset", token.Position.Offset)
+		}
+	}
+
+	// Other statements may leave their closing tokens to the semicolon check
+	switch stmt.(type) {
+	case *SelectStmt, *ExplainStmt, *InsertStmt, *UpdateStmt, *DeleteStmt:
+		if !parser.Complete() {
+			return nil, errors.New(ErrUnparsedTokens, "statement could not be parsed to its end", nil).
+				AddContext("offset", parser.lexer.tokens[parser.pos].Position.Offset)
+		}
+	}
+	return stmt, nil
+}
+
 // NewParser creates a new parser
 func NewParser(lexer *Lexer) *Parser {
 	return &Parser{
@@ -745,6 +772,12 @@ func (p *Parser) consume() {
 	p.pos++
 }
 
+// Complete reports whether the statement parsed consumed every token up to its closing
+// semicolon, as statements may parse without their trailing tokens
+func (p *Parser) Complete() bool {
+	return p.pos >= len(p.lexer.tokens)-1
+}
+
 // Enhanced error creation methods for better error reporting
 
 // newSyntaxError creates a new syntax error with enhanced context
@@ -2069,9 +2102,11 @@ func (p *Parser) parseAlterTableStmt() (Node, error) {
 			var value interface{}
 			if p.peek(0).tokenT == LITERAL_TOK {
 				rawValue := p.peek(0).value
-				// Convert uint64 to int for consistency
+				// Convert uint64 to int for consistency, and strip the quotes of strings
 				if uint64Val, ok := rawValue.(uint64); ok {
 					value = int(uint64Val)
+				} else if strVal, ok := rawValue.(string); ok {
+					value = strings.TrimSuffix(strings.TrimPrefix(strVal, "'"), "'")
 				} else {
 					value = rawValue
 				}
@@ -2374,6 +2409,10 @@ func (p *Parser) parseShowStmt() (Node, error) {
 func (p *Parser) parseRevokeStmt() (Node, error) {
 	p.consume() // Consume REVOKE
 
+	if p.peek(0).tokenT == IDENT_TOK {
+		return p.parseRoleGrantStmt(true)
+	}
+
 	if p.peek(0).tokenT != KEYWORD_TOK {
 		return nil, errors.New(ErrExpectedKeyword, "expected keyword", nil)
 	}
@@ -2392,6 +2431,10 @@ func (p *Parser) parseGrantStmt() (Node, error) {
 
 	p.consume() // Consume GRANT
 
+	if p.peek(0).tokenT == IDENT_TOK {
+		return p.parseRoleGrantStmt(false)
+	}
+
 	if p.peek(0).tokenT != KEYWORD_TOK {
 		return nil, errors.New(ErrExpectedKeyword, "expected keyword", nil)
 	}
@@ -2537,6 +2580,15 @@ func (p *Parser) parsePrivilegeStmt(revoke bool) (Node, error) {
 
 		p.consume()
 
+		// Optional column list restricting the privilege to those columns
+		if p.peek(0).tokenT == LPAREN_TOK {
+			columns, err := p.parseIdentifierList()
+			if err != nil {
+				return nil, err
+			}
+			privilegeDefinition.Columns = append(privilegeDefinition.Columns, columns...)
+		}
+
 		if p.peek(0).tokenT == COMMA_TOK {
 			p.consume()
 			continue
@@ -2566,13 +2618,17 @@ func (p *Parser) parsePrivilegeStmt(revoke bool) (Node, error) {
 		// if asterisk is found, set database to *
 		if p.peek(0).value == "*" {
 			db = &Identifier{Value: "*"}
+			table = &Identifier{Value: "*"}
 			p.consume()
 
-			// check if next value is * or identifier
-			if p.peek(0).value == ".*" {
-				table = &Identifier{Value: "*"}
+			// *.* is the same object as a bare *
+			if p.peek(0).tokenT == DOT_TOK {
+				p.consume() // Consume .
+				if p.peek(0).tokenT != ASTERISK_TOK {
+					return nil, errors.New(ErrExpectedStarOrTableAfterDot, "expected * after *.", nil)
+				}
+				p.consume() // Consume *
 			}
-			p.consume() // Consume table name
 			privilegeDefinition.Object = &Identifier{Value: db.Value + "." + table.Value}
 
 		} else {
@@ -2632,6 +2688,255 @@ func (p *Parser) parsePrivilegeStmt(revoke bool) (Node, error) {
 
 }
 
+// parseIdentifierList parses a parenthesized, comma-separated list of identifiers
+func (p *Parser) parseIdentifierList() ([]*Identifier, error) {
+	p.consume() // Consume (
+
+	var identifiers []*Identifier
+	for {
+		if p.peek(0).tokenT != IDENT_TOK {
+			return nil, errors.New(ErrExpectedIdentifier, "expected identifier", nil)
+		}
+		identifiers = append(identifiers, &Identifier{Value: p.peek(0).value.(string)})
+		p.consume() // Consume identifier
+
+		if p.peek(0).tokenT == COMMA_TOK {
+			p.consume() // Consume ,
+			continue
+		}
+		break
+	}
+
+	if p.peek(0).tokenT != RPAREN_TOK {
+		return nil, errors.New(ErrExpectedRightParen, "expected )", nil)
+	}
+	p.consume() // Consume )
+
+	return identifiers, nil
+}
+
+// parseRoleGrantStmt parses GRANT role [, role] TO user and REVOKE role [, role] FROM user
+func (p *Parser) parseRoleGrantStmt(revoke bool) (Node, error) {
+	var roles []*Identifier
+	for {
+		if p.peek(0).tokenT != IDENT_TOK {
+			return nil, errors.New(ErrExpectedIdentifier, "expected identifier", nil)
+		}
+		roles = append(roles, &Identifier{Value: p.peek(0).value.(string)})
+		p.consume() // Consume role name
+
+		if p.peek(0).tokenT == COMMA_TOK {
+			p.consume() // Consume ,
+			continue
+		}
+		break
+	}
+
+	if revoke {
+		if p.peek(0).value != "FROM" {
+			return nil, errors.New(ErrExpectedFrom, "expected FROM", nil)
+		}
+	} else if p.peek(0).value != "TO" {
+		return nil, errors.New(ErrExpectedKeyword, "expected TO", nil)
+	}
+	p.consume() // Consume TO or FROM
+
+	if p.peek(0).tokenT != IDENT_TOK {
+		return nil, errors.New(ErrExpectedIdentifier, "expected identifier", nil)
+	}
+	user := &Identifier{Value: p.peek(0).value.(string)}
+	p.consume() // Consume user
+
+	if revoke {
+		return &RevokeRoleStmt{Roles: roles, Revokee: user}, nil
+	}
+	return &GrantRoleStmt{Roles: roles, Grantee: user}, nil
+}
+
+// parseCreateRoleStmt parses a CREATE ROLE statement
+func (p *Parser) parseCreateRoleStmt() (Node, error) {
+	p.consume() // Consume ROLE
+
+	if p.peek(0).tokenT != IDENT_TOK {
+		return nil, errors.New(ErrExpectedIdentifier, "expected identifier", nil)
+	}
+
+	role := p.peek(0).value.(string)
+	p.consume() // Consume role name
+
+	return &CreateRoleStmt{Name: &Identifier{Value: role}}, nil
+}
+
+// parseDropRoleStmt parses a DROP ROLE statement
+func (p *Parser) parseDropRoleStmt() (Node, error) {
+	p.consume() // Consume ROLE
+
+	if p.peek(0).tokenT != IDENT_TOK {
+		return nil, errors.New(ErrExpectedIdentifier, "expected identifier", nil)
+	}
+
+	role := p.peek(0).value.(string)
+	p.consume() // Consume role name
+
+	return &DropRoleStmt{Name: &Identifier{Value: role}}, nil
+}
+
+// parseCreatePolicyStmt parses a CREATE ROW ACCESS POLICY or CREATE MASKING POLICY statement
+func (p *Parser) parseCreatePolicyStmt() (Node, error) {
+	kind, name, table, err := p.parsePolicyTarget()
+	if err != nil {
+		return nil, err
+	}
+	stmt := &CreatePolicyStmt{Kind: kind, Name: name, Table: table}
+
+	// Masking policies name the masked column in parentheses
+	if kind == POLICY_MASKING {
+		if p.peek(0).tokenT != LPAREN_TOK {
+			return nil, errors.New(ErrExpectedLeftParen, "expected (", nil)
+		}
+		p.consume() // Consume (
+
+		if p.peek(0).tokenT != IDENT_TOK {
+			return nil, errors.New(ErrExpectedIdentifier, "expected identifier", nil)
+		}
+		stmt.Column = &Identifier{Value: p.peek(0).value.(string)}
+		p.consume() // Consume column
+
+		if p.peek(0).tokenT != RPAREN_TOK {
+			return nil, errors.New(ErrExpectedRightParen, "expected )", nil)
+		}
+		p.consume() // Consume )
+	}
+
+	if !p.peekWord(0, "USING") {
+		return nil, errors.New(ErrExpectedKeyword, "expected USING", nil)
+	}
+	p.consume() // Consume USING
+
+	stmt.Expression, err = p.parseRawParenthesized()
+	if err != nil {
+		return nil, err
+	}
+
+	switch {
+	case kind == POLICY_ROW_ACCESS && p.peek(0).value == "TO":
+		p.consume() // Consume TO
+		stmt.To, err = p.parseGranteeList()
+	case kind == POLICY_MASKING && p.peekWord(0, "EXEMPT"):
+		p.consume() // Consume EXEMPT
+		stmt.Exempt, err = p.parseGranteeList()
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return stmt, nil
+}
+
+// parseDropPolicyStmt parses a DROP ROW ACCESS POLICY or DROP MASKING POLICY statement
+func (p *Parser) parseDropPolicyStmt() (Node, error) {
+	kind, name, table, err := p.parsePolicyTarget()
+	if err != nil {
+		return nil, err
+	}
+	return &DropPolicyStmt{Kind: kind, Name: name, Table: table}, nil
+}
+
+// parsePolicyTarget parses ROW ACCESS POLICY name ON table or MASKING POLICY name ON table
+func (p *Parser) parsePolicyTarget() (PolicyKind, *Identifier, *TableIdentifier, error) {
+	kind := POLICY_MASKING
+	if p.peekWord(0, "ROW") {
+		if !p.peekWord(1, "ACCESS") {
+			return "", nil, nil, errors.New(ErrExpectedKeyword, "expected ACCESS", nil)
+		}
+		kind = POLICY_ROW_ACCESS
+		p.consume() // Consume ROW
+	}
+	p.consume() // Consume ACCESS or MASKING
+
+	if !p.peekWord(0, "POLICY") {
+		return "", nil, nil, errors.New(ErrExpectedKeyword, "expected POLICY", nil)
+	}
+	p.consume() // Consume POLICY
+
+	if p.peek(0).tokenT != IDENT_TOK {
+		return "", nil, nil, errors.New(ErrExpectedIdentifier, "expected identifier", nil)
+	}
+	name := &Identifier{Value: p.peek(0).value.(string)}
+	p.consume() // Consume policy name
+
+	if p.peek(0).value != "ON" {
+		return "", nil, nil, errors.New(ErrExpectedOn, "expected ON", nil)
+	}
+	p.consume() // Consume ON
+
+	table, err := p.parseTableIdentifier()
+	if err != nil {
+		return "", nil, nil, err
+	}
+
+	return kind, name, table, nil
+}
+
+// parseGranteeList parses a comma-separated list of users and roles
+func (p *Parser) parseGranteeList() ([]*Identifier, error) {
+	var identifiers []*Identifier
+	for {
+		if p.peek(0).tokenT != IDENT_TOK {
+			return nil, errors.New(ErrExpectedIdentifier, "expected identifier", nil)
+		}
+		identifiers = append(identifiers, &Identifier{Value: p.peek(0).value.(string)})
+		p.consume() // Consume identifier
+
+		if p.peek(0).tokenT != COMMA_TOK {
+			return identifiers, nil
+		}
+		p.consume() // Consume ,
+	}
+}
+
+// parseRawParenthesized consumes a parenthesized expression and returns the source text
+// between the parentheses, so it can be handed to the execution engine unchanged
+func (p *Parser) parseRawParenthesized() (string, error) {
+	if p.peek(0).tokenT != LPAREN_TOK {
+		return "", errors.New(ErrExpectedLeftParen, "expected (", nil)
+	}
+	start := p.peek(0).Position.Offset + p.peek(0).Position.Length
+	p.consume() // Consume (
+
+	for depth := 1; ; p.consume() {
+		switch p.peek(0).tokenT {
+		case EOF_TOK:
+			return "", errors.New(ErrExpectedRightParen, "expected )", nil)
+		case LPAREN_TOK:
+			depth++
+		case RPAREN_TOK:
+			depth--
+		}
+		if depth == 0 {
+			break
+		}
+	}
+
+	expression := strings.TrimSpace(string(p.lexer.input[start:p.peek(0).Position.Offset]))
+	p.consume() // Consume )
+
+	if expression == "" {
+		return "", errors.New(ErrExpectedExpression, "expected expression", nil)
+	}
+	return expression, nil
+}
+
+// peekWord reports whether the token at offset i is the unreserved word w
+func (p *Parser) peekWord(i int, w string) bool {
+	token := p.peek(i)
+	if token.tokenT != IDENT_TOK && token.tokenT != KEYWORD_TOK {
+		return false
+	}
+	value, ok := token.value.(string)
+	return ok && strings.EqualFold(value, w)
+}
+
 // parseBeginStmt parses a BEGIN statement
 func (p *Parser) parseBeginStmt() (Node, error) {
 	p.consume() // Consume BEGIN
@@ -2781,6 +3086,16 @@ func (p *Parser) parseUpdateStmt() (Node, error) {
 func (p *Parser) parseDropStmt() (Node, error) {
 	p.consume() // Consume DROP
 
+	// ROLE, ROW and MASKING are not reserved, so they arrive as identifiers
+	if p.peek(0).tokenT == IDENT_TOK {
+		switch strings.ToUpper(p.peek(0).value.(string)) {
+		case "ROLE":
+			return p.parseDropRoleStmt()
+		case "ROW", "MASKING":
+			return p.parseDropPolicyStmt()
+		}
+	}
+
 	if p.peek(0).tokenT != KEYWORD_TOK {
 		return nil, errors.New(ErrExpectedKeyword, "expected keyword", nil)
 	}
@@ -3098,8 +3413,18 @@ func (p *Parser) parseInsertStmt() (Node, error) {
 func (p *Parser) parseCreateStmt() (Node, error) {
 	p.consume() // Consume CREATE
 
+	// ROLE, ROW and MASKING are not reserved, so they arrive as identifiers
+	if p.peek(0).tokenT == IDENT_TOK {
+		switch strings.ToUpper(p.peek(0).value.(string)) {
+		case "ROLE":
+			return p.parseCreateRoleStmt()
+		case "ROW", "MASKING":
+			return p.parseCreatePolicyStmt()
+		}
+	}
+
 	if p.peek(0).tokenT != KEYWORD_TOK {
-		return nil, p.expectToken([]string{"DATABASE", "TABLE", "INDEX", "USER", "PROCEDURE"}, p.peekToken(0))
+		return nil, p.expectToken([]string{"DATABASE", "TABLE", "INDEX", "USER", "ROLE", "PROCEDURE"}, p.peekToken(0))
 	}
 
 	switch strings.ToUpper(p.peek(0).value.(string)) {
@@ -3127,7 +3452,7 @@ func (p *Parser) parseCreateStmt() (Node, error) {
 		return p.parseCreateProcedureStmt()
 	}
 
-	return nil, p.expectToken([]string{"DATABASE", "TABLE", "INDEX", "USER", "PROCEDURE"}, p.peekToken(0))
+	return nil, p.expectToken([]string{"DATABASE", "TABLE", "INDEX", "USER", "ROLE", "PROCEDURE"}, p.peekToken(0))
 
 }
 
@@ -6192,9 +6517,11 @@ func (p *Parser) parseSettingsClause(createTableStmt *CreateTableStmt) error {
 		var value interface{}
 		if p.peek(0).tokenT == LITERAL_TOK {
 			rawValue := p.peek(0).value
-			// Convert uint64 to int for consistency
+			// Convert uint64 to int for consistency, and strip the quotes of strings
 			if uint64Val, ok := rawValue.(uint64); ok {
 				value = int(uint64Val)
+			} else if strVal, ok := rawValue.(string); ok {
+				value = strings.TrimSuffix(strings.TrimPrefix(strVal, "'"), "'")
 			} else {
 				value = rawValue
 			}
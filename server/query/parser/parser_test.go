@@ -19,6 +19,8 @@ package parser
 import (
 	"strings"
 	"testing"
+
+	"github.com/gear6io/ranger/pkg/errors"
 )
 
 // TestNewParserCreateDatabase tests CREATE DATABASE statement parsing with various scenarios
@@ -1256,6 +1258,206 @@ func TestNewParserRevoke(t *testing.T) {
 	}
 }
 
+// TestNewParserGrantColumns tests GRANT with a column list
+func TestNewParserGrantColumns(t *testing.T) {
+	statement := []byte(`
+	GRANT SELECT (id, name) ON db1.users TO analyst;
+`)
+
+	parser := NewParser(NewLexer(statement))
+	stmt, err := parser.Parse()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	grantStmt, ok := stmt.(*GrantStmt)
+	if !ok {
+		t.Fatalf("expected *GrantStmt, got %T", stmt)
+	}
+
+	definition := grantStmt.PrivilegeDefinition
+	if len(definition.Actions) != 1 || definition.Actions[0] != PRIV_SELECT {
+		t.Fatalf("expected SELECT, got %v", definition.Actions)
+	}
+
+	if len(definition.Columns) != 2 || definition.Columns[0].Value != "id" || definition.Columns[1].Value != "name" {
+		t.Fatalf("expected columns id, name, got %v", definition.Columns)
+	}
+
+	if definition.Object.Value != "db1.users" {
+		t.Fatalf("expected db1.users, got %s", definition.Object.Value)
+	}
+
+	if definition.Grantee.Value != "analyst" {
+		t.Fatalf("expected analyst, got %s", definition.Grantee.Value)
+	}
+}
+
+// TestNewParserGrantAllObjects tests GRANT on *.* and *
+func TestNewParserGrantAllObjects(t *testing.T) {
+	for _, statement := range []string{`GRANT SELECT ON *.* TO bob;`, `GRANT SELECT ON * TO bob;`} {
+		stmt, err := NewParser(NewLexer([]byte(statement))).Parse()
+		if err != nil {
+			t.Fatalf("%s: %v", statement, err)
+		}
+
+		grantStmt, ok := stmt.(*GrantStmt)
+		if !ok {
+			t.Fatalf("expected *GrantStmt, got %T", stmt)
+		}
+
+		if grantStmt.PrivilegeDefinition.Object.Value != "*.*" {
+			t.Fatalf("expected *.*, got %s", grantStmt.PrivilegeDefinition.Object.Value)
+		}
+
+		if grantStmt.PrivilegeDefinition.Grantee.Value != "bob" {
+			t.Fatalf("expected bob, got %s", grantStmt.PrivilegeDefinition.Grantee.Value)
+		}
+	}
+}
+
+// TestNewParserGrantRole tests GRANT and REVOKE of roles
+func TestNewParserGrantRole(t *testing.T) {
+	stmt, err := NewParser(NewLexer([]byte(`GRANT analyst, auditor TO alice;`))).Parse()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	grantRoleStmt, ok := stmt.(*GrantRoleStmt)
+	if !ok {
+		t.Fatalf("expected *GrantRoleStmt, got %T", stmt)
+	}
+
+	if len(grantRoleStmt.Roles) != 2 || grantRoleStmt.Roles[0].Value != "analyst" || grantRoleStmt.Roles[1].Value != "auditor" {
+		t.Fatalf("expected roles analyst, auditor, got %v", grantRoleStmt.Roles)
+	}
+
+	if grantRoleStmt.Grantee.Value != "alice" {
+		t.Fatalf("expected alice, got %s", grantRoleStmt.Grantee.Value)
+	}
+
+	stmt, err = NewParser(NewLexer([]byte(`REVOKE analyst FROM alice;`))).Parse()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	revokeRoleStmt, ok := stmt.(*RevokeRoleStmt)
+	if !ok {
+		t.Fatalf("expected *RevokeRoleStmt, got %T", stmt)
+	}
+
+	if revokeRoleStmt.Roles[0].Value != "analyst" || revokeRoleStmt.Revokee.Value != "alice" {
+		t.Fatalf("expected analyst from alice, got %s from %s", revokeRoleStmt.Roles[0].Value, revokeRoleStmt.Revokee.Value)
+	}
+}
+
+// TestNewParserCreateDropRole tests CREATE ROLE and DROP ROLE statement parsing
+func TestNewParserCreateDropRole(t *testing.T) {
+	stmt, err := NewParser(NewLexer([]byte(`CREATE ROLE analyst;`))).Parse()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	createRoleStmt, ok := stmt.(*CreateRoleStmt)
+	if !ok {
+		t.Fatalf("expected *CreateRoleStmt, got %T", stmt)
+	}
+
+	if createRoleStmt.Name.Value != "analyst" {
+		t.Fatalf("expected analyst, got %s", createRoleStmt.Name.Value)
+	}
+
+	stmt, err = NewParser(NewLexer([]byte(`DROP ROLE analyst;`))).Parse()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dropRoleStmt, ok := stmt.(*DropRoleStmt)
+	if !ok {
+		t.Fatalf("expected *DropRoleStmt, got %T", stmt)
+	}
+
+	if dropRoleStmt.Name.Value != "analyst" {
+		t.Fatalf("expected analyst, got %s", dropRoleStmt.Name.Value)
+	}
+}
+
+// TestNewParserCreateRowAccessPolicy tests CREATE ROW ACCESS POLICY statement parsing
+func TestNewParserCreateRowAccessPolicy(t *testing.T) {
+	stmt, err := NewParser(NewLexer([]byte(`CREATE ROW ACCESS POLICY eu_only ON sales.customers USING (region = 'EU' AND (tier > 1)) TO analyst, bob;`))).Parse()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	policyStmt, ok := stmt.(*CreatePolicyStmt)
+	if !ok {
+		t.Fatalf("expected *CreatePolicyStmt, got %T", stmt)
+	}
+
+	if policyStmt.Kind != POLICY_ROW_ACCESS {
+		t.Fatalf("expected %s, got %s", POLICY_ROW_ACCESS, policyStmt.Kind)
+	}
+
+	if policyStmt.Name.Value != "eu_only" || policyStmt.Table.Database.Value != "sales" || policyStmt.Table.Table.Value != "customers" {
+		t.Fatalf("unexpected policy target %+v", policyStmt)
+	}
+
+	if policyStmt.Expression != "region = 'EU' AND (tier > 1)" {
+		t.Fatalf("unexpected expression %q", policyStmt.Expression)
+	}
+
+	if len(policyStmt.To) != 2 || policyStmt.To[0].Value != "analyst" || policyStmt.To[1].Value != "bob" {
+		t.Fatalf("unexpected grantees %v", policyStmt.To)
+	}
+}
+
+// TestNewParserCreateMaskingPolicy tests CREATE MASKING POLICY statement parsing
+func TestNewParserCreateMaskingPolicy(t *testing.T) {
+	stmt, err := NewParser(NewLexer([]byte(`CREATE MASKING POLICY hide_email ON customers (email) USING (md5(email)) EXEMPT admin;`))).Parse()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	policyStmt, ok := stmt.(*CreatePolicyStmt)
+	if !ok {
+		t.Fatalf("expected *CreatePolicyStmt, got %T", stmt)
+	}
+
+	if policyStmt.Kind != POLICY_MASKING || policyStmt.Column.Value != "email" || policyStmt.Expression != "md5(email)" {
+		t.Fatalf("unexpected masking policy %+v", policyStmt)
+	}
+
+	if len(policyStmt.Exempt) != 1 || policyStmt.Exempt[0].Value != "admin" {
+		t.Fatalf("unexpected exemptions %v", policyStmt.Exempt)
+	}
+
+	if _, err := NewParser(NewLexer([]byte(`CREATE MASKING POLICY hide_email ON customers (email) USING ();`))).Parse(); err == nil {
+		t.Fatal("expected an error for an empty masking expression")
+	}
+}
+
+// TestNewParserDropPolicy tests DROP ROW ACCESS POLICY and DROP MASKING POLICY statement parsing
+func TestNewParserDropPolicy(t *testing.T) {
+	for statement, kind := range map[string]PolicyKind{
+		`DROP ROW ACCESS POLICY eu_only ON sales.customers;`: POLICY_ROW_ACCESS,
+		`DROP MASKING POLICY eu_only ON sales.customers;`:    POLICY_MASKING,
+	} {
+		stmt, err := NewParser(NewLexer([]byte(statement))).Parse()
+		if err != nil {
+			t.Fatalf("%s: %v", statement, err)
+		}
+
+		dropPolicyStmt, ok := stmt.(*DropPolicyStmt)
+		if !ok {
+			t.Fatalf("expected *DropPolicyStmt, got %T", stmt)
+		}
+
+		if dropPolicyStmt.Kind != kind || dropPolicyStmt.Name.Value != "eu_only" || dropPolicyStmt.Table.Table.Value != "customers" {
+			t.Fatalf("unexpected statement %+v", dropPolicyStmt)
+		}
+	}
+}
+
 // TestNewParserDropUser tests DROP USER statement parsing
 func TestNewParserDropUser(t *testing.T) {
 	statement := []byte(`
@@ -1999,3 +2201,54 @@ func TestNewParserLexerParentheses(t *testing.T) {
 		t.Fatalf("expected 2 right parentheses, found %d", rightParenCount)
 	}
 }
+
+// TestParserComplete tests that statements parsed without their trailing tokens are reported
+func TestParserComplete(t *testing.T) {
+	tests := []struct {
+		statement string
+		complete  bool
+	}{
+		{"SELECT * FROM orders WHERE id = 5;", true},
+		{"SELECT id FROM orders WHERE id = 5 LIMIT 1;", true},
+		{"SELECT * FROM orders WHERE id = 5 garbage;", false},
+		{"SELECT * FROM orders WHERE name = 'it''s';", false},
+	}
+
+	for _, tt := range tests {
+		parser := NewParser(NewLexer([]byte(tt.statement)))
+		if _, err := parser.Parse(); err != nil {
+			t.Fatalf("%s: unexpected error: %v", tt.statement, err)
+		}
+		if parser.Complete() != tt.complete {
+			t.Errorf("%s: expected complete %v, got %v", tt.statement, tt.complete, parser.Complete())
+		}
+	}
+}
+
+// TestParseSingle tests that input holding more than the statement parsed is rejected
+func TestParseSingle(t *testing.T) {
+	tests := []struct {
+		statement string
+		code      string
+	}{
+		{"SELECT * FROM orders WHERE id = 5;", ""},
+		{"SHOW DATABASES;", ""},
+		{"SELECT * FROM public_t; SELECT * FROM secret;", ErrMultipleStatements.String()},
+		{"SHOW DATABASES; DROP TABLE orders;", ErrMultipleStatements.String()},
+		{"SELECT * FROM (SELECT * FROM secret) x;", ErrUnparsedTokens.String()},
+		{"SELECT * FROM orders WHERE id = 5 garbage;", ErrUnparsedTokens.String()},
+	}
+
+	for _, tt := range tests {
+		_, err := ParseSingle(tt.statement)
+		if tt.code == "" {
+			if err != nil {
+				t.Errorf("%s: unexpected error: %v", tt.statement, err)
+			}
+			continue
+		}
+		if errors.GetCode(err) != tt.code {
+			t.Errorf("%s: expected error %s, got %v", tt.statement, tt.code, err)
+		}
+	}
+}
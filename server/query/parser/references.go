@@ -0,0 +1,169 @@
+package parser
+
+import "strings"
+
+// TableReference is a table read by a query, with the span of the reference (the table
+// name and its alias, if any) in the query text
+type TableReference struct {
+	Database string // empty when unqualified
+	Table    string
+	Alias    string // empty when not aliased
+	Offset   int
+	Length   int
+}
+
+// nonAliasWords are unreserved words that may follow a table reference without aliasing it
+var nonAliasWords = map[string]bool{
+	"JOIN": true, "LEFT": true, "RIGHT": true, "INNER": true, "OUTER": true, "FULL": true,
+	"CROSS": true, "NATURAL": true, "SEMI": true, "ANTI": true, "POSITIONAL": true, "ASOF": true,
+	"USING": true, "QUALIFY": true, "WINDOW": true, "TABLESAMPLE": true,
+}
+
+// fromClauseEnd are the keywords that close a FROM clause
+var fromClauseEnd = map[string]bool{
+	"WHERE": true, "GROUP": true, "HAVING": true, "ORDER": true, "LIMIT": true, "OFFSET": true,
+	"UNION": true, "SET": true,
+}
+
+// TableReferences returns the tables a query reads in its FROM and JOIN clauses, including
+// those of subqueries, in order of appearance. The target of a DELETE is not included.
+func TableReferences(query string) []TableReference {
+	tokens := tokenizeQuery(query)
+
+	var references []TableReference
+	inFrom := map[int]bool{} // whether each parenthesis depth is inside a FROM clause
+	depth := 0
+	for i := 0; i < len(tokens); i++ {
+		expectTable := false
+		switch token := tokens[i]; token.tokenT {
+		case LPAREN_TOK:
+			depth++
+		case RPAREN_TOK:
+			inFrom[depth] = false
+			depth--
+		case COMMA_TOK:
+			expectTable = inFrom[depth]
+		case KEYWORD_TOK:
+			switch {
+			case token.value == "FROM":
+				inFrom[depth] = true
+				expectTable = i == 0 || tokens[i-1].value != "DELETE"
+			case fromClauseEnd[token.value.(string)]:
+				inFrom[depth] = false
+			}
+		case IDENT_TOK:
+			switch strings.ToUpper(token.value.(string)) {
+			case "JOIN":
+				expectTable = true
+			case "QUALIFY", "WINDOW":
+				inFrom[depth] = false
+			}
+		}
+		if !expectTable {
+			continue
+		}
+
+		if reference, last, ok := readTableReference(tokens, i+1); ok {
+			references = append(references, reference)
+			i = last
+		}
+	}
+
+	return references
+}
+
+// WhereClauseSpan returns the span of the top-level WHERE condition of a query, from just
+// after the WHERE keyword to the end of the statement. Without a WHERE clause, start and
+// end are both the end of the statement.
+func WhereClauseSpan(query string) (start, end int, found bool) {
+	tokens := tokenizeQuery(query)
+
+	end = len(query)
+	if len(tokens) > 0 && tokens[len(tokens)-1].tokenT == SEMICOLON_TOK {
+		end = tokens[len(tokens)-1].Position.Offset
+	}
+
+	start = end
+	depth := 0
+	for _, token := range tokens {
+		switch {
+		case token.tokenT == LPAREN_TOK:
+			depth++
+		case token.tokenT == RPAREN_TOK:
+			depth--
+		case depth == 0 && token.tokenT == KEYWORD_TOK && token.value == "WHERE":
+			start = token.Position.Offset + token.Position.Length
+			found = true
+		}
+	}
+
+	return start, end, found
+}
+
+// tokenizeQuery returns the tokens of query without comments
+func tokenizeQuery(query string) []Token {
+	lexer := NewLexer([]byte(query))
+	lexer.tokenize()
+	lexer.stripComments()
+	return lexer.tokens
+}
+
+// readTableReference reads a table name and optional alias starting at tokens[i], returning
+// the reference and the index of its last token. Subqueries and table functions are not
+// table references.
+func readTableReference(tokens []Token, i int) (TableReference, int, bool) {
+	name, ok := referenceName(tokens, i)
+	if !ok {
+		return TableReference{}, 0, false
+	}
+
+	reference := TableReference{Table: name, Offset: tokens[i].Position.Offset}
+	last := i
+	if last+2 < len(tokens) && tokens[last+1].tokenT == DOT_TOK {
+		table, ok := referenceName(tokens, last+2)
+		if !ok {
+			return TableReference{}, 0, false
+		}
+		reference.Database, reference.Table = name, table
+		last += 2
+	}
+
+	if last+1 < len(tokens) && tokens[last+1].tokenT == LPAREN_TOK {
+		return TableReference{}, 0, false
+	}
+
+	if last+2 < len(tokens) && tokens[last+1].tokenT == KEYWORD_TOK && tokens[last+1].value == "AS" {
+		if alias, ok := referenceName(tokens, last+2); ok {
+			reference.Alias = alias
+			last += 2
+		}
+	} else if alias, ok := referenceName(tokens, last+1); ok && !nonAliasWords[strings.ToUpper(alias)] {
+		reference.Alias = alias
+		last++
+	}
+
+	reference.Length = tokens[last].Position.Offset + tokens[last].Position.Length - reference.Offset
+	return reference, last, true
+}
+
+// referenceName returns the identifier at tokens[i], unquoting double-quoted identifiers
+func referenceName(tokens []Token, i int) (string, bool) {
+	if i >= len(tokens) {
+		return "", false
+	}
+
+	value, ok := tokens[i].value.(string)
+	if !ok {
+		return "", false
+	}
+
+	switch tokens[i].tokenT {
+	case IDENT_TOK:
+		return value, true
+	case LITERAL_TOK:
+		if len(value) > 2 && strings.HasPrefix(value, `"`) && strings.HasSuffix(value, `"`) {
+			return value[1 : len(value)-1], true
+		}
+	}
+	return "", false
+}
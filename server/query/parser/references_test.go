@@ -0,0 +1,75 @@
+package parser
+
+import (
+	"testing"
+)
+
+// TestTableReferences tests locating the tables a query reads
+func TestTableReferences(t *testing.T) {
+	tests := []struct {
+		name      string
+		statement string
+		expected  []TableReference
+	}{
+		{
+			name:      "Qualified",
+			statement: `SELECT a FROM sales.customers;`,
+			expected:  []TableReference{{Database: "sales", Table: "customers", Offset: 14, Length: 15}},
+		},
+		{
+			name:      "AliasesAndCommaList",
+			statement: `SELECT a FROM customers AS c, orders o WHERE c.id = o.id;`,
+			expected: []TableReference{
+				{Table: "customers", Alias: "c", Offset: 14, Length: 14},
+				{Table: "orders", Alias: "o", Offset: 30, Length: 8},
+			},
+		},
+		{
+			name:      "JoinAndSubquery",
+			statement: `SELECT a FROM t JOIN u ON t.a = u.a WHERE a IN (SELECT a FROM v);`,
+			expected: []TableReference{
+				{Table: "t", Offset: 14, Length: 1},
+				{Table: "u", Offset: 21, Length: 1},
+				{Table: "v", Offset: 62, Length: 1},
+			},
+		},
+		{
+			name:      "TableFunctionSkipped",
+			statement: `SELECT a FROM read_parquet('x.parquet');`,
+		},
+		{
+			name:      "DeleteTargetSkipped",
+			statement: `DELETE FROM t WHERE a IN (SELECT a FROM u);`,
+			expected:  []TableReference{{Table: "u", Offset: 40, Length: 1}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			references := TableReferences(tt.statement)
+			if len(references) != len(tt.expected) {
+				t.Fatalf("expected %d references, got %+v", len(tt.expected), references)
+			}
+			for i, reference := range references {
+				if reference != tt.expected[i] {
+					t.Fatalf("expected %+v, got %+v", tt.expected[i], reference)
+				}
+			}
+		})
+	}
+}
+
+// TestWhereClauseSpan tests locating the top-level WHERE condition of a statement
+func TestWhereClauseSpan(t *testing.T) {
+	statement := `DELETE FROM t WHERE a IN (SELECT a FROM u WHERE b = 1);`
+	start, end, found := WhereClauseSpan(statement)
+	if !found || statement[start:end] != ` a IN (SELECT a FROM u WHERE b = 1)` {
+		t.Fatalf("unexpected span %q (found %v)", statement[start:end], found)
+	}
+
+	statement = `UPDATE t SET a = 1;`
+	start, end, found = WhereClauseSpan(statement)
+	if found || start != end || statement[end:] != ";" {
+		t.Fatalf("unexpected span %d-%d (found %v)", start, end, found)
+	}
+}
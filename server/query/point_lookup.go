@@ -0,0 +1,245 @@
+package query
+
+import (
+	"context"
+	"strings"
+
+	"github.com/apache/arrow-go/v18/arrow"
+	"github.com/gear6io/ranger/pkg/errors"
+	"github.com/gear6io/ranger/server/query/parser"
+	"github.com/gear6io/ranger/server/storage/parquet"
+)
+
+// pointLookup is a SELECT of the rows of one table where a column equals a literal, such as
+// SELECT * FROM events WHERE user_id = 42
+type pointLookup struct {
+	database string
+	table    string
+	column   string
+	value    interface{}
+	selected []*parser.ValueExpression // nil selects every column
+	limit    int64                     // 0 is unlimited
+}
+
+// parsePointLookup recognizes a SELECT of plain columns, or *, from one table filtered by
+// column = literal and optionally limited, which data files can answer without DuckDB
+func parsePointLookup(query, database string) (*pointLookup, bool) {
+	// Only a statement parsed to its end is known to be nothing more than a point lookup
+	node, err := parser.ParseSingle(query)
+	if err != nil {
+		return nil, false
+	}
+	stmt, ok := node.(*parser.SelectStmt)
+	if !ok || stmt.Distinct || stmt.Union != nil || stmt.SelectList == nil || stmt.TableExpression == nil {
+		return nil, false
+	}
+	expression := stmt.TableExpression
+	if expression.FromClause == nil || len(expression.FromClause.Tables) != 1 || expression.WhereClause == nil ||
+		expression.GroupByClause != nil || expression.HavingClause != nil || expression.OrderByClause != nil {
+		return nil, false
+	}
+
+	table := expression.FromClause.Tables[0]
+	if table.Name == nil {
+		return nil, false
+	}
+	lookup := &pointLookup{database: database, table: table.Name.Value}
+	if table.Database != nil && table.Database.Value != "" {
+		lookup.database = table.Database.Value
+	}
+
+	if limit := expression.LimitClause; limit != nil {
+		if limit.Offset != nil || limit.Count == nil {
+			return nil, false
+		}
+		count, ok := limit.Count.Value.(uint64)
+		if !ok || count == 0 {
+			return nil, false
+		}
+		lookup.limit = int64(count)
+	}
+
+	for _, item := range stmt.SelectList.Expressions {
+		switch value := item.Value.(type) {
+		case *parser.Wildcard:
+			if len(stmt.SelectList.Expressions) != 1 {
+				return nil, false
+			}
+		case *parser.ColumnSpecification:
+			if !plainColumn(value) {
+				return nil, false
+			}
+			lookup.selected = append(lookup.selected, item)
+		default:
+			return nil, false
+		}
+	}
+
+	predicate, ok := expression.WhereClause.SearchCondition.(*parser.ComparisonPredicate)
+	if !ok || predicate.Op != parser.OP_EQ || predicate.Left == nil || predicate.Right == nil {
+		return nil, false
+	}
+	column, literal := predicate.Left.Value, predicate.Right.Value
+	if _, ok := column.(*parser.Literal); ok {
+		column, literal = literal, column
+	}
+	spec, ok := column.(*parser.ColumnSpecification)
+	if !ok || !plainColumn(spec) {
+		return nil, false
+	}
+	value, ok := literal.(*parser.Literal)
+	if !ok {
+		return nil, false
+	}
+	lookup.column = spec.ColumnName.Value
+	if lookup.value, ok = literalValue(value); !ok {
+		return nil, false
+	}
+	return lookup, true
+}
+
+// plainColumn reports whether a column is named without a table
+func plainColumn(column *parser.ColumnSpecification) bool {
+	return column.ColumnName != nil && column.TableName == nil
+}
+
+// literalValue returns the value of a number or a quoted string literal
+func literalValue(literal *parser.Literal) (interface{}, bool) {
+	switch value := literal.Value.(type) {
+	case string:
+		// Escaped quotes are kept raw by the lexer and left to DuckDB
+		if len(value) < 2 || value[0] != '\'' || value[len(value)-1] != '\'' ||
+			strings.ContainsAny(value[1:len(value)-1], "'\\") {
+			return nil, false
+		}
+		return value[1 : len(value)-1], true
+	case uint64, int64, float64:
+		return value, true
+	}
+	return nil, false
+}
+
+// executePointLookup answers a point lookup from the data files of its table, reading only
+// the row groups that its bloom filter or page indexes do not rule out. It reports false,
+// having read nothing, when the table is not indexed for the lookup or the query cannot be
+// answered this way, so that DuckDB runs it instead.
+func (e *Engine) executePointLookup(ctx context.Context, lookup *pointLookup) (*QueryResult, bool, error) {
+	if !e.storageMgr.EqualityIndexed(ctx, lookup.database, lookup.table, lookup.column) {
+		return nil, false, nil
+	}
+	icebergSchema, err := e.storageMgr.GetSchema(ctx, lookup.database, lookup.table)
+	if err != nil {
+		return nil, false, nil
+	}
+	schema, err := parquet.ConvertIcebergToArrowSchema(icebergSchema)
+	if err != nil {
+		return nil, false, nil
+	}
+
+	// Every selected column and the filtered one must be in the table
+	column, ok := fieldIndex(schema, lookup.column)
+	if !ok {
+		return nil, false, nil
+	}
+	lookup.column = schema.Field(column).Name
+	var indices []int
+	var names []string
+	if lookup.selected == nil {
+		for i, field := range schema.Fields() {
+			indices = append(indices, i)
+			names = append(names, field.Name)
+		}
+	}
+	for _, item := range lookup.selected {
+		index, ok := fieldIndex(schema, item.Value.(*parser.ColumnSpecification).ColumnName.Value)
+		if !ok {
+			return nil, false, nil
+		}
+		name := schema.Field(index).Name
+		if item.Alias != nil {
+			name = item.Alias.Value
+		}
+		indices = append(indices, index)
+		names = append(names, name)
+	}
+
+	rows, err := e.storageMgr.GetTableDataWhereEqual(ctx, lookup.database, lookup.table, lookup.column, lookup.value)
+	if err != nil {
+		// A literal the column cannot hold is left to DuckDB to cast or reject
+		if errors.GetCode(err) == parquet.ParquetIndexInvalidValue.String() {
+			return nil, false, nil
+		}
+		return nil, true, err
+	}
+	if lookup.limit > 0 && int64(len(rows)) > lookup.limit {
+		rows = rows[:lookup.limit]
+	}
+
+	data := make([][]interface{}, len(rows))
+	for r, row := range rows {
+		data[r] = make([]interface{}, len(indices))
+		for c, index := range indices {
+			data[r][c] = row[index]
+		}
+	}
+	columnTypes := make([]string, len(indices))
+	for c, index := range indices {
+		columnTypes[c] = arrowSQLTypeName(schema.Field(index).Type)
+	}
+
+	e.logger.Debug().
+		Str("database", lookup.database).
+		Str("table", lookup.table).
+		Str("column", lookup.column).
+		Int("rows", len(data)).
+		Msg("Answered point lookup from indexed data files")
+
+	return &QueryResult{
+		Data:        data,
+		RowCount:    int64(len(data)),
+		Columns:     names,
+		ColumnTypes: columnTypes,
+		Message:     "OK",
+	}, true, nil
+}
+
+// fieldIndex returns the index of a column of schema, matching its name case-insensitively
+// as DuckDB does
+func fieldIndex(schema *arrow.Schema, name string) (int, bool) {
+	for i, field := range schema.Fields() {
+		if strings.EqualFold(field.Name, name) {
+			return i, true
+		}
+	}
+	return 0, false
+}
+
+// arrowSQLTypeName returns the DuckDB type name of columns of an Arrow type
+func arrowSQLTypeName(dataType arrow.DataType) string {
+	switch dataType.ID() {
+	case arrow.BOOL:
+		return "BOOLEAN"
+	case arrow.INT8:
+		return "TINYINT"
+	case arrow.INT16:
+		return "SMALLINT"
+	case arrow.INT32:
+		return "INTEGER"
+	case arrow.INT64:
+		return "BIGINT"
+	case arrow.FLOAT32:
+		return "FLOAT"
+	case arrow.FLOAT64:
+		return "DOUBLE"
+	case arrow.DATE32, arrow.DATE64:
+		return "DATE"
+	case arrow.TIME32, arrow.TIME64:
+		return "TIME"
+	case arrow.TIMESTAMP:
+		return "TIMESTAMP"
+	case arrow.BINARY, arrow.LARGE_BINARY:
+		return "BLOB"
+	default:
+		return "VARCHAR"
+	}
+}
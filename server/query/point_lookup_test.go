@@ -0,0 +1,49 @@
+package query
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParsePointLookup(t *testing.T) {
+	lookup, ok := parsePointLookup("SELECT * FROM sales.orders WHERE user_id = 42;", "default")
+	require.True(t, ok)
+	assert.Equal(t, "sales", lookup.database)
+	assert.Equal(t, "orders", lookup.table)
+	assert.Equal(t, "user_id", lookup.column)
+	assert.Equal(t, uint64(42), lookup.value)
+	assert.Nil(t, lookup.selected)
+
+	// Quotes are removed from strings
+	lookup, ok = parsePointLookup("SELECT id, name AS customer FROM orders WHERE request_id = 'abc' LIMIT 5;", "default")
+	require.True(t, ok)
+	assert.Equal(t, "default", lookup.database)
+	assert.Equal(t, "request_id", lookup.column)
+	assert.Equal(t, "abc", lookup.value)
+	assert.Len(t, lookup.selected, 2)
+	assert.Equal(t, int64(5), lookup.limit)
+
+	// Literals may come first
+	lookup, ok = parsePointLookup("SELECT id FROM orders WHERE 5 = request_id;", "default")
+	require.True(t, ok)
+	assert.Equal(t, "request_id", lookup.column)
+	assert.Equal(t, uint64(5), lookup.value)
+
+	// Anything else is left to DuckDB
+	for _, query := range []string{
+		"SELECT * FROM orders;",
+		"SELECT * FROM orders WHERE id > 5;",
+		"SELECT * FROM orders WHERE id = 5 AND name = 'a';",
+		"SELECT COUNT(*) FROM orders WHERE id = 5;",
+		"SELECT * FROM orders WHERE id = 5 ORDER BY name;",
+		"SELECT DISTINCT name FROM orders WHERE id = 5;",
+		"SELECT * FROM orders WHERE id = 5 OR id = 6;",
+		"SELECT * FROM orders WHERE name = 'it''s';",
+		"SELECT * FROM orders WHERE id = 5 garbage;",
+	} {
+		_, ok := parsePointLookup(query, "default")
+		assert.False(t, ok, query)
+	}
+}
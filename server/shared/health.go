@@ -0,0 +1,44 @@
+package shared
+
+import "context"
+
+// Health describes the state of a single component
+type Health struct {
+	// Live is false when the component has failed and the process must be restarted
+	Live bool
+	// Ready is false while the component cannot serve requests
+	Ready bool
+	// Message explains why the component is not live or not ready
+	Message string
+	// Details holds component-specific state, such as queue lengths or versions
+	Details map[string]interface{}
+}
+
+// HealthChecker is implemented by components that can report their own health
+type HealthChecker interface {
+	// CheckHealth returns the current health of the component; it must respect ctx
+	CheckHealth(ctx context.Context) Health
+}
+
+// ComponentHealth is the health of one component as reported by the health endpoints
+type ComponentHealth struct {
+	Name      string                 `json:"name"`
+	Live      bool                   `json:"live"`
+	Ready     bool                   `json:"ready"`
+	Message   string                 `json:"message,omitempty"`
+	Details   map[string]interface{} `json:"details,omitempty"`
+	LatencyMs float64                `json:"latency_ms"`
+}
+
+// HealthReport aggregates the health of every component of the server
+type HealthReport struct {
+	Status     string            `json:"status"`
+	Live       bool              `json:"live"`
+	Ready      bool              `json:"ready"`
+	Phase      string            `json:"phase"`
+	Message    string            `json:"message,omitempty"`
+	Components []ComponentHealth `json:"components"`
+}
+
+// HealthFunc returns the current health report of the server
+type HealthFunc func(ctx context.Context) *HealthReport
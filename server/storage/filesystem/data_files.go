@@ -0,0 +1,212 @@
+package filesystem
+
+import (
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/apache/arrow-go/v18/arrow"
+	"github.com/apache/arrow-go/v18/arrow/memory"
+	pq "github.com/apache/arrow-go/v18/parquet"
+	"github.com/apache/arrow-go/v18/parquet/file"
+	"github.com/apache/arrow-go/v18/parquet/pqarrow"
+	"github.com/gear6io/ranger/pkg/errors"
+	parquet "github.com/gear6io/ranger/server/storage/parquet"
+)
+
+// Package-specific error codes for table data files
+var (
+	FileStorageReadFileFailed   = errors.MustNewCode("filesystem.read_file_failed")
+	FileStorageRemoveFileFailed = errors.MustNewCode("filesystem.remove_file_failed")
+)
+
+// WriteDataFiles writes rows into new Parquet files of the table with config, the defaults
+// when nil, and returns them. Files of a table with a sort key hold the rows sorted by it.
+// Existing files are never modified, and no file is left behind when the write fails.
+func (mfs *FileStorage) WriteDataFiles(schema *arrow.Schema, config *parquet.ParquetConfig, database, tableName string, data [][]interface{}) ([]*parquet.FileInfo, error) {
+	return mfs.writeFiles(schema, config, database, tableName, func(manager *ParquetManager, config *parquet.ParquetConfig) error {
+		// Each batch becomes a row group of the current file
+		for start := 0; start < len(data); start += config.BatchSize {
+			end := min(start+config.BatchSize, len(data))
+			if err := manager.StoreData(data[start:end]); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// WriteRecordFiles writes records with the table schema into new Parquet files of the table,
+// with the same guarantees as WriteDataFiles
+func (mfs *FileStorage) WriteRecordFiles(schema *arrow.Schema, config *parquet.ParquetConfig, database, tableName string, records []arrow.Record) ([]*parquet.FileInfo, error) {
+	return mfs.writeFiles(schema, config, database, tableName, func(manager *ParquetManager, _ *parquet.ParquetConfig) error {
+		for _, record := range records {
+			if err := manager.StoreRecord(record); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// writeFiles runs write against a Parquet manager of the table and returns the files it
+// wrote, removing them all if any step fails
+func (mfs *FileStorage) writeFiles(schema *arrow.Schema, config *parquet.ParquetConfig, database, tableName string, write func(*ParquetManager, *parquet.ParquetConfig) error) ([]*parquet.FileInfo, error) {
+	if err := mfs.SetupTable(database, tableName); err != nil {
+		return nil, err
+	}
+
+	if config == nil {
+		config = parquet.DefaultParquetConfig()
+	}
+	manager, err := NewParquetManager(schema, config, mfs.pathManager, database, tableName)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := write(manager, config); err != nil {
+		manager.Close()
+		mfs.RemoveDataFiles(manager.GetWrittenFiles())
+		return nil, err
+	}
+
+	if err := manager.Close(); err != nil {
+		mfs.RemoveDataFiles(manager.GetWrittenFiles())
+		return nil, err
+	}
+	return manager.GetWrittenFiles(), nil
+}
+
+// RemoveDataFiles deletes data files, such as those of an insert that could not be registered
+func (mfs *FileStorage) RemoveDataFiles(files []*parquet.FileInfo) error {
+	for _, f := range files {
+		if err := os.Remove(f.Path); err != nil && !os.IsNotExist(err) {
+			return errors.New(FileStorageRemoveFileFailed, "failed to remove data file", err).AddContext("path", f.Path)
+		}
+	}
+	return nil
+}
+
+// ReadDataFiles reads the rows of every Parquet file of the table, oldest file first
+func (mfs *FileStorage) ReadDataFiles(database, tableName string) ([][]interface{}, error) {
+	return mfs.ReadDataFilesMatching(database, tableName, nil)
+}
+
+// ReadDataFilesMatching reads the rows of the Parquet files of the table matching probe,
+// oldest file first, or every row when probe is nil. Row groups whose bloom filter or page
+// index rules the value out are not read.
+func (mfs *FileStorage) ReadDataFilesMatching(database, tableName string, probe *parquet.EqualityProbe) ([][]interface{}, error) {
+	paths, err := filepath.Glob(mfs.pathManager.GetParquetFilePattern(database, tableName))
+	if err != nil {
+		return nil, errors.New(FileStorageReadFileFailed, "failed to list data files", err).AddContext("database", database).AddContext("table", tableName)
+	}
+
+	type dataFile struct {
+		path    string
+		modTime int64
+	}
+	files := make([]dataFile, 0, len(paths))
+	for _, path := range paths {
+		info, err := os.Stat(path)
+		if err != nil {
+			continue // Removed since it was listed
+		}
+		files = append(files, dataFile{path: path, modTime: info.ModTime().UnixNano()})
+	}
+	sort.Slice(files, func(i, j int) bool {
+		if files[i].modTime != files[j].modTime {
+			return files[i].modTime < files[j].modTime
+		}
+		return files[i].path < files[j].path
+	})
+
+	var rows [][]interface{}
+	for _, f := range files {
+		fileRows, err := readParquetRows(f.path, probe)
+		if err != nil {
+			return nil, err
+		}
+		rows = append(rows, fileRows...)
+	}
+	return rows, nil
+}
+
+// readParquetRows decodes the rows of a Parquet file on the local filesystem matching probe
+func readParquetRows(path string, probe *parquet.EqualityProbe) ([][]interface{}, error) {
+	source, err := os.Open(path)
+	if err != nil {
+		return nil, errors.New(FileStorageReadFileFailed, "failed to open data file", err).AddContext("path", path)
+	}
+	defer source.Close()
+	return ReadParquetRows(source, path, probe)
+}
+
+// ReadParquetRows decodes the rows of the Parquet file read from source matching probe, or all
+// of them when it is nil, into rows of Go values; nulls become nil, and dates and timestamps
+// come back in their textual form. Path names the file in errors.
+func ReadParquetRows(source pq.ReaderAtSeeker, path string, probe *parquet.EqualityProbe) ([][]interface{}, error) {
+	parquetFile, err := file.NewParquetReader(source)
+	if err != nil {
+		return nil, errors.New(FileStorageReadFileFailed, "failed to open data file", err).AddContext("path", path)
+	}
+	defer parquetFile.Close()
+
+	// Only the row groups that may hold the value are read
+	var rowGroups []int
+	probeColumn := -1
+	if probe != nil {
+		rowGroups, err = probe.MatchingRowGroups(parquetFile)
+		if err != nil {
+			return nil, errors.AddContext(err, "path", path)
+		}
+		if len(rowGroups) == 0 {
+			return nil, nil
+		}
+	}
+
+	reader, err := pqarrow.NewFileReader(parquetFile, pqarrow.ArrowReadProperties{BatchSize: 1024}, memory.DefaultAllocator)
+	if err != nil {
+		return nil, errors.New(FileStorageReadFileFailed, "failed to read data file", err).AddContext("path", path)
+	}
+	if probe != nil {
+		schema, err := reader.Schema()
+		if err != nil {
+			return nil, errors.New(FileStorageReadFileFailed, "failed to read data file schema", err).AddContext("path", path)
+		}
+		indices := schema.FieldIndices(probe.Column)
+		if len(indices) == 0 {
+			return nil, errors.New(parquet.ParquetIndexUnknownColumn, "column does not exist in data file", nil).AddContext("path", path).AddContext("column", probe.Column)
+		}
+		probeColumn = indices[0]
+	}
+
+	records, err := reader.GetRecordReader(context.Background(), nil, rowGroups)
+	if err != nil {
+		return nil, errors.New(FileStorageReadFileFailed, "failed to read data file", err).AddContext("path", path)
+	}
+	defer records.Release()
+
+	rows := make([][]interface{}, 0, parquetFile.NumRows())
+	for records.Next() {
+		record := records.Record()
+		for i := 0; i < int(record.NumRows()); i++ {
+			if probe != nil && !probe.Matches(record.Column(probeColumn), i) {
+				continue
+			}
+			row := make([]interface{}, record.NumCols())
+			for c, column := range record.Columns() {
+				if column.IsNull(i) {
+					continue
+				}
+				row[c] = parquet.ArrayValue(column, i)
+			}
+			rows = append(rows, row)
+		}
+	}
+	if err := records.Err(); err != nil && err != io.EOF {
+		return nil, errors.New(FileStorageReadFileFailed, "failed to read data file", err).AddContext("path", path)
+	}
+	return rows, nil
+}
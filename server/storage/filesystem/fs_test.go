@@ -5,7 +5,14 @@ import (
 	"path/filepath"
 	"testing"
 
+	"github.com/apache/arrow-go/v18/arrow"
+	"github.com/apache/arrow-go/v18/arrow/array"
+	"github.com/apache/arrow-go/v18/arrow/memory"
+	"github.com/apache/arrow-go/v18/parquet/compress"
+	"github.com/apache/arrow-go/v18/parquet/file"
+	"github.com/gear6io/ranger/pkg/errors"
 	"github.com/gear6io/ranger/server/paths"
+	parquet "github.com/gear6io/ranger/server/storage/parquet"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -129,3 +136,265 @@ func TestFileStorageRemoveTableEnvironment(t *testing.T) {
 	// Verify it was removed
 	assert.NoDirExists(t, tablePath)
 }
+
+func TestFileStorageWriteDataFiles(t *testing.T) {
+	if isCI() {
+		t.Skip("Skipping filesystem tests in CI due to Windows path handling issues")
+	}
+
+	tempDir := t.TempDir()
+	pathManager := &paths.MockPathManager{BasePath: tempDir}
+
+	mfs := NewFileStorage(pathManager)
+
+	schema := arrow.NewSchema([]arrow.Field{
+		{Name: "id", Type: arrow.PrimitiveTypes.Int64, Nullable: false},
+		{Name: "name", Type: arrow.BinaryTypes.String, Nullable: true},
+	}, nil)
+
+	// Each insert appends a new file instead of replacing the table data
+	first, err := mfs.WriteDataFiles(schema, nil, "testdb", "testtable", [][]interface{}{{int64(1), "alice"}, {int64(2), nil}})
+	require.NoError(t, err)
+	require.Len(t, first, 1)
+	second, err := mfs.WriteDataFiles(schema, nil, "testdb", "testtable", [][]interface{}{{int64(3), "carol"}})
+	require.NoError(t, err)
+	require.Len(t, second, 1)
+	assert.NotEqual(t, first[0].Path, second[0].Path)
+
+	for _, file := range append(first, second...) {
+		stat, err := os.Stat(file.Path)
+		require.NoError(t, err)
+		assert.Equal(t, stat.Size(), file.Size)
+		assert.Len(t, file.Checksum, 64)
+	}
+	assert.Equal(t, int64(2), first[0].RowCount)
+	assert.Equal(t, int64(1), second[0].RowCount)
+
+	rows, err := mfs.ReadDataFiles("testdb", "testtable")
+	require.NoError(t, err)
+	assert.Equal(t, [][]interface{}{{int64(1), "alice"}, {int64(2), nil}, {int64(3), "carol"}}, rows)
+
+	// Removed files are no longer read
+	require.NoError(t, mfs.RemoveDataFiles(second))
+	rows, err = mfs.ReadDataFiles("testdb", "testtable")
+	require.NoError(t, err)
+	assert.Len(t, rows, 2)
+}
+
+func TestFileStorageWriteRecordFiles(t *testing.T) {
+	if isCI() {
+		t.Skip("Skipping filesystem tests in CI due to Windows path handling issues")
+	}
+
+	tempDir := t.TempDir()
+	pathManager := &paths.MockPathManager{BasePath: tempDir}
+
+	mfs := NewFileStorage(pathManager)
+
+	schema := arrow.NewSchema([]arrow.Field{
+		{Name: "id", Type: arrow.PrimitiveTypes.Int64, Nullable: false},
+		{Name: "name", Type: arrow.BinaryTypes.String, Nullable: true},
+	}, nil)
+	builder := array.NewRecordBuilder(memory.DefaultAllocator, schema)
+	defer builder.Release()
+	builder.Field(0).(*array.Int64Builder).AppendValues([]int64{1, 2}, nil)
+	builder.Field(1).(*array.StringBuilder).AppendValues([]string{"alice", ""}, []bool{true, false})
+	record := builder.NewRecord()
+	defer record.Release()
+
+	files, err := mfs.WriteRecordFiles(schema, nil, "testdb", "testtable", []arrow.Record{record, record})
+	require.NoError(t, err)
+	require.Len(t, files, 1)
+	assert.Equal(t, int64(4), files[0].RowCount)
+
+	rows, err := mfs.ReadDataFiles("testdb", "testtable")
+	require.NoError(t, err)
+	assert.Equal(t, [][]interface{}{{int64(1), "alice"}, {int64(2), nil}, {int64(1), "alice"}, {int64(2), nil}}, rows)
+
+	// Records of another schema are rejected and leave no file behind
+	other := arrow.NewSchema([]arrow.Field{{Name: "id", Type: arrow.PrimitiveTypes.Int32}}, nil)
+	_, err = mfs.WriteRecordFiles(other, nil, "testdb", "testtable", []arrow.Record{record})
+	require.Error(t, err)
+	rows, err = mfs.ReadDataFiles("testdb", "testtable")
+	require.NoError(t, err)
+	assert.Len(t, rows, 4)
+}
+
+func TestFileStorageWriteSortedFiles(t *testing.T) {
+	if isCI() {
+		t.Skip("Skipping filesystem tests in CI due to Windows path handling issues")
+	}
+
+	tempDir := t.TempDir()
+	pathManager := &paths.MockPathManager{BasePath: tempDir}
+
+	mfs := NewFileStorage(pathManager)
+
+	schema := arrow.NewSchema([]arrow.Field{
+		{Name: "id", Type: arrow.PrimitiveTypes.Int64, Nullable: false},
+		{Name: "name", Type: arrow.BinaryTypes.String, Nullable: true},
+	}, nil)
+	config := parquet.DefaultParquetConfig()
+	config.SortOrder = []string{"name", "id"}
+	config.BatchSize = 2
+
+	// Rows of every batch are sorted together into the file, nulls first
+	files, err := mfs.WriteDataFiles(schema, config, "testdb", "testtable", [][]interface{}{
+		{int64(3), "carol"}, {int64(2), "alice"}, {int64(4), nil}, {int64(1), "alice"}, {int64(5), "bob"},
+	})
+	require.NoError(t, err)
+	require.Len(t, files, 1)
+	assert.Equal(t, int64(5), files[0].RowCount)
+	assert.True(t, files[0].Sorted)
+
+	rows, err := mfs.ReadDataFiles("testdb", "testtable")
+	require.NoError(t, err)
+	assert.Equal(t, [][]interface{}{
+		{int64(4), nil}, {int64(1), "alice"}, {int64(2), "alice"}, {int64(5), "bob"}, {int64(3), "carol"},
+	}, rows)
+
+	// A sort key the table does not have fails the write and leaves no file behind
+	config.SortOrder = []string{"missing"}
+	_, err = mfs.WriteDataFiles(schema, config, "testdb", "testtable", [][]interface{}{{int64(6), "dave"}})
+	assert.Equal(t, parquet.ParquetSortUnknownColumn.String(), errors.GetCode(err))
+	rows, err = mfs.ReadDataFiles("testdb", "testtable")
+	require.NoError(t, err)
+	assert.Len(t, rows, 5)
+}
+
+func TestFileStorageReadIndexedFiles(t *testing.T) {
+	if isCI() {
+		t.Skip("Skipping filesystem tests in CI due to Windows path handling issues")
+	}
+
+	tempDir := t.TempDir()
+	pathManager := &paths.MockPathManager{BasePath: tempDir}
+
+	mfs := NewFileStorage(pathManager)
+
+	schema := arrow.NewSchema([]arrow.Field{
+		{Name: "id", Type: arrow.PrimitiveTypes.Int64, Nullable: false},
+		{Name: "name", Type: arrow.BinaryTypes.String, Nullable: true},
+	}, nil)
+	config := parquet.DefaultParquetConfig()
+	config.BloomFilterColumns = []string{"name"}
+	config.PageIndex = true
+	config.BatchSize = 2
+
+	// Each batch becomes a row group with its own bloom filter and page index
+	files, err := mfs.WriteDataFiles(schema, config, "testdb", "testtable", [][]interface{}{
+		{int64(1), "alice"}, {int64(2), "bob"}, {int64(3), "carol"}, {int64(4), nil}, {int64(5), "bob"},
+	})
+	require.NoError(t, err)
+	require.Len(t, files, 1)
+	assert.Equal(t, []string{"name"}, files[0].BloomFilterColumns)
+	assert.True(t, files[0].PageIndex)
+
+	probe, err := parquet.NewEqualityProbe(schema, "name", "bob")
+	require.NoError(t, err)
+	rows, err := mfs.ReadDataFilesMatching("testdb", "testtable", probe)
+	require.NoError(t, err)
+	assert.Equal(t, [][]interface{}{{int64(2), "bob"}, {int64(5), "bob"}}, rows)
+
+	probe, err = parquet.NewEqualityProbe(schema, "id", 3)
+	require.NoError(t, err)
+	rows, err = mfs.ReadDataFilesMatching("testdb", "testtable", probe)
+	require.NoError(t, err)
+	assert.Equal(t, [][]interface{}{{int64(3), "carol"}}, rows)
+
+	probe, err = parquet.NewEqualityProbe(schema, "name", "dave")
+	require.NoError(t, err)
+	rows, err = mfs.ReadDataFilesMatching("testdb", "testtable", probe)
+	require.NoError(t, err)
+	assert.Empty(t, rows)
+}
+
+func TestFileStorageWriteEncodedFiles(t *testing.T) {
+	if isCI() {
+		t.Skip("Skipping filesystem tests in CI due to Windows path handling issues")
+	}
+
+	tempDir := t.TempDir()
+	pathManager := &paths.MockPathManager{BasePath: tempDir}
+
+	mfs := NewFileStorage(pathManager)
+
+	schema := arrow.NewSchema([]arrow.Field{
+		{Name: "id", Type: arrow.PrimitiveTypes.Int64, Nullable: false},
+		{Name: "name", Type: arrow.BinaryTypes.String, Nullable: true},
+	}, nil)
+	config := parquet.DefaultParquetConfig()
+	require.NoError(t, parquet.ApplyWriterSettings(config, map[string]interface{}{
+		parquet.SettingCompression:       "zstd",
+		parquet.SettingColumnCompression: "name:gzip",
+		parquet.SettingColumnDictionary:  "id:false",
+		parquet.SettingRowGroupSize:      2,
+	}))
+
+	files, err := mfs.WriteDataFiles(schema, config, "testdb", "testtable", [][]interface{}{
+		{int64(1), "alice"}, {int64(2), "bob"}, {int64(3), "carol"}, {int64(4), nil}, {int64(5), "bob"},
+	})
+	require.NoError(t, err)
+	require.Len(t, files, 1)
+
+	reader, err := file.OpenParquetFile(files[0].Path, false)
+	require.NoError(t, err)
+	defer reader.Close()
+
+	// The batch is split into row groups of at most two rows
+	require.Equal(t, 3, reader.NumRowGroups())
+	for i := 0; i < reader.NumRowGroups(); i++ {
+		rowGroup := reader.MetaData().RowGroup(i)
+		id, err := rowGroup.ColumnChunk(0)
+		require.NoError(t, err)
+		name, err := rowGroup.ColumnChunk(1)
+		require.NoError(t, err)
+
+		assert.Equal(t, compress.Codecs.Zstd, id.Compression())
+		assert.False(t, id.HasDictionaryPage())
+		assert.Equal(t, compress.Codecs.Gzip, name.Compression())
+		assert.True(t, name.HasDictionaryPage())
+	}
+}
+
+func TestFileStorageWriteNestedFiles(t *testing.T) {
+	if isCI() {
+		t.Skip("Skipping filesystem tests in CI due to Windows path handling issues")
+	}
+
+	tempDir := t.TempDir()
+	pathManager := &paths.MockPathManager{BasePath: tempDir}
+
+	mfs := NewFileStorage(pathManager)
+
+	schema := arrow.NewSchema([]arrow.Field{
+		{Name: "id", Type: arrow.PrimitiveTypes.Int64, Nullable: false},
+		{Name: "tags", Type: arrow.ListOf(arrow.BinaryTypes.String), Nullable: true},
+		{Name: "attrs", Type: arrow.MapOf(arrow.BinaryTypes.String, arrow.PrimitiveTypes.Int32), Nullable: true},
+		{Name: "point", Type: arrow.StructOf(
+			arrow.Field{Name: "x", Type: arrow.PrimitiveTypes.Int32, Nullable: true},
+			arrow.Field{Name: "label", Type: arrow.BinaryTypes.String, Nullable: true},
+		), Nullable: true},
+	}, nil)
+
+	// Rows hold nested values as plain Go values, or as JSON text
+	_, err := mfs.WriteDataFiles(schema, nil, "testdb", "testtable", [][]interface{}{
+		{int64(1), []interface{}{"a", nil}, map[string]interface{}{"k": 1}, map[string]interface{}{"x": int32(2), "label": "p"}},
+		{int64(2), `["b"]`, `{"k": 2}`, `{"label": "q"}`},
+		{int64(3), nil, nil, nil},
+	})
+	require.NoError(t, err)
+
+	rows, err := mfs.ReadDataFiles("testdb", "testtable")
+	require.NoError(t, err)
+	assert.Equal(t, [][]interface{}{
+		{int64(1), []interface{}{"a", nil}, map[string]interface{}{"k": int32(1)}, map[string]interface{}{"x": int32(2), "label": "p"}},
+		{int64(2), []interface{}{"b"}, map[string]interface{}{"k": int32(2)}, map[string]interface{}{"x": nil, "label": "q"}},
+		{int64(3), nil, nil, nil},
+	}, rows)
+
+	_, err = mfs.WriteDataFiles(schema, nil, "testdb", "testtable", [][]interface{}{
+		{int64(4), []interface{}{1}, nil, nil},
+	})
+	require.Error(t, err)
+}
@@ -1,9 +1,15 @@
 package filesystem
 
 import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
+	"hash"
+	"io"
 	"os"
 	"path/filepath"
+	"strings"
 	"sync"
 	"time"
 
@@ -11,9 +17,11 @@ import (
 	"github.com/apache/arrow-go/v18/arrow/array"
 	"github.com/apache/arrow-go/v18/arrow/memory"
 	"github.com/apache/arrow-go/v18/parquet/pqarrow"
+	"github.com/apache/iceberg-go"
 	"github.com/gear6io/ranger/pkg/errors"
 	"github.com/gear6io/ranger/server/paths"
 	parquet "github.com/gear6io/ranger/server/storage/parquet"
+	"github.com/google/uuid"
 )
 
 // Package-specific error codes for filesystem parquet manager
@@ -28,7 +36,6 @@ var (
 	FilesystemParquetCreateFileFailed       = errors.MustNewCode("filesystem_parquet.create_file_failed")
 	FilesystemParquetCreatePropertiesFailed = errors.MustNewCode("filesystem_parquet.create_properties_failed")
 	FilesystemParquetCreateWriterFailed     = errors.MustNewCode("filesystem_parquet.create_writer_failed")
-	FilesystemParquetGetSizeFailed          = errors.MustNewCode("filesystem_parquet.get_size_failed")
 	FilesystemParquetCloseFailed            = errors.MustNewCode("filesystem_parquet.close_failed")
 	FilesystemParquetListFilesFailed        = errors.MustNewCode("filesystem_parquet.list_files_failed")
 	FilesystemParquetSchemaIsNil            = errors.MustNewCode("filesystem_parquet.schema_is_nil")
@@ -39,16 +46,32 @@ var (
 	FilesystemParquetUnsupportedType        = errors.MustNewCode("filesystem_parquet.unsupported_type")
 )
 
+// DataFileTarget creates the data files a ParquetManager writes, each under a new name
+type DataFileTarget interface {
+	// CreateDataFile creates the sequence-th data file of a manager and returns its path
+	CreateDataFile(sequence int) (string, DataFileWriter, error)
+}
+
+// DataFileWriter writes a data file; Close completes the file and Abort discards it
+type DataFileWriter interface {
+	io.Writer
+	Close() error
+	Abort() error
+}
+
 // ParquetManager manages Parquet data operations for filesystem storage
 type ParquetManager struct {
 	schema      *arrow.Schema
 	config      *parquet.ParquetConfig
 	memoryPool  memory.Allocator
-	pathManager paths.PathManager
+	target      DataFileTarget
+	pathManager paths.PathManager // Set when the files are on the local filesystem
 	database    string
 	tableName   string
 	currentFile *ParquetFile
 	fileCount   int
+	written     []*parquet.FileInfo // Files completed by this manager, in write order
+	pending     []arrow.Record      // Records held back until Close to be written sorted
 	stats       *parquet.WriteStats
 	mu          sync.RWMutex
 	closed      bool
@@ -58,32 +81,53 @@ type ParquetManager struct {
 type ParquetFile struct {
 	Path      string
 	Writer    *pqarrow.FileWriter
-	File      *os.File
+	File      DataFileWriter
 	RowCount  int64
 	FileSize  int64
 	CreatedAt time.Time
 	LastWrite time.Time
+
+	// checksum hashes and written counts every byte written to File
+	checksum hash.Hash
+	written  byteCounter
+}
+
+// byteCounter counts the bytes written through it
+type byteCounter int64
+
+// Write adds the length of p to the count
+func (c *byteCounter) Write(p []byte) (int, error) {
+	*c += byteCounter(len(p))
+	return len(p), nil
 }
 
 // NewParquetManager creates a new filesystem Parquet manager
 func NewParquetManager(schema *arrow.Schema, config *parquet.ParquetConfig, pathManager paths.PathManager, database, tableName string) (*ParquetManager, error) {
-	if config == nil {
-		config = parquet.DefaultParquetConfig()
-	}
-
 	// Create base directory using PathManager if it doesn't exist
 	dataPath := pathManager.GetParquetDataPath(database, tableName)
 	if err := os.MkdirAll(dataPath, 0755); err != nil {
 		return nil, errors.New(FilesystemParquetCreateDirFailed, "failed to create data directory", err).AddContext("path", dataPath)
 	}
 
+	manager := NewParquetManagerWithTarget(schema, config, &localDataFiles{pathManager: pathManager, database: database, tableName: tableName}, database, tableName)
+	manager.pathManager = pathManager
+	return manager, nil
+}
+
+// NewParquetManagerWithTarget creates a Parquet manager writing the data files of a table
+// to target, such as an object store
+func NewParquetManagerWithTarget(schema *arrow.Schema, config *parquet.ParquetConfig, target DataFileTarget, database, tableName string) *ParquetManager {
+	if config == nil {
+		config = parquet.DefaultParquetConfig()
+	}
+
 	return &ParquetManager{
-		schema:      schema,
-		config:      config,
-		memoryPool:  memory.NewGoAllocator(),
-		pathManager: pathManager,
-		database:    database,
-		tableName:   tableName,
+		schema:     schema,
+		config:     config,
+		memoryPool: memory.NewGoAllocator(),
+		target:     target,
+		database:   database,
+		tableName:  tableName,
 		stats: &parquet.WriteStats{
 			RowsWritten:      0,
 			BytesWritten:     0,
@@ -91,7 +135,43 @@ func NewParquetManager(schema *arrow.Schema, config *parquet.ParquetConfig, path
 			CompressionRatio: 1.0,
 			MemoryUsage:      0,
 		},
-	}, nil
+	}
+}
+
+// localDataFiles creates the data files of a table on the local filesystem
+type localDataFiles struct {
+	pathManager paths.PathManager
+	database    string
+	tableName   string
+}
+
+// CreateDataFile creates a data file of the table, never replacing an existing one
+func (l *localDataFiles) CreateDataFile(sequence int) (string, DataFileWriter, error) {
+	// The random suffix keeps files of concurrent managers apart, since data files are
+	// immutable once written
+	timestamp := time.Now().Format("20060102_150405") + "_" + strings.ReplaceAll(uuid.NewString(), "-", "")[:12]
+	filename := l.pathManager.GetParquetFileName(timestamp, sequence)
+	filePath := l.pathManager.GetParquetFilePath(l.database, l.tableName, filename)
+
+	file, err := os.OpenFile(filePath, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+	if err != nil {
+		return "", nil, errors.New(FilesystemParquetCreateFileFailed, "failed to create Parquet file", err).AddContext("path", filePath)
+	}
+	return filePath, localDataFile{file}, nil
+}
+
+// localDataFile is a data file being written on the local filesystem
+type localDataFile struct {
+	*os.File
+}
+
+// Abort closes and removes the file
+func (f localDataFile) Abort() error {
+	f.File.Close()
+	if err := os.Remove(f.Name()); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
 }
 
 // StoreData stores data as Parquet files on disk
@@ -120,6 +200,68 @@ func (fm *ParquetManager) StoreData(data [][]interface{}) error {
 	record := array.NewRecord(fm.schema, arrays, int64(len(data)))
 	defer record.Release()
 
+	return fm.writeRecord(record, startTime)
+}
+
+// StoreRecord writes a record that already has the manager's schema, without converting
+// its values one by one
+func (fm *ParquetManager) StoreRecord(record arrow.Record) error {
+	if fm.closed {
+		return errors.New(FilesystemParquetManagerClosed, "parquet manager is closed", nil)
+	}
+
+	if record.NumRows() == 0 {
+		return nil
+	}
+
+	if !record.Schema().Equal(fm.schema) {
+		return errors.New(FilesystemParquetWriteFailed, "record schema does not match the table schema", nil).AddContext("record_schema", record.Schema().String())
+	}
+
+	return fm.writeRecord(record, time.Now())
+}
+
+// writeRecord writes a record to the active file, or holds it back until Close when the
+// table has a sort key, so that everything written is sorted together
+func (fm *ParquetManager) writeRecord(record arrow.Record, startTime time.Time) error {
+	if len(fm.config.SortOrder) > 0 {
+		record.Retain()
+		fm.mu.Lock()
+		fm.pending = append(fm.pending, record)
+		fm.mu.Unlock()
+		return nil
+	}
+	return fm.writeToFile(record, startTime)
+}
+
+// writeSorted writes the records held back for the sort key as one record sorted by it
+func (fm *ParquetManager) writeSorted() error {
+	fm.mu.Lock()
+	pending := fm.pending
+	fm.pending = nil
+	fm.mu.Unlock()
+
+	if len(pending) == 0 {
+		return nil
+	}
+	defer func() {
+		for _, record := range pending {
+			record.Release()
+		}
+	}()
+
+	startTime := time.Now()
+	sorted, err := parquet.SortRecords(context.Background(), pending, fm.config.SortOrder)
+	if err != nil {
+		return err
+	}
+	defer sorted.Release()
+
+	return fm.writeToFile(sorted, startTime)
+}
+
+// writeToFile writes a record to the active file and rotates it when it is full
+func (fm *ParquetManager) writeToFile(record arrow.Record, startTime time.Time) error {
 	// Ensure we have an active file
 	if err := fm.ensureActiveFile(); err != nil {
 		return err
@@ -132,9 +274,9 @@ func (fm *ParquetManager) StoreData(data [][]interface{}) error {
 
 	// Update statistics
 	fm.mu.Lock()
-	fm.currentFile.RowCount += int64(len(data))
+	fm.currentFile.RowCount += record.NumRows()
 	fm.currentFile.LastWrite = time.Now()
-	fm.stats.RowsWritten += int64(len(data))
+	fm.stats.RowsWritten += record.NumRows()
 	fm.stats.WriteDuration = time.Since(startTime).Nanoseconds()
 	fm.mu.Unlock()
 
@@ -155,45 +297,38 @@ func (fm *ParquetManager) ensureActiveFile() error {
 		return nil
 	}
 
-	// Generate file path using PathManager
-	timestamp := time.Now().Format("20060102_150405")
-	filename := fm.pathManager.GetParquetFileName(timestamp, fm.fileCount)
-	filePath := fm.pathManager.GetParquetFilePath(fm.database, fm.tableName, filename)
-
-	// Create the file
-	file, err := os.Create(filePath)
+	// Create Parquet writer with the codecs, encodings and indexes of the table
+	props, err := parquet.CreateWriterProperties(fm.config, fm.schema)
 	if err != nil {
-		return errors.New(FilesystemParquetCreateFileFailed, "failed to create Parquet file", err).AddContext("path", filePath)
-	}
-
-	// Create Parquet writer with compression settings
-	_, err = parquet.CreateWriterProperties(fm.config, fm.schema)
-	if err != nil {
-		file.Close()
 		return err
 	}
 
-	// For now, use default writer properties
-	// TODO: Apply compression settings from CreateWriterProperties
-	writer, err := pqarrow.NewFileWriter(fm.schema, file, nil, pqarrow.DefaultWriterProps())
+	// Create the file through the target, never replacing an existing one
+	filePath, file, err := fm.target.CreateDataFile(fm.fileCount)
 	if err != nil {
-		file.Close()
-		return errors.New(FilesystemParquetCreateWriterFailed, "failed to create Parquet writer", err).AddContext("filesystem_parquet", "external_library_call_failed")
+		return err
 	}
 
-	// Store compression info for stats
-	fm.stats.CompressionRatio = parquet.GetCompressionRatio(fm.config.Compression)
-
-	fm.currentFile = &ParquetFile{
+	current := &ParquetFile{
 		Path:      filePath,
-		Writer:    writer,
 		File:      file,
 		RowCount:  0,
 		FileSize:  0,
 		CreatedAt: time.Now(),
 		LastWrite: time.Now(),
+		checksum:  sha256.New(),
+	}
+	current.Writer, err = pqarrow.NewFileWriter(fm.schema, io.MultiWriter(file, current.checksum, &current.written), props, pqarrow.DefaultWriterProps())
+	if err != nil {
+		file.Abort()
+		return errors.New(FilesystemParquetCreateWriterFailed, "failed to create Parquet writer", err).AddContext("filesystem_parquet", "external_library_call_failed")
 	}
 
+	// Store compression info for stats
+	fm.stats.CompressionRatio = parquet.GetCompressionRatio(fm.config.Compression)
+
+	fm.currentFile = current
+
 	fm.fileCount++
 	return nil
 }
@@ -207,13 +342,8 @@ func (fm *ParquetManager) checkFileRotation() error {
 		return nil
 	}
 
-	// Check file size
-	fileInfo, err := fm.currentFile.File.Stat()
-	if err != nil {
-		return errors.New(FilesystemParquetGetSizeFailed, "failed to get file size", err).AddContext("filesystem_parquet", "external_library_call_failed")
-	}
-
-	fm.currentFile.FileSize = fileInfo.Size()
+	// Check the size written so far
+	fm.currentFile.FileSize = int64(fm.currentFile.written)
 
 	// Rotate if file is too large
 	if fm.currentFile.FileSize >= fm.config.MaxFileSize {
@@ -237,17 +367,39 @@ func (fm *ParquetManager) rotateFile(reason string) error {
 		return nil
 	}
 
-	// Close the Parquet writer (this also closes the underlying file)
+	// Close the Parquet writer, which writes the footer, then the file it wrote through
 	if err := fm.currentFile.Writer.Close(); err != nil {
+		// A file without its footer cannot be read, so it is not kept
+		fm.currentFile.File.Abort()
+		fm.currentFile = nil
 		return errors.New(FilesystemParquetCloseFailed, "failed to close Parquet writer", err).AddContext("filesystem_parquet", "external_library_call_failed")
 	}
+	if err := fm.currentFile.File.Close(); err != nil {
+		// Nor is a file that was not completed
+		path := fm.currentFile.Path
+		fm.currentFile.File.Abort()
+		fm.currentFile = nil
+		return errors.New(FilesystemParquetCloseFailed, "failed to close Parquet file", err).AddContext("path", path)
+	}
 
 	// Update final file size
-	fileInfo, err := os.Stat(fm.currentFile.Path)
-	if err == nil {
-		fm.currentFile.FileSize = fileInfo.Size()
-		fm.stats.BytesWritten += fm.currentFile.FileSize
-	}
+	fm.currentFile.FileSize = int64(fm.currentFile.written)
+	fm.stats.BytesWritten += fm.currentFile.FileSize
+
+	fm.written = append(fm.written, &parquet.FileInfo{
+		Path:     fm.currentFile.Path,
+		Size:     fm.currentFile.FileSize,
+		RowCount: fm.currentFile.RowCount,
+		Schema:   fm.schema,
+		Created:  fm.currentFile.CreatedAt.Unix(),
+		Modified: fm.currentFile.LastWrite.Unix(),
+		Checksum: hex.EncodeToString(fm.currentFile.checksum.Sum(nil)),
+
+		BloomFilterColumns: parquet.IndexedColumns(fm.config, fm.schema),
+		PageIndex:          fm.config.PageIndex,
+
+		Sorted: len(fm.config.SortOrder) > 0,
+	})
 
 	// Log rotation (could be replaced with proper logging)
 	fmt.Printf("Rotated Parquet file %s (reason: %s, rows: %d, size: %d bytes)\n",
@@ -259,11 +411,18 @@ func (fm *ParquetManager) rotateFile(reason string) error {
 	return nil
 }
 
-// GetFiles returns information about all Parquet files
+// GetFiles returns information about all Parquet files of the table on the local filesystem,
+// or the files the manager wrote when it writes to another target
 func (fm *ParquetManager) GetFiles() ([]*parquet.FileInfo, error) {
 	fm.mu.RLock()
 	defer fm.mu.RUnlock()
 
+	if fm.pathManager == nil {
+		files := make([]*parquet.FileInfo, len(fm.written))
+		copy(files, fm.written)
+		return files, nil
+	}
+
 	pattern := fm.pathManager.GetParquetFilePattern(fm.database, fm.tableName)
 	files, err := filepath.Glob(pattern)
 	if err != nil {
@@ -289,6 +448,17 @@ func (fm *ParquetManager) GetFiles() ([]*parquet.FileInfo, error) {
 	return fileInfos, nil
 }
 
+// GetWrittenFiles returns the files this manager has completed, with their row count and
+// checksum; the file being written is included once it is rotated or the manager closed
+func (fm *ParquetManager) GetWrittenFiles() []*parquet.FileInfo {
+	fm.mu.RLock()
+	defer fm.mu.RUnlock()
+
+	files := make([]*parquet.FileInfo, len(fm.written))
+	copy(files, fm.written)
+	return files
+}
+
 // GetStats returns writing statistics
 func (fm *ParquetManager) GetStats() *parquet.WriteStats {
 	fm.mu.RLock()
@@ -313,8 +483,14 @@ func (fm *ParquetManager) GetMemoryUsage() int64 {
 	return fm.stats.MemoryUsage
 }
 
-// Close closes the Parquet manager and any open files
+// Close writes the records held back for the sort key, then closes the Parquet manager and
+// any open files
 func (fm *ParquetManager) Close() error {
+	var sortErr error
+	if !fm.closed {
+		sortErr = fm.writeSorted()
+	}
+
 	fm.mu.Lock()
 	defer fm.mu.Unlock()
 
@@ -324,14 +500,14 @@ func (fm *ParquetManager) Close() error {
 
 	fm.closed = true
 
-	// Close current file if any
+	// Close current file if any, including one a failed sorted write left open
 	if fm.currentFile != nil {
 		if err := fm.rotateFile("manager closing"); err != nil {
 			return err
 		}
 	}
 
-	return nil
+	return sortErr
 }
 
 // convertDataToArrays converts data to Arrow arrays (same as memory implementation)
@@ -435,6 +611,46 @@ func (fm *ParquetManager) appendValueToBuilder(builder array.Builder, value inte
 			return errors.New(FilesystemParquetTypeMismatch, "expected string", nil).AddContext("actual_type", fmt.Sprintf("%T", value))
 		}
 
+	case *arrow.BinaryType:
+		switch v := value.(type) {
+		case []byte:
+			builder.(*array.BinaryBuilder).Append(v)
+		case string:
+			builder.(*array.BinaryBuilder).AppendString(v)
+		default:
+			return errors.New(FilesystemParquetTypeMismatch, "expected []byte", nil).AddContext("actual_type", fmt.Sprintf("%T", value))
+		}
+
+	case *arrow.Date32Type:
+		t, ok := fm.convertToTime(value, iceberg.PrimitiveTypes.Date)
+		if !ok {
+			return errors.New(FilesystemParquetTypeMismatch, "expected date", nil).AddContext("actual_type", fmt.Sprintf("%T", value))
+		}
+		builder.(*array.Date32Builder).Append(arrow.Date32FromTime(t))
+
+	case *arrow.TimestampType:
+		t, ok := fm.convertToTime(value, iceberg.PrimitiveTypes.Timestamp)
+		if !ok {
+			return errors.New(FilesystemParquetTypeMismatch, "expected timestamp", nil).AddContext("actual_type", fmt.Sprintf("%T", value))
+		}
+		ts, err := arrow.TimestampFromTime(t, dataType.(*arrow.TimestampType).Unit)
+		if err != nil {
+			return errors.New(FilesystemParquetTypeMismatch, "timestamp out of range", err).AddContext("value", t.String())
+		}
+		builder.(*array.TimestampBuilder).Append(ts)
+
+	case *arrow.Time64Type:
+		d, ok := fm.convertToTimeOfDay(value)
+		if !ok {
+			return errors.New(FilesystemParquetTypeMismatch, "expected time", nil).AddContext("actual_type", fmt.Sprintf("%T", value))
+		}
+		builder.(*array.Time64Builder).Append(arrow.Time64(d / dataType.(*arrow.Time64Type).Unit.Multiplier()))
+
+	case *arrow.ListType, *arrow.MapType, *arrow.StructType:
+		if err := parquet.AppendNestedValue(builder, value); err != nil {
+			return errors.New(FilesystemParquetTypeMismatch, "expected "+dataType.String(), err).AddContext("actual_type", fmt.Sprintf("%T", value))
+		}
+
 	default:
 		return errors.New(FilesystemParquetUnsupportedType, "unsupported data type", nil).AddContext("data_type", fmt.Sprintf("%T", dataType))
 	}
@@ -494,6 +710,41 @@ func (fm *ParquetManager) convertToFloat32(value interface{}) (float32, bool) {
 	}
 }
 
+// convertToTime accepts time.Time values and their textual form for dates and timestamps
+func (fm *ParquetManager) convertToTime(value interface{}, icebergType iceberg.Type) (time.Time, bool) {
+	switch v := value.(type) {
+	case time.Time:
+		return v, true
+	case string:
+		parsed, err := parquet.ParseTextValue(v, icebergType)
+		if err != nil {
+			return time.Time{}, false
+		}
+		return parsed.(time.Time), true
+	default:
+		return time.Time{}, false
+	}
+}
+
+// convertToTimeOfDay accepts times of day as HH:MM:SS[.fraction] text or as time.Time
+func (fm *ParquetManager) convertToTimeOfDay(value interface{}) (time.Duration, bool) {
+	var t time.Time
+	switch v := value.(type) {
+	case time.Time:
+		t = v
+	case string:
+		parsed, err := time.Parse("15:04:05.999999999", strings.TrimSpace(v))
+		if err != nil {
+			return 0, false
+		}
+		t = parsed
+	default:
+		return 0, false
+	}
+	return time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute +
+		time.Duration(t.Second())*time.Second + time.Duration(t.Nanosecond()), true
+}
+
 func (fm *ParquetManager) convertToFloat64(value interface{}) (float64, bool) {
 	switch v := value.(type) {
 	case float64:
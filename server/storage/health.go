@@ -0,0 +1,53 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/gear6io/ranger/server/shared"
+)
+
+// healthReporter is implemented by storage engines that monitor a backing server
+type healthReporter interface {
+	HealthStatus() (bool, map[string]interface{})
+}
+
+// CheckHealth reports the health of the storage engines and the schema cache; the metadata
+// manager is checked as a component of its own
+func (s *Storage) CheckHealth(ctx context.Context) shared.Health {
+	health := shared.Health{Live: true, Ready: true, Details: make(map[string]interface{})}
+
+	var unhealthy []string
+	engines := make(map[string]interface{})
+	for _, name := range s.ListEngines() {
+		engine, err := s.GetEngine(name)
+		if err != nil {
+			continue
+		}
+		reporter, ok := engine.(healthReporter)
+		if !ok {
+			engines[name] = map[string]interface{}{"healthy": true}
+			continue
+		}
+		healthy, details := reporter.HealthStatus()
+		details["healthy"] = healthy
+		engines[name] = details
+		if !healthy {
+			unhealthy = append(unhealthy, name)
+		}
+	}
+	health.Details["engines"] = engines
+
+	if s.Schema != nil {
+		health.Details["schema_cache"] = s.GetCacheStats()
+	}
+
+	if len(unhealthy) > 0 {
+		sort.Strings(unhealthy)
+		health.Ready = false
+		health.Message = fmt.Sprintf("storage engines unhealthy: %s", strings.Join(unhealthy, ", "))
+	}
+	return health
+}
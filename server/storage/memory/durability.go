@@ -0,0 +1,594 @@
+package memory
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"hash/crc32"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/apache/arrow-go/v18/arrow"
+	"github.com/apache/arrow-go/v18/arrow/array"
+	"github.com/apache/arrow-go/v18/arrow/ipc"
+	"github.com/gear6io/ranger/pkg/errors"
+	"github.com/gear6io/ranger/server/storage/parquet"
+)
+
+// Durability is how a memory table survives restarts, set per table with
+// SETTINGS durability = 'none' | 'wal' | 'snapshot'
+type Durability string
+
+const (
+	// DurabilityNone keeps the table in memory only; it is empty after a restart
+	DurabilityNone Durability = "none"
+	// DurabilityWAL logs every insert before acknowledging it and snapshots the table
+	// periodically, so no acknowledged insert is lost
+	DurabilityWAL Durability = "wal"
+	// DurabilitySnapshot snapshots the table periodically; inserts since the last snapshot
+	// are lost on a crash
+	DurabilitySnapshot Durability = "snapshot"
+)
+
+// DurabilitySetting is the table setting selecting the durability of a memory table
+const DurabilitySetting = "durability"
+
+// DefaultSnapshotInterval is how often durable tables with new inserts are snapshotted
+const DefaultSnapshotInterval = time.Minute
+
+// Files of a durable table, kept in the memory directory under its table path
+const (
+	durableDir   = "memory"
+	manifestFile = "table.json"
+	snapshotFile = "snapshot.arrow"
+	walFile      = "wal.log"
+)
+
+// walHeaderSize is the size of the length, checksum and sequence number preceding each WAL
+// entry; the checksum covers the sequence number and the record
+const walHeaderSize = 16
+
+// snapshotSequenceKey is the schema metadata key of a snapshot holding the sequence number
+// of the last WAL entry it covers
+const snapshotSequenceKey = "ranger.wal_sequence"
+
+// parseDurability returns the durability selected by table settings; none when unset
+func parseDurability(settings map[string]interface{}) (Durability, error) {
+	value, ok := settings[DurabilitySetting]
+	if !ok {
+		return DurabilityNone, nil
+	}
+	text, ok := value.(string)
+	if !ok {
+		return "", errors.New(ErrInvalidDurability, "durability must be a string", nil).AddContext("durability", value)
+	}
+
+	switch durability := Durability(strings.ToLower(text)); durability {
+	case DurabilityNone, DurabilityWAL, DurabilitySnapshot:
+		return durability, nil
+	default:
+		return "", errors.New(ErrInvalidDurability, "durability must be 'none', 'wal' or 'snapshot'", nil).AddContext("durability", text)
+	}
+}
+
+// tableManifest identifies a durable table in its directory, so it can be recovered
+// without the Registry
+type tableManifest struct {
+	Database     string     `json:"database"`
+	Table        string     `json:"table"`
+	Durability   Durability `json:"durability"`
+	HotTierBytes int64      `json:"hot_tier_bytes,omitempty"`
+}
+
+// durableTable persists a memory table as an Arrow IPC snapshot plus, with the wal
+// durability, a log of the records inserted since
+type durableTable struct {
+	dir        string
+	durability Durability
+
+	mu       sync.Mutex // Serializes inserts with snapshots
+	wal      *os.File   // nil unless the durability is wal
+	sequence uint64     // Sequence number of the last WAL entry
+	dirty    bool       // Records were inserted since the last snapshot
+}
+
+// createDurableTable creates the directory and manifest of a durable table
+func createDurableTable(dir string, manifest tableManifest) (*durableTable, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, errors.New(ErrDurabilityUnavailable, "failed to create table directory", err).AddContext("path", dir)
+	}
+	if err := writeManifest(dir, manifest); err != nil {
+		return nil, err
+	}
+	return openDurableTable(dir, manifest.Durability)
+}
+
+// writeManifest replaces the manifest of a durable table
+func writeManifest(dir string, manifest tableManifest) error {
+	data, err := json.Marshal(manifest)
+	if err != nil {
+		return errors.New(ErrDataMarshalFailed, "failed to marshal table manifest", err)
+	}
+	if err := writeFileAtomic(dir, manifestFile, func(w io.Writer) error {
+		_, err := w.Write(data)
+		return err
+	}); err != nil {
+		return errors.New(ErrDurabilityUnavailable, "failed to write table manifest", err).AddContext("path", dir)
+	}
+	return nil
+}
+
+// openDurableTable opens the files of a durable table
+func openDurableTable(dir string, durability Durability) (*durableTable, error) {
+	table := &durableTable{dir: dir, durability: durability}
+	if durability == DurabilityWAL {
+		wal, err := os.OpenFile(filepath.Join(dir, walFile), os.O_CREATE|os.O_RDWR|os.O_APPEND, 0644)
+		if err != nil {
+			return nil, errors.New(ErrDurabilityUnavailable, "failed to open write-ahead log", err).AddContext("path", dir)
+		}
+		table.wal = wal
+	}
+	return table, nil
+}
+
+// readManifest reads the manifest of a durable table
+func readManifest(path string) (tableManifest, error) {
+	var manifest tableManifest
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return manifest, errors.New(ErrRecoveryFailed, "failed to read table manifest", err).AddContext("path", path)
+	}
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		return manifest, errors.New(ErrRecoveryFailed, "failed to parse table manifest", err).AddContext("path", path)
+	}
+	return manifest, nil
+}
+
+// log appends a record to the write-ahead log and syncs it; the caller holds mu
+func (t *durableTable) log(record arrow.Record) error {
+	t.dirty = true
+	if t.wal == nil {
+		return nil
+	}
+
+	var entry bytes.Buffer
+	entry.Write(make([]byte, walHeaderSize))
+	writer := ipc.NewWriter(&entry, ipc.WithSchema(record.Schema()))
+	if err := writer.Write(record); err != nil {
+		return errors.New(ErrWALWriteFailed, "failed to encode record", err)
+	}
+	if err := writer.Close(); err != nil {
+		return errors.New(ErrWALWriteFailed, "failed to encode record", err)
+	}
+
+	data := entry.Bytes()
+	payload := data[walHeaderSize:]
+	binary.LittleEndian.PutUint32(data[0:4], uint32(len(payload)))
+	binary.LittleEndian.PutUint64(data[8:16], t.sequence+1)
+	binary.LittleEndian.PutUint32(data[4:8], crc32.ChecksumIEEE(data[8:]))
+
+	if _, err := t.wal.Write(data); err != nil {
+		return errors.New(ErrWALWriteFailed, "failed to append to write-ahead log", err).AddContext("path", t.dir)
+	}
+	if err := t.wal.Sync(); err != nil {
+		return errors.New(ErrWALWriteFailed, "failed to sync write-ahead log", err).AddContext("path", t.dir)
+	}
+	t.sequence++
+	return nil
+}
+
+// snapshot replaces the snapshot with records and empties the write-ahead log, which the
+// snapshot now covers; the caller holds mu. The snapshot records the sequence number of the
+// last logged entry, so a crash before the log is emptied does not replay it twice.
+func (t *durableTable) snapshot(records []arrow.Record) error {
+	path := filepath.Join(t.dir, snapshotFile)
+	schema := arrow.NewSchema(nil, nil)
+	if len(records) > 0 {
+		schema = records[0].Schema()
+	}
+	schema = withSnapshotSequence(schema, t.sequence)
+
+	if err := writeFileAtomic(t.dir, snapshotFile, func(w io.Writer) error {
+		writer, err := ipc.NewFileWriter(w, ipc.WithSchema(schema))
+		if err != nil {
+			return err
+		}
+		for _, record := range records {
+			record = array.NewRecord(schema, record.Columns(), record.NumRows())
+			err := writer.Write(record)
+			record.Release()
+			if err != nil {
+				return err
+			}
+		}
+		return writer.Close()
+	}); err != nil {
+		return errors.New(ErrSnapshotFailed, "failed to write snapshot", err).AddContext("path", path)
+	}
+
+	if t.wal != nil {
+		if err := t.wal.Truncate(0); err != nil {
+			return errors.New(ErrSnapshotFailed, "failed to truncate write-ahead log", err).AddContext("path", t.dir)
+		}
+	}
+	t.dirty = false
+	return nil
+}
+
+// recover reads the records of the snapshot followed by those of the write-ahead log the
+// snapshot does not cover. A torn entry at the end of the log, left by a crash during an
+// insert that was never acknowledged, is truncated away. The caller releases the records.
+func (t *durableTable) recover() ([]arrow.Record, error) {
+	records, sequence, err := readSnapshot(filepath.Join(t.dir, snapshotFile))
+	if err != nil {
+		return nil, err
+	}
+	t.sequence = sequence
+	if t.wal == nil {
+		return records, nil
+	}
+
+	logged, last, valid, err := readWAL(t.wal, t.sequence)
+	records = append(records, logged...)
+	if err != nil {
+		releaseRecords(records)
+		return nil, err
+	}
+	if err := t.wal.Truncate(valid); err != nil {
+		releaseRecords(records)
+		return nil, errors.New(ErrRecoveryFailed, "failed to truncate write-ahead log", err).AddContext("path", t.dir)
+	}
+	t.sequence = last
+
+	// The next snapshot folds the logged records in
+	t.dirty = len(logged) > 0
+	return records, nil
+}
+
+// close closes the write-ahead log; the caller holds mu
+func (t *durableTable) close() error {
+	if t.wal == nil {
+		return nil
+	}
+	err := t.wal.Close()
+	t.wal = nil
+	return err
+}
+
+// remove closes the table and deletes its files; the caller holds mu
+func (t *durableTable) remove() error {
+	t.close()
+	t.dirty = false
+	return os.RemoveAll(t.dir)
+}
+
+// readSnapshot reads the records of a snapshot and the sequence number of the last WAL entry
+// it covers; a missing snapshot has neither
+func readSnapshot(path string) ([]arrow.Record, uint64, error) {
+	file, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return nil, 0, nil
+	}
+	if err != nil {
+		return nil, 0, errors.New(ErrRecoveryFailed, "failed to open snapshot", err).AddContext("path", path)
+	}
+	defer file.Close()
+
+	reader, err := ipc.NewFileReader(file)
+	if err != nil {
+		return nil, 0, errors.New(ErrRecoveryFailed, "failed to read snapshot", err).AddContext("path", path)
+	}
+	defer reader.Close()
+
+	schema, sequence, err := splitSnapshotSequence(reader.Schema())
+	if err != nil {
+		return nil, 0, errors.New(ErrRecoveryFailed, "failed to read snapshot sequence", err).AddContext("path", path)
+	}
+	records := make([]arrow.Record, 0, reader.NumRecords())
+	for i := 0; i < reader.NumRecords(); i++ {
+		record, err := reader.RecordAt(i)
+		if err != nil {
+			releaseRecords(records)
+			return nil, 0, errors.New(ErrRecoveryFailed, "failed to read snapshot record", err).AddContext("path", path)
+		}
+		records = append(records, array.NewRecord(schema, record.Columns(), record.NumRows()))
+		record.Release()
+	}
+	return records, sequence, nil
+}
+
+// withSnapshotSequence returns schema with the sequence number of a snapshot in its metadata
+func withSnapshotSequence(schema *arrow.Schema, sequence uint64) *arrow.Schema {
+	metadata := schema.Metadata()
+	keys := []string{snapshotSequenceKey}
+	values := []string{strconv.FormatUint(sequence, 10)}
+	for i, key := range metadata.Keys() {
+		if key != snapshotSequenceKey {
+			keys = append(keys, key)
+			values = append(values, metadata.Values()[i])
+		}
+	}
+	newMetadata := arrow.NewMetadata(keys, values)
+	return arrow.NewSchema(schema.Fields(), &newMetadata)
+}
+
+// splitSnapshotSequence returns the schema of a snapshot without its sequence number, and
+// the sequence number, which is 0 for a snapshot without one
+func splitSnapshotSequence(schema *arrow.Schema) (*arrow.Schema, uint64, error) {
+	metadata := schema.Metadata()
+	index := metadata.FindKey(snapshotSequenceKey)
+	if index < 0 {
+		return schema, 0, nil
+	}
+	sequence, err := strconv.ParseUint(metadata.Values()[index], 10, 64)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	var keys, values []string
+	for i, key := range metadata.Keys() {
+		if i != index {
+			keys = append(keys, key)
+			values = append(values, metadata.Values()[i])
+		}
+	}
+	if len(keys) == 0 {
+		return arrow.NewSchema(schema.Fields(), nil), sequence, nil
+	}
+	newMetadata := arrow.NewMetadata(keys, values)
+	return arrow.NewSchema(schema.Fields(), &newMetadata), sequence, nil
+}
+
+// readWAL reads the records of a write-ahead log logged after the entry numbered covered, up
+// to the first torn or corrupt entry. It returns them with the sequence number of the last
+// valid entry, or covered if it is later, and the length of the valid part of the log.
+func readWAL(file *os.File, covered uint64) ([]arrow.Record, uint64, int64, error) {
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		return nil, 0, 0, errors.New(ErrRecoveryFailed, "failed to read write-ahead log", err)
+	}
+
+	info, err := file.Stat()
+	if err != nil {
+		return nil, 0, 0, errors.New(ErrRecoveryFailed, "failed to read write-ahead log", err)
+	}
+
+	var records []arrow.Record
+	var valid int64
+	last := covered
+	input := bufio.NewReader(file)
+	header := make([]byte, walHeaderSize)
+	for {
+		if _, err := io.ReadFull(input, header); err != nil {
+			return records, last, valid, nil
+		}
+		length := int64(binary.LittleEndian.Uint32(header[0:4]))
+		if valid+walHeaderSize+length > info.Size() {
+			return records, last, valid, nil
+		}
+		payload := make([]byte, length)
+		if _, err := io.ReadFull(input, payload); err != nil {
+			return records, last, valid, nil
+		}
+		checksum := crc32.Update(crc32.ChecksumIEEE(header[8:16]), crc32.IEEETable, payload)
+		if checksum != binary.LittleEndian.Uint32(header[4:8]) {
+			return records, last, valid, nil
+		}
+
+		// Entries the snapshot already holds were logged before a crash emptied the log
+		if sequence := binary.LittleEndian.Uint64(header[8:16]); sequence > covered {
+			record, err := decodeRecord(payload)
+			if err != nil {
+				return records, last, valid, errors.New(ErrRecoveryFailed, "failed to decode write-ahead log entry", err).AddContext("offset", valid)
+			}
+			records = append(records, record)
+			last = sequence
+		}
+		valid += int64(walHeaderSize + len(payload))
+	}
+}
+
+// decodeRecord decodes a record written to the write-ahead log as an Arrow IPC stream
+func decodeRecord(payload []byte) (arrow.Record, error) {
+	reader, err := ipc.NewReader(bytes.NewReader(payload))
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Release()
+
+	if !reader.Next() {
+		if err := reader.Err(); err != nil {
+			return nil, err
+		}
+		return nil, io.ErrUnexpectedEOF
+	}
+	record := reader.Record()
+	record.Retain()
+	return record, nil
+}
+
+// writeFileAtomic writes a file of dir through a temporary file renamed into place, so a
+// crash leaves either the old or the new file
+func writeFileAtomic(dir, name string, write func(w io.Writer) error) error {
+	temp, err := os.CreateTemp(dir, ".tmp-"+name+"-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(temp.Name())
+
+	output := bufio.NewWriter(temp)
+	if err := write(output); err != nil {
+		temp.Close()
+		return err
+	}
+	if err := output.Flush(); err != nil {
+		temp.Close()
+		return err
+	}
+	if err := temp.Sync(); err != nil {
+		temp.Close()
+		return err
+	}
+	if err := temp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(temp.Name(), filepath.Join(dir, name))
+}
+
+// releaseRecords releases records
+func releaseRecords(records []arrow.Record) {
+	for _, record := range records {
+		record.Release()
+	}
+}
+
+// durableDir returns the directory of the files of a durable table
+func (ms *MemoryStorage) durableDir(database, tableName string) string {
+	return filepath.Join(ms.pathManager.GetTablePath(database, tableName), durableDir)
+}
+
+// recoverTables loads every durable table found under the data path
+func (ms *MemoryStorage) recoverTables() error {
+	manifests, err := filepath.Glob(filepath.Join(ms.pathManager.GetDataPath(), "*", "*", durableDir, manifestFile))
+	if err != nil {
+		return errors.New(ErrRecoveryFailed, "failed to find durable tables", err)
+	}
+
+	for _, path := range manifests {
+		manifest, err := readManifest(path)
+		if err != nil {
+			return err
+		}
+		if err := ms.recoverTable(filepath.Dir(path), manifest); err != nil {
+			return errors.AddContext(err, "database", manifest.Database).AddContext("table", manifest.Table)
+		}
+	}
+	return nil
+}
+
+// recoverTable loads a durable table from its snapshot and write-ahead log
+func (ms *MemoryStorage) recoverTable(dir string, manifest tableManifest) error {
+	durable, err := openDurableTable(dir, manifest.Durability)
+	if err != nil {
+		return err
+	}
+	records, err := durable.recover()
+	if err != nil {
+		durable.close()
+		return err
+	}
+	defer releaseRecords(records)
+
+	tableData := &TableData{
+		Database:     manifest.Database,
+		TableName:    manifest.Table,
+		hotTierBytes: manifest.HotTierBytes,
+		durable:      durable,
+	}
+	if len(records) > 0 {
+		tableData.Schema = records[0].Schema()
+		tableData.ParquetManager = NewParquetManager(tableData.Schema, parquet.DefaultParquetConfig())
+		for _, record := range records {
+			tableData.ParquetManager.appendRecord(record)
+		}
+	}
+	ms.tables[ms.getTableKey(manifest.Database, manifest.Table)] = tableData
+	return nil
+}
+
+// snapshotLoop snapshots durable tables with new inserts until the storage is closed
+func (ms *MemoryStorage) snapshotLoop() {
+	defer close(ms.done)
+
+	ticker := time.NewTicker(ms.snapshotInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ms.stop:
+			return
+		case <-ticker.C:
+			if err := ms.Checkpoint(); err != nil {
+				// Retried at the next tick
+				ms.logger.Warn().Err(err).Msg("Failed to snapshot memory tables")
+			}
+		}
+	}
+}
+
+// Checkpoint snapshots every durable table with inserts since its last snapshot; with the
+// wal durability this also empties its write-ahead log
+func (ms *MemoryStorage) Checkpoint() error {
+	ms.mu.RLock()
+	tables := make([]*TableData, 0, len(ms.tables))
+	for _, tableData := range ms.tables {
+		if tableData.durable != nil {
+			tables = append(tables, tableData)
+		}
+	}
+	ms.mu.RUnlock()
+
+	var firstErr error
+	for _, tableData := range tables {
+		if err := ms.snapshotTable(tableData); err != nil && firstErr == nil {
+			firstErr = errors.AddContext(err, "database", tableData.Database).AddContext("table", tableData.TableName)
+		}
+	}
+	return firstErr
+}
+
+// snapshotTable snapshots a durable table if it has inserts since its last snapshot
+func (ms *MemoryStorage) snapshotTable(tableData *TableData) error {
+	// Locks are taken in the order inserts take them, storage before table
+	ms.mu.RLock()
+	defer ms.mu.RUnlock()
+	durable := tableData.durable
+	durable.mu.Lock()
+	defer durable.mu.Unlock()
+	if !durable.dirty {
+		return nil
+	}
+
+	// Inserts hold durable.mu, so the records are exactly those logged so far
+	manager := tableData.ParquetManager
+
+	var records []arrow.Record
+	if manager != nil {
+		records = manager.records()
+		defer releaseRecords(records)
+	}
+	return durable.snapshot(records)
+}
+
+// Close snapshots the durable tables with new inserts and closes their files
+func (ms *MemoryStorage) Close() error {
+	var err error
+	ms.closeOnce.Do(func() {
+		if ms.stop == nil {
+			return
+		}
+		close(ms.stop)
+		<-ms.done
+
+		err = ms.Checkpoint()
+		ms.closeTables()
+	})
+	return err
+}
+
+// closeTables closes the files of the durable tables
+func (ms *MemoryStorage) closeTables() {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+	for _, tableData := range ms.tables {
+		if tableData.durable != nil {
+			tableData.durable.mu.Lock()
+			tableData.durable.close()
+			tableData.durable.mu.Unlock()
+		}
+	}
+}
@@ -0,0 +1,183 @@
+package memory
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/gear6io/ranger/pkg/errors"
+	"github.com/gear6io/ranger/server/paths"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// openDurable opens a durable memory storage that only snapshots when told to
+func openDurable(t *testing.T, pathManager paths.PathManager) *MemoryStorage {
+	ms, err := NewDurableMemoryStorage(pathManager, time.Hour, zerolog.Nop())
+	require.NoError(t, err)
+	return ms
+}
+
+// crash stops a storage without the snapshot Close takes, as a killed server would
+func crash(ms *MemoryStorage) {
+	close(ms.stop)
+	<-ms.done
+	ms.closeTables()
+}
+
+// insertRows writes rows to a table as the storage manager does
+func insertRows(t *testing.T, ms *MemoryStorage, rows [][]interface{}) {
+	writer, err := ms.OpenTableForWrite("db", "events")
+	require.NoError(t, err)
+	data, err := json.Marshal(rows)
+	require.NoError(t, err)
+	_, err = writer.Write(append(data, '\n'))
+	require.NoError(t, err)
+	require.NoError(t, writer.Close())
+}
+
+// tableRows returns the rows of the table, or nil when it has no data
+func tableRows(t *testing.T, ms *MemoryStorage) [][]interface{} {
+	tableData, exists := ms.tables[ms.getTableKey("db", "events")]
+	require.True(t, exists, "table should exist")
+	if tableData.ParquetManager == nil {
+		return nil
+	}
+	rows, err := tableData.ParquetManager.GetData()
+	require.NoError(t, err)
+	return rows
+}
+
+func TestDurableMemoryStorage_WAL(t *testing.T) {
+	pathManager := &paths.MockPathManager{BasePath: t.TempDir()}
+	ms := openDurable(t, pathManager)
+	require.NoError(t, ms.SetupTableWithSettings("db", "events", map[string]interface{}{"durability": "wal"}))
+	insertRows(t, ms, [][]interface{}{{1, "a", 1.5}, {2, "b", 2.5}})
+	insertRows(t, ms, [][]interface{}{{3, "c", 3.5}})
+	crash(ms)
+
+	expected := [][]interface{}{{1.0, "a", 1.5}, {2.0, "b", 2.5}, {3.0, "c", 3.5}}
+	dir := filepath.Join(pathManager.GetTablePath("db", "events"), durableDir)
+
+	t.Run("InsertsSurviveCrash", func(t *testing.T) {
+		ms := openDurable(t, pathManager)
+		defer crash(ms)
+		assert.Equal(t, expected, tableRows(t, ms))
+	})
+
+	t.Run("TornEntryIsDropped", func(t *testing.T) {
+		wal, err := os.OpenFile(filepath.Join(dir, walFile), os.O_APPEND|os.O_WRONLY, 0)
+		require.NoError(t, err)
+		_, err = wal.Write([]byte{0x40, 0, 0, 0, 1, 2})
+		require.NoError(t, err)
+		require.NoError(t, wal.Close())
+
+		ms := openDurable(t, pathManager)
+		defer crash(ms)
+		assert.Equal(t, expected, tableRows(t, ms))
+
+		// The log ends at its last whole entry, ready for new ones
+		insertRows(t, ms, [][]interface{}{{4, "d", 4.5}})
+		assert.Len(t, tableRows(t, ms), 4)
+	})
+
+	t.Run("CheckpointCompactsLog", func(t *testing.T) {
+		ms := openDurable(t, pathManager)
+		require.Len(t, tableRows(t, ms), 4)
+		require.NoError(t, ms.Checkpoint())
+		crash(ms)
+
+		info, err := os.Stat(filepath.Join(dir, walFile))
+		require.NoError(t, err)
+		assert.Zero(t, info.Size())
+
+		ms = openDurable(t, pathManager)
+		defer crash(ms)
+		assert.Len(t, tableRows(t, ms), 4)
+	})
+
+	t.Run("CoveredEntriesAreSkipped", func(t *testing.T) {
+		// A crash between writing the snapshot and emptying the log leaves entries the
+		// snapshot already holds, followed by new ones
+		ms := openDurable(t, pathManager)
+		insertRows(t, ms, [][]interface{}{{5, "e", 5.5}})
+		covered, err := os.ReadFile(filepath.Join(dir, walFile))
+		require.NoError(t, err)
+		require.NoError(t, ms.Checkpoint())
+		insertRows(t, ms, [][]interface{}{{6, "f", 6.5}})
+		crash(ms)
+
+		logged, err := os.ReadFile(filepath.Join(dir, walFile))
+		require.NoError(t, err)
+		require.NoError(t, os.WriteFile(filepath.Join(dir, walFile), append(covered, logged...), 0644))
+
+		ms = openDurable(t, pathManager)
+		rows := tableRows(t, ms)
+		require.Len(t, rows, 6)
+		assert.Equal(t, []interface{}{6.0, "f", 6.5}, rows[5])
+
+		// Entries logged after recovery follow the covered ones
+		insertRows(t, ms, [][]interface{}{{7, "g", 7.5}})
+		crash(ms)
+		ms = openDurable(t, pathManager)
+		defer crash(ms)
+		assert.Len(t, tableRows(t, ms), 7)
+	})
+
+	t.Run("RemoveDeletesFiles", func(t *testing.T) {
+		ms := openDurable(t, pathManager)
+		require.NoError(t, ms.RemoveTableEnvironment("db", "events"))
+		require.NoError(t, ms.Close())
+		assert.NoDirExists(t, dir)
+
+		ms = openDurable(t, pathManager)
+		defer crash(ms)
+		assert.Empty(t, ms.tables)
+	})
+}
+
+func TestDurableMemoryStorage_Snapshot(t *testing.T) {
+	pathManager := &paths.MockPathManager{BasePath: t.TempDir()}
+	ms := openDurable(t, pathManager)
+	require.NoError(t, ms.SetupTableWithSettings("db", "events", map[string]interface{}{"durability": "SNAPSHOT"}))
+	insertRows(t, ms, [][]interface{}{{1, "a", 1.5}})
+	crash(ms)
+
+	// Inserts since the last snapshot are lost, but the table is not
+	ms = openDurable(t, pathManager)
+	assert.Nil(t, tableRows(t, ms))
+	insertRows(t, ms, [][]interface{}{{2, "b", 2.5}})
+	require.NoError(t, ms.Close())
+
+	ms = openDurable(t, pathManager)
+	defer crash(ms)
+	assert.Equal(t, [][]interface{}{{2.0, "b", 2.5}}, tableRows(t, ms))
+	assert.NoFileExists(t, filepath.Join(pathManager.GetTablePath("db", "events"), durableDir, walFile))
+}
+
+func TestMemoryStorage_DurabilitySettings(t *testing.T) {
+	pathManager := &paths.MockPathManager{BasePath: t.TempDir()}
+	ms := openDurable(t, pathManager)
+	defer ms.Close()
+
+	assert.NoError(t, ms.ValidateTableSettings(nil))
+	assert.NoError(t, ms.ValidateTableSettings(map[string]interface{}{"durability": "none"}))
+
+	err := ms.ValidateTableSettings(map[string]interface{}{"durability": "forever"})
+	assert.Equal(t, ErrInvalidDurability.String(), errors.GetCode(err))
+	err = ms.ValidateTableSettings(map[string]interface{}{"durability": 1})
+	assert.Equal(t, ErrInvalidDurability.String(), errors.GetCode(err))
+
+	// Tables without durability leave nothing on disk
+	require.NoError(t, ms.SetupTableWithSettings("db", "events", map[string]interface{}{"durability": "none"}))
+	assert.NoDirExists(t, pathManager.GetTablePath("db", "events"))
+
+	// Storage without a data path cannot keep durable tables
+	inMemory, err := NewMemoryStorage()
+	require.NoError(t, err)
+	err = inMemory.SetupTableWithSettings("db", "events", map[string]interface{}{"durability": "wal"})
+	assert.Equal(t, ErrDurabilityUnavailable.String(), errors.GetCode(err))
+}
@@ -39,4 +39,17 @@ var (
 	ErrTypeMismatch           = errors.MustNewCode("memory.type_mismatch")
 	ErrUnsupportedDataType    = errors.MustNewCode("memory.unsupported_data_type")
 	ErrValueExtractionFailed  = errors.MustNewCode("memory.value_extraction_failed")
+
+	// Durability errors
+	ErrInvalidDurability     = errors.MustNewCode("memory.invalid_durability")
+	ErrDurabilityUnavailable = errors.MustNewCode("memory.durability_unavailable")
+	ErrWALWriteFailed        = errors.MustNewCode("memory.wal_write_failed")
+	ErrSnapshotFailed        = errors.MustNewCode("memory.snapshot_failed")
+	ErrRecoveryFailed        = errors.MustNewCode("memory.recovery_failed")
+
+	// Hot tier errors
+	ErrInvalidHotTier      = errors.MustNewCode("memory.invalid_hot_tier")
+	ErrSpillUnavailable    = errors.MustNewCode("memory.spill_unavailable")
+	ErrSpillFailed         = errors.MustNewCode("memory.spill_failed")
+	ErrSettingNotAlterable = errors.MustNewCode("memory.setting_not_alterable")
 )
@@ -1,15 +1,19 @@
 package memory
 
 import (
+	"bytes"
 	"encoding/json"
 	"fmt"
 	"io"
 	"sync"
+	"time"
 
 	"github.com/apache/arrow-go/v18/arrow"
 	"github.com/apache/iceberg-go"
 	"github.com/gear6io/ranger/pkg/errors"
+	"github.com/gear6io/ranger/server/paths"
 	"github.com/gear6io/ranger/server/storage/parquet"
+	"github.com/rs/zerolog"
 )
 
 // StorageType constant for this storage engine
@@ -20,6 +24,19 @@ type MemoryStorage struct {
 	data   map[string][]byte
 	tables map[string]*TableData
 	mu     sync.RWMutex
+
+	// Tables over their hot tier, or all tables over the budget, spill their oldest batches
+	// to the spiller; nil when tables only fail inserts past their memory limit
+	spiller       Spiller
+	hotTierBudget int64
+
+	// Durable tables are kept under their table paths; nil when tables live in memory only
+	pathManager      paths.PathManager
+	snapshotInterval time.Duration
+	logger           zerolog.Logger
+	stop             chan struct{}
+	done             chan struct{}
+	closeOnce        sync.Once
 }
 
 // TableData represents a table in memory storage
@@ -29,9 +46,12 @@ type TableData struct {
 	IcebergSchema  *iceberg.Schema
 	Database       string
 	TableName      string
+
+	hotTierBytes int64         // Memory the table holds before it spills; its memory limit when 0
+	durable      *durableTable // nil unless the table has durability
 }
 
-// NewMemoryStorage creates a new memory storage
+// NewMemoryStorage creates a new memory storage whose tables live in memory only
 func NewMemoryStorage() (*MemoryStorage, error) {
 	return &MemoryStorage{
 		data:   make(map[string][]byte),
@@ -39,6 +59,32 @@ func NewMemoryStorage() (*MemoryStorage, error) {
 	}, nil
 }
 
+// NewDurableMemoryStorage creates a memory storage whose tables may be made durable with
+// SETTINGS durability. Durable tables found under the data path are recovered from their
+// snapshots and write-ahead logs, and tables with new inserts are snapshotted every
+// snapshotInterval until Close, with failures logged to logger.
+func NewDurableMemoryStorage(pathManager paths.PathManager, snapshotInterval time.Duration, logger zerolog.Logger) (*MemoryStorage, error) {
+	if snapshotInterval <= 0 {
+		snapshotInterval = DefaultSnapshotInterval
+	}
+	ms := &MemoryStorage{
+		data:             make(map[string][]byte),
+		tables:           make(map[string]*TableData),
+		pathManager:      pathManager,
+		snapshotInterval: snapshotInterval,
+		logger:           logger,
+		stop:             make(chan struct{}),
+		done:             make(chan struct{}),
+	}
+	if err := ms.recoverTables(); err != nil {
+		ms.closeTables()
+		return nil, err
+	}
+
+	go ms.snapshotLoop()
+	return ms, nil
+}
+
 // GetStorageType returns the storage type identifier
 func (ms *MemoryStorage) GetStorageType() string {
 	return Type
@@ -46,6 +92,32 @@ func (ms *MemoryStorage) GetStorageType() string {
 
 // SetupTable creates the storage environment for a table
 func (ms *MemoryStorage) SetupTable(database, tableName string) error {
+	return ms.SetupTableWithSettings(database, tableName, nil)
+}
+
+// ValidateTableSettings checks the settings of a new table, such as its durability and
+// hot tier
+func (ms *MemoryStorage) ValidateTableSettings(settings map[string]interface{}) error {
+	durability, err := parseDurability(settings)
+	if err != nil {
+		return err
+	}
+	if durability != DurabilityNone && ms.pathManager == nil {
+		return errors.New(ErrDurabilityUnavailable, "memory storage has no data path for durable tables", nil).AddContext("durability", string(durability))
+	}
+	_, err = ms.parseHotTier(settings)
+	return err
+}
+
+// SetupTableWithSettings creates the storage environment for a table with the given
+// settings; a durable table gets its directory, manifest and write-ahead log
+func (ms *MemoryStorage) SetupTableWithSettings(database, tableName string, settings map[string]interface{}) error {
+	if err := ms.ValidateTableSettings(settings); err != nil {
+		return err
+	}
+	durability, _ := parseDurability(settings)
+	hotTierBytes, _ := ms.parseHotTier(settings)
+
 	ms.mu.Lock()
 	defer ms.mu.Unlock()
 
@@ -58,10 +130,24 @@ func (ms *MemoryStorage) SetupTable(database, tableName string) error {
 
 	// Create a placeholder table entry
 	// The actual schema will be set when StoreTableData is called
-	ms.tables[tableKey] = &TableData{
-		Database:  database,
-		TableName: tableName,
+	tableData := &TableData{
+		Database:     database,
+		TableName:    tableName,
+		hotTierBytes: hotTierBytes,
+	}
+	if durability != DurabilityNone {
+		durable, err := createDurableTable(ms.durableDir(database, tableName), tableManifest{
+			Database:     database,
+			Table:        tableName,
+			Durability:   durability,
+			HotTierBytes: hotTierBytes,
+		})
+		if err != nil {
+			return err
+		}
+		tableData.durable = durable
 	}
+	ms.tables[tableKey] = tableData
 
 	return nil
 }
@@ -138,6 +224,23 @@ func (ms *MemoryStorage) RemoveTableEnvironment(database, tableName string) erro
 		}
 	}
 
+	// Remove the snapshot and write-ahead log of a durable table
+	if tableData.durable != nil {
+		tableData.durable.mu.Lock()
+		err := tableData.durable.remove()
+		tableData.durable.mu.Unlock()
+		if err != nil {
+			return errors.New(ErrDurabilityUnavailable, "failed to remove durable table files", err).AddContext("database", database).AddContext("table", tableName)
+		}
+	}
+
+	// Remove the batches the table spilled
+	if ms.spiller != nil {
+		if err := ms.spiller.RemoveSpilled(database, tableName); err != nil {
+			return errors.New(ErrSpillFailed, "failed to remove spilled table data", err).AddContext("database", database).AddContext("table", tableName)
+		}
+	}
+
 	// Remove table entry
 	delete(ms.tables, tableKey)
 
@@ -242,6 +345,15 @@ func (ms *MemoryStorage) SetTableSchema(database, tableName string, icebergSchem
 	config := parquet.DefaultParquetConfig()
 	tableData.ParquetManager = NewParquetManager(arrowSchema, config)
 
+	// The data of a durable table was dropped with its old schema, and so are its files
+	if tableData.durable != nil {
+		tableData.durable.mu.Lock()
+		defer tableData.durable.mu.Unlock()
+		if err := tableData.durable.snapshot(nil); err != nil {
+			return err
+		}
+	}
+
 	return nil
 }
 
@@ -293,13 +405,7 @@ func (ms *MemoryStorage) getTableKey(database, tableName string) string {
 }
 
 // createDefaultSchema creates a default schema based on parsed data
-func (ms *MemoryStorage) createDefaultSchema(data []byte) (*arrow.Schema, error) {
-	// Parse the JSON to understand the data structure
-	var parsedData [][]interface{}
-	if err := json.Unmarshal(data, &parsedData); err != nil {
-		return nil, errors.New(ErrSchemaInferenceFailed, "failed to parse data for schema inference", err)
-	}
-
+func (ms *MemoryStorage) createDefaultSchema(parsedData [][]interface{}) (*arrow.Schema, error) {
 	if len(parsedData) == 0 {
 		return nil, errors.New(ErrEmptyDataForSchema, "cannot infer schema from empty data", nil)
 	}
@@ -348,14 +454,20 @@ func (ms *MemoryStorage) createDefaultSchema(data []byte) (*arrow.Schema, error)
 	return arrow.NewSchema(fields, nil), nil
 }
 
-// convertBytesToInterface converts JSON bytes back to interface format
+// convertBytesToInterface converts JSON bytes back to interface format; the bytes may hold
+// several batches, one after another, as table writers write them
 func (ms *MemoryStorage) convertBytesToInterface(data []byte) ([][]interface{}, error) {
-	// Parse JSON back to [][]interface{}
 	var result [][]interface{}
-	if err := json.Unmarshal(data, &result); err != nil {
-		return nil, errors.New(ErrDataUnmarshalFailed, "failed to unmarshal JSON data", err)
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	for {
+		var batch [][]interface{}
+		if err := decoder.Decode(&batch); err == io.EOF {
+			return result, nil
+		} else if err != nil {
+			return nil, errors.New(ErrDataUnmarshalFailed, "failed to unmarshal JSON data", err)
+		}
+		result = append(result, batch...)
 	}
-	return result, nil
 }
 
 // convertInterfaceToBytes converts interface format back to bytes
@@ -398,6 +510,13 @@ func (mtw *memoryTableWriter) Write(p []byte) (n int, err error) {
 	return len(p), nil
 }
 
+// Abort discards the buffered rows; nothing is added to the table
+func (mtw *memoryTableWriter) Abort() error {
+	mtw.closed = true
+	mtw.buffer = nil
+	return nil
+}
+
 func (mtw *memoryTableWriter) Close() error {
 	if mtw.closed {
 		return nil
@@ -411,6 +530,9 @@ func (mtw *memoryTableWriter) Close() error {
 		if err != nil {
 			return err
 		}
+		if len(interfaceData) == 0 {
+			return nil
+		}
 
 		mtw.storage.mu.Lock()
 		defer mtw.storage.mu.Unlock()
@@ -418,7 +540,7 @@ func (mtw *memoryTableWriter) Close() error {
 		tableData := mtw.storage.tables[mtw.tableKey]
 		if tableData.ParquetManager == nil {
 			// Create default schema and Parquet manager
-			schema, err := mtw.storage.createDefaultSchema(mtw.buffer)
+			schema, err := mtw.storage.createDefaultSchema(interfaceData)
 			if err != nil {
 				return err
 			}
@@ -428,12 +550,56 @@ func (mtw *memoryTableWriter) Close() error {
 			tableData.ParquetManager = NewParquetManager(tableData.Schema, config)
 		}
 
-		// Store data using Parquet manager
-		if err := tableData.ParquetManager.StoreData(interfaceData); err != nil {
+		return mtw.storage.store(tableData, interfaceData)
+	}
+
+	return nil
+}
+
+// store stores data in a table; the caller holds mu. Without a spiller an insert past the
+// memory limit of the table fails, and with one the oldest batches of tables over their hot
+// tier spill once the insert is stored.
+func (ms *MemoryStorage) store(tableData *TableData, data [][]interface{}) error {
+	manager := tableData.ParquetManager
+	record, err := manager.newRecord(data)
+	if err != nil {
+		return err
+	}
+	defer record.Release()
+
+	if ms.spiller == nil {
+		if err := manager.checkMemoryUsage(record); err != nil {
 			return err
 		}
 	}
+	if err := storeRecord(tableData, record); err != nil {
+		return err
+	}
+
+	if ms.spiller != nil {
+		if err := ms.enforceHotTier(); err != nil {
+			// The insert is stored; the spill is retried after the next one
+			fmt.Printf("Warning: failed to spill memory tables: %v\n", err)
+		}
+	}
+	return nil
+}
 
+// storeRecord stores a record in a table; with the wal durability the record is in the
+// write-ahead log before it is stored, so an acknowledged insert survives a crash
+func storeRecord(tableData *TableData, record arrow.Record) error {
+	durable := tableData.durable
+	if durable == nil {
+		tableData.ParquetManager.appendRecord(record)
+		return nil
+	}
+
+	durable.mu.Lock()
+	defer durable.mu.Unlock()
+	if err := durable.log(record); err != nil {
+		return err
+	}
+	tableData.ParquetManager.appendRecord(record)
 	return nil
 }
 
@@ -2,6 +2,7 @@ package memory
 
 import (
 	"encoding/json"
+	"io"
 	"testing"
 
 	"github.com/apache/iceberg-go"
@@ -195,6 +196,32 @@ func TestMemoryStorage_GetTableRowCount(t *testing.T) {
 	assert.Greater(t, count, int64(0))
 }
 
+func TestMemoryStorage_AbortTableWrite(t *testing.T) {
+	ms, err := NewMemoryStorage()
+	require.NoError(t, err)
+
+	write := func(rows [][]interface{}) io.WriteCloser {
+		writer, err := ms.OpenTableForWrite("testdb", "testtable")
+		require.NoError(t, err)
+		jsonData, err := json.Marshal(rows)
+		require.NoError(t, err)
+		_, err = writer.Write(jsonData)
+		require.NoError(t, err)
+		return writer
+	}
+
+	require.NoError(t, write([][]interface{}{{1, "kept", 1.5}}).Close())
+
+	// An aborted write adds nothing and leaves the earlier rows in place
+	aborted := write([][]interface{}{{2, "dropped", 2.5}, {3, "dropped", 3.5}})
+	require.NoError(t, aborted.(interface{ Abort() error }).Abort())
+	require.NoError(t, aborted.Close())
+
+	count, err := ms.GetTableRowCount("testdb", "testtable")
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), count)
+}
+
 func TestMemoryStorage_GetTableMemoryUsage(t *testing.T) {
 	ms, err := NewMemoryStorage()
 	require.NoError(t, err)
@@ -0,0 +1,232 @@
+package memory
+
+import (
+	"math"
+	"strconv"
+
+	"github.com/apache/arrow-go/v18/arrow"
+	"github.com/gear6io/ranger/pkg/errors"
+)
+
+// HotTierSetting is the table setting bounding the bytes a memory table holds before its
+// oldest batches spill, set with SETTINGS hot_tier_bytes = <bytes> on CREATE or ALTER TABLE
+const HotTierSetting = "hot_tier_bytes"
+
+// Spiller keeps the batches that memory tables spill as part of the same tables in another
+// tier, such as Parquet files of the filesystem engine
+type Spiller interface {
+	// SpillRecords stores records spilled from a table, oldest first
+	SpillRecords(database, tableName string, records []arrow.Record) error
+
+	// ReadSpilled returns the rows spilled from a table, oldest first
+	ReadSpilled(database, tableName string) ([][]interface{}, error)
+
+	// RemoveSpilled deletes the rows spilled from a table
+	RemoveSpilled(database, tableName string) error
+}
+
+// SetSpiller makes tables spill their oldest batches to spiller instead of failing inserts
+// past their memory limit. A table spills once it holds more than its hot_tier_bytes, or its
+// memory limit when unset, and the largest tables spill while all tables together hold more
+// than budget, unless budget is 0.
+func (ms *MemoryStorage) SetSpiller(spiller Spiller, budget int64) {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+	ms.spiller = spiller
+	ms.hotTierBudget = budget
+}
+
+// parseHotTier returns the hot tier selected by table settings; 0 when unset
+func (ms *MemoryStorage) parseHotTier(settings map[string]interface{}) (int64, error) {
+	value, ok := settings[HotTierSetting]
+	if !ok {
+		return 0, nil
+	}
+
+	var bytes int64
+	switch v := value.(type) {
+	case int:
+		bytes = int64(v)
+	case int64:
+		bytes = v
+	case float64:
+		if v != math.Trunc(v) || v > math.MaxInt64 {
+			return 0, errors.New(ErrInvalidHotTier, "hot_tier_bytes must be a whole number of bytes", nil).AddContext("hot_tier_bytes", value)
+		}
+		bytes = int64(v)
+	case string:
+		parsed, err := strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			return 0, errors.New(ErrInvalidHotTier, "hot_tier_bytes must be a whole number of bytes", err).AddContext("hot_tier_bytes", value)
+		}
+		bytes = parsed
+	default:
+		return 0, errors.New(ErrInvalidHotTier, "hot_tier_bytes must be a whole number of bytes", nil).AddContext("hot_tier_bytes", value)
+	}
+	if bytes < 0 {
+		return 0, errors.New(ErrInvalidHotTier, "hot_tier_bytes cannot be negative", nil).AddContext("hot_tier_bytes", value)
+	}
+	if bytes > 0 && ms.spiller == nil {
+		return 0, errors.New(ErrSpillUnavailable, "memory storage has no tier to spill tables to", nil).AddContext("hot_tier_bytes", value)
+	}
+	return bytes, nil
+}
+
+// UpdateTableSettings applies the SETTINGS of ALTER TABLE to a table. Only the hot tier can
+// change; tables over their new hot tier spill at once.
+func (ms *MemoryStorage) UpdateTableSettings(database, tableName string, settings map[string]interface{}) error {
+	for key := range settings {
+		if key != HotTierSetting {
+			return errors.New(ErrSettingNotAlterable, "setting cannot be changed on a memory table", nil).AddContext("setting", key)
+		}
+	}
+	hotTierBytes, err := ms.parseHotTier(settings)
+	if err != nil {
+		return err
+	}
+
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+
+	tableData, exists := ms.tables[ms.getTableKey(database, tableName)]
+	if !exists {
+		return errors.New(ErrTableNotFound, "table does not exist", nil).AddContext("database", database).AddContext("table", tableName)
+	}
+
+	// A durable table keeps its hot tier across restarts
+	if durable := tableData.durable; durable != nil {
+		durable.mu.Lock()
+		err := writeManifest(durable.dir, tableManifest{
+			Database:     database,
+			Table:        tableName,
+			Durability:   durable.durability,
+			HotTierBytes: hotTierBytes,
+		})
+		durable.mu.Unlock()
+		if err != nil {
+			return err
+		}
+	}
+	tableData.hotTierBytes = hotTierBytes
+
+	if ms.spiller == nil {
+		return nil
+	}
+	return ms.enforceHotTier()
+}
+
+// ReadTable returns the rows of a table, those it spilled first, oldest first. Spills hold
+// mu, so no row is read from both tiers or from neither.
+func (ms *MemoryStorage) ReadTable(database, tableName string) ([][]interface{}, error) {
+	ms.mu.RLock()
+	defer ms.mu.RUnlock()
+
+	tableData, exists := ms.tables[ms.getTableKey(database, tableName)]
+	if !exists {
+		return nil, errors.New(ErrTableNotFound, "table does not exist", nil).AddContext("database", database).AddContext("table", tableName)
+	}
+
+	var rows [][]interface{}
+	if ms.spiller != nil {
+		spilled, err := ms.spiller.ReadSpilled(database, tableName)
+		if err != nil {
+			return nil, errors.New(ErrDataRetrievalFailed, "failed to read spilled table data", err).AddContext("database", database).AddContext("table", tableName)
+		}
+		rows = spilled
+	}
+	if tableData.ParquetManager != nil {
+		data, err := tableData.ParquetManager.GetData()
+		if err != nil {
+			return nil, err
+		}
+		rows = append(rows, data...)
+	}
+	return rows, nil
+}
+
+// hotTier returns the bytes a table holds before it spills
+func (tableData *TableData) hotTier() int64 {
+	if tableData.hotTierBytes > 0 {
+		return tableData.hotTierBytes
+	}
+	return tableData.ParquetManager.config.MaxMemoryUsage
+}
+
+// enforceHotTier spills the oldest batches of each table over its hot tier, then those of
+// the largest tables while all tables together are over the budget; the caller holds mu
+func (ms *MemoryStorage) enforceHotTier() error {
+	sizes := make(map[*TableData][]int64)
+	usage := make(map[*TableData]int64)
+	spill := make(map[*TableData]int)
+	var total int64
+	for _, tableData := range ms.tables {
+		if tableData.ParquetManager == nil {
+			continue
+		}
+		batches := tableData.ParquetManager.batchSizes()
+		var used int64
+		for _, size := range batches {
+			used += size
+		}
+		for limit := tableData.hotTier(); used > limit && spill[tableData] < len(batches); spill[tableData]++ {
+			used -= batches[spill[tableData]]
+		}
+		sizes[tableData] = batches
+		usage[tableData] = used
+		total += used
+	}
+
+	for ms.hotTierBudget > 0 && total > ms.hotTierBudget {
+		var largest *TableData
+		for tableData, used := range usage {
+			if spill[tableData] < len(sizes[tableData]) && (largest == nil || used > usage[largest]) {
+				largest = tableData
+			}
+		}
+		if largest == nil {
+			break
+		}
+		size := sizes[largest][spill[largest]]
+		usage[largest] -= size
+		total -= size
+		spill[largest]++
+	}
+
+	var firstErr error
+	for tableData, n := range spill {
+		if n == 0 {
+			continue
+		}
+		if err := ms.spillOldest(tableData, n); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+// spillOldest moves the n oldest batches of a table to the spiller; the caller holds mu. A
+// durable table is snapshotted without them, so they are not recovered from its files too;
+// a crash in between recovers them twice rather than losing them.
+func (ms *MemoryStorage) spillOldest(tableData *TableData, n int) error {
+	manager := tableData.ParquetManager
+	records := manager.oldest(n)
+	defer releaseRecords(records)
+
+	durable := tableData.durable
+	if durable != nil {
+		durable.mu.Lock()
+		defer durable.mu.Unlock()
+	}
+
+	if err := ms.spiller.SpillRecords(tableData.Database, tableData.TableName, records); err != nil {
+		return errors.New(ErrSpillFailed, "failed to spill table data", err).AddContext("database", tableData.Database).AddContext("table", tableData.TableName)
+	}
+	manager.dropOldest(len(records))
+
+	if durable == nil {
+		return nil
+	}
+	remaining := manager.records()
+	defer releaseRecords(remaining)
+	return durable.snapshot(remaining)
+}
@@ -0,0 +1,170 @@
+package memory
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/apache/arrow-go/v18/arrow"
+	"github.com/gear6io/ranger/pkg/errors"
+	"github.com/gear6io/ranger/server/paths"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// rowBytes is the estimated size of a row written by insertRows: two float64s and a string
+const rowBytes = 32
+
+// fakeSpiller keeps spilled rows per table and fails spills when told to
+type fakeSpiller struct {
+	rows map[string][][]interface{}
+	fail bool
+}
+
+func newFakeSpiller() *fakeSpiller {
+	return &fakeSpiller{rows: make(map[string][][]interface{})}
+}
+
+func (f *fakeSpiller) SpillRecords(database, tableName string, records []arrow.Record) error {
+	if f.fail {
+		return fmt.Errorf("spill target unavailable")
+	}
+	key := database + "." + tableName
+	for _, record := range records {
+		rows, err := (&ParquetManager{}).convertRecordToData(record)
+		if err != nil {
+			return err
+		}
+		f.rows[key] = append(f.rows[key], rows...)
+	}
+	return nil
+}
+
+func (f *fakeSpiller) ReadSpilled(database, tableName string) ([][]interface{}, error) {
+	return f.rows[database+"."+tableName], nil
+}
+
+func (f *fakeSpiller) RemoveSpilled(database, tableName string) error {
+	delete(f.rows, database+"."+tableName)
+	return nil
+}
+
+func TestMemoryStorage_HotTier(t *testing.T) {
+	ms, err := NewMemoryStorage()
+	require.NoError(t, err)
+	spiller := newFakeSpiller()
+	ms.SetSpiller(spiller, 0)
+	require.NoError(t, ms.SetupTableWithSettings("db", "events", map[string]interface{}{HotTierSetting: 2 * rowBytes}))
+
+	insertRows(t, ms, [][]interface{}{{1, "a", 1.5}})
+	insertRows(t, ms, [][]interface{}{{2, "b", 2.5}})
+	assert.Empty(t, spiller.rows)
+
+	// The oldest batch spills once the table is over its hot tier
+	insertRows(t, ms, [][]interface{}{{3, "c", 3.5}})
+	assert.Equal(t, [][]interface{}{{1.0, "a", 1.5}}, spiller.rows["db.events"])
+	assert.Equal(t, [][]interface{}{{2.0, "b", 2.5}, {3.0, "c", 3.5}}, tableRows(t, ms))
+
+	usage, err := ms.GetTableMemoryUsage("db", "events")
+	require.NoError(t, err)
+	assert.Equal(t, int64(2*rowBytes), usage)
+	count, err := ms.GetTableRowCount("db", "events")
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), count)
+
+	// Reads cover both tiers, oldest first
+	rows, err := ms.ReadTable("db", "events")
+	require.NoError(t, err)
+	assert.Equal(t, [][]interface{}{{1.0, "a", 1.5}, {2.0, "b", 2.5}, {3.0, "c", 3.5}}, rows)
+
+	t.Run("AlterSpillsAtOnce", func(t *testing.T) {
+		require.NoError(t, ms.UpdateTableSettings("db", "events", map[string]interface{}{HotTierSetting: "32"}))
+		assert.Len(t, spiller.rows["db.events"], 2)
+		assert.Equal(t, [][]interface{}{{3.0, "c", 3.5}}, tableRows(t, ms))
+	})
+
+	t.Run("FailedSpillKeepsRows", func(t *testing.T) {
+		spiller.fail = true
+		insertRows(t, ms, [][]interface{}{{4, "d", 4.5}})
+		assert.Len(t, tableRows(t, ms), 2)
+
+		spiller.fail = false
+		insertRows(t, ms, [][]interface{}{{5, "e", 5.5}})
+		assert.Equal(t, [][]interface{}{{5.0, "e", 5.5}}, tableRows(t, ms))
+		rows, err := ms.ReadTable("db", "events")
+		require.NoError(t, err)
+		assert.Len(t, rows, 5)
+	})
+
+	t.Run("RemoveDeletesSpilled", func(t *testing.T) {
+		require.NoError(t, ms.RemoveTableEnvironment("db", "events"))
+		assert.Empty(t, spiller.rows)
+	})
+}
+
+func TestMemoryStorage_HotTierBudget(t *testing.T) {
+	ms, err := NewMemoryStorage()
+	require.NoError(t, err)
+	spiller := newFakeSpiller()
+	ms.SetSpiller(spiller, 3*rowBytes)
+
+	insertRows(t, ms, [][]interface{}{{1, "a", 1.5}})
+	insertRows(t, ms, [][]interface{}{{2, "b", 2.5}})
+	writer, err := ms.OpenTableForWrite("db", "small")
+	require.NoError(t, err)
+	_, err = writer.Write([]byte(`[[9, "z", 9.5]]`))
+	require.NoError(t, err)
+	require.NoError(t, writer.Close())
+	assert.Empty(t, spiller.rows)
+
+	// Over the budget, the largest table spills its oldest batch
+	insertRows(t, ms, [][]interface{}{{3, "c", 3.5}})
+	assert.Equal(t, [][]interface{}{{1.0, "a", 1.5}}, spiller.rows["db.events"])
+	assert.Empty(t, spiller.rows["db.small"])
+}
+
+func TestDurableMemoryStorage_HotTier(t *testing.T) {
+	pathManager := &paths.MockPathManager{BasePath: t.TempDir()}
+	spiller := newFakeSpiller()
+	ms := openDurable(t, pathManager)
+	ms.SetSpiller(spiller, 0)
+	require.NoError(t, ms.SetupTableWithSettings("db", "events", map[string]interface{}{
+		DurabilitySetting: "wal",
+		HotTierSetting:    rowBytes,
+	}))
+	insertRows(t, ms, [][]interface{}{{1, "a", 1.5}})
+	insertRows(t, ms, [][]interface{}{{2, "b", 2.5}})
+	require.Len(t, spiller.rows["db.events"], 1)
+	crash(ms)
+
+	// Spilled rows are not recovered into memory, and the hot tier is kept
+	ms = openDurable(t, pathManager)
+	defer crash(ms)
+	ms.SetSpiller(spiller, 0)
+	assert.Equal(t, [][]interface{}{{2.0, "b", 2.5}}, tableRows(t, ms))
+	insertRows(t, ms, [][]interface{}{{3, "c", 3.5}})
+	assert.Len(t, spiller.rows["db.events"], 2)
+}
+
+func TestMemoryStorage_HotTierSettings(t *testing.T) {
+	ms, err := NewMemoryStorage()
+	require.NoError(t, err)
+
+	// Storage without a spiller cannot keep a hot tier
+	err = ms.ValidateTableSettings(map[string]interface{}{HotTierSetting: 1024})
+	assert.Equal(t, ErrSpillUnavailable.String(), errors.GetCode(err))
+	assert.NoError(t, ms.ValidateTableSettings(map[string]interface{}{HotTierSetting: 0}))
+
+	ms.SetSpiller(newFakeSpiller(), 0)
+	assert.NoError(t, ms.ValidateTableSettings(map[string]interface{}{HotTierSetting: 1024}))
+	assert.NoError(t, ms.ValidateTableSettings(map[string]interface{}{HotTierSetting: float64(1024)}))
+	for _, value := range []interface{}{-1, "lots", 1.5, true} {
+		err := ms.ValidateTableSettings(map[string]interface{}{HotTierSetting: value})
+		assert.Equal(t, ErrInvalidHotTier.String(), errors.GetCode(err), "value %v", value)
+	}
+
+	require.NoError(t, ms.SetupTable("db", "events"))
+	err = ms.UpdateTableSettings("db", "events", map[string]interface{}{DurabilitySetting: "wal"})
+	assert.Equal(t, ErrSettingNotAlterable.String(), errors.GetCode(err))
+	err = ms.UpdateTableSettings("db", "missing", map[string]interface{}{HotTierSetting: 1024})
+	assert.Equal(t, ErrTableNotFound.String(), errors.GetCode(err))
+}
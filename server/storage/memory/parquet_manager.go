@@ -56,38 +56,110 @@ func (dm *ParquetManager) StoreData(data [][]interface{}) error {
 
 	startTime := time.Now()
 
+	record, err := dm.newRecord(data)
+	if err != nil {
+		return err
+	}
+	defer record.Release()
+
+	// Check memory usage
+	if err := dm.checkMemoryUsage(record); err != nil {
+		return err
+	}
+
+	dm.appendRecord(record)
+
+	// Update stats
+	duration := time.Since(startTime).Nanoseconds()
+	dm.stats.WriteDuration = duration
+
+	return nil
+}
+
+// newRecord validates data against the schema and converts it to a record
+func (dm *ParquetManager) newRecord(data [][]interface{}) (arrow.Record, error) {
 	// Validate data against schema
 	if err := parquet.ValidateData(data, dm.schema); err != nil {
-		return errors.New(ErrDataValidationFailed, "data validation failed", err)
+		return nil, errors.New(ErrDataValidationFailed, "data validation failed", err)
 	}
 
 	// Convert data to Arrow arrays
 	arrays, err := dm.convertDataToArrays(data)
 	if err != nil {
-		return err
+		return nil, err
 	}
+	defer func() {
+		for _, arr := range arrays {
+			arr.Release()
+		}
+	}()
 
 	// Create Arrow record
-	record := array.NewRecord(dm.schema, arrays, int64(len(data)))
+	return array.NewRecord(dm.schema, arrays, int64(len(data))), nil
+}
 
-	// Check memory usage
-	if err := dm.checkMemoryUsage(record); err != nil {
-		record.Release()
-		return err
-	}
+// appendRecord stores a record, taking a reference to it
+func (dm *ParquetManager) appendRecord(record arrow.Record) {
+	record.Retain()
 
-	// Store the record
 	dm.mu.Lock()
+	defer dm.mu.Unlock()
 	dm.recordBatches = append(dm.recordBatches, record)
-	dm.stats.RowsWritten += int64(len(data))
+	dm.stats.RowsWritten += record.NumRows()
 	dm.stats.MemoryUsage = dm.calculateMemoryUsage()
-	dm.mu.Unlock()
+}
 
-	// Update stats
-	duration := time.Since(startTime).Nanoseconds()
-	dm.stats.WriteDuration = duration
+// records returns the stored records, each retained for the caller to release
+func (dm *ParquetManager) records() []arrow.Record {
+	dm.mu.RLock()
+	defer dm.mu.RUnlock()
 
-	return nil
+	records := make([]arrow.Record, len(dm.recordBatches))
+	for i, record := range dm.recordBatches {
+		record.Retain()
+		records[i] = record
+	}
+	return records
+}
+
+// batchSizes returns the estimated memory size of each stored record, oldest first
+func (dm *ParquetManager) batchSizes() []int64 {
+	dm.mu.RLock()
+	defer dm.mu.RUnlock()
+
+	sizes := make([]int64, len(dm.recordBatches))
+	for i, record := range dm.recordBatches {
+		sizes[i] = dm.estimateRecordSize(record)
+	}
+	return sizes
+}
+
+// oldest returns the n oldest stored records, each retained for the caller to release
+func (dm *ParquetManager) oldest(n int) []arrow.Record {
+	dm.mu.RLock()
+	defer dm.mu.RUnlock()
+
+	n = min(n, len(dm.recordBatches))
+	records := make([]arrow.Record, n)
+	for i, record := range dm.recordBatches[:n] {
+		record.Retain()
+		records[i] = record
+	}
+	return records
+}
+
+// dropOldest releases the n oldest stored records, such as those moved to another tier
+func (dm *ParquetManager) dropOldest(n int) {
+	dm.mu.Lock()
+	defer dm.mu.Unlock()
+
+	n = min(n, len(dm.recordBatches))
+	for _, record := range dm.recordBatches[:n] {
+		dm.stats.RowsWritten -= record.NumRows()
+		record.Release()
+	}
+	dm.recordBatches = append([]arrow.Record(nil), dm.recordBatches[n:]...)
+	dm.stats.MemoryUsage = dm.calculateMemoryUsage()
 }
 
 // GetData retrieves all stored data as interface slices
@@ -314,6 +386,11 @@ func (dm *ParquetManager) appendValueToBuilder(builder array.Builder, value inte
 			return errors.New(ErrTypeMismatch, "expected string", nil).AddContext("actual_type", fmt.Sprintf("%T", value))
 		}
 
+	case *arrow.ListType, *arrow.MapType, *arrow.StructType:
+		if err := parquet.AppendNestedValue(builder, value); err != nil {
+			return errors.New(ErrTypeMismatch, "expected "+dataType.String(), err).AddContext("actual_type", fmt.Sprintf("%T", value))
+		}
+
 	default:
 		return errors.New(ErrUnsupportedDataType, "unsupported data type", nil).AddContext("data_type", fmt.Sprintf("%T", dataType))
 	}
@@ -367,6 +444,8 @@ func (dm *ParquetManager) extractValue(col arrow.Array, rowIdx int, dataType arr
 		return col.(*array.Float64).Value(rowIdx), nil
 	case *arrow.StringType:
 		return col.(*array.String).Value(rowIdx), nil
+	case *arrow.ListType, *arrow.MapType, *arrow.StructType:
+		return parquet.ArrayValue(col, rowIdx), nil
 	default:
 		return nil, errors.New(ErrUnsupportedDataType, "unsupported data type", nil).AddContext("data_type", fmt.Sprintf("%T", dt))
 	}
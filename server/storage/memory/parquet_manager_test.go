@@ -179,6 +179,38 @@ func TestParquetManager_GetData(t *testing.T) {
 	}
 }
 
+func TestParquetManager_NestedData(t *testing.T) {
+	schema := arrow.NewSchema([]arrow.Field{
+		{Name: "id", Type: arrow.PrimitiveTypes.Int64, Nullable: false},
+		{Name: "scores", Type: arrow.ListOf(arrow.PrimitiveTypes.Int32), Nullable: true},
+		{Name: "attrs", Type: arrow.MapOf(arrow.BinaryTypes.String, arrow.BinaryTypes.String), Nullable: true},
+		{Name: "owner", Type: arrow.StructOf(
+			arrow.Field{Name: "name", Type: arrow.BinaryTypes.String, Nullable: true},
+			arrow.Field{Name: "tags", Type: arrow.ListOf(arrow.BinaryTypes.String), Nullable: true},
+		), Nullable: true},
+	}, nil)
+
+	pm := NewParquetManager(schema, nil)
+	defer pm.Close()
+
+	// Rows reach memory tables through JSON, so numbers arrive as float64
+	err := pm.StoreData([][]interface{}{
+		{int64(1), []interface{}{float64(1), nil}, map[string]interface{}{"k": "v"}, map[string]interface{}{"name": "a", "tags": []interface{}{"x"}}},
+		{int64(2), []interface{}{}, nil, map[string]interface{}{"name": nil}},
+	})
+	require.NoError(t, err)
+
+	retrievedData, err := pm.GetData()
+	require.NoError(t, err)
+	assert.Equal(t, [][]interface{}{
+		{int64(1), []interface{}{int32(1), nil}, map[string]interface{}{"k": "v"}, map[string]interface{}{"name": "a", "tags": []interface{}{"x"}}},
+		{int64(2), []interface{}{}, nil, map[string]interface{}{"name": nil, "tags": nil}},
+	}, retrievedData)
+
+	err = pm.StoreData([][]interface{}{{int64(3), []interface{}{"x"}, nil, nil}})
+	require.Error(t, err)
+}
+
 func TestParquetManager_GetDataBatch(t *testing.T) {
 	schema := arrow.NewSchema([]arrow.Field{
 		{Name: "id", Type: arrow.PrimitiveTypes.Int64, Nullable: false},
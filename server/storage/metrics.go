@@ -0,0 +1,30 @@
+package storage
+
+import "github.com/gear6io/ranger/server/metrics"
+
+// catalogStats is implemented by catalogs that count their operations, such as the JSON catalog
+type catalogStats interface {
+	GetMetrics() map[string]int64
+}
+
+// registerMetrics exposes the operation counts of the catalog, when it keeps them
+func (s *Storage) registerMetrics() {
+	stats, ok := s.catalog.(catalogStats)
+	if !ok {
+		return
+	}
+	counter := func(name, help, key string) metrics.Stat[map[string]int64] {
+		return metrics.Counter(name, help, func(stats map[string]int64) float64 { return float64(stats[key]) })
+	}
+	metrics.Register(metrics.NewStatsCollector("catalog", stats.GetMetrics,
+		counter("tables_created_total", "Tables created in the catalog.", "tables_created"),
+		counter("tables_dropped_total", "Tables dropped from the catalog.", "tables_dropped"),
+		counter("views_created_total", "Views created in the catalog.", "views_created"),
+		counter("views_dropped_total", "Views dropped from the catalog.", "views_dropped"),
+		counter("namespaces_created_total", "Namespaces created in the catalog.", "namespaces_created"),
+		counter("namespaces_dropped_total", "Namespaces dropped from the catalog.", "namespaces_dropped"),
+		counter("errors_total", "Failed catalog operations.", "operation_errors"),
+		counter("cache_hits_total", "Catalog cache hits.", "cache_hits"),
+		counter("cache_misses_total", "Catalog cache misses.", "cache_misses"),
+	))
+}
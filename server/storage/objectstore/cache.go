@@ -0,0 +1,111 @@
+package objectstore
+
+import (
+	"container/list"
+	"sync"
+)
+
+// Defaults of the page cache
+const (
+	DefaultCacheSize = 16 << 20 // 16 MiB
+	CachePageSize    = 64 << 10 // 64 KiB
+)
+
+// PageCache is a least recently used cache of fixed-size pages of objects, shared by the
+// readers of a store. Pages are keyed by the ETag of their object as well as its key, so a
+// replaced object never serves stale pages.
+type PageCache struct {
+	capacity int64
+	mu       sync.Mutex
+	pages    map[pageKey]*list.Element
+	order    *list.List // Front is the most recently used page
+
+	stats CacheStats
+}
+
+// CacheStats reports the use of a page cache
+type CacheStats struct {
+	Size      int64 // Bytes cached
+	Hits      int64
+	Misses    int64
+	Evictions int64
+}
+
+// pageKey identifies a page of an object version
+type pageKey struct {
+	key   string
+	etag  string
+	index int64
+}
+
+// cachedPage is an entry of the page cache
+type cachedPage struct {
+	key  pageKey
+	data []byte
+}
+
+// NewPageCache creates a cache holding up to capacity bytes of pages; it returns nil, a
+// valid cache that caches nothing, when capacity is not positive
+func NewPageCache(capacity int64) *PageCache {
+	if capacity <= 0 {
+		return nil
+	}
+	return &PageCache{
+		capacity: capacity,
+		pages:    make(map[pageKey]*list.Element),
+		order:    list.New(),
+	}
+}
+
+// get returns a cached page
+func (c *PageCache) get(key pageKey) ([]byte, bool) {
+	if c == nil {
+		return nil, false
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	element, ok := c.pages[key]
+	if !ok {
+		c.stats.Misses++
+		return nil, false
+	}
+	c.stats.Hits++
+	c.order.MoveToFront(element)
+	return element.Value.(*cachedPage).data, true
+}
+
+// put caches a page, evicting the least recently used pages beyond the capacity
+func (c *PageCache) put(key pageKey, data []byte) {
+	if c == nil || int64(len(data)) > c.capacity {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if element, ok := c.pages[key]; ok {
+		c.order.MoveToFront(element)
+		return
+	}
+	c.pages[key] = c.order.PushFront(&cachedPage{key: key, data: data})
+	c.stats.Size += int64(len(data))
+
+	for c.stats.Size > c.capacity {
+		oldest := c.order.Back()
+		page := oldest.Value.(*cachedPage)
+		c.order.Remove(oldest)
+		delete(c.pages, page.key)
+		c.stats.Size -= int64(len(page.data))
+		c.stats.Evictions++
+	}
+}
+
+// Stats returns the use of the cache so far
+func (c *PageCache) Stats() CacheStats {
+	if c == nil {
+		return CacheStats{}
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.stats
+}
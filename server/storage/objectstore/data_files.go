@@ -0,0 +1,139 @@
+package objectstore
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/apache/arrow-go/v18/arrow"
+	"github.com/gear6io/ranger/pkg/errors"
+	"github.com/gear6io/ranger/server/storage/filesystem"
+	parquet "github.com/gear6io/ranger/server/storage/parquet"
+	"github.com/google/uuid"
+)
+
+// Suffixes of the data objects of a table
+const (
+	parquetSuffix = ".parquet"
+	jsonSuffix    = ".json"
+)
+
+// WriteDataFiles writes rows into new Parquet objects of the table with config, the defaults
+// when nil, and returns them with their keys as paths. Existing objects are never modified,
+// and no object is left behind when the write fails.
+func (t *TableStorage) WriteDataFiles(schema *arrow.Schema, config *parquet.ParquetConfig, database, tableName string, data [][]interface{}) ([]*parquet.FileInfo, error) {
+	return t.writeFiles(schema, config, database, tableName, func(manager *filesystem.ParquetManager, config *parquet.ParquetConfig) error {
+		// Each batch becomes a row group of the current object
+		for start := 0; start < len(data); start += config.BatchSize {
+			end := min(start+config.BatchSize, len(data))
+			if err := manager.StoreData(data[start:end]); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// WriteRecordFiles writes records with the table schema into new Parquet objects of the
+// table, with the same guarantees as WriteDataFiles
+func (t *TableStorage) WriteRecordFiles(schema *arrow.Schema, config *parquet.ParquetConfig, database, tableName string, records []arrow.Record) ([]*parquet.FileInfo, error) {
+	return t.writeFiles(schema, config, database, tableName, func(manager *filesystem.ParquetManager, _ *parquet.ParquetConfig) error {
+		for _, record := range records {
+			if err := manager.StoreRecord(record); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// writeFiles runs write against a Parquet manager uploading to the table and returns the
+// objects it wrote, removing them all if any step fails
+func (t *TableStorage) writeFiles(schema *arrow.Schema, config *parquet.ParquetConfig, database, tableName string, write func(*filesystem.ParquetManager, *parquet.ParquetConfig) error) ([]*parquet.FileInfo, error) {
+	if config == nil {
+		config = parquet.DefaultParquetConfig()
+	}
+	target := &objectDataFiles{tables: t, prefix: tableDataPrefix(database, tableName)}
+	manager := filesystem.NewParquetManagerWithTarget(schema, config, target, database, tableName)
+
+	if err := write(manager, config); err != nil {
+		manager.Close()
+		t.RemoveDataFiles(manager.GetWrittenFiles())
+		return nil, err
+	}
+
+	if err := manager.Close(); err != nil {
+		t.RemoveDataFiles(manager.GetWrittenFiles())
+		return nil, err
+	}
+	return manager.GetWrittenFiles(), nil
+}
+
+// RemoveDataFiles deletes data objects, such as those of an insert that could not be registered
+func (t *TableStorage) RemoveDataFiles(files []*parquet.FileInfo) error {
+	ctx := context.Background()
+	for _, f := range files {
+		if err := t.store.Delete(ctx, f.Path); err != nil {
+			return errors.New(ErrDeleteFailed, "failed to remove data object", err).AddContext("key", f.Path)
+		}
+	}
+	return nil
+}
+
+// ReadDataFiles reads the rows of every Parquet object of the table, oldest object first
+func (t *TableStorage) ReadDataFiles(database, tableName string) ([][]interface{}, error) {
+	return t.ReadDataFilesMatching(database, tableName, nil)
+}
+
+// ReadDataFilesMatching reads the rows of the Parquet objects of the table matching probe,
+// oldest object first, or every row when probe is nil. Only the footer and the row groups
+// whose bloom filter or page index do not rule the value out are fetched.
+func (t *TableStorage) ReadDataFilesMatching(database, tableName string, probe *parquet.EqualityProbe) ([][]interface{}, error) {
+	ctx := context.Background()
+	objects, err := t.store.List(ctx, tableDataPrefix(database, tableName)+"/")
+	if err != nil {
+		return nil, err
+	}
+
+	// Timestamped keys list in insertion order
+	var rows [][]interface{}
+	for _, object := range objects {
+		if !strings.HasSuffix(object.Key, parquetSuffix) {
+			continue
+		}
+		reader, err := NewObjectReader(ctx, t.store, object.Key, t.cache)
+		if err != nil {
+			if IsNotFound(err) {
+				continue // Removed since it was listed
+			}
+			return nil, err
+		}
+		objectRows, err := filesystem.ReadParquetRows(reader, object.Key, probe)
+		reader.Close()
+		if err != nil {
+			return nil, err
+		}
+		rows = append(rows, objectRows...)
+	}
+	return rows, nil
+}
+
+// objectDataFiles creates the data objects of a table for a Parquet manager
+type objectDataFiles struct {
+	tables *TableStorage
+	prefix string
+	stamp  string
+}
+
+// CreateDataFile starts the upload of a data object of the table; it appears once closed
+func (o *objectDataFiles) CreateDataFile(sequence int) (string, filesystem.DataFileWriter, error) {
+	// The objects of a manager share a timestamp and a random suffix, which keep them in
+	// insertion order and apart from those of concurrent managers
+	if o.stamp == "" {
+		o.stamp = time.Now().UTC().Format("20060102T150405.000000000") + "_" + uuid.New().String()[:8]
+	}
+	key := path.Join(o.prefix, fmt.Sprintf("data_%s_%04d%s", o.stamp, sequence, parquetSuffix))
+	return key, NewUploadWriter(context.Background(), o.tables.store, key, o.tables.uploads), nil
+}
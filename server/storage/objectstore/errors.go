@@ -0,0 +1,22 @@
+package objectstore
+
+import "github.com/gear6io/ranger/pkg/errors"
+
+// Error codes for object store package
+var (
+	// Object errors
+	ErrObjectNotFound = errors.MustNewCode("objectstore.object_not_found")
+	ErrInvalidKey     = errors.MustNewCode("objectstore.invalid_key")
+	ErrInvalidRange   = errors.MustNewCode("objectstore.invalid_range")
+
+	// Operation errors
+	ErrReadFailed   = errors.MustNewCode("objectstore.read_failed")
+	ErrWriteFailed  = errors.MustNewCode("objectstore.write_failed")
+	ErrListFailed   = errors.MustNewCode("objectstore.list_failed")
+	ErrDeleteFailed = errors.MustNewCode("objectstore.delete_failed")
+	ErrUploadFailed = errors.MustNewCode("objectstore.upload_failed")
+
+	// Configuration errors
+	ErrInvalidConfig = errors.MustNewCode("objectstore.invalid_config")
+	ErrBucketMissing = errors.MustNewCode("objectstore.bucket_missing")
+)
@@ -0,0 +1,300 @@
+package objectstore
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/gear6io/ranger/pkg/errors"
+	"github.com/google/uuid"
+)
+
+// uploadsDir holds the parts of incomplete multipart uploads under the root of a LocalStore
+const uploadsDir = ".uploads"
+
+// tempPrefix starts the names of files being written, which List skips
+const tempPrefix = ".tmp-"
+
+// LocalStore stores objects as files under a root directory. Objects are written to a
+// temporary file and renamed into place, so readers never see a partial object.
+type LocalStore struct {
+	root string
+}
+
+// NewLocalStore creates a store rooted at the given directory, creating it if needed
+func NewLocalStore(root string) (*LocalStore, error) {
+	if root == "" {
+		return nil, errors.New(ErrInvalidConfig, "local store root is required", nil)
+	}
+	if err := os.MkdirAll(root, 0755); err != nil {
+		return nil, errors.New(ErrWriteFailed, "failed to create local store root", err).AddContext("root", root)
+	}
+	return &LocalStore{root: root}, nil
+}
+
+// path returns the file path of a key
+func (s *LocalStore) path(key string) (string, error) {
+	cleaned, err := cleanKey(key)
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(s.root, filepath.FromSlash(cleaned)), nil
+}
+
+// Get opens an object for reading
+func (s *LocalStore) Get(ctx context.Context, key string) (io.ReadCloser, error) {
+	return s.GetRange(ctx, key, 0, -1)
+}
+
+// GetRange opens length bytes of an object starting at offset
+func (s *LocalStore) GetRange(ctx context.Context, key string, offset, length int64) (io.ReadCloser, error) {
+	if offset < 0 {
+		return nil, errors.New(ErrInvalidRange, "offset cannot be negative", nil).AddContext("offset", offset)
+	}
+	filePath, err := s.path(key)
+	if err != nil {
+		return nil, err
+	}
+
+	file, err := os.Open(filePath)
+	if err != nil {
+		return nil, s.openError(key, err)
+	}
+	if offset > 0 {
+		if _, err := file.Seek(offset, io.SeekStart); err != nil {
+			file.Close()
+			return nil, errors.New(ErrReadFailed, "failed to seek object", err).AddContext("key", key)
+		}
+	}
+	if length < 0 {
+		return file, nil
+	}
+	return &limitedReadCloser{Reader: io.LimitReader(file, length), Closer: file}, nil
+}
+
+// Put writes an object through a temporary file renamed into place
+func (s *LocalStore) Put(ctx context.Context, key string, r io.Reader, size int64) error {
+	filePath, err := s.path(key)
+	if err != nil {
+		return err
+	}
+	return writeAtomic(filePath, func(w io.Writer) error {
+		_, err := io.Copy(w, r)
+		return err
+	})
+}
+
+// Stat returns the metadata of an object
+func (s *LocalStore) Stat(ctx context.Context, key string) (ObjectInfo, error) {
+	filePath, err := s.path(key)
+	if err != nil {
+		return ObjectInfo{}, err
+	}
+	info, err := os.Stat(filePath)
+	if err != nil || info.IsDir() {
+		if err == nil {
+			err = fs.ErrNotExist
+		}
+		return ObjectInfo{}, s.openError(key, err)
+	}
+	cleaned, _ := cleanKey(key)
+	return localObjectInfo(cleaned, info), nil
+}
+
+// List returns the objects whose keys start with prefix, sorted by key
+func (s *LocalStore) List(ctx context.Context, prefix string) ([]ObjectInfo, error) {
+	// Walk only the directory the prefix points into
+	dir := s.root
+	if i := strings.LastIndex(prefix, "/"); i > 0 {
+		dir = filepath.Join(s.root, filepath.FromSlash(path.Clean(prefix[:i])))
+	}
+
+	var objects []ObjectInfo
+	err := filepath.WalkDir(dir, func(filePath string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) {
+				return nil
+			}
+			return err
+		}
+		if entry.IsDir() {
+			if entry.Name() == uploadsDir && filepath.Dir(filePath) == filepath.Clean(s.root) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if strings.HasPrefix(entry.Name(), tempPrefix) {
+			return nil
+		}
+
+		rel, err := filepath.Rel(s.root, filePath)
+		if err != nil {
+			return err
+		}
+		key := filepath.ToSlash(rel)
+		if !strings.HasPrefix(key, prefix) {
+			return nil
+		}
+		info, err := entry.Info()
+		if err != nil {
+			if os.IsNotExist(err) {
+				return nil
+			}
+			return err
+		}
+		objects = append(objects, localObjectInfo(key, info))
+		return nil
+	})
+	if err != nil {
+		return nil, errors.New(ErrListFailed, "failed to list objects", err).AddContext("prefix", prefix)
+	}
+
+	sort.Slice(objects, func(i, j int) bool { return objects[i].Key < objects[j].Key })
+	return objects, nil
+}
+
+// Delete removes an object
+func (s *LocalStore) Delete(ctx context.Context, key string) error {
+	filePath, err := s.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(filePath); err != nil && !os.IsNotExist(err) {
+		return errors.New(ErrDeleteFailed, "failed to delete object", err).AddContext("key", key)
+	}
+	return nil
+}
+
+// CreateMultipartUpload starts an upload whose parts are kept as files until completed
+func (s *LocalStore) CreateMultipartUpload(ctx context.Context, key string) (MultipartUpload, error) {
+	filePath, err := s.path(key)
+	if err != nil {
+		return nil, err
+	}
+	dir := filepath.Join(s.root, uploadsDir, uuid.New().String())
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, errors.New(ErrUploadFailed, "failed to create upload directory", err).AddContext("key", key)
+	}
+	return &localUpload{key: key, path: filePath, dir: dir, parts: make(map[int]string)}, nil
+}
+
+// openError converts a failure to open an object into a store error
+func (s *LocalStore) openError(key string, err error) error {
+	if os.IsNotExist(err) {
+		return errors.New(ErrObjectNotFound, "object not found", err).AddContext("key", key)
+	}
+	return errors.New(ErrReadFailed, "failed to open object", err).AddContext("key", key)
+}
+
+// localUpload is a multipart upload of a LocalStore
+type localUpload struct {
+	key   string
+	path  string
+	dir   string
+	mu    sync.Mutex
+	parts map[int]string
+}
+
+// UploadPart writes a part to its own file
+func (u *localUpload) UploadPart(ctx context.Context, number int, r io.Reader, size int64) error {
+	if number < 1 {
+		return errors.New(ErrUploadFailed, "part numbers start at 1", nil).AddContext("part", number)
+	}
+	partPath := filepath.Join(u.dir, fmt.Sprintf("part-%05d", number))
+	if err := writeAtomic(partPath, func(w io.Writer) error {
+		_, err := io.Copy(w, r)
+		return err
+	}); err != nil {
+		return errors.New(ErrUploadFailed, "failed to upload part", err).AddContext("key", u.key).AddContext("part", number)
+	}
+
+	u.mu.Lock()
+	u.parts[number] = partPath
+	u.mu.Unlock()
+	return nil
+}
+
+// Complete concatenates the parts into the object and removes them
+func (u *localUpload) Complete(ctx context.Context) error {
+	u.mu.Lock()
+	numbers := make([]int, 0, len(u.parts))
+	for number := range u.parts {
+		numbers = append(numbers, number)
+	}
+	u.mu.Unlock()
+	sort.Ints(numbers)
+
+	err := writeAtomic(u.path, func(w io.Writer) error {
+		for _, number := range numbers {
+			part, err := os.Open(u.parts[number])
+			if err != nil {
+				return err
+			}
+			_, err = io.Copy(w, part)
+			part.Close()
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return errors.New(ErrUploadFailed, "failed to complete upload", err).AddContext("key", u.key)
+	}
+	return u.Abort(ctx)
+}
+
+// Abort removes the uploaded parts
+func (u *localUpload) Abort(ctx context.Context) error {
+	if err := os.RemoveAll(u.dir); err != nil {
+		return errors.New(ErrUploadFailed, "failed to remove upload parts", err).AddContext("key", u.key)
+	}
+	return nil
+}
+
+// writeAtomic writes a file through a temporary file in the same directory renamed into place
+func writeAtomic(filePath string, write func(w io.Writer) error) error {
+	dir := filepath.Dir(filePath)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return errors.New(ErrWriteFailed, "failed to create object directory", err).AddContext("path", filePath)
+	}
+
+	tmp, err := os.CreateTemp(dir, tempPrefix+"*")
+	if err != nil {
+		return errors.New(ErrWriteFailed, "failed to create temporary file", err).AddContext("path", filePath)
+	}
+	tmpPath := tmp.Name()
+
+	err = write(tmp)
+	if err == nil {
+		err = tmp.Sync()
+	}
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tmpPath, filePath)
+	}
+	if err != nil {
+		os.Remove(tmpPath)
+		return errors.New(ErrWriteFailed, "failed to write object", err).AddContext("path", filePath)
+	}
+	return nil
+}
+
+// localObjectInfo returns the metadata of an object file
+func localObjectInfo(key string, info fs.FileInfo) ObjectInfo {
+	return ObjectInfo{
+		Key:     key,
+		Size:    info.Size(),
+		ModTime: info.ModTime(),
+		ETag:    fmt.Sprintf("%x-%x", info.ModTime().UnixNano(), info.Size()),
+	}
+}
@@ -0,0 +1,202 @@
+package objectstore
+
+import (
+	"bytes"
+	"context"
+	"crypto/md5"
+	"encoding/hex"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gear6io/ranger/pkg/errors"
+)
+
+// MemoryStore keeps objects in memory; it is meant for tests
+type MemoryStore struct {
+	mu      sync.RWMutex
+	objects map[string]memoryObject
+}
+
+// memoryObject is an object of a MemoryStore
+type memoryObject struct {
+	data    []byte
+	modTime time.Time
+	etag    string
+}
+
+// NewMemoryStore creates an empty in-memory store
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{objects: make(map[string]memoryObject)}
+}
+
+// Get opens an object for reading
+func (s *MemoryStore) Get(ctx context.Context, key string) (io.ReadCloser, error) {
+	return s.GetRange(ctx, key, 0, -1)
+}
+
+// GetRange opens length bytes of an object starting at offset
+func (s *MemoryStore) GetRange(ctx context.Context, key string, offset, length int64) (io.ReadCloser, error) {
+	if offset < 0 {
+		return nil, errors.New(ErrInvalidRange, "offset cannot be negative", nil).AddContext("offset", offset)
+	}
+	object, err := s.get(key)
+	if err != nil {
+		return nil, err
+	}
+
+	// Objects are never modified in place, so the slice can be shared
+	data := object.data
+	if offset > int64(len(data)) {
+		offset = int64(len(data))
+	}
+	data = data[offset:]
+	if length >= 0 && length < int64(len(data)) {
+		data = data[:length]
+	}
+	return io.NopCloser(bytes.NewReader(data)), nil
+}
+
+// Put stores a copy of the object
+func (s *MemoryStore) Put(ctx context.Context, key string, r io.Reader, size int64) error {
+	cleaned, err := cleanKey(key)
+	if err != nil {
+		return err
+	}
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return errors.New(ErrWriteFailed, "failed to read object data", err).AddContext("key", key)
+	}
+	s.put(cleaned, data)
+	return nil
+}
+
+// Stat returns the metadata of an object
+func (s *MemoryStore) Stat(ctx context.Context, key string) (ObjectInfo, error) {
+	cleaned, err := cleanKey(key)
+	if err != nil {
+		return ObjectInfo{}, err
+	}
+	object, err := s.get(key)
+	if err != nil {
+		return ObjectInfo{}, err
+	}
+	return object.info(cleaned), nil
+}
+
+// List returns the objects whose keys start with prefix, sorted by key
+func (s *MemoryStore) List(ctx context.Context, prefix string) ([]ObjectInfo, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var objects []ObjectInfo
+	for key, object := range s.objects {
+		if strings.HasPrefix(key, prefix) {
+			objects = append(objects, object.info(key))
+		}
+	}
+	sort.Slice(objects, func(i, j int) bool { return objects[i].Key < objects[j].Key })
+	return objects, nil
+}
+
+// Delete removes an object
+func (s *MemoryStore) Delete(ctx context.Context, key string) error {
+	cleaned, err := cleanKey(key)
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	delete(s.objects, cleaned)
+	s.mu.Unlock()
+	return nil
+}
+
+// CreateMultipartUpload starts an upload whose parts are kept in memory until completed
+func (s *MemoryStore) CreateMultipartUpload(ctx context.Context, key string) (MultipartUpload, error) {
+	cleaned, err := cleanKey(key)
+	if err != nil {
+		return nil, err
+	}
+	return &memoryUpload{store: s, key: cleaned, parts: make(map[int][]byte)}, nil
+}
+
+// get returns an object by key
+func (s *MemoryStore) get(key string) (memoryObject, error) {
+	cleaned, err := cleanKey(key)
+	if err != nil {
+		return memoryObject{}, err
+	}
+	s.mu.RLock()
+	object, ok := s.objects[cleaned]
+	s.mu.RUnlock()
+	if !ok {
+		return memoryObject{}, errors.New(ErrObjectNotFound, "object not found", nil).AddContext("key", key)
+	}
+	return object, nil
+}
+
+// put replaces an object
+func (s *MemoryStore) put(key string, data []byte) {
+	sum := md5.Sum(data)
+	s.mu.Lock()
+	s.objects[key] = memoryObject{data: data, modTime: time.Now(), etag: hex.EncodeToString(sum[:])}
+	s.mu.Unlock()
+}
+
+// info returns the metadata of the object
+func (o memoryObject) info(key string) ObjectInfo {
+	return ObjectInfo{Key: key, Size: int64(len(o.data)), ModTime: o.modTime, ETag: o.etag}
+}
+
+// memoryUpload is a multipart upload of a MemoryStore
+type memoryUpload struct {
+	store *MemoryStore
+	key   string
+	mu    sync.Mutex
+	parts map[int][]byte
+}
+
+// UploadPart keeps a copy of the part
+func (u *memoryUpload) UploadPart(ctx context.Context, number int, r io.Reader, size int64) error {
+	if number < 1 {
+		return errors.New(ErrUploadFailed, "part numbers start at 1", nil).AddContext("part", number)
+	}
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return errors.New(ErrUploadFailed, "failed to read part data", err).AddContext("key", u.key).AddContext("part", number)
+	}
+	u.mu.Lock()
+	u.parts[number] = data
+	u.mu.Unlock()
+	return nil
+}
+
+// Complete stores the parts in number order as the object
+func (u *memoryUpload) Complete(ctx context.Context) error {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	numbers := make([]int, 0, len(u.parts))
+	for number := range u.parts {
+		numbers = append(numbers, number)
+	}
+	sort.Ints(numbers)
+
+	var data []byte
+	for _, number := range numbers {
+		data = append(data, u.parts[number]...)
+	}
+	u.store.put(u.key, data)
+	u.parts = make(map[int][]byte)
+	return nil
+}
+
+// Abort discards the parts
+func (u *memoryUpload) Abort(ctx context.Context) error {
+	u.mu.Lock()
+	u.parts = make(map[int][]byte)
+	u.mu.Unlock()
+	return nil
+}
@@ -0,0 +1,196 @@
+package objectstore
+
+import (
+	"context"
+	"io"
+	"sync"
+
+	"github.com/gear6io/ranger/pkg/errors"
+)
+
+// maxCachedRead is the largest ReadAt served through the page cache. Footers, page indexes and
+// other metadata are read in small pieces and cached; larger reads such as column chunks go
+// straight to the store.
+const maxCachedRead = 4 * CachePageSize
+
+// ObjectReader reads an object with ranged requests. ReadAt fetches only the requested bytes,
+// and Read streams from the current position, so Seek costs nothing until the next Read.
+// Small reads are served from the page cache, which keeps the footers of Parquet files that
+// readers fetch again and again.
+type ObjectReader struct {
+	ctx   context.Context
+	store Store
+	info  ObjectInfo
+	cache *PageCache
+
+	mu   sync.Mutex
+	pos  int64
+	body io.ReadCloser
+}
+
+// NewObjectReader opens an object for ranged reads; cache may be nil
+func NewObjectReader(ctx context.Context, store Store, key string, cache *PageCache) (*ObjectReader, error) {
+	info, err := store.Stat(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	info.Key = key
+	return &ObjectReader{ctx: ctx, store: store, info: info, cache: cache}, nil
+}
+
+// Info returns the metadata of the object
+func (r *ObjectReader) Info() ObjectInfo {
+	return r.info
+}
+
+// Size returns the size of the object
+func (r *ObjectReader) Size() int64 {
+	return r.info.Size
+}
+
+// ReadAt reads len(p) bytes at offset off; it may be called concurrently
+func (r *ObjectReader) ReadAt(p []byte, off int64) (int, error) {
+	if off < 0 {
+		return 0, errors.New(ErrInvalidRange, "offset cannot be negative", nil).AddContext("offset", off)
+	}
+	if off >= r.info.Size {
+		return 0, io.EOF
+	}
+	n := int64(len(p))
+	if remaining := r.info.Size - off; n > remaining {
+		n = remaining
+	}
+
+	var err error
+	if r.cache != nil && n <= maxCachedRead {
+		err = r.readPages(p[:n], off)
+	} else {
+		err = r.readRange(p[:n], off)
+	}
+	if err != nil {
+		return 0, err
+	}
+	if n < int64(len(p)) {
+		return int(n), io.EOF
+	}
+	return int(n), nil
+}
+
+// readRange fills p from offset off with one ranged request
+func (r *ObjectReader) readRange(p []byte, off int64) error {
+	body, err := r.store.GetRange(r.ctx, r.info.Key, off, int64(len(p)))
+	if err != nil {
+		return err
+	}
+	defer body.Close()
+	if _, err := io.ReadFull(body, p); err != nil {
+		return errors.New(ErrReadFailed, "failed to read object range", err).
+			AddContext("key", r.info.Key).
+			AddContext("offset", off).
+			AddContext("length", len(p))
+	}
+	return nil
+}
+
+// readPages fills p from offset off through the page cache
+func (r *ObjectReader) readPages(p []byte, off int64) error {
+	for len(p) > 0 {
+		index := off / CachePageSize
+		page, err := r.page(index)
+		if err != nil {
+			return err
+		}
+		n := copy(p, page[off-index*CachePageSize:])
+		p = p[n:]
+		off += int64(n)
+	}
+	return nil
+}
+
+// page returns a page of the object, fetching it on a cache miss
+func (r *ObjectReader) page(index int64) ([]byte, error) {
+	key := pageKey{key: r.info.Key, etag: r.info.ETag, index: index}
+	if data, ok := r.cache.get(key); ok {
+		return data, nil
+	}
+
+	start := index * CachePageSize
+	length := int64(CachePageSize)
+	if remaining := r.info.Size - start; length > remaining {
+		length = remaining
+	}
+	data := make([]byte, length)
+	if err := r.readRange(data, start); err != nil {
+		return nil, err
+	}
+	r.cache.put(key, data)
+	return data, nil
+}
+
+// Read reads from the current position, opening a ranged stream on the first read after a seek
+func (r *ObjectReader) Read(p []byte) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.pos >= r.info.Size {
+		return 0, io.EOF
+	}
+	if r.body == nil {
+		body, err := r.store.GetRange(r.ctx, r.info.Key, r.pos, -1)
+		if err != nil {
+			return 0, err
+		}
+		r.body = body
+	}
+
+	n, err := r.body.Read(p)
+	r.pos += int64(n)
+	if err != nil && err != io.EOF {
+		return n, errors.New(ErrReadFailed, "failed to read object", err).AddContext("key", r.info.Key)
+	}
+	return n, err
+}
+
+// Seek sets the position of the next Read
+func (r *ObjectReader) Seek(offset int64, whence int) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var pos int64
+	switch whence {
+	case io.SeekStart:
+		pos = offset
+	case io.SeekCurrent:
+		pos = r.pos + offset
+	case io.SeekEnd:
+		pos = r.info.Size + offset
+	default:
+		return 0, errors.New(ErrInvalidRange, "invalid whence", nil).AddContext("whence", whence)
+	}
+	if pos < 0 {
+		return 0, errors.New(ErrInvalidRange, "negative position", nil).AddContext("position", pos)
+	}
+
+	if pos != r.pos {
+		r.closeBody()
+		r.pos = pos
+	}
+	return pos, nil
+}
+
+// Close releases the open stream, if any
+func (r *ObjectReader) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.closeBody()
+}
+
+// closeBody closes the stream of sequential reads
+func (r *ObjectReader) closeBody() error {
+	if r.body == nil {
+		return nil
+	}
+	err := r.body.Close()
+	r.body = nil
+	return err
+}
@@ -0,0 +1,130 @@
+package objectstore
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestObjectReader tests ranged reads, seeks and the page cache
+func TestObjectReader(t *testing.T) {
+	ctx := context.Background()
+	data := testData(5*CachePageSize + 100)
+
+	for name, store := range testStores(t) {
+		t.Run(name, func(t *testing.T) {
+			require.NoError(t, store.Put(ctx, "reader/object", bytes.NewReader(data), int64(len(data))))
+			recorder := &recordingStore{Store: store}
+			reader, err := NewObjectReader(ctx, recorder, "reader/object", NewPageCache(DefaultCacheSize))
+			require.NoError(t, err)
+			defer reader.Close()
+			assert.Equal(t, int64(len(data)), reader.Size())
+
+			t.Run("FooterIsCached", func(t *testing.T) {
+				// Parquet readers fetch the footer length, then the footer
+				tail := make([]byte, 8)
+				n, err := reader.ReadAt(tail, reader.Size()-8)
+				require.NoError(t, err)
+				assert.Equal(t, 8, n)
+				assert.Equal(t, data[len(data)-8:], tail)
+
+				footer := make([]byte, 1000)
+				_, err = reader.ReadAt(footer, reader.Size()-1008)
+				require.NoError(t, err)
+				assert.Equal(t, data[len(data)-1008:len(data)-8], footer)
+
+				requests := recorder.ranges
+				_, err = reader.ReadAt(tail, reader.Size()-8)
+				require.NoError(t, err)
+				_, err = reader.ReadAt(footer, reader.Size()-1008)
+				require.NoError(t, err)
+				assert.Equal(t, requests, recorder.ranges, "footer reads should hit the cache")
+			})
+
+			t.Run("ReadsSpanningPages", func(t *testing.T) {
+				buf := make([]byte, CachePageSize+10)
+				_, err := reader.ReadAt(buf, CachePageSize-5)
+				require.NoError(t, err)
+				assert.Equal(t, data[CachePageSize-5:2*CachePageSize+5], buf)
+			})
+
+			t.Run("LargeReadsBypassCache", func(t *testing.T) {
+				requests := recorder.ranges
+				buf := make([]byte, maxCachedRead+1)
+				_, err := reader.ReadAt(buf, 7)
+				require.NoError(t, err)
+				assert.Equal(t, data[7:7+len(buf)], buf)
+				assert.Equal(t, requests+1, recorder.ranges)
+			})
+
+			t.Run("ReadAtPastEnd", func(t *testing.T) {
+				buf := make([]byte, 50)
+				n, err := reader.ReadAt(buf, reader.Size()-20)
+				assert.Equal(t, io.EOF, err)
+				assert.Equal(t, 20, n)
+				assert.Equal(t, data[len(data)-20:], buf[:20])
+
+				_, err = reader.ReadAt(buf, reader.Size())
+				assert.Equal(t, io.EOF, err)
+			})
+
+			t.Run("SeekAndRead", func(t *testing.T) {
+				pos, err := reader.Seek(-10, io.SeekEnd)
+				require.NoError(t, err)
+				assert.Equal(t, reader.Size()-10, pos)
+				rest, err := io.ReadAll(reader)
+				require.NoError(t, err)
+				assert.Equal(t, data[len(data)-10:], rest)
+
+				_, err = reader.Seek(100, io.SeekStart)
+				require.NoError(t, err)
+				buf := make([]byte, 10)
+				_, err = io.ReadFull(reader, buf)
+				require.NoError(t, err)
+				assert.Equal(t, data[100:110], buf)
+
+				_, err = reader.Seek(-1, io.SeekStart)
+				assert.Error(t, err)
+			})
+		})
+	}
+
+	t.Run("NotFound", func(t *testing.T) {
+		_, err := NewObjectReader(ctx, NewMemoryStore(), "missing", nil)
+		assert.True(t, IsNotFound(err), "got %v", err)
+	})
+}
+
+// TestPageCacheEviction tests that the cache stays within its capacity
+func TestPageCacheEviction(t *testing.T) {
+	cache := NewPageCache(2 * CachePageSize)
+	page := make([]byte, CachePageSize)
+
+	cache.put(pageKey{key: "a", index: 0}, page)
+	cache.put(pageKey{key: "a", index: 1}, page)
+	_, ok := cache.get(pageKey{key: "a", index: 0})
+	assert.True(t, ok)
+
+	// The least recently used page is evicted
+	cache.put(pageKey{key: "a", index: 2}, page)
+	_, ok = cache.get(pageKey{key: "a", index: 1})
+	assert.False(t, ok)
+	_, ok = cache.get(pageKey{key: "a", index: 0})
+	assert.True(t, ok)
+
+	// Pages of another version of the object do not match
+	_, ok = cache.get(pageKey{key: "a", etag: "v2", index: 0})
+	assert.False(t, ok)
+
+	assert.Equal(t, CacheStats{Size: 2 * CachePageSize, Hits: 2, Misses: 2, Evictions: 1}, cache.Stats())
+
+	// A disabled cache caches nothing
+	var disabled *PageCache = NewPageCache(0)
+	disabled.put(pageKey{key: "a"}, page)
+	_, ok = disabled.get(pageKey{key: "a"})
+	assert.False(t, ok)
+}
@@ -0,0 +1,310 @@
+package objectstore
+
+import (
+	"context"
+	"io"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/gear6io/ranger/pkg/errors"
+	"github.com/minio/minio-go/v7"
+	"github.com/minio/minio-go/v7/pkg/credentials"
+)
+
+// streamPartSize is the part size of uploads of unknown size; the S3 client buffers one part
+// at a time, and the default would be sized for the 5 TiB object limit
+const streamPartSize = 16 << 20
+
+// S3Config configures a bucket on an S3-compatible endpoint such as AWS S3, MinIO, Ceph or
+// the S3 interoperability API of Google Cloud Storage
+type S3Config struct {
+	Endpoint        string // host[:port] of the endpoint, e.g. s3.us-east-1.amazonaws.com
+	Region          string // Region of the bucket; detected by the endpoint when empty
+	Bucket          string // Existing bucket that holds the objects
+	Prefix          string // Key prefix under which the store keeps its objects
+	AccessKeyID     string // Static credentials; when empty, credentials come from the environment
+	SecretAccessKey string
+	SessionToken    string
+	UsePathStyle    bool // Address the bucket as endpoint/bucket instead of bucket.endpoint
+	Insecure        bool // Use plain HTTP instead of HTTPS
+}
+
+// S3Store stores objects in a bucket on an S3-compatible endpoint
+type S3Store struct {
+	core   *minio.Core
+	bucket string
+	prefix string
+}
+
+// NewS3Store creates a store for an existing bucket; it does not contact the endpoint
+func NewS3Store(cfg S3Config) (*S3Store, error) {
+	if cfg.Endpoint == "" {
+		return nil, errors.New(ErrInvalidConfig, "S3 endpoint is required", nil)
+	}
+	if cfg.Bucket == "" {
+		return nil, errors.New(ErrInvalidConfig, "S3 bucket is required", nil).AddContext("endpoint", cfg.Endpoint)
+	}
+
+	lookup := minio.BucketLookupAuto
+	if cfg.UsePathStyle {
+		lookup = minio.BucketLookupPath
+	}
+
+	core, err := minio.NewCore(cfg.Endpoint, &minio.Options{
+		Creds:        s3Credentials(cfg),
+		Secure:       !cfg.Insecure,
+		Region:       cfg.Region,
+		BucketLookup: lookup,
+	})
+	if err != nil {
+		return nil, errors.New(ErrInvalidConfig, "failed to create S3 client", err).AddContext("endpoint", cfg.Endpoint)
+	}
+
+	return &S3Store{
+		core:   core,
+		bucket: cfg.Bucket,
+		prefix: strings.Trim(cfg.Prefix, "/"),
+	}, nil
+}
+
+// NewS3StoreWithClient creates a store over an existing client, such as the one of the bundled
+// MinIO server
+func NewS3StoreWithClient(client *minio.Client, bucket, prefix string) *S3Store {
+	return &S3Store{
+		core:   &minio.Core{Client: client},
+		bucket: bucket,
+		prefix: strings.Trim(prefix, "/"),
+	}
+}
+
+// s3Credentials returns static credentials when configured, and otherwise looks them up in
+// the AWS and MinIO environment variables, the AWS credentials file and the instance role
+func s3Credentials(cfg S3Config) *credentials.Credentials {
+	if cfg.AccessKeyID != "" {
+		return credentials.NewStaticV4(cfg.AccessKeyID, cfg.SecretAccessKey, cfg.SessionToken)
+	}
+	return credentials.NewChainCredentials([]credentials.Provider{
+		&credentials.EnvAWS{},
+		&credentials.EnvMinio{},
+		&credentials.FileAWSCredentials{},
+		&credentials.IAM{},
+	})
+}
+
+// Ping checks that the bucket exists and the credentials can reach it
+func (s *S3Store) Ping(ctx context.Context) error {
+	exists, err := s.core.BucketExists(ctx, s.bucket)
+	if err != nil {
+		return errors.New(ErrReadFailed, "failed to reach S3 bucket", err).AddContext("bucket", s.bucket)
+	}
+	if !exists {
+		return errors.New(ErrBucketMissing, "S3 bucket does not exist", nil).AddContext("bucket", s.bucket)
+	}
+	return nil
+}
+
+// objectName returns the name of a key in the bucket
+func (s *S3Store) objectName(key string) (string, error) {
+	cleaned, err := cleanKey(key)
+	if err != nil {
+		return "", err
+	}
+	if s.prefix == "" {
+		return cleaned, nil
+	}
+	return path.Join(s.prefix, cleaned), nil
+}
+
+// Get opens an object for reading
+func (s *S3Store) Get(ctx context.Context, key string) (io.ReadCloser, error) {
+	return s.GetRange(ctx, key, 0, -1)
+}
+
+// GetRange opens length bytes of an object starting at offset with a ranged GET
+func (s *S3Store) GetRange(ctx context.Context, key string, offset, length int64) (io.ReadCloser, error) {
+	if offset < 0 {
+		return nil, errors.New(ErrInvalidRange, "offset cannot be negative", nil).AddContext("offset", offset)
+	}
+	name, err := s.objectName(key)
+	if err != nil {
+		return nil, err
+	}
+	if length == 0 {
+		// A zero-length range cannot be expressed in HTTP, so only check the object exists
+		if _, err := s.Stat(ctx, key); err != nil {
+			return nil, err
+		}
+		return io.NopCloser(strings.NewReader("")), nil
+	}
+
+	opts := minio.GetObjectOptions{}
+	switch {
+	case length > 0:
+		err = opts.SetRange(offset, offset+length-1)
+	case offset > 0:
+		err = opts.SetRange(offset, 0)
+	}
+	if err != nil {
+		return nil, errors.New(ErrInvalidRange, "invalid object range", err).AddContext("offset", offset).AddContext("length", length)
+	}
+
+	// GetObject of the core client sends the request right away, so a missing object fails here
+	reader, _, _, err := s.core.GetObject(ctx, s.bucket, name, opts)
+	if err != nil {
+		return nil, s.requestError(ErrReadFailed, "failed to get object", key, err)
+	}
+	return reader, nil
+}
+
+// Put uploads an object; objects of unknown size or larger than one part are uploaded in parts
+func (s *S3Store) Put(ctx context.Context, key string, r io.Reader, size int64) error {
+	name, err := s.objectName(key)
+	if err != nil {
+		return err
+	}
+	// Payloads are sent unsigned rather than with aws-chunked streaming signatures, which
+	// several S3-compatible services, the GCS interoperability API among them, do not accept
+	opts := minio.PutObjectOptions{DisableContentSha256: true}
+	if size < 0 {
+		opts.PartSize = streamPartSize
+	}
+	if _, err := s.core.Client.PutObject(ctx, s.bucket, name, r, size, opts); err != nil {
+		return s.requestError(ErrWriteFailed, "failed to put object", key, err)
+	}
+	return nil
+}
+
+// Stat returns the metadata of an object
+func (s *S3Store) Stat(ctx context.Context, key string) (ObjectInfo, error) {
+	name, err := s.objectName(key)
+	if err != nil {
+		return ObjectInfo{}, err
+	}
+	info, err := s.core.StatObject(ctx, s.bucket, name, minio.StatObjectOptions{})
+	if err != nil {
+		return ObjectInfo{}, s.requestError(ErrReadFailed, "failed to stat object", key, err)
+	}
+	return s.objectInfo(info), nil
+}
+
+// List returns the objects whose keys start with prefix, sorted by key
+func (s *S3Store) List(ctx context.Context, prefix string) ([]ObjectInfo, error) {
+	listPrefix := prefix
+	if s.prefix != "" {
+		listPrefix = s.prefix + "/" + prefix
+	}
+
+	var objects []ObjectInfo
+	for info := range s.core.Client.ListObjects(ctx, s.bucket, minio.ListObjectsOptions{Prefix: listPrefix, Recursive: true}) {
+		if info.Err != nil {
+			return nil, errors.New(ErrListFailed, "failed to list objects", info.Err).AddContext("prefix", prefix)
+		}
+		objects = append(objects, s.objectInfo(info))
+	}
+	sort.Slice(objects, func(i, j int) bool { return objects[i].Key < objects[j].Key })
+	return objects, nil
+}
+
+// Delete removes an object
+func (s *S3Store) Delete(ctx context.Context, key string) error {
+	name, err := s.objectName(key)
+	if err != nil {
+		return err
+	}
+	if err := s.core.RemoveObject(ctx, s.bucket, name, minio.RemoveObjectOptions{}); err != nil {
+		if isS3NotFound(err) {
+			return nil
+		}
+		return s.requestError(ErrDeleteFailed, "failed to delete object", key, err)
+	}
+	return nil
+}
+
+// CreateMultipartUpload starts an S3 multipart upload
+func (s *S3Store) CreateMultipartUpload(ctx context.Context, key string) (MultipartUpload, error) {
+	name, err := s.objectName(key)
+	if err != nil {
+		return nil, err
+	}
+	uploadID, err := s.core.NewMultipartUpload(ctx, s.bucket, name, minio.PutObjectOptions{})
+	if err != nil {
+		return nil, s.requestError(ErrUploadFailed, "failed to start multipart upload", key, err)
+	}
+	return &s3Upload{store: s, key: key, name: name, uploadID: uploadID, parts: make(map[int]minio.CompletePart)}, nil
+}
+
+// objectInfo converts the metadata of a bucket object
+func (s *S3Store) objectInfo(info minio.ObjectInfo) ObjectInfo {
+	key := info.Key
+	if s.prefix != "" {
+		key = strings.TrimPrefix(key, s.prefix+"/")
+	}
+	return ObjectInfo{Key: key, Size: info.Size, ModTime: info.LastModified, ETag: info.ETag}
+}
+
+// requestError converts a failed S3 request into a store error
+func (s *S3Store) requestError(code errors.Code, message, key string, err error) error {
+	if isS3NotFound(err) {
+		code, message = ErrObjectNotFound, "object not found"
+	}
+	return errors.New(code, message, err).AddContext("bucket", s.bucket).AddContext("key", key)
+}
+
+// isS3NotFound reports whether an S3 request failed because the object does not exist
+func isS3NotFound(err error) bool {
+	code := minio.ToErrorResponse(err).Code
+	return code == "NoSuchKey" || code == "NotFound"
+}
+
+// s3Upload is a multipart upload of an S3Store
+type s3Upload struct {
+	store    *S3Store
+	key      string
+	name     string
+	uploadID string
+	mu       sync.Mutex
+	parts    map[int]minio.CompletePart
+}
+
+// UploadPart uploads a part; S3 requires every part but the last to be at least 5 MiB
+func (u *s3Upload) UploadPart(ctx context.Context, number int, r io.Reader, size int64) error {
+	if number < 1 {
+		return errors.New(ErrUploadFailed, "part numbers start at 1", nil).AddContext("part", number)
+	}
+	// Unsigned payload, as in Put
+	part, err := u.store.core.PutObjectPart(ctx, u.store.bucket, u.name, u.uploadID, number, r, size, minio.PutObjectPartOptions{DisableContentSha256: true})
+	if err != nil {
+		return errors.New(ErrUploadFailed, "failed to upload part", err).AddContext("key", u.key).AddContext("part", number)
+	}
+
+	u.mu.Lock()
+	u.parts[number] = minio.CompletePart{PartNumber: number, ETag: part.ETag}
+	u.mu.Unlock()
+	return nil
+}
+
+// Complete assembles the uploaded parts into the object
+func (u *s3Upload) Complete(ctx context.Context) error {
+	u.mu.Lock()
+	parts := make([]minio.CompletePart, 0, len(u.parts))
+	for _, part := range u.parts {
+		parts = append(parts, part)
+	}
+	u.mu.Unlock()
+	sort.Slice(parts, func(i, j int) bool { return parts[i].PartNumber < parts[j].PartNumber })
+
+	if _, err := u.store.core.CompleteMultipartUpload(ctx, u.store.bucket, u.name, u.uploadID, parts, minio.PutObjectOptions{}); err != nil {
+		return errors.New(ErrUploadFailed, "failed to complete multipart upload", err).AddContext("key", u.key)
+	}
+	return nil
+}
+
+// Abort discards the uploaded parts
+func (u *s3Upload) Abort(ctx context.Context) error {
+	if err := u.store.core.AbortMultipartUpload(ctx, u.store.bucket, u.name, u.uploadID); err != nil {
+		return errors.New(ErrUploadFailed, "failed to abort multipart upload", err).AddContext("key", u.key)
+	}
+	return nil
+}
@@ -0,0 +1,81 @@
+// Package objectstore provides a flat object store interface with implementations for local
+// disk, S3-compatible endpoints and memory, and a table storage engine built on top of it.
+package objectstore
+
+import (
+	"context"
+	"io"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/gear6io/ranger/pkg/errors"
+)
+
+// Store is a flat key/value store of immutable objects. Keys are slash-separated paths
+// relative to the root of the store; there are no directories.
+type Store interface {
+	// Get opens an object for reading
+	Get(ctx context.Context, key string) (io.ReadCloser, error)
+
+	// GetRange opens length bytes of an object starting at offset; a negative length reads
+	// to the end of the object
+	GetRange(ctx context.Context, key string, offset, length int64) (io.ReadCloser, error)
+
+	// Put writes an object, replacing any object with the same key; size is -1 when unknown.
+	// Readers see either the old or the new object, never a partial one.
+	Put(ctx context.Context, key string, r io.Reader, size int64) error
+
+	// Stat returns the metadata of an object
+	Stat(ctx context.Context, key string) (ObjectInfo, error)
+
+	// List returns the objects whose keys start with prefix, sorted by key
+	List(ctx context.Context, prefix string) ([]ObjectInfo, error)
+
+	// Delete removes an object; deleting a missing object is not an error
+	Delete(ctx context.Context, key string) error
+
+	// CreateMultipartUpload starts uploading an object in parts
+	CreateMultipartUpload(ctx context.Context, key string) (MultipartUpload, error)
+}
+
+// MultipartUpload is an object uploaded in parts, which becomes visible once completed
+type MultipartUpload interface {
+	// UploadPart uploads the part with the given number, starting at 1; parts may be uploaded
+	// concurrently and in any order, and uploading a number again replaces the part
+	UploadPart(ctx context.Context, number int, r io.Reader, size int64) error
+
+	// Complete assembles the uploaded parts in number order into the object
+	Complete(ctx context.Context) error
+
+	// Abort discards the uploaded parts
+	Abort(ctx context.Context) error
+}
+
+// ObjectInfo describes a stored object
+type ObjectInfo struct {
+	Key     string
+	Size    int64
+	ModTime time.Time
+	ETag    string
+}
+
+// IsNotFound reports whether err reports a missing object
+func IsNotFound(err error) bool {
+	return errors.GetCode(err) == ErrObjectNotFound.String()
+}
+
+// cleanKey validates a key and returns it in canonical form
+func cleanKey(key string) (string, error) {
+	cleaned := path.Clean(strings.TrimPrefix(key, "/"))
+	if key == "" || cleaned == "." || cleaned == ".." || strings.HasPrefix(cleaned, "../") {
+		return "", errors.New(ErrInvalidKey, "invalid object key", nil).AddContext("key", key)
+	}
+	return cleaned, nil
+}
+
+// limitedReadCloser closes the underlying reader of a limited read
+type limitedReadCloser struct {
+	io.Reader
+	io.Closer
+}
@@ -0,0 +1,203 @@
+package objectstore
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/johannesboyne/gofakes3"
+	"github.com/johannesboyne/gofakes3/backend/s3mem"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newFakeS3Store creates an S3 store against an in-process fake S3 server
+func newFakeS3Store(t *testing.T, prefix string) *S3Store {
+	backend := s3mem.New()
+	require.NoError(t, backend.CreateBucket("ranger-test"))
+	server := httptest.NewServer(gofakes3.New(backend).Server())
+	t.Cleanup(server.Close)
+
+	endpoint, err := url.Parse(server.URL)
+	require.NoError(t, err)
+
+	store, err := NewS3Store(S3Config{
+		Endpoint:        endpoint.Host,
+		Region:          "us-east-1",
+		Bucket:          "ranger-test",
+		Prefix:          prefix,
+		AccessKeyID:     "test",
+		SecretAccessKey: "test-secret",
+		UsePathStyle:    true,
+		Insecure:        true,
+	})
+	require.NoError(t, err)
+	require.NoError(t, store.Ping(context.Background()))
+	return store
+}
+
+// testStores returns every store implementation, empty
+func testStores(t *testing.T) map[string]Store {
+	local, err := NewLocalStore(t.TempDir())
+	require.NoError(t, err)
+
+	return map[string]Store{
+		"Local":  local,
+		"Memory": NewMemoryStore(),
+		"S3":     newFakeS3Store(t, "warehouse"),
+	}
+}
+
+// readRange reads length bytes of an object starting at offset
+func readRange(t *testing.T, store Store, key string, offset, length int64) string {
+	reader, err := store.GetRange(context.Background(), key, offset, length)
+	require.NoError(t, err)
+	defer reader.Close()
+	data, err := io.ReadAll(reader)
+	require.NoError(t, err)
+	return string(data)
+}
+
+// TestStores tests that every store implements the same object semantics
+func TestStores(t *testing.T) {
+	ctx := context.Background()
+
+	for name, store := range testStores(t) {
+		t.Run(name, func(t *testing.T) {
+			t.Run("PutGetStat", func(t *testing.T) {
+				require.NoError(t, store.Put(ctx, "a/b/object.txt", strings.NewReader("hello world"), 11))
+
+				assert.Equal(t, "hello world", readRange(t, store, "a/b/object.txt", 0, -1))
+
+				info, err := store.Stat(ctx, "a/b/object.txt")
+				require.NoError(t, err)
+				assert.Equal(t, "a/b/object.txt", info.Key)
+				assert.Equal(t, int64(11), info.Size)
+				assert.NotEmpty(t, info.ETag)
+
+				// Unknown sizes and overwrites
+				require.NoError(t, store.Put(ctx, "a/b/object.txt", strings.NewReader("replaced"), -1))
+				assert.Equal(t, "replaced", readRange(t, store, "a/b/object.txt", 0, -1))
+			})
+
+			t.Run("GetRange", func(t *testing.T) {
+				require.NoError(t, store.Put(ctx, "range.bin", strings.NewReader("0123456789"), 10))
+
+				assert.Equal(t, "234", readRange(t, store, "range.bin", 2, 3))
+				assert.Equal(t, "789", readRange(t, store, "range.bin", 7, -1))
+				assert.Equal(t, "0123456789", readRange(t, store, "range.bin", 0, -1))
+				assert.Equal(t, "", readRange(t, store, "range.bin", 4, 0))
+			})
+
+			t.Run("NotFound", func(t *testing.T) {
+				_, err := store.Get(ctx, "missing/object")
+				assert.True(t, IsNotFound(err), "got %v", err)
+
+				_, err = store.Stat(ctx, "missing/object")
+				assert.True(t, IsNotFound(err), "got %v", err)
+
+				assert.NoError(t, store.Delete(ctx, "missing/object"))
+			})
+
+			t.Run("ListDelete", func(t *testing.T) {
+				for _, key := range []string{"list/2", "list/1", "list/sub/3", "listing"} {
+					require.NoError(t, store.Put(ctx, key, strings.NewReader(key), int64(len(key))))
+				}
+
+				objects, err := store.List(ctx, "list/")
+				require.NoError(t, err)
+				var keys []string
+				for _, object := range objects {
+					keys = append(keys, object.Key)
+				}
+				assert.Equal(t, []string{"list/1", "list/2", "list/sub/3"}, keys)
+
+				require.NoError(t, store.Delete(ctx, "list/1"))
+				objects, err = store.List(ctx, "list/")
+				require.NoError(t, err)
+				assert.Len(t, objects, 2)
+			})
+
+			t.Run("MultipartUpload", func(t *testing.T) {
+				upload, err := store.CreateMultipartUpload(ctx, "multipart/object")
+				require.NoError(t, err)
+
+				// Parts arrive concurrently and out of order
+				parts := []string{"first-", "second-", "third"}
+				var wg sync.WaitGroup
+				errs := make([]error, len(parts))
+				for i := len(parts) - 1; i >= 0; i-- {
+					wg.Add(1)
+					go func(i int) {
+						defer wg.Done()
+						errs[i] = upload.UploadPart(ctx, i+1, strings.NewReader(parts[i]), int64(len(parts[i])))
+					}(i)
+				}
+				wg.Wait()
+				for _, err := range errs {
+					require.NoError(t, err)
+				}
+
+				// The object is not visible before the upload completes
+				_, err = store.Stat(ctx, "multipart/object")
+				assert.True(t, IsNotFound(err), "got %v", err)
+
+				require.NoError(t, upload.Complete(ctx))
+				assert.Equal(t, "first-second-third", readRange(t, store, "multipart/object", 0, -1))
+			})
+
+			t.Run("AbortUpload", func(t *testing.T) {
+				upload, err := store.CreateMultipartUpload(ctx, "aborted/object")
+				require.NoError(t, err)
+				require.NoError(t, upload.UploadPart(ctx, 1, bytes.NewReader([]byte("data")), 4))
+				require.NoError(t, upload.Abort(ctx))
+
+				_, err = store.Stat(ctx, "aborted/object")
+				assert.True(t, IsNotFound(err), "got %v", err)
+				objects, err := store.List(ctx, "")
+				require.NoError(t, err)
+				for _, object := range objects {
+					assert.NotContains(t, object.Key, "aborted")
+				}
+			})
+
+			t.Run("InvalidKey", func(t *testing.T) {
+				for _, key := range []string{"", "..", "../escape"} {
+					err := store.Put(ctx, key, strings.NewReader("x"), 1)
+					assert.Error(t, err, "key %q", key)
+				}
+			})
+		})
+	}
+}
+
+// TestS3StorePrefix tests that an S3 store keeps its objects under its prefix
+func TestS3StorePrefix(t *testing.T) {
+	ctx := context.Background()
+	store := newFakeS3Store(t, "/warehouse/ranger/")
+
+	require.NoError(t, store.Put(ctx, "tables/t", strings.NewReader("x"), 1))
+
+	name, err := store.objectName("tables/t")
+	require.NoError(t, err)
+	assert.Equal(t, "warehouse/ranger/tables/t", name)
+
+	objects, err := store.List(ctx, "tables/")
+	require.NoError(t, err)
+	require.Len(t, objects, 1)
+	assert.Equal(t, "tables/t", objects[0].Key)
+}
+
+// TestNewS3StoreValidation tests that endpoint and bucket are required
+func TestNewS3StoreValidation(t *testing.T) {
+	_, err := NewS3Store(S3Config{Bucket: "bucket"})
+	assert.Error(t, err)
+
+	_, err = NewS3Store(S3Config{Endpoint: "localhost:9000"})
+	assert.Error(t, err)
+}
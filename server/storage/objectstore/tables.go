@@ -0,0 +1,157 @@
+package objectstore
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// healthCheckTimeout bounds the ping of a store that reports health
+const healthCheckTimeout = 5 * time.Second
+
+// pinger is implemented by stores that can check their backing service
+type pinger interface {
+	Ping(ctx context.Context) error
+}
+
+// TableStorage is a table storage engine over any Store. Each insert is written as new
+// objects under tables/<database>/<table>/data/: Parquet data files registered one by one, or
+// JSON batches streamed through OpenTableForWrite and read back as their concatenation in key
+// order, so inserts never rewrite earlier data.
+type TableStorage struct {
+	storageType string
+	store       Store
+	uploads     UploadOptions
+	cache       *PageCache
+}
+
+// NewTableStorage creates a table storage engine of the given type over a store; objects are
+// uploaded in parts as configured by uploads and read through cache, which may be nil
+func NewTableStorage(storageType string, store Store, uploads UploadOptions, cache *PageCache) *TableStorage {
+	return &TableStorage{storageType: storageType, store: store, uploads: uploads, cache: cache}
+}
+
+// GetStorageType returns the storage type identifier
+func (t *TableStorage) GetStorageType() string {
+	return t.storageType
+}
+
+// Store returns the object store the tables are kept in
+func (t *TableStorage) Store() Store {
+	return t.store
+}
+
+// HealthStatus reports whether the backing service of the store is reachable
+func (t *TableStorage) HealthStatus() (bool, map[string]interface{}) {
+	p, ok := t.store.(pinger)
+	if !ok {
+		return true, map[string]interface{}{}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), healthCheckTimeout)
+	defer cancel()
+	if err := p.Ping(ctx); err != nil {
+		return false, map[string]interface{}{"error": err.Error()}
+	}
+	return true, map[string]interface{}{}
+}
+
+// OpenForRead opens an object for ranged reads
+func (t *TableStorage) OpenForRead(path string) (io.ReadCloser, error) {
+	return NewObjectReader(context.Background(), t.store, path, t.cache)
+}
+
+// OpenForWrite opens an object for writing; it appears when the writer is closed
+func (t *TableStorage) OpenForWrite(path string) (io.WriteCloser, error) {
+	if _, err := cleanKey(path); err != nil {
+		return nil, err
+	}
+	return NewUploadWriter(context.Background(), t.store, path, t.uploads), nil
+}
+
+// OpenTableForWrite opens a writer for a new data object of the table
+func (t *TableStorage) OpenTableForWrite(database, tableName string) (io.WriteCloser, error) {
+	// Timestamped names keep the objects of a table in insertion order
+	key := path.Join(tableDataPrefix(database, tableName),
+		fmt.Sprintf("data_%s_%s%s", time.Now().UTC().Format("20060102T150405.000000000"), uuid.New().String()[:8], jsonSuffix))
+	return NewUploadWriter(context.Background(), t.store, key, t.uploads), nil
+}
+
+// OpenTableForRead opens a reader over the JSON data objects of the table in insertion order
+func (t *TableStorage) OpenTableForRead(database, tableName string) (io.ReadCloser, error) {
+	ctx := context.Background()
+	objects, err := t.store.List(ctx, tableDataPrefix(database, tableName)+"/")
+	if err != nil {
+		return nil, err
+	}
+
+	readers := make([]io.Reader, 0, len(objects))
+	closers := make([]io.Closer, 0, len(objects))
+	for _, object := range objects {
+		if !strings.HasSuffix(object.Key, jsonSuffix) {
+			continue
+		}
+		reader, err := t.store.Get(ctx, object.Key)
+		if err != nil {
+			for _, closer := range closers {
+				closer.Close()
+			}
+			return nil, err
+		}
+		readers = append(readers, reader)
+		closers = append(closers, reader)
+	}
+	return &multiReadCloser{Reader: io.MultiReader(readers...), closers: closers}, nil
+}
+
+// SetupTable prepares the table; object stores have no directories to create
+func (t *TableStorage) SetupTable(database, tableName string) error {
+	return nil
+}
+
+// RemoveTableEnvironment removes every object of the table
+func (t *TableStorage) RemoveTableEnvironment(database, tableName string) error {
+	ctx := context.Background()
+	objects, err := t.store.List(ctx, tablePrefix(database, tableName)+"/")
+	if err != nil {
+		return err
+	}
+	for _, object := range objects {
+		if err := t.store.Delete(ctx, object.Key); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// tablePrefix returns the key prefix of the objects of a table
+func tablePrefix(database, tableName string) string {
+	return path.Join("tables", database, tableName)
+}
+
+// tableDataPrefix returns the key prefix of the data objects of a table
+func tableDataPrefix(database, tableName string) string {
+	return path.Join(tablePrefix(database, tableName), "data")
+}
+
+// multiReadCloser reads several objects one after another and closes them all
+type multiReadCloser struct {
+	io.Reader
+	closers []io.Closer
+}
+
+// Close closes every object
+func (r *multiReadCloser) Close() error {
+	var firstErr error
+	for _, closer := range r.closers {
+		if err := closer.Close(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
@@ -0,0 +1,106 @@
+package objectstore
+
+import (
+	"context"
+	"io"
+	"testing"
+
+	"github.com/apache/arrow-go/v18/arrow"
+	parquet "github.com/gear6io/ranger/server/storage/parquet"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestTableStorage tests that table writes append objects and reads concatenate them
+func TestTableStorage(t *testing.T) {
+	for name, store := range testStores(t) {
+		t.Run(name, func(t *testing.T) {
+			tables := NewTableStorage("S3", store, UploadOptions{}, NewPageCache(DefaultCacheSize))
+			assert.Equal(t, "S3", tables.GetStorageType())
+			require.NoError(t, tables.SetupTable("db", "events"))
+
+			for _, batch := range []string{"[[1]]\n", "[[2],[3]]\n"} {
+				writer, err := tables.OpenTableForWrite("db", "events")
+				require.NoError(t, err)
+				_, err = io.WriteString(writer, batch)
+				require.NoError(t, err)
+				require.NoError(t, writer.Close())
+			}
+
+			reader, err := tables.OpenTableForRead("db", "events")
+			require.NoError(t, err)
+			data, err := io.ReadAll(reader)
+			require.NoError(t, err)
+			require.NoError(t, reader.Close())
+			assert.Equal(t, "[[1]]\n[[2],[3]]\n", string(data))
+
+			healthy, _ := tables.HealthStatus()
+			assert.True(t, healthy)
+
+			require.NoError(t, tables.RemoveTableEnvironment("db", "events"))
+			objects, err := store.List(context.Background(), "tables/db/events/")
+			require.NoError(t, err)
+			assert.Empty(t, objects)
+		})
+	}
+}
+
+// TestTableStorageDataFiles tests that inserts become Parquet objects of their own, which are
+// read back in insertion order and removed one by one
+func TestTableStorageDataFiles(t *testing.T) {
+	schema := arrow.NewSchema([]arrow.Field{
+		{Name: "id", Type: arrow.PrimitiveTypes.Int64},
+		{Name: "name", Type: arrow.BinaryTypes.String, Nullable: true},
+	}, nil)
+
+	for name, store := range testStores(t) {
+		t.Run(name, func(t *testing.T) {
+			tables := NewTableStorage("S3", store, UploadOptions{}, NewPageCache(DefaultCacheSize))
+
+			config := parquet.DefaultParquetConfig()
+			config.BloomFilterColumns = []string{"name"}
+			config.PageIndex = true
+			config.BatchSize = 2
+			first, err := tables.WriteDataFiles(schema, config, "db", "events", [][]interface{}{
+				{int64(1), "alice"}, {int64(2), "bob"}, {int64(3), nil},
+			})
+			require.NoError(t, err)
+			require.Len(t, first, 1)
+			second, err := tables.WriteDataFiles(schema, nil, "db", "events", [][]interface{}{{int64(4), "bob"}})
+			require.NoError(t, err)
+			require.Len(t, second, 1)
+
+			for _, file := range append(first, second...) {
+				info, err := store.Stat(context.Background(), file.Path)
+				require.NoError(t, err)
+				assert.Equal(t, info.Size, file.Size)
+				assert.Len(t, file.Checksum, 64)
+			}
+			assert.Equal(t, int64(3), first[0].RowCount)
+			assert.Equal(t, []string{"name"}, first[0].BloomFilterColumns)
+
+			rows, err := tables.ReadDataFiles("db", "events")
+			require.NoError(t, err)
+			assert.Equal(t, [][]interface{}{{int64(1), "alice"}, {int64(2), "bob"}, {int64(3), nil}, {int64(4), "bob"}}, rows)
+
+			probe, err := parquet.NewEqualityProbe(schema, "name", "bob")
+			require.NoError(t, err)
+			rows, err = tables.ReadDataFilesMatching("db", "events", probe)
+			require.NoError(t, err)
+			assert.Equal(t, [][]interface{}{{int64(2), "bob"}, {int64(4), "bob"}}, rows)
+
+			// Streamed JSON batches are read apart from the Parquet objects
+			reader, err := tables.OpenTableForRead("db", "events")
+			require.NoError(t, err)
+			data, err := io.ReadAll(reader)
+			require.NoError(t, err)
+			require.NoError(t, reader.Close())
+			assert.Empty(t, data)
+
+			require.NoError(t, tables.RemoveDataFiles(first))
+			rows, err = tables.ReadDataFiles("db", "events")
+			require.NoError(t, err)
+			assert.Equal(t, [][]interface{}{{int64(4), "bob"}}, rows)
+		})
+	}
+}
@@ -0,0 +1,257 @@
+package objectstore
+
+import (
+	"bytes"
+	"context"
+	"sync"
+	"time"
+
+	"github.com/gear6io/ranger/pkg/errors"
+)
+
+// Defaults of streaming uploads
+const (
+	DefaultPartSize          = 8 << 20 // 8 MiB
+	MinPartSize              = 5 << 20 // Smallest part S3 accepts for all parts but the last
+	DefaultUploadConcurrency = 4
+	DefaultPartRetries       = 2
+	partRetryDelay           = 200 * time.Millisecond
+)
+
+// UploadOptions configures a streaming upload
+type UploadOptions struct {
+	PartSize    int64 // Bytes per part; DefaultPartSize when 0, at least MinPartSize
+	Concurrency int   // Parts uploaded at once; DefaultUploadConcurrency when 0
+	Retries     int   // Further attempts of a failed part; DefaultPartRetries when 0, none when negative
+}
+
+// withDefaults returns the options with zero values replaced by the defaults
+func (o UploadOptions) withDefaults() UploadOptions {
+	if o.PartSize == 0 {
+		o.PartSize = DefaultPartSize
+	}
+	if o.PartSize < MinPartSize {
+		o.PartSize = MinPartSize
+	}
+	if o.Concurrency <= 0 {
+		o.Concurrency = DefaultUploadConcurrency
+	}
+	if o.Retries == 0 {
+		o.Retries = DefaultPartRetries
+	}
+	if o.Retries < 0 {
+		o.Retries = 0
+	}
+	return o
+}
+
+// UploadWriter uploads an object in parts while it is written. An object smaller than one
+// part is stored with a single Put on Close. Larger objects become a multipart upload with at
+// most Concurrency parts in flight, so memory stays within (Concurrency+1)*PartSize however
+// large the object grows. A failed part is retried on its own without resending the others;
+// if it keeps failing, or the writer is aborted, the upload is aborted and no object appears.
+type UploadWriter struct {
+	ctx    context.Context
+	store  Store
+	key    string
+	opts   UploadOptions
+	buffer []byte
+	upload MultipartUpload
+	parts  int
+	slots  chan struct{}
+	wg     sync.WaitGroup
+	mu     sync.Mutex
+	err    error
+	closed bool
+	size   int64
+}
+
+// NewUploadWriter creates a writer that streams an object into the store
+func NewUploadWriter(ctx context.Context, store Store, key string, opts UploadOptions) *UploadWriter {
+	opts = opts.withDefaults()
+	return &UploadWriter{
+		ctx:   ctx,
+		store: store,
+		key:   key,
+		opts:  opts,
+		slots: make(chan struct{}, opts.Concurrency),
+	}
+}
+
+// Write buffers data and uploads every full part
+func (w *UploadWriter) Write(p []byte) (int, error) {
+	if w.closed {
+		return 0, errors.New(ErrWriteFailed, "write on closed upload", nil).AddContext("key", w.key)
+	}
+	if err := w.failure(); err != nil {
+		return 0, err
+	}
+
+	written := 0
+	for len(p) > 0 {
+		if w.buffer == nil {
+			w.buffer = make([]byte, 0, w.opts.PartSize)
+		}
+		n := int(w.opts.PartSize) - len(w.buffer)
+		if n > len(p) {
+			n = len(p)
+		}
+		w.buffer = append(w.buffer, p[:n]...)
+		p = p[n:]
+		written += n
+		w.size += int64(n)
+
+		if int64(len(w.buffer)) == w.opts.PartSize {
+			if err := w.flushPart(); err != nil {
+				return written, err
+			}
+		}
+	}
+	return written, nil
+}
+
+// Size returns the number of bytes written so far
+func (w *UploadWriter) Size() int64 {
+	return w.size
+}
+
+// flushPart hands the buffered part to an uploader, waiting for a free slot. A part that
+// cannot be handed over fails the upload, so that Close never commits the object without it.
+func (w *UploadWriter) flushPart() error {
+	if w.upload == nil {
+		upload, err := w.store.CreateMultipartUpload(w.ctx, w.key)
+		if err != nil {
+			err = errors.New(ErrUploadFailed, "failed to create multipart upload", err).AddContext("key", w.key)
+			w.fail(err)
+			return err
+		}
+		w.upload = upload
+	}
+
+	select {
+	case w.slots <- struct{}{}:
+	case <-w.ctx.Done():
+		err := errors.New(ErrUploadFailed, "upload cancelled", w.ctx.Err()).AddContext("key", w.key)
+		w.fail(err)
+		return err
+	}
+	if err := w.failure(); err != nil {
+		<-w.slots
+		return err
+	}
+
+	w.parts++
+	number, data := w.parts, w.buffer
+	w.buffer = nil
+
+	w.wg.Add(1)
+	go func() {
+		defer func() {
+			<-w.slots
+			w.wg.Done()
+		}()
+		if err := w.uploadPart(number, data); err != nil {
+			w.fail(err)
+		}
+	}()
+	return nil
+}
+
+// uploadPart uploads a part, retrying it on failure
+func (w *UploadWriter) uploadPart(number int, data []byte) error {
+	var err error
+	for attempt := 0; attempt <= w.opts.Retries; attempt++ {
+		if attempt > 0 {
+			select {
+			case <-time.After(partRetryDelay * time.Duration(attempt)):
+			case <-w.ctx.Done():
+				return errors.New(ErrUploadFailed, "upload cancelled", w.ctx.Err()).AddContext("key", w.key)
+			}
+		}
+		if err = w.upload.UploadPart(w.ctx, number, bytes.NewReader(data), int64(len(data))); err == nil {
+			return nil
+		}
+	}
+	return errors.New(ErrUploadFailed, "failed to upload part", err).
+		AddContext("key", w.key).
+		AddContext("part", number).
+		AddContext("attempts", w.opts.Retries+1)
+}
+
+// fail records the first failure of an upload
+func (w *UploadWriter) fail(err error) {
+	w.mu.Lock()
+	if w.err == nil {
+		w.err = err
+	}
+	w.mu.Unlock()
+}
+
+// failure returns the first failure of an upload
+func (w *UploadWriter) failure() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.err
+}
+
+// Close uploads the remaining data and completes the object
+func (w *UploadWriter) Close() error {
+	if w.closed {
+		return w.failure()
+	}
+	w.closed = true
+
+	// Objects smaller than a part need no multipart upload
+	if w.upload == nil {
+		if err := w.failure(); err != nil {
+			return err
+		}
+		data := w.buffer
+		w.buffer = nil
+		if err := w.store.Put(w.ctx, w.key, bytes.NewReader(data), int64(len(data))); err != nil {
+			w.fail(err)
+			return err
+		}
+		return nil
+	}
+
+	var err error
+	if len(w.buffer) > 0 {
+		err = w.flushPart()
+	}
+	w.wg.Wait()
+	if err == nil {
+		err = w.failure()
+	}
+	if err == nil {
+		err = w.upload.Complete(w.ctx)
+	}
+	if err != nil {
+		w.fail(err)
+		w.abortUpload()
+		return w.failure()
+	}
+	return nil
+}
+
+// Abort discards everything written; no object is created
+func (w *UploadWriter) Abort() error {
+	if w.closed {
+		return nil
+	}
+	w.closed = true
+	w.buffer = nil
+	w.fail(errors.New(ErrUploadFailed, "upload aborted", nil).AddContext("key", w.key))
+	if w.upload == nil {
+		return nil
+	}
+	w.wg.Wait()
+	return w.abortUpload()
+}
+
+// abortUpload aborts the multipart upload, outliving a cancelled context so no parts are left behind
+func (w *UploadWriter) abortUpload() error {
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(w.ctx), time.Minute)
+	defer cancel()
+	return w.upload.Abort(ctx)
+}
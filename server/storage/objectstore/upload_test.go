@@ -0,0 +1,193 @@
+package objectstore
+
+import (
+	"bytes"
+	"context"
+	stderrors "errors"
+	"io"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// recordingStore counts the requests made to a store and fails upload parts on demand
+type recordingStore struct {
+	Store
+	mu        sync.Mutex
+	puts      int
+	ranges    int
+	parts     int
+	aborts    int
+	failParts int  // Part uploads to fail before succeeding; -1 fails every part
+	noUploads bool // Fail every multipart upload before it starts
+}
+
+// Put counts single-request uploads
+func (s *recordingStore) Put(ctx context.Context, key string, r io.Reader, size int64) error {
+	s.mu.Lock()
+	s.puts++
+	s.mu.Unlock()
+	return s.Store.Put(ctx, key, r, size)
+}
+
+// GetRange counts ranged reads
+func (s *recordingStore) GetRange(ctx context.Context, key string, offset, length int64) (io.ReadCloser, error) {
+	s.mu.Lock()
+	s.ranges++
+	s.mu.Unlock()
+	return s.Store.GetRange(ctx, key, offset, length)
+}
+
+// CreateMultipartUpload records the parts and aborts of the upload
+func (s *recordingStore) CreateMultipartUpload(ctx context.Context, key string) (MultipartUpload, error) {
+	if s.noUploads {
+		return nil, stderrors.New("injected upload failure")
+	}
+	upload, err := s.Store.CreateMultipartUpload(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	return &recordingUpload{MultipartUpload: upload, store: s}, nil
+}
+
+// recordingUpload is a multipart upload of a recordingStore
+type recordingUpload struct {
+	MultipartUpload
+	store *recordingStore
+}
+
+// UploadPart counts parts and fails them while the store says so
+func (u *recordingUpload) UploadPart(ctx context.Context, number int, r io.Reader, size int64) error {
+	u.store.mu.Lock()
+	u.store.parts++
+	fail := u.store.failParts != 0
+	if u.store.failParts > 0 {
+		u.store.failParts--
+	}
+	u.store.mu.Unlock()
+
+	if fail {
+		return stderrors.New("injected part failure")
+	}
+	return u.MultipartUpload.UploadPart(ctx, number, r, size)
+}
+
+// Abort counts aborted uploads
+func (u *recordingUpload) Abort(ctx context.Context) error {
+	u.store.mu.Lock()
+	u.store.aborts++
+	u.store.mu.Unlock()
+	return u.MultipartUpload.Abort(ctx)
+}
+
+// testData returns size bytes of a repeating pattern
+func testData(size int) []byte {
+	data := make([]byte, size)
+	for i := range data {
+		data[i] = byte(i*7 + i/251)
+	}
+	return data
+}
+
+// writeChunks writes data in small chunks, as table writers do
+func writeChunks(t *testing.T, w io.Writer, data []byte) {
+	for len(data) > 0 {
+		n := 64 << 10
+		if n > len(data) {
+			n = len(data)
+		}
+		_, err := w.Write(data[:n])
+		require.NoError(t, err)
+		data = data[n:]
+	}
+}
+
+// TestUploadWriter tests that objects are streamed in bounded parts
+func TestUploadWriter(t *testing.T) {
+	ctx := context.Background()
+
+	for name, store := range testStores(t) {
+		t.Run(name, func(t *testing.T) {
+			t.Run("SmallObjectUsesPut", func(t *testing.T) {
+				recorder := &recordingStore{Store: store}
+				writer := NewUploadWriter(ctx, recorder, "upload/small", UploadOptions{})
+				writeChunks(t, writer, testData(1000))
+				require.NoError(t, writer.Close())
+
+				assert.Equal(t, 1, recorder.puts)
+				assert.Equal(t, 0, recorder.parts)
+				assert.Equal(t, string(testData(1000)), readRange(t, store, "upload/small", 0, -1))
+			})
+
+			t.Run("LargeObjectUploadsParts", func(t *testing.T) {
+				recorder := &recordingStore{Store: store}
+				data := testData(2*MinPartSize + 1234)
+				writer := NewUploadWriter(ctx, recorder, "upload/large", UploadOptions{PartSize: MinPartSize, Concurrency: 2})
+				writeChunks(t, writer, data)
+				assert.Equal(t, int64(len(data)), writer.Size())
+				require.NoError(t, writer.Close())
+
+				assert.Equal(t, 0, recorder.puts)
+				assert.Equal(t, 3, recorder.parts)
+				assert.True(t, bytes.Equal(data, []byte(readRange(t, store, "upload/large", 0, -1))))
+			})
+		})
+	}
+
+	t.Run("RetriesFailedParts", func(t *testing.T) {
+		store := NewMemoryStore()
+		recorder := &recordingStore{Store: store, failParts: 1}
+		data := testData(MinPartSize + 10)
+		writer := NewUploadWriter(ctx, recorder, "retried", UploadOptions{PartSize: MinPartSize, Retries: 1})
+		writeChunks(t, writer, data)
+		require.NoError(t, writer.Close())
+
+		assert.Equal(t, 3, recorder.parts) // Two parts, one of them sent twice
+		assert.Equal(t, 0, recorder.aborts)
+		info, err := store.Stat(ctx, "retried")
+		require.NoError(t, err)
+		assert.Equal(t, int64(len(data)), info.Size)
+	})
+
+	t.Run("AbortsOnFailure", func(t *testing.T) {
+		store := NewMemoryStore()
+		recorder := &recordingStore{Store: store, failParts: -1}
+		writer := NewUploadWriter(ctx, recorder, "failed", UploadOptions{PartSize: MinPartSize, Retries: -1})
+		writeChunks(t, writer, testData(MinPartSize+10))
+		assert.Error(t, writer.Close())
+
+		assert.Equal(t, 1, recorder.aborts)
+		_, err := store.Stat(ctx, "failed")
+		assert.True(t, IsNotFound(err), "got %v", err)
+	})
+
+	t.Run("FailedUploadIsNotCommitted", func(t *testing.T) {
+		store := NewMemoryStore()
+		recorder := &recordingStore{Store: store, noUploads: true}
+		writer := NewUploadWriter(ctx, recorder, "unstarted", UploadOptions{PartSize: MinPartSize})
+		_, err := writer.Write(testData(MinPartSize + 10))
+		assert.Error(t, err)
+
+		// Close must not store the part still buffered as if it were the whole object
+		assert.Error(t, writer.Close())
+		assert.Equal(t, 0, recorder.puts)
+		_, err = store.Stat(ctx, "unstarted")
+		assert.True(t, IsNotFound(err), "got %v", err)
+	})
+
+	t.Run("Abort", func(t *testing.T) {
+		store := NewMemoryStore()
+		recorder := &recordingStore{Store: store}
+		writer := NewUploadWriter(ctx, recorder, "aborted", UploadOptions{PartSize: MinPartSize})
+		writeChunks(t, writer, testData(MinPartSize+10))
+		require.NoError(t, writer.Abort())
+
+		assert.Equal(t, 1, recorder.aborts)
+		_, err := writer.Write([]byte("more"))
+		assert.Error(t, err)
+		_, err = store.Stat(ctx, "aborted")
+		assert.True(t, IsNotFound(err), "got %v", err)
+	})
+}
@@ -5,7 +5,9 @@ import (
 	"strings"
 
 	"github.com/apache/arrow-go/v18/arrow"
+	pq "github.com/apache/arrow-go/v18/parquet"
 	"github.com/apache/arrow-go/v18/parquet/compress"
+	"github.com/apache/arrow-go/v18/parquet/pqarrow"
 	"github.com/gear6io/ranger/pkg/errors"
 )
 
@@ -15,6 +17,21 @@ var (
 	ParquetCompressionInvalidType     = errors.MustNewCode("parquet.compression_invalid_type")
 	ParquetCompressionInvalidLevel    = errors.MustNewCode("parquet.compression_invalid_level")
 	ParquetCompressionFailed          = errors.MustNewCode("parquet.compression_failed")
+	ParquetWriterInvalidSetting       = errors.MustNewCode("parquet.writer_invalid_setting")
+	ParquetWriterUnknownColumn        = errors.MustNewCode("parquet.writer_unknown_column")
+)
+
+// Table settings for how data files are encoded, given with CREATE TABLE ... SETTINGS. The
+// column settings are comma-separated column:value pairs, such as 'payload:zstd,id:none'.
+const (
+	SettingCompression            = "compression"
+	SettingCompressionLevel       = "compression_level"
+	SettingColumnCompression      = "column_compression"
+	SettingColumnCompressionLevel = "column_compression_level"
+	SettingDictionary             = "dictionary"
+	SettingColumnDictionary       = "column_dictionary"
+	SettingRowGroupSize           = "row_group_size" // rows
+	SettingDataPageSize           = "data_page_size" // bytes
 )
 
 // CompressionType represents supported compression algorithms
@@ -46,8 +63,9 @@ func GetCompressionCodec(compression string) (compress.Compression, error) {
 		return compress.Codecs.Gzip, nil
 	case "brotli":
 		return compress.Codecs.Brotli, nil
-	case "lz4":
-		return compress.Codecs.Lz4, nil
+	case "lz4", "lz4_raw":
+		// The framed LZ4 codec is deprecated by Parquet and cannot be written
+		return compress.Codecs.Lz4Raw, nil
 	case "zstd":
 		return compress.Codecs.Zstd, nil
 	default:
@@ -73,14 +91,158 @@ func ValidateCompressionConfig(config *ParquetConfig) error {
 			return errors.AddContext(err, "column", column)
 		}
 	}
+	for column, level := range config.ColumnCompressionLevel {
+		if err := validateCompressionLevel(GetCompressionForColumn(config, column), level); err != nil {
+			return errors.AddContext(err, "column", column)
+		}
+	}
 
 	return nil
 }
 
+// ApplyWriterSettings sets the codecs, compression levels, encodings and sizes requested by
+// table settings on config and validates the result; other settings are left alone
+func ApplyWriterSettings(config *ParquetConfig, settings map[string]interface{}) error {
+	if value, ok := settings[SettingCompression]; ok {
+		compression, ok := value.(string)
+		if !ok {
+			return errors.New(ParquetWriterInvalidSetting, "compression must name a codec", nil).AddContext(SettingCompression, value)
+		}
+		config.Compression = compression
+	}
+
+	if value, ok := settings[SettingCompressionLevel]; ok {
+		level, ok := integerValue(value)
+		if !ok {
+			return errors.New(ParquetWriterInvalidSetting, "compression_level must be a whole number", nil).AddContext(SettingCompressionLevel, value)
+		}
+		config.CompressionLevel = int(level)
+	}
+
+	if value, ok := settings[SettingColumnCompression]; ok {
+		values, err := columnSettings(SettingColumnCompression, value)
+		if err != nil {
+			return err
+		}
+		config.ColumnCompression = make(map[string]string, len(values))
+		for column, v := range values {
+			compression, ok := v.(string)
+			if !ok {
+				return errors.New(ParquetWriterInvalidSetting, "column_compression must name a codec for each column", nil).AddContext("column", column)
+			}
+			config.ColumnCompression[column] = compression
+		}
+	}
+
+	if value, ok := settings[SettingColumnCompressionLevel]; ok {
+		values, err := columnSettings(SettingColumnCompressionLevel, value)
+		if err != nil {
+			return err
+		}
+		config.ColumnCompressionLevel = make(map[string]int, len(values))
+		for column, v := range values {
+			level, ok := integerValue(v)
+			if !ok {
+				return errors.New(ParquetWriterInvalidSetting, "column_compression_level must be a whole number for each column", nil).AddContext("column", column)
+			}
+			config.ColumnCompressionLevel[column] = int(level)
+		}
+	}
+
+	if value, ok := settings[SettingDictionary]; ok {
+		enabled, ok := settingBool(value)
+		if !ok {
+			return errors.New(ParquetWriterInvalidSetting, "dictionary must be true or false", nil).AddContext(SettingDictionary, value)
+		}
+		config.Dictionary = enabled
+	}
+
+	if value, ok := settings[SettingColumnDictionary]; ok {
+		values, err := columnSettings(SettingColumnDictionary, value)
+		if err != nil {
+			return err
+		}
+		config.ColumnDictionary = make(map[string]bool, len(values))
+		for column, v := range values {
+			enabled, ok := settingBool(v)
+			if !ok {
+				return errors.New(ParquetWriterInvalidSetting, "column_dictionary must be true or false for each column", nil).AddContext("column", column)
+			}
+			config.ColumnDictionary[column] = enabled
+		}
+	}
+
+	for name, size := range map[string]*int64{
+		SettingRowGroupSize: &config.RowGroupSize,
+		SettingDataPageSize: &config.DataPageSize,
+	} {
+		if value, ok := settings[name]; ok {
+			n, ok := integerValue(value)
+			if !ok || n <= 0 {
+				return errors.New(ParquetWriterInvalidSetting, name+" must be a positive whole number", nil).AddContext(name, value)
+			}
+			*size = n
+		}
+	}
+
+	return ValidateCompressionConfig(config)
+}
+
+// ValidateWriterColumns checks that every column with its own codec, compression level or
+// dictionary setting in config is a column of the table
+func ValidateWriterColumns(config *ParquetConfig, schema *arrow.Schema) error {
+	check := func(setting, column string) error {
+		if len(schema.FieldIndices(column)) == 0 {
+			return errors.New(ParquetWriterUnknownColumn, "column does not exist in table", nil).AddContext("setting", setting).AddContext("column", column)
+		}
+		return nil
+	}
+	for column := range config.ColumnCompression {
+		if err := check(SettingColumnCompression, column); err != nil {
+			return err
+		}
+	}
+	for column := range config.ColumnCompressionLevel {
+		if err := check(SettingColumnCompressionLevel, column); err != nil {
+			return err
+		}
+	}
+	for column := range config.ColumnDictionary {
+		if err := check(SettingColumnDictionary, column); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// columnSettings returns the value of a column setting per column, given as comma-separated
+// column:value pairs or, once stored as JSON, possibly an object
+func columnSettings(name string, value interface{}) (map[string]interface{}, error) {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		return v, nil
+	case string:
+		values := make(map[string]interface{})
+		for _, pair := range strings.Split(v, ",") {
+			if strings.TrimSpace(pair) == "" {
+				continue
+			}
+			column, setting, found := strings.Cut(pair, ":")
+			column, setting = strings.TrimSpace(column), strings.TrimSpace(setting)
+			if !found || column == "" || setting == "" {
+				return nil, errors.New(ParquetWriterInvalidSetting, name+" must be comma-separated column:value pairs", nil).AddContext(name, value)
+			}
+			values[column] = setting
+		}
+		return values, nil
+	}
+	return nil, errors.New(ParquetWriterInvalidSetting, name+" must be comma-separated column:value pairs", nil).AddContext(name, value)
+}
+
 // validateCompressionLevel checks if compression level is valid for the algorithm
 func validateCompressionLevel(compression string, level int) error {
 	switch strings.ToLower(compression) {
-	case "none", "uncompressed", "snappy", "lz4":
+	case "none", "uncompressed", "snappy", "lz4", "lz4_raw":
 		// These don't use compression levels
 		return nil
 	case "gzip", "gz":
@@ -107,48 +269,65 @@ func GetCompressionForColumn(config *ParquetConfig, columnName string) string {
 	return config.Compression
 }
 
-// CreateWriterProperties creates Parquet writer properties with compression settings
-func CreateWriterProperties(config *ParquetConfig, schema *arrow.Schema) (map[string]interface{}, error) {
+// CreateWriterProperties creates the Parquet writer properties every data file of a table
+// is written with: its codec and compression level, overridden per column, dictionary
+// encoding, row group and data page sizes, statistics and the indexes it requests
+func CreateWriterProperties(config *ParquetConfig, schema *arrow.Schema) (*pq.WriterProperties, error) {
 	// Validate compression config first
 	if err := ValidateCompressionConfig(config); err != nil {
 		return nil, err
 	}
 
-	// For now, return a simple map with compression settings
-	// This will be used by the ParquetManager to configure the writer
-	properties := make(map[string]interface{})
-
-	// Set default compression
 	defaultCodec, err := GetCompressionCodec(config.Compression)
 	if err != nil {
 		return nil, err
 	}
-	properties["compression"] = defaultCodec
-
-	// Set compression level if applicable
-	if config.CompressionLevel > 0 {
-		properties["compression_level"] = config.CompressionLevel
+	props := []pq.WriterProperty{
+		pq.WithCompression(defaultCodec),
+		pq.WithDictionaryDefault(config.Dictionary),
+		pq.WithStats(config.EnableStats),
+	}
+	if requiresCompressionLevel(config.Compression) {
+		props = append(props, pq.WithCompressionLevel(config.CompressionLevel))
+	}
+	if config.RowGroupSize > 0 {
+		props = append(props, pq.WithMaxRowGroupLength(config.RowGroupSize))
+	}
+	if config.DataPageSize > 0 {
+		props = append(props, pq.WithDataPageSize(config.DataPageSize))
 	}
 
-	// Set column-specific compression
-	columnCompression := make(map[string]compress.Compression)
-	for _, field := range schema.Fields() {
-		columnName := field.Name
-		compression := GetCompressionForColumn(config, columnName)
+	// Column settings apply to every leaf column of a table column, such as the elements
+	// of a list
+	parquetSchema, err := pqarrow.ToParquet(schema, pq.NewWriterProperties(), pqarrow.DefaultWriterProps())
+	if err != nil {
+		return nil, errors.New(ParquetCompressionFailed, "failed to convert schema for writer properties", err)
+	}
+	for i := 0; i < parquetSchema.NumColumns(); i++ {
+		leaf := parquetSchema.Column(i)
+		path, column := leaf.Path(), leaf.ColumnPath()[0]
 
-		codec, err := GetCompressionCodec(compression)
-		if err != nil {
-			return nil, err
+		codec := defaultCodec
+		if compression, ok := config.ColumnCompression[column]; ok {
+			codec, err = GetCompressionCodec(compression)
+			if err != nil {
+				return nil, errors.AddContext(err, "column", column)
+			}
+			props = append(props, pq.WithCompressionFor(path, codec))
+		}
+		if level, ok := config.ColumnCompressionLevel[column]; ok {
+			props = append(props, pq.WithCompressionLevelFor(path, level))
+		} else if codec != defaultCodec {
+			// The table level belongs to the table codec
+			props = append(props, pq.WithCompressionLevelFor(path, compress.DefaultCompressionLevel))
+		}
+		if dictionary, ok := config.ColumnDictionary[column]; ok {
+			props = append(props, pq.WithDictionaryFor(path, dictionary))
 		}
-
-		columnCompression[columnName] = codec
 	}
-	properties["column_compression"] = columnCompression
-
-	// Additional performance settings
-	properties["enable_stats"] = config.EnableStats
 
-	return properties, nil
+	props = append(props, IndexWriterProperties(config, schema)...)
+	return pq.NewWriterProperties(props...), nil
 }
 
 // requiresCompressionLevel checks if a compression algorithm uses compression levels
@@ -168,7 +347,7 @@ func GetCompressionRatio(compression string) float64 {
 		return 1.0
 	case "snappy":
 		return 0.6 // ~40% compression
-	case "lz4":
+	case "lz4", "lz4_raw":
 		return 0.65 // ~35% compression
 	case "gzip", "gz":
 		return 0.4 // ~60% compression
@@ -4,6 +4,8 @@ import (
 	"testing"
 
 	"github.com/apache/arrow-go/v18/arrow"
+	"github.com/apache/arrow-go/v18/parquet/compress"
+	"github.com/gear6io/ranger/pkg/errors"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -21,6 +23,7 @@ func TestGetCompressionCodec(t *testing.T) {
 		{"GZ", "gz", false},
 		{"Brotli", "brotli", false},
 		{"LZ4", "lz4", false},
+		{"LZ4Raw", "lz4_raw", false},
 		{"ZSTD", "zstd", false},
 		{"Invalid", "invalid", true},
 		{"Empty", "", true},
@@ -185,22 +188,126 @@ func TestCreateWriterProperties(t *testing.T) {
 	require.NoError(t, err)
 	require.NotNil(t, properties)
 
-	// Check that properties contain expected keys
-	assert.Contains(t, properties, "compression")
-	assert.Contains(t, properties, "compression_level")
-	assert.Contains(t, properties, "column_compression")
-	assert.Contains(t, properties, "enable_stats")
+	// Table-wide settings
+	assert.Equal(t, compress.Codecs.Gzip, properties.Compression())
+	assert.Equal(t, 5, properties.CompressionLevel())
+	assert.True(t, properties.StatisticsEnabled())
+	assert.False(t, properties.DictionaryEnabled())
+
+	// Column-specific compression overrides the table codec and its level
+	assert.Equal(t, compress.Codecs.Snappy, properties.CompressionFor("name"))
+	assert.Equal(t, compress.DefaultCompressionLevel, properties.CompressionLevelFor("name"))
+	assert.Equal(t, compress.Codecs.Gzip, properties.CompressionFor("score"))
+	assert.Equal(t, 5, properties.CompressionLevelFor("score"))
+}
+
+func TestCreateWriterProperties_ColumnSettings(t *testing.T) {
+	schema := arrow.NewSchema([]arrow.Field{
+		{Name: "id", Type: arrow.PrimitiveTypes.Int64, Nullable: false},
+		{Name: "tags", Type: arrow.ListOf(arrow.BinaryTypes.String), Nullable: true},
+	}, nil)
 
-	// Check compression level
-	assert.Equal(t, 5, properties["compression_level"])
-	assert.Equal(t, true, properties["enable_stats"])
+	config := DefaultParquetConfig()
+	config.ColumnCompression = map[string]string{"tags": "zstd"}
+	config.ColumnCompressionLevel = map[string]int{"tags": 9}
+	config.ColumnDictionary = map[string]bool{"id": false}
+	config.RowGroupSize = 5000
+	config.DataPageSize = 64 << 10
 
-	// Check column compression - it should be the actual type returned by our function
-	columnCompressionRaw := properties["column_compression"]
-	require.NotNil(t, columnCompressionRaw, "column_compression should exist")
+	properties, err := CreateWriterProperties(config, schema)
+	require.NoError(t, err)
+
+	assert.Equal(t, compress.Codecs.Snappy, properties.CompressionFor("id"))
+	assert.False(t, properties.DictionaryEnabledFor("id"))
+	assert.Equal(t, int64(5000), properties.MaxRowGroupLength())
+	assert.Equal(t, int64(64<<10), properties.DataPageSize())
+
+	// Settings of a nested column apply to its leaf columns
+	assert.Equal(t, compress.Codecs.Zstd, properties.CompressionFor("tags.list.element"))
+	assert.Equal(t, 9, properties.CompressionLevelFor("tags.list.element"))
+	assert.True(t, properties.DictionaryEnabledFor("tags.list.element"))
+}
+
+func TestApplyWriterSettings(t *testing.T) {
+	t.Run("ParsesSettings", func(t *testing.T) {
+		config := DefaultParquetConfig()
+		err := ApplyWriterSettings(config, map[string]interface{}{
+			SettingCompression:            "zstd",
+			SettingCompressionLevel:       3,
+			SettingColumnCompression:      "payload:gzip, id:none",
+			SettingColumnCompressionLevel: "payload:9",
+			SettingDictionary:             false,
+			SettingColumnDictionary:       "kind:true",
+			SettingRowGroupSize:           10000,
+			SettingDataPageSize:           "65536",
+			"hot_tier_bytes":              10,
+		})
+		require.NoError(t, err)
+		assert.Equal(t, "zstd", config.Compression)
+		assert.Equal(t, 3, config.CompressionLevel)
+		assert.Equal(t, map[string]string{"payload": "gzip", "id": "none"}, config.ColumnCompression)
+		assert.Equal(t, map[string]int{"payload": 9}, config.ColumnCompressionLevel)
+		assert.False(t, config.Dictionary)
+		assert.Equal(t, map[string]bool{"kind": true}, config.ColumnDictionary)
+		assert.Equal(t, int64(10000), config.RowGroupSize)
+		assert.Equal(t, int64(65536), config.DataPageSize)
+	})
+
+	t.Run("ParsesStoredSettings", func(t *testing.T) {
+		// Settings read back from the Registry are decoded from JSON
+		config := DefaultParquetConfig()
+		err := ApplyWriterSettings(config, map[string]interface{}{
+			SettingCompressionLevel:  float64(1),
+			SettingColumnCompression: map[string]interface{}{"payload": "zstd"},
+			SettingRowGroupSize:      float64(500),
+		})
+		require.NoError(t, err)
+		assert.Equal(t, map[string]string{"payload": "zstd"}, config.ColumnCompression)
+		assert.Equal(t, int64(500), config.RowGroupSize)
+	})
+
+	t.Run("RejectsInvalidSettings", func(t *testing.T) {
+		for _, settings := range []map[string]interface{}{
+			{SettingCompression: 3},
+			{SettingColumnCompression: "payload"},
+			{SettingColumnCompressionLevel: "payload:high"},
+			{SettingDictionary: "sometimes"},
+			{SettingRowGroupSize: 0},
+			{SettingDataPageSize: 1.5},
+		} {
+			err := ApplyWriterSettings(DefaultParquetConfig(), settings)
+			require.Error(t, err, "settings %v", settings)
+			assert.Equal(t, ParquetWriterInvalidSetting.String(), errors.GetCode(err))
+		}
+	})
+
+	t.Run("RejectsInvalidCodecs", func(t *testing.T) {
+		err := ApplyWriterSettings(DefaultParquetConfig(), map[string]interface{}{SettingColumnCompression: "payload:rar"})
+		require.Error(t, err)
+		assert.Equal(t, ParquetCompressionUnsupportedType.String(), errors.GetCode(err))
+
+		err = ApplyWriterSettings(DefaultParquetConfig(), map[string]interface{}{
+			SettingColumnCompression:      "payload:gzip",
+			SettingColumnCompressionLevel: "payload:15",
+		})
+		require.Error(t, err)
+		assert.Equal(t, ParquetCompressionInvalidLevel.String(), errors.GetCode(err))
+	})
+}
+
+func TestValidateWriterColumns(t *testing.T) {
+	schema := arrow.NewSchema([]arrow.Field{
+		{Name: "id", Type: arrow.PrimitiveTypes.Int64},
+	}, nil)
+
+	config := DefaultParquetConfig()
+	config.ColumnCompression = map[string]string{"id": "zstd"}
+	assert.NoError(t, ValidateWriterColumns(config, schema))
 
-	// It should be of type map[string]compress.Compression
-	// For the test, we just verify it exists and is not nil
+	config.ColumnDictionary = map[string]bool{"missing": false}
+	err := ValidateWriterColumns(config, schema)
+	require.Error(t, err)
+	assert.Equal(t, ParquetWriterUnknownColumn.String(), errors.GetCode(err))
 }
 
 func TestCreateWriterProperties_InvalidConfig(t *testing.T) {
@@ -0,0 +1,117 @@
+package parquet
+
+import (
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/apache/iceberg-go"
+	"github.com/gear6io/ranger/pkg/errors"
+)
+
+// Package-specific error codes for value conversion
+var (
+	ParquetConvertInvalidValue    = errors.MustNewCode("parquet.convert_invalid_value")
+	ParquetConvertUnsupportedType = errors.MustNewCode("parquet.convert_unsupported_type")
+)
+
+// timestampLayouts are the textual timestamp layouts accepted by ParseTextValue
+var timestampLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02 15:04:05.999999999Z07:00",
+	"2006-01-02 15:04:05.999999999Z07",
+	"2006-01-02 15:04:05.999999999",
+	"2006-01-02T15:04:05.999999999",
+	"2006-01-02",
+}
+
+// ParseTextValue converts the textual representation of a value (as found in CSV,
+// pgwire text format, etc.) into the Go value ValidateData expects for icebergType. Lists
+// are read from JSON or PostgreSQL array literals, maps and structs from JSON objects.
+func ParseTextValue(text string, icebergType iceberg.Type) (interface{}, error) {
+	switch icebergType {
+	case iceberg.PrimitiveTypes.Bool:
+		switch strings.ToLower(strings.TrimSpace(text)) {
+		case "t", "true", "y", "yes", "on", "1":
+			return true, nil
+		case "f", "false", "n", "no", "off", "0":
+			return false, nil
+		}
+		return nil, invalidTextValue(text, icebergType, nil)
+	case iceberg.PrimitiveTypes.Int32:
+		v, err := strconv.ParseInt(strings.TrimSpace(text), 10, 32)
+		if err != nil {
+			return nil, invalidTextValue(text, icebergType, err)
+		}
+		return int32(v), nil
+	case iceberg.PrimitiveTypes.Int64:
+		v, err := strconv.ParseInt(strings.TrimSpace(text), 10, 64)
+		if err != nil {
+			return nil, invalidTextValue(text, icebergType, err)
+		}
+		return v, nil
+	case iceberg.PrimitiveTypes.Float32:
+		v, err := strconv.ParseFloat(strings.TrimSpace(text), 32)
+		if err != nil {
+			return nil, invalidTextValue(text, icebergType, err)
+		}
+		return float32(v), nil
+	case iceberg.PrimitiveTypes.Float64:
+		v, err := strconv.ParseFloat(strings.TrimSpace(text), 64)
+		if err != nil {
+			return nil, invalidTextValue(text, icebergType, err)
+		}
+		return v, nil
+	case iceberg.PrimitiveTypes.String, iceberg.PrimitiveTypes.UUID, iceberg.PrimitiveTypes.Time:
+		return text, nil
+	case iceberg.PrimitiveTypes.Binary:
+		// PostgreSQL hex bytea output (\x...) is accepted, anything else is taken verbatim
+		if strings.HasPrefix(text, `\x`) {
+			v, err := hex.DecodeString(text[2:])
+			if err != nil {
+				return nil, invalidTextValue(text, icebergType, err)
+			}
+			return v, nil
+		}
+		return []byte(text), nil
+	case iceberg.PrimitiveTypes.Date:
+		v, err := time.Parse("2006-01-02", strings.TrimSpace(text))
+		if err != nil {
+			return nil, invalidTextValue(text, icebergType, err)
+		}
+		return v, nil
+	case iceberg.PrimitiveTypes.Timestamp, iceberg.PrimitiveTypes.TimestampTz:
+		trimmed := strings.TrimSpace(text)
+		for _, layout := range timestampLayouts {
+			if v, err := time.Parse(layout, trimmed); err == nil {
+				return v, nil
+			}
+		}
+		return nil, invalidTextValue(text, icebergType, nil)
+	}
+
+	switch icebergType.(type) {
+	case iceberg.DecimalType:
+		// Decimals are kept in their textual form to avoid losing precision
+		trimmed := strings.TrimSpace(text)
+		if _, err := strconv.ParseFloat(trimmed, 64); err != nil {
+			return nil, invalidTextValue(text, icebergType, err)
+		}
+		return trimmed, nil
+	case iceberg.FixedType:
+		return []byte(text), nil
+	case *iceberg.ListType, *iceberg.MapType, *iceberg.StructType:
+		return parseNestedText(text, icebergType)
+	}
+
+	return nil, errors.New(ParquetConvertUnsupportedType, "unsupported type for text conversion", nil).
+		AddContext("type", icebergType.String())
+}
+
+// invalidTextValue builds the error returned when text cannot be parsed as icebergType
+func invalidTextValue(text string, icebergType iceberg.Type, cause error) error {
+	return errors.New(ParquetConvertInvalidValue, fmt.Sprintf("invalid input syntax for type %s: %q", icebergType.String(), text), cause).
+		AddContext("type", icebergType.String())
+}
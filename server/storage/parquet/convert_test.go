@@ -0,0 +1,51 @@
+package parquet
+
+import (
+	"testing"
+	"time"
+
+	"github.com/apache/iceberg-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseTextValue(t *testing.T) {
+	tests := []struct {
+		name     string
+		text     string
+		typ      iceberg.Type
+		expected interface{}
+	}{
+		{"Bool", "t", iceberg.PrimitiveTypes.Bool, true},
+		{"Int32", " 42 ", iceberg.PrimitiveTypes.Int32, int32(42)},
+		{"Int64", "-9000000000", iceberg.PrimitiveTypes.Int64, int64(-9000000000)},
+		{"Float64", "1.5", iceberg.PrimitiveTypes.Float64, 1.5},
+		{"String", " keep ", iceberg.PrimitiveTypes.String, " keep "},
+		{"Binary hex", `\x6869`, iceberg.PrimitiveTypes.Binary, []byte("hi")},
+		{"Date", "2024-02-29", iceberg.PrimitiveTypes.Date, time.Date(2024, 2, 29, 0, 0, 0, 0, time.UTC)},
+		{"Timestamp", "2024-02-29 10:11:12.5", iceberg.PrimitiveTypes.Timestamp, time.Date(2024, 2, 29, 10, 11, 12, 500000000, time.UTC)},
+		{"Decimal", "12.340", iceberg.DecimalTypeOf(10, 3), "12.340"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			value, err := ParseTextValue(tt.text, tt.typ)
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, value)
+		})
+	}
+}
+
+func TestParseTextValueInvalid(t *testing.T) {
+	_, err := ParseTextValue("abc", iceberg.PrimitiveTypes.Int32)
+	assert.Error(t, err)
+
+	_, err = ParseTextValue("3000000000", iceberg.PrimitiveTypes.Int32)
+	assert.Error(t, err)
+
+	_, err = ParseTextValue("maybe", iceberg.PrimitiveTypes.Bool)
+	assert.Error(t, err)
+
+	_, err = ParseTextValue("x", &iceberg.ListType{Element: iceberg.PrimitiveTypes.Int32})
+	assert.Error(t, err)
+}
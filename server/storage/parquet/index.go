@@ -0,0 +1,478 @@
+package parquet
+
+import (
+	"bytes"
+	"cmp"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/apache/arrow-go/v18/arrow"
+	"github.com/apache/arrow-go/v18/arrow/array"
+	pq "github.com/apache/arrow-go/v18/parquet"
+	"github.com/apache/arrow-go/v18/parquet/file"
+	"github.com/apache/arrow-go/v18/parquet/metadata"
+	"github.com/gear6io/ranger/pkg/errors"
+)
+
+// Table settings requesting indexes in data files, given with CREATE TABLE ... SETTINGS
+const (
+	SettingBloomFilterColumns = "bloom_filter_columns" // comma-separated column names
+	SettingBloomFilterFPP     = "bloom_filter_fpp"
+	SettingBloomFilterNDV     = "bloom_filter_ndv"
+	SettingPageIndex          = "page_index"
+)
+
+// Package-specific error codes for data file indexes
+var (
+	ParquetIndexInvalidSetting = errors.MustNewCode("parquet.index_invalid_setting")
+	ParquetIndexUnknownColumn  = errors.MustNewCode("parquet.index_unknown_column")
+	ParquetIndexInvalidValue   = errors.MustNewCode("parquet.index_invalid_value")
+	ParquetIndexReadFailed     = errors.MustNewCode("parquet.index_read_failed")
+)
+
+// ApplyIndexSettings sets the indexes requested by table settings on config; other settings
+// are left alone
+func ApplyIndexSettings(config *ParquetConfig, settings map[string]interface{}) error {
+	if value, ok := settings[SettingBloomFilterColumns]; ok {
+		columns, err := settingColumns(value)
+		if err != nil {
+			return err
+		}
+		config.BloomFilterColumns = columns
+	}
+
+	if value, ok := settings[SettingBloomFilterFPP]; ok {
+		fpp, ok := settingNumber(value)
+		if !ok || fpp <= 0 || fpp >= 1 {
+			return errors.New(ParquetIndexInvalidSetting, "bloom_filter_fpp must be a number between 0 and 1", nil).AddContext("bloom_filter_fpp", value)
+		}
+		config.BloomFilterFPP = fpp
+	}
+
+	if value, ok := settings[SettingBloomFilterNDV]; ok {
+		ndv, ok := settingNumber(value)
+		if !ok || ndv < 0 || ndv != math.Trunc(ndv) || ndv > math.MaxUint32 {
+			return errors.New(ParquetIndexInvalidSetting, "bloom_filter_ndv must be a whole number of distinct values", nil).AddContext("bloom_filter_ndv", value)
+		}
+		config.BloomFilterNDV = int64(ndv)
+	}
+
+	if value, ok := settings[SettingPageIndex]; ok {
+		enabled, ok := settingBool(value)
+		if !ok {
+			return errors.New(ParquetIndexInvalidSetting, "page_index must be true or false", nil).AddContext("page_index", value)
+		}
+		config.PageIndex = enabled
+	}
+
+	return nil
+}
+
+// ValidateIndexColumns checks that every bloom filter column of config is a column of the
+// table that a bloom filter can index
+func ValidateIndexColumns(config *ParquetConfig, schema *arrow.Schema) error {
+	for _, column := range config.BloomFilterColumns {
+		indices := schema.FieldIndices(column)
+		if len(indices) == 0 {
+			return errors.New(ParquetIndexUnknownColumn, "bloom filter column does not exist in table", nil).AddContext("column", column)
+		}
+		if !bloomFilterType(schema.Field(indices[0]).Type) {
+			return errors.New(ParquetIndexInvalidSetting, "bloom filters index only integer, floating point, date, time, timestamp, string and binary columns", nil).
+				AddContext("column", column).
+				AddContext("data_type", schema.Field(indices[0]).Type.String())
+		}
+	}
+	return nil
+}
+
+// IndexWriterProperties returns the writer properties that write the indexes of config into
+// data files with schema
+func IndexWriterProperties(config *ParquetConfig, schema *arrow.Schema) []pq.WriterProperty {
+	props := []pq.WriterProperty{pq.WithPageIndexEnabled(config.PageIndex)}
+	for _, column := range IndexedColumns(config, schema) {
+		props = append(props, pq.WithBloomFilterEnabledFor(column, true))
+		if config.BloomFilterFPP > 0 {
+			props = append(props, pq.WithBloomFilterFPPFor(column, config.BloomFilterFPP))
+		}
+		if config.BloomFilterNDV > 0 {
+			props = append(props, pq.WithBloomFilterNDVFor(column, config.BloomFilterNDV))
+		} else {
+			props = append(props, pq.WithAdaptiveBloomFilterEnabledFor(column, true))
+		}
+	}
+	return props
+}
+
+// IndexedColumns returns the bloom filter columns of config present in schema, which the
+// data files written with it carry bloom filters for
+func IndexedColumns(config *ParquetConfig, schema *arrow.Schema) []string {
+	columns := make([]string, 0, len(config.BloomFilterColumns))
+	for _, column := range config.BloomFilterColumns {
+		if indices := schema.FieldIndices(column); len(indices) > 0 && bloomFilterType(schema.Field(indices[0]).Type) {
+			columns = append(columns, column)
+		}
+	}
+	return columns
+}
+
+// EqualityProbe is an equality predicate on a column of a table, with its value stated in
+// the type the column is stored with, so that it can be looked up in the indexes of data
+// files and compared with their rows
+type EqualityProbe struct {
+	Column string
+	value  interface{} // int32, int64, float32, float64 or pq.ByteArray
+}
+
+// NewEqualityProbe returns the predicate column = value on a table with schema
+func NewEqualityProbe(schema *arrow.Schema, column string, value interface{}) (*EqualityProbe, error) {
+	indices := schema.FieldIndices(column)
+	if len(indices) == 0 {
+		return nil, errors.New(ParquetIndexUnknownColumn, "column does not exist in table", nil).AddContext("column", column)
+	}
+	dataType := schema.Field(indices[0]).Type
+	if !bloomFilterType(dataType) {
+		return nil, errors.New(ParquetIndexInvalidValue, "column type cannot be compared for equality", nil).
+			AddContext("column", column).
+			AddContext("data_type", dataType.String())
+	}
+
+	stored, err := storedValue(dataType, value)
+	if err != nil {
+		return nil, errors.AddContext(err, "column", column)
+	}
+	return &EqualityProbe{Column: column, value: stored}, nil
+}
+
+// MatchingRowGroups returns the row groups of a data file that may hold rows matching the
+// predicate. Row groups are skipped when the bloom filter of the column, or the minimum and
+// maximum of every page of it in the column index, rule the value out; files without these
+// indexes keep every row group.
+func (p *EqualityProbe) MatchingRowGroups(reader *file.Reader) ([]int, error) {
+	column := reader.MetaData().Schema.ColumnIndexByName(p.Column)
+	if column < 0 {
+		return nil, errors.New(ParquetIndexUnknownColumn, "column does not exist in data file", nil).AddContext("column", p.Column)
+	}
+
+	matching := make([]int, 0, reader.NumRowGroups())
+	for rowGroup := 0; rowGroup < reader.NumRowGroups(); rowGroup++ {
+		skip, err := p.skipRowGroup(reader, rowGroup, column)
+		if err != nil {
+			return nil, errors.AddContext(err, "column", p.Column).AddContext("row_group", rowGroup)
+		}
+		if !skip {
+			matching = append(matching, rowGroup)
+		}
+	}
+	return matching, nil
+}
+
+// skipRowGroup reports whether the indexes of the row group rule the value out
+func (p *EqualityProbe) skipRowGroup(reader *file.Reader, rowGroup, column int) (bool, error) {
+	bloomFilters, err := reader.GetBloomFilterReader().RowGroup(rowGroup)
+	if err != nil {
+		return false, errors.New(ParquetIndexReadFailed, "failed to read bloom filters", err)
+	}
+	filter, err := bloomFilters.GetColumnBloomFilter(column)
+	if err != nil {
+		return false, errors.New(ParquetIndexReadFailed, "failed to read bloom filter", err)
+	}
+	if filter != nil && !p.inBloomFilter(filter) {
+		return true, nil
+	}
+
+	pageIndexes, err := reader.GetPageIndexReader().RowGroup(rowGroup)
+	if err != nil {
+		return false, errors.New(ParquetIndexReadFailed, "failed to read page index", err)
+	}
+	if pageIndexes == nil {
+		return false, nil
+	}
+	index, err := pageIndexes.GetColumnIndex(column)
+	if err != nil {
+		return false, errors.New(ParquetIndexReadFailed, "failed to read column index", err)
+	}
+	return index != nil && !p.inColumnIndex(index), nil
+}
+
+// inBloomFilter reports whether the bloom filter may hold the value
+func (p *EqualityProbe) inBloomFilter(filter metadata.BloomFilter) bool {
+	switch v := p.value.(type) {
+	case int32:
+		return (&metadata.TypedBloomFilter[int32]{BloomFilter: filter}).Check(v)
+	case int64:
+		return (&metadata.TypedBloomFilter[int64]{BloomFilter: filter}).Check(v)
+	case float32:
+		return (&metadata.TypedBloomFilter[float32]{BloomFilter: filter}).Check(v)
+	case float64:
+		return (&metadata.TypedBloomFilter[float64]{BloomFilter: filter}).Check(v)
+	case pq.ByteArray:
+		return (&metadata.TypedBloomFilter[pq.ByteArray]{BloomFilter: filter}).Check(v)
+	}
+	return true
+}
+
+// inColumnIndex reports whether a page in the column index may hold the value
+func (p *EqualityProbe) inColumnIndex(index metadata.ColumnIndex) bool {
+	switch v := p.value.(type) {
+	case int32:
+		if typed, ok := index.(*metadata.TypedColumnIndex[int32]); ok {
+			return inPages(typed, cmp.Compare[int32], v)
+		}
+	case int64:
+		if typed, ok := index.(*metadata.TypedColumnIndex[int64]); ok {
+			return inPages(typed, cmp.Compare[int64], v)
+		}
+	case float32:
+		if typed, ok := index.(*metadata.TypedColumnIndex[float32]); ok {
+			return inPages(typed, cmp.Compare[float32], v)
+		}
+	case float64:
+		if typed, ok := index.(*metadata.TypedColumnIndex[float64]); ok {
+			return inPages(typed, cmp.Compare[float64], v)
+		}
+	case pq.ByteArray:
+		if typed, ok := index.(*metadata.TypedColumnIndex[pq.ByteArray]); ok {
+			return inPages(typed, func(a, b pq.ByteArray) int { return bytes.Compare(a, b) }, v)
+		}
+	}
+	return true
+}
+
+// inPages reports whether value lies between the minimum and maximum of a page with values
+func inPages[T pq.ColumnTypes](index *metadata.TypedColumnIndex[T], compare func(a, b T) int, value T) bool {
+	minValues, maxValues := index.MinValues(), index.MaxValues()
+	for _, page := range index.NonNullPageIndices() {
+		if compare(minValues[page], value) <= 0 && compare(value, maxValues[page]) <= 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// Matches reports whether a row of column, the probed column of a record, equals the value
+func (p *EqualityProbe) Matches(column arrow.Array, row int) bool {
+	if column.IsNull(row) {
+		return false
+	}
+	switch c := column.(type) {
+	case *array.Int8:
+		return p.value == int32(c.Value(row))
+	case *array.Int16:
+		return p.value == int32(c.Value(row))
+	case *array.Int32:
+		return p.value == c.Value(row)
+	case *array.Date32:
+		return p.value == int32(c.Value(row))
+	case *array.Time32:
+		return p.value == int32(c.Value(row))
+	case *array.Int64:
+		return p.value == c.Value(row)
+	case *array.Time64:
+		return p.value == int64(c.Value(row))
+	case *array.Timestamp:
+		return p.value == int64(c.Value(row))
+	case *array.Float32:
+		return p.value == c.Value(row)
+	case *array.Float64:
+		return p.value == c.Value(row)
+	case *array.String:
+		value, ok := p.value.(pq.ByteArray)
+		return ok && string(value) == c.Value(row)
+	case *array.LargeString:
+		value, ok := p.value.(pq.ByteArray)
+		return ok && string(value) == c.Value(row)
+	case *array.Binary:
+		value, ok := p.value.(pq.ByteArray)
+		return ok && bytes.Equal(value, c.Value(row))
+	case *array.LargeBinary:
+		value, ok := p.value.(pq.ByteArray)
+		return ok && bytes.Equal(value, c.Value(row))
+	}
+	return false
+}
+
+// bloomFilterType reports whether columns of the type are stored as values a bloom filter
+// and column index can look up; unsigned integers, booleans, times and timestamps in seconds,
+// which are rescaled when stored, and nested types are not
+func bloomFilterType(dataType arrow.DataType) bool {
+	switch t := dataType.(type) {
+	case *arrow.Int8Type, *arrow.Int16Type, *arrow.Int32Type, *arrow.Int64Type,
+		*arrow.Float32Type, *arrow.Float64Type,
+		*arrow.Date32Type, *arrow.Time64Type,
+		*arrow.StringType, *arrow.LargeStringType, *arrow.BinaryType, *arrow.LargeBinaryType:
+		return true
+	case *arrow.Time32Type:
+		return t.Unit != arrow.Second
+	case *arrow.TimestampType:
+		return t.Unit != arrow.Second
+	}
+	return false
+}
+
+// storedValue states value in the type columns of dataType are stored with in data files
+func storedValue(dataType arrow.DataType, value interface{}) (interface{}, error) {
+	invalid := func(cause error) error {
+		return errors.New(ParquetIndexInvalidValue, "value cannot be compared with the column", cause).
+			AddContext("value", value).
+			AddContext("data_type", dataType.String())
+	}
+
+	switch t := dataType.(type) {
+	case *arrow.Int8Type, *arrow.Int16Type, *arrow.Int32Type, *arrow.Time32Type:
+		n, ok := integerValue(value)
+		if !ok || n < math.MinInt32 || n > math.MaxInt32 {
+			return nil, invalid(nil)
+		}
+		return int32(n), nil
+	case *arrow.Date32Type:
+		if text, ok := value.(string); ok {
+			date, err := time.Parse("2006-01-02", text)
+			if err != nil {
+				return nil, invalid(err)
+			}
+			return int32(arrow.Date32FromTime(date)), nil
+		}
+		n, ok := integerValue(value)
+		if !ok || n < math.MinInt32 || n > math.MaxInt32 {
+			return nil, invalid(nil)
+		}
+		return int32(n), nil
+	case *arrow.TimestampType:
+		if text, ok := value.(string); ok {
+			ts, err := arrow.TimestampFromString(text, t.Unit)
+			if err != nil {
+				return nil, invalid(err)
+			}
+			return int64(ts), nil
+		}
+		n, ok := integerValue(value)
+		if !ok {
+			return nil, invalid(nil)
+		}
+		return n, nil
+	case *arrow.Int64Type, *arrow.Time64Type:
+		n, ok := integerValue(value)
+		if !ok {
+			return nil, invalid(nil)
+		}
+		return n, nil
+	case *arrow.Float32Type:
+		f, ok := settingNumber(value)
+		if !ok {
+			return nil, invalid(nil)
+		}
+		return float32(f), nil
+	case *arrow.Float64Type:
+		f, ok := settingNumber(value)
+		if !ok {
+			return nil, invalid(nil)
+		}
+		return f, nil
+	case *arrow.StringType, *arrow.LargeStringType, *arrow.BinaryType, *arrow.LargeBinaryType:
+		switch v := value.(type) {
+		case string:
+			return pq.ByteArray(v), nil
+		case []byte:
+			return pq.ByteArray(v), nil
+		}
+		return nil, invalid(nil)
+	}
+	return nil, invalid(nil)
+}
+
+// integerValue returns value as a whole number
+func integerValue(value interface{}) (int64, bool) {
+	switch v := value.(type) {
+	case int:
+		return int64(v), true
+	case int8:
+		return int64(v), true
+	case int16:
+		return int64(v), true
+	case int32:
+		return int64(v), true
+	case int64:
+		return v, true
+	case uint8:
+		return int64(v), true
+	case uint16:
+		return int64(v), true
+	case uint32:
+		return int64(v), true
+	case uint64:
+		if v > math.MaxInt64 {
+			return 0, false
+		}
+		return int64(v), true
+	case float64:
+		if v != math.Trunc(v) || v < math.MinInt64 || v >= math.MaxInt64 {
+			return 0, false
+		}
+		return int64(v), true
+	case string:
+		n, err := strconv.ParseInt(v, 10, 64)
+		return n, err == nil
+	}
+	return 0, false
+}
+
+// settingNumber returns a numeric setting or value as a float64
+func settingNumber(value interface{}) (float64, bool) {
+	switch v := value.(type) {
+	case float64:
+		return v, true
+	case float32:
+		return float64(v), true
+	case string:
+		f, err := strconv.ParseFloat(v, 64)
+		return f, err == nil
+	}
+	if n, ok := integerValue(value); ok {
+		return float64(n), true
+	}
+	return 0, false
+}
+
+// settingBool returns a setting given as true or false, possibly quoted
+func settingBool(value interface{}) (bool, bool) {
+	switch v := value.(type) {
+	case bool:
+		return v, true
+	case string:
+		enabled, err := strconv.ParseBool(v)
+		return enabled, err == nil
+	}
+	return false, false
+}
+
+// settingColumns returns the columns named by a setting, given as a comma-separated string
+// or, once stored as JSON, possibly a list
+func settingColumns(value interface{}) ([]string, error) {
+	var names []string
+	switch v := value.(type) {
+	case string:
+		names = strings.Split(v, ",")
+	case []string:
+		names = v
+	case []interface{}:
+		for _, name := range v {
+			text, ok := name.(string)
+			if !ok {
+				return nil, errors.New(ParquetIndexInvalidSetting, "bloom_filter_columns must name columns", nil).AddContext("bloom_filter_columns", value)
+			}
+			names = append(names, text)
+		}
+	default:
+		return nil, errors.New(ParquetIndexInvalidSetting, "bloom_filter_columns must name columns", nil).AddContext("bloom_filter_columns", value)
+	}
+
+	columns := make([]string, 0, len(names))
+	for _, name := range names {
+		if name = strings.TrimSpace(name); name != "" {
+			columns = append(columns, name)
+		}
+	}
+	return columns, nil
+}
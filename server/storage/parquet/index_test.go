@@ -0,0 +1,181 @@
+package parquet
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+
+	"github.com/apache/arrow-go/v18/arrow"
+	"github.com/apache/arrow-go/v18/arrow/array"
+	"github.com/apache/arrow-go/v18/arrow/memory"
+	pq "github.com/apache/arrow-go/v18/parquet"
+	"github.com/apache/arrow-go/v18/parquet/file"
+	"github.com/apache/arrow-go/v18/parquet/pqarrow"
+	"github.com/gear6io/ranger/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestApplyIndexSettings(t *testing.T) {
+	t.Run("ParsesSettings", func(t *testing.T) {
+		config := DefaultParquetConfig()
+		err := ApplyIndexSettings(config, map[string]interface{}{
+			SettingBloomFilterColumns: "user_id, request_id",
+			SettingBloomFilterFPP:     0.05,
+			SettingBloomFilterNDV:     1000,
+			SettingPageIndex:          true,
+			"hot_tier_bytes":          10,
+		})
+		require.NoError(t, err)
+		assert.Equal(t, []string{"user_id", "request_id"}, config.BloomFilterColumns)
+		assert.Equal(t, 0.05, config.BloomFilterFPP)
+		assert.Equal(t, int64(1000), config.BloomFilterNDV)
+		assert.True(t, config.PageIndex)
+	})
+
+	t.Run("ParsesStoredSettings", func(t *testing.T) {
+		// Settings read back from the Registry are decoded from JSON
+		config := DefaultParquetConfig()
+		err := ApplyIndexSettings(config, map[string]interface{}{
+			SettingBloomFilterColumns: []interface{}{"user_id"},
+			SettingBloomFilterNDV:     float64(1000),
+			SettingPageIndex:          "false",
+		})
+		require.NoError(t, err)
+		assert.Equal(t, []string{"user_id"}, config.BloomFilterColumns)
+		assert.Equal(t, int64(1000), config.BloomFilterNDV)
+		assert.False(t, config.PageIndex)
+	})
+
+	t.Run("RejectsInvalidSettings", func(t *testing.T) {
+		for _, settings := range []map[string]interface{}{
+			{SettingBloomFilterFPP: 1.5},
+			{SettingBloomFilterNDV: -1},
+			{SettingPageIndex: 1},
+			{SettingBloomFilterColumns: 7},
+		} {
+			err := ApplyIndexSettings(DefaultParquetConfig(), settings)
+			require.Error(t, err, "settings %v", settings)
+			assert.Equal(t, ParquetIndexInvalidSetting.String(), errors.GetCode(err))
+		}
+	})
+}
+
+func TestValidateIndexColumns(t *testing.T) {
+	schema := arrow.NewSchema([]arrow.Field{
+		{Name: "user_id", Type: arrow.PrimitiveTypes.Int64},
+		{Name: "active", Type: arrow.FixedWidthTypes.Boolean},
+	}, nil)
+
+	config := DefaultParquetConfig()
+	config.BloomFilterColumns = []string{"user_id"}
+	assert.NoError(t, ValidateIndexColumns(config, schema))
+
+	config.BloomFilterColumns = []string{"missing"}
+	err := ValidateIndexColumns(config, schema)
+	require.Error(t, err)
+	assert.Equal(t, ParquetIndexUnknownColumn.String(), errors.GetCode(err))
+
+	config.BloomFilterColumns = []string{"active"}
+	err = ValidateIndexColumns(config, schema)
+	require.Error(t, err)
+	assert.Equal(t, ParquetIndexInvalidSetting.String(), errors.GetCode(err))
+}
+
+func TestEqualityProbe(t *testing.T) {
+	schema := arrow.NewSchema([]arrow.Field{
+		{Name: "user_id", Type: arrow.PrimitiveTypes.Int64, Nullable: false},
+		{Name: "request_id", Type: arrow.BinaryTypes.String, Nullable: true},
+	}, nil)
+
+	// writeFile writes three row groups holding user IDs 0-99, 100-199 and 200-299
+	writeFile := func(t *testing.T, config *ParquetConfig) *file.Reader {
+		var buf bytes.Buffer
+		props := pq.NewWriterProperties(IndexWriterProperties(config, schema)...)
+		writer, err := pqarrow.NewFileWriter(schema, &buf, props, pqarrow.DefaultWriterProps())
+		require.NoError(t, err)
+		for group := 0; group < 3; group++ {
+			builder := array.NewRecordBuilder(memory.DefaultAllocator, schema)
+			for i := 0; i < 100; i++ {
+				id := group*100 + i
+				builder.Field(0).(*array.Int64Builder).Append(int64(id))
+				builder.Field(1).(*array.StringBuilder).Append(fmt.Sprintf("req-%d", id))
+			}
+			record := builder.NewRecord()
+			require.NoError(t, writer.Write(record))
+			record.Release()
+			builder.Release()
+		}
+		require.NoError(t, writer.Close())
+
+		reader, err := file.NewParquetReader(bytes.NewReader(buf.Bytes()))
+		require.NoError(t, err)
+		t.Cleanup(func() { reader.Close() })
+		return reader
+	}
+
+	t.Run("SkipsRowGroupsByBloomFilter", func(t *testing.T) {
+		config := DefaultParquetConfig()
+		config.BloomFilterColumns = []string{"request_id"}
+		reader := writeFile(t, config)
+
+		probe, err := NewEqualityProbe(schema, "request_id", "req-150")
+		require.NoError(t, err)
+		rowGroups, err := probe.MatchingRowGroups(reader)
+		require.NoError(t, err)
+		assert.Equal(t, []int{1}, rowGroups)
+
+		probe, err = NewEqualityProbe(schema, "request_id", "req-missing")
+		require.NoError(t, err)
+		rowGroups, err = probe.MatchingRowGroups(reader)
+		require.NoError(t, err)
+		assert.Empty(t, rowGroups)
+	})
+
+	t.Run("SkipsRowGroupsByPageIndex", func(t *testing.T) {
+		config := DefaultParquetConfig()
+		config.PageIndex = true
+		reader := writeFile(t, config)
+
+		probe, err := NewEqualityProbe(schema, "user_id", 250)
+		require.NoError(t, err)
+		rowGroups, err := probe.MatchingRowGroups(reader)
+		require.NoError(t, err)
+		assert.Equal(t, []int{2}, rowGroups)
+	})
+
+	t.Run("KeepsRowGroupsWithoutIndexes", func(t *testing.T) {
+		reader := writeFile(t, DefaultParquetConfig())
+
+		probe, err := NewEqualityProbe(schema, "user_id", 250)
+		require.NoError(t, err)
+		rowGroups, err := probe.MatchingRowGroups(reader)
+		require.NoError(t, err)
+		assert.Equal(t, []int{0, 1, 2}, rowGroups)
+	})
+
+	t.Run("MatchesRows", func(t *testing.T) {
+		builder := array.NewInt64Builder(memory.DefaultAllocator)
+		defer builder.Release()
+		builder.AppendValues([]int64{7, 8}, nil)
+		builder.AppendNull()
+		column := builder.NewArray()
+		defer column.Release()
+
+		probe, err := NewEqualityProbe(schema, "user_id", "8")
+		require.NoError(t, err)
+		assert.False(t, probe.Matches(column, 0))
+		assert.True(t, probe.Matches(column, 1))
+		assert.False(t, probe.Matches(column, 2))
+	})
+
+	t.Run("RejectsInvalidPredicates", func(t *testing.T) {
+		_, err := NewEqualityProbe(schema, "missing", 1)
+		require.Error(t, err)
+		assert.Equal(t, ParquetIndexUnknownColumn.String(), errors.GetCode(err))
+
+		_, err = NewEqualityProbe(schema, "user_id", "not a number")
+		require.Error(t, err)
+		assert.Equal(t, ParquetIndexInvalidValue.String(), errors.GetCode(err))
+	})
+}
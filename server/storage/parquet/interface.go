@@ -80,6 +80,13 @@ type FileInfo struct {
 	Modified    int64 // Unix timestamp
 	Compression string
 	Version     string
+	Checksum    string // Hex SHA-256 of the file contents, set for files written by this process
+
+	// Indexes written into the file, set for files written by this process
+	BloomFilterColumns []string
+	PageIndex          bool
+
+	Sorted bool // Rows are ordered by the table sort key
 }
 
 // ValidationError represents a validation error
@@ -109,8 +116,17 @@ type ParquetConfig struct {
 	Compression      string // none, snappy, gzip, brotli, lz4, zstd
 	CompressionLevel int    // compression level (1-9 for gzip, 1-22 for zstd)
 
-	// Column-specific compression (map of column name to compression type)
-	ColumnCompression map[string]string
+	// Column-specific compression (map of column name to compression type and level)
+	ColumnCompression      map[string]string
+	ColumnCompressionLevel map[string]int
+
+	// Encoding settings
+	Dictionary       bool            // dictionary-encode columns
+	ColumnDictionary map[string]bool // per-column override of Dictionary
+
+	// Layout settings; 0 keeps the writer defaults
+	RowGroupSize int64 // maximum rows per row group
+	DataPageSize int64 // bytes
 
 	// Performance settings
 	EnableStats    bool
@@ -119,6 +135,15 @@ type ParquetConfig struct {
 	// File settings
 	MaxFileSize     int64 // bytes
 	RotationTimeout int64 // seconds
+
+	// Sort settings (columns each data file is sorted by, ascending with nulls first)
+	SortOrder []string
+
+	// Index settings
+	BloomFilterColumns []string // columns with a bloom filter in every row group
+	BloomFilterFPP     float64  // false positive probability of the bloom filters
+	BloomFilterNDV     int64    // expected distinct values per row group; 0 sizes filters adaptively
+	PageIndex          bool     // write column and offset page indexes
 }
 
 // DefaultParquetConfig returns default configuration
@@ -131,9 +156,11 @@ func DefaultParquetConfig() *ParquetConfig {
 		Compression:       "snappy",
 		CompressionLevel:  1,
 		ColumnCompression: make(map[string]string),
+		Dictionary:        true,
 		EnableStats:       true,
 		MemoryPoolSize:    100 << 20, // 100MB
 		MaxFileSize:       50 << 30,  // 50GB
 		RotationTimeout:   300,       // 5 minutes
+		BloomFilterFPP:    0.01,
 	}
 }
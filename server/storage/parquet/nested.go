@@ -0,0 +1,603 @@
+package parquet
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/apache/arrow-go/v18/arrow"
+	"github.com/apache/arrow-go/v18/arrow/array"
+	"github.com/apache/arrow-go/v18/arrow/memory"
+	"github.com/apache/iceberg-go"
+	"github.com/gear6io/ranger/pkg/errors"
+	"github.com/gear6io/ranger/server/types"
+)
+
+// Package-specific error codes for list, map and struct values
+var (
+	ParquetNestedInvalidType  = errors.MustNewCode("parquet.nested_invalid_type")
+	ParquetNestedInvalidValue = errors.MustNewCode("parquet.nested_invalid_value")
+	ParquetNestedUnknownField = errors.MustNewCode("parquet.nested_unknown_field")
+)
+
+// isNestedTypeString reports whether a registry data type is a list, map or struct type
+func isNestedTypeString(dataType string) bool {
+	lower := strings.ToLower(strings.TrimSpace(dataType))
+	return strings.HasPrefix(lower, "list<") || strings.HasPrefix(lower, "map<") || strings.HasPrefix(lower, "struct<")
+}
+
+// parseRegistryNestedType converts a list, map or struct data type from the registry to an
+// Iceberg type. Iceberg gives every element, key, value and struct field its own ID, so the
+// IDs are taken from nextID, which must start past the IDs of the table columns.
+func parseRegistryNestedType(dataType string, nextID *int) (iceberg.Type, error) {
+	parsed, err := types.ParseAndValidateType(strings.TrimSpace(dataType))
+	if err != nil {
+		return nil, errors.New(ParquetNestedInvalidType, "invalid nested data type", err).AddContext("data_type", dataType)
+	}
+	return nestedIcebergType(parsed, nextID)
+}
+
+// nestedIcebergType converts a parsed type to an Iceberg type, numbering nested fields
+func nestedIcebergType(parsed types.IcebergType, nextID *int) (iceberg.Type, error) {
+	switch t := parsed.(type) {
+	case *types.ListType:
+		elementID := takeID(nextID)
+		element, err := nestedIcebergType(t.ElementType, nextID)
+		if err != nil {
+			return nil, err
+		}
+		return &iceberg.ListType{ElementID: elementID, Element: element}, nil
+	case *types.MapType:
+		keyID, valueID := takeID(nextID), takeID(nextID)
+		key, err := nestedIcebergType(t.KeyType, nextID)
+		if err != nil {
+			return nil, err
+		}
+		value, err := nestedIcebergType(t.ValueType, nextID)
+		if err != nil {
+			return nil, err
+		}
+		return &iceberg.MapType{KeyID: keyID, KeyType: key, ValueID: valueID, ValueType: value}, nil
+	case *types.StructType:
+		// Fields of a struct are numbered before the types nested in them, as Iceberg does
+		fields := make([]iceberg.NestedField, len(t.Fields))
+		for i, field := range t.Fields {
+			fields[i] = iceberg.NestedField{ID: takeID(nextID), Name: field.Name}
+		}
+		for i, field := range t.Fields {
+			fieldType, err := nestedIcebergType(field.Type, nextID)
+			if err != nil {
+				return nil, err
+			}
+			fields[i].Type = fieldType
+		}
+		return &iceberg.StructType{FieldList: fields}, nil
+	}
+	return parseRegistryDataType(parsed.String())
+}
+
+// takeID returns the next free field ID
+func takeID(nextID *int) int {
+	*nextID++
+	return *nextID
+}
+
+// isNestedType reports whether dataType is a list, map or struct type
+func isNestedType(dataType arrow.DataType) bool {
+	switch dataType.(type) {
+	case *arrow.ListType, *arrow.MapType, *arrow.StructType:
+		return true
+	}
+	return false
+}
+
+// AppendNestedValue appends a value to builder, descending into list, map and struct
+// builders. Lists take any slice, maps any map and structs a map keyed by field name; a
+// string given for one of them is decoded as JSON first. Map keys and leaf values are
+// converted to the builder type the way ParseTextValue reads text, so values that went
+// through JSON (numbers as float64, timestamps as strings) are accepted as well.
+func AppendNestedValue(builder array.Builder, value interface{}) error {
+	if value == nil {
+		builder.AppendNull()
+		return nil
+	}
+
+	if text, ok := value.(string); ok && isNestedType(builder.Type()) {
+		decoded, err := decodeNestedJSON(text)
+		if err != nil {
+			return invalidNestedValue(builder.Type(), value, err)
+		}
+		if decoded == nil {
+			builder.AppendNull()
+			return nil
+		}
+		value = decoded
+	}
+
+	switch b := builder.(type) {
+	case *array.ListBuilder:
+		elements, ok := sliceValues(value)
+		if !ok {
+			return invalidNestedValue(b.Type(), value, nil)
+		}
+		b.Append(true)
+		for _, element := range elements {
+			if err := AppendNestedValue(b.ValueBuilder(), element); err != nil {
+				return err
+			}
+		}
+	case *array.MapBuilder:
+		entries := reflect.ValueOf(value)
+		if entries.Kind() != reflect.Map {
+			return invalidNestedValue(b.Type(), value, nil)
+		}
+		b.Append(true)
+		for _, key := range sortedMapKeys(entries) {
+			if key.Interface() == nil {
+				return errors.New(ParquetNestedInvalidValue, "map keys cannot be null", nil).AddContext("type", b.Type().String())
+			}
+			if err := AppendNestedValue(b.KeyBuilder(), key.Interface()); err != nil {
+				return err
+			}
+			if err := AppendNestedValue(b.ItemBuilder(), entries.MapIndex(key).Interface()); err != nil {
+				return err
+			}
+		}
+	case *array.StructBuilder:
+		fields, ok := stringKeyed(value)
+		if !ok {
+			return invalidNestedValue(b.Type(), value, nil)
+		}
+		structType := b.Type().(*arrow.StructType)
+		for name := range fields {
+			if _, ok := structType.FieldIdx(name); !ok {
+				return errors.New(ParquetNestedUnknownField, fmt.Sprintf("struct has no field %s", name), nil).
+					AddContext("type", structType.String()).
+					AddContext("field", name)
+			}
+		}
+		b.Append(true)
+		for i, field := range structType.Fields() {
+			if err := AppendNestedValue(b.FieldBuilder(i), fields[field.Name]); err != nil {
+				return err
+			}
+		}
+	default:
+		return appendLeafValue(builder, value)
+	}
+	return nil
+}
+
+// appendLeafValue appends a primitive value nested in a list, map or struct
+func appendLeafValue(builder array.Builder, value interface{}) error {
+	if number, ok := value.(json.Number); ok {
+		value = number.String()
+	}
+
+	ok := true
+	switch b := builder.(type) {
+	case *array.BooleanBuilder:
+		var v bool
+		if v, ok = settingBool(value); ok {
+			b.Append(v)
+		}
+	case *array.Int32Builder:
+		var v int64
+		if v, ok = integerValue(value); ok && v >= math.MinInt32 && v <= math.MaxInt32 {
+			b.Append(int32(v))
+		} else {
+			ok = false
+		}
+	case *array.Int64Builder:
+		var v int64
+		if v, ok = integerValue(value); ok {
+			b.Append(v)
+		}
+	case *array.Float32Builder:
+		var v float64
+		if v, ok = settingNumber(value); ok {
+			b.Append(float32(v))
+		}
+	case *array.Float64Builder:
+		var v float64
+		if v, ok = settingNumber(value); ok {
+			b.Append(v)
+		}
+	case *array.StringBuilder:
+		var v string
+		if v, ok = value.(string); ok {
+			b.Append(v)
+		}
+	case *array.BinaryBuilder:
+		switch v := value.(type) {
+		case []byte:
+			b.Append(v)
+		case string:
+			b.AppendString(v)
+		default:
+			ok = false
+		}
+	case *array.Date32Builder:
+		var v time.Time
+		if v, ok = leafTime(value, "2006-01-02"); ok {
+			b.Append(arrow.Date32FromTime(v))
+		}
+	case *array.TimestampBuilder:
+		var v time.Time
+		if v, ok = leafTime(value, timestampLayouts...); ok {
+			timestamp, err := arrow.TimestampFromTime(v, b.Type().(*arrow.TimestampType).Unit)
+			if ok = err == nil; ok {
+				b.Append(timestamp)
+			}
+		}
+	case *array.Time64Builder:
+		var v time.Time
+		if v, ok = leafTime(value, "15:04:05.999999999"); ok {
+			sinceMidnight := v.Sub(v.Truncate(24 * time.Hour))
+			b.Append(arrow.Time64(sinceMidnight / b.Type().(*arrow.Time64Type).Unit.Multiplier()))
+		}
+	default:
+		return errors.New(ParquetNestedInvalidType, "unsupported nested element type", nil).AddContext("type", builder.Type().String())
+	}
+
+	if !ok {
+		return invalidNestedValue(builder.Type(), value, nil)
+	}
+	return nil
+}
+
+// leafTime returns a time value, parsing text with the first layout that matches it
+func leafTime(value interface{}, layouts ...string) (time.Time, bool) {
+	switch v := value.(type) {
+	case time.Time:
+		return v, true
+	case string:
+		return parseTime(strings.TrimSpace(v), layouts...)
+	}
+	return time.Time{}, false
+}
+
+// ArrayValue returns the value at row of column as a plain Go value: lists become
+// []interface{}, structs map[string]interface{} keyed by field name and maps
+// map[string]interface{} keyed by the text of each key, so the value marshals to JSON and
+// can be appended back with AppendNestedValue. Other values are returned as
+// GetOneForMarshal renders them.
+func ArrayValue(column arrow.Array, row int) interface{} {
+	if column.IsNull(row) {
+		return nil
+	}
+
+	switch c := column.(type) {
+	case *array.Map:
+		start, end := c.ValueOffsets(row)
+		keys, items := c.Keys(), c.Items()
+		entries := make(map[string]interface{}, end-start)
+		for i := int(start); i < int(end); i++ {
+			entries[fmt.Sprint(ArrayValue(keys, i))] = ArrayValue(items, i)
+		}
+		return entries
+	case *array.List:
+		start, end := c.ValueOffsets(row)
+		values := c.ListValues()
+		elements := make([]interface{}, 0, end-start)
+		for i := int(start); i < int(end); i++ {
+			elements = append(elements, ArrayValue(values, i))
+		}
+		return elements
+	case *array.Struct:
+		structType := c.DataType().(*arrow.StructType)
+		fields := make(map[string]interface{}, structType.NumFields())
+		for i, field := range structType.Fields() {
+			fields[field.Name] = ArrayValue(c.Field(i), row)
+		}
+		return fields
+	}
+	return column.GetOneForMarshal(row)
+}
+
+// validateNestedValue checks that value can be stored in a column of a nested type
+func validateNestedValue(value interface{}, dataType arrow.DataType) error {
+	builder := array.NewBuilder(memory.DefaultAllocator, dataType)
+	defer builder.Release()
+	return AppendNestedValue(builder, value)
+}
+
+// parseNestedText converts the textual form of a list, map or struct value to the plain Go
+// value ArrayValue returns. Lists are read as JSON arrays or PostgreSQL array literals
+// ({1,2,"a b",NULL}); maps and structs as JSON objects.
+func parseNestedText(text string, icebergType iceberg.Type) (interface{}, error) {
+	trimmed := strings.TrimSpace(text)
+	if listType, ok := icebergType.(*iceberg.ListType); ok && strings.HasPrefix(trimmed, "{") {
+		literal, rest, err := parseArrayLiteral(trimmed)
+		if err == nil && strings.TrimSpace(rest) != "" {
+			err = fmt.Errorf("unexpected %q after array", rest)
+		}
+		if err != nil {
+			return nil, invalidTextValue(text, icebergType, err)
+		}
+		value, err := arrayLiteralValue(literal, listType)
+		if err != nil {
+			return nil, invalidTextValue(text, icebergType, err)
+		}
+		return value, nil
+	}
+
+	decoded, err := decodeNestedJSON(trimmed)
+	if err != nil {
+		return nil, invalidTextValue(text, icebergType, err)
+	}
+	value, err := typedNestedValue(decoded, icebergType)
+	if err != nil {
+		return nil, invalidTextValue(text, icebergType, err)
+	}
+	return value, nil
+}
+
+// ParseNestedValue converts a list, map or struct value decoded from JSON (with numbers as
+// json.Number or float64) or read with ArrayValue to the Go value ParseTextValue returns
+// for icebergType
+func ParseNestedValue(value interface{}, icebergType iceberg.Type) (interface{}, error) {
+	typed, err := typedNestedValue(value, icebergType)
+	if err != nil {
+		return nil, errors.New(ParquetConvertInvalidValue, fmt.Sprintf("invalid value for type %s", icebergType), err).
+			AddContext("type", icebergType.String())
+	}
+	return typed, nil
+}
+
+// typedNestedValue converts a decoded JSON value to icebergType, parsing leaves and map
+// keys with ParseTextValue
+func typedNestedValue(value interface{}, icebergType iceberg.Type) (interface{}, error) {
+	if value == nil {
+		return nil, nil
+	}
+
+	switch t := icebergType.(type) {
+	case *iceberg.ListType:
+		elements, ok := sliceValues(value)
+		if !ok {
+			return nil, fmt.Errorf("expected an array, got %T", value)
+		}
+		typed := make([]interface{}, len(elements))
+		for i, element := range elements {
+			v, err := typedNestedValue(element, t.Element)
+			if err != nil {
+				return nil, err
+			}
+			typed[i] = v
+		}
+		return typed, nil
+	case *iceberg.MapType:
+		entries, ok := stringKeyed(value)
+		if !ok {
+			return nil, fmt.Errorf("expected an object, got %T", value)
+		}
+		typed := make(map[string]interface{}, len(entries))
+		for key, entry := range entries {
+			if _, err := ParseTextValue(key, t.KeyType); err != nil {
+				return nil, err
+			}
+			v, err := typedNestedValue(entry, t.ValueType)
+			if err != nil {
+				return nil, err
+			}
+			typed[key] = v
+		}
+		return typed, nil
+	case *iceberg.StructType:
+		fields, ok := stringKeyed(value)
+		if !ok {
+			return nil, fmt.Errorf("expected an object, got %T", value)
+		}
+		typed := make(map[string]interface{}, len(fields))
+		for name, fieldValue := range fields {
+			field, ok := structField(t, name)
+			if !ok {
+				return nil, fmt.Errorf("struct has no field %s", name)
+			}
+			v, err := typedNestedValue(fieldValue, field.Type)
+			if err != nil {
+				return nil, err
+			}
+			typed[name] = v
+		}
+		return typed, nil
+	}
+
+	switch v := value.(type) {
+	case string:
+		return ParseTextValue(v, icebergType)
+	case json.Number:
+		return ParseTextValue(v.String(), icebergType)
+	case bool:
+		return ParseTextValue(strconv.FormatBool(v), icebergType)
+	case int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64, float32, float64:
+		return ParseTextValue(fmt.Sprint(v), icebergType)
+	}
+	return nil, fmt.Errorf("expected a %s value, got %T", icebergType, value)
+}
+
+// structField returns the field of structType called name
+func structField(structType *iceberg.StructType, name string) (iceberg.NestedField, bool) {
+	for _, field := range structType.FieldList {
+		if field.Name == name {
+			return field, true
+		}
+	}
+	return iceberg.NestedField{}, false
+}
+
+// arrayElement is one element of a PostgreSQL array literal: a nested array, or text that
+// is NULL when it is the unquoted word NULL
+type arrayElement struct {
+	elements []arrayElement
+	nested   bool
+	text     string
+	null     bool
+}
+
+// parseArrayLiteral parses a PostgreSQL array literal at the start of text and returns its
+// elements and what follows it
+func parseArrayLiteral(text string) ([]arrayElement, string, error) {
+	if !strings.HasPrefix(text, "{") {
+		return nil, text, fmt.Errorf("array must start with {")
+	}
+	rest := strings.TrimLeft(text[1:], " \t\r\n")
+	if strings.HasPrefix(rest, "}") {
+		return []arrayElement{}, rest[1:], nil
+	}
+
+	var elements []arrayElement
+	for {
+		rest = strings.TrimLeft(rest, " \t\r\n")
+		var element arrayElement
+		switch {
+		case strings.HasPrefix(rest, "{"):
+			nested, after, err := parseArrayLiteral(rest)
+			if err != nil {
+				return nil, rest, err
+			}
+			element, rest = arrayElement{elements: nested, nested: true}, after
+		case strings.HasPrefix(rest, `"`):
+			var quoted strings.Builder
+			i, closed := 1, false
+			for ; i < len(rest); i++ {
+				if rest[i] == '\\' && i+1 < len(rest) {
+					i++
+					quoted.WriteByte(rest[i])
+				} else if rest[i] == '"' {
+					closed = true
+					break
+				} else {
+					quoted.WriteByte(rest[i])
+				}
+			}
+			if !closed {
+				return nil, rest, fmt.Errorf("unterminated quoted element")
+			}
+			element, rest = arrayElement{text: quoted.String()}, rest[i+1:]
+		default:
+			end := strings.IndexAny(rest, ",}")
+			if end < 0 {
+				return nil, rest, fmt.Errorf("array is not closed")
+			}
+			word := strings.TrimSpace(rest[:end])
+			if word == "" {
+				return nil, rest, fmt.Errorf("empty array element")
+			}
+			element, rest = arrayElement{text: word, null: strings.EqualFold(word, "NULL")}, rest[end:]
+		}
+		elements = append(elements, element)
+
+		rest = strings.TrimLeft(rest, " \t\r\n")
+		switch {
+		case strings.HasPrefix(rest, ","):
+			rest = rest[1:]
+		case strings.HasPrefix(rest, "}"):
+			return elements, rest[1:], nil
+		default:
+			return nil, rest, fmt.Errorf("expected , or } in array")
+		}
+	}
+}
+
+// arrayLiteralValue converts the elements of a PostgreSQL array literal to listType. Nested
+// arrays fill nested lists; elements of map or struct type are read from their JSON text.
+func arrayLiteralValue(elements []arrayElement, listType *iceberg.ListType) ([]interface{}, error) {
+	values := make([]interface{}, len(elements))
+	for i, element := range elements {
+		switch {
+		case element.null:
+			values[i] = nil
+		case element.nested:
+			elementList, ok := listType.Element.(*iceberg.ListType)
+			if !ok {
+				return nil, fmt.Errorf("unexpected nested array for %s elements", listType.Element)
+			}
+			nested, err := arrayLiteralValue(element.elements, elementList)
+			if err != nil {
+				return nil, err
+			}
+			values[i] = nested
+		default:
+			v, err := ParseTextValue(element.text, listType.Element)
+			if err != nil {
+				return nil, err
+			}
+			values[i] = v
+		}
+	}
+	return values, nil
+}
+
+// decodeNestedJSON decodes a JSON value keeping numbers exact
+func decodeNestedJSON(text string) (interface{}, error) {
+	decoder := json.NewDecoder(strings.NewReader(text))
+	decoder.UseNumber()
+	var value interface{}
+	if err := decoder.Decode(&value); err != nil {
+		return nil, err
+	}
+	if decoder.More() {
+		return nil, fmt.Errorf("unexpected data after JSON value")
+	}
+	return value, nil
+}
+
+// sliceValues returns the elements of any slice or array except byte strings
+func sliceValues(value interface{}) ([]interface{}, bool) {
+	if elements, ok := value.([]interface{}); ok {
+		return elements, true
+	}
+	if _, ok := value.([]byte); ok {
+		return nil, false
+	}
+	v := reflect.ValueOf(value)
+	if v.Kind() != reflect.Slice && v.Kind() != reflect.Array {
+		return nil, false
+	}
+	elements := make([]interface{}, v.Len())
+	for i := range elements {
+		elements[i] = v.Index(i).Interface()
+	}
+	return elements, true
+}
+
+// stringKeyed returns the entries of any map keyed by the text of each key
+func stringKeyed(value interface{}) (map[string]interface{}, bool) {
+	if entries, ok := value.(map[string]interface{}); ok {
+		return entries, true
+	}
+	v := reflect.ValueOf(value)
+	if v.Kind() != reflect.Map {
+		return nil, false
+	}
+	entries := make(map[string]interface{}, v.Len())
+	iter := v.MapRange()
+	for iter.Next() {
+		entries[fmt.Sprint(iter.Key().Interface())] = iter.Value().Interface()
+	}
+	return entries, true
+}
+
+// sortedMapKeys returns the keys of a map in the order of their text, so maps are stored
+// the same way every time
+func sortedMapKeys(entries reflect.Value) []reflect.Value {
+	keys := entries.MapKeys()
+	sort.Slice(keys, func(i, j int) bool {
+		return fmt.Sprint(keys[i].Interface()) < fmt.Sprint(keys[j].Interface())
+	})
+	return keys
+}
+
+// invalidNestedValue builds the error returned when value does not fit dataType
+func invalidNestedValue(dataType arrow.DataType, value interface{}, cause error) error {
+	return errors.New(ParquetNestedInvalidValue, fmt.Sprintf("value of type %T does not fit %s", value, dataType), cause).
+		AddContext("type", dataType.String()).
+		AddContext("actual_type", fmt.Sprintf("%T", value))
+}
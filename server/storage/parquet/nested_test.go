@@ -0,0 +1,241 @@
+package parquet
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/apache/arrow-go/v18/arrow"
+	"github.com/apache/arrow-go/v18/arrow/array"
+	"github.com/apache/arrow-go/v18/arrow/memory"
+	"github.com/apache/iceberg-go"
+	"github.com/gear6io/ranger/pkg/errors"
+	"github.com/gear6io/ranger/server/metadata/registry"
+	"github.com/gear6io/ranger/server/metadata/registry/regtypes"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// nestedColumns are the registry columns of the nested fixture table
+var nestedColumns = []*regtypes.TableColumn{
+	{ID: 1, ColumnName: "id", DataType: "int64"},
+	{ID: 2, ColumnName: "tags", DataType: "list<string>"},
+	{ID: 3, ColumnName: "attrs", DataType: "map<string,int32>"},
+	{ID: 4, ColumnName: "point", DataType: "struct<x:int32,label:string>"},
+	{ID: 5, ColumnName: "events", DataType: "list<struct<at:timestamp,ok:boolean>>"},
+	{ID: 6, ColumnName: "matrix", DataType: "list<list<int64>>"},
+}
+
+// nestedRows are the fixture rows as ArrayValue returns them
+var nestedRows = [][]interface{}{
+	{
+		int64(1),
+		[]interface{}{"a", "b, c", nil},
+		map[string]interface{}{"x": int32(1), "y": int32(-2)},
+		map[string]interface{}{"x": int32(3), "label": "p\"q"},
+		[]interface{}{map[string]interface{}{"at": "2024-03-01 10:00:00", "ok": true}},
+		[]interface{}{[]interface{}{int64(1), int64(2)}, []interface{}{}},
+	},
+	{int64(2), []interface{}{}, map[string]interface{}{}, map[string]interface{}{"x": nil, "label": nil}, nil, nil},
+	{int64(3), nil, nil, nil, []interface{}{}, []interface{}{nil}},
+}
+
+// nestedSchema returns the Arrow schema of the nested fixture table
+func nestedSchema(t *testing.T) *arrow.Schema {
+	t.Helper()
+	icebergSchema, err := ConvertRegistryDataToIcebergSchema(&registry.SchemaData{Columns: nestedColumns})
+	require.NoError(t, err)
+	arrowSchema, err := ConvertIcebergToArrowSchema(icebergSchema)
+	require.NoError(t, err)
+	return arrowSchema
+}
+
+// buildNestedRecord appends rows to a record of schema with AppendNestedValue
+func buildNestedRecord(t *testing.T, schema *arrow.Schema, rows [][]interface{}) arrow.Record {
+	t.Helper()
+	builder := array.NewRecordBuilder(memory.DefaultAllocator, schema)
+	defer builder.Release()
+	for _, row := range rows {
+		for c, value := range row {
+			require.NoError(t, AppendNestedValue(builder.Field(c), value), schema.Field(c).Name)
+		}
+	}
+	return builder.NewRecord()
+}
+
+func TestConvertRegistryNestedTypes(t *testing.T) {
+	icebergSchema, err := ConvertRegistryDataToIcebergSchema(&registry.SchemaData{Columns: nestedColumns})
+	require.NoError(t, err)
+
+	point := icebergSchema.Field(3).Type.(*iceberg.StructType)
+	assert.Equal(t, []string{"x", "label"}, []string{point.FieldList[0].Name, point.FieldList[1].Name})
+	assert.Equal(t, iceberg.PrimitiveTypes.Int32, point.FieldList[0].Type)
+	events := icebergSchema.Field(4).Type.(*iceberg.ListType)
+	assert.IsType(t, &iceberg.StructType{}, events.Element)
+
+	// Nested fields are numbered after the columns, without reusing an ID
+	seen := map[int]bool{}
+	for _, id := range []int{
+		icebergSchema.Field(1).Type.(*iceberg.ListType).ElementID,
+		icebergSchema.Field(2).Type.(*iceberg.MapType).KeyID,
+		icebergSchema.Field(2).Type.(*iceberg.MapType).ValueID,
+		point.FieldList[0].ID, point.FieldList[1].ID, events.ElementID,
+	} {
+		assert.Greater(t, id, 6)
+		assert.False(t, seen[id], "duplicate field ID %d", id)
+		seen[id] = true
+	}
+
+	schema := nestedSchema(t)
+	assert.Equal(t, arrow.ListOf(arrow.BinaryTypes.String), schema.Field(1).Type)
+	assert.Equal(t, arrow.MAP, schema.Field(2).Type.ID())
+	assert.Equal(t, arrow.STRUCT, schema.Field(3).Type.ID())
+
+	_, err = ConvertRegistryDataToIcebergSchema(&registry.SchemaData{Columns: []*regtypes.TableColumn{
+		{ID: 1, ColumnName: "bad", DataType: "list<nope>"},
+	}})
+	require.Error(t, err)
+}
+
+func TestNestedValuesRoundTrip(t *testing.T) {
+	schema := nestedSchema(t)
+	require.NoError(t, ValidateDataWithContext(nestedRows, schema, "testdb", "nested"))
+
+	record := buildNestedRecord(t, schema, nestedRows)
+	defer record.Release()
+
+	// ArrayValue returns the fixture, apart from timestamps which come back as text
+	for r, row := range nestedRows {
+		for c, expected := range row {
+			actual := ArrayValue(record.Column(c), r)
+			if c == 4 && r == 0 {
+				event := actual.([]interface{})[0].(map[string]interface{})
+				assert.Equal(t, true, event["ok"])
+				assert.Contains(t, event["at"], "2024-03-01 10:00:00")
+				continue
+			}
+			assert.Equal(t, expected, actual, "row %d column %s", r, schema.Field(c).Name)
+		}
+	}
+
+	// Values that went through JSON, as memory tables store them, rebuild the same record
+	data, err := json.Marshal(nestedRows)
+	require.NoError(t, err)
+	var decoded [][]interface{}
+	require.NoError(t, json.Unmarshal(data, &decoded))
+	require.NoError(t, ValidateData(decoded, schema))
+	rebuilt := buildNestedRecord(t, schema, decoded)
+	defer rebuilt.Release()
+	assert.True(t, array.RecordEqual(record, rebuilt))
+
+	// JSON text is accepted for nested values too
+	builder := array.NewBuilder(memory.DefaultAllocator, schema.Field(3).Type)
+	defer builder.Release()
+	require.NoError(t, AppendNestedValue(builder, `{"x": 3, "label": "p\"q"}`))
+	point := builder.NewArray()
+	defer point.Release()
+	assert.Equal(t, nestedRows[0][3], ArrayValue(point, 0))
+}
+
+func TestValidateNestedValues(t *testing.T) {
+	schema := nestedSchema(t)
+	for name, value := range map[string]interface{}{
+		"tags":   "not json",
+		"attrs":  map[string]interface{}{"x": "one"},
+		"point":  map[string]interface{}{"z": 1},
+		"events": []interface{}{map[string]interface{}{"at": "yesterday"}},
+		"matrix": []interface{}{1},
+	} {
+		indices := schema.FieldIndices(name)
+		row := make([]interface{}, schema.NumFields())
+		row[indices[0]] = value
+
+		err := ValidateData([][]interface{}{row}, schema)
+		require.Error(t, err, name)
+		assert.Equal(t, ParquetSchemaTypeMismatch.String(), errors.GetCode(err), name)
+
+		err = ValidateDataWithContext([][]interface{}{row}, schema, "testdb", "nested")
+		require.Error(t, err, name)
+		assert.Contains(t, err.Error(), name)
+	}
+}
+
+func TestParseTextValueNested(t *testing.T) {
+	schema, err := ConvertRegistryDataToIcebergSchema(&registry.SchemaData{Columns: nestedColumns})
+	require.NoError(t, err)
+	typeOf := func(name string) iceberg.Type {
+		field, ok := schema.FindFieldByName(name)
+		require.True(t, ok)
+		return field.Type
+	}
+
+	tests := []struct {
+		name     string
+		text     string
+		column   string
+		expected interface{}
+	}{
+		{"Array literal", `{a,"b, c",NULL}`, "tags", []interface{}{"a", "b, c", nil}},
+		{"Quoted array literal", `{"NULL","x\"y",""}`, "tags", []interface{}{"NULL", `x"y`, ""}},
+		{"Empty array literal", `{}`, "tags", []interface{}{}},
+		{"JSON array", `["a", null]`, "tags", []interface{}{"a", nil}},
+		{"Nested array literal", `{{1,2},{}}`, "matrix", []interface{}{[]interface{}{int64(1), int64(2)}, []interface{}{}}},
+		{"Map", `{"x": 1, "y": -2}`, "attrs", map[string]interface{}{"x": int32(1), "y": int32(-2)}},
+		{"Struct", `{"x": 3, "label": "p"}`, "point", map[string]interface{}{"x": int32(3), "label": "p"}},
+		{"Array of structs", `{"{\"at\": \"2024-03-01 10:00:00\", \"ok\": true}"}`, "events", []interface{}{
+			map[string]interface{}{"at": time.Date(2024, 3, 1, 10, 0, 0, 0, time.UTC), "ok": true},
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			value, err := ParseTextValue(tt.text, typeOf(tt.column))
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, value)
+		})
+	}
+
+	for text, column := range map[string]string{
+		`{a,b`:              "tags",
+		`{a} x`:             "tags",
+		`{"x": "one"}`:      "attrs",
+		`{"z": 1}`:          "point",
+		`[1, 2]`:            "point",
+		`{{1},{x}}`:         "matrix",
+		`{"x": 1} {"x": 2}`: "point",
+	} {
+		_, err := ParseTextValue(text, typeOf(column))
+		require.Error(t, err, text)
+		assert.Equal(t, ParquetConvertInvalidValue.String(), errors.GetCode(err), text)
+	}
+}
+
+func TestConformRecordNested(t *testing.T) {
+	schema := nestedSchema(t)
+
+	// Clients without the table types send structs as maps and lists as JSON text
+	incoming := arrow.NewSchema([]arrow.Field{
+		{Name: "id", Type: arrow.PrimitiveTypes.Int64, Nullable: true},
+		{Name: "point", Type: arrow.MapOf(arrow.BinaryTypes.String, arrow.BinaryTypes.String), Nullable: true},
+		{Name: "tags", Type: arrow.BinaryTypes.String, Nullable: true},
+	}, nil)
+	record := buildNestedRecord(t, incoming, [][]interface{}{
+		{int64(1), map[string]interface{}{"x": "3", "label": "p"}, `["a", null]`},
+		{int64(2), nil, nil},
+	})
+	defer record.Release()
+
+	conformed, err := ConformRecord(context.Background(), record, schema, "testdb", "nested")
+	require.NoError(t, err)
+	defer conformed.Release()
+	assert.Equal(t, map[string]interface{}{"x": int32(3), "label": "p"}, ArrayValue(conformed.Column(3), 0))
+	assert.Equal(t, []interface{}{"a", nil}, ArrayValue(conformed.Column(1), 0))
+	assert.Nil(t, ArrayValue(conformed.Column(3), 1))
+
+	bad := buildNestedRecord(t, incoming, [][]interface{}{{int64(1), map[string]interface{}{"z": "1"}, nil}})
+	defer bad.Release()
+	_, err = ConformRecord(context.Background(), bad, schema, "testdb", "nested")
+	require.Error(t, err)
+	assert.Equal(t, ParquetSchemaTypeMismatch.String(), errors.GetCode(err))
+}
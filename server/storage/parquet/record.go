@@ -0,0 +1,243 @@
+package parquet
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/apache/arrow-go/v18/arrow"
+	"github.com/apache/arrow-go/v18/arrow/array"
+	"github.com/apache/arrow-go/v18/arrow/compute"
+	"github.com/apache/arrow-go/v18/arrow/memory"
+	"github.com/gear6io/ranger/pkg/errors"
+)
+
+// Package-specific error codes for record validation
+var (
+	ParquetRecordUnknownColumn = errors.MustNewCode("parquet.record_unknown_column")
+	ParquetRecordMissingColumn = errors.MustNewCode("parquet.record_missing_column")
+)
+
+// ConformRecord validates a record against a table schema one column at a time and returns
+// it with the columns, order and types of the schema. Columns are matched by name, missing
+// nullable columns are filled with nulls, and columns of another type are cast safely (list,
+// map and struct columns are rebuilt from their values), so a value that does not fit the
+// table type rejects the record. The caller owns the result.
+func ConformRecord(ctx context.Context, record arrow.Record, schema *arrow.Schema, database, tableName string) (arrow.Record, error) {
+	if schema == nil {
+		return nil, errors.New(ParquetSchemaNilSchema, "schema cannot be nil", nil).
+			AddContext("database", database).
+			AddContext("table", tableName)
+	}
+
+	for _, field := range record.Schema().Fields() {
+		if _, ok := schema.FieldsByName(field.Name); !ok {
+			return nil, errors.New(ParquetRecordUnknownColumn, fmt.Sprintf("column %s does not exist in table", field.Name), nil).
+				AddContext("database", database).
+				AddContext("table", tableName).
+				AddContext("column", field.Name)
+		}
+	}
+
+	columns := make([]arrow.Array, 0, schema.NumFields())
+	defer func() {
+		for _, column := range columns {
+			column.Release()
+		}
+	}()
+
+	for colIndex, field := range schema.Fields() {
+		column, err := conformColumn(ctx, record, field, colIndex, database, tableName)
+		if err != nil {
+			return nil, err
+		}
+		columns = append(columns, column)
+	}
+
+	return array.NewRecord(schema, columns, record.NumRows()), nil
+}
+
+// conformColumn returns the column of record for field, cast to the field type
+func conformColumn(ctx context.Context, record arrow.Record, field arrow.Field, colIndex int, database, tableName string) (arrow.Array, error) {
+	indices := record.Schema().FieldIndices(field.Name)
+	if len(indices) == 0 {
+		if !field.Nullable && record.NumRows() > 0 {
+			return nil, errors.New(ParquetRecordMissingColumn, fmt.Sprintf("required column %s is missing", field.Name), nil).
+				AddContext("database", database).
+				AddContext("table", tableName).
+				AddContext("column", field.Name)
+		}
+		return array.MakeArrayOfNull(memory.DefaultAllocator, field.Type, int(record.NumRows())), nil
+	}
+
+	column := record.Column(indices[0])
+	if !field.Nullable && column.NullN() > 0 {
+		rowIndex := firstNull(column)
+		validationErr := NewNullValueValidationError(rowIndex, colIndex, field.Name, database, tableName)
+		return nil, errors.New(ParquetSchemaFieldCannotBeNull, validationErr.Message, validationErr).
+			AddContext("database", database).
+			AddContext("table", tableName).
+			AddContext("validation_type", "null_constraint_violation")
+	}
+
+	if arrow.TypeEqual(column.DataType(), field.Type) {
+		column.Retain()
+		return column, nil
+	}
+
+	if text, ok := column.(*array.String); ok && isTemporal(field.Type) {
+		parsed, rowIndex := parseTemporalColumn(text, field.Type)
+		if parsed == nil {
+			validationErr := NewDetailedValidationError(rowIndex, colIndex, field.Name, field.Type.String(), column.DataType().String(), text.Value(rowIndex), database, tableName)
+			return nil, errors.New(ParquetSchemaTypeMismatch, validationErr.Message, validationErr).
+				AddContext("database", database).
+				AddContext("table", tableName).
+				AddContext("validation_type", "type_mismatch")
+		}
+		return parsed, nil
+	}
+
+	if isNestedType(field.Type) {
+		// Arrow casts nested types only between matching layouts and never from JSON text,
+		// so nested columns are rebuilt value by value (a map column into a struct, say)
+		rebuilt, rowIndex, err := rebuildNestedColumn(column, field.Type)
+		if err != nil {
+			validationErr := NewDetailedValidationError(rowIndex, colIndex, field.Name, field.Type.String(), column.DataType().String(), ArrayValue(column, rowIndex), database, tableName)
+			validationErr.Message = fmt.Sprintf("%s: %s", validationErr.Message, errors.AsError(err).Message)
+			return nil, errors.New(ParquetSchemaTypeMismatch, validationErr.Message, validationErr).
+				AddContext("database", database).
+				AddContext("table", tableName).
+				AddContext("validation_type", "type_mismatch")
+		}
+		return rebuilt, nil
+	}
+
+	if !compute.CanCast(column.DataType(), field.Type) {
+		validationErr := NewDetailedValidationError(0, colIndex, field.Name, field.Type.String(), column.DataType().String(), nil, database, tableName)
+		validationErr.Message = fmt.Sprintf("validation failed at column %d (%s): cannot convert %s to %s",
+			colIndex, field.Name, column.DataType(), field.Type)
+		return nil, errors.New(ParquetSchemaTypeMismatch, validationErr.Message, validationErr).
+			AddContext("database", database).
+			AddContext("table", tableName).
+			AddContext("validation_type", "type_mismatch")
+	}
+
+	cast, err := compute.CastArray(ctx, column, compute.SafeCastOptions(field.Type))
+	if err != nil {
+		rowIndex := firstCastFailure(ctx, column, field.Type)
+		var value interface{}
+		if rowIndex < column.Len() {
+			value = ArrayValue(column, rowIndex)
+		}
+		validationErr := NewDetailedValidationError(rowIndex, colIndex, field.Name, field.Type.String(), column.DataType().String(), value, database, tableName)
+		return nil, errors.New(ParquetSchemaTypeMismatch, validationErr.Message, validationErr).
+			AddContext("database", database).
+			AddContext("table", tableName).
+			AddContext("validation_type", "type_mismatch")
+	}
+	return cast, nil
+}
+
+// rebuildNestedColumn converts column to a nested target type through Go values. It returns
+// the index of the first value that does not fit on failure.
+func rebuildNestedColumn(column arrow.Array, target arrow.DataType) (arrow.Array, int, error) {
+	builder := array.NewBuilder(memory.DefaultAllocator, target)
+	defer builder.Release()
+	builder.Reserve(column.Len())
+
+	for i := 0; i < column.Len(); i++ {
+		if err := AppendNestedValue(builder, ArrayValue(column, i)); err != nil {
+			return nil, i, err
+		}
+	}
+	return builder.NewArray(), 0, nil
+}
+
+// firstNull returns the index of the first null value of column
+func firstNull(column arrow.Array) int {
+	for i := 0; i < column.Len(); i++ {
+		if column.IsNull(i) {
+			return i
+		}
+	}
+	return column.Len()
+}
+
+// firstCastFailure returns the index of the first value of column that cannot be cast to
+// target; it is only used to report a failed cast, so it can afford to go value by value
+func firstCastFailure(ctx context.Context, column arrow.Array, target arrow.DataType) int {
+	for i := 0; i < column.Len(); i++ {
+		value := array.NewSlice(column, int64(i), int64(i+1))
+		cast, err := compute.CastArray(ctx, value, compute.SafeCastOptions(target))
+		value.Release()
+		if err != nil {
+			return i
+		}
+		cast.Release()
+	}
+	return column.Len()
+}
+
+// isTemporal reports whether dataType is a date, time or timestamp type
+func isTemporal(dataType arrow.DataType) bool {
+	switch dataType.(type) {
+	case *arrow.Date32Type, *arrow.Time64Type, *arrow.TimestampType:
+		return true
+	}
+	return false
+}
+
+// parseTemporalColumn parses a textual column into a date, time or timestamp column with
+// the layouts ParseTextValue accepts, since Arrow casts do not parse dates or times. It
+// returns nil and the index of the first value that cannot be parsed on failure.
+func parseTemporalColumn(column *array.String, target arrow.DataType) (arrow.Array, int) {
+	builder := array.NewBuilder(memory.DefaultAllocator, target)
+	defer builder.Release()
+	builder.Reserve(column.Len())
+
+	for i := 0; i < column.Len(); i++ {
+		if column.IsNull(i) {
+			builder.AppendNull()
+			continue
+		}
+
+		text := strings.TrimSpace(column.Value(i))
+		var ok bool
+		switch b := builder.(type) {
+		case *array.Date32Builder:
+			var value time.Time
+			if value, ok = parseTime(text, "2006-01-02"); ok {
+				b.Append(arrow.Date32FromTime(value))
+			}
+		case *array.Time64Builder:
+			var value time.Time
+			if value, ok = parseTime(text, "15:04:05.999999999"); ok {
+				sinceMidnight := value.Sub(value.Truncate(24 * time.Hour))
+				b.Append(arrow.Time64(sinceMidnight / target.(*arrow.Time64Type).Unit.Multiplier()))
+			}
+		case *array.TimestampBuilder:
+			var value time.Time
+			if value, ok = parseTime(text, timestampLayouts...); ok {
+				timestamp, err := arrow.TimestampFromTime(value, target.(*arrow.TimestampType).Unit)
+				if ok = err == nil; ok {
+					b.Append(timestamp)
+				}
+			}
+		}
+		if !ok {
+			return nil, i
+		}
+	}
+	return builder.NewArray(), 0
+}
+
+// parseTime parses text with the first layout that matches it
+func parseTime(text string, layouts ...string) (time.Time, bool) {
+	for _, layout := range layouts {
+		if value, err := time.Parse(layout, text); err == nil {
+			return value, true
+		}
+	}
+	return time.Time{}, false
+}
@@ -0,0 +1,211 @@
+package parquet
+
+import (
+	"context"
+	stderrors "errors"
+	"testing"
+	"time"
+
+	"github.com/apache/arrow-go/v18/arrow"
+	"github.com/apache/arrow-go/v18/arrow/array"
+	"github.com/apache/arrow-go/v18/arrow/decimal128"
+	"github.com/apache/arrow-go/v18/arrow/memory"
+	"github.com/gear6io/ranger/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConformRecord(t *testing.T) {
+	ctx := context.Background()
+	schema := arrow.NewSchema([]arrow.Field{
+		{Name: "id", Type: arrow.PrimitiveTypes.Int32, Nullable: false},
+		{Name: "name", Type: arrow.BinaryTypes.String, Nullable: true},
+		{Name: "score", Type: arrow.PrimitiveTypes.Float64, Nullable: true},
+	}, nil)
+
+	// newRecord builds a record with an int64 id column and an optional name column
+	newRecord := func(ids []int64, valid []bool, names []string) arrow.Record {
+		fields := []arrow.Field{{Name: "id", Type: arrow.PrimitiveTypes.Int64, Nullable: true}}
+		if names != nil {
+			fields = append([]arrow.Field{{Name: "name", Type: arrow.BinaryTypes.String, Nullable: true}}, fields...)
+		}
+		builder := array.NewRecordBuilder(memory.DefaultAllocator, arrow.NewSchema(fields, nil))
+		defer builder.Release()
+		if names != nil {
+			builder.Field(0).(*array.StringBuilder).AppendValues(names, nil)
+			builder.Field(1).(*array.Int64Builder).AppendValues(ids, valid)
+		} else {
+			builder.Field(0).(*array.Int64Builder).AppendValues(ids, valid)
+		}
+		return builder.NewRecord()
+	}
+
+	t.Run("ReordersCastsAndFillsColumns", func(t *testing.T) {
+		record := newRecord([]int64{1, 2}, nil, []string{"a", "b"})
+		defer record.Release()
+
+		conformed, err := ConformRecord(ctx, record, schema, "testdb", "users")
+		require.NoError(t, err)
+		defer conformed.Release()
+
+		assert.True(t, conformed.Schema().Equal(schema))
+		assert.Equal(t, int64(2), conformed.NumRows())
+		assert.Equal(t, []int32{1, 2}, conformed.Column(0).(*array.Int32).Int32Values())
+		assert.Equal(t, "b", conformed.Column(1).(*array.String).Value(1))
+		assert.Equal(t, 2, conformed.Column(2).NullN())
+	})
+
+	t.Run("RejectsNullInRequiredColumn", func(t *testing.T) {
+		record := newRecord([]int64{1, 0, 3}, []bool{true, false, true}, nil)
+		defer record.Release()
+
+		_, err := ConformRecord(ctx, record, schema, "testdb", "users")
+		require.Error(t, err)
+		assert.Equal(t, ParquetSchemaFieldCannotBeNull.String(), errors.GetCode(err))
+
+		var detail *DetailedValidationError
+		require.True(t, stderrors.As(err, &detail))
+		assert.Equal(t, 1, detail.RowIndex)
+		assert.Equal(t, "id", detail.ColumnName)
+	})
+
+	t.Run("RejectsValueThatDoesNotFit", func(t *testing.T) {
+		record := newRecord([]int64{1, 1 << 40}, nil, nil)
+		defer record.Release()
+
+		_, err := ConformRecord(ctx, record, schema, "testdb", "users")
+		require.Error(t, err)
+		assert.Equal(t, ParquetSchemaTypeMismatch.String(), errors.GetCode(err))
+
+		var detail *DetailedValidationError
+		require.True(t, stderrors.As(err, &detail))
+		assert.Equal(t, 1, detail.RowIndex)
+		assert.Equal(t, int64(1<<40), detail.Value)
+	})
+
+	t.Run("RejectsUnknownColumn", func(t *testing.T) {
+		builder := array.NewRecordBuilder(memory.DefaultAllocator, arrow.NewSchema([]arrow.Field{
+			{Name: "id", Type: arrow.PrimitiveTypes.Int32},
+			{Name: "unknown", Type: arrow.PrimitiveTypes.Int32},
+		}, nil))
+		defer builder.Release()
+		builder.Field(0).(*array.Int32Builder).Append(1)
+		builder.Field(1).(*array.Int32Builder).Append(1)
+		record := builder.NewRecord()
+		defer record.Release()
+
+		_, err := ConformRecord(ctx, record, schema, "testdb", "users")
+		require.Error(t, err)
+		assert.Equal(t, ParquetRecordUnknownColumn.String(), errors.GetCode(err))
+	})
+
+	t.Run("RejectsMissingRequiredColumn", func(t *testing.T) {
+		builder := array.NewRecordBuilder(memory.DefaultAllocator, arrow.NewSchema([]arrow.Field{
+			{Name: "name", Type: arrow.BinaryTypes.String, Nullable: true},
+		}, nil))
+		defer builder.Release()
+		builder.Field(0).(*array.StringBuilder).Append("a")
+		record := builder.NewRecord()
+		defer record.Release()
+
+		_, err := ConformRecord(ctx, record, schema, "testdb", "users")
+		require.Error(t, err)
+		assert.Equal(t, ParquetRecordMissingColumn.String(), errors.GetCode(err))
+	})
+
+	t.Run("ParsesTextualTemporalColumns", func(t *testing.T) {
+		temporal := arrow.NewSchema([]arrow.Field{
+			{Name: "day", Type: arrow.FixedWidthTypes.Date32, Nullable: true},
+			{Name: "at", Type: arrow.FixedWidthTypes.Timestamp_ns, Nullable: true},
+		}, nil)
+		builder := array.NewRecordBuilder(memory.DefaultAllocator, arrow.NewSchema([]arrow.Field{
+			{Name: "day", Type: arrow.BinaryTypes.String, Nullable: true},
+			{Name: "at", Type: arrow.BinaryTypes.String, Nullable: true},
+		}, nil))
+		defer builder.Release()
+		builder.Field(0).(*array.StringBuilder).AppendValues([]string{"2024-01-02", ""}, []bool{true, false})
+		builder.Field(1).(*array.StringBuilder).AppendValues([]string{"2024-01-02 15:04:05", "not a time"}, nil)
+		record := builder.NewRecord()
+		defer record.Release()
+
+		_, err := ConformRecord(ctx, record, temporal, "testdb", "events")
+		require.Error(t, err)
+		var detail *DetailedValidationError
+		require.True(t, stderrors.As(err, &detail))
+		assert.Equal(t, 1, detail.RowIndex)
+		assert.Equal(t, "at", detail.ColumnName)
+
+		valid := record.NewSlice(0, 1)
+		defer valid.Release()
+		conformed, err := ConformRecord(ctx, valid, temporal, "testdb", "events")
+		require.NoError(t, err)
+		defer conformed.Release()
+		assert.Equal(t, "2024-01-02", conformed.Column(0).(*array.Date32).Value(0).ToTime().Format("2006-01-02"))
+		assert.Equal(t, "2024-01-02T15:04:05Z", conformed.Column(1).(*array.Timestamp).Value(0).ToTime(arrow.Nanosecond).Format(time.RFC3339))
+	})
+}
+
+func TestSortRecords(t *testing.T) {
+	ctx := context.Background()
+	schema := arrow.NewSchema([]arrow.Field{
+		{Name: "ts", Type: arrow.FixedWidthTypes.Timestamp_ns, Nullable: true},
+		{Name: "id", Type: arrow.PrimitiveTypes.Int32, Nullable: false},
+	}, nil)
+
+	newRecord := func(ts []arrow.Timestamp, valid []bool, ids []int32) arrow.Record {
+		builder := array.NewRecordBuilder(memory.DefaultAllocator, schema)
+		defer builder.Release()
+		builder.Field(0).(*array.TimestampBuilder).AppendValues(ts, valid)
+		builder.Field(1).(*array.Int32Builder).AppendValues(ids, nil)
+		return builder.NewRecord()
+	}
+
+	first := newRecord([]arrow.Timestamp{30, 10, 0}, []bool{true, true, false}, []int32{1, 2, 3})
+	defer first.Release()
+	second := newRecord([]arrow.Timestamp{10, 20}, nil, []int32{4, 5})
+	defer second.Release()
+
+	t.Run("SortsAcrossRecordsNullsFirst", func(t *testing.T) {
+		sorted, err := SortRecords(ctx, []arrow.Record{first, second}, []string{"ts"})
+		require.NoError(t, err)
+		defer sorted.Release()
+
+		require.Equal(t, int64(5), sorted.NumRows())
+		assert.True(t, sorted.Column(0).IsNull(0))
+		assert.Equal(t, []arrow.Timestamp{0, 10, 10, 20, 30}, sorted.Column(0).(*array.Timestamp).TimestampValues())
+		// Rows with equal timestamps keep the order they were written in
+		assert.Equal(t, []int32{3, 2, 4, 5, 1}, sorted.Column(1).(*array.Int32).Int32Values())
+	})
+
+	t.Run("SortsBySeveralColumns", func(t *testing.T) {
+		sorted, err := SortRecord(ctx, second, []string{"ts", "id"})
+		require.NoError(t, err)
+		defer sorted.Release()
+		assert.Equal(t, []int32{4, 5}, sorted.Column(1).(*array.Int32).Int32Values())
+	})
+
+	t.Run("RejectsUnknownColumn", func(t *testing.T) {
+		_, err := SortRecord(ctx, first, []string{"missing"})
+		assert.Equal(t, ParquetSortUnknownColumn.String(), errors.GetCode(err))
+	})
+
+	t.Run("SortsDecimals", func(t *testing.T) {
+		decimalType := &arrow.Decimal128Type{Precision: 10, Scale: 2}
+		builder := array.NewRecordBuilder(memory.DefaultAllocator, arrow.NewSchema([]arrow.Field{
+			{Name: "price", Type: decimalType, Nullable: true},
+		}, nil))
+		defer builder.Release()
+		builder.Field(0).(*array.Decimal128Builder).AppendValues(
+			[]decimal128.Num{decimal128.FromI64(250), decimal128.FromI64(-100), decimal128.FromI64(0), decimal128.FromI64(1999)},
+			[]bool{true, true, false, true})
+		record := builder.NewRecord()
+		defer record.Release()
+
+		sorted, err := SortRecord(ctx, record, []string{"price"})
+		require.NoError(t, err)
+		defer sorted.Release()
+		prices := sorted.Column(0).(*array.Decimal128)
+		assert.True(t, prices.IsNull(0))
+		assert.Equal(t, []decimal128.Num{decimal128.FromI64(-100), decimal128.FromI64(250), decimal128.FromI64(1999)}, prices.Values()[1:])
+	})
+}
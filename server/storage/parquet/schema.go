@@ -232,9 +232,21 @@ func ConvertRegistryDataToIcebergSchema(schemaData *registry.SchemaData) (*icebe
 	// Convert columns to Iceberg nested fields
 	fields := make([]iceberg.NestedField, 0, len(schemaData.Columns))
 
+	// Elements, keys, values and fields of nested columns are numbered after the columns
+	nextID := len(schemaData.Columns)
+	for _, col := range schemaData.Columns {
+		nextID = max(nextID, int(col.ID))
+	}
+
 	for _, col := range schemaData.Columns {
 		// Parse the data type to Iceberg type
-		icebergType, err := parseRegistryDataType(col.DataType)
+		var icebergType iceberg.Type
+		var err error
+		if isNestedTypeString(col.DataType) {
+			icebergType, err = parseRegistryNestedType(col.DataType, &nextID)
+		} else {
+			icebergType, err = parseRegistryDataType(col.DataType)
+		}
 		if err != nil {
 			return nil, errors.New(ParquetSchemaTypeConversionFailed, "failed to parse registry data type", err).
 				AddContext("column", col.ColumnName).
@@ -534,8 +546,16 @@ func validateType(value interface{}, arrowType arrow.DataType, fieldName string,
 				AddContext("row_index", fmt.Sprintf("%d", rowIndex)).
 				AddContext("col_index", fmt.Sprintf("%d", colIndex))
 		}
+	case *arrow.ListType, *arrow.MapType, *arrow.StructType:
+		if err := validateNestedValue(value, arrowType); err != nil {
+			return errors.New(ParquetSchemaTypeMismatch, fmt.Sprintf("field expects %s", arrowType), err).
+				AddContext("field_name", fieldName).
+				AddContext("actual_type", fmt.Sprintf("%T", value)).
+				AddContext("row_index", fmt.Sprintf("%d", rowIndex)).
+				AddContext("col_index", fmt.Sprintf("%d", colIndex))
+		}
 	default:
-		// For complex types, we'll do basic validation
+		// Other types are checked when the value is written
 		return nil
 	}
 
@@ -620,8 +640,17 @@ func validateTypeWithContext(value interface{}, arrowType arrow.DataType, fieldN
 				AddContext("table", tableName).
 				AddContext("validation_type", "type_mismatch")
 		}
+	case *arrow.ListType, *arrow.MapType, *arrow.StructType:
+		if err := validateNestedValue(value, arrowType); err != nil {
+			validationErr := NewDetailedValidationError(rowIndex, colIndex, fieldName, arrowType.String(), actualType, value, database, tableName)
+			validationErr.Message = fmt.Sprintf("%s: %s", validationErr.Message, errors.AsError(err).Message)
+			return errors.New(ParquetSchemaTypeMismatch, validationErr.Message, validationErr).
+				AddContext("database", database).
+				AddContext("table", tableName).
+				AddContext("validation_type", "type_mismatch")
+		}
 	default:
-		// For complex types, we'll do basic validation
+		// Other types are checked when the value is written
 		return nil
 	}
 
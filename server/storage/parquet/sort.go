@@ -0,0 +1,216 @@
+package parquet
+
+import (
+	"bytes"
+	"cmp"
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/apache/arrow-go/v18/arrow"
+	"github.com/apache/arrow-go/v18/arrow/array"
+	"github.com/apache/arrow-go/v18/arrow/compute"
+	"github.com/apache/arrow-go/v18/arrow/memory"
+	"github.com/gear6io/ranger/pkg/errors"
+)
+
+// Package-specific error codes for sorting records
+var (
+	ParquetSortUnknownColumn   = errors.MustNewCode("parquet.sort_unknown_column")
+	ParquetSortUnsupportedType = errors.MustNewCode("parquet.sort_unsupported_type")
+	ParquetSortFailed          = errors.MustNewCode("parquet.sort_failed")
+)
+
+// SortRecords returns the rows of records, which share a schema, as one record sorted by
+// the columns in ascending order with nulls first. Rows equal on every sort column keep the
+// order they were given in. The caller owns the result.
+func SortRecords(ctx context.Context, records []arrow.Record, columns []string) (arrow.Record, error) {
+	if len(records) == 0 {
+		return nil, errors.New(ParquetSortFailed, "no records to sort", nil)
+	}
+	schema := records[0].Schema()
+
+	merged := make([]arrow.Array, schema.NumFields())
+	defer func() {
+		for _, column := range merged {
+			if column != nil {
+				column.Release()
+			}
+		}
+	}()
+	var rows int64
+	for _, record := range records {
+		rows += record.NumRows()
+	}
+	for i := range merged {
+		chunks := make([]arrow.Array, len(records))
+		for r, record := range records {
+			chunks[r] = record.Column(i)
+		}
+		column, err := array.Concatenate(chunks, memory.DefaultAllocator)
+		if err != nil {
+			return nil, errors.New(ParquetSortFailed, "failed to merge records", err).AddContext("column", schema.Field(i).Name)
+		}
+		merged[i] = column
+	}
+
+	record := array.NewRecord(schema, merged, rows)
+	defer record.Release()
+	return SortRecord(ctx, record, columns)
+}
+
+// SortRecord returns record with its rows sorted by the columns in ascending order with
+// nulls first, keeping the order of rows equal on every sort column. The caller owns the
+// result.
+func SortRecord(ctx context.Context, record arrow.Record, columns []string) (arrow.Record, error) {
+	compares := make([]func(i, j int) int, 0, len(columns))
+	for _, name := range columns {
+		indices := record.Schema().FieldIndices(name)
+		if len(indices) == 0 {
+			return nil, errors.New(ParquetSortUnknownColumn, fmt.Sprintf("sort column %s does not exist in table", name), nil).AddContext("column", name)
+		}
+		compare, err := columnComparator(record.Column(indices[0]))
+		if err != nil {
+			return nil, errors.AddContext(err, "column", name)
+		}
+		compares = append(compares, compare)
+	}
+
+	order := make([]int, record.NumRows())
+	for i := range order {
+		order[i] = i
+	}
+	sorted := sort.SliceIsSorted(order, func(a, b int) bool {
+		return compareRows(compares, order[a], order[b]) < 0
+	})
+	if sorted {
+		record.Retain()
+		return record, nil
+	}
+	sort.SliceStable(order, func(a, b int) bool {
+		return compareRows(compares, order[a], order[b]) < 0
+	})
+
+	builder := array.NewInt64Builder(memory.DefaultAllocator)
+	defer builder.Release()
+	for _, row := range order {
+		builder.Append(int64(row))
+	}
+	take := builder.NewInt64Array()
+	defer take.Release()
+
+	sortedColumns := make([]arrow.Array, 0, record.NumCols())
+	defer func() {
+		for _, column := range sortedColumns {
+			column.Release()
+		}
+	}()
+	for i, column := range record.Columns() {
+		sortedColumn, err := compute.TakeArray(ctx, column, take)
+		if err != nil {
+			return nil, errors.New(ParquetSortFailed, "failed to reorder column", err).AddContext("column", record.Schema().Field(i).Name)
+		}
+		sortedColumns = append(sortedColumns, sortedColumn)
+	}
+
+	return array.NewRecord(record.Schema(), sortedColumns, record.NumRows()), nil
+}
+
+// compareRows compares two rows by each sort column in turn
+func compareRows(compares []func(i, j int) int, i, j int) int {
+	for _, compare := range compares {
+		if c := compare(i, j); c != 0 {
+			return c
+		}
+	}
+	return 0
+}
+
+// columnComparator returns a function ordering two rows of column, nulls first
+func columnComparator(column arrow.Array) (func(i, j int) int, error) {
+	var compare func(i, j int) int
+	switch c := column.(type) {
+	case *array.Boolean:
+		compare = func(i, j int) int {
+			return cmp.Compare(boolRank(c.Value(i)), boolRank(c.Value(j)))
+		}
+	case *array.Int8:
+		compare = compareValues(c)
+	case *array.Int16:
+		compare = compareValues(c)
+	case *array.Int32:
+		compare = compareValues(c)
+	case *array.Int64:
+		compare = compareValues(c)
+	case *array.Uint8:
+		compare = compareValues(c)
+	case *array.Uint16:
+		compare = compareValues(c)
+	case *array.Uint32:
+		compare = compareValues(c)
+	case *array.Uint64:
+		compare = compareValues(c)
+	case *array.Float32:
+		compare = compareValues(c)
+	case *array.Float64:
+		compare = compareValues(c)
+	case *array.Date32:
+		compare = compareValues(c)
+	case *array.Date64:
+		compare = compareValues(c)
+	case *array.Time32:
+		compare = compareValues(c)
+	case *array.Time64:
+		compare = compareValues(c)
+	case *array.Timestamp:
+		compare = compareValues(c)
+	case *array.String:
+		compare = compareValues(c)
+	case *array.LargeString:
+		compare = compareValues(c)
+	case *array.Binary:
+		compare = func(i, j int) int { return bytes.Compare(c.Value(i), c.Value(j)) }
+	case *array.LargeBinary:
+		compare = func(i, j int) int { return bytes.Compare(c.Value(i), c.Value(j)) }
+	case *array.FixedSizeBinary:
+		compare = func(i, j int) int { return bytes.Compare(c.Value(i), c.Value(j)) }
+	case *array.Decimal128:
+		// Values of a column share its scale, so their unscaled integers order them
+		compare = func(i, j int) int { return c.Value(i).Cmp(c.Value(j)) }
+	case *array.Decimal256:
+		compare = func(i, j int) int { return c.Value(i).Cmp(c.Value(j)) }
+	default:
+		return nil, errors.New(ParquetSortUnsupportedType, "cannot sort by column of this type", nil).AddContext("data_type", column.DataType().String())
+	}
+
+	if column.NullN() == 0 {
+		return compare, nil
+	}
+	return func(i, j int) int {
+		iNull, jNull := column.IsNull(i), column.IsNull(j)
+		switch {
+		case iNull && jNull:
+			return 0
+		case iNull:
+			return -1
+		case jNull:
+			return 1
+		}
+		return compare(i, j)
+	}, nil
+}
+
+// compareValues orders two rows of an array of ordered values
+func compareValues[T cmp.Ordered](values interface{ Value(int) T }) func(i, j int) int {
+	return func(i, j int) int {
+		return cmp.Compare(values.Value(i), values.Value(j))
+	}
+}
+
+// boolRank orders false before true
+func boolRank(b bool) int {
+	if b {
+		return 1
+	}
+	return 0
+}
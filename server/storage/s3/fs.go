@@ -1,7 +1,6 @@
 package s3
 
 import (
-	"bytes"
 	"context"
 	"crypto/tls"
 	"fmt"
@@ -17,7 +16,10 @@ import (
 	"sync/atomic"
 	"time"
 
+	"github.com/apache/arrow-go/v18/arrow"
 	icebergio "github.com/apache/iceberg-go/io"
+	"github.com/gear6io/ranger/server/storage/objectstore"
+	parquet "github.com/gear6io/ranger/server/storage/parquet"
 	"github.com/johannesboyne/gofakes3"
 	"github.com/johannesboyne/gofakes3/backend/s3mem"
 	"github.com/minio/minio-go/v7"
@@ -215,6 +217,9 @@ type EmbeddedMinIO struct {
 type S3FileSystem struct {
 	minioServer *EmbeddedMinIO
 	client      *minio.Client
+	store       *objectstore.S3Store   // Ranged reads and multipart uploads over client
+	tableStore  *objectstore.S3Store   // The objects of tables, under prefix
+	pageCache   *objectstore.PageCache // Footers and small reads; nil when caching is disabled
 	bucket      string
 	prefix      string
 	config      *FileSystemConfig
@@ -228,6 +233,21 @@ func (s3fs *S3FileSystem) GetStorageType() string {
 	return Type
 }
 
+// HealthStatus reports whether the embedded MinIO server is running and passed its last
+// health check, along with the time of that check
+func (s3fs *S3FileSystem) HealthStatus() (bool, map[string]interface{}) {
+	metrics := s3fs.minioServer.GetMetrics()
+	running := s3fs.minioServer.IsRunning()
+	details := map[string]interface{}{
+		"running":       running,
+		"health_status": metrics.HealthCheckStatus,
+	}
+	if !metrics.LastHealthCheck.IsZero() {
+		details["last_health_check"] = metrics.LastHealthCheck
+	}
+	return running && metrics.HealthCheckStatus != "unhealthy", details
+}
+
 // FileSystemConfig represents configuration for the MinIO filesystem
 type FileSystemConfig struct {
 	RetryAttempts     int           `yaml:"retry_attempts" json:"retry_attempts"`
@@ -241,6 +261,8 @@ type FileSystemConfig struct {
 	EnableCaching     bool          `yaml:"enable_caching" json:"enable_caching"`
 	CacheSize         int           `yaml:"cache_size" json:"cache_size"`
 	CacheTTL          time.Duration `yaml:"cache_ttl" json:"cache_ttl"`
+	PartSize          int64         `yaml:"part_size" json:"part_size"`                   // Bytes per multipart upload part
+	UploadConcurrency int           `yaml:"upload_concurrency" json:"upload_concurrency"` // Parts of one object uploaded at once
 }
 
 // FileSystemMetrics tracks filesystem operation metrics
@@ -864,9 +886,11 @@ func NewS3FileSystemWithServer(minioServer *EmbeddedMinIO, bucket, prefix string
 		EnableChecksums:   true,
 		MaxConcurrentOps:  10,
 		OperationTimeout:  DefaultRequestTimeout,
-		EnableCaching:     false,
-		CacheSize:         0,
+		EnableCaching:     true,
+		CacheSize:         objectstore.DefaultCacheSize,
 		CacheTTL:          0,
+		PartSize:          objectstore.DefaultPartSize,
+		UploadConcurrency: objectstore.DefaultUploadConcurrency,
 	}
 
 	// Initialize logger
@@ -875,15 +899,73 @@ func NewS3FileSystemWithServer(minioServer *EmbeddedMinIO, bucket, prefix string
 		logger.SetOutput(io.Discard)
 	}
 
-	return &S3FileSystem{
+	fs := &S3FileSystem{
 		minioServer: minioServer,
 		client:      client,
+		store:       objectstore.NewS3StoreWithClient(client, bucket, ""),
+		tableStore:  objectstore.NewS3StoreWithClient(client, bucket, prefix),
+		pageCache:   objectstore.NewPageCache(int64(config.CacheSize)),
 		bucket:      bucket,
 		prefix:      prefix,
 		config:      config,
 		metrics:     &FileSystemMetrics{},
 		logger:      logger,
-	}, nil
+	}
+	fs.registerMetrics()
+	return fs, nil
+}
+
+// ConfigureTransfers sets the part size and concurrency of uploads and the size of the read
+// cache; zero values keep the current settings and a negative cache size disables the cache
+func (fs *S3FileSystem) ConfigureTransfers(partSize int64, concurrency int, cacheSize int64) {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+
+	if partSize > 0 {
+		fs.config.PartSize = partSize
+	}
+	if concurrency > 0 {
+		fs.config.UploadConcurrency = concurrency
+	}
+	if cacheSize != 0 {
+		fs.config.EnableCaching = cacheSize > 0
+		fs.config.CacheSize = int(cacheSize)
+		fs.pageCache = nil
+		if fs.config.EnableCaching {
+			fs.pageCache = objectstore.NewPageCache(cacheSize)
+		}
+	}
+}
+
+// newUpload starts a streaming multipart upload of an object
+func (fs *S3FileSystem) newUpload(objectName string) *objectstore.UploadWriter {
+	return objectstore.NewUploadWriter(context.Background(), fs.store, objectName, fs.uploadOptions())
+}
+
+// uploadOptions returns the options of uploads with the configured transfer settings
+func (fs *S3FileSystem) uploadOptions() objectstore.UploadOptions {
+	// Each part gets the configured attempts; the upload is aborted once one runs out
+	retries := fs.config.RetryAttempts - 1
+	if retries <= 0 {
+		retries = -1
+	}
+	return objectstore.UploadOptions{
+		PartSize:    fs.config.PartSize,
+		Concurrency: fs.config.UploadConcurrency,
+		Retries:     retries,
+	}
+}
+
+// tables returns the table storage over the bucket with the current transfer settings
+func (fs *S3FileSystem) tables() *objectstore.TableStorage {
+	fs.mu.RLock()
+	defer fs.mu.RUnlock()
+	return objectstore.NewTableStorage(Type, fs.tableStore, fs.uploadOptions(), fs.pageCache)
+}
+
+// newReader opens an object for ranged reads through the page cache
+func (fs *S3FileSystem) newReader(objectName string) (*objectstore.ObjectReader, error) {
+	return objectstore.NewObjectReader(context.Background(), fs.store, objectName, fs.pageCache)
 }
 
 // Open opens a file for reading from MinIO with comprehensive error handling
@@ -910,21 +992,14 @@ func (fs *S3FileSystem) Open(location string) (icebergio.File, error) {
 	// Retry logic for opening files
 	var lastErr error
 	for attempt := 0; attempt < fs.config.RetryAttempts; attempt++ {
-		ctx, cancel := context.WithTimeout(context.Background(), fs.config.OperationTimeout)
-
-		object, err := fs.client.GetObject(ctx, fs.bucket, objectName, minio.GetObjectOptions{})
-		cancel()
-
+		reader, err := fs.newReader(objectName)
 		if err == nil {
 			// Update metrics
 			fs.updateMetrics("read", time.Since(start), 0, false)
 
 			return &minioFile{
-				object:     object,
+				reader:     reader,
 				objectName: objectName,
-				bucket:     fs.bucket,
-				client:     fs.client,
-				position:   0,
 				fs:         fs,
 			}, nil
 		}
@@ -968,9 +1043,7 @@ func (fs *S3FileSystem) Create(location string) (icebergio.File, error) {
 
 	return &minioWriteFile{
 		objectName: objectName,
-		bucket:     fs.bucket,
-		client:     fs.client,
-		buffer:     make([]byte, 0, fs.config.BufferSize),
+		upload:     fs.newUpload(objectName),
 		fs:         fs,
 		startTime:  time.Now(),
 	}, nil
@@ -1087,6 +1160,7 @@ func (fs *S3FileSystem) GetMetrics() *FileSystemMetrics {
 	defer fs.metrics.mu.RUnlock()
 
 	// Create a copy to avoid race conditions (without copying the mutex)
+	cache := fs.pageCache.Stats()
 	metrics := &FileSystemMetrics{
 		ReadOperations:   fs.metrics.ReadOperations,
 		WriteOperations:  fs.metrics.WriteOperations,
@@ -1102,9 +1176,9 @@ func (fs *S3FileSystem) GetMetrics() *FileSystemMetrics {
 		DeleteErrors:     fs.metrics.DeleteErrors,
 		NetworkErrors:    fs.metrics.NetworkErrors,
 		TimeoutErrors:    fs.metrics.TimeoutErrors,
-		CacheHits:        fs.metrics.CacheHits,
-		CacheMisses:      fs.metrics.CacheMisses,
-		CacheEvictions:   fs.metrics.CacheEvictions,
+		CacheHits:        cache.Hits,
+		CacheMisses:      cache.Misses,
+		CacheEvictions:   cache.Evictions,
 	}
 	return metrics
 }
@@ -1157,10 +1231,7 @@ func (fs *S3FileSystem) OpenForRead(path string) (io.ReadCloser, error) {
 
 	objectName := fs.getObjectName(path)
 
-	ctx, cancel := context.WithTimeout(context.Background(), fs.config.OperationTimeout)
-	defer cancel()
-
-	obj, err := fs.client.GetObject(ctx, fs.bucket, objectName, minio.GetObjectOptions{})
+	obj, err := fs.newReader(objectName)
 	if err != nil {
 		fs.incrementErrorMetric("read")
 		return nil, &MinIOError{
@@ -1178,7 +1249,8 @@ func (fs *S3FileSystem) OpenForRead(path string) (io.ReadCloser, error) {
 	return obj, nil
 }
 
-// OpenForWrite opens a file for streaming write
+// OpenForWrite opens a file for streaming write; the object is uploaded in parts as it is
+// written and appears when the writer is closed
 func (fs *S3FileSystem) OpenForWrite(path string) (io.WriteCloser, error) {
 	fs.mu.Lock()
 	defer fs.mu.Unlock()
@@ -1188,39 +1260,37 @@ func (fs *S3FileSystem) OpenForWrite(path string) (io.WriteCloser, error) {
 	return &s3WriteCloser{
 		fs:         fs,
 		objectName: objectName,
-		buffer:     make([]byte, 0, fs.config.BufferSize),
+		upload:     fs.newUpload(objectName),
+		start:      time.Now(),
 	}, nil
 }
 
-// s3WriteCloser implements io.WriteCloser for S3
+// s3WriteCloser implements io.WriteCloser for S3 as a streaming multipart upload
 type s3WriteCloser struct {
 	fs         *S3FileSystem
 	objectName string
-	buffer     []byte
-	closed     bool
+	upload     *objectstore.UploadWriter
+	start      time.Time
 }
 
 func (w *s3WriteCloser) Write(p []byte) (n int, err error) {
-	if w.closed {
-		return 0, fmt.Errorf("write on closed writer")
+	n, err = w.upload.Write(p)
+	if err != nil {
+		w.fs.incrementErrorMetric("write")
+		return n, &MinIOError{
+			Op:  "write",
+			Err: err,
+			Context: map[string]interface{}{
+				"object_name": w.objectName,
+				"bucket":      w.fs.bucket,
+			},
+		}
 	}
-	w.buffer = append(w.buffer, p...)
-	return len(p), nil
+	return n, nil
 }
 
 func (w *s3WriteCloser) Close() error {
-	if w.closed {
-		return nil
-	}
-	w.closed = true
-
-	// Upload the buffered data
-	ctx, cancel := context.WithTimeout(context.Background(), w.fs.config.OperationTimeout)
-	defer cancel()
-
-	reader := bytes.NewReader(w.buffer)
-	_, err := w.fs.client.PutObject(ctx, w.fs.bucket, w.objectName, reader, int64(len(w.buffer)), minio.PutObjectOptions{})
-	if err != nil {
+	if err := w.upload.Close(); err != nil {
 		w.fs.incrementErrorMetric("write")
 		return &MinIOError{
 			Op:  "write_close",
@@ -1228,15 +1298,20 @@ func (w *s3WriteCloser) Close() error {
 			Context: map[string]interface{}{
 				"object_name": w.objectName,
 				"bucket":      w.fs.bucket,
-				"size":        len(w.buffer),
+				"size":        w.upload.Size(),
 			},
 		}
 	}
 
-	w.fs.updateMetrics("write", time.Since(time.Now()), int64(len(w.buffer)), false)
+	w.fs.updateMetrics("write", time.Since(w.start), w.upload.Size(), false)
 	return nil
 }
 
+// Abort discards everything written; no object is created
+func (w *s3WriteCloser) Abort() error {
+	return w.upload.Abort()
+}
+
 // ensureBucket creates a bucket if it doesn't exist with comprehensive error handling
 func (m *EmbeddedMinIO) ensureBucket(ctx context.Context, bucketName string) error {
 	if err := validateBucketName(bucketName); err != nil {
@@ -1873,107 +1948,64 @@ func (fs *S3FileSystem) getObjectName(location string) string {
 	return location
 }
 
-// Enhanced minioFile implementation with comprehensive error handling and performance optimizations
+// minioFile reads an object with ranged GETs: ReadAt fetches only the requested bytes, Seek
+// takes effect on the next Read, and footers and other small reads are served from the
+// filesystem's page cache
 type minioFile struct {
-	object     *minio.Object
+	reader     *objectstore.ObjectReader
 	objectName string
-	bucket     string
-	client     *minio.Client
-	position   int64
 	fs         *S3FileSystem
 	requestID  string
-	mu         sync.Mutex
 }
 
 func (f *minioFile) Read(p []byte) (n int, err error) {
-	f.mu.Lock()
-	defer f.mu.Unlock()
-
 	start := time.Now()
-	n, err = f.object.Read(p)
+	n, err = f.reader.Read(p)
 
-	if err == nil {
-		f.position += int64(n)
+	if err == nil || err == io.EOF {
 		f.fs.updateMetrics("read", time.Since(start), int64(n), false)
-	} else {
-		f.fs.updateMetrics("read", time.Since(start), 0, true)
+		return n, err
 	}
 
-	return n, err
+	f.fs.updateMetrics("read", time.Since(start), int64(n), true)
+	return n, &MinIOError{
+		Op:  "read",
+		Err: err,
+		Context: map[string]interface{}{
+			"object_name": f.objectName,
+			"request_id":  f.requestID,
+		},
+	}
 }
 
 func (f *minioFile) ReadAt(p []byte, off int64) (n int, err error) {
 	start := time.Now()
+	n, err = f.reader.ReadAt(p, off)
 
-	ctx, cancel := context.WithTimeout(context.Background(), f.fs.config.OperationTimeout)
-	defer cancel()
-
-	opts := minio.GetObjectOptions{}
-	if err := opts.SetRange(off, off+int64(len(p))-1); err != nil {
-		f.fs.updateMetrics("read", time.Since(start), 0, true)
-		return 0, &MinIOError{
-			Op:  "set_range",
-			Err: err,
-			Context: map[string]interface{}{
-				"offset":      off,
-				"length":      len(p),
-				"object_name": f.objectName,
-				"request_id":  f.requestID,
-			},
-		}
-	}
-
-	obj, err := f.client.GetObject(ctx, f.bucket, f.objectName, opts)
-	if err != nil {
-		f.fs.updateMetrics("read", time.Since(start), 0, true)
-		return 0, &MinIOError{
-			Op:  "get_object_range",
-			Err: err,
-			Context: map[string]interface{}{
-				"offset":      off,
-				"length":      len(p),
-				"object_name": f.objectName,
-				"request_id":  f.requestID,
-			},
-		}
-	}
-	defer obj.Close()
-
-	n, err = obj.Read(p)
-	if err != nil && err != io.EOF {
-		f.fs.updateMetrics("read", time.Since(start), int64(n), true)
-	} else {
+	if err == nil || err == io.EOF {
 		f.fs.updateMetrics("read", time.Since(start), int64(n), false)
+		return n, err
 	}
 
-	return n, err
+	f.fs.updateMetrics("read", time.Since(start), 0, true)
+	return n, &MinIOError{
+		Op:  "get_object_range",
+		Err: err,
+		Context: map[string]interface{}{
+			"offset":      off,
+			"length":      len(p),
+			"object_name": f.objectName,
+			"request_id":  f.requestID,
+		},
+	}
 }
 
 func (f *minioFile) Seek(offset int64, whence int) (int64, error) {
-	f.mu.Lock()
-	defer f.mu.Unlock()
-
-	var newPos int64
-	switch whence {
-	case io.SeekStart:
-		newPos = offset
-	case io.SeekCurrent:
-		newPos = f.position + offset
-	case io.SeekEnd:
-		return 0, &MinIOError{
-			Op:  "seek",
-			Err: fmt.Errorf("seek from end not supported for MinIO objects"),
-			Context: map[string]interface{}{
-				"whence":      whence,
-				"offset":      offset,
-				"object_name": f.objectName,
-				"request_id":  f.requestID,
-			},
-		}
-	default:
+	pos, err := f.reader.Seek(offset, whence)
+	if err != nil {
 		return 0, &MinIOError{
 			Op:  "seek",
-			Err: fmt.Errorf("invalid whence value: %d", whence),
+			Err: err,
 			Context: map[string]interface{}{
 				"whence":      whence,
 				"offset":      offset,
@@ -1982,97 +2014,28 @@ func (f *minioFile) Seek(offset int64, whence int) (int64, error) {
 			},
 		}
 	}
-
-	if newPos < 0 {
-		return 0, &MinIOError{
-			Op:  "seek",
-			Err: fmt.Errorf("negative position not allowed"),
-			Context: map[string]interface{}{
-				"new_position": newPos,
-				"object_name":  f.objectName,
-				"request_id":   f.requestID,
-			},
-		}
-	}
-
-	// Close current object and reopen from new position
-	if f.object != nil {
-		f.object.Close()
-	}
-
-	ctx, cancel := context.WithTimeout(context.Background(), f.fs.config.OperationTimeout)
-	defer cancel()
-
-	opts := minio.GetObjectOptions{}
-	if newPos > 0 {
-		if err := opts.SetRange(newPos, -1); err != nil {
-			return 0, &MinIOError{
-				Op:  "set_range_seek",
-				Err: err,
-				Context: map[string]interface{}{
-					"new_position": newPos,
-					"object_name":  f.objectName,
-					"request_id":   f.requestID,
-				},
-			}
-		}
-	}
-
-	object, err := f.client.GetObject(ctx, f.bucket, f.objectName, opts)
-	if err != nil {
-		return 0, &MinIOError{
-			Op:  "get_object_seek",
-			Err: err,
-			Context: map[string]interface{}{
-				"new_position": newPos,
-				"object_name":  f.objectName,
-				"request_id":   f.requestID,
-			},
-		}
-	}
-
-	f.object = object
-	f.position = newPos
-	return newPos, nil
+	return pos, nil
 }
 
 func (f *minioFile) Stat() (os.FileInfo, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), f.fs.config.OperationTimeout)
-	defer cancel()
-
-	objInfo, err := f.client.StatObject(ctx, f.bucket, f.objectName, minio.StatObjectOptions{})
-	if err != nil {
-		return nil, &MinIOError{
-			Op:  "stat_object",
-			Err: err,
-			Context: map[string]interface{}{
-				"object_name": f.objectName,
-				"bucket":      f.bucket,
-				"request_id":  f.requestID,
-			},
-		}
-	}
-	return &minioFileInfo{objInfo: objInfo}, nil
+	info := f.reader.Info()
+	return &minioFileInfo{objInfo: minio.ObjectInfo{
+		Key:          f.objectName,
+		Size:         info.Size,
+		LastModified: info.ModTime,
+		ETag:         info.ETag,
+	}}, nil
 }
 
 func (f *minioFile) Close() error {
-	f.mu.Lock()
-	defer f.mu.Unlock()
-
-	if f.object != nil {
-		err := f.object.Close()
-		f.object = nil
-		return err
-	}
-	return nil
+	return f.reader.Close()
 }
 
-// Enhanced minioWriteFile implementation with buffering and comprehensive error handling
+// minioWriteFile streams writes to an object as a multipart upload, so memory stays bounded
+// by the part size and upload concurrency however large the file grows
 type minioWriteFile struct {
 	objectName string
-	bucket     string
-	client     *minio.Client
-	buffer     []byte
+	upload     *objectstore.UploadWriter
 	fs         *S3FileSystem
 	requestID  string
 	startTime  time.Time
@@ -2121,7 +2084,7 @@ func (f *minioWriteFile) Stat() (os.FileInfo, error) {
 
 	return &minioWriteFileInfo{
 		name: filepath.Base(f.objectName),
-		size: int64(len(f.buffer)),
+		size: f.upload.Size(),
 	}, nil
 }
 
@@ -2129,81 +2092,41 @@ func (f *minioWriteFile) Write(p []byte) (n int, err error) {
 	f.mu.Lock()
 	defer f.mu.Unlock()
 
-	// Check buffer size limits
-	if len(f.buffer)+len(p) > MaxBufferSize {
-		return 0, &MinIOError{
-			Op:  "write_buffer_overflow",
-			Err: fmt.Errorf("write would exceed maximum buffer size of %d bytes", MaxBufferSize),
+	n, err = f.upload.Write(p)
+	if err != nil {
+		return n, &MinIOError{
+			Op:  "write",
+			Err: err,
 			Context: map[string]interface{}{
-				"current_size": len(f.buffer),
-				"write_size":   len(p),
-				"max_size":     MaxBufferSize,
-				"object_name":  f.objectName,
-				"request_id":   f.requestID,
+				"object_name": f.objectName,
+				"request_id":  f.requestID,
 			},
 		}
 	}
-
-	f.buffer = append(f.buffer, p...)
-	return len(p), nil
+	return n, nil
 }
 
 func (f *minioWriteFile) Close() error {
 	f.mu.Lock()
 	defer f.mu.Unlock()
 
-	start := time.Now()
-
-	// Retry logic for upload
-	var lastErr error
-	for attempt := 0; attempt < f.fs.config.RetryAttempts; attempt++ {
-		ctx, cancel := context.WithTimeout(context.Background(), f.fs.config.OperationTimeout)
-
-		// Use enhanced PutObject with proper options
-		putOptions := minio.PutObjectOptions{
-			ContentType: "application/octet-stream",
-		}
-
-		// Enable compression if configured
-		if f.fs.config.EnableCompression {
-			putOptions.ContentEncoding = "gzip"
-		}
-
-		_, lastErr = f.client.PutObject(
-			ctx,
-			f.bucket,
-			f.objectName,
-			bytes.NewReader(f.buffer),
-			int64(len(f.buffer)),
-			putOptions,
-		)
-		cancel()
-
-		if lastErr == nil {
-			// Update metrics on success
-			f.fs.updateMetrics("write", time.Since(start), int64(len(f.buffer)), false)
-			return nil
-		}
-
-		if attempt < f.fs.config.RetryAttempts-1 {
-			time.Sleep(f.fs.config.RetryDelay * time.Duration(attempt+1))
+	// Parts were uploaded while writing, each retried on its own; a failed upload is aborted
+	if err := f.upload.Close(); err != nil {
+		f.fs.updateMetrics("write", time.Since(f.startTime), 0, true)
+		return &MinIOError{
+			Op:  "put_object",
+			Err: err,
+			Context: map[string]interface{}{
+				"object_name": f.objectName,
+				"bucket":      f.fs.bucket,
+				"size":        f.upload.Size(),
+				"request_id":  f.requestID,
+			},
 		}
 	}
 
-	// Update metrics on failure
-	f.fs.updateMetrics("write", time.Since(start), 0, true)
-
-	return &MinIOError{
-		Op:  "put_object",
-		Err: lastErr,
-		Context: map[string]interface{}{
-			"object_name": f.objectName,
-			"bucket":      f.bucket,
-			"size":        len(f.buffer),
-			"attempts":    f.fs.config.RetryAttempts,
-			"request_id":  f.requestID,
-		},
-	}
+	f.fs.updateMetrics("write", time.Since(f.startTime), f.upload.Size(), false)
+	return nil
 }
 
 // FileInfo implementations with enhanced metadata
@@ -2231,171 +2154,50 @@ func (fi *minioWriteFileInfo) IsDir() bool        { return false }
 func (fi *minioWriteFileInfo) Sys() interface{}   { return nil }
 
 // ============================================================================
-// STORAGE ENVIRONMENT PREPARATION METHODS - Placeholder implementations for now
+// TABLE STORAGE METHODS
 // ============================================================================
 
-// SetupTable creates the storage environment for a table
+// SetupTable creates the storage environment for a table; objects need none
 func (s3fs *S3FileSystem) SetupTable(database, tableName string) error {
-	// TODO: Implement table environment preparation in S3 with database namespace
-	// For now, just return success
-	return nil
+	return s3fs.tables().SetupTable(database, tableName)
 }
 
-// RemoveTableEnvironment removes the storage environment for a table
+// RemoveTableEnvironment removes every object of the table
 func (s3fs *S3FileSystem) RemoveTableEnvironment(database, tableName string) error {
-	// TODO: Implement table environment removal from S3 with database namespace
-	// For now, just return success
-	return nil
+	return s3fs.tables().RemoveTableEnvironment(database, tableName)
 }
 
-// OpenTableForWrite opens a table for streaming write operations
+// OpenTableForWrite opens a writer for a new data object of the table
 func (s3fs *S3FileSystem) OpenTableForWrite(database, tableName string) (io.WriteCloser, error) {
-	// Ensure table environment exists
-	if err := s3fs.SetupTable(database, tableName); err != nil {
-		return nil, fmt.Errorf("failed to setup table environment: %w", err)
-	}
-
-	// Create data file path
-	dataPath := filepath.Join("tables", database, tableName, "data", "data.json")
-	objectName := s3fs.getObjectName(dataPath)
-
-	return &s3TableWriter{
-		fs:         s3fs,
-		bucket:     s3fs.bucket,
-		objectName: objectName,
-		buffer:     make([]byte, 0),
-	}, nil
+	return s3fs.tables().OpenTableForWrite(database, tableName)
 }
 
-// OpenTableForRead opens a table for streaming read operations
+// OpenTableForRead opens a reader over the data objects of the table in insertion order
 func (s3fs *S3FileSystem) OpenTableForRead(database, tableName string) (io.ReadCloser, error) {
-	dataPath := filepath.Join("tables", database, tableName, "data", "data.json")
-	objectName := s3fs.getObjectName(dataPath)
-
-	// Check if object exists
-	ctx, cancel := context.WithTimeout(context.Background(), s3fs.config.OperationTimeout)
-	defer cancel()
-
-	_, err := s3fs.client.StatObject(ctx, s3fs.bucket, objectName, minio.StatObjectOptions{})
-	if err != nil {
-		return nil, fmt.Errorf("table data file does not exist: %w", err)
-	}
-
-	// Get object info for size
-	objInfo, err := s3fs.client.StatObject(ctx, s3fs.bucket, objectName, minio.StatObjectOptions{})
-	if err != nil {
-		return nil, fmt.Errorf("failed to get object info: %w", err)
-	}
-
-	// Return streaming reader
-	return &s3TableReader{
-		fs:         s3fs,
-		bucket:     s3fs.bucket,
-		objectName: objectName,
-		size:       objInfo.Size,
-	}, nil
+	return s3fs.tables().OpenTableForRead(database, tableName)
 }
 
-// s3TableWriter implements io.WriteCloser for S3 table operations
-type s3TableWriter struct {
-	fs         *S3FileSystem
-	bucket     string
-	objectName string
-	buffer     []byte
-	closed     bool
+// WriteDataFiles writes rows into new Parquet objects of the table
+func (s3fs *S3FileSystem) WriteDataFiles(schema *arrow.Schema, config *parquet.ParquetConfig, database, tableName string, data [][]interface{}) ([]*parquet.FileInfo, error) {
+	return s3fs.tables().WriteDataFiles(schema, config, database, tableName, data)
 }
 
-func (stw *s3TableWriter) Write(p []byte) (n int, err error) {
-	if stw.closed {
-		return 0, fmt.Errorf("write on closed writer")
-	}
-	stw.buffer = append(stw.buffer, p...)
-	return len(p), nil
-}
-
-func (stw *s3TableWriter) Close() error {
-	if stw.closed {
-		return nil
-	}
-	stw.closed = true
-
-	if len(stw.buffer) == 0 {
-		return nil
-	}
-
-	// Upload the buffered data
-	ctx, cancel := context.WithTimeout(context.Background(), stw.fs.config.OperationTimeout)
-	defer cancel()
-
-	reader := bytes.NewReader(stw.buffer)
-	_, err := stw.fs.client.PutObject(ctx, stw.bucket, stw.objectName, reader, int64(len(stw.buffer)), minio.PutObjectOptions{})
-	if err != nil {
-		return &MinIOError{
-			Op:  "put_object",
-			Err: err,
-			Context: map[string]interface{}{
-				"object_name": stw.objectName,
-				"bucket":      stw.bucket,
-				"size":        len(stw.buffer),
-			},
-		}
-	}
-
-	return nil
+// WriteRecordFiles writes records with the table schema into new Parquet objects of the table
+func (s3fs *S3FileSystem) WriteRecordFiles(schema *arrow.Schema, config *parquet.ParquetConfig, database, tableName string, records []arrow.Record) ([]*parquet.FileInfo, error) {
+	return s3fs.tables().WriteRecordFiles(schema, config, database, tableName, records)
 }
 
-// s3TableReader implements io.ReadCloser for S3 table operations
-type s3TableReader struct {
-	fs         *S3FileSystem
-	bucket     string
-	objectName string
-	size       int64
-	position   int64
-	closed     bool
+// RemoveDataFiles deletes data objects of tables
+func (s3fs *S3FileSystem) RemoveDataFiles(files []*parquet.FileInfo) error {
+	return s3fs.tables().RemoveDataFiles(files)
 }
 
-func (str *s3TableReader) Read(p []byte) (n int, err error) {
-	if str.closed {
-		return 0, io.EOF
-	}
-
-	if str.position >= str.size {
-		return 0, io.EOF
-	}
-
-	// Calculate how much to read
-	remaining := str.size - str.position
-	toRead := int64(len(p))
-	if toRead > remaining {
-		toRead = remaining
-	}
-
-	// Read chunk from S3
-	ctx, cancel := context.WithTimeout(context.Background(), str.fs.config.OperationTimeout)
-	defer cancel()
-
-	obj, err := str.fs.client.GetObject(ctx, str.bucket, str.objectName, minio.GetObjectOptions{})
-	if err != nil {
-		return 0, fmt.Errorf("failed to get object: %w", err)
-	}
-	defer obj.Close()
-
-	// Seek to current position
-	if _, err := obj.Seek(str.position, 0); err != nil {
-		return 0, fmt.Errorf("failed to seek in object: %w", err)
-	}
-
-	// Read the chunk
-	n, err = obj.Read(p[:toRead])
-	if err != nil && err != io.EOF {
-		return n, fmt.Errorf("failed to read object chunk: %w", err)
-	}
-
-	str.position += int64(n)
-	return n, err
+// ReadDataFiles reads the rows of every Parquet object of the table, oldest object first
+func (s3fs *S3FileSystem) ReadDataFiles(database, tableName string) ([][]interface{}, error) {
+	return s3fs.tables().ReadDataFiles(database, tableName)
 }
 
-func (str *s3TableReader) Close() error {
-	str.closed = true
-	return nil
+// ReadDataFilesMatching reads the rows of the Parquet objects of the table matching probe
+func (s3fs *S3FileSystem) ReadDataFilesMatching(database, tableName string, probe *parquet.EqualityProbe) ([][]interface{}, error) {
+	return s3fs.tables().ReadDataFilesMatching(database, tableName, probe)
 }
@@ -829,14 +829,6 @@ func TestReadFileOperations(t *testing.T) {
 		operation func() error
 		errorOp   string
 	}{
-		{
-			name: "seek from end",
-			operation: func() error {
-				_, err := readFile.Seek(0, io.SeekEnd)
-				return err
-			},
-			errorOp: "seek",
-		},
 		{
 			name: "seek with invalid whence",
 			operation: func() error {
@@ -1037,7 +1029,7 @@ func TestConcurrentFileOperations(t *testing.T) {
 }
 
 // Buffer Overflow Tests
-func TestBufferOverflow(t *testing.T) {
+func TestLargeWriteStreams(t *testing.T) {
 	t.Skip("Skipping MinIO tests for now")
 	config := createTestConfig()
 	config.BufferSize = 1024 // Small buffer for testing
@@ -1047,18 +1039,25 @@ func TestBufferOverflow(t *testing.T) {
 	fs, err := NewS3FileSystemWithServer(server, testBucket, testPrefix)
 	require.NoError(t, err)
 
-	writeFile, err := fs.Create("overflow-test.txt")
+	writeFile, err := fs.Create("large-write-test.bin")
 	require.NoError(t, err)
 
-	// Try to write data larger than max buffer size
-	largeData := make([]byte, MaxBufferSize+1)
+	// Data larger than the old buffer limit is uploaded in parts rather than rejected
+	largeData := generateTestData(MaxBufferSize + 1)
 	_, err = writeToMinIOFile(t, writeFile, largeData)
-	assert.Error(t, err)
+	require.NoError(t, err)
+	require.NoError(t, writeFile.Close())
 
-	var minioErr *MinIOError
-	if assert.ErrorAs(t, err, &minioErr) {
-		assert.Equal(t, "write_buffer_overflow", minioErr.Op)
-	}
+	readFile, err := fs.Open("large-write-test.bin")
+	require.NoError(t, err)
+	defer readFile.Close()
+
+	pos, err := readFile.Seek(-10, io.SeekEnd)
+	require.NoError(t, err)
+	assert.Equal(t, int64(len(largeData)-10), pos)
+	tail, err := io.ReadAll(readFile)
+	require.NoError(t, err)
+	assert.Equal(t, largeData[len(largeData)-10:], tail)
 }
 
 // Retry Logic Tests
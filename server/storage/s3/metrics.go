@@ -0,0 +1,43 @@
+package s3
+
+import "github.com/gear6io/ranger/server/metrics"
+
+// registerMetrics exposes the filesystem's operation statistics
+func (fs *S3FileSystem) registerMetrics() {
+	metrics.Register(metrics.NewStatsCollector("s3", fs.GetMetrics,
+		metrics.Counter("read_operations_total", "Object reads.",
+			func(m *FileSystemMetrics) float64 { return float64(m.ReadOperations) }),
+		metrics.Counter("write_operations_total", "Object writes.",
+			func(m *FileSystemMetrics) float64 { return float64(m.WriteOperations) }),
+		metrics.Counter("delete_operations_total", "Object deletions.",
+			func(m *FileSystemMetrics) float64 { return float64(m.DeleteOperations) }),
+		metrics.Counter("list_operations_total", "Object listings.",
+			func(m *FileSystemMetrics) float64 { return float64(m.ListOperations) }),
+		metrics.Counter("read_bytes_total", "Bytes read from objects.",
+			func(m *FileSystemMetrics) float64 { return float64(m.BytesRead) }),
+		metrics.Counter("written_bytes_total", "Bytes written to objects.",
+			func(m *FileSystemMetrics) float64 { return float64(m.BytesWritten) }),
+		metrics.Gauge("read_latency_seconds", "Average object read latency.",
+			func(m *FileSystemMetrics) float64 { return float64(m.AvgReadLatency) / 1000 }),
+		metrics.Gauge("write_latency_seconds", "Average object write latency.",
+			func(m *FileSystemMetrics) float64 { return float64(m.AvgWriteLatency) / 1000 }),
+		metrics.Gauge("delete_latency_seconds", "Average object deletion latency.",
+			func(m *FileSystemMetrics) float64 { return float64(m.AvgDeleteLatency) / 1000 }),
+		metrics.Counter("read_errors_total", "Failed object reads.",
+			func(m *FileSystemMetrics) float64 { return float64(m.ReadErrors) }),
+		metrics.Counter("write_errors_total", "Failed object writes.",
+			func(m *FileSystemMetrics) float64 { return float64(m.WriteErrors) }),
+		metrics.Counter("delete_errors_total", "Failed object deletions.",
+			func(m *FileSystemMetrics) float64 { return float64(m.DeleteErrors) }),
+		metrics.Counter("network_errors_total", "Object operations that failed on the network.",
+			func(m *FileSystemMetrics) float64 { return float64(m.NetworkErrors) }),
+		metrics.Counter("timeout_errors_total", "Object operations that timed out.",
+			func(m *FileSystemMetrics) float64 { return float64(m.TimeoutErrors) }),
+		metrics.Counter("cache_hits_total", "Reads served from the page cache.",
+			func(m *FileSystemMetrics) float64 { return float64(m.CacheHits) }),
+		metrics.Counter("cache_misses_total", "Pages fetched from the object store.",
+			func(m *FileSystemMetrics) float64 { return float64(m.CacheMisses) }),
+		metrics.Counter("cache_evictions_total", "Pages evicted from the page cache.",
+			func(m *FileSystemMetrics) float64 { return float64(m.CacheEvictions) }),
+	))
+}
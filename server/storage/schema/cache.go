@@ -7,6 +7,7 @@ import (
 
 	"github.com/apache/iceberg-go"
 	"github.com/gear6io/ranger/server/config"
+	"github.com/gear6io/ranger/server/metrics"
 	"github.com/jellydator/ttlcache/v3"
 )
 
@@ -64,10 +65,27 @@ func NewSchemaCache(config *config.SchemaManagerConfig) *SchemaCache {
 
 	// Set up event handlers for metrics
 	sc.setupEventHandlers()
+	sc.registerMetrics()
 
 	return sc
 }
 
+// registerMetrics exposes the cache statistics
+func (sc *SchemaCache) registerMetrics() {
+	metrics.Register(metrics.NewStatsCollector("schema_cache", sc.GetStats,
+		metrics.Counter("hits_total", "Schema cache hits.",
+			func(s CacheStats) float64 { return float64(s.HitCount) }),
+		metrics.Counter("misses_total", "Schema cache misses.",
+			func(s CacheStats) float64 { return float64(s.MissCount) }),
+		metrics.Counter("evictions_total", "Schemas evicted from the cache.",
+			func(s CacheStats) float64 { return float64(s.EvictCount) }),
+		metrics.Gauge("entries", "Schemas in the cache.",
+			func(s CacheStats) float64 { return float64(s.CacheSize) }),
+		metrics.Gauge("memory_bytes", "Estimated memory used by cached schemas.",
+			func(s CacheStats) float64 { return float64(s.MemoryUsage) }),
+	))
+}
+
 // setupEventHandlers configures event handlers for cache metrics
 func (sc *SchemaCache) setupEventHandlers() {
 	sc.cache.OnInsertion(func(ctx context.Context, item *ttlcache.Item[string, *SchemaCacheEntry]) {
@@ -249,6 +249,16 @@ func (s *Schema) PerformMaintenanceCleanup() error {
 	return nil
 }
 
+// GetTableSettings returns the registry settings of a table, such as its batch size and
+// validation limits; it is nil when the table has none
+func (s *Schema) GetTableSettings(ctx context.Context, database, tableName string) (*regtypes.TableMetadata, error) {
+	schemaData, err := s.schemaLoader(ctx, database, tableName)
+	if err != nil {
+		return nil, err
+	}
+	return schemaData.Metadata, nil
+}
+
 // GetParquetConfigForTable returns the resolved parquet configuration for a table
 // This is the main function that storage managers will use to get parquet config
 func (s *Schema) GetParquetConfigForTable(ctx context.Context, database, tableName string) (*parquet.ParquetConfig, error) {
@@ -281,6 +291,16 @@ func (s *Schema) applyTableMetadataToConfig(config *parquet.ParquetConfig, metad
 		config.BatchSize = metadata.BatchSize
 	}
 
+	// Apply the sort key of CREATE TABLE ... ORDER BY
+	if metadata.SortOrder != "" {
+		var sortOrder []string
+		if err := json.Unmarshal([]byte(metadata.SortOrder), &sortOrder); err != nil {
+			return errors.New(ErrSchemaManagerRetrievalError, "failed to parse table sort order JSON", err).
+				AddContext("sort_order", metadata.SortOrder)
+		}
+		config.SortOrder = sortOrder
+	}
+
 	// Parse Settings JSON for additional parquet-specific settings
 	if metadata.Settings != "" {
 		var settings map[string]interface{}
@@ -299,9 +319,6 @@ func (s *Schema) applyTableMetadataToConfig(config *parquet.ParquetConfig, metad
 		if chunkSize, ok := settings["chunk_size"].(float64); ok {
 			config.ChunkSize = int(chunkSize)
 		}
-		if compressionLevel, ok := settings["compression_level"].(float64); ok {
-			config.CompressionLevel = int(compressionLevel)
-		}
 		if enableStats, ok := settings["enable_stats"].(bool); ok {
 			config.EnableStats = enableStats
 		}
@@ -315,14 +332,14 @@ func (s *Schema) applyTableMetadataToConfig(config *parquet.ParquetConfig, metad
 			config.RotationTimeout = int64(rotationTimeout)
 		}
 
-		// Handle column-specific compression
-		if columnCompression, ok := settings["column_compression"].(map[string]interface{}); ok {
-			config.ColumnCompression = make(map[string]string)
-			for col, comp := range columnCompression {
-				if compStr, ok := comp.(string); ok {
-					config.ColumnCompression[col] = compStr
-				}
-			}
+		// Bloom filters and page indexes requested for the data files
+		if err := parquet.ApplyIndexSettings(config, settings); err != nil {
+			return errors.AddContext(err, "settings", metadata.Settings)
+		}
+
+		// Codecs, encodings and sizes of the data files
+		if err := parquet.ApplyWriterSettings(config, settings); err != nil {
+			return errors.AddContext(err, "settings", metadata.Settings)
 		}
 	}
 
@@ -0,0 +1,78 @@
+package storage
+
+import (
+	"context"
+
+	"github.com/apache/arrow-go/v18/arrow"
+	"github.com/gear6io/ranger/pkg/errors"
+	"github.com/gear6io/ranger/server/storage/memory"
+)
+
+// memorySpill keeps the batches that memory tables spill as Parquet files of the same tables
+// in an engine storing data files. The files are registered in the file lists of the tables
+// like inserted ones, without counting their rows again.
+type memorySpill struct {
+	storage *Storage
+	engine  string
+	store   DataFileStore
+	files   FileSystem
+}
+
+// newMemorySpill returns the spill target of memory tables in the named engine, or nil when
+// the engine does not store data files
+func (s *Storage) newMemorySpill(engineName string) *memorySpill {
+	engine, err := s.GetEngine(engineName)
+	if err != nil {
+		return nil
+	}
+	store, ok := engine.(DataFileStore)
+	if !ok {
+		return nil
+	}
+	return &memorySpill{storage: s, engine: engineName, store: store, files: engine}
+}
+
+// SpillRecords writes records spilled from a table to new Parquet files with the table
+// schema and registers them
+func (m *memorySpill) SpillRecords(database, tableName string, records []arrow.Record) error {
+	ctx := context.Background()
+	arrowSchema, err := m.storage.tableArrowSchema(ctx, database, tableName)
+	if err != nil {
+		return err
+	}
+	config, err := m.storage.tableParquetConfig(ctx, database, tableName)
+	if err != nil {
+		return err
+	}
+
+	// Memory tables may hold records with inferred columns, so the rows are written anew
+	rows := recordsToRows(records)
+	files, err := m.store.WriteDataFiles(arrowSchema, config, database, tableName, rows)
+	if err != nil {
+		return errors.New(StorageManagerWriteFailed, "failed to write spilled data files", err).AddContext("database", database).AddContext("tableName", tableName)
+	}
+	if err := m.storage.registerDataFiles(ctx, m.store, database, tableName, files, true); err != nil {
+		return err
+	}
+
+	m.storage.logger.Debug().
+		Str("database", database).
+		Str("table", tableName).
+		Str("storage_engine", m.engine).
+		Int("rows", len(rows)).
+		Int("files", len(files)).
+		Msg("Memory table spilled to Parquet files")
+	return nil
+}
+
+// ReadSpilled returns the rows of the Parquet files spilled from a table, oldest first
+func (m *memorySpill) ReadSpilled(database, tableName string) ([][]interface{}, error) {
+	return m.store.ReadDataFiles(database, tableName)
+}
+
+// RemoveSpilled deletes the Parquet files spilled from a table
+func (m *memorySpill) RemoveSpilled(database, tableName string) error {
+	return m.files.RemoveTableEnvironment(database, tableName)
+}
+
+var _ memory.Spiller = (*memorySpill)(nil)
@@ -6,26 +6,33 @@ import (
 	"fmt"
 	"io"
 	"os"
+	"path/filepath"
 	"time"
 
 	"bufio"
 	"strings"
 
+	"github.com/apache/arrow-go/v18/arrow"
+	"github.com/apache/arrow-go/v18/arrow/array"
 	"github.com/gear6io/ranger/pkg/errors"
 	"github.com/gear6io/ranger/server/catalog"
 	"github.com/gear6io/ranger/server/config"
 	"github.com/gear6io/ranger/server/metadata"
 	"github.com/gear6io/ranger/server/metadata/registry"
 	"github.com/gear6io/ranger/server/metadata/registry/regtypes"
+	"github.com/gear6io/ranger/server/metrics"
 	"github.com/gear6io/ranger/server/paths"
 	"github.com/gear6io/ranger/server/query/parser"
 	"github.com/gear6io/ranger/server/storage/filesystem"
 	"github.com/gear6io/ranger/server/storage/memory"
+	"github.com/gear6io/ranger/server/storage/objectstore"
 	"github.com/gear6io/ranger/server/storage/parquet"
 	"github.com/gear6io/ranger/server/storage/s3"
 	"github.com/gear6io/ranger/server/storage/schema"
+	"github.com/gear6io/ranger/server/tracing"
 	"github.com/gear6io/ranger/server/types"
 	"github.com/rs/zerolog"
+	"go.opentelemetry.io/otel/attribute"
 )
 
 // Package-specific error codes for storage management
@@ -77,6 +84,46 @@ type FileSystem interface {
 	RemoveTableEnvironment(database, tableName string) error
 }
 
+// DataFileStore is implemented by engines that write each insert to new immutable Parquet
+// files, which are registered in the Registry one by one instead of streamed into the table
+type DataFileStore interface {
+	WriteDataFiles(schema *arrow.Schema, config *parquet.ParquetConfig, database, tableName string, data [][]interface{}) ([]*parquet.FileInfo, error)
+	WriteRecordFiles(schema *arrow.Schema, config *parquet.ParquetConfig, database, tableName string, records []arrow.Record) ([]*parquet.FileInfo, error)
+	RemoveDataFiles(files []*parquet.FileInfo) error
+	ReadDataFiles(database, tableName string) ([][]interface{}, error)
+	ReadDataFilesMatching(database, tableName string, probe *parquet.EqualityProbe) ([][]interface{}, error)
+}
+
+// Inserts into S3 tables become Parquet objects, registered like the files of filesystem tables
+var (
+	_ DataFileStore = (*s3.S3FileSystem)(nil)
+	_ DataFileStore = (*objectstore.TableStorage)(nil)
+)
+
+// tableWriterAborter is implemented by table writers that can discard everything written to
+// them, so that a failed insert leaves the data of the table as it was
+type tableWriterAborter interface {
+	Abort() error
+}
+
+// TableSettingsEngine is implemented by engines that take the SETTINGS of CREATE TABLE, which
+// are validated before the table is registered and applied when it is set up
+type TableSettingsEngine interface {
+	ValidateTableSettings(settings map[string]interface{}) error
+	SetupTableWithSettings(database, tableName string, settings map[string]interface{}) error
+}
+
+// TableSettingsUpdater is implemented by engines whose tables take the SETTINGS of ALTER TABLE
+type TableSettingsUpdater interface {
+	UpdateTableSettings(database, tableName string, settings map[string]interface{}) error
+}
+
+// TieredTableReader is implemented by engines that move older rows of their tables to
+// another tier, and read the rows of both
+type TieredTableReader interface {
+	ReadTable(database, tableName string) ([][]interface{}, error)
+}
+
 // NewManager creates a new data storage manager
 func NewStorage(ctx context.Context, cfg *config.Config, logger zerolog.Logger, meta *metadata.MetadataManager) (*Storage, error) {
 	// Get the base data path (already validated in config layer)
@@ -115,6 +162,7 @@ func NewStorage(ctx context.Context, cfg *config.Config, logger zerolog.Logger,
 		pathManager:           pathManager,
 		catalog:               catalog,
 	}
+	storage.registerMetrics()
 
 	// Initialize storage engines with the PathManager
 	if err := storage.initializeStorageEngines(cfg); err != nil {
@@ -135,21 +183,41 @@ func (s *Storage) initializeStorageEngines(cfg *config.Config) error {
 	fsEngine := filesystem.NewFileStorage(s.pathManager)
 	s.RegisterEngine(filesystem.Type, fsEngine)
 
-	// Initialize memory engine (no dependencies needed)
-	memEngine, err := memory.NewMemoryStorage()
+	// Initialize memory engine; durable memory tables are recovered under their table paths
+	memEngine, err := memory.NewDurableMemoryStorage(s.pathManager, memory.DefaultSnapshotInterval, s.logger)
 	if err != nil {
 		return err
 	}
 	s.RegisterEngine(memory.Type, memEngine)
 
-	// Initialize S3 engine (if credentials are available)
-	if s3Engine, err := s3.NewS3FileSystem(cfg); err == nil {
+	// Initialize S3 engine on the configured bucket, or on the bundled MinIO when none is set
+	if cfg != nil && cfg.Storage.S3.Endpoint != "" {
+		s.initializeExternalS3Engine(cfg.Storage.S3)
+	} else if s3Engine, err := s3.NewS3FileSystem(cfg); err == nil {
+		if cfg != nil {
+			uploads, cacheSize := s3Transfers(cfg.Storage.S3)
+			s3Engine.ConfigureTransfers(uploads.PartSize, uploads.Concurrency, cacheSize)
+		}
 		s.RegisterEngine(s3.Type, s3Engine)
 		s.logger.Info().Msg("S3 storage engine initialized successfully")
 	} else {
 		s.logger.Warn().Err(err).Msg("S3 storage engine not available (credentials missing or invalid)")
 	}
 
+	// Memory tables over their hot tier spill their oldest batches to the configured engine
+	spillEngine, budget := filesystem.Type, int64(0)
+	if cfg != nil {
+		if cfg.Storage.Memory.SpillEngine != "" {
+			spillEngine = strings.ToUpper(cfg.Storage.Memory.SpillEngine)
+		}
+		budget = int64(cfg.Storage.Memory.HotTierMB) << 20
+	}
+	spill := s.newMemorySpill(spillEngine)
+	if spill == nil {
+		return errors.New(StorageManagerInitializationFailed, "memory spill engine is not available", nil).AddContext("spill_engine", spillEngine)
+	}
+	memEngine.SetSpiller(spill, budget)
+
 	// Set default engine based on available engines
 	if _, exists := s.engines[filesystem.Type]; exists {
 		s.defaultEngine = filesystem.Type
@@ -163,6 +231,56 @@ func (s *Storage) initializeStorageEngines(cfg *config.Config) error {
 	return nil
 }
 
+// s3Transfers returns the upload options and read cache size configured for the S3 engine; an
+// unset cache size selects the default and a negative one disables the cache
+func s3Transfers(s3Cfg config.S3Config) (objectstore.UploadOptions, int64) {
+	uploads := objectstore.UploadOptions{
+		PartSize:    int64(s3Cfg.PartSizeMB) << 20,
+		Concurrency: s3Cfg.UploadConcurrency,
+	}
+	cacheSize := int64(s3Cfg.CacheSizeMB) << 20
+	if s3Cfg.CacheSizeMB == 0 {
+		cacheSize = objectstore.DefaultCacheSize
+	}
+	return uploads, cacheSize
+}
+
+// initializeExternalS3Engine registers the S3 engine on an existing bucket of an S3-compatible
+// endpoint, provided the bucket can be reached
+func (s *Storage) initializeExternalS3Engine(s3Cfg config.S3Config) {
+	store, err := objectstore.NewS3Store(objectstore.S3Config{
+		Endpoint:        s3Cfg.Endpoint,
+		Region:          s3Cfg.Region,
+		Bucket:          s3Cfg.Bucket,
+		Prefix:          s3Cfg.Prefix,
+		AccessKeyID:     s3Cfg.AccessKeyID,
+		SecretAccessKey: s3Cfg.SecretAccessKey,
+		SessionToken:    s3Cfg.SessionToken,
+		UsePathStyle:    s3Cfg.UsePathStyle,
+		Insecure:        s3Cfg.Insecure,
+	})
+	if err == nil {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		err = store.Ping(ctx)
+		cancel()
+	}
+	if err != nil {
+		s.logger.Warn().
+			Err(err).
+			Str("endpoint", s3Cfg.Endpoint).
+			Str("bucket", s3Cfg.Bucket).
+			Msg("S3 storage engine not available (bucket unreachable)")
+		return
+	}
+
+	uploads, cacheSize := s3Transfers(s3Cfg)
+	s.RegisterEngine(s3.Type, objectstore.NewTableStorage(s3.Type, store, uploads, objectstore.NewPageCache(cacheSize)))
+	s.logger.Info().
+		Str("endpoint", s3Cfg.Endpoint).
+		Str("bucket", s3Cfg.Bucket).
+		Msg("S3 storage engine initialized on external bucket")
+}
+
 // initializeSchemaManager initializes the schema manager with proper configuration
 func (s *Storage) initializeSchemaManager(ctx context.Context, cfg *config.Config) error {
 	// Convert config to schema manager config
@@ -233,8 +351,24 @@ func (s *Storage) Shutdown(ctx context.Context) error {
 // Close closes the data storage
 func (s *Storage) Close() error {
 	s.logger.Info().Msg("Closing data storage")
-	// TODO: Implement actual storage cleanup
-	return nil
+
+	// Engines holding files, such as durable memory tables, flush and close them
+	var firstErr error
+	for _, name := range s.ListEngines() {
+		engine, err := s.GetEngine(name)
+		if err != nil {
+			continue
+		}
+		if closer, ok := engine.(io.Closer); ok {
+			if err := closer.Close(); err != nil {
+				s.logger.Error().Err(err).Str("engine", name).Msg("Failed to close storage engine")
+				if firstErr == nil {
+					firstErr = errors.New(StorageManagerCloseFailed, "failed to close storage engine", err).AddContext("engine", name)
+				}
+			}
+		}
+	}
+	return firstErr
 }
 
 // GetStatus returns the current status of the storage manager
@@ -357,7 +491,23 @@ func (s *Storage) CreateTable(ctx context.Context, req *types.CreateTableRequest
 			AddContext("request_id", req.RequestID)
 	}
 
-	// 3. Convert to registry types
+	// 3. Validate the table settings the engine takes before the table is registered
+	engine, err := s.GetEngine(req.StorageEngine)
+	if err != nil {
+		return nil, errors.AddContext(err, "table_name", tableName).
+			AddContext("database", req.Database).
+			AddContext("request_id", req.RequestID)
+	}
+	settingsEngine, hasSettings := engine.(TableSettingsEngine)
+	if hasSettings {
+		if err := settingsEngine.ValidateTableSettings(stmt.Settings); err != nil {
+			return nil, errors.AddContext(err, "table_name", tableName).
+				AddContext("database", req.Database).
+				AddContext("request_id", req.RequestID)
+		}
+	}
+
+	// 4. Convert to registry types
 	var tableRecord *regtypes.Table
 	var columns []*regtypes.TableColumn
 
@@ -372,7 +522,13 @@ func (s *Storage) CreateTable(ctx context.Context, req *types.CreateTableRequest
 		columns = s.convertToColumnRecords(stmt.TableSchema.ColumnDefinitions)
 	}()
 
-	// 4. Create table through metadata manager
+	if err := validateFileSettings(stmt.Settings, columns); err != nil {
+		return nil, errors.AddContext(err, "table_name", tableName).
+			AddContext("database", req.Database).
+			AddContext("request_id", req.RequestID)
+	}
+
+	// 5. Create table through metadata manager
 	tableID, err := s.CreateTableWithSchema(ctx, req.Database, tableRecord, columns)
 	if err != nil {
 		if strings.Contains(err.Error(), "already exists") || strings.Contains(err.Error(), "duplicate") {
@@ -386,16 +542,13 @@ func (s *Storage) CreateTable(ctx context.Context, req *types.CreateTableRequest
 			AddContext("request_id", req.RequestID)
 	}
 
-	// 5. Get storage engine
-	engine, err := s.GetEngine(req.StorageEngine)
-	if err != nil {
-		return nil, errors.AddContext(err, "table_name", tableName).
-			AddContext("database", req.Database).
-			AddContext("request_id", req.RequestID)
-	}
-
 	// 6. Setup storage environment
-	if err := engine.SetupTable(req.Database, tableName); err != nil {
+	if hasSettings {
+		err = settingsEngine.SetupTableWithSettings(req.Database, tableName, stmt.Settings)
+	} else {
+		err = engine.SetupTable(req.Database, tableName)
+	}
+	if err != nil {
 		return nil, err
 	}
 
@@ -425,7 +578,17 @@ func (s *Storage) CreateTable(ctx context.Context, req *types.CreateTableRequest
 }
 
 // InsertData inserts data into a table using streaming for memory efficiency
-func (s *Storage) InsertData(ctx context.Context, database, tableName string, data [][]interface{}) error {
+func (s *Storage) InsertData(ctx context.Context, database, tableName string, data [][]interface{}) (err error) {
+	start := time.Now()
+	ctx, span := tracing.Start(ctx, "storage.insert",
+		attribute.String("db.namespace", database),
+		attribute.String("db.collection.name", tableName),
+		attribute.Int("ranger.rows", len(data)))
+	defer func() {
+		tracing.End(span, err)
+		metrics.ObserveStorageWrite(database+"."+tableName, len(data), err != nil, time.Since(start))
+	}()
+
 	s.logger.Info().
 		Str("database", database).
 		Str("table", tableName).
@@ -437,33 +600,82 @@ func (s *Storage) InsertData(ctx context.Context, database, tableName string, da
 		return errors.New(errors.CommonNotFound, "table does not exist", nil).AddContext("database", database).AddContext("tableName", tableName)
 	}
 
-	// Retrieve schema before processing data
-	icebergSchema, err := s.GetSchema(ctx, database, tableName)
+	// Validate data against schema before any storage operations
+	arrowSchema, err := s.validateInsertData(ctx, database, tableName, data)
 	if err != nil {
-		return errors.New(StorageManagerMetadataFailed, "failed to retrieve table schema", err).AddContext("database", database).AddContext("tableName", tableName)
+		return err
 	}
 
-	// Convert Iceberg schema to Arrow schema for validation
-	arrowSchema, err := parquet.ConvertIcebergToArrowSchema(icebergSchema)
+	// Get table metadata to determine storage engine
+	metadata, err := s.LoadTableMetadata(ctx, database, tableName)
+	if err != nil {
+		return err
+	}
+
+	// Get the appropriate storage engine for this table
+	engine, err := s.GetEngine(metadata.StorageEngine)
 	if err != nil {
-		return errors.New(StorageManagerMetadataFailed, "failed to convert schema for validation", err).AddContext("database", database).AddContext("tableName", tableName)
+		return err
 	}
 
-	// Validate data against schema before any storage operations
-	if err := parquet.ValidateDataWithContext(data, arrowSchema, database, tableName); err != nil {
-		s.logger.Error().
-			Err(err).
-			Str("database", database).
-			Str("table", tableName).
-			Int("batch_size", len(data)).
-			Msg("Data validation failed - entire batch rejected")
+	if store, ok := engine.(DataFileStore); ok {
+		config, err := s.tableParquetConfig(ctx, database, tableName)
+		if err != nil {
+			return err
+		}
+		return s.insertDataFiles(ctx, store, database, tableName, metadata.StorageEngine, len(data), func() ([]*parquet.FileInfo, error) {
+			return store.WriteDataFiles(arrowSchema, config, database, tableName, data)
+		})
+	}
 
-		return errors.New(StorageManagerWriteFailed, "data validation failed - batch rejected", err).
-			AddContext("database", database).
-			AddContext("tableName", tableName).
-			AddContext("batch_size", fmt.Sprintf("%d", len(data)))
+	return s.streamRows(ctx, engine, database, tableName, metadata.StorageEngine, data)
+}
+
+// InsertRecords inserts Arrow records into a table. Each record is validated and cast column
+// by column against the table schema, and engines that store Parquet files write the columns
+// as they are, without converting values one by one. The whole insert is rejected if any
+// record is invalid.
+func (s *Storage) InsertRecords(ctx context.Context, database, tableName string, reader array.RecordReader) (err error) {
+	start := time.Now()
+	rows := 0
+	ctx, span := tracing.Start(ctx, "storage.insert",
+		attribute.String("db.namespace", database),
+		attribute.String("db.collection.name", tableName))
+	defer func() {
+		span.SetAttributes(attribute.Int("ranger.rows", rows))
+		tracing.End(span, err)
+		metrics.ObserveStorageWrite(database+"."+tableName, rows, err != nil, time.Since(start))
+	}()
+
+	// Check if table exists in metadata
+	if !s.TableExists(ctx, database, tableName) {
+		return errors.New(errors.CommonNotFound, "table does not exist", nil).AddContext("database", database).AddContext("tableName", tableName)
 	}
 
+	// Validate records against schema before any storage operations
+	arrowSchema, records, err := s.conformRecords(ctx, database, tableName, reader)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		for _, record := range records {
+			record.Release()
+		}
+	}()
+	for _, record := range records {
+		rows += int(record.NumRows())
+	}
+	if rows == 0 {
+		return nil
+	}
+
+	s.logger.Info().
+		Str("database", database).
+		Str("table", tableName).
+		Int("rows", rows).
+		Int("records", len(records)).
+		Msg("Inserting Arrow records into table")
+
 	// Get table metadata to determine storage engine
 	metadata, err := s.LoadTableMetadata(ctx, database, tableName)
 	if err != nil {
@@ -476,25 +688,51 @@ func (s *Storage) InsertData(ctx context.Context, database, tableName string, da
 		return err
 	}
 
+	if store, ok := engine.(DataFileStore); ok {
+		config, err := s.tableParquetConfig(ctx, database, tableName)
+		if err != nil {
+			return err
+		}
+		return s.insertDataFiles(ctx, store, database, tableName, metadata.StorageEngine, rows, func() ([]*parquet.FileInfo, error) {
+			return store.WriteRecordFiles(arrowSchema, config, database, tableName, records)
+		})
+	}
+
+	// Engines without Parquet files store rows
+	return s.streamRows(ctx, engine, database, tableName, metadata.StorageEngine, recordsToRows(records))
+}
+
+// streamRows streams rows into the table through the writer of an engine that keeps them
+// itself rather than in data files, as JSON batches, and counts them in the Registry
+func (s *Storage) streamRows(ctx context.Context, engine FileSystem, database, tableName, storageEngine string, data [][]interface{}) error {
 	// Open streaming writer for the table
+	_, writeSpan := tracing.Start(ctx, "storage.write", attribute.String("ranger.storage_engine", storageEngine))
 	writer, err := engine.OpenTableForWrite(database, tableName)
 	if err != nil {
+		tracing.End(writeSpan, err)
 		return err
 	}
 
-	// Ensure writer is closed and handle rollback on failure
+	// A writer left open by a failed insert discards only what it was given
 	var writeErr error
+	closed := false
 	defer func() {
-		if writeErr != nil {
-			if rollbackErr := engine.RemoveTableEnvironment(database, tableName); rollbackErr != nil {
-				s.logger.Error().
-					Err(rollbackErr).
-					Str("database", database).
-					Str("table", tableName).
-					Msg("Failed to rollback table environment")
-			}
+		tracing.End(writeSpan, writeErr)
+		if closed {
+			return
+		}
+		aborter, ok := writer.(tableWriterAborter)
+		if !ok {
+			writer.Close()
+			return
+		}
+		if abortErr := aborter.Abort(); abortErr != nil {
+			s.logger.Error().
+				Err(abortErr).
+				Str("database", database).
+				Str("table", tableName).
+				Msg("Failed to discard the data of a failed insert")
 		}
-		writer.Close()
 	}()
 
 	// Stream data in batches to avoid memory buildup
@@ -527,21 +765,231 @@ func (s *Storage) InsertData(ctx context.Context, database, tableName string, da
 		}
 	}
 
+	// The data is only committed once the writer closes, which must succeed before it is
+	// registered; a writer that fails to close leaves nothing behind
+	closed = true
+	if err := writer.Close(); err != nil {
+		writeErr = errors.New(StorageManagerWriteFailed, "failed to commit table data", err).AddContext("database", database).AddContext("tableName", tableName)
+		return writeErr
+	}
+
 	s.logger.Debug().
 		Str("database", database).
 		Str("table", tableName).
-		Str("storage_engine", metadata.StorageEngine).
+		Str("storage_engine", storageEngine).
 		Int("rows", len(data)).
 		Msg("Data inserted successfully using streaming")
 
-	// Update metadata after successful data insertion
-	if err := s.updateMetadataAfterInsertion(ctx, database, tableName, len(data), metadata.StorageEngine); err != nil {
-		// Don't fail the insertion if metadata update fails
+	// The engine keeps the rows itself, so there is no data file to register, only rows
+	if err := s.AddTableRows(ctx, database, tableName, int64(len(data))); err != nil {
+		return errors.New(StorageManagerWriteFailed, "failed to register inserted rows", err).AddContext("database", database).AddContext("tableName", tableName)
+	}
+
+	return nil
+}
+
+// insertDataFiles writes an insert to new Parquet files of the table with write and registers
+// each of them with its real size, row count and checksum. Files that could not be registered
+// are removed, so the table never holds data the Registry does not know about.
+func (s *Storage) insertDataFiles(ctx context.Context, store DataFileStore, database, tableName, storageEngine string, rows int, write func() ([]*parquet.FileInfo, error)) error {
+	_, writeSpan := tracing.Start(ctx, "storage.write", attribute.String("ranger.storage_engine", storageEngine))
+	files, err := write()
+	tracing.End(writeSpan, err)
+	if err != nil {
+		return errors.New(StorageManagerWriteFailed, "failed to write data files", err).AddContext("database", database).AddContext("tableName", tableName)
 	}
+	if err := s.registerDataFiles(ctx, store, database, tableName, files, false); err != nil {
+		return err
+	}
+
+	s.logger.Debug().
+		Str("database", database).
+		Str("table", tableName).
+		Str("storage_engine", storageEngine).
+		Int("rows", rows).
+		Int("files", len(files)).
+		Msg("Data inserted successfully as new Parquet files")
 
 	return nil
 }
 
+// registerDataFiles registers data files of the table one by one, removing those that could
+// not be registered; relocated files hold rows the table already counts
+func (s *Storage) registerDataFiles(ctx context.Context, store DataFileStore, database, tableName string, files []*parquet.FileInfo, relocated bool) error {
+	fileInfos := make([]registry.FileInsertionInfo, 0, len(files))
+	for _, file := range files {
+		fileInfos = append(fileInfos, registry.FileInsertionInfo{
+			FileName:  filepath.Base(file.Path),
+			FilePath:  file.Path,
+			FileSize:  file.Size,
+			FileType:  regtypes.FileTypeParquet,
+			RowCount:  file.RowCount,
+			Checksum:  file.Checksum,
+			Relocated: relocated,
+
+			BloomFilterColumns: file.BloomFilterColumns,
+			PageIndex:          file.PageIndex,
+			Sorted:             file.Sorted,
+		})
+	}
+
+	// The files are registered together, so none is left registered when this fails
+	if err := s.updateMetadataAfterInsertion(ctx, database, tableName, fileInfos); err != nil {
+		if removeErr := store.RemoveDataFiles(files); removeErr != nil {
+			s.logger.Error().
+				Err(removeErr).
+				Str("database", database).
+				Str("table", tableName).
+				Msg("Failed to remove unregistered data files")
+		}
+		return err
+	}
+	return nil
+}
+
+// validateInsertData checks rows against the table schema; any invalid row rejects the
+// whole batch
+func (s *Storage) validateInsertData(ctx context.Context, database, tableName string, data [][]interface{}) (_ *arrow.Schema, err error) {
+	ctx, span := tracing.Start(ctx, "storage.validate")
+	defer func() { tracing.End(span, err) }()
+
+	arrowSchema, err := s.tableArrowSchema(ctx, database, tableName)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := parquet.ValidateDataWithContext(data, arrowSchema, database, tableName); err != nil {
+		s.logger.Error().
+			Err(err).
+			Str("database", database).
+			Str("table", tableName).
+			Int("batch_size", len(data)).
+			Msg("Data validation failed - entire batch rejected")
+
+		return nil, errors.New(StorageManagerWriteFailed, "data validation failed - batch rejected", err).
+			AddContext("database", database).
+			AddContext("tableName", tableName).
+			AddContext("batch_size", fmt.Sprintf("%d", len(data)))
+	}
+
+	return arrowSchema, nil
+}
+
+// conformRecords validates every record of reader against the table schema and returns them
+// cast to it; the caller releases the records
+func (s *Storage) conformRecords(ctx context.Context, database, tableName string, reader array.RecordReader) (_ *arrow.Schema, records []arrow.Record, err error) {
+	ctx, span := tracing.Start(ctx, "storage.validate")
+	defer func() { tracing.End(span, err) }()
+
+	arrowSchema, err := s.tableArrowSchema(ctx, database, tableName)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	release := func() {
+		for _, record := range records {
+			record.Release()
+		}
+	}
+
+	for reader.Next() {
+		record, err := parquet.ConformRecord(ctx, reader.Record(), arrowSchema, database, tableName)
+		if err != nil {
+			release()
+			s.logger.Error().
+				Err(err).
+				Str("database", database).
+				Str("table", tableName).
+				Int("record", len(records)).
+				Msg("Record validation failed - entire batch rejected")
+
+			return nil, nil, errors.New(StorageManagerWriteFailed, "data validation failed - batch rejected", err).
+				AddContext("database", database).
+				AddContext("tableName", tableName).
+				AddContext("record", fmt.Sprintf("%d", len(records)))
+		}
+		records = append(records, record)
+	}
+	if err := reader.Err(); err != nil && err != io.EOF {
+		release()
+		return nil, nil, errors.New(StorageManagerWriteFailed, "failed to read records", err).AddContext("database", database).AddContext("tableName", tableName)
+	}
+
+	return arrowSchema, records, nil
+}
+
+// tableArrowSchema returns the Arrow schema that inserted data must match
+func (s *Storage) tableArrowSchema(ctx context.Context, database, tableName string) (*arrow.Schema, error) {
+	// Retrieve schema before processing data
+	icebergSchema, err := s.GetSchema(ctx, database, tableName)
+	if err != nil {
+		return nil, errors.New(StorageManagerMetadataFailed, "failed to retrieve table schema", err).AddContext("database", database).AddContext("tableName", tableName)
+	}
+
+	// Convert Iceberg schema to Arrow schema for validation
+	arrowSchema, err := parquet.ConvertIcebergToArrowSchema(icebergSchema)
+	if err != nil {
+		return nil, errors.New(StorageManagerMetadataFailed, "failed to convert schema for validation", err).AddContext("database", database).AddContext("tableName", tableName)
+	}
+	return arrowSchema, nil
+}
+
+// tableParquetConfig returns the configuration data files of the table are written with,
+// such as its sort key
+func (s *Storage) tableParquetConfig(ctx context.Context, database, tableName string) (*parquet.ParquetConfig, error) {
+	config, err := s.GetParquetConfigForTable(ctx, database, tableName)
+	if err != nil {
+		return nil, errors.New(StorageManagerMetadataFailed, "failed to resolve table write settings", err).AddContext("database", database).AddContext("tableName", tableName)
+	}
+	return config, nil
+}
+
+// validateFileSettings checks the codecs, encodings and indexes requested by the SETTINGS of
+// CREATE TABLE against the columns of the new table
+func validateFileSettings(settings map[string]interface{}, columns []*regtypes.TableColumn) error {
+	config := parquet.DefaultParquetConfig()
+	if err := parquet.ApplyIndexSettings(config, settings); err != nil {
+		return err
+	}
+	if err := parquet.ApplyWriterSettings(config, settings); err != nil {
+		return err
+	}
+	if len(config.BloomFilterColumns) == 0 && len(config.ColumnCompression) == 0 &&
+		len(config.ColumnCompressionLevel) == 0 && len(config.ColumnDictionary) == 0 {
+		return nil
+	}
+
+	icebergSchema, err := parquet.ConvertRegistryDataToIcebergSchema(&registry.SchemaData{Columns: columns})
+	if err != nil {
+		return err
+	}
+	arrowSchema, err := parquet.ConvertIcebergToArrowSchema(icebergSchema)
+	if err != nil {
+		return err
+	}
+	if err := parquet.ValidateWriterColumns(config, arrowSchema); err != nil {
+		return err
+	}
+	return parquet.ValidateIndexColumns(config, arrowSchema)
+}
+
+// recordsToRows converts records to rows of Go values for engines that store rows
+func recordsToRows(records []arrow.Record) [][]interface{} {
+	var rows [][]interface{}
+	for _, record := range records {
+		for i := 0; i < int(record.NumRows()); i++ {
+			row := make([]interface{}, record.NumCols())
+			for c, column := range record.Columns() {
+				if !column.IsNull(i) {
+					row[c] = parquet.ArrayValue(column, i)
+				}
+			}
+			rows = append(rows, row)
+		}
+	}
+	return rows
+}
+
 // GetTableMetadata returns metadata for a table
 func (s *Storage) GetTableMetadata(ctx context.Context, database, tableName string) (*registry.TableMetadata, error) {
 	return s.LoadTableMetadata(ctx, database, tableName)
@@ -550,37 +998,24 @@ func (s *Storage) GetTableMetadata(ctx context.Context, database, tableName stri
 // Note: Individual metadata update methods have been replaced with a single
 // atomic Registry call in updateMetadataAfterInsertion()
 
-// updateMetadataAfterInsertion performs all metadata updates using a single Registry call
-func (s *Storage) updateMetadataAfterInsertion(ctx context.Context, database, tableName string, rowCount int, storageEngine string) error {
+// updateMetadataAfterInsertion registers the files written by an insert and updates the
+// table statistics using a single Registry call
+func (s *Storage) updateMetadataAfterInsertion(ctx context.Context, database, tableName string, fileInfos []registry.FileInsertionInfo) error {
+	var rowCount, fileSize int64
+	for _, fileInfo := range fileInfos {
+		rowCount += fileInfo.RowCount
+		fileSize += fileInfo.FileSize
+	}
+
 	s.logger.Debug().
 		Str("database", database).
 		Str("table", tableName).
-		Int("row_count", rowCount).
-		Str("storage_engine", storageEngine).
+		Int64("row_count", rowCount).
+		Int("file_count", len(fileInfos)).
 		Msg("Starting metadata updates after insertion")
 
-	// Calculate file size (approximate for now)
-	estimatedFileSize := int64(rowCount * 100) // Rough estimate: 100 bytes per row
-
-	// Generate a file name based on timestamp
-	fileName := fmt.Sprintf("data_%d_%s.parquet", time.Now().Unix(), storageEngine)
-	filePath := fmt.Sprintf("databases/%s/%s/%s", database, tableName, fileName)
-	checksum := fmt.Sprintf("checksum_%d_%s", time.Now().Unix(), storageEngine)
-
-	// Create file insertion info for the Registry
-	fileInfo := registry.FileInsertionInfo{
-		FileName:      fileName,
-		FilePath:      filePath,
-		FileSize:      estimatedFileSize,
-		FileType:      "parquet",
-		PartitionPath: "", // No partitioning for now
-		RowCount:      int64(rowCount),
-		Checksum:      checksum,
-		IsCompressed:  false,
-	}
-
 	// Single atomic call to Registry for all metadata updates
-	if err := s.UpdateTableAfterInsertion(ctx, database, tableName, fileInfo); err != nil {
+	if err := s.UpdateTableAfterInsertion(ctx, database, tableName, fileInfos...); err != nil {
 		s.logger.Error().
 			Err(err).
 			Str("database", database).
@@ -592,9 +1027,9 @@ func (s *Storage) updateMetadataAfterInsertion(ctx context.Context, database, ta
 	s.logger.Info().
 		Str("database", database).
 		Str("table", tableName).
-		Int("row_count", rowCount).
-		Str("file_name", fileName).
-		Int64("file_size", estimatedFileSize).
+		Int64("row_count", rowCount).
+		Int("file_count", len(fileInfos)).
+		Int64("file_size", fileSize).
 		Msg("Metadata updates completed successfully after insertion")
 
 	return nil
@@ -639,6 +1074,13 @@ func (s *Storage) GetTableData(ctx context.Context, database, tableName string)
 		return nil, err
 	}
 
+	if store, ok := engine.(DataFileStore); ok {
+		return store.ReadDataFiles(database, tableName)
+	}
+	if tiered, ok := engine.(TieredTableReader); ok {
+		return tiered.ReadTable(database, tableName)
+	}
+
 	// Open streaming reader for the table
 	reader, err := engine.OpenTableForRead(database, tableName)
 	if err != nil {
@@ -683,6 +1125,112 @@ func (s *Storage) GetTableData(ctx context.Context, database, tableName string)
 	return allData, nil
 }
 
+// GetTableDataWhereEqual returns the rows of a table whose column equals value. Row groups of
+// data files that the bloom filters or page indexes of the table rule out are not read.
+func (s *Storage) GetTableDataWhereEqual(ctx context.Context, database, tableName, column string, value interface{}) ([][]interface{}, error) {
+	if !s.TableExists(ctx, database, tableName) {
+		return nil, errors.New(errors.CommonNotFound, "table does not exist", nil).AddContext("database", database).AddContext("tableName", tableName)
+	}
+
+	metadata, err := s.LoadTableMetadata(ctx, database, tableName)
+	if err != nil {
+		return nil, err
+	}
+	engine, err := s.GetEngine(metadata.StorageEngine)
+	if err != nil {
+		return nil, err
+	}
+	store, ok := engine.(DataFileStore)
+	if !ok {
+		return nil, errors.New(StorageManagerUnsupportedEngine, "storage engine does not store data files", nil).
+			AddContext("database", database).
+			AddContext("tableName", tableName).
+			AddContext("storage_engine", metadata.StorageEngine)
+	}
+
+	arrowSchema, err := s.tableArrowSchema(ctx, database, tableName)
+	if err != nil {
+		return nil, err
+	}
+	probe, err := parquet.NewEqualityProbe(arrowSchema, column, value)
+	if err != nil {
+		return nil, errors.AddContext(err, "database", database).AddContext("tableName", tableName)
+	}
+	return store.ReadDataFilesMatching(database, tableName, probe)
+}
+
+// EqualityIndexed reports whether the data files of a table are written with a bloom filter
+// on column or with page indexes, which let GetTableDataWhereEqual skip their row groups
+func (s *Storage) EqualityIndexed(ctx context.Context, database, tableName, column string) bool {
+	metadata, err := s.LoadTableMetadata(ctx, database, tableName)
+	if err != nil {
+		return false
+	}
+	engine, err := s.GetEngine(metadata.StorageEngine)
+	if err != nil {
+		return false
+	}
+	if _, ok := engine.(DataFileStore); !ok {
+		return false
+	}
+
+	config, err := s.tableParquetConfig(ctx, database, tableName)
+	if err != nil {
+		return false
+	}
+	if config.PageIndex {
+		return true
+	}
+	for _, indexed := range config.BloomFilterColumns {
+		if strings.EqualFold(indexed, column) {
+			return true
+		}
+	}
+	return false
+}
+
+// AlterTableSettings applies the SETTINGS of ALTER TABLE to a table through its engine and
+// records them with the settings the table already has in the Registry
+func (s *Storage) AlterTableSettings(ctx context.Context, database, tableName string, settings map[string]interface{}) error {
+	if !s.TableExists(ctx, database, tableName) {
+		return errors.New(errors.CommonNotFound, "table does not exist", nil).AddContext("database", database).AddContext("tableName", tableName)
+	}
+
+	metadata, err := s.LoadTableMetadata(ctx, database, tableName)
+	if err != nil {
+		return err
+	}
+	engine, err := s.GetEngine(metadata.StorageEngine)
+	if err != nil {
+		return err
+	}
+
+	updater, ok := engine.(TableSettingsUpdater)
+	if !ok {
+		return errors.New(StorageManagerUnsupportedEngine, "storage engine does not support altering table settings", nil).
+			AddContext("database", database).
+			AddContext("tableName", tableName).
+			AddContext("storage_engine", metadata.StorageEngine)
+	}
+	if err := updater.UpdateTableSettings(database, tableName, settings); err != nil {
+		return errors.AddContext(err, "database", database).AddContext("tableName", tableName)
+	}
+
+	// The Registry keeps the settings the table is set up with when the server restarts
+	merged, err := s.MergeTableSettings(ctx, database, tableName, settings)
+	if err != nil {
+		return errors.New(StorageManagerMetadataFailed, "failed to record altered table settings", err).AddContext("database", database).AddContext("tableName", tableName)
+	}
+
+	s.logger.Info().
+		Str("database", database).
+		Str("table", tableName).
+		Str("storage_engine", metadata.StorageEngine).
+		Interface("settings", merged).
+		Msg("Table settings altered")
+	return nil
+}
+
 // RemoveTable removes a table and all its data
 func (s *Storage) RemoveTable(ctx context.Context, database, tableName string) error {
 	s.logger.Info().
@@ -846,9 +1394,22 @@ func (s *Storage) validateStorageEngine(storageEngine string) error {
 	return nil
 }
 
-// convertToTableRecord converts CREATE TABLE request to registry Table record
+// convertToTableRecord converts CREATE TABLE request to registry Table record, with the
+// storage engine and the ORDER BY sort key its data files are written with
 func (s *Storage) convertToTableRecord(req *types.CreateTableRequest, stmt *parser.CreateTableStmt) *regtypes.Table {
 	now := time.Now()
+
+	sortColumns := make([]string, 0, len(stmt.OrderBy))
+	for _, column := range stmt.OrderBy {
+		sortColumns = append(sortColumns, column.Value)
+	}
+	sortOrder, _ := json.Marshal(sortColumns)
+	settings, _ := json.Marshal(stmt.Settings)
+	if stmt.Settings == nil {
+		settings = []byte("{}")
+	}
+	compression, _ := stmt.Settings[parquet.SettingCompression].(string)
+
 	return &regtypes.Table{
 		// DatabaseID will be set by the registry when creating the table
 		Name:        stmt.TableName.Table.Value,
@@ -864,6 +1425,13 @@ func (s *Storage) convertToTableRecord(req *types.CreateTableRequest, stmt *pars
 			CreatedAt: now,
 			UpdatedAt: now,
 		},
+		Metadata: &regtypes.TableMetadata{
+			StorageEngine: req.StorageEngine,
+			Compression:   compression,
+			SortOrder:     string(sortOrder),
+			SortStrategy:  "asc",
+			Settings:      string(settings),
+		},
 	}
 }
 
@@ -0,0 +1,10 @@
+package tracing
+
+import "github.com/gear6io/ranger/pkg/errors"
+
+// Tracing-specific error codes
+var (
+	ErrExporterSetupFailed = errors.MustNewCode("tracing.exporter_setup_failed")
+	ErrUnknownExporter     = errors.MustNewCode("tracing.unknown_exporter")
+	ErrShutdownFailed      = errors.MustNewCode("tracing.shutdown_failed")
+)
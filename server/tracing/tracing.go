@@ -0,0 +1,167 @@
+// Package tracing exports OpenTelemetry spans of the query and write paths and carries
+// trace context in from clients, over the native protocol and HTTP traceparent headers.
+package tracing
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"os"
+
+	"github.com/gear6io/ranger/pkg/errors"
+	"github.com/gear6io/ranger/server/config"
+	"github.com/rs/zerolog"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// ComponentType defines the tracing component type identifier
+const ComponentType = "tracing"
+
+// instrumentation names the tracer of every server span
+const instrumentation = "github.com/gear6io/ranger/server"
+
+// TraceParentHeader is the W3C header, and ClientQuery field, carrying trace context
+const TraceParentHeader = "traceparent"
+
+// propagator reads and writes W3C trace context
+var propagator = propagation.TraceContext{}
+
+// stdout is where the stdout exporter writes spans
+var stdout io.Writer = os.Stdout
+
+// Provider exports the spans of the server. When tracing is disabled it holds no
+// provider and spans are not recorded.
+type Provider struct {
+	provider *sdktrace.TracerProvider
+	logger   zerolog.Logger
+}
+
+// NewProvider creates the span exporter described by cfg and installs it as the global
+// tracer provider
+func NewProvider(ctx context.Context, cfg config.TracingConfig, logger zerolog.Logger) (*Provider, error) {
+	p := &Provider{logger: logger.With().Str("component", ComponentType).Logger()}
+	otel.SetTextMapPropagator(propagator)
+	if !cfg.Enabled {
+		return p, nil
+	}
+
+	exporter, err := newExporter(ctx, cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	serviceName := cfg.ServiceName
+	if serviceName == "" {
+		serviceName = "ranger-server"
+	}
+
+	p.provider = sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(resource.NewSchemaless(attribute.String("service.name", serviceName))),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(cfg.SampleRatio))),
+	)
+	otel.SetTracerProvider(p.provider)
+
+	p.logger.Info().
+		Str("exporter", cfg.Exporter).
+		Str("endpoint", cfg.Endpoint).
+		Float64("sample_ratio", cfg.SampleRatio).
+		Msg("Tracing enabled")
+
+	return p, nil
+}
+
+// newExporter creates the span exporter named by cfg.Exporter
+func newExporter(ctx context.Context, cfg config.TracingConfig) (sdktrace.SpanExporter, error) {
+	switch cfg.Exporter {
+	case "", config.TracingExporterOTLP:
+		opts := []otlptracehttp.Option{otlptracehttp.WithEndpoint(cfg.Endpoint)}
+		if cfg.Insecure {
+			opts = append(opts, otlptracehttp.WithInsecure())
+		}
+		exporter, err := otlptracehttp.New(ctx, opts...)
+		if err != nil {
+			return nil, errors.New(ErrExporterSetupFailed, "failed to create OTLP exporter", err).AddContext("endpoint", cfg.Endpoint)
+		}
+		return exporter, nil
+	case config.TracingExporterStdout:
+		exporter, err := stdouttrace.New(stdouttrace.WithWriter(stdout))
+		if err != nil {
+			return nil, errors.New(ErrExporterSetupFailed, "failed to create stdout exporter", err)
+		}
+		return exporter, nil
+	default:
+		return nil, errors.New(ErrUnknownExporter, "unknown span exporter", nil).AddContext("exporter", cfg.Exporter)
+	}
+}
+
+// GetType returns the component type identifier
+func (p *Provider) GetType() string {
+	return ComponentType
+}
+
+// Shutdown flushes the spans not exported yet and stops the exporter
+func (p *Provider) Shutdown(ctx context.Context) error {
+	if p.provider == nil {
+		return nil
+	}
+	if err := p.provider.Shutdown(ctx); err != nil {
+		return errors.New(ErrShutdownFailed, "failed to flush spans", err)
+	}
+	return nil
+}
+
+// Start starts a span named name as a child of the span in ctx
+func Start(ctx context.Context, name string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	return otel.Tracer(instrumentation).Start(ctx, name, trace.WithAttributes(attrs...))
+}
+
+// StartServer starts the span of a request received from a client, as a child of the
+// client's span in ctx
+func StartServer(ctx context.Context, name string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	return otel.Tracer(instrumentation).Start(ctx, name, trace.WithAttributes(attrs...), trace.WithSpanKind(trace.SpanKindServer))
+}
+
+// End ends span, marking it failed when err is not nil
+func End(span trace.Span, err error) {
+	RecordError(span, err)
+	span.End()
+}
+
+// RecordError marks span failed when err is not nil, for errors that are reported to
+// the client rather than returned
+func RecordError(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+}
+
+// WithTraceParent returns ctx carrying the remote span described by a W3C traceparent;
+// ctx is returned as is when traceparent is empty or invalid
+func WithTraceParent(ctx context.Context, traceparent string) context.Context {
+	if traceparent == "" {
+		return ctx
+	}
+	return propagator.Extract(ctx, propagation.MapCarrier{TraceParentHeader: traceparent})
+}
+
+// WithHTTPHeaders returns ctx carrying the remote span of the traceparent header of a
+// request
+func WithHTTPHeaders(ctx context.Context, header http.Header) context.Context {
+	return propagator.Extract(ctx, propagation.HeaderCarrier(header))
+}
+
+// WithSpanOf returns ctx carrying the span of from, so work that outlives a request
+// stays in the request's trace without inheriting its cancellation
+func WithSpanOf(ctx, from context.Context) context.Context {
+	return trace.ContextWithSpanContext(ctx, trace.SpanContextFromContext(from))
+}
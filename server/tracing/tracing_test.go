@@ -0,0 +1,71 @@
+package tracing
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/gear6io/ranger/pkg/errors"
+	"github.com/gear6io/ranger/server/config"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	testTraceID     = "4bf92f3577b34da6a3ce929d0e0e4736"
+	testTraceParent = "00-" + testTraceID + "-00f067aa0ba902b7-01"
+)
+
+func TestStdoutExporter(t *testing.T) {
+	var buf bytes.Buffer
+	stdout = &buf
+
+	cfg := config.LoadDefaultConfig().Tracing
+	cfg.Enabled = true
+	cfg.Exporter = config.TracingExporterStdout
+	provider, err := NewProvider(context.Background(), cfg, zerolog.Nop())
+	require.NoError(t, err)
+
+	// Spans join the trace of the client
+	ctx, span := Start(WithTraceParent(context.Background(), testTraceParent), "storage.insert")
+	assert.Equal(t, testTraceID, span.SpanContext().TraceID().String())
+	_, child := Start(ctx, "storage.validate")
+	End(child, errors.New(errors.CommonInternal, "validation failed", nil))
+	End(span, nil)
+
+	require.NoError(t, provider.Shutdown(context.Background()))
+	output := buf.String()
+	assert.Contains(t, output, `"Name":"storage.insert"`)
+	assert.Contains(t, output, `"Name":"storage.validate"`)
+	assert.Contains(t, output, testTraceID)
+	assert.Contains(t, output, "validation failed")
+	assert.Contains(t, output, "ranger-server")
+}
+
+func TestUnknownExporter(t *testing.T) {
+	cfg := config.TracingConfig{Enabled: true, Exporter: "jaeger"}
+	_, err := NewProvider(context.Background(), cfg, zerolog.Nop())
+	require.Error(t, err)
+	assert.Equal(t, ErrUnknownExporter.String(), errors.GetCode(err))
+}
+
+func TestTraceContextPropagation(t *testing.T) {
+	header := http.Header{}
+	header.Set(TraceParentHeader, testTraceParent)
+	ctx := WithHTTPHeaders(context.Background(), header)
+	assert.Equal(t, testTraceID, trace.SpanContextFromContext(ctx).TraceID().String())
+
+	// Invalid trace parents are ignored
+	ctx = WithTraceParent(context.Background(), "00-invalid")
+	assert.False(t, trace.SpanContextFromContext(ctx).IsValid())
+
+	// Detached work keeps the trace but not the cancellation of the request
+	requestCtx, cancel := context.WithCancel(WithTraceParent(context.Background(), testTraceParent))
+	cancel()
+	ctx = WithSpanOf(context.Background(), requestCtx)
+	assert.NoError(t, ctx.Err())
+	assert.Equal(t, testTraceID, trace.SpanContextFromContext(ctx).TraceID().String())
+}
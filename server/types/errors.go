@@ -19,3 +19,6 @@ var (
 	ErrTypeConversionFailed = errors.MustNewCode("types.conversion_failed")
 	ErrUnsupportedType      = errors.MustNewCode("types.unsupported_type")
 )
+
+// ErrStopRows is returned by a RowSink to end a result early without failing the query
+var ErrStopRows = errors.MustNewCode("types.stop_rows")
@@ -1,9 +1,26 @@
 package types
 
+// Protocols a query can arrive through, recorded in QueryContext.Protocol
+const (
+	ProtocolHTTP   = "http"
+	ProtocolJDBC   = "jdbc"
+	ProtocolNative = "native"
+)
+
 // QueryContext holds the context information for query execution
 type QueryContext struct {
 	Query      string
 	Database   string
 	User       string
 	ClientAddr string
+	Protocol   string // one of the Protocol constants
+	QueryID    string // chosen by the client; generated when empty
+}
+
+// RowSink receives the rows of a query result as they are produced
+type RowSink interface {
+	// Columns is called once, before any row, with the column names and SQL type names
+	Columns(names, types []string) error
+	// Row is called for each result row; an error with code ErrStopRows ends the result
+	Row(values []interface{}) error
 }